@@ -0,0 +1,593 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tybalt/internal/testutils"
+	"tybalt/notifications"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// author@soup.com approves the seeded submitted timesheets and delegates to
+// fakemanager@fakesite.xyz, who holds the tapr claim.
+const (
+	delegatorUID = "f2j5a8vk006baub"
+	delegateUID  = "wegviunlyr2jjjv"
+)
+
+func addApprovalDelegation(tb testing.TB, app core.App, delegator string, delegate string, startDate string, endDate string, scopes ...string) {
+	tb.Helper()
+
+	collection, err := app.FindCollectionByNameOrId("approval_delegations")
+	if err != nil {
+		tb.Fatalf("failed to load approval_delegations collection: %v", err)
+	}
+	record := core.NewRecord(collection)
+	record.Set("delegator", delegator)
+	record.Set("delegate", delegate)
+	record.Set("start_date", startDate)
+	record.Set("end_date", endDate)
+	record.Set("scopes", scopes)
+	if err := app.Save(record); err != nil {
+		tb.Fatalf("failed to save approval delegation: %v", err)
+	}
+}
+
+func setupTestAppWithActiveTimeDelegation(tb testing.TB) *tests.TestApp {
+	tb.Helper()
+
+	app := testutils.SetupTestApp(tb)
+	today := time.Now().UTC()
+	addApprovalDelegation(tb, app, delegatorUID, delegateUID, today.AddDate(0, 0, -1).Format(time.DateOnly), today.AddDate(0, 0, 7).Format(time.DateOnly), "time")
+	return app
+}
+
+func setupTestAppWithExpiredTimeDelegation(tb testing.TB) *tests.TestApp {
+	tb.Helper()
+
+	app := testutils.SetupTestApp(tb)
+	today := time.Now().UTC()
+	addApprovalDelegation(tb, app, delegatorUID, delegateUID, today.AddDate(0, 0, -14).Format(time.DateOnly), today.AddDate(0, 0, -7).Format(time.DateOnly), "time")
+	return app
+}
+
+// setupTestAppWithActivePurchaseOrderDelegation delegates the purchase order
+// approvals of fatt@mac.com, the assigned approver of Unapproved PO
+// gal6e5la2fa4rpn, to u_no_claims.
+func setupTestAppWithActivePurchaseOrderDelegation(tb testing.TB) *tests.TestApp {
+	tb.Helper()
+
+	app := testutils.SetupTestApp(tb)
+	today := time.Now().UTC()
+	addApprovalDelegation(tb, app, "etysnrlup2f6bak", "u_no_claims", today.Format(time.DateOnly), today.Format(time.DateOnly), "purchase_orders")
+	return app
+}
+
+func TestApprovalDelegations_Create(t *testing.T) {
+	delegatorToken, err := testutils.GenerateRecordToken("users", "author@soup.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "approver delegates time and expense approvals",
+			Method: http.MethodPost,
+			URL:    "/api/collections/approval_delegations/records",
+			Body:   strings.NewReader(`{"delegator": "f2j5a8vk006baub", "delegate": "wegviunlyr2jjjv", "start_date": "2030-01-01", "end_date": "2030-01-14", "scopes": ["time", "expenses"]}`),
+			Headers: map[string]string{
+				"Authorization": delegatorToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"delegate":"wegviunlyr2jjjv"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:   "approver cannot delegate to themselves",
+			Method: http.MethodPost,
+			URL:    "/api/collections/approval_delegations/records",
+			Body:   strings.NewReader(`{"delegator": "f2j5a8vk006baub", "delegate": "f2j5a8vk006baub", "start_date": "2030-01-01", "end_date": "2030-01-14", "scopes": ["time"]}`),
+			Headers: map[string]string{
+				"Authorization": delegatorToken,
+			},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"code":"delegate_is_delegator"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:   "timesheet delegate must hold the tapr claim",
+			Method: http.MethodPost,
+			URL:    "/api/collections/approval_delegations/records",
+			Body:   strings.NewReader(`{"delegator": "f2j5a8vk006baub", "delegate": "u_no_claims", "start_date": "2030-01-01", "end_date": "2030-01-14", "scopes": ["time"]}`),
+			Headers: map[string]string{
+				"Authorization": delegatorToken,
+			},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"code":"unqualified_delegate"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:   "end date cannot be before start date",
+			Method: http.MethodPost,
+			URL:    "/api/collections/approval_delegations/records",
+			Body:   strings.NewReader(`{"delegator": "f2j5a8vk006baub", "delegate": "wegviunlyr2jjjv", "start_date": "2030-01-14", "end_date": "2030-01-01", "scopes": ["purchase_orders"]}`),
+			Headers: map[string]string{
+				"Authorization": delegatorToken,
+			},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"code":"end_before_start"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestTimesheetApprove_ByDelegate(t *testing.T) {
+	delegateToken, err := testutils.GenerateRecordToken("users", "fakemanager@fakesite.xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "delegate approves on behalf of the approver",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/aeyl94og4xmnpq4/approve",
+			Headers: map[string]string{
+				"Authorization": delegateToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"message":"Record approved successfully"`},
+			TestAppFactory:  setupTestAppWithActiveTimeDelegation,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+				record, err := app.FindRecordById("time_sheets", "aeyl94og4xmnpq4")
+				if err != nil {
+					tb.Fatalf("failed to load timesheet: %v", err)
+				}
+				if record.GetDateTime("approved").IsZero() {
+					tb.Fatal("timesheet was not approved")
+				}
+				if record.GetString("approver") != delegatorUID || record.GetString("delegated_approver") != delegateUID {
+					tb.Fatalf("approver = %q delegated_approver = %q, want the approver kept and the delegate recorded", record.GetString("approver"), record.GetString("delegated_approver"))
+				}
+			},
+		},
+		{
+			Name:   "an expired delegation does not authorize approval",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/aeyl94og4xmnpq4/approve",
+			Headers: map[string]string{
+				"Authorization": delegateToken,
+			},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{"you are not authorized to approve this record"},
+			TestAppFactory:  setupTestAppWithExpiredTimeDelegation,
+		},
+		{
+			Name:   "delegate sees the approver's pending timesheets in the pending tallies",
+			Method: http.MethodGet,
+			URL:    "/api/time_sheets/tallies/pending",
+			Headers: map[string]string{
+				"Authorization": delegateToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"id":"av32qwch9xrcb5n"`},
+			TestAppFactory:  setupTestAppWithActiveTimeDelegation,
+		},
+		{
+			Name:   "delegate does not see the approver's timesheets without an active delegation",
+			Method: http.MethodGet,
+			URL:    "/api/time_sheets/tallies/pending",
+			Headers: map[string]string{
+				"Authorization": delegateToken,
+			},
+			ExpectedStatus:     http.StatusOK,
+			NotExpectedContent: []string{`"id":"av32qwch9xrcb5n"`},
+			TestAppFactory:     setupTestAppWithExpiredTimeDelegation,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestTimesheetApprovedTallies_ShowDelegatedApprover(t *testing.T) {
+	app := setupTestAppWithActiveTimeDelegation(t)
+	defer app.Cleanup()
+
+	delegateToken, err := testutils.GenerateRecordToken("users", "fakemanager@fakesite.xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	headers := map[string]string{"Authorization": delegateToken}
+	mustStatus(t, performTestAPIRequest(t, app, http.MethodPost, "/api/time_sheets/aeyl94og4xmnpq4/approve", nil, headers), http.StatusOK)
+
+	res := performTestAPIRequest(t, app, http.MethodGet, "/api/time_sheets/tallies/approved", nil, headers)
+	mustStatus(t, res, http.StatusOK)
+
+	var tallies []struct {
+		ID                    string `json:"id"`
+		Approver              string `json:"approver"`
+		DelegatedApprover     string `json:"delegated_approver"`
+		DelegatedApproverName string `json:"delegated_approver_name"`
+	}
+	if err := json.NewDecoder(res.Body).Decode(&tallies); err != nil {
+		t.Fatalf("failed to decode tallies: %v", err)
+	}
+	for _, tally := range tallies {
+		if tally.ID != "aeyl94og4xmnpq4" {
+			continue
+		}
+		if tally.Approver != delegatorUID || tally.DelegatedApprover != delegateUID || tally.DelegatedApproverName == "" {
+			t.Fatalf("tally = %+v, want approved by the delegate on behalf of the approver", tally)
+		}
+		return
+	}
+	t.Fatalf("delegate's approved tallies %+v do not include the timesheet they approved", tallies)
+}
+
+func TestQueueTimesheetApprovalReminders_GoToActiveDelegate(t *testing.T) {
+	app := setupTestAppWithActiveTimeDelegation(t)
+	defer app.Cleanup()
+
+	if err := notifications.QueueTimesheetApprovalReminders(app, false); err != nil {
+		t.Fatalf("expected no error from QueueTimesheetApprovalReminders, got %v", err)
+	}
+
+	countFor := func(uid string) int {
+		var result struct {
+			Count int `db:"count"`
+		}
+		err := app.DB().NewQuery(`
+			SELECT COUNT(*) AS count
+			FROM notifications n
+			JOIN notification_templates nt ON n.template = nt.id
+			WHERE nt.code = 'timesheet_approval_reminder'
+			  AND n.recipient = {:uid}
+		`).Bind(dbx.Params{"uid": uid}).One(&result)
+		if err != nil {
+			t.Fatalf("failed to count reminders: %v", err)
+		}
+		return result.Count
+	}
+	if got := countFor(delegateUID); got != 1 {
+		t.Fatalf("delegate reminders = %d, want 1", got)
+	}
+	if got := countFor(delegatorUID); got != 0 {
+		t.Fatalf("delegator reminders = %d, want none while the delegation is active", got)
+	}
+}
+
+func TestPurchaseOrderApprove_ByDelegate(t *testing.T) {
+	delegateToken, err := testutils.GenerateRecordToken("users", "u_no_claims@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "delegate sees the approver's purchase order in the pending queue",
+			Method: http.MethodGet,
+			URL:    "/api/purchase_orders/pending",
+			Headers: map[string]string{
+				"Authorization": delegateToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"id":"gal6e5la2fa4rpn"`},
+			TestAppFactory:  setupTestAppWithActivePurchaseOrderDelegation,
+		},
+		{
+			Name:   "delegate gives the first approval on behalf of the assigned approver",
+			Method: http.MethodPost,
+			URL:    "/api/purchase_orders/gal6e5la2fa4rpn/approve",
+			Headers: map[string]string{
+				"Authorization": delegateToken,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"status":"Active"`,
+				`"approver":"etysnrlup2f6bak"`,
+				`"delegated_approver":"u_no_claims"`,
+			},
+			TestAppFactory: setupTestAppWithActivePurchaseOrderDelegation,
+		},
+		{
+			Name:   "without a delegation the same user cannot approve",
+			Method: http.MethodPost,
+			URL:    "/api/purchase_orders/gal6e5la2fa4rpn/approve",
+			Headers: map[string]string{
+				"Authorization": delegateToken,
+			},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{`"code":"unauthorized_approval"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestReject_ByDelegate(t *testing.T) {
+	timeDelegateToken, err := testutils.GenerateRecordToken("users", "fakemanager@fakesite.xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expenseDelegateToken, err := testutils.GenerateRecordToken("users", "time@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	poDelegateToken, err := testutils.GenerateRecordToken("users", "u_no_claims@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	poOwnerDelegateToken, err := testutils.GenerateRecordToken("users", "author@soup.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "delegate rejects on behalf of the approver",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/aeyl94og4xmnpq4/reject",
+			Body:   strings.NewReader(`{"rejection_reason": "Missing job numbers"}`),
+			Headers: map[string]string{
+				"Authorization": timeDelegateToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"message":"record rejected successfully"`},
+			TestAppFactory:  setupTestAppWithActiveTimeDelegation,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+				record, err := app.FindRecordById("time_sheets", "aeyl94og4xmnpq4")
+				if err != nil {
+					tb.Fatalf("failed to load timesheet: %v", err)
+				}
+				if record.GetDateTime("rejected").IsZero() || record.GetString("rejector") != delegateUID {
+					tb.Fatalf("rejected = %v rejector = %q, want the timesheet rejected by the delegate", record.GetDateTime("rejected"), record.GetString("rejector"))
+				}
+			},
+		},
+		{
+			Name:   "delegate rejects a batch on behalf of the approver",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/reject_batch",
+			Body:   strings.NewReader(`{"ids": ["aeyl94og4xmnpq4"], "rejection_reason": "Missing job numbers"}`),
+			Headers: map[string]string{
+				"Authorization": timeDelegateToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"id":"aeyl94og4xmnpq4"`, `"status":200`},
+			TestAppFactory:  setupTestAppWithActiveTimeDelegation,
+		},
+		{
+			Name:   "with an expired delegation a commit holder may only reject approved records",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/aeyl94og4xmnpq4/reject",
+			Body:   strings.NewReader(`{"rejection_reason": "Missing job numbers"}`),
+			Headers: map[string]string{
+				"Authorization": timeDelegateToken,
+			},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"code":"record_not_approved"`},
+			TestAppFactory:  setupTestAppWithExpiredTimeDelegation,
+		},
+		{
+			Name:   "delegate cannot reject their own expense",
+			Method: http.MethodPost,
+			URL:    "/api/expenses/exp_approve_closed_po_1/reject",
+			Body:   strings.NewReader(`{"rejection_reason": "Missing receipt"}`),
+			Headers: map[string]string{
+				"Authorization": expenseDelegateToken,
+			},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{`"code":"delegate_is_owner"`},
+			TestAppFactory:  setupTestAppWithDelegateOwnedRecords,
+		},
+		{
+			Name:   "delegate rejects a purchase order on behalf of the assigned approver",
+			Method: http.MethodPost,
+			URL:    "/api/purchase_orders/gal6e5la2fa4rpn/reject",
+			Body:   strings.NewReader(`{"rejection_reason": "Wrong vendor"}`),
+			Headers: map[string]string{
+				"Authorization": poDelegateToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"rejector":"u_no_claims"`},
+			TestAppFactory:  setupTestAppWithActivePurchaseOrderDelegation,
+		},
+		{
+			Name:   "delegate cannot reject their own purchase order",
+			Method: http.MethodPost,
+			URL:    "/api/purchase_orders/gal6e5la2fa4rpn/reject",
+			Body:   strings.NewReader(`{"rejection_reason": "Wrong vendor"}`),
+			Headers: map[string]string{
+				"Authorization": poOwnerDelegateToken,
+			},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{`"code":"delegate_is_owner"`},
+			TestAppFactory:  setupTestAppWithDelegateOwnedRecords,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+// delegateOwnSheetID is a submitted timesheet of the time delegate, approved
+// by the delegator.
+const delegateOwnSheetID = "tsdelegateown01"
+
+// setupTestAppWithDelegateOwnedRecords sets up delegations to the owners of
+// records the delegators approve:
+//
+//   - author@soup.com delegates time approvals to fakemanager@fakesite.xyz,
+//     who owns delegateOwnSheetID.
+//   - author@soup.com delegates expense approvals to time@test.com, who owns
+//     submitted expense exp_approve_closed_po_1.
+//   - fatt@mac.com delegates purchase order approvals to author@soup.com, who
+//     owns Unapproved PO gal6e5la2fa4rpn.
+func setupTestAppWithDelegateOwnedRecords(tb testing.TB) *tests.TestApp {
+	tb.Helper()
+
+	app := setupTestAppWithActiveTimeDelegation(tb)
+	addSubmittedTimesheet(tb, app, delegateOwnSheetID, delegateUID, delegatorUID, time.Now().UTC())
+	// Pending queues only list sheets that have entries.
+	if _, err := app.NonconcurrentDB().NewQuery(`
+		INSERT INTO time_entries (id, tsid, uid, date, hours, time_type, division)
+		SELECT 'tedelegateown01', {:tsid}, {:uid}, '2025-01-01', 8,
+			(SELECT id FROM time_types WHERE code = 'R'), (SELECT id FROM divisions LIMIT 1)
+	`).Bind(dbx.Params{"tsid": delegateOwnSheetID, "uid": delegateUID}).Execute(); err != nil {
+		tb.Fatalf("failed to add time entry to %s: %v", delegateOwnSheetID, err)
+	}
+	today := time.Now().UTC().Format(time.DateOnly)
+	addApprovalDelegation(tb, app, delegatorUID, "rzr98oadsp9qc11", today, today, "expenses")
+	addApprovalDelegation(tb, app, "etysnrlup2f6bak", delegatorUID, today, today, "purchase_orders")
+	return app
+}
+
+func TestApprove_DelegateCannotApproveOwnRecords(t *testing.T) {
+	timeDelegateToken, err := testutils.GenerateRecordToken("users", "fakemanager@fakesite.xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expenseDelegateToken, err := testutils.GenerateRecordToken("users", "time@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	poDelegateToken, err := testutils.GenerateRecordToken("users", "author@soup.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:            "delegate cannot approve their own timesheet",
+			Method:          http.MethodPost,
+			URL:             "/api/time_sheets/" + delegateOwnSheetID + "/approve",
+			Headers:         map[string]string{"Authorization": timeDelegateToken},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{`"error":"you cannot approve your own record as a delegate"`},
+			TestAppFactory:  setupTestAppWithDelegateOwnedRecords,
+		},
+		{
+			Name:               "delegate's own timesheet is left out of their pending tallies",
+			Method:             http.MethodGet,
+			URL:                "/api/time_sheets/tallies/pending",
+			Headers:            map[string]string{"Authorization": timeDelegateToken},
+			ExpectedStatus:     http.StatusOK,
+			ExpectedContent:    []string{`"id":"av32qwch9xrcb5n"`},
+			NotExpectedContent: []string{`"id":"` + delegateOwnSheetID + `"`},
+			TestAppFactory:     setupTestAppWithDelegateOwnedRecords,
+		},
+		{
+			Name:            "delegate cannot approve their own expense",
+			Method:          http.MethodPost,
+			URL:             "/api/expenses/exp_approve_closed_po_1/approve",
+			Headers:         map[string]string{"Authorization": expenseDelegateToken},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{`"error":"you cannot approve your own record as a delegate"`},
+			TestAppFactory:  setupTestAppWithDelegateOwnedRecords,
+		},
+		{
+			Name:               "delegate's own expense is left out of their pending list",
+			Method:             http.MethodGet,
+			URL:                "/api/expenses/pending",
+			Headers:            map[string]string{"Authorization": expenseDelegateToken},
+			ExpectedStatus:     http.StatusOK,
+			NotExpectedContent: []string{`"id":"exp_approve_closed_po_1"`},
+			TestAppFactory:     setupTestAppWithDelegateOwnedRecords,
+		},
+		{
+			Name:            "delegate cannot approve their own purchase order",
+			Method:          http.MethodPost,
+			URL:             "/api/purchase_orders/gal6e5la2fa4rpn/approve",
+			Headers:         map[string]string{"Authorization": poDelegateToken},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{`"code":"delegate_is_owner"`},
+			TestAppFactory:  setupTestAppWithDelegateOwnedRecords,
+		},
+		{
+			Name:               "delegate's own purchase order is left out of their pending queue",
+			Method:             http.MethodGet,
+			URL:                "/api/purchase_orders/pending",
+			Headers:            map[string]string{"Authorization": poDelegateToken},
+			ExpectedStatus:     http.StatusOK,
+			NotExpectedContent: []string{`"id":"gal6e5la2fa4rpn"`},
+			TestAppFactory:     setupTestAppWithDelegateOwnedRecords,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestNavBadges_DelegateOwnRecordsNotPending(t *testing.T) {
+	app := setupTestAppWithDelegateOwnedRecords(t)
+	defer app.Cleanup()
+
+	pendingSheets := func(email string) int {
+		t.Helper()
+		token, err := testutils.GenerateRecordToken("users", email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := performTestAPIRequest(t, app, http.MethodGet, "/api/nav/badges", nil, map[string]string{"Authorization": token})
+		mustStatus(t, res, http.StatusOK)
+		var counts map[string]int
+		if err := json.Unmarshal(res.Body.Bytes(), &counts); err != nil {
+			t.Fatalf("failed to decode nav badges: %v", err)
+		}
+		return counts["/time/sheets/pending"]
+	}
+
+	// The delegator sees every sheet they approve; the delegate sees the same
+	// sheets less their own.
+	delegator, delegate := pendingSheets("author@soup.com"), pendingSheets("fakemanager@fakesite.xyz")
+	if delegate != delegator-1 {
+		t.Fatalf("delegate pending sheets = %d, want %d (the delegator's %d less the delegate's own sheet)", delegate, delegator-1, delegator)
+	}
+}
+
+func TestQueueTimesheetApprovalReminders_DelegateOwnSheetGoesToDelegator(t *testing.T) {
+	app := setupTestAppWithDelegateOwnedRecords(t)
+	defer app.Cleanup()
+
+	if err := notifications.QueueTimesheetApprovalReminders(app, false); err != nil {
+		t.Fatalf("expected no error from QueueTimesheetApprovalReminders, got %v", err)
+	}
+	if got := testutils.CountNotificationsByTemplateCode(t, app, "timesheet_approval_reminder"); got != 2 {
+		t.Fatalf("reminders = %d, want one each for %s and %s", got, delegateUID, delegatorUID)
+	}
+	for _, uid := range []string{delegateUID, delegatorUID} {
+		var result struct {
+			Count int `db:"count"`
+		}
+		if err := app.DB().NewQuery(`
+			SELECT COUNT(*) AS count
+			FROM notifications n
+			JOIN notification_templates nt ON n.template = nt.id
+			WHERE nt.code = 'timesheet_approval_reminder'
+			  AND n.recipient = {:uid}
+		`).Bind(dbx.Params{"uid": uid}).One(&result); err != nil {
+			t.Fatalf("failed to count reminders: %v", err)
+		}
+		if result.Count != 1 {
+			t.Fatalf("reminders for %s = %d, want 1", uid, result.Count)
+		}
+	}
+}
@@ -0,0 +1,173 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"tybalt/internal/testutils"
+
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+type batchRecordResultResponse struct {
+	ID      string `json:"id"`
+	Status  int    `json:"status"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func decodeBatchResults(tb testing.TB, res *http.Response) map[string]batchRecordResultResponse {
+	tb.Helper()
+
+	var results []batchRecordResultResponse
+	if err := json.NewDecoder(res.Body).Decode(&results); err != nil {
+		tb.Fatalf("failed to decode batch results: %v", err)
+	}
+	byID := make(map[string]batchRecordResultResponse, len(results))
+	for _, result := range results {
+		byID[result.ID] = result
+	}
+	return byID
+}
+
+func TestTimesheetApproveBatch(t *testing.T) {
+	approverToken, err := testutils.GenerateRecordToken("users", "author@soup.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	noClaimsToken, err := testutils.GenerateRecordToken("users", "u_no_claims@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "approver approves several timesheets and gets a result per id",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/approve_batch",
+			Body:   strings.NewReader(`{"ids": ["aeyl94og4xmnpq4", "av32qwch9xrcb5n", "j1lr2oddjongtoj", "no_such_sheet", "aeyl94og4xmnpq4"]}`),
+			Headers: map[string]string{
+				"Authorization": approverToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"id":"aeyl94og4xmnpq4"`},
+			TestAppFactory:  testutils.SetupTestApp,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+				results := decodeBatchResults(tb, res)
+				if len(results) != 4 {
+					tb.Fatalf("got %d results, want one per unique id: %+v", len(results), results)
+				}
+				for _, id := range []string{"aeyl94og4xmnpq4", "av32qwch9xrcb5n"} {
+					if results[id].Status != http.StatusOK {
+						tb.Fatalf("result for %s = %+v, want approved", id, results[id])
+					}
+					record, err := app.FindRecordById("time_sheets", id)
+					if err != nil {
+						tb.Fatalf("failed to load timesheet %s: %v", id, err)
+					}
+					if record.GetDateTime("approved").IsZero() {
+						tb.Fatalf("timesheet %s was not approved", id)
+					}
+				}
+				if results["j1lr2oddjongtoj"].Code != "record_committed" {
+					tb.Fatalf("committed timesheet result = %+v, want record_committed", results["j1lr2oddjongtoj"])
+				}
+				if results["no_such_sheet"].Status != http.StatusNotFound {
+					tb.Fatalf("missing timesheet result = %+v, want 404", results["no_such_sheet"])
+				}
+			},
+		},
+		{
+			Name:   "records the caller does not approve are reported without being approved",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/approve_batch",
+			Body:   strings.NewReader(`{"ids": ["aeyl94og4xmnpq4"]}`),
+			Headers: map[string]string{
+				"Authorization": noClaimsToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"code":"unauthorized"`, `"status":403`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:   "an empty batch is rejected",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/approve_batch",
+			Body:   strings.NewReader(`{"ids": []}`),
+			Headers: map[string]string{
+				"Authorization": approverToken,
+			},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"code":"missing_ids"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestTimesheetRejectBatch_QueuesNotifications(t *testing.T) {
+	approverToken, err := testutils.GenerateRecordToken("users", "author@soup.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	baselineApp := testutils.SetupTestApp(t)
+	beforeCount := testutils.CountNotificationsByTemplateCode(t, baselineApp, "timesheet_rejected")
+	baselineApp.Cleanup()
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "reject batch rejects each timesheet with the shared reason",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/reject_batch",
+			Body:   strings.NewReader(`{"ids": ["aeyl94og4xmnpq4", "o9ydei05shks0at", "j1lr2oddjongtoj"], "rejection_reason": "Missing job numbers"}`),
+			Headers: map[string]string{
+				"Authorization": approverToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"id":"aeyl94og4xmnpq4"`},
+			TestAppFactory:  setupTestAppWithSynchronousImmediateNotifications,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+				results := decodeBatchResults(tb, res)
+				for _, id := range []string{"aeyl94og4xmnpq4", "o9ydei05shks0at"} {
+					if results[id].Status != http.StatusOK {
+						tb.Fatalf("result for %s = %+v, want rejected", id, results[id])
+					}
+					record, err := app.FindRecordById("time_sheets", id)
+					if err != nil {
+						tb.Fatalf("failed to load timesheet %s: %v", id, err)
+					}
+					if record.GetString("rejection_reason") != "Missing job numbers" {
+						tb.Fatalf("timesheet %s rejection_reason = %q", id, record.GetString("rejection_reason"))
+					}
+				}
+				if results["j1lr2oddjongtoj"].Code != "record_committed" {
+					tb.Fatalf("committed timesheet result = %+v, want record_committed", results["j1lr2oddjongtoj"])
+				}
+				if afterCount := testutils.CountNotificationsByTemplateCode(tb, app, "timesheet_rejected"); afterCount < beforeCount+2 {
+					tb.Fatalf("expected a timesheet_rejected notification per rejected timesheet, before=%d after=%d", beforeCount, afterCount)
+				}
+			},
+		},
+		{
+			Name:   "reject batch requires a rejection reason",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/reject_batch",
+			Body:   strings.NewReader(`{"ids": ["aeyl94og4xmnpq4"], "rejection_reason": "no"}`),
+			Headers: map[string]string{
+				"Authorization": approverToken,
+			},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"code":"rejection_reason_too_short"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
@@ -7,6 +7,7 @@ import (
 	"tybalt/internal/testutils"
 
 	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
 	"github.com/pocketbase/pocketbase/tests"
 )
 
@@ -219,3 +220,224 @@ func setProjectAuthorizationBundleGateConfig(tb testing.TB, app *tests.TestApp,
 		tb.Fatalf("failed to save jobs app_config: %v", err)
 	}
 }
+
+// TestBundleTimesheet_TimeOffRequestEnforcement verifies the
+// time.time_off_request_enforcement modes for an OV entry bundled with and
+// without a covering approved time off request.
+//
+// Fixture: the self-approver's week ending 2024-09-14 entry is turned into 8
+// hours of vacation on 2024-09-09 with enough opening balance to claim it.
+func TestBundleTimesheet_TimeOffRequestEnforcement(t *testing.T) {
+	recordToken, err := testutils.GenerateRecordToken("users", "self_apv_yes@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []struct {
+		name               string
+		mode               string
+		requested          bool
+		expectedStatus     int
+		expectedContent    []string
+		notExpectedContent []string
+	}{
+		{
+			name:           "warn mode bundles and reports unrequested time off",
+			mode:           "warn",
+			expectedStatus: http.StatusOK,
+			expectedContent: []string{
+				`"message":"Time sheet processed successfully"`,
+				`"code":"time_off_request_missing"`,
+				`"id":"te_self_apv_yes_001"`,
+			},
+		},
+		{
+			name:           "block mode rejects unrequested time off",
+			mode:           "block",
+			expectedStatus: http.StatusUnprocessableEntity,
+			expectedContent: []string{
+				`"code":"time_off_request_required"`,
+			},
+		},
+		{
+			name:           "block mode allows time off covered by an approved request",
+			mode:           "block",
+			requested:      true,
+			expectedStatus: http.StatusOK,
+			expectedContent: []string{
+				`"message":"Time sheet processed successfully"`,
+			},
+			notExpectedContent: []string{`"warnings"`},
+		},
+		{
+			name:           "off mode skips the check",
+			mode:           "off",
+			expectedStatus: http.StatusOK,
+			expectedContent: []string{
+				`"message":"Time sheet processed successfully"`,
+			},
+			notExpectedContent: []string{`"warnings"`},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			apiScenario := tests.ApiScenario{
+				Name:               scenario.name,
+				Method:             http.MethodPost,
+				URL:                "/api/time_sheets/2024-09-14/bundle",
+				Headers:            map[string]string{"Authorization": recordToken},
+				ExpectedStatus:     scenario.expectedStatus,
+				ExpectedContent:    scenario.expectedContent,
+				NotExpectedContent: scenario.notExpectedContent,
+				TestAppFactory: func(tb testing.TB) *tests.TestApp {
+					return setupTimeOffRequestBundleApp(tb, scenario.mode, scenario.requested)
+				},
+			}
+			apiScenario.Test(t)
+		})
+	}
+}
+
+func setupTimeOffRequestBundleApp(tb testing.TB, mode string, requested bool) *tests.TestApp {
+	tb.Helper()
+	app := testutils.SetupTestApp(tb)
+
+	record, err := app.FindFirstRecordByData("app_config", "key", "time")
+	if err != nil {
+		tb.Fatalf("failed to load time app_config: %v", err)
+	}
+	record.Set("value", `{"create_edit": true, "time_off_request_enforcement": "`+mode+`"}`)
+	if err := app.Save(record); err != nil {
+		tb.Fatalf("failed to save time app_config: %v", err)
+	}
+
+	if _, err := app.DB().NewQuery(`
+		UPDATE time_entries
+		SET time_type = 'd35auo4vawx7t9u', job = '', division = '', tsid = ''
+		WHERE id = 'te_self_apv_yes_001'
+	`).Execute(); err != nil {
+		tb.Fatalf("failed to turn self-approver time entry into vacation: %v", err)
+	}
+	if _, err := app.DB().NewQuery(`
+		UPDATE admin_profiles SET opening_ov = 40 WHERE uid = 'u_self_apv_yes'
+	`).Execute(); err != nil {
+		tb.Fatalf("failed to set self-approver opening vacation: %v", err)
+	}
+
+	if requested {
+		collection, err := app.FindCollectionByNameOrId("time_off_requests")
+		if err != nil {
+			tb.Fatalf("failed to load time_off_requests collection: %v", err)
+		}
+		request := core.NewRecord(collection)
+		request.Set("uid", "u_self_apv_yes")
+		request.Set("time_type", "d35auo4vawx7t9u")
+		request.Set("start_date", "2024-09-09")
+		request.Set("end_date", "2024-09-10")
+		request.Set("hours", 16)
+		request.Set("approver", "u_self_apv_yes")
+		request.Set("submitted", true)
+		request.Set("approved", "2024-08-30 12:00:00.000Z")
+		if err := app.Save(request); err != nil {
+			tb.Fatalf("failed to save approved time off request: %v", err)
+		}
+	}
+	return app
+}
+
+// TestTimesheetPreflight verifies that the preflight route reports every bundle
+// violation at once, with the offending entry ids and the computed tallies,
+// and never creates a time sheet.
+func TestTimesheetPreflight(t *testing.T) {
+	recordToken, err := testutils.GenerateRecordToken("users", "self_apv_yes@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	noClaimsToken, err := testutils.GenerateRecordToken("users", "u_no_claims@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	assertNoTimeSheet := func(tb testing.TB, app *tests.TestApp, _ *http.Response) {
+		if _, err := app.FindFirstRecordByFilter(
+			"time_sheets",
+			"uid = {:uid} && week_ending = {:weekEnding}",
+			dbx.Params{"uid": "u_self_apv_yes", "weekEnding": "2024-09-14"},
+		); err == nil {
+			tb.Fatal("preflight must not create a time sheet")
+		}
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:           "preflight requires time claim",
+			Method:         http.MethodGet,
+			URL:            "/api/time_sheets/2024-09-14/preflight",
+			Headers:        map[string]string{"Authorization": noClaimsToken},
+			ExpectedStatus: http.StatusForbidden,
+			ExpectedContent: []string{
+				`"message":"Time claim required."`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "preflight rejects a week ending that is not a Saturday",
+			Method:         http.MethodGet,
+			URL:            "/api/time_sheets/2024-09-13/preflight",
+			Headers:        map[string]string{"Authorization": recordToken},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedContent: []string{
+				`"error":"Week ending date must be a Saturday"`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "preflight reports a bundleable week as ready",
+			Method:         http.MethodGet,
+			URL:            "/api/time_sheets/2024-09-14/preflight",
+			Headers:        map[string]string{"Authorization": recordToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"ready":true`,
+				`"violations":[]`,
+				`"approver_uid":"u_self_apv_yes"`,
+				`"job_hours":8`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+			AfterTestFunc:  assertNoTimeSheet,
+		},
+		{
+			Name:           "preflight reports every violation with entry ids",
+			Method:         http.MethodGet,
+			URL:            "/api/time_sheets/2024-09-14/preflight",
+			Headers:        map[string]string{"Authorization": recordToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"ready":false`,
+				`"code":"ov_claim_exceeds_balance"`,
+				`"code":"time_off_request_required"`,
+				`"entry_ids":["te_self_apv_yes_001"]`,
+				`"used_ov":8`,
+				`"opening_ov":0`,
+			},
+			NotExpectedContent: []string{
+				`"code":"time_sheet_exists"`,
+			},
+			TestAppFactory: func(tb testing.TB) *tests.TestApp {
+				app := setupTimeOffRequestBundleApp(tb, "block", false)
+				if _, err := app.DB().NewQuery(`
+					UPDATE admin_profiles SET opening_ov = 0 WHERE uid = 'u_self_apv_yes'
+				`).Execute(); err != nil {
+					tb.Fatalf("failed to clear self-approver opening vacation: %v", err)
+				}
+				return app
+			},
+			AfterTestFunc: assertNoTimeSheet,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
@@ -0,0 +1,241 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tybalt/internal/testutils"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+type cardStatementTestDetail struct {
+	Statement struct {
+		ID             string `json:"id"`
+		PeriodStart    string `json:"period_start"`
+		PeriodEnd      string `json:"period_end"`
+		LineCount      int    `json:"line_count"`
+		UnmatchedCount int    `json:"unmatched_count"`
+		SuggestedCount int    `json:"suggested_count"`
+		ConfirmedCount int    `json:"confirmed_count"`
+	} `json:"statement"`
+	Lines []struct {
+		ID            string  `json:"id"`
+		Date          string  `json:"date"`
+		Amount        float64 `json:"amount"`
+		MatchStatus   string  `json:"match_status"`
+		Expense       string  `json:"expense"`
+		VendorMatched bool    `json:"vendor_matched"`
+		Confirmer     string  `json:"confirmer"`
+	} `json:"lines"`
+	UnmatchedExpenses []struct {
+		ID string `json:"id"`
+	} `json:"unmatched_expenses"`
+}
+
+func mustCardStatementUpload(t testing.TB, fields map[string]string, filename string, content string) (*bytes.Buffer, string) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	for k, v := range fields {
+		if err := w.WriteField(k, v); err != nil {
+			t.Fatalf("failed to write multipart field %s: %v", k, err)
+		}
+	}
+	fw, err := w.CreateFormFile("file", filename)
+	if err != nil {
+		t.Fatalf("failed to create file field: %v", err)
+	}
+	if _, err := fw.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write statement: %v", err)
+	}
+	contentType := w.FormDataContentType()
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close multipart writer: %v", err)
+	}
+	return buf, contentType
+}
+
+// addCorporateCardExpense copies the seeded corporate credit card expense
+// b4o6xph4ngwx4nw (card 0656) with a new id, date, total and vendor.
+func addCorporateCardExpense(tb testing.TB, app *tests.TestApp, id string, date string, total float64, vendor string) {
+	tb.Helper()
+
+	original, err := app.FindRecordById("expenses", "b4o6xph4ngwx4nw")
+	if err != nil {
+		tb.Fatalf("failed to load seeded expense: %v", err)
+	}
+	record := core.NewRecord(original.Collection())
+	record.Load(original.FieldsData())
+	record.Set("id", id)
+	record.Set("date", date)
+	record.Set("total", total)
+	record.Set("vendor", vendor)
+	if err := app.Save(record); err != nil {
+		tb.Fatalf("failed to save expense %s: %v", id, err)
+	}
+}
+
+func decodeCardStatementDetail(tb testing.TB, body []byte) cardStatementTestDetail {
+	tb.Helper()
+
+	var detail cardStatementTestDetail
+	if err := json.Unmarshal(body, &detail); err != nil {
+		tb.Fatalf("failed to decode card statement detail: %v; body=%s", err, body)
+	}
+	return detail
+}
+
+func TestCardStatementReconciliation(t *testing.T) {
+	payablesToken, err := testutils.GenerateRecordToken("users", "book@keeper.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	regularToken, err := testutils.GenerateRecordToken("users", "time@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := testutils.SetupTestApp(t)
+	defer app.Cleanup()
+
+	// Two expenses on card 0656 have the first charge's amount and date; the
+	// one whose vendor (Big Vendor Industries) is on the statement is
+	// suggested. The seeded expense and ccstmtearly0001 stay unmatched.
+	addCorporateCardExpense(t, app, "ccstmtvendor001", "2024-09-19", 69.42, "2zqxtsmymf670ha")
+	addCorporateCardExpense(t, app, "ccstmtearly0001", "2024-09-10", 20, "")
+
+	statementCSV := "Date,Description,Amount,Reference\n" +
+		"2024-09-18,BIG VENDOR IND #42,69.42,ref-1\n" +
+		"2024-09-19,MYSTERY CHARGE,12.34,ref-2\n" +
+		"2024-09-20,PAYMENT - THANK YOU,-100.00,ref-3\n"
+	fields := map[string]string{
+		"cc_last_4_digits": "0656",
+		"cardholder":       "rzr98oadsp9qc11",
+		"period_start":     "2024-09-01",
+		"period_end":       "2024-09-30",
+	}
+
+	body, contentType := mustCardStatementUpload(t, fields, "september.csv", statementCSV)
+	forbidden := performTestAPIRequest(t, app, http.MethodPost, "/api/card_statements/import", body, map[string]string{
+		"Authorization": regularToken,
+		"Content-Type":  contentType,
+	})
+	mustStatus(t, forbidden, http.StatusForbidden)
+
+	body, contentType = mustCardStatementUpload(t, fields, "september.csv", statementCSV)
+	imported := performTestAPIRequest(t, app, http.MethodPost, "/api/card_statements/import", body, map[string]string{
+		"Authorization": payablesToken,
+		"Content-Type":  contentType,
+	})
+	mustStatus(t, imported, http.StatusOK)
+	detail := decodeCardStatementDetail(t, imported.Body.Bytes())
+	if detail.Statement.PeriodStart != "2024-09-01" || detail.Statement.PeriodEnd != "2024-09-30" || detail.Statement.LineCount != 2 {
+		t.Fatalf("unexpected statement summary %+v", detail.Statement)
+	}
+	if len(detail.Lines) != 2 {
+		t.Fatalf("expected the payment to be skipped, got lines %+v", detail.Lines)
+	}
+	vendorLine, mysteryLine := detail.Lines[0], detail.Lines[1]
+	if vendorLine.MatchStatus != "suggested" || vendorLine.Expense != "ccstmtvendor001" || !vendorLine.VendorMatched {
+		t.Fatalf("expected the vendor's expense to be suggested, got %+v", vendorLine)
+	}
+	if mysteryLine.MatchStatus != "unmatched" || mysteryLine.Expense != "" {
+		t.Fatalf("expected the unknown charge to be unmatched, got %+v", mysteryLine)
+	}
+	unmatchedExpenses := []string{}
+	for _, expense := range detail.UnmatchedExpenses {
+		unmatchedExpenses = append(unmatchedExpenses, expense.ID)
+	}
+	if strings.Join(unmatchedExpenses, ",") != "ccstmtearly0001,b4o6xph4ngwx4nw" {
+		t.Fatalf("unmatched expenses = %v", unmatchedExpenses)
+	}
+
+	statementURL := "/api/card_statements/" + detail.Statement.ID
+	confirmed := performTestAPIRequest(t, app, http.MethodPost, statementURL+"/lines/"+vendorLine.ID+"/confirm", nil, map[string]string{
+		"Authorization": payablesToken,
+	})
+	mustStatus(t, confirmed, http.StatusOK)
+	detail = decodeCardStatementDetail(t, confirmed.Body.Bytes())
+	if detail.Lines[0].MatchStatus != "confirmed" || detail.Lines[0].Confirmer != "tqqf7q0f3378rvp" || detail.Statement.ConfirmedCount != 1 {
+		t.Fatalf("expected the suggestion to be confirmed, got %+v", detail.Lines[0])
+	}
+
+	alreadyMatched := performTestAPIRequest(t, app, http.MethodPost, statementURL+"/lines/"+mysteryLine.ID+"/confirm", strings.NewReader(`{"expense":"ccstmtvendor001"}`), map[string]string{
+		"Authorization": payablesToken,
+	})
+	mustStatus(t, alreadyMatched, http.StatusConflict)
+	if body := mustReadBody(t, alreadyMatched); !strings.Contains(body, `"code":"expense_already_matched"`) {
+		t.Fatalf("expected expense_already_matched, body=%s", body)
+	}
+
+	reminded := performTestAPIRequest(t, app, http.MethodPost, statementURL+"/remind", nil, map[string]string{
+		"Authorization": payablesToken,
+	})
+	mustStatus(t, reminded, http.StatusOK)
+	if body := mustReadBody(t, reminded); !strings.Contains(body, `"line_count":1`) || !strings.Contains(body, `"notification_count":1`) {
+		t.Fatalf("expected one reminder for the unmatched line, body=%s", body)
+	}
+	if got := testutils.CountNotificationsByTemplateCode(t, app, "card_statement_unmatched"); got != 1 {
+		t.Fatalf("expected 1 card_statement_unmatched notification, got %d", got)
+	}
+	line, err := app.FindRecordById("card_statement_lines", mysteryLine.ID)
+	if err != nil {
+		t.Fatalf("failed to load statement line: %v", err)
+	}
+	if line.GetDateTime("reminded").IsZero() {
+		t.Fatal("expected the unmatched line to record the reminder")
+	}
+
+	manual := performTestAPIRequest(t, app, http.MethodPost, statementURL+"/lines/"+mysteryLine.ID+"/confirm", strings.NewReader(`{"expense":"ccstmtearly0001"}`), map[string]string{
+		"Authorization": payablesToken,
+	})
+	mustStatus(t, manual, http.StatusOK)
+	detail = decodeCardStatementDetail(t, manual.Body.Bytes())
+	if detail.Lines[1].MatchStatus != "confirmed" || detail.Lines[1].Expense != "ccstmtearly0001" || len(detail.UnmatchedExpenses) != 1 {
+		t.Fatalf("expected the chosen expense to be confirmed, got %+v", detail)
+	}
+
+	noUnmatched := performTestAPIRequest(t, app, http.MethodPost, statementURL+"/remind", nil, map[string]string{
+		"Authorization": payablesToken,
+	})
+	mustStatus(t, noUnmatched, http.StatusBadRequest)
+
+	unmatched := performTestAPIRequest(t, app, http.MethodPost, statementURL+"/lines/"+mysteryLine.ID+"/unmatch", nil, map[string]string{
+		"Authorization": payablesToken,
+	})
+	mustStatus(t, unmatched, http.StatusOK)
+	detail = decodeCardStatementDetail(t, unmatched.Body.Bytes())
+	if detail.Lines[1].MatchStatus != "unmatched" || detail.Lines[1].Expense != "" || detail.Statement.UnmatchedCount != 1 {
+		t.Fatalf("expected the line to be unmatched, got %+v", detail.Lines[1])
+	}
+
+	ofx := "<OFX><CCACCTFROM><ACCTID>4500000000001234</CCACCTFROM><BANKTRANLIST>" +
+		"<STMTTRN><DTPOSTED>20240918<TRNAMT>-69.42<NAME>BIG VENDOR</BANKTRANLIST></OFX>"
+	body, contentType = mustCardStatementUpload(t, map[string]string{
+		"cc_last_4_digits": "0656",
+		"cardholder":       "rzr98oadsp9qc11",
+	}, "september.ofx", ofx)
+	mismatch := performTestAPIRequest(t, app, http.MethodPost, "/api/card_statements/import", body, map[string]string{
+		"Authorization": payablesToken,
+		"Content-Type":  contentType,
+	})
+	mustStatus(t, mismatch, http.StatusBadRequest)
+	if body := mustReadBody(t, mismatch); !strings.Contains(body, `"code":"card_mismatch"`) {
+		t.Fatalf("expected card_mismatch, body=%s", body)
+	}
+
+	list := performTestAPIRequest(t, app, http.MethodGet, "/api/card_statements", nil, map[string]string{
+		"Authorization": payablesToken,
+	})
+	mustStatus(t, list, http.StatusOK)
+	if body := mustReadBody(t, list); !strings.Contains(body, `"id":"`+detail.Statement.ID+`"`) {
+		t.Fatalf("expected the imported statement in the list, body=%s", body)
+	}
+}
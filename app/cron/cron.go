@@ -1,7 +1,9 @@
 package cron
 
 import (
+	"time"
 	"tybalt/notifications"
+	"tybalt/reports"
 	"tybalt/utilities"
 
 	"github.com/pocketbase/pocketbase/core"
@@ -43,6 +45,46 @@ func AddCronJobs(app core.App) {
 		notifications.QueueTimesheetApprovalReminders(app, true)
 	})
 
+	// escalate stale timesheet approvals at 12:30pm UTC on weekdays. Timesheets
+	// waiting longer than app_config time.approval_escalation_business_days are
+	// escalated once to the alternate manager or the approver's manager.
+	app.Cron().MustAdd("timesheet_approval_escalations", "30 12 * * 1-5", func() {
+		if err := notifications.QueueTimesheetApprovalEscalations(app, true); err != nil {
+			app.Logger().Error("timesheet approval escalation failed", "error", err)
+		}
+	})
+
+	// retry failed notifications every 10 minutes. Failed sends are scheduled
+	// with exponential backoff; due ones are returned to the pending queue and
+	// sent. Notifications that exhaust their attempts are dead-lettered.
+	app.Cron().MustAdd("notification_retries", "*/10 * * * *", func() {
+		requeued, err := notifications.RetryDueNotifications(app)
+		if err != nil {
+			app.Logger().Error("notification retry failed", "requeued", requeued, "error", err)
+		}
+	})
+
+	// send notification_digest summaries at 1pm UTC every day, after the
+	// morning reminder jobs. Recipients who opted into the digest get one email
+	// with every system reminder held for them since the previous digest.
+	app.Cron().MustAdd("notification_digests", "0 13 * * *", func() {
+		if _, err := notifications.SendNotificationDigests(app); err != nil {
+			app.Logger().Error("notification digest delivery failed", "error", err)
+		}
+	})
+
+	// deliver report_subscriptions at 11am UTC on Mondays. Each subscription
+	// receives the report for the most recently completed week or pay period,
+	// once per period, so payroll-period reports go out every second Monday.
+	app.Cron().MustAdd("report_subscription_deliveries", "0 11 * * 1", func() {
+		delivered, err := reports.DeliverReportSubscriptions(app, time.Now())
+		if err != nil {
+			app.Logger().Error("report subscription delivery failed", "delivered", delivered, "error", err)
+			return
+		}
+		app.Logger().Info("report subscriptions delivered", "delivered", delivered)
+	})
+
 	// Refresh foreign-exchange rates on weekday evenings after the Bank of Canada
 	// business-day feed is expected to be published.
 	app.Cron().MustAdd("currency_rate_sync", "0 22 * * 1-5", func() {
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"tybalt/internal/testutils"
+	"tybalt/reports"
+)
+
+type expenseExtractionTestDetails struct {
+	Total                  float64 `json:"total"`
+	AttachmentDocument     string  `json:"attachment_document"`
+	ExtractionStatus       string  `json:"extraction_status"`
+	ExtractedTotal         float64 `json:"extracted_total"`
+	ExtractedTax           float64 `json:"extracted_tax"`
+	ExtractedInvoiceDate   string  `json:"extracted_invoice_date"`
+	ExtractedVendorName    string  `json:"extracted_vendor_name"`
+	ExtractedInvoiceNumber string  `json:"extracted_invoice_number"`
+	ExtractedVendor        string  `json:"extracted_vendor"`
+	ExtractedTotalMismatch bool    `json:"extracted_total_mismatch"`
+}
+
+func TestExpenseDocumentExtraction(t *testing.T) {
+	token, err := testutils.GenerateRecordToken("users", "time@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := testutils.SetupTestApp(t)
+	defer app.Cleanup()
+
+	capitalKind, err := app.FindFirstRecordByFilter("expenditure_kinds", "name = 'capital'")
+	if err != nil {
+		t.Fatalf("failed to load capital kind: %v", err)
+	}
+
+	invoice := reports.NewPDFDocument("Invoice INV-20417", false)
+	invoice.Heading("Big Vendor Industries Ltd.", 1)
+	invoice.Fields([][2]string{{"Invoice Number", "INV-20417"}, {"Invoice Date", "Sep 18, 2024"}})
+	invoice.Table([]reports.PDFColumn{{Header: "Item", Width: 0.5}, {Header: "Amount", Width: 0.2}}, [][]string{
+		{"Widgets", "100.00"},
+		{"Subtotal", "100.00"},
+		{"GST 5%", "5.00"},
+		{"Total", "105.00"},
+	})
+
+	createExpense := func(description string, filename string, content []byte) expenseExtractionTestDetails {
+		t.Helper()
+		body, contentType := mustMultipartExpenseWithContent(t, map[string]string{
+			"uid":          "rzr98oadsp9qc11",
+			"date":         "2024-09-18",
+			"division":     "vccd5fo56ctbigh",
+			"description":  description,
+			"payment_type": "Expense",
+			"total":        "99",
+			"vendor":       "2zqxtsmymf670ha",
+			"kind":         capitalKind.Id,
+		}, filename, content)
+		created := performTestAPIRequest(t, app, http.MethodPost, "/api/collections/expenses/records", body, map[string]string{
+			"Authorization": token,
+			"Content-Type":  contentType,
+		})
+		mustStatus(t, created, http.StatusOK)
+		var record struct {
+			ID string `json:"id"`
+		}
+		if err := json.Unmarshal(created.Body.Bytes(), &record); err != nil {
+			t.Fatalf("failed to decode created expense: %v", err)
+		}
+
+		res := performTestAPIRequest(t, app, http.MethodGet, "/api/expenses/details/"+record.ID, nil, map[string]string{
+			"Authorization": token,
+		})
+		mustStatus(t, res, http.StatusOK)
+		var details expenseExtractionTestDetails
+		if err := json.Unmarshal(res.Body.Bytes(), &details); err != nil {
+			t.Fatalf("failed to decode expense details: %v", err)
+		}
+		return details
+	}
+
+	details := createExpense("invoice with text", "invoice.pdf", invoice.Bytes())
+	if details.ExtractionStatus != "extracted" || details.ExtractedTotal != 105 || details.ExtractedTax != 5 ||
+		details.ExtractedInvoiceDate != "2024-09-18" || details.ExtractedVendorName != "Big Vendor Industries Ltd." ||
+		details.ExtractedInvoiceNumber != "INV-20417" {
+		t.Fatalf("unexpected extracted fields %+v", details)
+	}
+	if details.ExtractedVendor != "2zqxtsmymf670ha" {
+		t.Fatalf("expected the extracted vendor name to match Big Vendor Industries, got %q", details.ExtractedVendor)
+	}
+	if !details.ExtractedTotalMismatch {
+		t.Fatalf("expected a total of %.2f to mismatch the receipt's %.2f", details.Total, details.ExtractedTotal)
+	}
+
+	document, err := app.FindRecordById("expense_documents", details.AttachmentDocument)
+	if err != nil {
+		t.Fatalf("failed to load expense document: %v", err)
+	}
+	if document.GetString("extraction_status") != "extracted" || document.GetFloat("extracted_total") != 105 {
+		t.Fatalf("expected the extraction to be stored on the document, got %+v", document.FieldsData())
+	}
+
+	image := createExpense("photo of a receipt", "receipt.png", []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A})
+	if image.ExtractionStatus != "unsupported" || image.ExtractedTotal != 0 || image.ExtractedTotalMismatch || image.ExtractedVendor != "" {
+		t.Fatalf("expected an image to be left for manual entry, got %+v", image)
+	}
+}
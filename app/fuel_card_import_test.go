@@ -0,0 +1,192 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"mime/multipart"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tybalt/internal/testutils"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+type fuelCardImportTestResponse struct {
+	DryRun               bool `json:"dry_run"`
+	RowCount             int  `json:"row_count"`
+	InvalidCount         int  `json:"invalid_count"`
+	CreatedCount         int  `json:"created_count"`
+	MatchedCount         int  `json:"matched_count"`
+	AlreadyImportedCount int  `json:"already_imported_count"`
+	Rows                 []struct {
+		Row    int    `json:"row"`
+		Status string `json:"status"`
+		ID     string `json:"id"`
+		UID    string `json:"uid"`
+		Vendor string `json:"vendor"`
+		Errors []struct {
+			Field string `json:"field"`
+			Code  string `json:"code"`
+		} `json:"errors"`
+	} `json:"rows"`
+}
+
+func performFuelCardImport(t *testing.T, app *tests.TestApp, token string, content string, confirm bool) (*fuelCardImportTestResponse, int) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	if confirm {
+		if err := w.WriteField("confirm", "true"); err != nil {
+			t.Fatalf("failed to write confirm field: %v", err)
+		}
+	}
+	fw, err := w.CreateFormFile("file", "fleet-september.csv")
+	if err != nil {
+		t.Fatalf("failed to create file field: %v", err)
+	}
+	if _, err := fw.Write([]byte(content)); err != nil {
+		t.Fatalf("failed to write transactions: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close multipart writer: %v", err)
+	}
+
+	res := performTestAPIRequest(t, app, http.MethodPost, "/api/expenses/fuel_card_import", buf, map[string]string{
+		"Authorization": token,
+		"Content-Type":  w.FormDataContentType(),
+	})
+	if res.Code != http.StatusOK && res.Code != http.StatusUnprocessableEntity {
+		return nil, res.Code
+	}
+	var response fuelCardImportTestResponse
+	if err := json.Unmarshal(res.Body.Bytes(), &response); err != nil {
+		t.Fatalf("failed to decode fuel card import response: %v; body=%s", err, res.Body.String())
+	}
+	return &response, res.Code
+}
+
+func TestFuelCardImport(t *testing.T) {
+	payablesToken, err := testutils.GenerateRecordToken("users", "book@keeper.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	driverToken, err := testutils.GenerateRecordToken("users", "time@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := testutils.SetupTestApp(t)
+	defer app.Cleanup()
+
+	profile, err := app.FindFirstRecordByData("admin_profiles", "uid", "rzr98oadsp9qc11")
+	if err != nil {
+		t.Fatalf("failed to load admin profile: %v", err)
+	}
+	profile.Set("fuel_card_number", "7083050012345678")
+	if err := app.Save(profile); err != nil {
+		t.Fatalf("failed to set fuel card number: %v", err)
+	}
+
+	// The driver already entered the second fill-up by hand.
+	original, err := app.FindRecordById("expenses", "b4o6xph4ngwx4nw")
+	if err != nil {
+		t.Fatalf("failed to load seeded expense: %v", err)
+	}
+	handEntered := core.NewRecord(original.Collection())
+	handEntered.Load(original.FieldsData())
+	handEntered.Set("id", "fuelhandentered")
+	handEntered.Set("payment_type", "FuelCard")
+	handEntered.Set("cc_last_4_digits", "")
+	handEntered.Set("date", "2024-09-20")
+	handEntered.Set("total", 80)
+	if err := app.Save(handEntered); err != nil {
+		t.Fatalf("failed to save hand-entered expense: %v", err)
+	}
+
+	export := "Card Number,Transaction Date,Site Name,Product,Litres,Amount,Odometer,Distance,Transaction ID\n" +
+		"XXXX-XXXX-XXXX-5678,2024-09-18,BIG VENDOR INDUSTRIES,Diesel,45.2,69.42,120512,412,T1001\n" +
+		"7083 0500 1234 5678,2024-09-20,ROADSIDE FUEL,Regular,50,80.00,120924,,T1002\n"
+
+	if _, status := performFuelCardImport(t, app, driverToken, export, true); status != http.StatusForbidden {
+		t.Fatalf("expected a driver to be forbidden, got %d", status)
+	}
+
+	dryRun, status := performFuelCardImport(t, app, payablesToken, export, false)
+	if status != http.StatusOK || !dryRun.DryRun || dryRun.CreatedCount != 1 || dryRun.MatchedCount != 1 || dryRun.InvalidCount != 0 {
+		t.Fatalf("unexpected dry run status %d response %+v", status, dryRun)
+	}
+	if dryRun.Rows[0].Status != "created" || dryRun.Rows[0].UID != "rzr98oadsp9qc11" || dryRun.Rows[0].Vendor != "2zqxtsmymf670ha" || dryRun.Rows[0].ID != "" {
+		t.Fatalf("unexpected dry run row %+v", dryRun.Rows[0])
+	}
+	if imported, _ := app.FindFirstRecordByData("expenses", "fuel_card_transaction", "7083050012345678:T1001"); imported != nil {
+		t.Fatal("expected a dry run to save nothing")
+	}
+
+	confirmed, status := performFuelCardImport(t, app, payablesToken, export, true)
+	if status != http.StatusOK || confirmed.DryRun || confirmed.CreatedCount != 1 || confirmed.MatchedCount != 1 {
+		t.Fatalf("unexpected confirmed status %d response %+v", status, confirmed)
+	}
+	if confirmed.Rows[1].Status != "matched" || confirmed.Rows[1].ID != "fuelhandentered" {
+		t.Fatalf("expected the hand-entered expense to be matched, got %+v", confirmed.Rows[1])
+	}
+
+	draft, err := app.FindRecordById("expenses", confirmed.Rows[0].ID)
+	if err != nil {
+		t.Fatalf("failed to load draft expense: %v", err)
+	}
+	if draft.GetString("uid") != "rzr98oadsp9qc11" || draft.GetString("creator") != "rzr98oadsp9qc11" ||
+		draft.GetString("payment_type") != "FuelCard" || draft.GetString("date") != "2024-09-18" ||
+		draft.GetFloat("total") != 69.42 || draft.GetFloat("distance") != 412 ||
+		draft.GetString("vendor") != "2zqxtsmymf670ha" || draft.GetBool("submitted") {
+		t.Fatalf("unexpected draft expense %+v", draft.FieldsData())
+	}
+	if draft.GetString("description") != "Diesel, 45.20 L at BIG VENDOR INDUSTRIES" {
+		t.Fatalf("unexpected draft description %q", draft.GetString("description"))
+	}
+	if draft.GetString("approver") != "f2j5a8vk006baub" || draft.GetString("branch") == "" || draft.GetString("kind") == "" {
+		t.Fatalf("expected the draft to be cleaned like an expense, got %+v", draft.FieldsData())
+	}
+
+	// The driver only adds the job and its division, then submits. The export
+	// stands in for the receipt and the import reference cannot be edited.
+	updated := performTestAPIRequest(t, app, http.MethodPatch, "/api/collections/expenses/records/"+draft.Id, strings.NewReader(`{
+		"job": "cjf0kt0defhq480",
+		"division": "vccd5fo56ctbigh",
+		"category": "t5nmdl188gtlhz0",
+		"fuel_card_transaction": ""
+	}`), map[string]string{"Authorization": driverToken})
+	mustStatus(t, updated, http.StatusOK)
+	submitted := performTestAPIRequest(t, app, http.MethodPost, "/api/expenses/"+draft.Id+"/submit", nil, map[string]string{
+		"Authorization": driverToken,
+	})
+	mustStatus(t, submitted, http.StatusOK)
+	draft, err = app.FindRecordById("expenses", draft.Id)
+	if err != nil {
+		t.Fatalf("failed to reload draft expense: %v", err)
+	}
+	if !draft.GetBool("submitted") || draft.GetString("job") != "cjf0kt0defhq480" || draft.GetString("fuel_card_transaction") != "7083050012345678:T1001" {
+		t.Fatalf("expected the completed draft to be submitted, got %+v", draft.FieldsData())
+	}
+
+	again, status := performFuelCardImport(t, app, payablesToken, export, true)
+	if status != http.StatusOK || again.AlreadyImportedCount != 2 || again.CreatedCount != 0 {
+		t.Fatalf("expected a repeated import to create nothing, got %d %+v", status, again)
+	}
+
+	unknown, status := performFuelCardImport(t, app, payablesToken, export+
+		"7083050099999999,2024-09-21,ROADSIDE FUEL,Regular,30,45.00,,,T1003\n"+
+		"7083050012345678,2024-09-22,ROADSIDE FUEL,Regular,30,45.00,,,T1004\n", true)
+	if status != http.StatusUnprocessableEntity || unknown.InvalidCount != 1 {
+		t.Fatalf("expected an unknown card to fail the import, got %d %+v", status, unknown)
+	}
+	if len(unknown.Rows[2].Errors) != 1 || unknown.Rows[2].Errors[0].Code != "unknown_card" {
+		t.Fatalf("expected unknown_card, got %+v", unknown.Rows[2])
+	}
+	if imported, _ := app.FindFirstRecordByData("expenses", "fuel_card_transaction", "7083050012345678:T1004"); imported != nil {
+		t.Fatal("expected an import with an invalid row to save nothing")
+	}
+}
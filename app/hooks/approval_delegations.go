@@ -0,0 +1,82 @@
+package hooks
+
+import (
+	"net/http"
+	"slices"
+	"time"
+
+	"tybalt/errs"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ProcessApprovalDelegation validates an approval delegation before create or
+// update. The delegate must be an active user other than the delegator, the
+// date range must be valid and, because timesheet and expense approvers need
+// the tapr claim, a delegate receiving those scopes must hold it too.
+// Delegating to one of the delegator's own reports is allowed; the approve
+// and reject routes refuse delegated action on the delegate's own records.
+func ProcessApprovalDelegation(app core.App, e *core.RecordRequestEvent) error {
+	record := e.Record
+	delegatorID := record.GetString("delegator")
+	delegateID := record.GetString("delegate")
+
+	fieldErrors := map[string]errs.CodeError{}
+
+	if delegateID == delegatorID {
+		fieldErrors["delegate"] = errs.CodeError{Code: "delegate_is_delegator", Message: "you cannot delegate approvals to yourself"}
+	} else {
+		active, err := utilities.IsUserActive(app, delegateID)
+		if err != nil {
+			return &errs.HookError{
+				Status:  http.StatusInternalServerError,
+				Message: "failed to check delegate active status",
+			}
+		}
+		if !active {
+			fieldErrors["delegate"] = errs.CodeError{Code: "delegate_not_active", Message: "the delegate must be an active user"}
+		}
+	}
+
+	startDate := record.GetString("start_date")
+	endDate := record.GetString("end_date")
+	_, startErr := time.Parse(time.DateOnly, startDate)
+	if startErr != nil {
+		fieldErrors["start_date"] = errs.CodeError{Code: "invalid_date", Message: "start date must be in YYYY-MM-DD format"}
+	}
+	if _, err := time.Parse(time.DateOnly, endDate); err != nil {
+		fieldErrors["end_date"] = errs.CodeError{Code: "invalid_date", Message: "end date must be in YYYY-MM-DD format"}
+	} else if startErr == nil && endDate < startDate {
+		fieldErrors["end_date"] = errs.CodeError{Code: "end_before_start", Message: "end date must not be before start date"}
+	}
+
+	scopes := record.GetStringSlice("scopes")
+	if len(scopes) == 0 {
+		fieldErrors["scopes"] = errs.CodeError{Code: "missing_scopes", Message: "choose at least one kind of approval to delegate"}
+	} else if _, ok := fieldErrors["delegate"]; !ok &&
+		(slices.Contains(scopes, utilities.ApprovalDelegationScopeTime) || slices.Contains(scopes, utilities.ApprovalDelegationScopeExpenses)) {
+		hasTapr, err := utilities.HasClaimByUserID(app, delegateID, "tapr")
+		if err != nil {
+			return &errs.HookError{
+				Status:  http.StatusInternalServerError,
+				Message: "hook error when validating approval delegation",
+				Data: map[string]errs.CodeError{
+					"delegate": {Code: "error_checking_claim", Message: "error checking delegate claim"},
+				},
+			}
+		}
+		if !hasTapr {
+			fieldErrors["delegate"] = errs.CodeError{Code: "unqualified_delegate", Message: "a delegate for timesheets or expenses must have the tapr claim"}
+		}
+	}
+
+	if len(fieldErrors) > 0 {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when validating approval delegation",
+			Data:    fieldErrors,
+		}
+	}
+	return nil
+}
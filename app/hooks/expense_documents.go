@@ -1,6 +1,9 @@
 package hooks
 
 import (
+	"bytes"
+	"errors"
+	"io"
 	"net/http"
 	"strings"
 	"tybalt/constants"
@@ -277,14 +280,19 @@ func createExpenseDocument(app core.App, e *core.RecordRequestEvent, file *files
 	}
 
 	now := types.NowDateTime()
-	_, err = app.DB().Insert(constants.ExpenseDocumentsCollectionName, dbx.Params{
+	params := dbx.Params{
 		"id":              document.Id,
 		"attachment":      file.Name,
 		"attachment_hash": attachmentHash,
 		"uploaded_by":     uploadedBy,
 		"created":         now,
 		"updated":         now,
-	}).Execute()
+	}
+	extracted := extractExpenseDocumentFields(app, file)
+	for field, value := range extracted {
+		params[field] = value
+	}
+	_, err = app.DB().Insert(constants.ExpenseDocumentsCollectionName, params).Execute()
 	if err != nil {
 		_ = fsys.Delete(storagePath)
 		return nil, err
@@ -296,6 +304,9 @@ func createExpenseDocument(app core.App, e *core.RecordRequestEvent, file *files
 	document.SetRaw("uploaded_by", uploadedBy)
 	document.SetRaw("created", now)
 	document.SetRaw("updated", now)
+	for field, value := range extracted {
+		document.SetRaw(field, value)
+	}
 	if err := document.PostScan(); err != nil {
 		return nil, err
 	}
@@ -303,6 +314,59 @@ func createExpenseDocument(app core.App, e *core.RecordRequestEvent, file *files
 	return document, nil
 }
 
+// extractExpenseDocumentFields reads the invoice fields from an uploaded PDF
+// receipt. The values are only suggestions, so a file that cannot be read
+// records why in extraction_status rather than failing the upload.
+func extractExpenseDocumentFields(app core.App, file *filesystem.File) dbx.Params {
+	params := dbx.Params{
+		"extraction_status":        "unsupported",
+		"extracted_total":          0,
+		"extracted_tax":            0,
+		"extracted_invoice_date":   "",
+		"extracted_vendor_name":    "",
+		"extracted_invoice_number": "",
+	}
+	reader, err := file.Reader.Open()
+	if err != nil {
+		app.Logger().Warn("failed to open expense document for extraction", "name", file.Name, "error", err)
+		params["extraction_status"] = "failed"
+		return params
+	}
+	defer reader.Close()
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		app.Logger().Warn("failed to read expense document for extraction", "name", file.Name, "error", err)
+		params["extraction_status"] = "failed"
+		return params
+	}
+	if !bytes.HasPrefix(content, []byte("%PDF-")) {
+		return params
+	}
+
+	text, err := utilities.ExtractPDFText(content)
+	if errors.Is(err, utilities.ErrPDFNoText) {
+		params["extraction_status"] = "no_text"
+		return params
+	}
+	if err != nil {
+		app.Logger().Warn("failed to extract expense document text", "name", file.Name, "error", err)
+		params["extraction_status"] = "failed"
+		return params
+	}
+	fields := utilities.ExtractInvoiceFields(text)
+	if fields.IsEmpty() {
+		params["extraction_status"] = "no_fields"
+		return params
+	}
+	params["extraction_status"] = "extracted"
+	params["extracted_total"] = fields.Total
+	params["extracted_tax"] = fields.Tax
+	params["extracted_invoice_date"] = fields.InvoiceDate
+	params["extracted_vendor_name"] = fields.VendorName
+	params["extracted_invoice_number"] = fields.InvoiceNumber
+	return params
+}
+
 func validateExpenseDocumentFile(file *filesystem.File) error {
 	if file == nil {
 		return duplicateAttachmentPermissionError("attachment", "required", "attachment is required")
@@ -0,0 +1,94 @@
+package hooks
+
+import (
+	"math"
+	"net/http"
+
+	"tybalt/errs"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// cleanExpenseSalesTax fills in and checks the sales tax split of an expense.
+// Allowances, mileage and personal reimbursements carry no sales tax. For other
+// expenses a blank tax_code defaults to EXEMPT for foreign currency purchases
+// and otherwise to the vendor's tax_code or the branch's province. When only
+// one of tax_amount and pre_tax_amount is entered the other is derived from
+// the tax-inclusive total; when neither is entered, or the total or code
+// changed without them, both are computed at the code's rate.
+func cleanExpenseSalesTax(app core.App, expenseRecord *core.Record, paymentType string, homeCurrency bool) error {
+	switch paymentType {
+	case "Allowance", "Mileage", "PersonalReimbursement":
+		expenseRecord.Set("tax_code", "")
+		expenseRecord.Set("tax_amount", 0)
+		expenseRecord.Set("pre_tax_amount", 0)
+		return nil
+	}
+
+	config := utilities.LoadSalesTaxConfig(app)
+	code := utilities.NormalizeSalesTaxCode(expenseRecord.GetString("tax_code"))
+	switch {
+	case code == "" && !homeCurrency:
+		code = utilities.SalesTaxCodeExempt
+	case code == "":
+		vendorCode := ""
+		if vendorID := expenseRecord.GetString("vendor"); vendorID != "" {
+			if vendor, err := app.FindRecordById("vendors", vendorID); err == nil {
+				vendorCode = vendor.GetString("tax_code")
+			}
+		}
+		code = config.DefaultCode(vendorCode, expenseRecord.GetString("branch"))
+	case !config.Valid(code):
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when cleaning expense",
+			Data: map[string]errs.CodeError{
+				"tax_code": {Code: "invalid_tax_code", Message: "tax code must be a configured province or EXEMPT"},
+			},
+		}
+	}
+	expenseRecord.Set("tax_code", code)
+
+	total := expenseRecord.GetFloat("total")
+	tax := expenseRecord.GetFloat("tax_amount")
+	preTax := expenseRecord.GetFloat("pre_tax_amount")
+	taxEntered, preTaxEntered, stale := tax != 0, preTax != 0, false
+	if original := expenseRecord.Original(); !expenseRecord.IsNew() && original != nil {
+		taxEntered = tax != original.GetFloat("tax_amount")
+		preTaxEntered = preTax != original.GetFloat("pre_tax_amount")
+		stale = total != original.GetFloat("total") || code != utilities.NormalizeSalesTaxCode(original.GetString("tax_code"))
+	}
+	switch {
+	case taxEntered && !preTaxEntered:
+		preTax = total - tax
+	case preTaxEntered && !taxEntered:
+		tax = total - preTax
+	case !taxEntered && !preTaxEntered && (stale || (tax == 0 && preTax == 0)):
+		preTax, tax = config.SplitInclusive(code, total)
+	}
+	tax = utilities.RoundCurrencyAmount(tax)
+	preTax = utilities.RoundCurrencyAmount(preTax)
+	expenseRecord.Set("tax_amount", tax)
+	expenseRecord.Set("pre_tax_amount", preTax)
+
+	if tax < 0 || preTax < 0 || math.Abs(preTax+tax-total) > 0.005 {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when cleaning expense",
+			Data: map[string]errs.CodeError{
+				"tax_amount": {Code: "tax_split_mismatch", Message: "pre-tax amount and tax must add up to the total"},
+			},
+		}
+	}
+	if config.ExceedsRate(code, preTax, tax) {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when cleaning expense",
+			Data: map[string]errs.CodeError{
+				"tax_amount": {Code: "tax_exceeds_rate", Message: "tax is more than the tax code's rate allows on the pre-tax amount"},
+			},
+		}
+	}
+	return nil
+}
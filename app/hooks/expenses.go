@@ -230,6 +230,23 @@ func cleanExpense(app core.App, expenseRecord *core.Record, poRecord *core.Recor
 			}
 		}
 
+		// an expense with logged trips claims the sum of their kilometres
+		if !expenseRecord.IsNew() {
+			tripDistance, hasTrips, tripsErr := mileageTripsDistance(app, expenseRecord.Id, "")
+			if tripsErr != nil {
+				return &errs.HookError{
+					Status:  http.StatusInternalServerError,
+					Message: "hook error when cleaning expense",
+					Data: map[string]errs.CodeError{
+						"distance": {Code: "error_loading_mileage_trips", Message: "error loading mileage trips"},
+					},
+				}
+			}
+			if hasTrips {
+				expenseRecord.Set("distance", tripDistance)
+			}
+		}
+
 		// if the paymentType is "Mileage", distance must be a positive integer
 		// and we calculate the total by multiplying distance by the rate
 		distance := expenseRecord.GetFloat("distance")
@@ -350,6 +367,29 @@ func cleanExpense(app core.App, expenseRecord *core.Record, poRecord *core.Recor
 
 	}
 
+	// logged trips only make sense on a mileage expense
+	if paymentType != "Mileage" && !expenseRecord.IsNew() {
+		_, hasTrips, tripsErr := mileageTripsDistance(app, expenseRecord.Id, "")
+		if tripsErr != nil {
+			return &errs.HookError{
+				Status:  http.StatusInternalServerError,
+				Message: "hook error when cleaning expense",
+				Data: map[string]errs.CodeError{
+					"payment_type": {Code: "error_loading_mileage_trips", Message: "error loading mileage trips"},
+				},
+			}
+		}
+		if hasTrips {
+			return &errs.HookError{
+				Status:  http.StatusBadRequest,
+				Message: "hook error when cleaning expense",
+				Data: map[string]errs.CodeError{
+					"payment_type": {Code: "has_mileage_trips", Message: "delete the logged trips before changing this expense from mileage"},
+				},
+			}
+		}
+	}
+
 	if purchaseOrderID == "" {
 		switch paymentType {
 		case "Allowance", "FuelCard", "Mileage", "PersonalReimbursement":
@@ -396,7 +436,7 @@ func cleanExpense(app core.App, expenseRecord *core.Record, poRecord *core.Recor
 		expenseRecord.Set("settler", "")
 		expenseRecord.Set("settled", "")
 	}
-	return nil
+	return cleanExpenseSalesTax(app, expenseRecord, paymentType, utilities.IsHomeCurrencyInfo(currencyInfo))
 }
 
 func setManagerApprover(app core.App, expenseRecord *core.Record) error {
@@ -589,6 +629,18 @@ func validateBookkeeperOnBehalfCreate(app core.App, expenseRecord *core.Record,
 	return nil
 }
 
+// PrepareDraftExpense cleans a draft expense created for its owner outside a
+// request, such as one imported from a fuel card transaction export. It sets
+// the branch, kind, currency and approver the same way ProcessExpense does but
+// does not validate the record: the owner completes the draft, which validates
+// it, before submitting.
+func PrepareDraftExpense(app core.App, expenseRecord *core.Record) error {
+	if err := cleanExpense(app, expenseRecord, nil, false); err != nil {
+		return err
+	}
+	return EnsureActiveDivision(app, expenseRecord.GetString("division"), "division")
+}
+
 // The processExpense function is used to process the expense record. It is
 // called by the hooks for the expenses collection to ensure that the record
 // is in a valid state before it is created or updated.
@@ -735,6 +787,16 @@ func ProcessExpense(app core.App, e *core.RecordRequestEvent) error {
 		}
 	}
 
+	// delegated_approver is only set by the approve route.
+	expenseRecord.Set("delegated_approver", "")
+
+	// fuel_card_transaction is only set by the fuel card import.
+	if expenseRecord.IsNew() {
+		expenseRecord.Set("fuel_card_transaction", "")
+	} else {
+		expenseRecord.Set("fuel_card_transaction", expenseRecord.Original().GetString("fuel_card_transaction"))
+	}
+
 	// clean the expense record
 	if err := cleanExpense(app, expenseRecord, poRecord, creatorApprover); err != nil {
 		return err
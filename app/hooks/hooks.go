@@ -353,6 +353,100 @@ func AddHooks(app core.App) {
 		return e.Next()
 	}
 	app.OnRecordDeleteRequest("time_entries").BindFunc(timeEntriesGateHook)
+
+	// hooks for work records. The collections have no API write rules, so
+	// writes arrive from /api/first_class_work_records or internal saves and
+	// the checks live on model hooks.
+	app.OnRecordCreate("work_records").BindFunc(func(e *core.RecordEvent) error {
+		if err := ValidateWorkRecord(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdate("work_records").BindFunc(func(e *core.RecordEvent) error {
+		if err := ValidateWorkRecord(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordDelete("work_records").BindFunc(func(e *core.RecordEvent) error {
+		if err := ValidateWorkRecordDelete(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordCreate("work_records_subjects").BindFunc(func(e *core.RecordEvent) error {
+		if err := ValidateWorkRecordSubject(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdate("work_records_subjects").BindFunc(func(e *core.RecordEvent) error {
+		if err := ValidateWorkRecordSubject(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordDelete("work_records_subjects").BindFunc(func(e *core.RecordEvent) error {
+		if err := ValidateWorkRecordSubjectDelete(e.App, e.Record); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordCreate("work_records_consumable_entries").BindFunc(func(e *core.RecordEvent) error {
+		if err := ValidateWorkRecordConsumableEntry(e.App, e.Record, false); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdate("work_records_consumable_entries").BindFunc(func(e *core.RecordEvent) error {
+		if err := ValidateWorkRecordConsumableEntry(e.App, e.Record, false); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+	app.OnRecordDelete("work_records_consumable_entries").BindFunc(func(e *core.RecordEvent) error {
+		if err := ValidateWorkRecordConsumableEntry(e.App, e.Record, true); err != nil {
+			return err
+		}
+		return e.Next()
+	})
+
+	// Keep work_records_subjects.approved in sync with the time sheet state of
+	// the linked time entry. Bundling and unbundling save the entries, while
+	// approve, reject and recall save the time sheet, so both sides fan out.
+	syncTimeEntryWorkRecordApproval := func(e *core.RecordEvent) error {
+		previousSubjectID := ""
+		if !e.Record.IsNew() {
+			previousSubjectID = e.Record.Original().GetString("work_record_subject_id")
+		}
+		currentSubjectID := e.Record.GetString("work_record_subject_id")
+		if err := e.Next(); err != nil {
+			return err
+		}
+		if err := SyncWorkRecordSubjectApproval(e.App, previousSubjectID); err != nil {
+			return err
+		}
+		if currentSubjectID == previousSubjectID {
+			return nil
+		}
+		return SyncWorkRecordSubjectApproval(e.App, currentSubjectID)
+	}
+	app.OnRecordCreate("time_entries").BindFunc(syncTimeEntryWorkRecordApproval)
+	app.OnRecordUpdate("time_entries").BindFunc(syncTimeEntryWorkRecordApproval)
+	app.OnRecordDelete("time_entries").BindFunc(func(e *core.RecordEvent) error {
+		subjectID := e.Record.GetString("work_record_subject_id")
+		if err := e.Next(); err != nil {
+			return err
+		}
+		return SyncWorkRecordSubjectApproval(e.App, subjectID)
+	})
+	app.OnRecordUpdate("time_sheets").BindFunc(func(e *core.RecordEvent) error {
+		if err := e.Next(); err != nil {
+			return err
+		}
+		return SyncWorkRecordApprovalsForTimeSheet(e.App, e.Record.Id)
+	})
 	// hooks for time_amendments model
 	app.OnRecordCreateRequest("time_amendments").BindFunc(func(e *core.RecordRequestEvent) error {
 		if err := ProcessTimeAmendment(app, e); err != nil {
@@ -366,7 +460,7 @@ func AddHooks(app core.App) {
 		}
 		return e.Next()
 	})
-	timeAmendmentsGateHook := func(e *core.RecordRequestEvent) error {
+	timeEditingGateHook := func(e *core.RecordRequestEvent) error {
 		enabled, err := utilities.IsTimeEditingEnabled(app)
 		if err != nil {
 			return AnnotateHookError(app, e, err)
@@ -376,7 +470,48 @@ func AddHooks(app core.App) {
 		}
 		return e.Next()
 	}
-	app.OnRecordDeleteRequest("time_amendments").BindFunc(timeAmendmentsGateHook)
+	app.OnRecordDeleteRequest("time_amendments").BindFunc(timeEditingGateHook)
+	// hooks for time_off_requests model
+	app.OnRecordCreateRequest("time_off_requests").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := ProcessTimeOffRequest(app, e); err != nil {
+			return AnnotateHookError(app, e, err)
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdateRequest("time_off_requests").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := ProcessTimeOffRequest(app, e); err != nil {
+			return AnnotateHookError(app, e, err)
+		}
+		return e.Next()
+	})
+	app.OnRecordDeleteRequest("time_off_requests").BindFunc(timeEditingGateHook)
+	// hooks for time_entry_templates model
+	app.OnRecordCreateRequest("time_entry_templates").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := ProcessTimeEntryTemplate(app, e); err != nil {
+			return AnnotateHookError(app, e, err)
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdateRequest("time_entry_templates").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := ProcessTimeEntryTemplate(app, e); err != nil {
+			return AnnotateHookError(app, e, err)
+		}
+		return e.Next()
+	})
+	app.OnRecordDeleteRequest("time_entry_templates").BindFunc(timeEditingGateHook)
+	// hooks for approval_delegations model
+	app.OnRecordCreateRequest("approval_delegations").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := ProcessApprovalDelegation(app, e); err != nil {
+			return AnnotateHookError(app, e, err)
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdateRequest("approval_delegations").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := ProcessApprovalDelegation(app, e); err != nil {
+			return AnnotateHookError(app, e, err)
+		}
+		return e.Next()
+	})
 	// hooks for purchase_orders model
 	app.OnRecordCreateRequest("purchase_orders").BindFunc(func(e *core.RecordRequestEvent) error {
 		nid, err := ProcessPurchaseOrder(app, e)
@@ -440,6 +575,7 @@ func AddHooks(app core.App) {
 		}
 		return e.Next()
 	})
+	app.OnRecordEnrich("profiles").BindFunc(HideProfileWebhookURL)
 	// hooks for currencies model
 	app.OnRecordCreateRequest("currencies").BindFunc(func(e *core.RecordRequestEvent) error {
 		if err := ProcessCurrency(app, e); err != nil {
@@ -482,6 +618,19 @@ func AddHooks(app core.App) {
 		}
 		return e.Next()
 	})
+	// hooks for notification_preferences model
+	app.OnRecordCreateRequest("notification_preferences").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := ProcessNotificationPreference(app, e); err != nil {
+			return AnnotateHookError(app, e, err)
+		}
+		return e.Next()
+	})
+	app.OnRecordUpdateRequest("notification_preferences").BindFunc(func(e *core.RecordRequestEvent) error {
+		if err := ProcessNotificationPreference(app, e); err != nil {
+			return AnnotateHookError(app, e, err)
+		}
+		return e.Next()
+	})
 	// hooks for client_notes model
 	app.OnRecordCreateRequest("client_notes").BindFunc(func(e *core.RecordRequestEvent) error {
 		if err := ProcessClientNote(app, e); err != nil {
@@ -535,6 +684,17 @@ func AddHooks(app core.App) {
 		}
 		return err
 	})
+	// hooks for mileage_trips model: each change re-derives the distance and
+	// total of the trip's expense in the same transaction.
+	app.OnRecordCreateRequest("mileage_trips").BindFunc(func(e *core.RecordRequestEvent) error {
+		return processMileageTripRequest(app, e, false)
+	})
+	app.OnRecordUpdateRequest("mileage_trips").BindFunc(func(e *core.RecordRequestEvent) error {
+		return processMileageTripRequest(app, e, false)
+	})
+	app.OnRecordDeleteRequest("mileage_trips").BindFunc(func(e *core.RecordRequestEvent) error {
+		return processMileageTripRequest(app, e, true)
+	})
 	// Gate-only hook: blocks the request when expenses editing is disabled.
 	// Used for delete hooks on expenses/purchase_orders and all CUD hooks on vendors.
 	expensesGateHook := func(e *core.RecordRequestEvent) error {
@@ -0,0 +1,185 @@
+package hooks
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"tybalt/errs"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// processMileageTripRequest validates a mileage trip change and applies it to
+// the trip's expense in the same transaction as the change itself.
+func processMileageTripRequest(app core.App, e *core.RecordRequestEvent, deleting bool) error {
+	originalApp := e.App
+	return e.App.RunInTransaction(func(txApp core.App) error {
+		e.App = txApp
+		defer func() { e.App = originalApp }()
+
+		if deleting {
+			if err := checkExpensesEditing(txApp); err != nil {
+				return AnnotateHookError(txApp, e, err)
+			}
+			if _, err := mileageTripExpense(txApp, e.Record); err != nil {
+				return AnnotateHookError(txApp, e, err)
+			}
+		} else if err := ProcessMileageTrip(txApp, e); err != nil {
+			return AnnotateHookError(txApp, e, err)
+		}
+		if err := SyncMileageTripExpense(txApp, e.Record, deleting); err != nil {
+			return AnnotateHookError(txApp, e, err)
+		}
+		return e.Next()
+	})
+}
+
+// ProcessMileageTrip checks a mileage trip before it is created or updated.
+// The trip must belong to an unsubmitted Mileage expense and cannot be dated
+// after the expense.
+func ProcessMileageTrip(app core.App, e *core.RecordRequestEvent) error {
+	if err := checkExpensesEditing(app); err != nil {
+		return err
+	}
+	record := e.Record
+	expense, err := mileageTripExpense(app, record)
+	if err != nil {
+		return err
+	}
+
+	for _, field := range []string{"origin", "destination", "purpose"} {
+		record.Set(field, strings.TrimSpace(record.GetString(field)))
+		if record.GetString(field) == "" {
+			return &errs.HookError{
+				Status:  http.StatusBadRequest,
+				Message: "hook error when validating mileage trip",
+				Data: map[string]errs.CodeError{
+					field: {Code: "required", Message: field + " is required"},
+				},
+			}
+		}
+	}
+
+	tripDate, err := time.Parse(time.DateOnly, record.GetString("date"))
+	if err != nil {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when validating mileage trip",
+			Data: map[string]errs.CodeError{
+				"date": {Code: "invalid_date", Message: "date must be in YYYY-MM-DD format"},
+			},
+		}
+	}
+	if expenseDate, err := time.Parse(time.DateOnly, expense.GetString("date")); err == nil && tripDate.After(expenseDate) {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when validating mileage trip",
+			Data: map[string]errs.CodeError{
+				"date": {Code: "after_expense_date", Message: "a trip cannot be dated after its expense"},
+			},
+		}
+	}
+	return nil
+}
+
+// mileageTripExpense returns the expense a trip is logged on, provided it is
+// an unsubmitted Mileage expense.
+func mileageTripExpense(app core.App, record *core.Record) (*core.Record, error) {
+	expense, err := app.FindRecordById("expenses", record.GetString("expense"))
+	if err != nil || expense == nil {
+		return nil, &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when validating mileage trip",
+			Data: map[string]errs.CodeError{
+				"expense": {Code: "not_found", Message: "referenced expense not found"},
+			},
+		}
+	}
+	if expense.GetString("payment_type") != "Mileage" {
+		return nil, &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when validating mileage trip",
+			Data: map[string]errs.CodeError{
+				"expense": {Code: "not_mileage", Message: "trips can only be logged on mileage expenses"},
+			},
+		}
+	}
+	if expense.GetBool("submitted") || expense.GetString("committed") != "" {
+		return nil, &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when validating mileage trip",
+			Data: map[string]errs.CodeError{
+				"expense": {Code: "is_submitted", Message: "cannot change the trips of a submitted expense"},
+			},
+		}
+	}
+	return expense, nil
+}
+
+// mileageTripsDistance returns the kilometres logged on an expense's trips,
+// leaving out the trip excludeTripID, and whether there are any.
+func mileageTripsDistance(app core.App, expenseID string, excludeTripID string) (float64, bool, error) {
+	result := struct {
+		Trips    int     `db:"trips"`
+		Distance float64 `db:"distance"`
+	}{}
+	if err := app.DB().NewQuery(`
+		SELECT COUNT(*) AS trips, COALESCE(SUM(distance), 0) AS distance
+		FROM mileage_trips
+		WHERE expense = {:expense} AND id != {:exclude}
+	`).Bind(dbx.Params{"expense": expenseID, "exclude": excludeTripID}).One(&result); err != nil {
+		return 0, false, err
+	}
+	return result.Distance, result.Trips > 0, nil
+}
+
+// SyncMileageTripExpense sets the distance of the trip's Mileage expense to
+// the sum of its trips once this change is made, and recalculates the total.
+// It runs before the trip is saved or deleted, in the same transaction, so a
+// failure leaves both unchanged. An expense whose last trip is deleted keeps
+// the distance it had.
+func SyncMileageTripExpense(app core.App, trip *core.Record, deleting bool) error {
+	expense, err := app.FindRecordById("expenses", trip.GetString("expense"))
+	if err != nil {
+		return err
+	}
+	distance, hasTrips, err := mileageTripsDistance(app, expense.Id, trip.Id)
+	if err != nil {
+		return err
+	}
+	if !deleting {
+		distance += trip.GetFloat("distance")
+		hasTrips = true
+	}
+	if !hasTrips || expense.GetString("payment_type") != "Mileage" {
+		return nil
+	}
+
+	expense.Set("distance", distance)
+	expenseRateRecord, err := utilities.GetExpenseRateRecord(app, expense)
+	if err != nil {
+		return &errs.HookError{
+			Status:  http.StatusInternalServerError,
+			Message: "hook error when updating mileage expense",
+			Data: map[string]errs.CodeError{
+				"global": {Code: "error_loading_expense_rate_record", Message: "error loading expense rate record"},
+			},
+		}
+	}
+	total, err := utilities.CalculateMileageTotal(app, expense, expenseRateRecord)
+	if err != nil {
+		return &errs.HookError{
+			Status:  http.StatusInternalServerError,
+			Message: "hook error when updating mileage expense",
+			Data: map[string]errs.CodeError{
+				"total": {Code: "error_calculating_mileage_total", Message: "error calculating mileage total"},
+			},
+		}
+	}
+	expense.Set("total", total)
+	expense.Set("settled_total", total)
+	return app.Save(expense)
+}
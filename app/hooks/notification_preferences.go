@@ -0,0 +1,45 @@
+package hooks
+
+import (
+	"net/http"
+
+	"tybalt/errs"
+	"tybalt/notifications"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ProcessNotificationPreference enforces business rules for notification
+// preference create/update. System notification templates cannot be muted.
+func ProcessNotificationPreference(app core.App, e *core.RecordRequestEvent) error {
+	if e.Record.GetString("mode") != notifications.PreferenceMute {
+		return nil
+	}
+
+	template, err := app.FindRecordById("notification_templates", e.Record.GetString("template"))
+	if err != nil {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when validating template",
+			Data: map[string]errs.CodeError{
+				"template": {
+					Code:    "invalid_template",
+					Message: "notification template not found",
+				},
+			},
+		}
+	}
+	if !template.GetBool("mutable") {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when validating mode",
+			Data: map[string]errs.CodeError{
+				"mode": {
+					Code:    "template_not_mutable",
+					Message: notifications.ErrTemplateNotMutable.Error(),
+				},
+			},
+		}
+	}
+	return nil
+}
@@ -1,6 +1,7 @@
 package hooks
 
 import (
+	"tybalt/notifications"
 	"tybalt/utilities"
 
 	validation "github.com/go-ozzo/ozzo-validation/v4"
@@ -68,5 +69,34 @@ func ProcessProfile(app core.App, e *core.RecordRequestEvent) error {
 		}
 	}
 
+	// A webhook transport needs somewhere to post to. The URL is only checked
+	// when set so switching back to email does not require clearing it.
+	webhookURL := e.Record.GetString("notification_webhook_url")
+	if webhookURL != "" {
+		if err := notifications.ValidateWebhookURL(webhookURL); err != nil {
+			return validation.Errors{
+				"notification_webhook_url": validation.NewError("invalid_webhook_url", err.Error()),
+			}
+		}
+	}
+	if e.Record.GetString("notification_transport") == notifications.TransportWebhook && webhookURL == "" {
+		return validation.Errors{
+			"notification_webhook_url": validation.NewError("webhook_url_required", "a webhook URL is required to receive notifications by webhook"),
+		}
+	}
+
 	return nil
 }
+
+// HideProfileWebhookURL removes notification_webhook_url from profiles returned
+// to anyone other than the owner. Profiles are viewable by every signed-in
+// user but a webhook URL lets its holder post to the owner's channel.
+func HideProfileWebhookURL(e *core.RecordEnrichEvent) error {
+	if e.RequestInfo != nil && e.RequestInfo.HasSuperuserAuth() {
+		return e.Next()
+	}
+	if e.RequestInfo == nil || e.RequestInfo.Auth == nil || e.RequestInfo.Auth.Id != e.Record.GetString("uid") {
+		e.Record.Hide("notification_webhook_url")
+	}
+	return e.Next()
+}
@@ -727,6 +727,7 @@ func ProcessPurchaseOrder(app core.App, e *core.RecordRequestEvent) (notificatio
 
 	requestUID := strings.TrimSpace(record.GetString("uid"))
 	if record.IsNew() {
+		record.Set("delegated_approver", "")
 		if requestUID != authRecord.Id {
 			return "", &errs.HookError{
 				Status:  http.StatusBadRequest,
@@ -780,6 +781,9 @@ func ProcessPurchaseOrder(app core.App, e *core.RecordRequestEvent) (notificatio
 			}
 		}
 
+		// delegated_approver is only set by the approve route.
+		record.Set("delegated_approver", original.GetString("delegated_approver"))
+
 		if original.GetString("status") != "Unapproved" {
 			return "", &errs.HookError{
 				Status:  http.StatusBadRequest,
@@ -829,6 +833,7 @@ func ProcessPurchaseOrder(app core.App, e *core.RecordRequestEvent) (notificatio
 		record.Set("approver", "")
 		record.Set("second_approval", "")
 		record.Set("second_approver", "")
+		record.Set("delegated_approver", "")
 		// Keep any submitted assignee as the next first-stage approver.
 		record.Set("approver", submittedApproverID)
 	}
@@ -139,8 +139,14 @@ func validateTimeAmendment(app core.App, timeAmendmentRecord *core.Record, requi
 // time_amendment record is invalid this function throws an error explaining
 // which field(s) are invalid and why.
 func ProcessTimeAmendment(app core.App, e *core.RecordRequestEvent) error {
-	record := e.Record
-	authRecord := e.Auth
+	return PrepareTimeAmendment(app, e.Record, e.Auth)
+}
+
+// PrepareTimeAmendment cleans and validates a time_amendment record created or
+// edited by authRecord, setting its creator, week_ending and tsid. It runs the
+// same checks for the collection API hooks and the time amendment import
+// route.
+func PrepareTimeAmendment(app core.App, record *core.Record, authRecord *core.Record) error {
 	if authRecord == nil || strings.TrimSpace(authRecord.Id) == "" {
 		return apis.NewApiError(http.StatusUnauthorized, "authentication is required", map[string]validation.Error{
 			"creator": validation.NewError(
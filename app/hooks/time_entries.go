@@ -67,6 +67,17 @@ func cleanTimeEntry(app core.App, timeEntryRecord *core.Record) ([]string, error
 	// below and must survive cleanup.
 	allowedFields = append(allowedFields, "id", "uid", "created", "updated", "role", "branch")
 
+	// work_record_subject_id is the first-class replacement for the legacy
+	// work_record text, so it is allowed wherever work_record is. When a worker
+	// row is linked, the legacy text is cleared so only one source of truth is
+	// stored.
+	if list.ExistInSlice("work_record", allowedFields) {
+		allowedFields = append(allowedFields, "work_record_subject_id")
+		if timeEntryRecord.GetString("work_record_subject_id") != "" {
+			timeEntryRecord.Set("work_record", "")
+		}
+	}
+
 	// remove any fields from the time_entry record that are not in allowedFields.
 	// I'm not sure if this is the best way to do this but let's try it.
 	// FieldsData() is probably a drop in replacement for ColumnValueMap()
@@ -185,7 +196,11 @@ func validateTimeEntry(app core.App, timeEntryRecord *core.Record, requiredField
 		}
 	}
 
-	return otherValidationsErrors.Filter()
+	if err := otherValidationsErrors.Filter(); err != nil {
+		return err
+	}
+
+	return validateTimeEntryWorkRecordSubject(app, timeEntryRecord)
 }
 
 // The ProcessTimeEntry function is used to validate the time_entry record
@@ -245,6 +260,10 @@ func ProcessTimeEntry(app core.App, e *core.RecordRequestEvent) error {
 	// perform the validation for the time_entry record. In this step we also
 	// write the uid property to the record so that we can validate it against the
 	if validationErr := validateTimeEntry(app, record, requiredFields); validationErr != nil {
+		var hookErr *errs.HookError
+		if errors.As(validationErr, &hookErr) {
+			return hookErr
+		}
 		return apis.NewBadRequestError("Validation error", validationErr)
 	}
 
@@ -0,0 +1,51 @@
+package hooks
+
+import (
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// timeEntryTemplateFields are the time_entries fields a template carries.
+var timeEntryTemplateFields = []string{"time_type", "job", "division", "category", "role", "hours", "description"}
+
+// NewTimeEntryFromTemplate returns an unsaved time_entries record for the
+// template's owner on the given date. The caller is expected to run it
+// through ProcessTimeEntry before saving.
+func NewTimeEntryFromTemplate(app core.App, template *core.Record, date string) (*core.Record, error) {
+	collection, err := app.FindCollectionByNameOrId("time_entries")
+	if err != nil {
+		return nil, err
+	}
+	entry := core.NewRecord(collection)
+	entry.Set("uid", template.GetString("uid"))
+	entry.Set("date", date)
+	for _, field := range timeEntryTemplateFields {
+		entry.Set(field, template.Get(field))
+	}
+	return entry, nil
+}
+
+// ProcessTimeEntryTemplate validates a time entry template before create or
+// update by building the time entry it would produce today and running it
+// through ProcessTimeEntry. Fields the time type does not allow are cleared on
+// the template as they would be on the entry.
+func ProcessTimeEntryTemplate(app core.App, e *core.RecordRequestEvent) error {
+	record := e.Record
+
+	probe, err := NewTimeEntryFromTemplate(app, record, time.Now().Format(time.DateOnly))
+	if err != nil {
+		return err
+	}
+	if err := ProcessTimeEntry(app, &core.RecordRequestEvent{
+		RequestEvent: e.RequestEvent,
+		Record:       probe,
+	}); err != nil {
+		return err
+	}
+
+	for _, field := range timeEntryTemplateFields {
+		record.Set(field, probe.Get(field))
+	}
+	return nil
+}
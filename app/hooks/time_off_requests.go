@@ -0,0 +1,83 @@
+package hooks
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"tybalt/errs"
+	"tybalt/timeoff"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ProcessTimeOffRequest validates a time off request before create or update
+// and assigns the owner's manager as the approver. Submitted requests are
+// locked; the owner recalls them to edit.
+func ProcessTimeOffRequest(app core.App, e *core.RecordRequestEvent) error {
+	record := e.Record
+
+	enabled, err := utilities.IsTimeEditingEnabled(app)
+	if err != nil {
+		return err
+	}
+	if !enabled {
+		return utilities.ErrTimeEditingDisabled
+	}
+
+	if !record.IsNew() {
+		if original := record.Original(); original != nil && original.GetBool("submitted") {
+			return &errs.HookError{
+				Status:  http.StatusBadRequest,
+				Message: "hook error when processing time off request",
+				Data: map[string]errs.CodeError{
+					"submitted": {
+						Code:    "is_submitted",
+						Message: "cannot edit a submitted time off request",
+					},
+				},
+			}
+		}
+	}
+
+	fieldErrors := map[string]errs.CodeError{}
+
+	timeType, err := app.FindRecordById("time_types", record.GetString("time_type"))
+	if err != nil {
+		fieldErrors["time_type"] = errs.CodeError{Code: "invalid_time_type", Message: "time type not found"}
+	} else if !timeoff.IsRequestCode(timeType.GetString("code")) {
+		fieldErrors["time_type"] = errs.CodeError{
+			Code:    "invalid_time_type",
+			Message: "time off requests are only used for " + strings.Join(timeoff.RequestCodes, " and "),
+		}
+	}
+
+	startDate := record.GetString("start_date")
+	endDate := record.GetString("end_date")
+	_, startErr := time.Parse(time.DateOnly, startDate)
+	if startErr != nil {
+		fieldErrors["start_date"] = errs.CodeError{Code: "invalid_date", Message: "start date must be in YYYY-MM-DD format"}
+	}
+	if _, err := time.Parse(time.DateOnly, endDate); err != nil {
+		fieldErrors["end_date"] = errs.CodeError{Code: "invalid_date", Message: "end date must be in YYYY-MM-DD format"}
+	} else if startErr == nil && endDate < startDate {
+		fieldErrors["end_date"] = errs.CodeError{Code: "end_before_start", Message: "end date must not be before start date"}
+	}
+
+	if err := utilities.IsPositiveMultipleOfPointFive()(record.GetFloat("hours")); err != nil || record.GetFloat("hours") <= 0 {
+		fieldErrors["hours"] = errs.CodeError{Code: "invalid_hours", Message: "hours must be a positive multiple of 0.5"}
+	}
+
+	if len(fieldErrors) > 0 {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "hook error when validating time off request",
+			Data:    fieldErrors,
+		}
+	}
+
+	// The owner's manager approves, with the same active and tapr checks as
+	// expenses.
+	return setManagerApprover(app, record)
+}
@@ -0,0 +1,508 @@
+// this file implements numbering, validation, approval locking and approval
+// synchronization for the first-class work records collections
+
+package hooks
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+	"time"
+	"tybalt/errs"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// workRecordsNow is the clock used for work record numbering. Tests may
+// replace it to pin the YYMM component.
+var workRecordsNow = time.Now
+
+func workRecordError(status int, field string, code string, message string) *errs.HookError {
+	return &errs.HookError{
+		Status:  status,
+		Message: message,
+		Data: map[string]errs.CodeError{
+			field: {Code: code, Message: message},
+		},
+	}
+}
+
+var errWorkRecordLocked = workRecordError(
+	http.StatusConflict,
+	"global",
+	"work_record_locked",
+	"this work record is locked because a worker row has been approved",
+)
+
+// GenerateWorkRecordNumber returns the next WYYMM-NNNN number for the current
+// month. Sequences restart each month and are independent of any
+// parent_work_record lineage.
+func GenerateWorkRecordNumber(app core.App) (string, error) {
+	now := workRecordsNow()
+	prefix := fmt.Sprintf("W%02d%02d-", now.Year()%100, int(now.Month()))
+
+	var last struct {
+		Number string `db:"number"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT number FROM work_records
+		WHERE number LIKE {:pattern}
+		ORDER BY number DESC
+		LIMIT 1
+	`).Bind(dbx.Params{"pattern": prefix + "%"}).One(&last)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return "", fmt.Errorf("error querying work record numbers: %v", err)
+	}
+
+	next := 1
+	if last.Number != "" {
+		var sequence int
+		if _, scanErr := fmt.Sscanf(strings.TrimPrefix(last.Number, prefix), "%d", &sequence); scanErr != nil {
+			return "", fmt.Errorf("unable to parse work record number %q: %v", last.Number, scanErr)
+		}
+		next = sequence + 1
+	}
+	if next > 9999 {
+		return "", fmt.Errorf("maximum number of work records reached (9999) for %s", strings.TrimSuffix(prefix, "-"))
+	}
+	return fmt.Sprintf("%s%04d", prefix, next), nil
+}
+
+// WorkRecordIsLocked reports whether any worker row under the work record is
+// approved. Shared parent fields, the worker roster and consumable entries are
+// all locked while this is true.
+func WorkRecordIsLocked(app core.App, workRecordID string) (bool, error) {
+	var result struct {
+		Count int `db:"count"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT COUNT(*) AS count FROM work_records_subjects
+		WHERE work_record = {:id} AND approved = 1
+	`).Bind(dbx.Params{"id": workRecordID}).One(&result)
+	if err != nil {
+		return false, err
+	}
+	return result.Count > 0, nil
+}
+
+func workRecordSubjectIsLinked(app core.App, subjectID string) (bool, error) {
+	var result struct {
+		Count int `db:"count"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT COUNT(*) AS count FROM time_entries WHERE work_record_subject_id = {:id}
+	`).Bind(dbx.Params{"id": subjectID}).One(&result)
+	if err != nil {
+		return false, err
+	}
+	return result.Count > 0, nil
+}
+
+// ValidateWorkRecord is called from the work_records create and update model
+// hooks. It assigns the backend number on first save, keeps system-managed
+// fields immutable and enforces the job, type and lane_kms rules. Model hooks
+// are used rather than request hooks because the collection API is closed and
+// every write arrives through a backend route or an internal save.
+func ValidateWorkRecord(app core.App, record *core.Record) error {
+	isNew := record.IsNew()
+	original := record.Original()
+
+	if !isNew {
+		locked, err := WorkRecordIsLocked(app, record.Id)
+		if err != nil {
+			return err
+		}
+		if locked && utilities.RecordHasMeaningfulChanges(record) {
+			return errWorkRecordLocked
+		}
+		if original.GetString("number") != "" && record.GetString("number") != original.GetString("number") {
+			return workRecordError(http.StatusBadRequest, "number", "immutable", "work record number cannot be changed")
+		}
+		if record.GetString("creator") != original.GetString("creator") {
+			return workRecordError(http.StatusBadRequest, "creator", "immutable", "work record creator cannot be changed")
+		}
+		if record.GetString("parent_work_record") != original.GetString("parent_work_record") {
+			return workRecordError(http.StatusBadRequest, "parent_work_record", "immutable", "parent_work_record is system managed")
+		}
+	}
+
+	if !isNew && (record.GetString("job") != original.GetString("job") || record.GetString("date") != original.GetString("date")) {
+		if err := ValidateWorkRecordDelete(app, record); err != nil {
+			return workRecordError(http.StatusConflict, "global", "linked_time_entries",
+				"job and date cannot change while time entries are linked to this work record")
+		}
+	}
+
+	if err := utilities.IsValidDate(record.GetString("date")); err != nil {
+		return workRecordError(http.StatusBadRequest, "date", "invalid_date", "date must be a valid YYYY-MM-DD date")
+	}
+
+	jobChanged := isNew || record.GetString("job") != original.GetString("job")
+	if jobChanged {
+		job, err := app.FindRecordById("jobs", record.GetString("job"))
+		if err != nil {
+			return workRecordError(http.StatusBadRequest, "job", "not_found", "job not found")
+		}
+		if err := validateJobAllowsTimeTracking(app, job); err != nil {
+			return workRecordError(http.StatusBadRequest, "job", "invalid_job", err.Error())
+		}
+	}
+
+	typeChanged := isNew || record.GetString("type") != original.GetString("type")
+	workRecordType, err := app.FindRecordById("work_records_types", record.GetString("type"))
+	if err != nil {
+		return workRecordError(http.StatusBadRequest, "type", "not_found", "work record type not found")
+	}
+	if typeChanged && !workRecordType.GetBool("active") {
+		return workRecordError(http.StatusBadRequest, "type", "inactive", "work record type is not active")
+	}
+
+	// Disabling allow_lane_kms on a type does not invalidate historical
+	// values; only saves that add or change lane_kms, or that move the record
+	// to a type that disallows it, are rejected.
+	laneKmsChanged := isNew || record.GetFloat("lane_kms") != original.GetFloat("lane_kms")
+	if (laneKmsChanged || typeChanged) && record.GetFloat("lane_kms") != 0 && !workRecordType.GetBool("allow_lane_kms") {
+		message := "lane_kms is not allowed for this work record type"
+		if typeChanged && !isNew {
+			message = "the selected work record type does not allow lane_kms; clear lane_kms first"
+		}
+		return workRecordError(http.StatusBadRequest, "lane_kms", "not_allowed", message)
+	}
+
+	if isNew && record.GetString("number") == "" {
+		number, err := GenerateWorkRecordNumber(app)
+		if err != nil {
+			return err
+		}
+		record.Set("number", number)
+	}
+	return nil
+}
+
+// ValidateWorkRecordDelete allows a parent to be deleted only when none of its
+// worker rows is referenced by a time entry.
+func ValidateWorkRecordDelete(app core.App, record *core.Record) error {
+	var result struct {
+		Count int `db:"count"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT COUNT(*) AS count
+		FROM time_entries te
+		JOIN work_records_subjects s ON s.id = te.work_record_subject_id
+		WHERE s.work_record = {:id}
+	`).Bind(dbx.Params{"id": record.Id}).One(&result)
+	if err != nil {
+		return err
+	}
+	if result.Count > 0 {
+		return workRecordError(http.StatusConflict, "global", "linked_time_entries", "work record has linked time entries and cannot be deleted")
+	}
+	return nil
+}
+
+// ValidateWorkRecordRoster checks the saved worker roster of a work record as
+// a whole. It runs at the end of a roster save, after omitted rows have been
+// pruned, so the count limit applies to the final roster and a type change
+// that lowers max_subjects is validated against it. A worker may also appear
+// on only one work record for the same job and date.
+func ValidateWorkRecordRoster(app core.App, workRecordID string) error {
+	parent, err := app.FindRecordById("work_records", workRecordID)
+	if err != nil {
+		return workRecordError(http.StatusBadRequest, "work_record", "not_found", "work record not found")
+	}
+	workRecordType, err := app.FindRecordById("work_records_types", parent.GetString("type"))
+	if err != nil {
+		return workRecordError(http.StatusBadRequest, "type", "not_found", "work record type not found")
+	}
+
+	var count struct {
+		Count int `db:"count"`
+	}
+	if err := app.DB().NewQuery(`
+		SELECT COUNT(*) AS count FROM work_records_subjects WHERE work_record = {:id}
+	`).Bind(dbx.Params{"id": workRecordID}).One(&count); err != nil {
+		return err
+	}
+	if count.Count < 1 {
+		return workRecordError(http.StatusBadRequest, "subjects", "required", "a work record needs at least one worker")
+	}
+	if count.Count > workRecordType.GetInt("max_subjects") {
+		return workRecordError(http.StatusBadRequest, "subjects", "too_many_subjects",
+			fmt.Sprintf("this work record type allows at most %d workers", workRecordType.GetInt("max_subjects")))
+	}
+
+	var conflict struct {
+		UID    string `db:"uid"`
+		Number string `db:"number"`
+	}
+	err = app.DB().NewQuery(`
+		SELECT s.uid, wr.number
+		FROM work_records_subjects s
+		JOIN work_records wr ON wr.id = s.work_record
+		WHERE wr.id != {:id}
+			AND wr.job = {:job}
+			AND wr.date = {:date}
+			AND s.uid IN (SELECT uid FROM work_records_subjects WHERE work_record = {:id})
+		LIMIT 1
+	`).Bind(dbx.Params{
+		"id":   workRecordID,
+		"job":  parent.GetString("job"),
+		"date": parent.GetString("date"),
+	}).One(&conflict)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if conflict.UID != "" {
+		return workRecordError(http.StatusBadRequest, "subjects", "duplicate_worker_for_day",
+			fmt.Sprintf("a worker is already on work record %s for this job and date", conflict.Number))
+	}
+	return nil
+}
+
+// ValidateWorkRecordSubject is called from the work_records_subjects create
+// and update model hooks. Roster changes are blocked while the parent is
+// locked, an approved worker row cannot be edited, and vehicle fields must be
+// consistent with vehicle_type. The approved flag itself is only changed by
+// SyncWorkRecordSubjectApproval.
+func ValidateWorkRecordSubject(app core.App, record *core.Record) error {
+	workRecordID := record.GetString("work_record")
+
+	if record.IsNew() {
+		locked, err := WorkRecordIsLocked(app, workRecordID)
+		if err != nil {
+			return err
+		}
+		if locked {
+			return errWorkRecordLocked
+		}
+		parent, err := app.FindRecordById("work_records", workRecordID)
+		if err != nil {
+			return workRecordError(http.StatusBadRequest, "work_record", "not_found", "work record not found")
+		}
+		workRecordType, err := app.FindRecordById("work_records_types", parent.GetString("type"))
+		if err != nil {
+			return workRecordError(http.StatusBadRequest, "type", "not_found", "work record type not found")
+		}
+		var result struct {
+			Count int `db:"count"`
+		}
+		if err := app.DB().NewQuery(`
+			SELECT COUNT(*) AS count FROM work_records_subjects WHERE work_record = {:id}
+		`).Bind(dbx.Params{"id": workRecordID}).One(&result); err != nil {
+			return err
+		}
+		if result.Count+1 > workRecordType.GetInt("max_subjects") {
+			return workRecordError(http.StatusBadRequest, "subjects", "too_many_subjects",
+				fmt.Sprintf("this work record type allows at most %d workers", workRecordType.GetInt("max_subjects")))
+		}
+		record.Set("approved", false)
+	} else {
+		original := record.Original()
+		if record.GetString("uid") != original.GetString("uid") || record.GetString("work_record") != original.GetString("work_record") {
+			return workRecordError(http.StatusBadRequest, "uid", "immutable", "worker rows cannot be reassigned; remove the row and add a new one instead")
+		}
+		if original.GetBool("approved") && record.GetBool("approved") && utilities.RecordHasMeaningfulChanges(record) {
+			return workRecordError(http.StatusConflict, "global", "work_record_subject_locked", "this worker row is approved and cannot be edited")
+		}
+	}
+
+	for _, field := range []string{"hours_on_site", "hours_travel_time"} {
+		if value := record.GetFloat(field); value < 0 || value > 24 {
+			return workRecordError(http.StatusBadRequest, field, "out_of_range", "hours must be between 0 and 24")
+		}
+	}
+	if record.GetFloat("hours_on_site")+record.GetFloat("hours_travel_time") <= 0 {
+		return workRecordError(http.StatusBadRequest, "hours_on_site", "required", "a worker must record on-site or travel hours")
+	}
+
+	switch record.GetString("vehicle_type") {
+	case "company":
+		if strings.TrimSpace(record.GetString("company_vehicle_unit_number")) == "" {
+			return workRecordError(http.StatusBadRequest, "company_vehicle_unit_number", "required", "company vehicle unit number is required for company vehicles")
+		}
+	default:
+		if strings.TrimSpace(record.GetString("company_vehicle_unit_number")) != "" {
+			return workRecordError(http.StatusBadRequest, "company_vehicle_unit_number", "not_allowed", "company vehicle unit number is only allowed for company vehicles")
+		}
+	}
+	return nil
+}
+
+// ValidateWorkRecordSubjectDelete blocks roster removal while the parent is
+// locked and when a time entry still references the worker row.
+func ValidateWorkRecordSubjectDelete(app core.App, record *core.Record) error {
+	locked, err := WorkRecordIsLocked(app, record.GetString("work_record"))
+	if err != nil {
+		return err
+	}
+	if locked {
+		return errWorkRecordLocked
+	}
+	linked, err := workRecordSubjectIsLinked(app, record.Id)
+	if err != nil {
+		return err
+	}
+	if linked {
+		return workRecordError(http.StatusConflict, "global", "linked_time_entry", "this worker row is linked to a time entry and cannot be removed")
+	}
+	return nil
+}
+
+// ValidateWorkRecordConsumableEntry enforces the approval lock on consumable
+// entries and, for creates and updates, the type allowlist and the
+// "exactly one meaningful value" rule driven by the consumable's input_kind.
+func ValidateWorkRecordConsumableEntry(app core.App, record *core.Record, deleting bool) error {
+	workRecordID := record.GetString("work_record")
+	locked, err := WorkRecordIsLocked(app, workRecordID)
+	if err != nil {
+		return err
+	}
+	if locked {
+		return errWorkRecordLocked
+	}
+	if deleting {
+		return nil
+	}
+
+	parent, err := app.FindRecordById("work_records", workRecordID)
+	if err != nil {
+		return workRecordError(http.StatusBadRequest, "work_record", "not_found", "work record not found")
+	}
+	consumable, err := app.FindRecordById("work_records_consumables", record.GetString("consumable"))
+	if err != nil {
+		return workRecordError(http.StatusBadRequest, "consumable", "not_found", "consumable not found")
+	}
+	allowed, err := app.FindFirstRecordByFilter("work_records_type_consumables", "type = {:type} && consumable = {:consumable} && active = true", dbx.Params{
+		"type":       parent.GetString("type"),
+		"consumable": consumable.Id,
+	})
+	if err != nil || allowed == nil {
+		return workRecordError(http.StatusBadRequest, "consumable", "not_allowed",
+			fmt.Sprintf("%s is not available for this work record type; record it in the notes instead", consumable.GetString("name")))
+	}
+
+	switch consumable.GetString("input_kind") {
+	case "number":
+		if !(record.GetFloat("quantity_number") > 0) {
+			return workRecordError(http.StatusBadRequest, "quantity_number", "required", "quantity must be greater than 0")
+		}
+	case "boolean":
+		if record.GetFloat("quantity_number") != 0 {
+			return workRecordError(http.StatusBadRequest, "quantity_number", "not_allowed", "quantity must be empty for yes/no consumables")
+		}
+	}
+	return nil
+}
+
+// validateTimeEntryWorkRecordSubject checks a time entry's link to a work
+// record worker row. The linked row must belong to the same person, its
+// parent must match the entry's job and date, and the entry's hours must
+// equal the worker row's on-site plus travel hours.
+func validateTimeEntryWorkRecordSubject(app core.App, timeEntryRecord *core.Record) error {
+	subjectID := strings.TrimSpace(timeEntryRecord.GetString("work_record_subject_id"))
+	if subjectID == "" {
+		if utilities.IsLinkedWorkRecordsOnly(app) && strings.TrimSpace(timeEntryRecord.GetString("work_record")) != "" {
+			return workRecordError(http.StatusBadRequest, "work_record", "link_required",
+				"work record text is no longer accepted; link this time entry to a work record worker row instead")
+		}
+		return nil
+	}
+
+	subject, err := app.FindRecordById("work_records_subjects", subjectID)
+	if err != nil {
+		return workRecordError(http.StatusBadRequest, "work_record_subject_id", "not_found", "work record worker row not found")
+	}
+	if subject.GetString("uid") != timeEntryRecord.GetString("uid") {
+		return workRecordError(http.StatusBadRequest, "work_record_subject_id", "uid_mismatch", "you can only link time entries to your own work record worker row")
+	}
+	parent, err := app.FindRecordById("work_records", subject.GetString("work_record"))
+	if err != nil {
+		return workRecordError(http.StatusBadRequest, "work_record_subject_id", "not_found", "work record not found")
+	}
+	if parent.GetString("job") != timeEntryRecord.GetString("job") {
+		return workRecordError(http.StatusBadRequest, "job", "work_record_mismatch", "job must match the linked work record")
+	}
+	if parent.GetString("date") != timeEntryRecord.GetString("date") {
+		return workRecordError(http.StatusBadRequest, "date", "work_record_mismatch", "date must match the linked work record")
+	}
+	expectedHours := subject.GetFloat("hours_on_site") + subject.GetFloat("hours_travel_time")
+	if math.Abs(timeEntryRecord.GetFloat("hours")-expectedHours) > 0.0001 {
+		return workRecordError(http.StatusBadRequest, "hours", "work_record_hours_mismatch",
+			fmt.Sprintf("hours must equal the work record's %.2f on-site and travel hours; edit the work record worker row to match or create a separate time entry that is not linked to the work record", expectedHours))
+	}
+
+	existing, err := app.FindFirstRecordByFilter("time_entries", "work_record_subject_id = {:subject} && id != {:id}", dbx.Params{
+		"subject": subjectID,
+		"id":      timeEntryRecord.Id,
+	})
+	if err == nil && existing != nil {
+		return workRecordError(http.StatusBadRequest, "work_record_subject_id", "already_linked", "this work record worker row is already linked to another time entry")
+	}
+	return nil
+}
+
+// SyncWorkRecordSubjectApproval recomputes the approved flag of one worker row
+// from the time sheet of its linked time entry. A row is approved only when
+// the entry is bundled into a time sheet that is approved and not rejected;
+// unlinking, unbundling, recalling and rejecting all clear it again. The row is
+// saved only when the flag actually changes.
+func SyncWorkRecordSubjectApproval(app core.App, subjectID string) error {
+	if subjectID == "" {
+		return nil
+	}
+	subject, err := app.FindRecordById("work_records_subjects", subjectID)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		return err
+	}
+
+	var result struct {
+		Count int `db:"count"`
+	}
+	err = app.DB().NewQuery(`
+		SELECT COUNT(*) AS count
+		FROM time_entries te
+		JOIN time_sheets ts ON ts.id = te.tsid
+		WHERE te.work_record_subject_id = {:id}
+			AND COALESCE(ts.approved, '') != ''
+			AND COALESCE(ts.rejected, '') = ''
+	`).Bind(dbx.Params{"id": subjectID}).One(&result)
+	if err != nil {
+		return err
+	}
+
+	approved := result.Count > 0
+	if subject.GetBool("approved") == approved {
+		return nil
+	}
+	subject.Set("approved", approved)
+	return app.Save(subject)
+}
+
+// SyncWorkRecordApprovalsForTimeSheet fans a time sheet state change out to
+// every worker row referenced by the sheet's time entries.
+func SyncWorkRecordApprovalsForTimeSheet(app core.App, timeSheetID string) error {
+	var rows []struct {
+		SubjectID string `db:"work_record_subject_id"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT work_record_subject_id FROM time_entries
+		WHERE tsid = {:tsid} AND COALESCE(work_record_subject_id, '') != ''
+	`).Bind(dbx.Params{"tsid": timeSheetID}).All(&rows)
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := SyncWorkRecordSubjectApproval(app, row.SubjectID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
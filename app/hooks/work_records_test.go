@@ -0,0 +1,213 @@
+package hooks
+
+import (
+	"errors"
+	"testing"
+	"time"
+	"tybalt/errs"
+	"tybalt/internal/testseed"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// Fixtures: work record wrfixture000001 (W2604-0001) on job cjf0kt0defhq480
+// dated 2026-04-13 has two unlinked worker rows: wrsubjauthor001 for
+// f2j5a8vk006baub (4 + 1 hours) and wrsubjtime00001 for rzr98oadsp9qc11
+// (6 + 0 hours). Time sheet aeyl94og4xmnpq4 is an unapproved author sheet.
+const (
+	wrFixtureID       = "wrfixture000001"
+	wrAuthorSubjectID = "wrsubjauthor001"
+	wrTimeSubjectID   = "wrsubjtime00001"
+	wrTimeSheetID     = "aeyl94og4xmnpq4"
+)
+
+func newWorkRecordsHookTestApp(t *testing.T) *tests.TestApp {
+	t.Helper()
+	app := testseed.NewSeededTestApp(t)
+	AddHooks(app)
+	return app
+}
+
+func hookErrorCode(t *testing.T, err error, field string) string {
+	t.Helper()
+	var hookErr *errs.HookError
+	if !errors.As(err, &hookErr) {
+		t.Fatalf("expected HookError, got %v", err)
+	}
+	return hookErr.Data[field].Code
+}
+
+func linkedWorkRecordTimeEntry(t *testing.T, app core.App, uid string, subjectID string, hours float64) *core.Record {
+	t.Helper()
+	collection, err := app.FindCollectionByNameOrId("time_entries")
+	if err != nil {
+		t.Fatalf("failed to load time_entries collection: %v", err)
+	}
+	record := core.NewRecord(collection)
+	record.Set("uid", uid)
+	record.Set("job", "cjf0kt0defhq480")
+	record.Set("date", "2026-04-13")
+	record.Set("week_ending", "2026-04-18")
+	record.Set("time_type", "sdyfl3q7j7ap849")
+	record.Set("hours", hours)
+	record.Set("work_record_subject_id", subjectID)
+	return record
+}
+
+func TestGenerateWorkRecordNumberContinuesMonthlySequence(t *testing.T) {
+	app := newWorkRecordsHookTestApp(t)
+	originalNow := workRecordsNow
+	t.Cleanup(func() { workRecordsNow = originalNow })
+
+	workRecordsNow = func() time.Time { return time.Date(2026, time.April, 20, 0, 0, 0, 0, time.UTC) }
+	number, err := GenerateWorkRecordNumber(app)
+	if err != nil || number != "W2604-0002" {
+		t.Fatalf("April number = %q, %v; want W2604-0002", number, err)
+	}
+
+	workRecordsNow = func() time.Time { return time.Date(2026, time.May, 1, 0, 0, 0, 0, time.UTC) }
+	number, err = GenerateWorkRecordNumber(app)
+	if err != nil || number != "W2605-0001" {
+		t.Fatalf("May number = %q, %v; want W2605-0001", number, err)
+	}
+}
+
+func TestValidateTimeEntryWorkRecordSubject(t *testing.T) {
+	app := newWorkRecordsHookTestApp(t)
+
+	tests := []struct {
+		name   string
+		mutate func(record *core.Record)
+		field  string
+		code   string
+	}{
+		{name: "matching link", mutate: func(record *core.Record) {}},
+		{name: "hours differ from worker row", mutate: func(record *core.Record) { record.Set("hours", 5) }, field: "hours", code: "work_record_hours_mismatch"},
+		{name: "different worker", mutate: func(record *core.Record) { record.Set("uid", "f2j5a8vk006baub") }, field: "work_record_subject_id", code: "uid_mismatch"},
+		{name: "different date", mutate: func(record *core.Record) { record.Set("date", "2026-04-14") }, field: "date", code: "work_record_mismatch"},
+		{name: "unknown worker row", mutate: func(record *core.Record) { record.Set("work_record_subject_id", "missingsubject1") }, field: "work_record_subject_id", code: "not_found"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			record := linkedWorkRecordTimeEntry(t, app, "rzr98oadsp9qc11", wrTimeSubjectID, 6)
+			tt.mutate(record)
+			err := validateTimeEntryWorkRecordSubject(app, record)
+			if tt.code == "" {
+				if err != nil {
+					t.Fatalf("expected valid link, got %v", err)
+				}
+				return
+			}
+			if got := hookErrorCode(t, err, tt.field); got != tt.code {
+				t.Fatalf("%s code = %q, want %q", tt.field, got, tt.code)
+			}
+		})
+	}
+}
+
+func TestWorkRecordSubjectApprovalFollowsTimeSheet(t *testing.T) {
+	app := newWorkRecordsHookTestApp(t)
+
+	entry := linkedWorkRecordTimeEntry(t, app, "f2j5a8vk006baub", wrAuthorSubjectID, 5)
+	entry.Set("tsid", wrTimeSheetID)
+	if err := app.Save(entry); err != nil {
+		t.Fatalf("failed to save linked time entry: %v", err)
+	}
+
+	assertApproved := func(want bool, step string) {
+		t.Helper()
+		subject, err := app.FindRecordById("work_records_subjects", wrAuthorSubjectID)
+		if err != nil {
+			t.Fatalf("failed to load worker row: %v", err)
+		}
+		if subject.GetBool("approved") != want {
+			t.Fatalf("%s: approved = %v, want %v", step, subject.GetBool("approved"), want)
+		}
+	}
+	assertApproved(false, "bundled into unapproved sheet")
+
+	sheet, err := app.FindRecordById("time_sheets", wrTimeSheetID)
+	if err != nil {
+		t.Fatalf("failed to load time sheet: %v", err)
+	}
+	sheet.Set("approved", time.Now())
+	if err := app.Save(sheet); err != nil {
+		t.Fatalf("failed to approve time sheet: %v", err)
+	}
+	assertApproved(true, "time sheet approved")
+
+	// Shared parent fields and the roster lock while any worker row is
+	// approved; notes remain append-only and allowed.
+	parent, err := app.FindRecordById("work_records", wrFixtureID)
+	if err != nil {
+		t.Fatalf("failed to load work record: %v", err)
+	}
+	parent.Set("location", "Somewhere else")
+	if got := hookErrorCode(t, app.Save(parent), "global"); got != "work_record_locked" {
+		t.Fatalf("parent update code = %q, want work_record_locked", got)
+	}
+	otherSubject, err := app.FindRecordById("work_records_subjects", wrTimeSubjectID)
+	if err != nil {
+		t.Fatalf("failed to load other worker row: %v", err)
+	}
+	if got := hookErrorCode(t, app.Delete(otherSubject), "global"); got != "work_record_locked" {
+		t.Fatalf("roster delete code = %q, want work_record_locked", got)
+	}
+	notes, err := app.FindCollectionByNameOrId("work_records_notes")
+	if err != nil {
+		t.Fatalf("failed to load notes collection: %v", err)
+	}
+	note := core.NewRecord(notes)
+	note.Set("work_record", wrFixtureID)
+	note.Set("uid", "rzr98oadsp9qc11")
+	note.Set("note", "Added after approval")
+	if err := app.Save(note); err != nil {
+		t.Fatalf("expected note to save after approval: %v", err)
+	}
+
+	sheet.Set("rejected", time.Now())
+	if err := app.Save(sheet); err != nil {
+		t.Fatalf("failed to reject time sheet: %v", err)
+	}
+	assertApproved(false, "time sheet rejected")
+
+	sheet.Set("rejected", "")
+	if err := app.Save(sheet); err != nil {
+		t.Fatalf("failed to clear rejection: %v", err)
+	}
+	assertApproved(true, "rejection cleared")
+
+	entry.Set("tsid", "")
+	if err := app.Save(entry); err != nil {
+		t.Fatalf("failed to unbundle time entry: %v", err)
+	}
+	assertApproved(false, "time entry unbundled")
+}
+
+func TestWorkRecordSubjectDeleteBlockedWhenLinked(t *testing.T) {
+	app := newWorkRecordsHookTestApp(t)
+
+	entry := linkedWorkRecordTimeEntry(t, app, "rzr98oadsp9qc11", wrTimeSubjectID, 6)
+	if err := app.Save(entry); err != nil {
+		t.Fatalf("failed to save linked time entry: %v", err)
+	}
+
+	subject, err := app.FindRecordById("work_records_subjects", wrTimeSubjectID)
+	if err != nil {
+		t.Fatalf("failed to load worker row: %v", err)
+	}
+	if got := hookErrorCode(t, app.Delete(subject), "global"); got != "linked_time_entry" {
+		t.Fatalf("delete code = %q, want linked_time_entry", got)
+	}
+
+	parent, err := app.FindRecordById("work_records", wrFixtureID)
+	if err != nil {
+		t.Fatalf("failed to load work record: %v", err)
+	}
+	parent.Set("date", "2026-04-14")
+	if got := hookErrorCode(t, app.Save(parent), "global"); got != "linked_time_entries" {
+		t.Fatalf("date change code = %q, want linked_time_entries", got)
+	}
+}
@@ -53,28 +53,45 @@ const (
 )
 
 var testFullOnlyTables = map[string]struct{}{
-	"users":                {},
-	"absorb_actions":       {},
-	"admin_profiles":       {},
-	"categories":           {},
-	"client_contacts":      {},
-	"client_notes":         {},
-	"clients":              {},
-	"currencies":           {},
-	"expenses":             {},
-	"job_time_allocations": {},
-	"jobs":                 {},
-	"machine_secrets":      {},
-	"notifications":        {},
-	"po_approver_props":    {},
-	"profiles":             {},
-	"purchase_orders":      {},
-	"time_amendments":      {},
-	"time_entries":         {},
-	"time_sheet_reviewers": {},
-	"time_sheets":          {},
-	"user_claims":          {},
-	"vendors":              {},
+	"users":                           {},
+	"absorb_actions":                  {},
+	"admin_profiles":                  {},
+	"approval_delegations":            {},
+	"card_statement_lines":            {},
+	"card_statements":                 {},
+	"categories":                      {},
+	"client_agreements":               {},
+	"client_contacts":                 {},
+	"client_notes":                    {},
+	"clients":                         {},
+	"currencies":                      {},
+	"expenses":                        {},
+	"job_time_allocations":            {},
+	"jobs":                            {},
+	"machine_secrets":                 {},
+	"mileage_trips":                   {},
+	"notification_preferences":        {},
+	"notifications":                   {},
+	"po_approver_props":               {},
+	"po_invoicing_records":            {},
+	"profiles":                        {},
+	"purchase_orders":                 {},
+	"report_subscriptions":            {},
+	"time_amendments":                 {},
+	"time_entries":                    {},
+	"time_entry_templates":            {},
+	"time_off_requests":               {},
+	"time_sheet_reviewers":            {},
+	"time_sheets":                     {},
+	"user_claims":                     {},
+	"vendors":                         {},
+	"work_records":                    {},
+	"work_records_consumable_entries": {},
+	"work_records_consumables":        {},
+	"work_records_notes":              {},
+	"work_records_subjects":           {},
+	"work_records_type_consumables":   {},
+	"work_records_types":              {},
 }
 
 var validProfiles = map[string]struct{}{
@@ -788,3 +788,57 @@ func TestJobsAPI_InactiveDivisionFails(t *testing.T) {
 		scenario.Test(t)
 	}
 }
+
+func TestJobTimeReportPDF(t *testing.T) {
+	recordToken, err := testutils.GenerateRecordToken("users", "time@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "job full time report renders as a PDF",
+			Method: http.MethodGet,
+			URL:    "/api/jobs/cjf0kt0defhq480/time/full_report.pdf",
+			Headers: map[string]string{
+				"Authorization": recordToken,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				"%PDF-1.4",
+				"(Job 24-321 Full Time Report) Tj",
+				"(Clarke St Boiler Replacement) Tj",
+				"(Total) Tj",
+			},
+			TestAppFactory: testutils.SetupTestApp,
+			AfterTestFunc: func(t testing.TB, _ *tests.TestApp, res *http.Response) {
+				if got := res.Header.Get("Content-Type"); got != "application/pdf" {
+					t.Fatalf("expected application/pdf content type, got %q", got)
+				}
+			},
+		},
+		{
+			Name:            "job full time report PDF requires authentication",
+			Method:          http.MethodGet,
+			URL:             "/api/jobs/cjf0kt0defhq480/time/full_report.pdf",
+			ExpectedStatus:  http.StatusUnauthorized,
+			ExpectedContent: []string{`"data":{}`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:   "job full time report PDF for unknown job is not found",
+			Method: http.MethodGet,
+			URL:    "/api/jobs/missingjob00000/time/full_report.pdf",
+			Headers: map[string]string{
+				"Authorization": recordToken,
+			},
+			ExpectedStatus:  http.StatusNotFound,
+			ExpectedContent: []string{`"message":"Job not found."`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
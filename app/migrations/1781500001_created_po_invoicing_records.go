@@ -0,0 +1,230 @@
+package migrations
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const (
+	invoicingClaimID          = "invoicingclm001"
+	invoicingClaimName        = "invoicing"
+	invoicingClaimDescription = "Can record client invoicing against Active or Closed project purchase orders"
+)
+
+// po_invoicing_records is append-only and has no collection API rules. All
+// reads and writes go through the /api/jobs/{id}/pos/invoicing routes so the
+// eligibility and claim checks live in one place.
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": null,
+			"deleteRule": null,
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "m19q72syy0e3lvm",
+					"hidden": false,
+					"id": "relation1781500001a",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "purchase_order",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "yovqzrnnomp0lkx",
+					"hidden": false,
+					"id": "relation1781500001b",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "job",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "1v6i9rrpniuatcx",
+					"hidden": false,
+					"id": "relation1781500001c",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "client",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1781500001a",
+					"max": 0,
+					"min": 0,
+					"name": "invoiced_on",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": false,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "number1781500001",
+					"max": null,
+					"min": 0.01,
+					"name": "amount",
+					"onlyInt": false,
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1781500001b",
+					"max": 100,
+					"min": 0,
+					"name": "invoice_number",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1781500001c",
+					"max": 1000,
+					"min": 0,
+					"name": "note",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1781500001d",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "uid",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1781500001",
+			"indexes": [
+				"CREATE INDEX ` + "`" + `idx_po_invoicing_records_job_po` + "`" + ` ON ` + "`" + `po_invoicing_records` + "`" + ` (` + "`" + `job` + "`" + `, ` + "`" + `purchase_order` + "`" + `, ` + "`" + `invoiced_on` + "`" + ` DESC, ` + "`" + `created` + "`" + ` DESC)",
+				"CREATE INDEX ` + "`" + `idx_po_invoicing_records_po` + "`" + ` ON ` + "`" + `po_invoicing_records` + "`" + ` (` + "`" + `purchase_order` + "`" + `, ` + "`" + `created` + "`" + ` DESC)"
+			],
+			"listRule": null,
+			"name": "po_invoicing_records",
+			"system": false,
+			"type": "base",
+			"updateRule": null,
+			"viewRule": null
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		return ensureInvoicingClaim(app)
+	}, func(app core.App) error {
+		record, err := app.FindRecordById("claims", invoicingClaimID)
+		if err == nil {
+			if err := app.Delete(record); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		collection, err := app.FindCollectionByNameOrId("pbc_1781500001")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
+
+func ensureInvoicingClaim(app core.App) error {
+	existing, err := app.FindFirstRecordByFilter("claims", "name={:name}", dbx.Params{"name": invoicingClaimName})
+	if err == nil && existing != nil {
+		existing.Set("description", invoicingClaimDescription)
+		return app.Save(existing)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	collection, err := app.FindCollectionByNameOrId("claims")
+	if err != nil {
+		return err
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("id", invoicingClaimID)
+	record.Set("name", invoicingClaimName)
+	record.Set("description", invoicingClaimDescription)
+	return app.Save(record)
+}
@@ -0,0 +1,189 @@
+package migrations
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const (
+	clientAgreementsClaimID          = "clagreeclaim001"
+	clientAgreementsClaimName        = "client_agreements"
+	clientAgreementsClaimDescription = "Can view, upload and remove client agreement documents on any job"
+)
+
+// client_agreements has no collection API rules and a protected file field.
+// Agreement visibility is narrower than job visibility, so listing, upload,
+// download and removal all go through /api/jobs/{id}/client_agreements.
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": null,
+			"deleteRule": null,
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "file1781600001",
+					"maxSelect": 1,
+					"maxSize": 20971520,
+					"mimeTypes": ["application/pdf"],
+					"name": "client_agreement",
+					"presentable": false,
+					"protected": true,
+					"required": true,
+					"system": false,
+					"thumbs": null,
+					"type": "file"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1781600001",
+					"max": 64,
+					"min": 64,
+					"name": "client_agreement_hash",
+					"pattern": "^[a-f0-9]{64}$",
+					"presentable": false,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "yovqzrnnomp0lkx",
+					"hidden": false,
+					"id": "relation1781600001a",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "job",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1781600001b",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "uploader",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "date1781600001",
+					"max": "",
+					"min": "",
+					"name": "uploaded",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1781600001",
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_client_agreements_hash` + "`" + ` ON ` + "`" + `client_agreements` + "`" + ` (` + "`" + `client_agreement_hash` + "`" + `)",
+				"CREATE INDEX ` + "`" + `idx_client_agreements_job` + "`" + ` ON ` + "`" + `client_agreements` + "`" + ` (` + "`" + `job` + "`" + `, ` + "`" + `uploaded` + "`" + ` DESC)"
+			],
+			"listRule": null,
+			"name": "client_agreements",
+			"system": false,
+			"type": "base",
+			"updateRule": null,
+			"viewRule": null
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		return ensureClientAgreementsClaim(app)
+	}, func(app core.App) error {
+		record, err := app.FindRecordById("claims", clientAgreementsClaimID)
+		if err == nil {
+			if err := app.Delete(record); err != nil {
+				return err
+			}
+		} else if !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		collection, err := app.FindCollectionByNameOrId("pbc_1781600001")
+		if err != nil {
+			return err
+		}
+
+		return app.Delete(collection)
+	})
+}
+
+func ensureClientAgreementsClaim(app core.App) error {
+	existing, err := app.FindFirstRecordByFilter("claims", "name={:name}", dbx.Params{"name": clientAgreementsClaimName})
+	if err == nil && existing != nil {
+		existing.Set("description", clientAgreementsClaimDescription)
+		return app.Save(existing)
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	collection, err := app.FindCollectionByNameOrId("claims")
+	if err != nil {
+		return err
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("id", clientAgreementsClaimID)
+	record.Set("name", clientAgreementsClaimName)
+	record.Set("description", clientAgreementsClaimDescription)
+	return app.Save(record)
+}
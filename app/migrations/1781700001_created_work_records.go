@@ -0,0 +1,317 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// Work record visibility: the creator, any worker on the record and holders of
+// the report claim. The child collections reach the same set through their
+// work_record relation. All writes go through /api/first_class_work_records so
+// roster, approval-lock and numbering rules are enforced in one place.
+const (
+	workRecordsVisibilityRule = "@request.auth.id != \"\" && (\n" +
+		"creator = @request.auth.id ||\n" +
+		"work_records_subjects_via_work_record.uid ?= @request.auth.id ||\n" +
+		"@request.auth.user_claims_via_uid.cid.name ?= 'report'\n)"
+	workRecordsChildVisibilityRule = "@request.auth.id != \"\" && (\n" +
+		"work_record.creator = @request.auth.id ||\n" +
+		"work_record.work_records_subjects_via_work_record.uid ?= @request.auth.id ||\n" +
+		"@request.auth.user_claims_via_uid.cid.name ?= 'report'\n)"
+	workRecordsNotesVisibilityRule = "@request.auth.id != \"\" && (\n" +
+		"uid = @request.auth.id ||\n" +
+		"work_record.creator = @request.auth.id ||\n" +
+		"work_record.work_records_subjects_via_work_record.uid ?= @request.auth.id ||\n" +
+		"@request.auth.user_claims_via_uid.cid.name ?= 'report'\n)"
+	workRecordsLookupRule = "@request.auth.id != \"\""
+)
+
+// workRecordsCollectionRules holds the list/view rule for each collection
+// created below. work_records itself is assigned after work_records_subjects
+// exists because its rule uses the back-relation.
+var workRecordsCollectionRules = map[string]string{
+	"work_records_types":              workRecordsLookupRule,
+	"work_records_consumables":        workRecordsLookupRule,
+	"work_records_type_consumables":   workRecordsLookupRule,
+	"work_records_subjects":           workRecordsChildVisibilityRule,
+	"work_records_notes":              workRecordsNotesVisibilityRule,
+	"work_records_consumable_entries": workRecordsChildVisibilityRule,
+}
+
+var workRecordsCollectionsJSON = []string{
+	`{
+		"createRule": null,
+		"deleteRule": null,
+		"fields": [
+			{"autogeneratePattern": "[a-z0-9]{15}", "hidden": false, "id": "text3208210256", "max": 15, "min": 15, "name": "id", "pattern": "^[a-z0-9]+$", "presentable": false, "primaryKey": true, "required": true, "system": true, "type": "text"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700001a", "max": 100, "min": 1, "name": "name", "pattern": "", "presentable": true, "primaryKey": false, "required": true, "system": false, "type": "text"},
+			{"hidden": false, "id": "number1781700001a", "max": null, "min": 1, "name": "max_subjects", "onlyInt": true, "presentable": false, "required": true, "system": false, "type": "number"},
+			{"hidden": false, "id": "bool1781700001a", "name": "allow_lane_kms", "presentable": false, "required": false, "system": false, "type": "bool"},
+			{"hidden": false, "id": "bool1781700001b", "name": "active", "presentable": false, "required": false, "system": false, "type": "bool"},
+			{"hidden": false, "id": "number1781700001b", "max": null, "min": null, "name": "sort_order", "onlyInt": true, "presentable": false, "required": false, "system": false, "type": "number"},
+			{"hidden": false, "id": "autodate2990389176", "name": "created", "onCreate": true, "onUpdate": false, "presentable": false, "system": false, "type": "autodate"},
+			{"hidden": false, "id": "autodate3332085495", "name": "updated", "onCreate": true, "onUpdate": true, "presentable": false, "system": false, "type": "autodate"}
+		],
+		"id": "pbc_1781700001",
+		"indexes": [
+			"CREATE UNIQUE INDEX ` + "`" + `idx_work_records_types_name` + "`" + ` ON ` + "`" + `work_records_types` + "`" + ` (` + "`" + `name` + "`" + `)"
+		],
+		"listRule": null,
+		"name": "work_records_types",
+		"system": false,
+		"type": "base",
+		"updateRule": null,
+		"viewRule": null
+	}`,
+	`{
+		"createRule": null,
+		"deleteRule": null,
+		"fields": [
+			{"autogeneratePattern": "[a-z0-9]{15}", "hidden": false, "id": "text3208210256", "max": 15, "min": 15, "name": "id", "pattern": "^[a-z0-9]+$", "presentable": false, "primaryKey": true, "required": true, "system": true, "type": "text"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700002a", "max": 100, "min": 1, "name": "name", "pattern": "", "presentable": true, "primaryKey": false, "required": true, "system": false, "type": "text"},
+			{"hidden": false, "id": "select1781700002", "maxSelect": 1, "name": "input_kind", "presentable": false, "required": true, "system": false, "type": "select", "values": ["number", "boolean"]},
+			{"hidden": false, "id": "bool1781700002", "name": "active", "presentable": false, "required": false, "system": false, "type": "bool"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700002b", "max": 20, "min": 0, "name": "unit_label", "pattern": "", "presentable": false, "primaryKey": false, "required": false, "system": false, "type": "text"},
+			{"hidden": false, "id": "autodate2990389176", "name": "created", "onCreate": true, "onUpdate": false, "presentable": false, "system": false, "type": "autodate"},
+			{"hidden": false, "id": "autodate3332085495", "name": "updated", "onCreate": true, "onUpdate": true, "presentable": false, "system": false, "type": "autodate"}
+		],
+		"id": "pbc_1781700002",
+		"indexes": [
+			"CREATE UNIQUE INDEX ` + "`" + `idx_work_records_consumables_name` + "`" + ` ON ` + "`" + `work_records_consumables` + "`" + ` (` + "`" + `name` + "`" + `)"
+		],
+		"listRule": null,
+		"name": "work_records_consumables",
+		"system": false,
+		"type": "base",
+		"updateRule": null,
+		"viewRule": null
+	}`,
+	`{
+		"createRule": null,
+		"deleteRule": null,
+		"fields": [
+			{"autogeneratePattern": "[a-z0-9]{15}", "hidden": false, "id": "text3208210256", "max": 15, "min": 15, "name": "id", "pattern": "^[a-z0-9]+$", "presentable": false, "primaryKey": true, "required": true, "system": true, "type": "text"},
+			{"cascadeDelete": false, "collectionId": "pbc_1781700001", "hidden": false, "id": "relation1781700003a", "maxSelect": 1, "minSelect": 0, "name": "type", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"cascadeDelete": false, "collectionId": "pbc_1781700002", "hidden": false, "id": "relation1781700003b", "maxSelect": 1, "minSelect": 0, "name": "consumable", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"hidden": false, "id": "number1781700003", "max": null, "min": null, "name": "sort_order", "onlyInt": true, "presentable": false, "required": false, "system": false, "type": "number"},
+			{"hidden": false, "id": "bool1781700003", "name": "active", "presentable": false, "required": false, "system": false, "type": "bool"},
+			{"hidden": false, "id": "autodate2990389176", "name": "created", "onCreate": true, "onUpdate": false, "presentable": false, "system": false, "type": "autodate"},
+			{"hidden": false, "id": "autodate3332085495", "name": "updated", "onCreate": true, "onUpdate": true, "presentable": false, "system": false, "type": "autodate"}
+		],
+		"id": "pbc_1781700003",
+		"indexes": [
+			"CREATE UNIQUE INDEX ` + "`" + `idx_work_records_type_consumables_pair` + "`" + ` ON ` + "`" + `work_records_type_consumables` + "`" + ` (` + "`" + `type` + "`" + `, ` + "`" + `consumable` + "`" + `)"
+		],
+		"listRule": null,
+		"name": "work_records_type_consumables",
+		"system": false,
+		"type": "base",
+		"updateRule": null,
+		"viewRule": null
+	}`,
+	`{
+		"createRule": null,
+		"deleteRule": null,
+		"fields": [
+			{"autogeneratePattern": "[a-z0-9]{15}", "hidden": false, "id": "text3208210256", "max": 15, "min": 15, "name": "id", "pattern": "^[a-z0-9]+$", "presentable": false, "primaryKey": true, "required": true, "system": true, "type": "text"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004a", "max": 0, "min": 0, "name": "number", "pattern": "^W[0-9]{4}-[0-9]{4}$", "presentable": true, "primaryKey": false, "required": false, "system": false, "type": "text"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004b", "max": 0, "min": 0, "name": "date", "pattern": "^\\d{4}-\\d{2}-\\d{2}$", "presentable": false, "primaryKey": false, "required": true, "system": false, "type": "text"},
+			{"cascadeDelete": false, "collectionId": "_pb_users_auth_", "hidden": false, "id": "relation1781700004a", "maxSelect": 1, "minSelect": 0, "name": "creator", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"cascadeDelete": false, "collectionId": "yovqzrnnomp0lkx", "hidden": false, "id": "relation1781700004b", "maxSelect": 1, "minSelect": 0, "name": "job", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"cascadeDelete": false, "collectionId": "pbc_1781700001", "hidden": false, "id": "relation1781700004c", "maxSelect": 1, "minSelect": 0, "name": "type", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004c", "max": 200, "min": 0, "name": "location", "pattern": "", "presentable": false, "primaryKey": false, "required": true, "system": false, "type": "text"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004d", "max": 2000, "min": 0, "name": "work_description", "pattern": "", "presentable": false, "primaryKey": false, "required": true, "system": false, "type": "text"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004e", "max": 200, "min": 0, "name": "report_to", "pattern": "", "presentable": false, "primaryKey": false, "required": false, "system": false, "type": "text"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004f", "max": 0, "min": 0, "name": "field_book_number", "pattern": "^[0-9]{2}-(00[1-9]|0[1-9][0-9]|[1-9][0-9]{2})$", "presentable": false, "primaryKey": false, "required": false, "system": false, "type": "text"},
+			{"hidden": false, "id": "number1781700004a", "max": null, "min": 1, "name": "field_book_page_number", "onlyInt": true, "presentable": false, "required": false, "system": false, "type": "number"},
+			{"hidden": false, "id": "number1781700004b", "max": null, "min": 0, "name": "lane_kms", "onlyInt": false, "presentable": false, "required": false, "system": false, "type": "number"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004g", "max": 200, "min": 0, "name": "sub_contractor", "pattern": "", "presentable": false, "primaryKey": false, "required": false, "system": false, "type": "text"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004h", "max": 500, "min": 0, "name": "equipment", "pattern": "", "presentable": false, "primaryKey": false, "required": false, "system": false, "type": "text"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004i", "max": 500, "min": 0, "name": "supplies", "pattern": "", "presentable": false, "primaryKey": false, "required": false, "system": false, "type": "text"},
+			{"hidden": false, "id": "file1781700004", "maxSelect": 1, "maxSize": 20971520, "mimeTypes": ["application/pdf"], "name": "attachment", "presentable": false, "protected": false, "required": false, "system": false, "thumbs": null, "type": "file"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700004j", "max": 64, "min": 0, "name": "attachment_hash", "pattern": "^[a-f0-9]{64}$", "presentable": false, "primaryKey": false, "required": false, "system": false, "type": "text"},
+			{"hidden": false, "id": "autodate2990389176", "name": "created", "onCreate": true, "onUpdate": false, "presentable": false, "system": false, "type": "autodate"},
+			{"hidden": false, "id": "autodate3332085495", "name": "updated", "onCreate": true, "onUpdate": true, "presentable": false, "system": false, "type": "autodate"}
+		],
+		"id": "pbc_1781700004",
+		"indexes": [
+			"CREATE UNIQUE INDEX ` + "`" + `idx_work_records_number` + "`" + ` ON ` + "`" + `work_records` + "`" + ` (` + "`" + `number` + "`" + `) WHERE ` + "`" + `number` + "`" + ` != ''",
+			"CREATE INDEX ` + "`" + `idx_work_records_job_date` + "`" + ` ON ` + "`" + `work_records` + "`" + ` (` + "`" + `job` + "`" + `, ` + "`" + `date` + "`" + `)",
+			"CREATE UNIQUE INDEX ` + "`" + `idx_work_records_attachment_hash` + "`" + ` ON ` + "`" + `work_records` + "`" + ` (` + "`" + `attachment_hash` + "`" + `) WHERE ` + "`" + `attachment_hash` + "`" + ` != ''"
+		],
+		"listRule": null,
+		"name": "work_records",
+		"system": false,
+		"type": "base",
+		"updateRule": null,
+		"viewRule": null
+	}`,
+	`{
+		"createRule": null,
+		"deleteRule": null,
+		"fields": [
+			{"autogeneratePattern": "[a-z0-9]{15}", "hidden": false, "id": "text3208210256", "max": 15, "min": 15, "name": "id", "pattern": "^[a-z0-9]+$", "presentable": false, "primaryKey": true, "required": true, "system": true, "type": "text"},
+			{"cascadeDelete": true, "collectionId": "pbc_1781700004", "hidden": false, "id": "relation1781700005a", "maxSelect": 1, "minSelect": 0, "name": "work_record", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"cascadeDelete": false, "collectionId": "_pb_users_auth_", "hidden": false, "id": "relation1781700005b", "maxSelect": 1, "minSelect": 0, "name": "uid", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"hidden": false, "id": "number1781700005a", "max": 24, "min": 0, "name": "hours_on_site", "onlyInt": false, "presentable": false, "required": false, "system": false, "type": "number"},
+			{"hidden": false, "id": "number1781700005b", "max": 24, "min": 0, "name": "hours_travel_time", "onlyInt": false, "presentable": false, "required": false, "system": false, "type": "number"},
+			{"hidden": false, "id": "number1781700005c", "max": null, "min": 0, "name": "distance_travelled_km", "onlyInt": false, "presentable": false, "required": false, "system": false, "type": "number"},
+			{"hidden": false, "id": "select1781700005", "maxSelect": 1, "name": "vehicle_type", "presentable": false, "required": false, "system": false, "type": "select", "values": ["company", "personal"]},
+			{"hidden": false, "id": "bool1781700005a", "name": "is_passenger", "presentable": false, "required": false, "system": false, "type": "bool"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700005", "max": 0, "min": 0, "name": "company_vehicle_unit_number", "pattern": "^0*[1-9][0-9]{0,2}$", "presentable": false, "primaryKey": false, "required": false, "system": false, "type": "text"},
+			{"hidden": false, "id": "bool1781700005b", "name": "approved", "presentable": false, "required": false, "system": false, "type": "bool"},
+			{"hidden": false, "id": "autodate2990389176", "name": "created", "onCreate": true, "onUpdate": false, "presentable": false, "system": false, "type": "autodate"},
+			{"hidden": false, "id": "autodate3332085495", "name": "updated", "onCreate": true, "onUpdate": true, "presentable": false, "system": false, "type": "autodate"}
+		],
+		"id": "pbc_1781700005",
+		"indexes": [
+			"CREATE UNIQUE INDEX ` + "`" + `idx_work_records_subjects_record_uid` + "`" + ` ON ` + "`" + `work_records_subjects` + "`" + ` (` + "`" + `work_record` + "`" + `, ` + "`" + `uid` + "`" + `)",
+			"CREATE INDEX ` + "`" + `idx_work_records_subjects_uid_created` + "`" + ` ON ` + "`" + `work_records_subjects` + "`" + ` (` + "`" + `uid` + "`" + `, ` + "`" + `created` + "`" + `)"
+		],
+		"listRule": null,
+		"name": "work_records_subjects",
+		"system": false,
+		"type": "base",
+		"updateRule": null,
+		"viewRule": null
+	}`,
+	`{
+		"createRule": null,
+		"deleteRule": null,
+		"fields": [
+			{"autogeneratePattern": "[a-z0-9]{15}", "hidden": false, "id": "text3208210256", "max": 15, "min": 15, "name": "id", "pattern": "^[a-z0-9]+$", "presentable": false, "primaryKey": true, "required": true, "system": true, "type": "text"},
+			{"cascadeDelete": true, "collectionId": "pbc_1781700004", "hidden": false, "id": "relation1781700006a", "maxSelect": 1, "minSelect": 0, "name": "work_record", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"cascadeDelete": false, "collectionId": "_pb_users_auth_", "hidden": false, "id": "relation1781700006b", "maxSelect": 1, "minSelect": 0, "name": "uid", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"autogeneratePattern": "", "hidden": false, "id": "text1781700006", "max": 2000, "min": 1, "name": "note", "pattern": "", "presentable": false, "primaryKey": false, "required": true, "system": false, "type": "text"},
+			{"hidden": false, "id": "autodate2990389176", "name": "created", "onCreate": true, "onUpdate": false, "presentable": false, "system": false, "type": "autodate"}
+		],
+		"id": "pbc_1781700006",
+		"indexes": [
+			"CREATE INDEX ` + "`" + `idx_work_records_notes_record_created` + "`" + ` ON ` + "`" + `work_records_notes` + "`" + ` (` + "`" + `work_record` + "`" + `, ` + "`" + `created` + "`" + `)"
+		],
+		"listRule": null,
+		"name": "work_records_notes",
+		"system": false,
+		"type": "base",
+		"updateRule": null,
+		"viewRule": null
+	}`,
+	`{
+		"createRule": null,
+		"deleteRule": null,
+		"fields": [
+			{"autogeneratePattern": "[a-z0-9]{15}", "hidden": false, "id": "text3208210256", "max": 15, "min": 15, "name": "id", "pattern": "^[a-z0-9]+$", "presentable": false, "primaryKey": true, "required": true, "system": true, "type": "text"},
+			{"cascadeDelete": true, "collectionId": "pbc_1781700004", "hidden": false, "id": "relation1781700007a", "maxSelect": 1, "minSelect": 0, "name": "work_record", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"cascadeDelete": false, "collectionId": "pbc_1781700002", "hidden": false, "id": "relation1781700007b", "maxSelect": 1, "minSelect": 0, "name": "consumable", "presentable": false, "required": true, "system": false, "type": "relation"},
+			{"hidden": false, "id": "number1781700007", "max": null, "min": null, "name": "quantity_number", "onlyInt": false, "presentable": false, "required": false, "system": false, "type": "number"},
+			{"hidden": false, "id": "autodate2990389176", "name": "created", "onCreate": true, "onUpdate": false, "presentable": false, "system": false, "type": "autodate"},
+			{"hidden": false, "id": "autodate3332085495", "name": "updated", "onCreate": true, "onUpdate": true, "presentable": false, "system": false, "type": "autodate"}
+		],
+		"id": "pbc_1781700007",
+		"indexes": [
+			"CREATE UNIQUE INDEX ` + "`" + `idx_work_records_consumable_entries_pair` + "`" + ` ON ` + "`" + `work_records_consumable_entries` + "`" + ` (` + "`" + `work_record` + "`" + `, ` + "`" + `consumable` + "`" + `)"
+		],
+		"listRule": null,
+		"name": "work_records_consumable_entries",
+		"system": false,
+		"type": "base",
+		"updateRule": null,
+		"viewRule": null
+	}`,
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		for _, jsonData := range workRecordsCollectionsJSON {
+			collection := &core.Collection{}
+			if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+				return err
+			}
+			if rule, ok := workRecordsCollectionRules[collection.Name]; ok {
+				collection.ListRule = pointerString(rule)
+				collection.ViewRule = pointerString(rule)
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		// The self-relation and the back-relation visibility rule can only be
+		// added once work_records and work_records_subjects both exist.
+		workRecords, err := app.FindCollectionByNameOrId("pbc_1781700004")
+		if err != nil {
+			return err
+		}
+		if err := workRecords.Fields.AddMarshaledJSON([]byte(`{
+			"cascadeDelete": false,
+			"collectionId": "pbc_1781700004",
+			"hidden": false,
+			"id": "relation1781700004d",
+			"maxSelect": 1,
+			"minSelect": 0,
+			"name": "parent_work_record",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "relation"
+		}`)); err != nil {
+			return err
+		}
+		workRecords.ListRule = pointerString(workRecordsVisibilityRule)
+		workRecords.ViewRule = pointerString(workRecordsVisibilityRule)
+		if err := app.Save(workRecords); err != nil {
+			return err
+		}
+
+		timeEntries, err := app.FindCollectionByNameOrId("time_entries")
+		if err != nil {
+			return err
+		}
+		if err := timeEntries.Fields.AddMarshaledJSON([]byte(`{
+			"cascadeDelete": false,
+			"collectionId": "pbc_1781700005",
+			"hidden": false,
+			"id": "relation1781700008",
+			"maxSelect": 1,
+			"minSelect": 0,
+			"name": "work_record_subject_id",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "relation"
+		}`)); err != nil {
+			return err
+		}
+		timeEntries.AddIndex("idx_time_entries_work_record_subject_id", true, "`work_record_subject_id`", "`work_record_subject_id` != ''")
+		return app.Save(timeEntries)
+	}, func(app core.App) error {
+		timeEntries, err := app.FindCollectionByNameOrId("time_entries")
+		if err != nil {
+			return err
+		}
+		timeEntries.RemoveIndex("idx_time_entries_work_record_subject_id")
+		timeEntries.Fields.RemoveById("relation1781700008")
+		if err := app.Save(timeEntries); err != nil {
+			return err
+		}
+
+		for i := len(workRecordsCollectionsJSON) - 1; i >= 0; i-- {
+			var header struct {
+				ID string `json:"id"`
+			}
+			if err := json.Unmarshal([]byte(workRecordsCollectionsJSON[i]), &header); err != nil {
+				return err
+			}
+			collection, err := app.FindCollectionByNameOrId(header.ID)
+			if err != nil {
+				return err
+			}
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,235 @@
+package migrations
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const (
+	scheduledReportTemplateID          = "schedreporttpl1"
+	scheduledReportTemplateCode        = "scheduled_report"
+	scheduledReportTemplateDescription = "Sent to report_subscriptions owners with the rendered report attached."
+	scheduledReportTemplateSubject     = "Your scheduled report is attached"
+	scheduledReportTemplateText        = "Hello {{.RecipientName}},\n\nYour scheduled {{.ReportName}} report for {{.Period}} is attached.{{if not .HasFiles}} There was nothing to report for this period.{{end}}\n\nYou can also download reports here:\n\n{{.ActionURL}}"
+)
+
+// report_subscriptions lets report claim holders receive a report by email
+// each period. Owners manage their own rows; last_period, last_delivered and
+// last_error are written only by the delivery cron job. The claim is checked
+// again at delivery time so removing it stops delivery.
+func init() {
+	m.Register(func(app core.App) error {
+		notifications, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return err
+		}
+		if err := notifications.Fields.AddMarshaledJSON([]byte(`{
+			"hidden": false,
+			"id": "file1781800001",
+			"maxSelect": 10,
+			"maxSize": 52428800,
+			"mimeTypes": [],
+			"name": "attachments",
+			"presentable": false,
+			"protected": true,
+			"required": false,
+			"system": false,
+			"thumbs": null,
+			"type": "file"
+		}`)); err != nil {
+			return err
+		}
+		if err := app.Save(notifications); err != nil {
+			return err
+		}
+
+		jsonData := `{
+			"createRule": "@request.auth.id != '' && uid = @request.auth.id && @request.auth.user_claims_via_uid.cid.name ?= 'report' && @request.body.last_period:isset = false && @request.body.last_delivered:isset = false && @request.body.last_error:isset = false",
+			"deleteRule": "uid = @request.auth.id",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1781800001",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "uid",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "select1781800001",
+					"maxSelect": 1,
+					"name": "report",
+					"presentable": true,
+					"required": true,
+					"system": false,
+					"type": "select",
+					"values": ["payroll_time", "weekly_time", "payroll_expense", "payroll_receipts", "payables_spreadsheet"]
+				},
+				{
+					"hidden": false,
+					"id": "bool1781800001",
+					"name": "active",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "bool"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1781800001",
+					"max": 0,
+					"min": 0,
+					"name": "last_period",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "date1781800001",
+					"max": "",
+					"min": "",
+					"name": "last_delivered",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1781800002",
+					"max": 0,
+					"min": 0,
+					"name": "last_error",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1781800001",
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_report_subscriptions_uid_report` + "`" + ` ON ` + "`" + `report_subscriptions` + "`" + ` (` + "`" + `uid` + "`" + `, ` + "`" + `report` + "`" + `)"
+			],
+			"listRule": "uid = @request.auth.id",
+			"name": "report_subscriptions",
+			"system": false,
+			"type": "base",
+			"updateRule": "uid = @request.auth.id && (@request.body.uid:isset = false || @request.body.uid = @request.auth.id) && @request.body.last_period:isset = false && @request.body.last_delivered:isset = false && @request.body.last_error:isset = false",
+			"viewRule": "uid = @request.auth.id"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+
+		return ensureScheduledReportTemplate(app)
+	}, func(app core.App) error {
+		template, err := app.FindRecordById("notification_templates", scheduledReportTemplateID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if template != nil {
+			if _, err := app.DB().NewQuery("DELETE FROM notifications WHERE template = {:template}").Bind(dbx.Params{"template": template.Id}).Execute(); err != nil {
+				return err
+			}
+			if err := app.Delete(template); err != nil {
+				return err
+			}
+		}
+
+		collection, err := app.FindCollectionByNameOrId("report_subscriptions")
+		if err != nil {
+			return err
+		}
+		if err := app.Delete(collection); err != nil {
+			return err
+		}
+
+		notifications, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return err
+		}
+		notifications.Fields.RemoveById("file1781800001")
+		return app.Save(notifications)
+	})
+}
+
+func ensureScheduledReportTemplate(app core.App) error {
+	existing, err := app.FindFirstRecordByFilter("notification_templates", "code={:code}", dbx.Params{"code": scheduledReportTemplateCode})
+	if err == nil && existing != nil {
+		return nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	collection, err := app.FindCollectionByNameOrId("notification_templates")
+	if err != nil {
+		return err
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("id", scheduledReportTemplateID)
+	record.Set("code", scheduledReportTemplateCode)
+	record.Set("description", scheduledReportTemplateDescription)
+	record.Set("subject", scheduledReportTemplateSubject)
+	record.Set("text_email", scheduledReportTemplateText)
+	return app.Save(record)
+}
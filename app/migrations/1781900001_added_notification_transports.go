@@ -0,0 +1,91 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// Notification transports: profiles choose how they receive notifications
+// (email by default, or a webhook URL), templates may restrict which
+// transports can carry them, and each notification records the transport it
+// was sent with.
+var notificationTransportFields = []struct {
+	collection string
+	id         string
+	json       string
+}{
+	{"profiles", "select1781900001", `{
+		"hidden": false,
+		"id": "select1781900001",
+		"maxSelect": 1,
+		"name": "notification_transport",
+		"presentable": false,
+		"required": false,
+		"system": false,
+		"type": "select",
+		"values": ["email", "webhook"]
+	}`},
+	{"profiles", "url1781900001", `{
+		"exceptDomains": null,
+		"hidden": false,
+		"id": "url1781900001",
+		"name": "notification_webhook_url",
+		"onlyDomains": null,
+		"presentable": false,
+		"required": false,
+		"system": false,
+		"type": "url"
+	}`},
+	{"notification_templates", "select1781900002", `{
+		"hidden": false,
+		"id": "select1781900002",
+		"maxSelect": 2,
+		"name": "transports",
+		"presentable": false,
+		"required": false,
+		"system": false,
+		"type": "select",
+		"values": ["email", "webhook"]
+	}`},
+	{"notifications", "select1781900003", `{
+		"hidden": false,
+		"id": "select1781900003",
+		"maxSelect": 1,
+		"name": "transport",
+		"presentable": false,
+		"required": false,
+		"system": false,
+		"type": "select",
+		"values": ["email", "webhook"]
+	}`},
+}
+
+func init() {
+	m.Register(func(app core.App) error {
+		for _, field := range notificationTransportFields {
+			collection, err := app.FindCollectionByNameOrId(field.collection)
+			if err != nil {
+				return err
+			}
+			if err := collection.Fields.AddMarshaledJSON([]byte(field.json)); err != nil {
+				return err
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		for _, field := range notificationTransportFields {
+			collection, err := app.FindCollectionByNameOrId(field.collection)
+			if err != nil {
+				return err
+			}
+			collection.Fields.RemoveById(field.id)
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
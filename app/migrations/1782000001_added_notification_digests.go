@@ -0,0 +1,155 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const (
+	notificationDigestTemplateID          = "notifdigesttpl1"
+	notificationDigestTemplateCode        = "notification_digest"
+	notificationDigestTemplateDescription = "Daily summary of the reminders held for recipients who opted into the digest."
+	notificationDigestTemplateSubject     = "Your daily Tybalt summary"
+	notificationDigestTemplateText        = "Hello {{.RecipientName}},\n\nHere is your daily summary of {{.Count}} notification(s).\n{{range .Items}}\n----\n{{.Subject}}\n\n{{.Text}}\n{{end}}"
+)
+
+// Daily digest: profiles opt in with notification_digest. System reminders
+// dispatched in deferred mode to opted-in recipients are created with the
+// digest status and rolled into one notification_digest notification per
+// recipient by a cron job; the rolled-up notifications point at it through
+// digest.
+func init() {
+	m.Register(func(app core.App) error {
+		profiles, err := app.FindCollectionByNameOrId("profiles")
+		if err != nil {
+			return err
+		}
+		if err := profiles.Fields.AddMarshaledJSON([]byte(`{
+			"hidden": false,
+			"id": "bool1782000001",
+			"name": "notification_digest",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "bool"
+		}`)); err != nil {
+			return err
+		}
+		if err := app.Save(profiles); err != nil {
+			return err
+		}
+
+		notifications, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return err
+		}
+		if err := notifications.Fields.AddMarshaledJSON([]byte(`{
+			"hidden": false,
+			"id": "select2063623452",
+			"maxSelect": 1,
+			"name": "status",
+			"presentable": false,
+			"required": true,
+			"system": false,
+			"type": "select",
+			"values": ["pending", "inflight", "sent", "error", "digest"]
+		}`)); err != nil {
+			return err
+		}
+		if err := notifications.Fields.AddMarshaledJSON([]byte(`{
+			"cascadeDelete": false,
+			"collectionId": "` + notifications.Id + `",
+			"hidden": false,
+			"id": "relation1782000001",
+			"maxSelect": 1,
+			"minSelect": 0,
+			"name": "digest",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "relation"
+		}`)); err != nil {
+			return err
+		}
+		if err := app.Save(notifications); err != nil {
+			return err
+		}
+
+		return ensureNotificationDigestTemplate(app)
+	}, func(app core.App) error {
+		template, err := app.FindRecordById("notification_templates", notificationDigestTemplateID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if template != nil {
+			if _, err := app.DB().NewQuery("UPDATE notifications SET digest = '' WHERE digest IN (SELECT id FROM notifications WHERE template = {:template})").Bind(dbx.Params{"template": template.Id}).Execute(); err != nil {
+				return err
+			}
+			if _, err := app.DB().NewQuery("DELETE FROM notifications WHERE template = {:template}").Bind(dbx.Params{"template": template.Id}).Execute(); err != nil {
+				return err
+			}
+			if err := app.Delete(template); err != nil {
+				return err
+			}
+		}
+		if _, err := app.DB().NewQuery("UPDATE notifications SET status = 'pending' WHERE status = 'digest'").Execute(); err != nil {
+			return err
+		}
+
+		notifications, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return err
+		}
+		notifications.Fields.RemoveById("relation1782000001")
+		if err := notifications.Fields.AddMarshaledJSON([]byte(`{
+			"hidden": false,
+			"id": "select2063623452",
+			"maxSelect": 1,
+			"name": "status",
+			"presentable": false,
+			"required": true,
+			"system": false,
+			"type": "select",
+			"values": ["pending", "inflight", "sent", "error"]
+		}`)); err != nil {
+			return err
+		}
+		if err := app.Save(notifications); err != nil {
+			return err
+		}
+
+		profiles, err := app.FindCollectionByNameOrId("profiles")
+		if err != nil {
+			return err
+		}
+		profiles.Fields.RemoveById("bool1782000001")
+		return app.Save(profiles)
+	})
+}
+
+func ensureNotificationDigestTemplate(app core.App) error {
+	existing, err := app.FindFirstRecordByFilter("notification_templates", "code={:code}", dbx.Params{"code": notificationDigestTemplateCode})
+	if err == nil && existing != nil {
+		return nil
+	}
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+
+	collection, err := app.FindCollectionByNameOrId("notification_templates")
+	if err != nil {
+		return err
+	}
+
+	record := core.NewRecord(collection)
+	record.Set("id", notificationDigestTemplateID)
+	record.Set("code", notificationDigestTemplateCode)
+	record.Set("description", notificationDigestTemplateDescription)
+	record.Set("subject", notificationDigestTemplateSubject)
+	record.Set("text_email", notificationDigestTemplateText)
+	return app.Save(record)
+}
@@ -0,0 +1,79 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// Notification retries: attempts counts send attempts and next_attempt is when
+// a failed (error) notification is due to be retried. After the last attempt
+// the notification moves to the terminal dead status.
+func init() {
+	m.Register(func(app core.App) error {
+		notifications, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return err
+		}
+		for _, field := range []string{`{
+			"hidden": false,
+			"id": "select2063623452",
+			"maxSelect": 1,
+			"name": "status",
+			"presentable": false,
+			"required": true,
+			"system": false,
+			"type": "select",
+			"values": ["pending", "inflight", "sent", "error", "digest", "dead"]
+		}`, `{
+			"hidden": false,
+			"id": "number1782100001",
+			"max": null,
+			"min": 0,
+			"name": "attempts",
+			"onlyInt": true,
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "number"
+		}`, `{
+			"hidden": false,
+			"id": "date1782100001",
+			"max": "",
+			"min": "",
+			"name": "next_attempt",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "date"
+		}`} {
+			if err := notifications.Fields.AddMarshaledJSON([]byte(field)); err != nil {
+				return err
+			}
+		}
+		return app.Save(notifications)
+	}, func(app core.App) error {
+		if _, err := app.DB().NewQuery("UPDATE notifications SET status = 'error' WHERE status = 'dead'").Execute(); err != nil {
+			return err
+		}
+		notifications, err := app.FindCollectionByNameOrId("notifications")
+		if err != nil {
+			return err
+		}
+		notifications.Fields.RemoveById("number1782100001")
+		notifications.Fields.RemoveById("date1782100001")
+		if err := notifications.Fields.AddMarshaledJSON([]byte(`{
+			"hidden": false,
+			"id": "select2063623452",
+			"maxSelect": 1,
+			"name": "status",
+			"presentable": false,
+			"required": true,
+			"system": false,
+			"type": "select",
+			"values": ["pending", "inflight", "sent", "error", "digest"]
+		}`)); err != nil {
+			return err
+		}
+		return app.Save(notifications)
+	})
+}
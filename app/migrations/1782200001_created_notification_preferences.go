@@ -0,0 +1,157 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// mutableNotificationTemplates are the reminder templates recipients may mute.
+// Every other template is a system notification and is always delivered.
+var mutableNotificationTemplates = []any{
+	"timesheet_submission_reminder",
+	"timesheet_approval_reminder",
+	"expense_approval_reminder",
+	"po_second_approval_required",
+}
+
+// notification_preferences holds per-user, per-template delivery choices:
+// mute (only for templates marked mutable), digest or immediate. A missing
+// row means the profile-level defaults apply. Owners manage their own rows;
+// the one-click unsubscribe route writes mute rows on their behalf.
+func init() {
+	m.Register(func(app core.App) error {
+		templates, err := app.FindCollectionByNameOrId("notification_templates")
+		if err != nil {
+			return err
+		}
+		if err := templates.Fields.AddMarshaledJSON([]byte(`{
+			"hidden": false,
+			"id": "bool1782200001",
+			"name": "mutable",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "bool"
+		}`)); err != nil {
+			return err
+		}
+		if err := app.Save(templates); err != nil {
+			return err
+		}
+		if _, err := app.DB().Update("notification_templates", dbx.Params{"mutable": true}, dbx.In("code", mutableNotificationTemplates...)).Execute(); err != nil {
+			return err
+		}
+
+		jsonData := `{
+			"createRule": "@request.auth.id != '' && uid = @request.auth.id",
+			"deleteRule": "uid = @request.auth.id",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782200001",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "uid",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "` + templates.Id + `",
+					"hidden": false,
+					"id": "relation1782200002",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "template",
+					"presentable": true,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "select1782200001",
+					"maxSelect": 1,
+					"name": "mode",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "select",
+					"values": ["mute", "digest", "immediate"]
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1782200001",
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_notification_preferences_uid_template` + "`" + ` ON ` + "`" + `notification_preferences` + "`" + ` (` + "`" + `uid` + "`" + `, ` + "`" + `template` + "`" + `)"
+			],
+			"listRule": "uid = @request.auth.id",
+			"name": "notification_preferences",
+			"system": false,
+			"type": "base",
+			"updateRule": "uid = @request.auth.id && (@request.body.uid:isset = false || @request.body.uid = @request.auth.id)",
+			"viewRule": "uid = @request.auth.id"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("notification_preferences")
+		if err != nil {
+			return err
+		}
+		if err := app.Delete(collection); err != nil {
+			return err
+		}
+
+		templates, err := app.FindCollectionByNameOrId("notification_templates")
+		if err != nil {
+			return err
+		}
+		templates.Fields.RemoveById("bool1782200001")
+		return app.Save(templates)
+	})
+}
@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const (
+	timesheetSubmissionEscalationTemplateID          = "tssubescaltpl01"
+	timesheetSubmissionEscalationTemplateCode        = "timesheet_submission_escalation"
+	timesheetSubmissionEscalationTemplateDescription = "Sent to a manager when a direct report has ignored two timesheet submission reminders for a week."
+	timesheetSubmissionEscalationTemplateSubject     = "A timesheet is still missing"
+	timesheetSubmissionEscalationTemplateText        = "Hello {{.RecipientName}},\n\n{{.EmployeeName}} has not submitted a timesheet for the week ending {{.WeekEnding}} after {{.ReminderCount}} reminders.\n\nYou can review missing timesheets for the week here:\n\n{{.ActionURL}}"
+)
+
+// Timesheet submission escalation: once a user has been sent two submission
+// reminders for a week without submitting, their manager is notified once.
+// The template is mutable like the reminders it escalates.
+func init() {
+	m.Register(func(app core.App) error {
+		existing, err := app.FindFirstRecordByFilter("notification_templates", "code={:code}", dbx.Params{"code": timesheetSubmissionEscalationTemplateCode})
+		if err == nil && existing != nil {
+			return nil
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		collection, err := app.FindCollectionByNameOrId("notification_templates")
+		if err != nil {
+			return err
+		}
+
+		record := core.NewRecord(collection)
+		record.Set("id", timesheetSubmissionEscalationTemplateID)
+		record.Set("code", timesheetSubmissionEscalationTemplateCode)
+		record.Set("description", timesheetSubmissionEscalationTemplateDescription)
+		record.Set("subject", timesheetSubmissionEscalationTemplateSubject)
+		record.Set("text_email", timesheetSubmissionEscalationTemplateText)
+		record.Set("mutable", true)
+		return app.Save(record)
+	}, func(app core.App) error {
+		template, err := app.FindRecordById("notification_templates", timesheetSubmissionEscalationTemplateID)
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if _, err := app.DB().NewQuery("DELETE FROM notifications WHERE template = {:template}").Bind(dbx.Params{"template": template.Id}).Execute(); err != nil {
+			return err
+		}
+		return app.Delete(template)
+	})
+}
@@ -0,0 +1,237 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// time_off_requests lets staff ask for planned time off (OP/OV) ahead of
+// entering it on a timesheet. Requests follow the same submit / approve /
+// reject / recall lifecycle as expenses: the owner edits drafts, the
+// workflow fields are written only by the custom routes, and the approver is
+// the owner's manager (set in the hook). time_off_manager, hr and admin may
+// view every request for the team calendar.
+func init() {
+	m.Register(func(app core.App) error {
+		timeTypes, err := app.FindCollectionByNameOrId("time_types")
+		if err != nil {
+			return err
+		}
+
+		jsonData := `{
+			"createRule": "@request.auth.id != '' && uid = @request.auth.id &&\n@request.body.submitted:isset = false &&\n@request.body.approved:isset = false &&\n@request.body.rejected:isset = false &&\n@request.body.rejector:isset = false &&\n@request.body.rejection_reason:isset = false",
+			"deleteRule": "uid = @request.auth.id && submitted = false",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782400001",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "uid",
+					"presentable": true,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "` + timeTypes.Id + `",
+					"hidden": false,
+					"id": "relation1782400002",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "time_type",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782400001",
+					"max": 0,
+					"min": 0,
+					"name": "start_date",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782400002",
+					"max": 0,
+					"min": 0,
+					"name": "end_date",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "number1782400001",
+					"max": null,
+					"min": null,
+					"name": "hours",
+					"onlyInt": false,
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782400003",
+					"max": 0,
+					"min": 0,
+					"name": "description",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782400003",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "approver",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "bool1782400001",
+					"name": "submitted",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "bool"
+				},
+				{
+					"hidden": false,
+					"id": "date1782400001",
+					"max": "",
+					"min": "",
+					"name": "approved",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "date1782400002",
+					"max": "",
+					"min": "",
+					"name": "rejected",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782400004",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "rejector",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782400004",
+					"max": 0,
+					"min": 0,
+					"name": "rejection_reason",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1782400001",
+			"indexes": [
+				"CREATE INDEX ` + "`" + `idx_time_off_requests_uid_dates` + "`" + ` ON ` + "`" + `time_off_requests` + "`" + ` (` + "`" + `uid` + "`" + `, ` + "`" + `start_date` + "`" + `, ` + "`" + `end_date` + "`" + `)"
+			],
+			"listRule": "uid = @request.auth.id ||\n(submitted = true && approver = @request.auth.id) ||\n@request.auth.user_claims_via_uid.cid.name ?= 'time_off_manager' ||\n@request.auth.user_claims_via_uid.cid.name ?= 'hr' ||\n@request.auth.user_claims_via_uid.cid.name ?= 'admin'",
+			"name": "time_off_requests",
+			"system": false,
+			"type": "base",
+			"updateRule": "uid = @request.auth.id && submitted = false &&\n@request.body.uid:changed = false &&\n@request.body.submitted:isset = false &&\n@request.body.approved:isset = false &&\n@request.body.rejected:isset = false &&\n@request.body.rejector:isset = false &&\n@request.body.rejection_reason:isset = false",
+			"viewRule": "uid = @request.auth.id ||\n(submitted = true && approver = @request.auth.id) ||\n@request.auth.user_claims_via_uid.cid.name ?= 'time_off_manager' ||\n@request.auth.user_claims_via_uid.cid.name ?= 'hr' ||\n@request.auth.user_claims_via_uid.cid.name ?= 'admin'"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("time_off_requests")
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
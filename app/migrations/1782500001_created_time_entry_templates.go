@@ -0,0 +1,218 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// time_entry_templates holds recurring time entries owned by a user. Each
+// template lists the weekdays it applies to along with the same job, division,
+// category, role, time type, hours and description a time entry would carry.
+// Templates are only materialized into time_entries by the apply route, which
+// runs every generated entry through the normal time entry validation.
+func init() {
+	m.Register(func(app core.App) error {
+		relatedIds := map[string]string{}
+		for _, name := range []string{"time_types", "jobs", "divisions", "categories", "rate_roles"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			relatedIds[name] = collection.Id
+		}
+
+		jsonData := `{
+			"createRule": "@request.auth.id != '' && uid = @request.auth.id",
+			"deleteRule": "uid = @request.auth.id",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782500001",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "uid",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782500001",
+					"max": 0,
+					"min": 0,
+					"name": "name",
+					"pattern": "",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "select1782500001",
+					"maxSelect": 7,
+					"name": "weekdays",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "select",
+					"values": ["sun", "mon", "tue", "wed", "thu", "fri", "sat"]
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "` + relatedIds["time_types"] + `",
+					"hidden": false,
+					"id": "relation1782500002",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "time_type",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "` + relatedIds["jobs"] + `",
+					"hidden": false,
+					"id": "relation1782500003",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "job",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "` + relatedIds["divisions"] + `",
+					"hidden": false,
+					"id": "relation1782500004",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "division",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "` + relatedIds["categories"] + `",
+					"hidden": false,
+					"id": "relation1782500005",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "category",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "` + relatedIds["rate_roles"] + `",
+					"hidden": false,
+					"id": "relation1782500006",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "role",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "number1782500001",
+					"max": null,
+					"min": null,
+					"name": "hours",
+					"onlyInt": false,
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782500002",
+					"max": 0,
+					"min": 0,
+					"name": "description",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1782500001",
+			"indexes": [
+				"CREATE INDEX ` + "`" + `idx_time_entry_templates_uid` + "`" + ` ON ` + "`" + `time_entry_templates` + "`" + ` (` + "`" + `uid` + "`" + `)"
+			],
+			"listRule": "uid = @request.auth.id",
+			"name": "time_entry_templates",
+			"system": false,
+			"type": "base",
+			"updateRule": "uid = @request.auth.id && @request.body.uid:changed = false",
+			"viewRule": "uid = @request.auth.id"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("time_entry_templates")
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,125 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// statutory_holidays is the holiday calendar. Each holiday lists the branches
+// that observe it; branches stand in for provinces, and a holiday without
+// branches is observed company-wide. Salaried staff are expected to record an
+// OH entry on each holiday their default branch observes.
+func init() {
+	m.Register(func(app core.App) error {
+		branches, err := app.FindCollectionByNameOrId("branches")
+		if err != nil {
+			return err
+		}
+
+		jsonData := `{
+			"createRule": "@request.auth.user_claims_via_uid.cid.name ?= 'admin'",
+			"deleteRule": "@request.auth.user_claims_via_uid.cid.name ?= 'admin'",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782600001",
+					"max": 0,
+					"min": 0,
+					"name": "date",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782600002",
+					"max": 0,
+					"min": 0,
+					"name": "name",
+					"pattern": "",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "` + branches.Id + `",
+					"hidden": false,
+					"id": "relation1782600001",
+					"maxSelect": 999,
+					"minSelect": 0,
+					"name": "branches",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1782600001",
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_statutory_holidays_date_name` + "`" + ` ON ` + "`" + `statutory_holidays` + "`" + ` (` + "`" + `date` + "`" + `, ` + "`" + `name` + "`" + `)"
+			],
+			"listRule": "@request.auth.id != ''",
+			"name": "statutory_holidays",
+			"system": false,
+			"type": "base",
+			"updateRule": "@request.auth.user_claims_via_uid.cid.name ?= 'admin'",
+			"viewRule": "@request.auth.id != ''"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("statutory_holidays")
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,146 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// approval_delegations lets an approver (delegator) hand their approvals to
+// another user (delegate) for an inclusive date range while they are away.
+// scopes selects which approval queues are delegated: time (timesheets),
+// expenses and purchase_orders (first-stage approval). The delegator manages
+// their own delegations and both parties can see them.
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": "@request.auth.id != '' && delegator = @request.auth.id",
+			"deleteRule": "delegator = @request.auth.id",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782700001",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "delegator",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782700002",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "delegate",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782700001",
+					"max": 0,
+					"min": 0,
+					"name": "start_date",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782700002",
+					"max": 0,
+					"min": 0,
+					"name": "end_date",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "select1782700001",
+					"maxSelect": 3,
+					"name": "scopes",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "select",
+					"values": ["time", "expenses", "purchase_orders"]
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1782700001",
+			"indexes": [
+				"CREATE INDEX ` + "`" + `idx_approval_delegations_delegator_dates` + "`" + ` ON ` + "`" + `approval_delegations` + "`" + ` (` + "`" + `delegator` + "`" + `, ` + "`" + `start_date` + "`" + `, ` + "`" + `end_date` + "`" + `)",
+				"CREATE INDEX ` + "`" + `idx_approval_delegations_delegate` + "`" + ` ON ` + "`" + `approval_delegations` + "`" + ` (` + "`" + `delegate` + "`" + `)"
+			],
+			"listRule": "delegator = @request.auth.id || delegate = @request.auth.id",
+			"name": "approval_delegations",
+			"system": false,
+			"type": "base",
+			"updateRule": "delegator = @request.auth.id && @request.body.delegator:changed = false",
+			"viewRule": "delegator = @request.auth.id || delegate = @request.auth.id"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("approval_delegations")
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
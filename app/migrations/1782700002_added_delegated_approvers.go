@@ -0,0 +1,59 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// delegatedApproverFieldIds holds the id of the delegated_approver field added
+// to each collection whose approvals can be delegated.
+var delegatedApproverFieldIds = map[string]string{
+	"time_sheets":     "relation1782700003",
+	"expenses":        "relation1782700004",
+	"purchase_orders": "relation1782700005",
+}
+
+// Delegated approvers: when a delegate approves a record on behalf of its
+// approver through an approval_delegations record, approver is left as the
+// delegator and delegated_approver records who actually approved it.
+func init() {
+	m.Register(func(app core.App) error {
+		for collectionName, fieldId := range delegatedApproverFieldIds {
+			collection, err := app.FindCollectionByNameOrId(collectionName)
+			if err != nil {
+				return err
+			}
+			if err := collection.Fields.AddMarshaledJSON([]byte(`{
+				"cascadeDelete": false,
+				"collectionId": "_pb_users_auth_",
+				"hidden": false,
+				"id": "` + fieldId + `",
+				"maxSelect": 1,
+				"minSelect": 0,
+				"name": "delegated_approver",
+				"presentable": false,
+				"required": false,
+				"system": false,
+				"type": "relation"
+			}`)); err != nil {
+				return err
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	}, func(app core.App) error {
+		for collectionName, fieldId := range delegatedApproverFieldIds {
+			collection, err := app.FindCollectionByNameOrId(collectionName)
+			if err != nil {
+				return err
+			}
+			collection.Fields.RemoveById(fieldId)
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
@@ -0,0 +1,125 @@
+package migrations
+
+import (
+	"database/sql"
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const (
+	timesheetApprovalEscalationTemplateID          = "tsapvescaltpl01"
+	timesheetApprovalEscalationTemplateCode        = "timesheet_approval_escalation"
+	timesheetApprovalEscalationTemplateDescription = "Sent to an alternate manager or the approver's manager when a submitted timesheet has waited too long for approval."
+	timesheetApprovalEscalationTemplateSubject     = "A timesheet is waiting for approval"
+	timesheetApprovalEscalationTemplateText        = "Hello {{.RecipientName}},\n\nThe timesheet {{.EmployeeName}} submitted for the week ending {{.WeekEnding}} has waited {{.BusinessDays}} business days for approval by {{.ApproverName}}. It has been escalated to you and you can now approve it.\n\nYou can review pending timesheets here:\n\n{{.ActionURL}}"
+
+	timesheetApprovalEscalatedFieldID   = "date1782800001"
+	timesheetApprovalEscalatedToFieldID = "relation1782800002"
+
+	timeSheetsApprovalEscalationOldRule = "@request.auth.id = uid ||\n(submitted = true && @request.auth.id = approver) ||\n(submitted = true && @request.auth.id ?= time_sheet_reviewers_via_time_sheet.reviewer) ||\n(submitted = true && approved != '' && @request.auth.user_claims_via_uid.cid.name ?= 'commit') ||\n(committed != '' && @request.auth.user_claims_via_uid.cid.name ?= 'report')"
+	timeSheetsApprovalEscalationNewRule = "@request.auth.id = uid ||\n(submitted = true && @request.auth.id = approver) ||\n(submitted = true && @request.auth.id = approval_escalated_to) ||\n(submitted = true && @request.auth.id ?= time_sheet_reviewers_via_time_sheet.reviewer) ||\n(submitted = true && approved != '' && @request.auth.user_claims_via_uid.cid.name ?= 'commit') ||\n(committed != '' && @request.auth.user_claims_via_uid.cid.name ?= 'report')"
+)
+
+// Timesheet approval escalation: a submitted timesheet left unapproved for a
+// configurable number of business days is escalated once. approval_escalated
+// records when and approval_escalated_to records who it was escalated to; that
+// user can then see and approve the timesheet and is notified with a mutable
+// template.
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("time_sheets")
+		if err != nil {
+			return err
+		}
+		if err := collection.Fields.AddMarshaledJSON([]byte(`{
+			"hidden": false,
+			"id": "` + timesheetApprovalEscalatedFieldID + `",
+			"max": "",
+			"min": "",
+			"name": "approval_escalated",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "date"
+		}`)); err != nil {
+			return err
+		}
+		if err := collection.Fields.AddMarshaledJSON([]byte(`{
+			"cascadeDelete": false,
+			"collectionId": "_pb_users_auth_",
+			"hidden": false,
+			"id": "` + timesheetApprovalEscalatedToFieldID + `",
+			"maxSelect": 1,
+			"minSelect": 0,
+			"name": "approval_escalated_to",
+			"presentable": false,
+			"required": false,
+			"system": false,
+			"type": "relation"
+		}`)); err != nil {
+			return err
+		}
+		if err := app.Save(collection); err != nil {
+			return err
+		}
+		if err := updateRule(app, "time_sheets", "listRule", timeSheetsApprovalEscalationNewRule); err != nil {
+			return err
+		}
+		if err := updateRule(app, "time_sheets", "viewRule", timeSheetsApprovalEscalationNewRule); err != nil {
+			return err
+		}
+
+		existing, err := app.FindFirstRecordByFilter("notification_templates", "code={:code}", dbx.Params{"code": timesheetApprovalEscalationTemplateCode})
+		if err == nil && existing != nil {
+			return nil
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		templates, err := app.FindCollectionByNameOrId("notification_templates")
+		if err != nil {
+			return err
+		}
+
+		record := core.NewRecord(templates)
+		record.Set("id", timesheetApprovalEscalationTemplateID)
+		record.Set("code", timesheetApprovalEscalationTemplateCode)
+		record.Set("description", timesheetApprovalEscalationTemplateDescription)
+		record.Set("subject", timesheetApprovalEscalationTemplateSubject)
+		record.Set("text_email", timesheetApprovalEscalationTemplateText)
+		record.Set("mutable", true)
+		return app.Save(record)
+	}, func(app core.App) error {
+		template, err := app.FindRecordById("notification_templates", timesheetApprovalEscalationTemplateID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil {
+			if _, err := app.DB().NewQuery("DELETE FROM notifications WHERE template = {:template}").Bind(dbx.Params{"template": template.Id}).Execute(); err != nil {
+				return err
+			}
+			if err := app.Delete(template); err != nil {
+				return err
+			}
+		}
+
+		if err := updateRule(app, "time_sheets", "listRule", timeSheetsApprovalEscalationOldRule); err != nil {
+			return err
+		}
+		if err := updateRule(app, "time_sheets", "viewRule", timeSheetsApprovalEscalationOldRule); err != nil {
+			return err
+		}
+
+		collection, err := app.FindCollectionByNameOrId("time_sheets")
+		if err != nil {
+			return err
+		}
+		collection.Fields.RemoveById(timesheetApprovalEscalatedFieldID)
+		collection.Fields.RemoveById(timesheetApprovalEscalatedToFieldID)
+		return app.Save(collection)
+	})
+}
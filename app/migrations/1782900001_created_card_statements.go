@@ -0,0 +1,419 @@
+package migrations
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const (
+	cardStatementUnmatchedTemplateID          = "cardstmtremtpl1"
+	cardStatementUnmatchedTemplateCode        = "card_statement_unmatched"
+	cardStatementUnmatchedTemplateDescription = "Sent to a corporate credit cardholder when payables finds statement charges with no matching expense."
+	cardStatementUnmatchedTemplateSubject     = "Corporate credit card charges need expenses"
+	cardStatementUnmatchedTemplateText        = "Hello {{.RecipientName}},\n\nThe statement for the corporate credit card ending in {{.CardLast4}} from {{.PeriodStart}} to {{.PeriodEnd}} has {{.LineCount}} charge(s) with no matching expense:\n\n{{.Lines}}\n\nPlease submit an expense for each charge here:\n\n{{.ActionURL}}"
+)
+
+// card_statements holds corporate credit card statements imported by
+// payables. Each statement has one card_statement_lines record per charge.
+// A line is matched to at most one CorporateCreditCard expense: matching
+// suggests an expense and payables confirms it. Cardholders are reminded of
+// unmatched lines with a mutable template. Only payables_admin holders may
+// list and view statements; all writes go through the card statement routes.
+func init() {
+	m.Register(func(app core.App) error {
+		statementsJSON := `{
+			"createRule": null,
+			"deleteRule": null,
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782900001",
+					"max": 4,
+					"min": 4,
+					"name": "cc_last_4_digits",
+					"pattern": "^\\d{4}$",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782900001",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "cardholder",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782900002",
+					"max": 0,
+					"min": 0,
+					"name": "period_start",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782900003",
+					"max": 0,
+					"min": 0,
+					"name": "period_end",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "select1782900001",
+					"maxSelect": 1,
+					"name": "source_format",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "select",
+					"values": ["csv", "ofx"]
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782900004",
+					"max": 0,
+					"min": 0,
+					"name": "file_name",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782900002",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "importer",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1782900001",
+			"indexes": [
+				"CREATE INDEX ` + "`" + `idx_card_statements_card_period` + "`" + ` ON ` + "`" + `card_statements` + "`" + ` (` + "`" + `cc_last_4_digits` + "`" + `, ` + "`" + `period_start` + "`" + `)"
+			],
+			"listRule": "@request.auth.user_claims_via_uid.cid.name ?= 'payables_admin'",
+			"name": "card_statements",
+			"system": false,
+			"type": "base",
+			"updateRule": null,
+			"viewRule": "@request.auth.user_claims_via_uid.cid.name ?= 'payables_admin'"
+		}`
+
+		linesJSON := `{
+			"createRule": null,
+			"deleteRule": null,
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "pbc_1782900001",
+					"hidden": false,
+					"id": "relation1782900003",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "statement",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "number1782900001",
+					"max": null,
+					"min": 1,
+					"name": "line",
+					"onlyInt": true,
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782900005",
+					"max": 0,
+					"min": 0,
+					"name": "date",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": true,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "number1782900002",
+					"max": null,
+					"min": null,
+					"name": "amount",
+					"onlyInt": false,
+					"presentable": true,
+					"required": true,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782900006",
+					"max": 0,
+					"min": 0,
+					"name": "description",
+					"pattern": "",
+					"presentable": true,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1782900007",
+					"max": 0,
+					"min": 0,
+					"name": "reference",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": false,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "o1vpz1mm7qsfoyy",
+					"hidden": false,
+					"id": "relation1782900004",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "expense",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "select1782900002",
+					"maxSelect": 1,
+					"name": "match_status",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "select",
+					"values": ["unmatched", "suggested", "confirmed"]
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "_pb_users_auth_",
+					"hidden": false,
+					"id": "relation1782900005",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "confirmer",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "date1782900001",
+					"max": "",
+					"min": "",
+					"name": "confirmed",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "date1782900002",
+					"max": "",
+					"min": "",
+					"name": "reminded",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "date"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1782900002",
+			"indexes": [
+				"CREATE UNIQUE INDEX ` + "`" + `idx_card_statement_lines_statement_line` + "`" + ` ON ` + "`" + `card_statement_lines` + "`" + ` (` + "`" + `statement` + "`" + `, ` + "`" + `line` + "`" + `)",
+				"CREATE UNIQUE INDEX ` + "`" + `idx_card_statement_lines_expense` + "`" + ` ON ` + "`" + `card_statement_lines` + "`" + ` (` + "`" + `expense` + "`" + `) WHERE ` + "`" + `expense` + "`" + ` != ''"
+			],
+			"listRule": "@request.auth.user_claims_via_uid.cid.name ?= 'payables_admin'",
+			"name": "card_statement_lines",
+			"system": false,
+			"type": "base",
+			"updateRule": null,
+			"viewRule": "@request.auth.user_claims_via_uid.cid.name ?= 'payables_admin'"
+		}`
+
+		for _, jsonData := range []string{statementsJSON, linesJSON} {
+			collection := &core.Collection{}
+			if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+				return err
+			}
+			if err := app.Save(collection); err != nil {
+				return err
+			}
+		}
+
+		existing, err := app.FindFirstRecordByFilter("notification_templates", "code={:code}", dbx.Params{"code": cardStatementUnmatchedTemplateCode})
+		if err == nil && existing != nil {
+			return nil
+		}
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+
+		templates, err := app.FindCollectionByNameOrId("notification_templates")
+		if err != nil {
+			return err
+		}
+
+		record := core.NewRecord(templates)
+		record.Set("id", cardStatementUnmatchedTemplateID)
+		record.Set("code", cardStatementUnmatchedTemplateCode)
+		record.Set("description", cardStatementUnmatchedTemplateDescription)
+		record.Set("subject", cardStatementUnmatchedTemplateSubject)
+		record.Set("text_email", cardStatementUnmatchedTemplateText)
+		record.Set("mutable", true)
+		return app.Save(record)
+	}, func(app core.App) error {
+		template, err := app.FindRecordById("notification_templates", cardStatementUnmatchedTemplateID)
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return err
+		}
+		if err == nil {
+			if _, err := app.DB().NewQuery("DELETE FROM notifications WHERE template = {:template}").Bind(dbx.Params{"template": template.Id}).Execute(); err != nil {
+				return err
+			}
+			if err := app.Delete(template); err != nil {
+				return err
+			}
+		}
+
+		for _, name := range []string{"card_statement_lines", "card_statements"} {
+			collection, err := app.FindCollectionByNameOrId(name)
+			if err != nil {
+				return err
+			}
+			if err := app.Delete(collection); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
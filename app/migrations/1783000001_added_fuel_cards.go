@@ -0,0 +1,86 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const (
+	adminProfilesFuelCardNumberFieldID = "text1783000001"
+	expensesFuelCardTransactionFieldID = "text1783000002"
+)
+
+// Fuel cards: admin_profiles.fuel_card_number maps a fuel card to its driver
+// so a provider's transaction export can be imported as draft FuelCard
+// expenses. expenses.fuel_card_transaction identifies the transaction an
+// expense was imported from or matched to, so a transaction is never imported
+// twice.
+func init() {
+	m.Register(func(app core.App) error {
+		adminProfiles, err := app.FindCollectionByNameOrId("admin_profiles")
+		if err != nil {
+			return err
+		}
+		if err := adminProfiles.Fields.AddMarshaledJSON([]byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "` + adminProfilesFuelCardNumberFieldID + `",
+			"max": 19,
+			"min": 0,
+			"name": "fuel_card_number",
+			"pattern": "^[0-9]+$",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+		adminProfiles.AddIndex("idx_admin_profiles_fuel_card_number", true, "`fuel_card_number`", "`fuel_card_number` != ''")
+		if err := app.Save(adminProfiles); err != nil {
+			return err
+		}
+
+		expenses, err := app.FindCollectionByNameOrId("expenses")
+		if err != nil {
+			return err
+		}
+		if err := expenses.Fields.AddMarshaledJSON([]byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "` + expensesFuelCardTransactionFieldID + `",
+			"max": 0,
+			"min": 0,
+			"name": "fuel_card_transaction",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+		expenses.AddIndex("idx_expenses_fuel_card_transaction", true, "`fuel_card_transaction`", "`fuel_card_transaction` != ''")
+		return app.Save(expenses)
+	}, func(app core.App) error {
+		expenses, err := app.FindCollectionByNameOrId("expenses")
+		if err != nil {
+			return err
+		}
+		expenses.RemoveIndex("idx_expenses_fuel_card_transaction")
+		expenses.Fields.RemoveById(expensesFuelCardTransactionFieldID)
+		if err := app.Save(expenses); err != nil {
+			return err
+		}
+
+		adminProfiles, err := app.FindCollectionByNameOrId("admin_profiles")
+		if err != nil {
+			return err
+		}
+		adminProfiles.RemoveIndex("idx_admin_profiles_fuel_card_number")
+		adminProfiles.Fields.RemoveById(adminProfilesFuelCardNumberFieldID)
+		return app.Save(adminProfiles)
+	})
+}
@@ -0,0 +1,121 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+var expenseDocumentExtractionFieldIDs = []string{
+	"select1783100001",
+	"number1783100001",
+	"number1783100002",
+	"text1783100001",
+	"text1783100002",
+	"text1783100003",
+}
+
+// Receipt extraction: when an uploaded PDF has embedded text, the invoice
+// total, tax, date, vendor name and number found in it are stored on the
+// expense document and offered as suggestions on the expense. The status
+// records why nothing was extracted, e.g. a scanned PDF or an image.
+func init() {
+	m.Register(func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_2089657321")
+		if err != nil {
+			return err
+		}
+		for _, field := range []string{
+			`{
+				"hidden": false,
+				"id": "select1783100001",
+				"maxSelect": 1,
+				"name": "extraction_status",
+				"presentable": false,
+				"required": false,
+				"system": false,
+				"type": "select",
+				"values": ["extracted", "no_fields", "no_text", "unsupported", "failed"]
+			}`,
+			`{
+				"hidden": false,
+				"id": "number1783100001",
+				"max": null,
+				"min": null,
+				"name": "extracted_total",
+				"onlyInt": false,
+				"presentable": false,
+				"required": false,
+				"system": false,
+				"type": "number"
+			}`,
+			`{
+				"hidden": false,
+				"id": "number1783100002",
+				"max": null,
+				"min": null,
+				"name": "extracted_tax",
+				"onlyInt": false,
+				"presentable": false,
+				"required": false,
+				"system": false,
+				"type": "number"
+			}`,
+			`{
+				"autogeneratePattern": "",
+				"hidden": false,
+				"id": "text1783100001",
+				"max": 0,
+				"min": 0,
+				"name": "extracted_invoice_date",
+				"pattern": "",
+				"presentable": false,
+				"primaryKey": false,
+				"required": false,
+				"system": false,
+				"type": "text"
+			}`,
+			`{
+				"autogeneratePattern": "",
+				"hidden": false,
+				"id": "text1783100002",
+				"max": 100,
+				"min": 0,
+				"name": "extracted_vendor_name",
+				"pattern": "",
+				"presentable": false,
+				"primaryKey": false,
+				"required": false,
+				"system": false,
+				"type": "text"
+			}`,
+			`{
+				"autogeneratePattern": "",
+				"hidden": false,
+				"id": "text1783100003",
+				"max": 0,
+				"min": 0,
+				"name": "extracted_invoice_number",
+				"pattern": "",
+				"presentable": false,
+				"primaryKey": false,
+				"required": false,
+				"system": false,
+				"type": "text"
+			}`,
+		} {
+			if err := collection.Fields.AddMarshaledJSON([]byte(field)); err != nil {
+				return err
+			}
+		}
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("pbc_2089657321")
+		if err != nil {
+			return err
+		}
+		for _, id := range expenseDocumentExtractionFieldIDs {
+			collection.Fields.RemoveById(id)
+		}
+		return app.Save(collection)
+	})
+}
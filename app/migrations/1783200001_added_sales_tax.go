@@ -0,0 +1,114 @@
+package migrations
+
+import (
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+const (
+	expensesTaxCodeFieldID      = "text1783200001"
+	expensesTaxAmountFieldID    = "number1783200001"
+	expensesPreTaxAmountFieldID = "number1783200002"
+	vendorsTaxCodeFieldID       = "text1783200002"
+)
+
+// Sales tax: expenses.tax_code names the province (or EXEMPT) whose sales tax
+// applies, and tax_amount and pre_tax_amount split the tax-inclusive total.
+// vendors.tax_code optionally sets the code for a vendor's purchases, e.g.
+// EXEMPT for a foreign supplier. The rates live in the sales_tax app_config
+// domain.
+func init() {
+	m.Register(func(app core.App) error {
+		expenses, err := app.FindCollectionByNameOrId("expenses")
+		if err != nil {
+			return err
+		}
+		for _, field := range []string{
+			`{
+				"autogeneratePattern": "",
+				"hidden": false,
+				"id": "` + expensesTaxCodeFieldID + `",
+				"max": 10,
+				"min": 0,
+				"name": "tax_code",
+				"pattern": "",
+				"presentable": false,
+				"primaryKey": false,
+				"required": false,
+				"system": false,
+				"type": "text"
+			}`,
+			`{
+				"hidden": false,
+				"id": "` + expensesTaxAmountFieldID + `",
+				"max": null,
+				"min": 0,
+				"name": "tax_amount",
+				"onlyInt": false,
+				"presentable": false,
+				"required": false,
+				"system": false,
+				"type": "number"
+			}`,
+			`{
+				"hidden": false,
+				"id": "` + expensesPreTaxAmountFieldID + `",
+				"max": null,
+				"min": 0,
+				"name": "pre_tax_amount",
+				"onlyInt": false,
+				"presentable": false,
+				"required": false,
+				"system": false,
+				"type": "number"
+			}`,
+		} {
+			if err := expenses.Fields.AddMarshaledJSON([]byte(field)); err != nil {
+				return err
+			}
+		}
+		if err := app.Save(expenses); err != nil {
+			return err
+		}
+
+		vendors, err := app.FindCollectionByNameOrId("vendors")
+		if err != nil {
+			return err
+		}
+		if err := vendors.Fields.AddMarshaledJSON([]byte(`{
+			"autogeneratePattern": "",
+			"hidden": false,
+			"id": "` + vendorsTaxCodeFieldID + `",
+			"max": 10,
+			"min": 0,
+			"name": "tax_code",
+			"pattern": "",
+			"presentable": false,
+			"primaryKey": false,
+			"required": false,
+			"system": false,
+			"type": "text"
+		}`)); err != nil {
+			return err
+		}
+		return app.Save(vendors)
+	}, func(app core.App) error {
+		vendors, err := app.FindCollectionByNameOrId("vendors")
+		if err != nil {
+			return err
+		}
+		vendors.Fields.RemoveById(vendorsTaxCodeFieldID)
+		if err := app.Save(vendors); err != nil {
+			return err
+		}
+
+		expenses, err := app.FindCollectionByNameOrId("expenses")
+		if err != nil {
+			return err
+		}
+		for _, id := range []string{expensesTaxCodeFieldID, expensesTaxAmountFieldID, expensesPreTaxAmountFieldID} {
+			expenses.Fields.RemoveById(id)
+		}
+		return app.Save(expenses)
+	})
+}
@@ -0,0 +1,174 @@
+package migrations
+
+import (
+	"encoding/json"
+
+	"github.com/pocketbase/pocketbase/core"
+	m "github.com/pocketbase/pocketbase/migrations"
+)
+
+// mileage_trips is the per-trip log behind a Mileage expense: one record per
+// trip with its date, start and end points, business purpose, kilometres and
+// optional job. Once an expense has trips, its distance is the sum of their
+// kilometres. Trips can be edited by the expense's creator until the expense
+// is submitted and are visible to whoever can see the expense.
+func init() {
+	m.Register(func(app core.App) error {
+		jsonData := `{
+			"createRule": "expense.creator = @request.auth.id && expense.submitted = false && expense.committed = \"\"",
+			"deleteRule": "expense.creator = @request.auth.id && expense.submitted = false && expense.committed = \"\"",
+			"fields": [
+				{
+					"autogeneratePattern": "[a-z0-9]{15}",
+					"hidden": false,
+					"id": "text3208210256",
+					"max": 15,
+					"min": 15,
+					"name": "id",
+					"pattern": "^[a-z0-9]+$",
+					"presentable": false,
+					"primaryKey": true,
+					"required": true,
+					"system": true,
+					"type": "text"
+				},
+				{
+					"cascadeDelete": true,
+					"collectionId": "o1vpz1mm7qsfoyy",
+					"hidden": false,
+					"id": "relation1783300001",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "expense",
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1783300001",
+					"max": 0,
+					"min": 0,
+					"name": "date",
+					"pattern": "^\\d{4}-\\d{2}-\\d{2}$",
+					"presentable": false,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1783300002",
+					"max": 200,
+					"min": 0,
+					"name": "origin",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1783300003",
+					"max": 200,
+					"min": 0,
+					"name": "destination",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"autogeneratePattern": "",
+					"hidden": false,
+					"id": "text1783300004",
+					"max": 500,
+					"min": 0,
+					"name": "purpose",
+					"pattern": "",
+					"presentable": false,
+					"primaryKey": false,
+					"required": true,
+					"system": false,
+					"type": "text"
+				},
+				{
+					"hidden": false,
+					"id": "number1783300001",
+					"max": null,
+					"min": 1,
+					"name": "distance",
+					"onlyInt": true,
+					"presentable": false,
+					"required": true,
+					"system": false,
+					"type": "number"
+				},
+				{
+					"cascadeDelete": false,
+					"collectionId": "yovqzrnnomp0lkx",
+					"hidden": false,
+					"id": "relation1783300002",
+					"maxSelect": 1,
+					"minSelect": 0,
+					"name": "job",
+					"presentable": false,
+					"required": false,
+					"system": false,
+					"type": "relation"
+				},
+				{
+					"hidden": false,
+					"id": "autodate2990389176",
+					"name": "created",
+					"onCreate": true,
+					"onUpdate": false,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				},
+				{
+					"hidden": false,
+					"id": "autodate3332085495",
+					"name": "updated",
+					"onCreate": true,
+					"onUpdate": true,
+					"presentable": false,
+					"system": false,
+					"type": "autodate"
+				}
+			],
+			"id": "pbc_1783300001",
+			"indexes": [
+				"CREATE INDEX ` + "`" + `idx_mileage_trips_expense_date` + "`" + ` ON ` + "`" + `mileage_trips` + "`" + ` (` + "`" + `expense` + "`" + `, ` + "`" + `date` + "`" + `)"
+			],
+			"listRule": "expense.uid = @request.auth.id || expense.creator = @request.auth.id || (expense.approver = @request.auth.id && expense.submitted = true) || (expense.approved != \"\" && @request.auth.user_claims_via_uid.cid.name ?= 'commit') || (expense.committed != \"\" && @request.auth.user_claims_via_uid.cid.name ?= 'report')",
+			"name": "mileage_trips",
+			"system": false,
+			"type": "base",
+			"updateRule": "expense.creator = @request.auth.id && expense.submitted = false && expense.committed = \"\" && (@request.body.expense:isset = false || @request.body.expense = expense)",
+			"viewRule": "expense.uid = @request.auth.id || expense.creator = @request.auth.id || (expense.approver = @request.auth.id && expense.submitted = true) || (expense.approved != \"\" && @request.auth.user_claims_via_uid.cid.name ?= 'commit') || (expense.committed != \"\" && @request.auth.user_claims_via_uid.cid.name ?= 'report')"
+		}`
+
+		collection := &core.Collection{}
+		if err := json.Unmarshal([]byte(jsonData), &collection); err != nil {
+			return err
+		}
+		return app.Save(collection)
+	}, func(app core.App) error {
+		collection, err := app.FindCollectionByNameOrId("mileage_trips")
+		if err != nil {
+			return err
+		}
+		return app.Delete(collection)
+	})
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tybalt/internal/testutils"
+)
+
+type mileageLogTestSummary struct {
+	Expenses           int     `json:"expenses"`
+	Trips              int     `json:"trips"`
+	CommittedDistance  int     `json:"committed_distance"`
+	CommittedAmount    float64 `json:"committed_amount"`
+	PendingDistance    int     `json:"pending_distance"`
+	CumulativeDistance int     `json:"cumulative_distance"`
+	CurrentTierFromKm  int     `json:"current_tier_from_km"`
+	DistanceToNextTier *int    `json:"distance_to_next_tier"`
+	Tiers              []struct {
+		FromKm   int     `json:"from_km"`
+		ToKm     *int    `json:"to_km"`
+		Rate     float64 `json:"rate"`
+		Distance int     `json:"distance"`
+		Amount   float64 `json:"amount"`
+	} `json:"tiers"`
+}
+
+func TestMileageLog(t *testing.T) {
+	tokens := map[string]string{}
+	for _, email := range []string{"u_mileage_valid@example.com", "author@soup.com", "fatt@mac.com", "noclaims@example.com"} {
+		token, err := testutils.GenerateRecordToken("users", email)
+		if err != nil {
+			t.Fatal(err)
+		}
+		tokens[email] = token
+	}
+	owner := tokens["u_mileage_valid@example.com"]
+
+	app := testutils.SetupTestApp(t)
+	defer app.Cleanup()
+
+	body, contentType := mustMultipartExpenseWithContent(t, map[string]string{
+		"uid":          "u_mileage_valid",
+		"date":         "2025-01-10",
+		"division":     "vccd5fo56ctbigh",
+		"description":  "site visits",
+		"payment_type": "Mileage",
+		"distance":     "100",
+		"total":        "0",
+	}, "mileage.png", []byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A, 0x25})
+	res := performTestAPIRequest(t, app, http.MethodPost, "/api/collections/expenses/records", body, map[string]string{
+		"Authorization": owner,
+		"Content-Type":  contentType,
+	})
+	mustStatus(t, res, http.StatusOK)
+	var created struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &created); err != nil {
+		t.Fatalf("failed to decode expense: %v", err)
+	}
+
+	send := func(method string, url string, fields map[string]any) (int, string) {
+		t.Helper()
+		var payload *strings.Reader
+		if fields != nil {
+			encoded, err := json.Marshal(fields)
+			if err != nil {
+				t.Fatal(err)
+			}
+			payload = strings.NewReader(string(encoded))
+		} else {
+			payload = strings.NewReader("")
+		}
+		res := performTestAPIRequest(t, app, method, url, payload, map[string]string{
+			"Authorization": owner,
+			"Content-Type":  "application/json",
+		})
+		return res.Code, res.Body.String()
+	}
+	addTrip := func(date string, distance int) (int, string) {
+		t.Helper()
+		return send(http.MethodPost, "/api/collections/mileage_trips/records", map[string]any{
+			"expense":     created.ID,
+			"date":        date,
+			"origin":      "Office",
+			"destination": "Site",
+			"purpose":     "Inspection",
+			"distance":    distance,
+		})
+	}
+	expectExpense := func(distance float64, total float64) {
+		t.Helper()
+		record, err := app.FindRecordById("expenses", created.ID)
+		if err != nil {
+			t.Fatalf("failed to load expense: %v", err)
+		}
+		if record.GetFloat("distance") != distance || record.GetFloat("total") != total {
+			t.Fatalf("expected %v km for %v, got %v km for %v", distance, total, record.GetFloat("distance"), record.GetFloat("total"))
+		}
+	}
+
+	// Logged trips replace the expense's distance and reprice it.
+	if code, failure := addTrip("2025-01-09", 40); code != http.StatusOK {
+		t.Fatalf("expected the trip to be created, got %d %s", code, failure)
+	}
+	expectExpense(40, 28)
+	code, second := addTrip("2025-01-10", 25)
+	if code != http.StatusOK {
+		t.Fatalf("expected the trip to be created, got %d %s", code, second)
+	}
+	expectExpense(65, 45.5)
+	var secondTrip struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal([]byte(second), &secondTrip); err != nil {
+		t.Fatalf("failed to decode trip: %v", err)
+	}
+
+	if code, failure := addTrip("2025-01-11", 5); code != http.StatusBadRequest || !strings.Contains(failure, `"after_expense_date"`) {
+		t.Fatalf("expected a trip after the expense date to fail, got %d %s", code, failure)
+	}
+	if code, failure := send(http.MethodPatch, "/api/collections/expenses/records/"+created.ID, map[string]any{"payment_type": "OnAccount"}); code != http.StatusBadRequest || !strings.Contains(failure, `"has_mileage_trips"`) {
+		t.Fatalf("expected changing the payment type to fail, got %d %s", code, failure)
+	}
+	if code, failure := send(http.MethodPatch, "/api/collections/expenses/records/"+created.ID, map[string]any{"distance": 500}); code != http.StatusOK {
+		t.Fatalf("expected the expense to update, got %d %s", code, failure)
+	}
+	expectExpense(65, 45.5)
+
+	if code, failure := send(http.MethodDelete, "/api/collections/mileage_trips/records/"+secondTrip.ID, nil); code != http.StatusNoContent {
+		t.Fatalf("expected the trip to be deleted, got %d %s", code, failure)
+	}
+	expectExpense(40, 28)
+
+	record, err := app.FindRecordById("expenses", created.ID)
+	if err != nil {
+		t.Fatalf("failed to load expense: %v", err)
+	}
+	record.Set("submitted", true)
+	record.Set("approved", "2025-01-11 12:00:00.000Z")
+	record.Set("committed", "2025-01-12 12:00:00.000Z")
+	if err := app.Save(record); err != nil {
+		t.Fatalf("failed to commit expense: %v", err)
+	}
+	if code, _ := addTrip("2025-01-08", 5); code == http.StatusOK {
+		t.Fatalf("expected a trip on a committed expense to fail")
+	}
+
+	getSummary := func(token string, url string) (int, mileageLogTestSummary) {
+		t.Helper()
+		res := performTestAPIRequest(t, app, http.MethodGet, url, nil, map[string]string{"Authorization": token})
+		var summary mileageLogTestSummary
+		if res.Code == http.StatusOK {
+			if err := json.Unmarshal(res.Body.Bytes(), &summary); err != nil {
+				t.Fatalf("failed to decode summary: %v", err)
+			}
+		}
+		return res.Code, summary
+	}
+
+	code, summary := getSummary(owner, "/api/expenses/mileage/u_mileage_valid/2025")
+	if code != http.StatusOK {
+		t.Fatalf("expected the owner to see their summary, got %d", code)
+	}
+	if summary.Expenses != 1 || summary.Trips != 1 || summary.CommittedDistance != 40 || summary.CommittedAmount != 28 ||
+		summary.PendingDistance != 0 || summary.CumulativeDistance != 40 || summary.CurrentTierFromKm != 0 ||
+		summary.DistanceToNextTier == nil || *summary.DistanceToNextTier != 4960 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+	if len(summary.Tiers) != 2 || summary.Tiers[0].Distance != 40 || summary.Tiers[0].Amount != 28 ||
+		summary.Tiers[0].ToKm == nil || *summary.Tiers[0].ToKm != 5000 || summary.Tiers[1].Rate != 0.64 || summary.Tiers[1].ToKm != nil {
+		t.Fatalf("unexpected tiers: %+v", summary.Tiers)
+	}
+
+	if code, _ := getSummary(tokens["author@soup.com"], "/api/expenses/mileage/u_mileage_valid/2025"); code != http.StatusOK {
+		t.Fatalf("expected the manager to see the summary, got %d", code)
+	}
+	if code, _ := getSummary(tokens["noclaims@example.com"], "/api/expenses/mileage/u_mileage_valid/2025"); code != http.StatusForbidden {
+		t.Fatalf("expected other employees to be forbidden, got %d", code)
+	}
+	if code, _ := getSummary(owner, "/api/expenses/mileage/u_mileage_valid/25"); code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid year to fail, got %d", code)
+	}
+
+	// Claims from before trips were logged still count towards the tiers.
+	code, summary = getSummary(tokens["fatt@mac.com"], "/api/expenses/mileage/uid_mileage_2023_test/2023")
+	if code != http.StatusOK {
+		t.Fatalf("expected a report holder to see the summary, got %d", code)
+	}
+	if summary.CommittedDistance != 4900 || summary.CommittedAmount != 2989 || summary.DistanceToNextTier == nil || *summary.DistanceToNextTier != 100 {
+		t.Fatalf("unexpected summary: %+v", summary)
+	}
+
+	res = performTestAPIRequest(t, app, http.MethodGet, "/api/expenses/mileage/u_mileage_valid/2025/logbook", nil, map[string]string{
+		"Authorization": tokens["fatt@mac.com"],
+	})
+	mustStatus(t, res, http.StatusOK)
+	if disposition := res.Header().Get("Content-Disposition"); !strings.Contains(disposition, "mileage_logbook_u_mileage_valid_2025.csv") {
+		t.Fatalf("unexpected content disposition %q", disposition)
+	}
+	rows, err := csv.NewReader(strings.NewReader(res.Body.String())).ReadAll()
+	if err != nil {
+		t.Fatalf("failed to parse logbook: %v", err)
+	}
+	if len(rows) != 3 {
+		t.Fatalf("expected a header, one trip and a total, got %v", rows)
+	}
+	trip := rows[1]
+	if trip[1] != "2025-01-09" || trip[2] != "Office" || trip[3] != "Site" || trip[4] != "Inspection" ||
+		trip[6] != "40" || trip[7] != "40" || trip[8] != "Committed" || trip[9] != created.ID {
+		t.Fatalf("unexpected trip row %v", trip)
+	}
+	if rows[2][1] != "Total" || rows[2][6] != "40" {
+		t.Fatalf("unexpected total row %v", rows[2])
+	}
+}
@@ -0,0 +1,226 @@
+package main
+
+import (
+	"strings"
+	"testing"
+
+	"tybalt/notifications"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// =============================================================================
+// Notification Digests
+// =============================================================================
+//
+// author@soup.com (f2j5a8vk006baub) is the digest recipient. The seeded
+// pending notifications are drained first so mailer counts only reflect the
+// notifications created by each test.
+
+const digestRecipientUID = "f2j5a8vk006baub"
+
+func setDigestOptIn(t *testing.T, app core.App, uid string, optIn bool) {
+	t.Helper()
+
+	profile, err := app.FindFirstRecordByData("profiles", "uid", uid)
+	if err != nil {
+		t.Fatalf("failed to load profile: %v", err)
+	}
+	profile.Set("notification_digest", optIn)
+	if err := app.Save(profile); err != nil {
+		t.Fatalf("failed to save profile: %v", err)
+	}
+}
+
+func dispatchDigestTestReminder(t *testing.T, app core.App, templateCode string, system bool) string {
+	t.Helper()
+
+	notificationID, err := notifications.DispatchNotification(app, notifications.DispatchArgs{
+		TemplateCode: templateCode,
+		RecipientUID: digestRecipientUID,
+		Data: map[string]any{
+			"POId":      "test_po_id",
+			"ActionURL": "https://example.com/pending",
+		},
+		System: system,
+		Mode:   notifications.DeliveryDeferred,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if notificationID == "" {
+		t.Fatal("expected a notification to be created")
+	}
+	return notificationID
+}
+
+func notificationStatusAndDigest(t *testing.T, app core.App, id string) (string, string) {
+	t.Helper()
+
+	var row struct {
+		Status string `db:"status"`
+		Digest string `db:"digest"`
+	}
+	if err := app.DB().NewQuery("SELECT status, digest FROM notifications WHERE id = {:id}").
+		Bind(dbx.Params{"id": id}).One(&row); err != nil {
+		t.Fatalf("failed to load notification %s: %v", id, err)
+	}
+	return row.Status, row.Digest
+}
+
+func TestNotificationDigest_HoldsAndRollsUpSystemReminders(t *testing.T) {
+	app := setupTestAppWithSynchronousImmediateNotifications(t)
+	defer app.Cleanup()
+	if _, err := notifications.SendNotifications(app); err != nil {
+		t.Fatalf("failed to drain pending notifications: %v", err)
+	}
+	setDigestOptIn(t, app, digestRecipientUID, true)
+
+	timesheetID := dispatchDigestTestReminder(t, app, "timesheet_approval_reminder", true)
+	expenseID := dispatchDigestTestReminder(t, app, "expense_approval_reminder", true)
+	poID := dispatchDigestTestReminder(t, app, "po_approval_required", false)
+
+	for _, id := range []string{timesheetID, expenseID} {
+		if status, _ := notificationStatusAndDigest(t, app, id); status != "digest" {
+			t.Fatalf("expected system reminder %s to be held, got %s", id, status)
+		}
+	}
+	if status, _ := notificationStatusAndDigest(t, app, poID); status != "pending" {
+		t.Fatalf("expected user-triggered notification to stay pending, got %s", status)
+	}
+
+	// Draining the pending queue sends only the PO notification.
+	before := app.TestMailer.TotalSend()
+	if _, err := notifications.SendNotifications(app); err != nil {
+		t.Fatalf("failed to drain pending notifications: %v", err)
+	}
+	if app.TestMailer.TotalSend() != before+1 {
+		t.Fatalf("expected only the pending notification to be sent, got %d", app.TestMailer.TotalSend()-before)
+	}
+
+	digests, err := notifications.SendNotificationDigests(app)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digests != 1 {
+		t.Fatalf("expected 1 digest, got %d", digests)
+	}
+	if app.TestMailer.TotalSend() != before+2 {
+		t.Fatal("expected one digest email")
+	}
+
+	message := app.TestMailer.LastMessage()
+	if message.Subject != "Your daily Tybalt summary" {
+		t.Fatalf("unexpected subject %q", message.Subject)
+	}
+	for _, want := range []string{"summary of 2 notification(s)", "Time sheets await your approval", "Expenses await your approval", "https://example.com/pending"} {
+		if !strings.Contains(message.Text, want) {
+			t.Fatalf("expected digest text to contain %q, got %q", want, message.Text)
+		}
+	}
+
+	_, timesheetDigest := notificationStatusAndDigest(t, app, timesheetID)
+	for _, id := range []string{timesheetID, expenseID} {
+		status, digest := notificationStatusAndDigest(t, app, id)
+		if status != "sent" || digest == "" || digest != timesheetDigest {
+			t.Fatalf("expected %s to be sent in the digest, got status %s digest %q", id, status, digest)
+		}
+	}
+	if status, _ := notificationStatusAndDigest(t, app, timesheetDigest); status != "sent" {
+		t.Fatalf("expected digest notification to be sent, got %s", status)
+	}
+
+	// Nothing is held any more, so a second run sends nothing.
+	digests, err = notifications.SendNotificationDigests(app)
+	if err != nil || digests != 0 {
+		t.Fatalf("expected no digests on second run, got %d (%v)", digests, err)
+	}
+}
+
+func TestNotificationDigest_NotOptedInStaysPending(t *testing.T) {
+	app := setupTestAppWithSynchronousImmediateNotifications(t)
+	defer app.Cleanup()
+
+	id := dispatchDigestTestReminder(t, app, "timesheet_approval_reminder", true)
+	if status, _ := notificationStatusAndDigest(t, app, id); status != "pending" {
+		t.Fatalf("expected pending without opt-in, got %s", status)
+	}
+}
+
+func TestNotificationDigest_DisabledFeatureReleasesHeldNotifications(t *testing.T) {
+	app := setupTestAppWithSynchronousImmediateNotifications(t)
+	defer app.Cleanup()
+	if _, err := notifications.SendNotifications(app); err != nil {
+		t.Fatalf("failed to drain pending notifications: %v", err)
+	}
+	setDigestOptIn(t, app, digestRecipientUID, true)
+
+	timesheetID := dispatchDigestTestReminder(t, app, "timesheet_approval_reminder", true)
+	expenseID := dispatchDigestTestReminder(t, app, "expense_approval_reminder", true)
+
+	upsertNotificationsConfigRawValue(t, app, `{"timesheet_approval_reminder":true,"expense_approval_reminder":true,"notification_digest":false}`)
+
+	before := app.TestMailer.TotalSend()
+	digests, err := notifications.SendNotificationDigests(app)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if digests != 0 {
+		t.Fatalf("expected no digests, got %d", digests)
+	}
+	if app.TestMailer.TotalSend() != before+2 {
+		t.Fatalf("expected the held notifications to be sent individually, got %d", app.TestMailer.TotalSend()-before)
+	}
+	for _, id := range []string{timesheetID, expenseID} {
+		if status, digest := notificationStatusAndDigest(t, app, id); status != "sent" || digest != "" {
+			t.Fatalf("expected %s to be sent individually, got status %s digest %q", id, status, digest)
+		}
+	}
+}
+
+func TestNotificationDigest_ReminderDedupeCountsHeldNotifications(t *testing.T) {
+	app := setupTestAppWithSynchronousImmediateNotifications(t)
+	defer app.Cleanup()
+
+	var managers []struct {
+		UID string `db:"uid"`
+	}
+	if err := app.DB().NewQuery(`
+		SELECT DISTINCT approver AS uid FROM time_sheets
+		WHERE submitted = 1 AND approved = '' AND committed = '' AND rejected = '' AND approver != ''
+	`).All(&managers); err != nil || len(managers) == 0 {
+		t.Fatalf("expected managers with pending timesheets: %v", err)
+	}
+	for _, manager := range managers {
+		setDigestOptIn(t, app, manager.UID, true)
+	}
+
+	countHeld := func() int {
+		var result struct {
+			Count int `db:"count"`
+		}
+		if err := app.DB().NewQuery(`
+			SELECT COUNT(*) AS count FROM notifications n
+			JOIN notification_templates t ON t.id = n.template
+			WHERE t.code = 'timesheet_approval_reminder' AND n.status = 'digest'
+		`).One(&result); err != nil {
+			t.Fatalf("failed to count held reminders: %v", err)
+		}
+		return result.Count
+	}
+
+	if err := notifications.QueueTimesheetApprovalReminders(app, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	held := countHeld()
+	if held != len(managers) {
+		t.Fatalf("expected %d held reminders, got %d", len(managers), held)
+	}
+	if err := notifications.QueueTimesheetApprovalReminders(app, true); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if countHeld() != held {
+		t.Fatalf("expected held reminders to dedupe, got %d", countHeld())
+	}
+}
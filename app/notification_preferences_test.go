@@ -0,0 +1,239 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tybalt/internal/testutils"
+	"tybalt/notifications"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// =============================================================================
+// Notification Preferences
+// =============================================================================
+//
+// author@soup.com (digestRecipientUID) sets preferences. The reminder
+// templates are mutable in the seed data; po_approval_required is a system
+// notification template and cannot be muted.
+
+func dispatchPreferenceTestNotification(t *testing.T, app core.App, templateCode string, system bool, mode notifications.DeliveryMode) string {
+	t.Helper()
+
+	notificationID, err := notifications.DispatchNotification(app, notifications.DispatchArgs{
+		TemplateCode: templateCode,
+		RecipientUID: digestRecipientUID,
+		Data: map[string]any{
+			"POId":      "test_po_id",
+			"ActionURL": "https://example.com/pending",
+		},
+		System: system,
+		Mode:   mode,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	return notificationID
+}
+
+func TestNotificationPreferences_MuteDigestAndImmediate(t *testing.T) {
+	app := setupTestAppWithSynchronousImmediateNotifications(t)
+	defer app.Cleanup()
+	if _, err := notifications.SendNotifications(app); err != nil {
+		t.Fatalf("failed to drain pending notifications: %v", err)
+	}
+
+	// Muted reminders are not created.
+	if err := notifications.SetNotificationPreference(app, digestRecipientUID, "expense_approval_reminder", notifications.PreferenceMute); err != nil {
+		t.Fatalf("failed to mute reminder: %v", err)
+	}
+	if id := dispatchPreferenceTestNotification(t, app, "expense_approval_reminder", true, notifications.DeliveryDeferred); id != "" {
+		t.Fatalf("expected muted reminder to be skipped, got %s", id)
+	}
+
+	// System notifications cannot be muted, and a stray mute row is ignored.
+	err := notifications.SetNotificationPreference(app, digestRecipientUID, "po_approval_required", notifications.PreferenceMute)
+	if !errors.Is(err, notifications.ErrTemplateNotMutable) {
+		t.Fatalf("expected ErrTemplateNotMutable, got %v", err)
+	}
+	template, err := app.FindFirstRecordByData("notification_templates", "code", "po_approval_required")
+	if err != nil {
+		t.Fatalf("failed to load template: %v", err)
+	}
+	collection, err := app.FindCollectionByNameOrId("notification_preferences")
+	if err != nil {
+		t.Fatalf("failed to load collection: %v", err)
+	}
+	stray := core.NewRecord(collection)
+	stray.Set("uid", digestRecipientUID)
+	stray.Set("template", template.Id)
+	stray.Set("mode", notifications.PreferenceMute)
+	if err := app.Save(stray); err != nil {
+		t.Fatalf("failed to save stray mute preference: %v", err)
+	}
+	before := app.TestMailer.TotalSend()
+	poID := dispatchPreferenceTestNotification(t, app, "po_approval_required", false, notifications.DeliveryImmediate)
+	if status, _ := notificationStatusAndDigest(t, app, poID); status != "sent" {
+		t.Fatalf("expected system notification to be sent despite mute, got %s", status)
+	}
+	if app.TestMailer.TotalSend() != before+1 {
+		t.Fatal("expected the system notification email")
+	}
+
+	// A digest preference holds even immediate, user-triggered notifications.
+	if err := notifications.SetNotificationPreference(app, digestRecipientUID, "po_approval_required", notifications.PreferenceDigest); err != nil {
+		t.Fatalf("failed to set digest preference: %v", err)
+	}
+	heldID := dispatchPreferenceTestNotification(t, app, "po_approval_required", false, notifications.DeliveryImmediate)
+	if status, _ := notificationStatusAndDigest(t, app, heldID); status != "digest" {
+		t.Fatalf("expected notification to be held for the digest, got %s", status)
+	}
+	if app.TestMailer.TotalSend() != before+1 {
+		t.Fatal("expected no email for the held notification")
+	}
+
+	// An immediate preference overrides the profile-level digest opt-in.
+	setDigestOptIn(t, app, digestRecipientUID, true)
+	if err := notifications.SetNotificationPreference(app, digestRecipientUID, "timesheet_approval_reminder", notifications.PreferenceImmediate); err != nil {
+		t.Fatalf("failed to set immediate preference: %v", err)
+	}
+	immediateID := dispatchPreferenceTestNotification(t, app, "timesheet_approval_reminder", true, notifications.DeliveryDeferred)
+	if status, _ := notificationStatusAndDigest(t, app, immediateID); status != "pending" {
+		t.Fatalf("expected immediate preference to keep the reminder pending, got %s", status)
+	}
+	otherID := dispatchPreferenceTestNotification(t, app, "po_second_approval_required", true, notifications.DeliveryDeferred)
+	if status, _ := notificationStatusAndDigest(t, app, otherID); status != "digest" {
+		t.Fatalf("expected the profile digest opt-in to apply without a preference, got %s", status)
+	}
+}
+
+func TestNotificationPreferences_UnsubscribeLink(t *testing.T) {
+	app := setupTestAppWithSynchronousImmediateNotifications(t)
+	defer app.Cleanup()
+	app.Settings().Meta.AppURL = "https://tybalt.example.com"
+	if _, err := notifications.SendNotifications(app); err != nil {
+		t.Fatalf("failed to drain pending notifications: %v", err)
+	}
+
+	dispatchPreferenceTestNotification(t, app, "timesheet_approval_reminder", true, notifications.DeliveryImmediate)
+	message := app.TestMailer.LastMessage()
+	const prefix = "https://tybalt.example.com/api/notifications/unsubscribe?token="
+	if !strings.Contains(message.Text, prefix) {
+		t.Fatalf("expected an unsubscribe link in the email, got %q", message.Text)
+	}
+	if got := message.Headers["List-Unsubscribe-Post"]; got != "List-Unsubscribe=One-Click" {
+		t.Fatalf("expected one-click unsubscribe header, got %q", got)
+	}
+	link := strings.Trim(message.Headers["List-Unsubscribe"], "<>")
+	if !strings.HasPrefix(link, prefix) {
+		t.Fatalf("unexpected List-Unsubscribe header %q", link)
+	}
+
+	uid, templateCode, err := notifications.ParseUnsubscribeToken(app, strings.TrimPrefix(link, prefix))
+	if err != nil {
+		t.Fatalf("failed to parse unsubscribe token: %v", err)
+	}
+	if uid != digestRecipientUID || templateCode != "timesheet_approval_reminder" {
+		t.Fatalf("unexpected token claims %s %s", uid, templateCode)
+	}
+	if _, _, err := notifications.ParseUnsubscribeToken(app, strings.TrimPrefix(link, prefix)+"x"); !errors.Is(err, notifications.ErrInvalidUnsubscribeToken) {
+		t.Fatalf("expected a tampered token to be rejected, got %v", err)
+	}
+
+	// System notifications carry no unsubscribe link.
+	dispatchPreferenceTestNotification(t, app, "po_approval_required", false, notifications.DeliveryImmediate)
+	message = app.TestMailer.LastMessage()
+	if strings.Contains(message.Text, "unsubscribe") || message.Headers["List-Unsubscribe"] != "" {
+		t.Fatalf("expected no unsubscribe link on a system notification, got %q", message.Text)
+	}
+}
+
+func TestNotificationPreferencesCollectionRules(t *testing.T) {
+	ownerToken, err := testutils.GenerateRecordToken("users", "author@soup.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	templateIDs := map[string]string{}
+	{
+		app := testutils.SetupTestApp(t)
+		for _, code := range []string{"timesheet_approval_reminder", "po_approval_required"} {
+			template, err := app.FindFirstRecordByFilter("notification_templates", "code = {:code}", dbx.Params{"code": code})
+			if err != nil {
+				t.Fatalf("failed to load template %s: %v", code, err)
+			}
+			templateIDs[code] = template.Id
+		}
+		app.Cleanup()
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:           "owner mutes a reminder",
+			Method:         http.MethodPost,
+			URL:            "/api/collections/notification_preferences/records",
+			Body:           strings.NewReader(`{"uid":"f2j5a8vk006baub","template":"` + templateIDs["timesheet_approval_reminder"] + `","mode":"mute"}`),
+			Headers:        map[string]string{"Authorization": ownerToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"mode":"mute"`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnRecordCreate":        1,
+				"OnRecordCreateRequest": 1,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "system notifications cannot be muted",
+			Method:         http.MethodPost,
+			URL:            "/api/collections/notification_preferences/records",
+			Body:           strings.NewReader(`{"uid":"f2j5a8vk006baub","template":"` + templateIDs["po_approval_required"] + `","mode":"mute"}`),
+			Headers:        map[string]string{"Authorization": ownerToken},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedContent: []string{
+				`"mode":{"code":"template_not_mutable"`,
+			},
+			ExpectedEvents: map[string]int{"OnRecordCreateRequest": 1},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "system notifications can still be held for the digest",
+			Method:         http.MethodPost,
+			URL:            "/api/collections/notification_preferences/records",
+			Body:           strings.NewReader(`{"uid":"f2j5a8vk006baub","template":"` + templateIDs["po_approval_required"] + `","mode":"digest"}`),
+			Headers:        map[string]string{"Authorization": ownerToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"mode":"digest"`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnRecordCreate":        1,
+				"OnRecordCreateRequest": 1,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "users cannot set preferences for someone else",
+			Method:         http.MethodPost,
+			URL:            "/api/collections/notification_preferences/records",
+			Body:           strings.NewReader(`{"uid":"rzr98oadsp9qc11","template":"` + templateIDs["timesheet_approval_reminder"] + `","mode":"mute"}`),
+			Headers:        map[string]string{"Authorization": ownerToken},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedContent: []string{
+				`"message":"Failed to create record."`,
+			},
+			ExpectedEvents: map[string]int{"*": 0},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
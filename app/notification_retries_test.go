@@ -0,0 +1,193 @@
+package main
+
+import (
+	"errors"
+	"net/http"
+	"testing"
+	"time"
+
+	"tybalt/notifications"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// =============================================================================
+// Notification Retries
+// =============================================================================
+//
+// Failures come from the webhook stand-in (see notification_transports_test.go)
+// responding with an error status, so retries can be made to succeed by
+// switching the stand-in back to 200.
+
+type notificationRetryState struct {
+	Status      string `db:"status"`
+	Attempts    int    `db:"attempts"`
+	NextAttempt string `db:"next_attempt"`
+	Error       string `db:"error"`
+}
+
+func loadNotificationRetryState(t *testing.T, app core.App, id string) notificationRetryState {
+	t.Helper()
+
+	var state notificationRetryState
+	if err := app.DB().NewQuery("SELECT status, attempts, next_attempt, error FROM notifications WHERE id = {:id}").
+		Bind(dbx.Params{"id": id}).One(&state); err != nil {
+		t.Fatalf("failed to load notification %s: %v", id, err)
+	}
+	return state
+}
+
+func makeNotificationRetryDue(t *testing.T, app core.App, id string, attempts int) {
+	t.Helper()
+
+	if _, err := app.NonconcurrentDB().NewQuery("UPDATE notifications SET attempts = {:attempts}, next_attempt = '2000-01-01 00:00:00.000Z' WHERE id = {:id}").
+		Bind(dbx.Params{"id": id, "attempts": attempts}).Execute(); err != nil {
+		t.Fatalf("failed to make notification due: %v", err)
+	}
+}
+
+func TestNotificationRetry_BackoffDeadLetterAndRequeue(t *testing.T) {
+	standIn := newWebhookStandIn(t)
+	standIn.status = http.StatusServiceUnavailable
+	app := setupTransportTestApp(t, standIn)
+	defer app.Cleanup()
+	if _, err := notifications.SendNotifications(app); err != nil {
+		t.Fatalf("failed to drain pending notifications: %v", err)
+	}
+	setRecipientTransport(t, app, notifications.TransportWebhook, standIn.URL+"/hook")
+
+	before := time.Now().UTC()
+	notificationID, err := notifications.DispatchNotification(app, notifications.DispatchArgs{
+		TemplateCode: "po_approval_required",
+		RecipientUID: transportRecipientUID,
+		Data: map[string]any{
+			"POId":      "test_po_id",
+			"ActionURL": "https://example.com/pos/test_po_id/details",
+		},
+		Mode: notifications.DeliveryImmediate,
+	})
+	if err != nil || notificationID == "" {
+		t.Fatalf("expected a notification, got %q (%v)", notificationID, err)
+	}
+
+	state := loadNotificationRetryState(t, app, notificationID)
+	if state.Status != "error" || state.Attempts != 1 {
+		t.Fatalf("expected error after first attempt, got %+v", state)
+	}
+	nextAttempt, err := time.Parse("2006-01-02 15:04:05.000Z", state.NextAttempt)
+	if err != nil {
+		t.Fatalf("failed to parse next_attempt %q: %v", state.NextAttempt, err)
+	}
+	if delay := nextAttempt.Sub(before); delay < 9*time.Minute || delay > 11*time.Minute {
+		t.Fatalf("expected a ten minute backoff, got %v", delay)
+	}
+
+	// Not due yet: nothing is requeued.
+	requeued, err := notifications.RetryDueNotifications(app)
+	if err != nil || requeued != 0 {
+		t.Fatalf("expected nothing due, got %d (%v)", requeued, err)
+	}
+
+	makeNotificationRetryDue(t, app, notificationID, 1)
+	requeued, err = notifications.RetryDueNotifications(app)
+	if err != nil || requeued != 1 {
+		t.Fatalf("expected one retry, got %d (%v)", requeued, err)
+	}
+	state = loadNotificationRetryState(t, app, notificationID)
+	if state.Status != "error" || state.Attempts != 2 || len(standIn.payloads) != 2 {
+		t.Fatalf("expected a second failed attempt, got %+v after %d posts", state, len(standIn.payloads))
+	}
+	nextAttempt, _ = time.Parse("2006-01-02 15:04:05.000Z", state.NextAttempt)
+	if delay := time.Until(nextAttempt); delay < 19*time.Minute || delay > 21*time.Minute {
+		t.Fatalf("expected a twenty minute backoff, got %v", delay)
+	}
+
+	// The last allowed attempt fails and dead-letters the notification.
+	makeNotificationRetryDue(t, app, notificationID, 4)
+	if _, err := notifications.RetryDueNotifications(app); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state = loadNotificationRetryState(t, app, notificationID)
+	if state.Status != "dead" || state.Attempts != 5 || state.NextAttempt != "" {
+		t.Fatalf("expected dead after five attempts, got %+v", state)
+	}
+	requeued, err = notifications.RetryDueNotifications(app)
+	if err != nil || requeued != 0 {
+		t.Fatalf("expected dead notifications to be skipped, got %d (%v)", requeued, err)
+	}
+
+	// A manual requeue resets the attempt count and the next run sends it.
+	standIn.status = http.StatusOK
+	if err := notifications.RequeueNotification(app, notificationID); err != nil {
+		t.Fatalf("unexpected requeue error: %v", err)
+	}
+	state = loadNotificationRetryState(t, app, notificationID)
+	if state.Status != "pending" || state.Attempts != 0 {
+		t.Fatalf("expected pending with no attempts, got %+v", state)
+	}
+	if _, err := notifications.RetryDueNotifications(app); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	state = loadNotificationRetryState(t, app, notificationID)
+	if state.Status != "sent" || state.Attempts != 1 || state.Error != "" {
+		t.Fatalf("expected sent after requeue, got %+v", state)
+	}
+
+	if err := notifications.RequeueNotification(app, notificationID); !errors.Is(err, notifications.ErrNotificationNotRequeueable) {
+		t.Fatalf("expected sent notifications to be rejected, got %v", err)
+	}
+}
+
+func TestNotificationRetry_LeavesDeferredNotificationsPending(t *testing.T) {
+	standIn := newWebhookStandIn(t)
+	standIn.status = http.StatusServiceUnavailable
+	app := setupTransportTestApp(t, standIn)
+	defer app.Cleanup()
+	if _, err := notifications.SendNotifications(app); err != nil {
+		t.Fatalf("failed to drain pending notifications: %v", err)
+	}
+	setRecipientTransport(t, app, notifications.TransportWebhook, standIn.URL+"/hook")
+
+	dispatch := func(mode notifications.DeliveryMode) string {
+		t.Helper()
+		id, err := notifications.DispatchNotification(app, notifications.DispatchArgs{
+			TemplateCode: "po_approval_required",
+			RecipientUID: transportRecipientUID,
+			Data: map[string]any{
+				"POId":      "test_po_id",
+				"ActionURL": "https://example.com/pos/test_po_id/details",
+			},
+			Mode: mode,
+		})
+		if err != nil || id == "" {
+			t.Fatalf("expected a notification, got %q (%v)", id, err)
+		}
+		return id
+	}
+	failedID := dispatch(notifications.DeliveryImmediate)
+	deferredID := dispatch(notifications.DeliveryDeferred)
+
+	// Nothing is due: the deferred notification is not sent.
+	requeued, err := notifications.RetryDueNotifications(app)
+	if err != nil || requeued != 0 {
+		t.Fatalf("expected nothing due, got %d (%v)", requeued, err)
+	}
+	if state := loadNotificationRetryState(t, app, deferredID); state.Status != "pending" || state.Attempts != 0 {
+		t.Fatalf("expected the deferred notification to stay pending, got %+v", state)
+	}
+
+	// Only the due notification is retried.
+	standIn.status = http.StatusOK
+	makeNotificationRetryDue(t, app, failedID, 1)
+	requeued, err = notifications.RetryDueNotifications(app)
+	if err != nil || requeued != 1 {
+		t.Fatalf("expected one retry, got %d (%v)", requeued, err)
+	}
+	if state := loadNotificationRetryState(t, app, failedID); state.Status != "sent" {
+		t.Fatalf("expected the retried notification to be sent, got %+v", state)
+	}
+	if state := loadNotificationRetryState(t, app, deferredID); state.Status != "pending" || state.Attempts != 0 {
+		t.Fatalf("expected the deferred notification to stay pending, got %+v", state)
+	}
+}
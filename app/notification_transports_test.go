@@ -0,0 +1,333 @@
+package main
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"tybalt/internal/testutils"
+	"tybalt/notifications"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// =============================================================================
+// Notification Transports
+// =============================================================================
+//
+// A local TLS server stands in for a Teams/Slack incoming webhook. The
+// recipient is author@soup.com and the template is po_approval_required.
+
+const transportRecipientUID = "f2j5a8vk006baub"
+
+type webhookStandIn struct {
+	*httptest.Server
+	mu       sync.Mutex
+	payloads []map[string]string
+	status   int
+}
+
+func newWebhookStandIn(t *testing.T) *webhookStandIn {
+	t.Helper()
+
+	standIn := &webhookStandIn{status: http.StatusOK}
+	standIn.Server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		payload := map[string]string{}
+		_ = json.Unmarshal(body, &payload)
+		standIn.mu.Lock()
+		standIn.payloads = append(standIn.payloads, payload)
+		status := standIn.status
+		standIn.mu.Unlock()
+		w.WriteHeader(status)
+		if status != http.StatusOK {
+			_, _ = w.Write([]byte("channel not found"))
+		}
+	}))
+	t.Cleanup(standIn.Close)
+	return standIn
+}
+
+func setupTransportTestApp(t *testing.T, standIn *webhookStandIn) *tests.TestApp {
+	t.Helper()
+
+	app := setupTestAppWithSynchronousImmediateNotifications(t)
+	notifications.SetWebhookClientForTest(app, standIn.Client())
+	return app
+}
+
+func setRecipientTransport(t *testing.T, app core.App, transport string, webhookURL string) {
+	t.Helper()
+
+	profile, err := app.FindFirstRecordByData("profiles", "uid", transportRecipientUID)
+	if err != nil {
+		t.Fatalf("failed to load recipient profile: %v", err)
+	}
+	profile.Set("notification_transport", transport)
+	profile.Set("notification_webhook_url", webhookURL)
+	if err := app.Save(profile); err != nil {
+		t.Fatalf("failed to save recipient profile: %v", err)
+	}
+}
+
+func setTemplateTransports(t *testing.T, app core.App, code string, transports []string) {
+	t.Helper()
+
+	template, err := app.FindFirstRecordByData("notification_templates", "code", code)
+	if err != nil {
+		t.Fatalf("failed to load template %s: %v", code, err)
+	}
+	template.Set("transports", transports)
+	if err := app.Save(template); err != nil {
+		t.Fatalf("failed to save template %s: %v", code, err)
+	}
+}
+
+func dispatchTransportTestNotification(t *testing.T, app core.App) (status string, transport string, errorText string) {
+	t.Helper()
+
+	notificationID, err := notifications.DispatchNotification(app, notifications.DispatchArgs{
+		TemplateCode: "po_approval_required",
+		RecipientUID: transportRecipientUID,
+		Data: map[string]any{
+			"POId":      "test_po_id",
+			"ActionURL": "https://example.com/pos/test_po_id/details",
+		},
+		Mode: notifications.DeliveryImmediate,
+	})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if notificationID == "" {
+		t.Fatal("expected a notification to be created")
+	}
+
+	var row struct {
+		Status    string `db:"status"`
+		Transport string `db:"transport"`
+		Error     string `db:"error"`
+	}
+	if err := app.DB().NewQuery("SELECT status, transport, error FROM notifications WHERE id = {:id}").
+		Bind(dbx.Params{"id": notificationID}).One(&row); err != nil {
+		t.Fatalf("failed to load notification: %v", err)
+	}
+	return row.Status, row.Transport, row.Error
+}
+
+func TestNotificationTransport_DefaultsToEmail(t *testing.T) {
+	standIn := newWebhookStandIn(t)
+	app := setupTransportTestApp(t, standIn)
+	defer app.Cleanup()
+
+	before := app.TestMailer.TotalSend()
+	status, transport, _ := dispatchTransportTestNotification(t, app)
+	if status != "sent" || transport != notifications.TransportEmail {
+		t.Fatalf("expected sent by email, got %s by %q", status, transport)
+	}
+	if app.TestMailer.TotalSend() != before+1 {
+		t.Fatal("expected one email to be sent")
+	}
+	if len(standIn.payloads) != 0 {
+		t.Fatalf("expected no webhook posts, got %d", len(standIn.payloads))
+	}
+}
+
+func TestNotificationTransport_WebhookPreferenceSendsWebhook(t *testing.T) {
+	standIn := newWebhookStandIn(t)
+	app := setupTransportTestApp(t, standIn)
+	defer app.Cleanup()
+	setRecipientTransport(t, app, notifications.TransportWebhook, standIn.URL+"/hook")
+
+	before := app.TestMailer.TotalSend()
+	status, transport, errorText := dispatchTransportTestNotification(t, app)
+	if status != "sent" || transport != notifications.TransportWebhook {
+		t.Fatalf("expected sent by webhook, got %s by %q (%s)", status, transport, errorText)
+	}
+	if app.TestMailer.TotalSend() != before {
+		t.Fatal("expected no email to be sent")
+	}
+	if len(standIn.payloads) != 1 {
+		t.Fatalf("expected one webhook post, got %d", len(standIn.payloads))
+	}
+	if text := standIn.payloads[0]["text"]; !strings.Contains(text, "test_po_id") {
+		t.Fatalf("expected rendered text in webhook payload, got %q", text)
+	}
+}
+
+func TestNotificationTransport_WebhookFailureRecordsError(t *testing.T) {
+	standIn := newWebhookStandIn(t)
+	standIn.status = http.StatusNotFound
+	app := setupTransportTestApp(t, standIn)
+	defer app.Cleanup()
+	setRecipientTransport(t, app, notifications.TransportWebhook, standIn.URL+"/hook")
+
+	status, transport, errorText := dispatchTransportTestNotification(t, app)
+	if status != "error" || transport != notifications.TransportWebhook {
+		t.Fatalf("expected error by webhook, got %s by %q", status, transport)
+	}
+	if !strings.Contains(errorText, "status 404") || !strings.Contains(errorText, "channel not found") {
+		t.Fatalf("expected webhook status in error, got %q", errorText)
+	}
+}
+
+func TestNotificationTransport_TemplateRestrictsTransport(t *testing.T) {
+	standIn := newWebhookStandIn(t)
+	app := setupTransportTestApp(t, standIn)
+	defer app.Cleanup()
+	setRecipientTransport(t, app, notifications.TransportWebhook, standIn.URL+"/hook")
+	setTemplateTransports(t, app, "po_approval_required", []string{notifications.TransportEmail})
+
+	status, transport, _ := dispatchTransportTestNotification(t, app)
+	if status != "sent" || transport != notifications.TransportEmail {
+		t.Fatalf("expected template to force email, got %s by %q", status, transport)
+	}
+	if len(standIn.payloads) != 0 {
+		t.Fatalf("expected no webhook posts, got %d", len(standIn.payloads))
+	}
+}
+
+func TestNotificationTransport_NoUsableTransportRecordsError(t *testing.T) {
+	standIn := newWebhookStandIn(t)
+	app := setupTransportTestApp(t, standIn)
+	defer app.Cleanup()
+	setTemplateTransports(t, app, "po_approval_required", []string{notifications.TransportWebhook})
+
+	before := app.TestMailer.TotalSend()
+	status, transport, errorText := dispatchTransportTestNotification(t, app)
+	if status != "error" || transport != "" {
+		t.Fatalf("expected an error without a transport, got %s by %q", status, transport)
+	}
+	if !strings.Contains(errorText, "no allowed transport") {
+		t.Fatalf("unexpected error %q", errorText)
+	}
+	if app.TestMailer.TotalSend() != before {
+		t.Fatal("expected no email to be sent")
+	}
+}
+
+func TestProfileNotificationTransportSettings(t *testing.T) {
+	ownerToken, err := testutils.GenerateRecordToken("users", "author@soup.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherToken, err := testutils.GenerateRecordToken("users", "time@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var profileID string
+	{
+		app := testutils.SetupTestApp(t)
+		profile, err := app.FindFirstRecordByData("profiles", "uid", transportRecipientUID)
+		if err != nil {
+			t.Fatalf("failed to load profile: %v", err)
+		}
+		profileID = profile.Id
+		app.Cleanup()
+	}
+
+	withWebhookURL := func(tb testing.TB) *tests.TestApp {
+		app := testutils.SetupTestApp(tb)
+		profile, err := app.FindRecordById("profiles", profileID)
+		if err != nil {
+			tb.Fatalf("failed to load profile: %v", err)
+		}
+		profile.Set("notification_transport", "webhook")
+		profile.Set("notification_webhook_url", "https://hooks.example.com/secret")
+		if err := app.Save(profile); err != nil {
+			tb.Fatalf("failed to save profile: %v", err)
+		}
+		return app
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:           "owner selects the webhook transport",
+			Method:         http.MethodPatch,
+			URL:            "/api/collections/profiles/records/" + profileID,
+			Body:           strings.NewReader(`{"notification_transport":"webhook","notification_webhook_url":"https://hooks.example.com/abc"}`),
+			Headers:        map[string]string{"Authorization": ownerToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"notification_transport":"webhook"`,
+				`"notification_webhook_url":"https://hooks.example.com/abc"`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnRecordUpdate":        1,
+				"OnRecordUpdateRequest": 1,
+				"OnRecordEnrich":        1,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "webhook transport requires a URL",
+			Method:         http.MethodPatch,
+			URL:            "/api/collections/profiles/records/" + profileID,
+			Body:           strings.NewReader(`{"notification_transport":"webhook"}`),
+			Headers:        map[string]string{"Authorization": ownerToken},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedContent: []string{
+				`"notification_webhook_url":{"code":"webhook_url_required"`,
+			},
+			ExpectedEvents: map[string]int{"OnRecordUpdateRequest": 1},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "webhook URL must be https",
+			Method:         http.MethodPatch,
+			URL:            "/api/collections/profiles/records/" + profileID,
+			Body:           strings.NewReader(`{"notification_transport":"webhook","notification_webhook_url":"http://hooks.example.com/abc"}`),
+			Headers:        map[string]string{"Authorization": ownerToken},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedContent: []string{
+				`"notification_webhook_url":{"code":"invalid_webhook_url"`,
+			},
+			ExpectedEvents: map[string]int{"OnRecordUpdateRequest": 1},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "other users do not see the webhook URL",
+			Method:         http.MethodGet,
+			URL:            "/api/collections/profiles/records/" + profileID,
+			Headers:        map[string]string{"Authorization": otherToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"notification_transport":"webhook"`,
+			},
+			NotExpectedContent: []string{
+				"notification_webhook_url",
+				"hooks.example.com",
+			},
+			ExpectedEvents: map[string]int{
+				"OnRecordViewRequest": 1,
+				"OnRecordEnrich":      1,
+			},
+			TestAppFactory: withWebhookURL,
+		},
+		{
+			Name:           "owner sees their webhook URL",
+			Method:         http.MethodGet,
+			URL:            "/api/collections/profiles/records/" + profileID,
+			Headers:        map[string]string{"Authorization": ownerToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"notification_webhook_url":"https://hooks.example.com/secret"`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnRecordViewRequest": 1,
+				"OnRecordEnrich":      1,
+			},
+			TestAppFactory: withWebhookURL,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
@@ -0,0 +1,159 @@
+package notifications
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// QueueTimesheetApprovalEscalations escalates submitted timesheets that have
+// waited for approval for at least the configured number of business days.
+// Each timesheet is escalated once, to the owner's alternate_manager or, when
+// they have none, to the approver's manager. The recipient must be an active
+// tapr holder other than the owner and the approver; timesheets with no such
+// recipient are left alone.
+//
+// approval_escalated and approval_escalated_to are set on the timesheet, which
+// lets the recipient see and approve it, and the recipient is notified.
+//
+// When send is true, it immediately drains the pending queue after creation;
+// otherwise notifications remain pending for later delivery.
+func QueueTimesheetApprovalEscalations(app core.App, send bool) error {
+	now := time.Now().UTC()
+	businessDays := utilities.GetTimesheetApprovalEscalationBusinessDays(app)
+	if businessDays == 0 {
+		return nil
+	}
+
+	cutoff, err := businessDaysBefore(app, now, businessDays)
+	if err != nil {
+		return fmt.Errorf("error computing timesheet approval escalation cutoff: %v", err)
+	}
+
+	rows := make([]dbx.NullStringMap, 0)
+	err = app.DB().NewQuery(`
+		SELECT
+			ts.id AS time_sheet_id,
+			ts.week_ending AS week_ending,
+			TRIM(COALESCE(p.given_name, '') || ' ' || COALESCE(p.surname, '')) AS employee_name,
+			TRIM(COALESCE(ap.given_name, '') || ' ' || COALESCE(ap.surname, '')) AS approver_name,
+			CASE
+				WHEN ` + escalationRecipientCondition("p.alternate_manager") + ` THEN p.alternate_manager
+				WHEN ` + escalationRecipientCondition("ap.manager") + ` THEN ap.manager
+				ELSE ''
+			END AS recipient_uid
+		FROM time_sheets ts
+		JOIN profiles p ON p.uid = ts.uid
+		LEFT JOIN profiles ap ON ap.uid = ts.approver
+		WHERE ts.submitted = 1
+		  AND ts.approved = ''
+		  AND ts.committed = ''
+		  AND ts.rejected = ''
+		  AND ts.approver != ''
+		  AND ts.approval_escalated = ''
+		  AND date(ts.created) <= {:cutoff}
+		ORDER BY ts.created
+	`).Bind(dbx.Params{"cutoff": cutoff}).All(&rows)
+	if err != nil {
+		return fmt.Errorf("error querying timesheets awaiting approval escalation: %v", err)
+	}
+
+	escalatedCount := 0
+	for _, row := range rows {
+		timeSheetID := rowStringValue(row, "time_sheet_id")
+		recipientUID := rowStringValue(row, "recipient_uid")
+		if recipientUID == "" {
+			continue
+		}
+
+		timeSheet, err := app.FindRecordById("time_sheets", timeSheetID)
+		if err != nil {
+			app.Logger().Error("error loading timesheet for approval escalation", "time_sheet", timeSheetID, "error", err)
+			continue
+		}
+		timeSheet.Set("approval_escalated", now)
+		timeSheet.Set("approval_escalated_to", recipientUID)
+		if err := app.Save(timeSheet); err != nil {
+			app.Logger().Error("error recording timesheet approval escalation", "time_sheet", timeSheetID, "error", err)
+			continue
+		}
+		escalatedCount++
+
+		if _, err := DispatchNotification(app, DispatchArgs{
+			TemplateCode: "timesheet_approval_escalation",
+			RecipientUID: recipientUID,
+			Data: map[string]any{
+				"EmployeeName": rowStringValue(row, "employee_name"),
+				"ApproverName": rowStringValue(row, "approver_name"),
+				"WeekEnding":   rowStringValue(row, "week_ending"),
+				"BusinessDays": strconv.Itoa(businessDays),
+				"ActionURL":    BuildActionURL(app, "/time/sheets/pending"),
+			},
+			System: true,
+			Mode:   DeliveryDeferred,
+		}); err != nil {
+			app.Logger().Error("error creating timesheet approval escalation notification", "time_sheet", timeSheetID, "recipient", recipientUID, "error", err)
+		}
+	}
+
+	app.Logger().Info("queued timesheet approval escalations", "escalated_count", escalatedCount, "cutoff", cutoff)
+
+	return sendQueuedIfRequested(app, send, "sent timesheet approval escalation notifications")
+}
+
+// escalationRecipientCondition is true when the user that uidExpr evaluates
+// to may receive an escalated timesheet: an active tapr holder who is neither
+// the timesheet's owner (ts.uid) nor its approver (ts.approver).
+func escalationRecipientCondition(uidExpr string) string {
+	return `COALESCE(` + uidExpr + `, '') NOT IN ('', ts.uid, ts.approver)
+				AND EXISTS (SELECT 1 FROM admin_profiles rap WHERE rap.uid = ` + uidExpr + ` AND rap.active = 1)
+				AND EXISTS (
+					SELECT 1 FROM user_claims ruc
+					JOIN claims rc ON rc.id = ruc.cid
+					WHERE ruc.uid = ` + uidExpr + ` AND rc.name = 'tapr'
+				)`
+}
+
+// businessDaysBefore returns the date (YYYY-MM-DD) that is days business days
+// before now. Business days are weekdays other than company-wide statutory
+// holidays; holidays observed only by some branches are not skipped.
+func businessDaysBefore(app core.App, now time.Time, days int) (string, error) {
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+
+	// Every run of seven days holds five weekdays, so this window always
+	// contains enough business days unless it is mostly holidays.
+	windowStart := today.AddDate(0, 0, -(days*2 + 14))
+	var holidays []struct {
+		Date string `db:"date"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT h.date
+		FROM statutory_holidays h
+		WHERE h.date BETWEEN {:start} AND {:end}
+		  AND json_array_length(CASE WHEN json_valid(h.branches) THEN h.branches ELSE '[]' END) = 0
+	`).Bind(dbx.Params{
+		"start": windowStart.Format(time.DateOnly),
+		"end":   today.Format(time.DateOnly),
+	}).All(&holidays)
+	if err != nil {
+		return "", err
+	}
+	isHoliday := map[string]bool{}
+	for _, holiday := range holidays {
+		isHoliday[holiday.Date] = true
+	}
+
+	day := today
+	for remaining := days; remaining > 0; {
+		day = day.AddDate(0, 0, -1)
+		if day.Weekday() == time.Saturday || day.Weekday() == time.Sunday || isHoliday[day.Format(time.DateOnly)] {
+			continue
+		}
+		remaining--
+	}
+	return day.Format(time.DateOnly), nil
+}
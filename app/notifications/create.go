@@ -11,25 +11,43 @@ import (
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
 )
 
 // DispatchNotification creates a notification record and dispatches it according
 // to args.Mode.
 //
 // Modes:
-//   - DeliveryDeferred: create pending notification only.
+//   - DeliveryDeferred: create pending notification only. System notifications
+//     for recipients who opted into the daily digest are created with the
+//     digest status instead and delivered by SendNotificationDigests.
 //   - DeliveryImmediate: create notification, then attempt targeted send.
 //
+// The recipient's notification_preferences row for the template overrides
+// both: digest holds the notification for SendNotificationDigests, immediate
+// creates it pending, and mute skips it unless the template is a
+// non-mutable system notification.
+//
 // Returns the created notification ID, or an empty string when creation is
-// intentionally skipped (for example, disabled feature flag or fail-closed
-// config read failure). In immediate mode, send errors are logged but not
+// intentionally skipped (for example, disabled feature flag, muted template or
+// fail-closed config read failure). In immediate mode, send errors are logged but not
 // returned to preserve non-blocking business behavior.
 func DispatchNotification(app core.App, args DispatchArgs) (notificationID string, err error) {
 	if args.Mode != DeliveryDeferred && args.Mode != DeliveryImmediate {
 		return "", fmt.Errorf("invalid delivery mode %q", args.Mode)
 	}
 
-	notificationID, err = createNotificationWithUser(app, args.TemplateCode, args.RecipientUID, args.Data, args.System, args.ActorUID)
+	status, skip := deliveryStatus(app, args)
+	if skip {
+		app.Logger().Info(
+			"notification creation skipped because the recipient muted the template",
+			"template_code", args.TemplateCode,
+			"recipient_uid", args.RecipientUID,
+		)
+		return "", nil
+	}
+
+	notificationID, err = createNotificationWithUser(app, args.TemplateCode, args.RecipientUID, args.Data, args.System, args.ActorUID, args.Attachments, status)
 	if err != nil {
 		return "", err
 	}
@@ -48,7 +66,7 @@ func DispatchNotification(app core.App, args DispatchArgs) (notificationID strin
 	return notificationID, nil
 }
 
-func createNotificationWithUser(app core.App, templateCode string, recipientUID string, data map[string]any, system bool, actorUID string) (string, error) {
+func createNotificationWithUser(app core.App, templateCode string, recipientUID string, data map[string]any, system bool, actorUID string, attachments []*filesystem.File, status string) (string, error) {
 	enabled, err := utilities.IsNotificationFeatureEnabled(app, templateCode)
 	if err != nil {
 		app.Logger().Error(
@@ -93,11 +111,14 @@ func createNotificationWithUser(app core.App, templateCode string, recipientUID
 	notificationRecord.Set("template", notificationTemplate.Get("id"))
 	notificationRecord.Set("subject", notificationTemplate.Get("subject"))
 	notificationRecord.Set("text_email", notificationTemplate.Get("text_email"))
-	notificationRecord.Set("status", "pending")
+	notificationRecord.Set("status", status)
 	notificationRecord.Set("system_notification", system)
 	if actorUID != "" {
 		notificationRecord.Set("user", actorUID)
 	}
+	if len(attachments) > 0 {
+		notificationRecord.Set("attachments", attachments)
+	}
 
 	if len(data) > 0 {
 		dataJSON, err := json.Marshal(data)
@@ -124,3 +145,18 @@ func createNotificationWithUser(app core.App, templateCode string, recipientUID
 
 	return notificationRecord.Id, nil
 }
+
+// recipientWantsDigest reports whether the recipient opted into the daily
+// digest. Profile lookup failures fall back to individual delivery.
+func recipientWantsDigest(app core.App, recipientUID string) bool {
+	var result struct {
+		Digest bool `db:"notification_digest"`
+	}
+	err := app.DB().NewQuery("SELECT notification_digest FROM profiles WHERE uid = {:uid}").Bind(dbx.Params{
+		"uid": recipientUID,
+	}).One(&result)
+	if err != nil {
+		return false
+	}
+	return result.Digest
+}
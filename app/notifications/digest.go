@@ -0,0 +1,129 @@
+// digest.go contains the daily digest roll-up.
+//
+// Notifications held with the digest status are rendered individually and
+// combined into one notification_digest notification per recipient, which is
+// then sent through the normal send engine.
+package notifications
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const digestTemplateCode = "notification_digest"
+
+// SendNotificationDigests rolls every notification held for a digest into one
+// summary notification per recipient and sends it. Rolled-up notifications
+// are marked sent and linked to the summary through their digest field.
+//
+// Held notifications that fail to render are marked error and left out of the
+// summary. When the notification_digest feature is disabled the held
+// notifications are released and sent individually so nothing is lost.
+//
+// It returns the number of digests sent.
+func SendNotificationDigests(app core.App) (int, error) {
+	var held []Notification
+	if err := app.DB().NewQuery(notificationSelectQuery + `
+		WHERE n.status = 'digest'
+		ORDER BY n.recipient, n.created`).All(&held); err != nil {
+		return 0, fmt.Errorf("error fetching held notifications: %v", err)
+	}
+
+	byRecipient := map[string][]Notification{}
+	recipients := []string{}
+	for _, notification := range held {
+		if _, ok := byRecipient[notification.RecipientUID]; !ok {
+			recipients = append(recipients, notification.RecipientUID)
+		}
+		byRecipient[notification.RecipientUID] = append(byRecipient[notification.RecipientUID], notification)
+	}
+
+	sentCount := 0
+	for _, recipientUID := range recipients {
+		items := []map[string]any{}
+		ids := []any{}
+		for _, notification := range byRecipient[recipientUID] {
+			text, err := renderNotificationText(app, &notification)
+			if err != nil {
+				app.Logger().Error(
+					"error rendering held notification for digest",
+					"notification_id", notification.Id,
+					"error", err,
+				)
+				updateNotificationStatus(app, notification, err)
+				continue
+			}
+			items = append(items, map[string]any{
+				"Subject": notification.Subject,
+				"Text":    text,
+			})
+			ids = append(ids, notification.Id)
+		}
+		if len(items) == 0 {
+			continue
+		}
+
+		// The digest is created pending rather than through DispatchNotification
+		// so it is never itself held for a digest, and so the held notifications
+		// are linked to it before it is sent.
+		digestID, err := createNotificationWithUser(app, digestTemplateCode, recipientUID, map[string]any{
+			"Count":     len(items),
+			"Items":     items,
+			"ActionURL": BuildActionURL(app, "/"),
+		}, true, "", nil, "pending")
+		if err != nil {
+			return sentCount, err
+		}
+
+		if digestID == "" {
+			if err := releaseHeldNotifications(app, ids); err != nil {
+				return sentCount, err
+			}
+			continue
+		}
+
+		if _, err := app.NonconcurrentDB().Update("notifications", dbx.Params{
+			"status":         "sent",
+			"digest":         digestID,
+			"status_updated": time.Now().UTC().Format("2006-01-02 15:04:05.000Z"),
+		}, dbx.In("id", ids...)).Execute(); err != nil {
+			return sentCount, fmt.Errorf("error linking held notifications to digest %s: %v", digestID, err)
+		}
+		if err := SendNotificationByID(app, digestID); err != nil {
+			app.Logger().Error(
+				"failed to send notification digest",
+				"notification_id", digestID,
+				"recipient_uid", recipientUID,
+				"error", err,
+			)
+			continue
+		}
+		sentCount++
+	}
+
+	app.Logger().Info(
+		"sent notification digests",
+		"held_count", len(held),
+		"digest_count", sentCount,
+	)
+	return sentCount, nil
+}
+
+// releaseHeldNotifications returns held notifications to pending and sends
+// them individually.
+func releaseHeldNotifications(app core.App, ids []any) error {
+	if _, err := app.NonconcurrentDB().Update("notifications", dbx.Params{
+		"status": "pending",
+	}, dbx.In("id", ids...)).Execute(); err != nil {
+		return fmt.Errorf("error releasing held notifications: %v", err)
+	}
+	for _, id := range ids {
+		if err := SendNotificationByID(app, id.(string)); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -88,6 +88,9 @@ func getProfileDisplayName(app core.App, uid string) (name string, profile *core
 	return name, profile, nil
 }
 
+// createAndSendToRecipients dispatches an immediate notification to each
+// recipient. Recipient preferences are applied per recipient by
+// DispatchNotification, so muted recipients are skipped and are not counted.
 func createAndSendToRecipients(
 	app core.App,
 	templateCode string,
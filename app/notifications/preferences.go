@@ -0,0 +1,178 @@
+// preferences.go contains per-user, per-template delivery preferences.
+//
+// A notification_preferences row lets a recipient mute a template, hold it for
+// the daily digest, or always receive it immediately. Only templates marked
+// mutable can be muted; every other template is a system notification and a
+// mute preference for it is ignored. Mutable notifications carry a signed
+// one-click unsubscribe link that writes a mute preference.
+package notifications
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/url"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/security"
+)
+
+const (
+	PreferenceMute      = "mute"
+	PreferenceDigest    = "digest"
+	PreferenceImmediate = "immediate"
+)
+
+const (
+	unsubscribeTokenType     = "notification_unsubscribe"
+	unsubscribeTokenDuration = 90 * 24 * time.Hour
+)
+
+// ErrTemplateNotMutable is returned when a mute preference targets a system
+// notification template.
+var ErrTemplateNotMutable = errors.New("system notifications cannot be muted")
+
+// ErrInvalidUnsubscribeToken is returned by ParseUnsubscribeToken for tokens
+// that are malformed, expired or not signed by this app.
+var ErrInvalidUnsubscribeToken = errors.New("invalid or expired unsubscribe link")
+
+// recipientPreference returns the recipient's preference for a template and
+// whether the template is mutable. An empty preference means no row exists.
+// Lookup failures fall back to the profile defaults.
+func recipientPreference(app core.App, recipientUID string, templateCode string) (preference string, mutable bool) {
+	var result struct {
+		Mode    string `db:"mode"`
+		Mutable bool   `db:"mutable"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT COALESCE(np.mode, '') AS mode, COALESCE(nt.mutable, 0) AS mutable
+		FROM notification_templates nt
+		LEFT JOIN notification_preferences np ON np.template = nt.id AND np.uid = {:uid}
+		WHERE nt.code = {:code}
+	`).Bind(dbx.Params{
+		"uid":  recipientUID,
+		"code": templateCode,
+	}).One(&result)
+	if err != nil {
+		return "", false
+	}
+	return result.Mode, result.Mutable
+}
+
+// deliveryStatus decides the initial status of a notification for the
+// recipient, or skip when the recipient muted a mutable template.
+//
+// An explicit digest or immediate preference wins. Otherwise deferred system
+// notifications are held for recipients who opted into the daily digest on
+// their profile.
+func deliveryStatus(app core.App, args DispatchArgs) (status string, skip bool) {
+	preference, mutable := recipientPreference(app, args.RecipientUID, args.TemplateCode)
+	switch preference {
+	case PreferenceMute:
+		if mutable {
+			return "", true
+		}
+	case PreferenceDigest:
+		return "digest", false
+	case PreferenceImmediate:
+		return "pending", false
+	}
+
+	if args.Mode == DeliveryDeferred && args.System && recipientWantsDigest(app, args.RecipientUID) {
+		return "digest", false
+	}
+	return "pending", false
+}
+
+// SetNotificationPreference creates or updates the recipient's preference for
+// a template. Muting a template that is not mutable returns
+// ErrTemplateNotMutable and an unknown template returns sql.ErrNoRows.
+func SetNotificationPreference(app core.App, recipientUID string, templateCode string, mode string) error {
+	template, err := app.FindFirstRecordByFilter("notification_templates", "code = {:code}", dbx.Params{
+		"code": templateCode,
+	})
+	if err != nil {
+		return err
+	}
+	if mode == PreferenceMute && !template.GetBool("mutable") {
+		return ErrTemplateNotMutable
+	}
+
+	record, err := app.FindFirstRecordByFilter("notification_preferences", "uid = {:uid} && template = {:template}", dbx.Params{
+		"uid":      recipientUID,
+		"template": template.Id,
+	})
+	if errors.Is(err, sql.ErrNoRows) {
+		collection, err := app.FindCollectionByNameOrId("notification_preferences")
+		if err != nil {
+			return fmt.Errorf("error finding notification_preferences collection: %w", err)
+		}
+		record = core.NewRecord(collection)
+		record.Set("uid", recipientUID)
+		record.Set("template", template.Id)
+	} else if err != nil {
+		return fmt.Errorf("error loading notification preference: %w", err)
+	}
+
+	record.Set("mode", mode)
+	if err := app.Save(record); err != nil {
+		return fmt.Errorf("error saving notification preference: %w", err)
+	}
+	return nil
+}
+
+// unsubscribeSigningKey derives the unsubscribe token key from the users
+// collection's auth token secret, so rotating that secret also invalidates
+// outstanding unsubscribe links.
+func unsubscribeSigningKey(app core.App) (string, error) {
+	users, err := app.FindCollectionByNameOrId("users")
+	if err != nil {
+		return "", err
+	}
+	return users.AuthToken.Secret + unsubscribeTokenType, nil
+}
+
+// BuildUnsubscribeURL returns a signed one-click link, built with
+// BuildActionURL, that mutes templateCode for the recipient. It returns an
+// empty string when the app URL is not configured or signing fails.
+func BuildUnsubscribeURL(app core.App, recipientUID string, templateCode string) string {
+	if appURL(app) == "" {
+		return ""
+	}
+	key, err := unsubscribeSigningKey(app)
+	if err != nil {
+		app.Logger().Error("error loading unsubscribe signing key", "error", err)
+		return ""
+	}
+	token, err := security.NewJWT(map[string]any{
+		"type":     unsubscribeTokenType,
+		"uid":      recipientUID,
+		"template": templateCode,
+	}, key, unsubscribeTokenDuration)
+	if err != nil {
+		app.Logger().Error("error signing unsubscribe token", "error", err)
+		return ""
+	}
+	return BuildActionURL(app, "/api/notifications/unsubscribe?token="+url.QueryEscape(token))
+}
+
+// ParseUnsubscribeToken verifies a token from BuildUnsubscribeURL and returns
+// the recipient and template code it was issued for.
+func ParseUnsubscribeToken(app core.App, token string) (recipientUID string, templateCode string, err error) {
+	key, err := unsubscribeSigningKey(app)
+	if err != nil {
+		return "", "", err
+	}
+	claims, err := security.ParseJWT(token, key)
+	if err != nil {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	recipientUID, _ = claims["uid"].(string)
+	templateCode, _ = claims["template"].(string)
+	if claims["type"] != unsubscribeTokenType || recipientUID == "" || templateCode == "" {
+		return "", "", ErrInvalidUnsubscribeToken
+	}
+	return recipientUID, templateCode, nil
+}
@@ -6,6 +6,7 @@ package notifications
 
 import (
 	"fmt"
+	"strings"
 	"tybalt/utilities"
 
 	"github.com/pocketbase/pocketbase/core"
@@ -261,3 +262,49 @@ func QueueTimesheetSharedNotifications(app core.App, timesheet *core.Record, sha
 
 	return nil
 }
+
+// QueueCardStatementUnmatchedNotifications creates an immediate notification
+// asking the cardholder of a card_statements record to submit expenses for
+// its unmatched lines.
+//
+// One notification lists every line passed in. It returns the number of
+// notifications created, which is zero when lines is empty or the cardholder
+// muted the template.
+func QueueCardStatementUnmatchedNotifications(app core.App, statement *core.Record, lines []*core.Record, reminderUID string) (int, error) {
+	if len(lines) == 0 {
+		return 0, nil
+	}
+
+	summaries := make([]string, 0, len(lines))
+	for _, line := range lines {
+		summaries = append(summaries, fmt.Sprintf("%s  %.2f  %s", line.GetString("date"), line.GetFloat("amount"), line.GetString("description")))
+	}
+
+	data := map[string]any{
+		"CardLast4":   statement.GetString("cc_last_4_digits"),
+		"PeriodStart": statement.GetString("period_start"),
+		"PeriodEnd":   statement.GetString("period_end"),
+		"LineCount":   len(lines),
+		"Lines":       strings.Join(summaries, "\n"),
+		"ActionURL":   BuildActionURL(app, "/expenses/list"),
+	}
+
+	createdCount := createAndSendToRecipients(
+		app,
+		"card_statement_unmatched",
+		[]string{statement.GetString("cardholder")},
+		data,
+		false,
+		reminderUID,
+		map[string]any{"card_statement_id": statement.Id},
+	)
+
+	app.Logger().Info(
+		"created card statement unmatched notifications",
+		"card_statement_id", statement.Id,
+		"line_count", len(lines),
+		"created_count", createdCount,
+	)
+
+	return createdCount, nil
+}
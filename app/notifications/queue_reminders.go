@@ -1,8 +1,9 @@
 // queue_reminders.go contains batched reminder queueing functions.
 //
 // It provides a generic ReminderJob engine for querying recipients,
-// deduplicating pending/inflight reminders, creating notification records,
-// and optionally sending queued notifications.
+// deduplicating unsent reminders (pending, inflight, held for a digest or
+// awaiting retry), creating notification records, and optionally sending
+// queued notifications.
 package notifications
 
 import (
@@ -97,12 +98,84 @@ func QueueTimesheetSubmissionReminders(app core.App, send bool) error {
 	return QueueTimesheetSubmissionRemindersForWeek(app, previousWeekEnding, send)
 }
 
+// timesheetSubmissionEscalationThreshold is the number of sent submission
+// reminders for a week after which the user's manager is notified.
+const timesheetSubmissionEscalationThreshold = 2
+
 // QueueTimesheetSubmissionRemindersForWeek queues reminders for users expected
 // to submit a timesheet but missing a submission for the specified week ending.
 //
-// Dedupe is week-based: it skips recipients that already have a pending or
-// inflight reminder for the same WeekEnding payload.
+// Expectation uses the same classification as the timesheet tracking missing
+// list (utilities.TimesheetExpectedCondition), so users not expected to submit
+// and users with a missing-time-sheet exception for the week are never
+// reminded. Inactive users are not reminded either
+// (utilities.TimesheetReminderCondition).
+//
+// Users who have already been sent timesheetSubmissionEscalationThreshold
+// reminders for the week are escalated once to their active manager through
+// timesheet_submission_escalation.
+//
+// Dedupe is week-based: it skips recipients that already have an unsent
+// reminder for the same WeekEnding payload.
 func QueueTimesheetSubmissionRemindersForWeek(app core.App, weekEnding string, send bool) error {
+	escalation := ReminderJob{
+		Name:         "timesheet submission escalations",
+		TemplateCode: "timesheet_submission_escalation",
+		Query: `
+			SELECT
+				p.manager AS recipient_uid,
+				u.id AS employee_uid,
+				TRIM(COALESCE(p.given_name, '') || ' ' || COALESCE(p.surname, '')) AS employee_name,
+				COUNT(r.id) AS reminder_count
+			FROM users u
+			LEFT JOIN time_sheets ts ON ts.uid = u.id AND ts.week_ending = {:week_ending} AND ts.submitted = 1
+			JOIN profiles p ON p.uid = u.id` + utilities.TimesheetExpectationJoins + `
+			JOIN admin_profiles mgr_ap ON mgr_ap.uid = p.manager AND mgr_ap.active = 1
+			JOIN notification_templates rt ON rt.code = 'timesheet_submission_reminder'
+			JOIN notifications r ON r.recipient = u.id
+				AND r.template = rt.id
+				AND r.status = 'sent'
+				AND json_extract(r.data, '$.WeekEnding') = {:week_ending}
+			WHERE ts.id IS NULL
+			  AND ` + utilities.TimesheetReminderCondition + `
+			  AND p.manager != ''
+			  AND p.manager != u.id
+			  AND NOT EXISTS (
+				SELECT 1
+				FROM notifications en
+				JOIN notification_templates et ON et.id = en.template
+				WHERE et.code = 'timesheet_submission_escalation'
+				  AND json_extract(en.data, '$.EmployeeUID') = u.id
+				  AND json_extract(en.data, '$.WeekEnding') = {:week_ending}
+			  )
+			GROUP BY u.id
+			HAVING COUNT(r.id) >= {:threshold}
+		`,
+		QueryParams: dbx.Params{
+			"week_ending": weekEnding,
+			"threshold":   timesheetSubmissionEscalationThreshold,
+		},
+		RecipientCol: "recipient_uid",
+		BuildData: func(row dbx.NullStringMap) map[string]any {
+			return map[string]any{
+				"EmployeeUID":   rowStringValue(row, "employee_uid"),
+				"EmployeeName":  rowStringValue(row, "employee_name"),
+				"ReminderCount": rowStringValue(row, "reminder_count"),
+				"WeekEnding":    weekEnding,
+				"ActionURL":     BuildActionURL(app, "/time/tracking/"+weekEnding),
+			}
+		},
+		LogFields: map[string]any{
+			"week_ending": weekEnding,
+		},
+	}
+
+	// Escalate before queueing this run's reminders so only reminders sent by
+	// earlier runs count.
+	if err := queueReminderJob(app, escalation, false); err != nil {
+		return err
+	}
+
 	job := ReminderJob{
 		Name:         "timesheet submission reminders",
 		TemplateCode: "timesheet_submission_reminder",
@@ -110,10 +183,9 @@ func QueueTimesheetSubmissionRemindersForWeek(app core.App, weekEnding string, s
 			SELECT DISTINCT
 				u.id AS recipient_uid
 			FROM users u
-			LEFT JOIN time_sheets ts ON ts.uid = u.id AND ts.week_ending = {:week_ending} AND ts.submitted = 1
-			LEFT JOIN admin_profiles ap ON ap.uid = u.id
+			LEFT JOIN time_sheets ts ON ts.uid = u.id AND ts.week_ending = {:week_ending} AND ts.submitted = 1` + utilities.TimesheetExpectationJoins + `
 			WHERE ts.id IS NULL
-			  AND COALESCE(ap.time_sheet_expected, 0) = 1
+			  AND ` + utilities.TimesheetReminderCondition + `
 		`,
 		QueryParams:  dbx.Params{"week_ending": weekEnding},
 		RecipientCol: "recipient_uid",
@@ -141,9 +213,10 @@ func QueueTimesheetSubmissionRemindersForWeek(app core.App, weekEnding string, s
 }
 
 // QueueTimesheetApprovalReminders queues reminders for managers who currently
-// have submitted timesheets awaiting approval.
+// have submitted timesheets awaiting approval. While a manager has an active
+// approval delegation the reminder goes to their delegate instead.
 //
-// Dedupe uses a rolling 24-hour window on pending/inflight reminders per
+// Dedupe uses a rolling 24-hour window on unsent reminders per
 // recipient and template.
 func QueueTimesheetApprovalReminders(app core.App, send bool) error {
 	job := ReminderJob{
@@ -151,7 +224,7 @@ func QueueTimesheetApprovalReminders(app core.App, send bool) error {
 		TemplateCode: "timesheet_approval_reminder",
 		Query: `
 			SELECT DISTINCT
-				ts.approver AS manager_uid
+				` + utilities.ApprovalRecipientExpr("ts.approver", "ts.uid", utilities.ApprovalDelegationScopeTime) + ` AS manager_uid
 			FROM time_sheets ts
 			WHERE ts.submitted = 1
 			  AND ts.approved = ''
@@ -177,9 +250,10 @@ func QueueTimesheetApprovalReminders(app core.App, send bool) error {
 }
 
 // QueueExpenseApprovalReminders queues reminders for managers who currently
-// have submitted expenses awaiting approval.
+// have submitted expenses awaiting approval. While a manager has an active
+// approval delegation the reminder goes to their delegate instead.
 //
-// Dedupe uses a rolling 24-hour window on pending/inflight reminders per
+// Dedupe uses a rolling 24-hour window on unsent reminders per
 // recipient and template.
 func QueueExpenseApprovalReminders(app core.App, send bool) error {
 	job := ReminderJob{
@@ -187,7 +261,7 @@ func QueueExpenseApprovalReminders(app core.App, send bool) error {
 		TemplateCode: "expense_approval_reminder",
 		Query: `
 			SELECT DISTINCT
-				e.approver AS manager_uid
+				` + utilities.ApprovalRecipientExpr("e.approver", "e.uid", utilities.ApprovalDelegationScopeExpenses) + ` AS manager_uid
 			FROM expenses e
 			WHERE e.submitted = 1
 			  AND e.approved = ''
@@ -310,7 +384,7 @@ func notificationExists(app core.App, recipientUID, templateID string, where str
 		FROM notifications n
 		WHERE n.recipient = {:recipient}
 		  AND n.template = {:template}
-		  AND n.status IN ('pending', 'inflight')
+		  AND n.status IN ('pending', 'inflight', 'digest', 'error')
 	`
 
 	whereClause := strings.TrimSpace(where)
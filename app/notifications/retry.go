@@ -0,0 +1,122 @@
+// retry.go contains send retry scheduling and dead-letter handling.
+//
+// A failed send moves a notification to error with next_attempt set using
+// exponential backoff. RetryDueNotifications returns due notifications to the
+// pending queue and sends only those. After maxNotificationAttempts failed attempts a
+// notification is dead and only an admin requeue sends it again.
+package notifications
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	maxNotificationAttempts    = 5
+	notificationRetryBaseDelay = 10 * time.Minute
+)
+
+// ErrNotificationNotRequeueable is returned by RequeueNotification when the
+// notification is not in the error or dead state.
+var ErrNotificationNotRequeueable = errors.New("only failed notifications can be requeued")
+
+// notificationRetryDelay returns the wait after the given failed attempt:
+// 10, 20, 40 and 80 minutes for attempts one through four.
+func notificationRetryDelay(attempt int) time.Duration {
+	if attempt < 1 {
+		attempt = 1
+	}
+	return notificationRetryBaseDelay << (attempt - 1)
+}
+
+// RetryDueNotifications returns every notification whose next_attempt has
+// passed to the pending queue and sends those notifications by ID. Due rows
+// are failed (error) notifications awaiting a retry and admin requeues; other
+// pending notifications, such as reminders queued with send=false, are left
+// for their own senders. It returns the number of notifications requeued.
+func RetryDueNotifications(app core.App) (int64, error) {
+	var due []struct {
+		ID string `db:"id"`
+	}
+	err := app.RunInTransaction(func(txApp core.App) error {
+		if err := txApp.DB().NewQuery(`
+			SELECT id
+			FROM notifications
+			WHERE status IN ('error', 'pending')
+			  AND next_attempt != ''
+			  AND next_attempt <= {:now}
+		`).Bind(dbx.Params{
+			"now": time.Now().UTC().Format("2006-01-02 15:04:05.000Z"),
+		}).All(&due); err != nil {
+			return fmt.Errorf("error finding due notifications: %v", err)
+		}
+		if len(due) == 0 {
+			return nil
+		}
+
+		ids := make([]any, 0, len(due))
+		for _, row := range due {
+			ids = append(ids, row.ID)
+		}
+		if _, err := txApp.NonconcurrentDB().Update("notifications", dbx.Params{
+			"status":       "pending",
+			"next_attempt": "",
+		}, dbx.In("id", ids...)).Execute(); err != nil {
+			return fmt.Errorf("error requeueing due notifications: %v", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+
+	// Send every requeued notification even if one fails so a render error
+	// does not strand the rest of the run.
+	var sendErr error
+	for _, row := range due {
+		if err := SendNotificationByID(app, row.ID); err != nil && sendErr == nil {
+			sendErr = err
+		}
+	}
+	return int64(len(due)), sendErr
+}
+
+// RequeueNotification returns a failed (error or dead) notification to the
+// pending queue with a fresh attempt count. next_attempt is set to now so the
+// next RetryDueNotifications run sends it. It returns sql.ErrNoRows when the
+// notification does not exist and ErrNotificationNotRequeueable when it has
+// not failed.
+func RequeueNotification(app core.App, notificationID string) error {
+	var current struct {
+		Status string `db:"status"`
+	}
+	err := app.DB().NewQuery("SELECT status FROM notifications WHERE id = {:id}").Bind(dbx.Params{
+		"id": notificationID,
+	}).One(&current)
+	if errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if err != nil {
+		return fmt.Errorf("error loading notification %s: %v", notificationID, err)
+	}
+	if current.Status != "error" && current.Status != "dead" {
+		return ErrNotificationNotRequeueable
+	}
+
+	if _, err := app.NonconcurrentDB().NewQuery(`
+		UPDATE notifications
+		SET status = 'pending', attempts = 0, next_attempt = {:now}, status_updated = {:now}
+		WHERE id = {:id}
+	`).Bind(dbx.Params{
+		"now": time.Now().UTC().Format("2006-01-02 15:04:05.000Z"),
+		"id":  notificationID,
+	}).Execute(); err != nil {
+		return fmt.Errorf("error requeueing notification %s: %v", notificationID, err)
+	}
+	return nil
+}
@@ -1,8 +1,8 @@
 // send.go contains notification delivery internals.
 //
 // It implements the send engine for pending notifications, including record
-// fetch/render, status transitions (pending -> inflight -> sent/error),
-// targeted send by ID, and queue draining.
+// fetch/render, transport selection, status transitions
+// (pending -> inflight -> sent/error), targeted send by ID, and queue draining.
 package notifications
 
 import (
@@ -10,14 +10,14 @@ import (
 	"database/sql"
 	"encoding/json"
 	"fmt"
+	"io"
 	"maps"
-	"net/mail"
+	"regexp"
 	"text/template"
 	"time"
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
-	"github.com/pocketbase/pocketbase/tools/mailer"
 )
 
 const sendNotificationAsyncStoreKey = "tybalt.notifications.sendAsync"
@@ -35,20 +35,33 @@ func SetSendNotificationAsyncForTest(app core.App, async bool) {
 	app.Store().Set(sendNotificationAsyncStoreKey, async)
 }
 
+// updateNotificationStatus records the outcome of a send attempt. Failures
+// schedule a retry with exponential backoff until maxNotificationAttempts is
+// reached, after which the notification is dead.
 func updateNotificationStatus(app core.App, notification Notification, sendErr error) {
+	now := time.Now().UTC()
+	attempts := notification.Attempts + 1
 	status := "sent"
 	errMsg := ""
+	nextAttempt := ""
 	if sendErr != nil {
-		status = "error"
 		errMsg = sendErr.Error()
+		if attempts >= maxNotificationAttempts {
+			status = "dead"
+		} else {
+			status = "error"
+			nextAttempt = now.Add(notificationRetryDelay(attempts)).Format("2006-01-02 15:04:05.000Z")
+		}
 	}
 
 	if _, err := app.NonconcurrentDB().NewQuery(
-		"UPDATE notifications SET status = {:status}, error = {:error}, status_updated = {:status_updated} WHERE id = {:id}",
+		"UPDATE notifications SET status = {:status}, error = {:error}, attempts = {:attempts}, next_attempt = {:next_attempt}, status_updated = {:status_updated} WHERE id = {:id}",
 	).Bind(dbx.Params{
 		"status":         status,
 		"error":          errMsg,
-		"status_updated": time.Now().UTC().Format("2006-01-02 15:04:05.000Z"),
+		"attempts":       attempts,
+		"next_attempt":   nextAttempt,
+		"status_updated": now.Format("2006-01-02 15:04:05.000Z"),
 		"id":             notification.Id,
 	}).Execute(); err != nil {
 		app.Logger().Error(
@@ -60,6 +73,118 @@ func updateNotificationStatus(app core.App, notification Notification, sendErr e
 	}
 }
 
+// storedAttachmentSuffix matches the random suffix PocketBase appends to
+// stored file names so attachments are emailed under their original names.
+var storedAttachmentSuffix = regexp.MustCompile(`_[a-z0-9]{10}(\.[^.]*)?$`)
+
+// loadNotificationAttachments reads the files stored on a notification into
+// memory for the mailer. Notifications without attachments return nil.
+func loadNotificationAttachments(app core.App, notificationID string) (map[string]io.Reader, error) {
+	record, err := app.FindRecordById("notifications", notificationID)
+	if err != nil {
+		return nil, err
+	}
+	names := record.GetStringSlice("attachments")
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		return nil, err
+	}
+	defer fsys.Close()
+
+	attachments := make(map[string]io.Reader, len(names))
+	for _, name := range names {
+		reader, err := fsys.GetReader(record.BaseFilesPath() + "/" + name)
+		if err != nil {
+			return nil, fmt.Errorf("error opening attachment %s: %w", name, err)
+		}
+		content, err := io.ReadAll(reader)
+		reader.Close()
+		if err != nil {
+			return nil, fmt.Errorf("error reading attachment %s: %w", name, err)
+		}
+		attachments[storedAttachmentSuffix.ReplaceAllString(name, "$1")] = bytes.NewReader(content)
+	}
+	return attachments, nil
+}
+
+// notificationSelectQuery loads notifications with the recipient, actor and
+// template columns needed to render and route them. Callers append the WHERE
+// clause.
+const notificationSelectQuery = `SELECT
+		n.*,
+		(r_profile.given_name || ' ' || r_profile.surname) AS recipient_name,
+		u.email,
+		r_profile.notification_type,
+		COALESCE(r_profile.notification_transport, '') AS notification_transport,
+		COALESCE(r_profile.notification_webhook_url, '') AS notification_webhook_url,
+		COALESCE(nt.transports, '') AS template_transports,
+		COALESCE(nt.code, '') AS template_code,
+		COALESCE(nt.mutable, 0) AS template_mutable,
+		COALESCE(u_profile.given_name || ' ' || u_profile.surname, '') AS user_name,
+		nt.subject,
+		nt.text_email,
+		n.data
+	FROM notifications n
+	LEFT JOIN profiles r_profile ON n.recipient = r_profile.uid
+	LEFT JOIN profiles u_profile ON n.user = u_profile.uid
+	LEFT JOIN notification_templates nt ON n.template = nt.id
+	LEFT JOIN users u ON n.recipient = u.id`
+
+// renderNotificationText executes the notification's text template with the
+// standard fields plus its data payload.
+func renderNotificationText(app core.App, notification *Notification) (string, error) {
+	if len(notification.Data) > 0 {
+		err := json.Unmarshal(notification.Data, &notification.parsedData)
+		if err != nil {
+			app.Logger().Error(
+				"Failed to unmarshal notification data",
+				"notification_id", notification.Id,
+				"error", err,
+				"raw_data", string(notification.Data),
+			)
+			return "", fmt.Errorf("error unmarshalling notification data for %s: %w", notification.Id, err)
+		}
+	}
+
+	textTemplate, err := template.New("text_email").Option("missingkey=error").Parse(notification.Template)
+	if err != nil {
+		return "", fmt.Errorf("error parsing text template for notification %s: %s", notification.Id, err)
+	}
+
+	templateData := map[string]any{
+		"Id":                 notification.Id,
+		"RecipientEmail":     notification.RecipientEmail,
+		"RecipientName":      notification.RecipientName,
+		"NotificationType":   notification.NotificationType,
+		"UserName":           notification.UserName,
+		"Subject":            notification.Subject,
+		"Template":           notification.Template,
+		"Status":             notification.Status,
+		"StatusUpdated":      notification.StatusUpdated,
+		"Error":              notification.Error,
+		"UserId":             notification.UserId,
+		"SystemNotification": notification.SystemNotification,
+	}
+
+	if notification.parsedData != nil {
+		maps.Copy(templateData, notification.parsedData)
+	}
+
+	var text bytes.Buffer
+	err = textTemplate.Execute(&text, templateData)
+	if err != nil {
+		return "", fmt.Errorf("error executing text template for notification %s: %s", notification.Id, err)
+	}
+	if unresolved := unresolvedLegacyPlaceholder(text.String()); unresolved != "" {
+		return "", fmt.Errorf("notification %s rendered with unresolved legacy placeholder %s", notification.Id, unresolved)
+	}
+	return text.String(), nil
+}
+
 // SendNotificationByID sends a single pending notification identified by its
 // notification record ID.
 //
@@ -67,22 +192,11 @@ func updateNotificationStatus(app core.App, notification Notification, sendErr e
 // returns nil.
 func SendNotificationByID(app core.App, notificationID string) error {
 	notification := Notification{}
-	message := &mailer.Message{}
+	message := OutboundMessage{}
+	transportName := ""
+	var selectErr error
 	err := app.RunInTransaction(func(txApp core.App) error {
-		err := txApp.DB().NewQuery(`SELECT
-				n.*,
-				(r_profile.given_name || ' ' || r_profile.surname) AS recipient_name,
-				u.email,
-				r_profile.notification_type,
-				COALESCE(u_profile.given_name || ' ' || u_profile.surname, '') AS user_name,
-				nt.subject,
-				nt.text_email,
-				n.data
-			FROM notifications n
-			LEFT JOIN profiles r_profile ON n.recipient = r_profile.uid
-			LEFT JOIN profiles u_profile ON n.user = u_profile.uid
-			LEFT JOIN notification_templates nt ON n.template = nt.id
-			LEFT JOIN users u ON n.recipient = u.id
+		err := txApp.DB().NewQuery(notificationSelectQuery + `
 			WHERE n.id = {:id}
 			  AND n.status = 'pending'`).Bind(dbx.Params{
 			"id": notificationID,
@@ -95,62 +209,47 @@ func SendNotificationByID(app core.App, notificationID string) error {
 			return fmt.Errorf("error fetching notification %s: %v", notificationID, err)
 		}
 
-		if len(notification.Data) > 0 {
-			err = json.Unmarshal(notification.Data, &notification.parsedData)
-			if err != nil {
-				app.Logger().Error(
-					"Failed to unmarshal notification data",
-					"notification_id", notification.Id,
-					"error", err,
-					"raw_data", string(notification.Data),
-				)
-				return fmt.Errorf("error unmarshalling notification data for %s: %w", notification.Id, err)
-			}
-		}
-
-		textTemplate, err := template.New("text_email").Option("missingkey=error").Parse(notification.Template)
+		text, err := renderNotificationText(app, &notification)
 		if err != nil {
-			return fmt.Errorf("error parsing text template for notification %s: %s", notification.Id, err)
+			return err
 		}
 
-		templateData := map[string]any{
-			"Id":                 notification.Id,
-			"RecipientEmail":     notification.RecipientEmail,
-			"RecipientName":      notification.RecipientName,
-			"NotificationType":   notification.NotificationType,
-			"UserName":           notification.UserName,
-			"Subject":            notification.Subject,
-			"Template":           notification.Template,
-			"Status":             notification.Status,
-			"StatusUpdated":      notification.StatusUpdated,
-			"Error":              notification.Error,
-			"UserId":             notification.UserId,
-			"SystemNotification": notification.SystemNotification,
+		message = OutboundMessage{
+			NotificationID: notification.Id,
+			RecipientName:  notification.RecipientName,
+			RecipientEmail: notification.RecipientEmail,
+			WebhookURL:     notification.WebhookURL,
+			Subject:        notification.Subject,
+			Text:           text,
 		}
-
-		if notification.parsedData != nil {
-			maps.Copy(templateData, notification.parsedData)
+		if notification.TemplateMutable {
+			message.UnsubscribeURL = BuildUnsubscribeURL(app, notification.RecipientUID, notification.TemplateCode)
+			if message.UnsubscribeURL != "" {
+				message.Text += "\n\n--\nTo stop receiving these notifications, unsubscribe here:\n" + message.UnsubscribeURL
+			}
 		}
-
-		var text bytes.Buffer
-		err = textTemplate.Execute(&text, templateData)
+		message.Attachments, err = loadNotificationAttachments(txApp, notification.Id)
 		if err != nil {
-			return fmt.Errorf("error executing text template for notification %s: %s", notification.Id, err)
-		}
-		if unresolved := unresolvedLegacyPlaceholder(text.String()); unresolved != "" {
-			return fmt.Errorf("notification %s rendered with unresolved legacy placeholder %s", notification.Id, unresolved)
+			return fmt.Errorf("error loading attachments for notification %s: %w", notification.Id, err)
 		}
 
-		message = &mailer.Message{
-			From:    mail.Address{Name: app.Settings().Meta.SenderName, Address: app.Settings().Meta.SenderAddress},
-			To:      []mail.Address{{Name: notification.RecipientName, Address: notification.RecipientEmail}},
-			Subject: notification.Subject,
-			Text:    text.String(),
+		var allowedTransports []string
+		if notification.TemplateTransports != "" {
+			if err := json.Unmarshal([]byte(notification.TemplateTransports), &allowedTransports); err != nil {
+				return fmt.Errorf("error reading template transports for notification %s: %w", notification.Id, err)
+			}
+		}
+		transportName, selectErr = selectTransport(notification.PreferredTransport, allowedTransports, notification.WebhookURL, len(message.Attachments) > 0)
+		if selectErr != nil {
+			// Leave the record for the status update below rather than keeping it
+			// pending forever; the recipient's settings have to change first.
+			return nil
 		}
 
 		_, err = txApp.NonconcurrentDB().NewQuery(
-			"UPDATE notifications SET status = 'inflight', status_updated = {:status_updated} WHERE id = {:id}",
+			"UPDATE notifications SET status = 'inflight', transport = {:transport}, status_updated = {:status_updated} WHERE id = {:id}",
 		).Bind(dbx.Params{
+			"transport":      transportName,
 			"status_updated": time.Now().UTC().Format("2006-01-02 15:04:05.000Z"),
 			"id":             notification.Id,
 		}).Execute()
@@ -165,22 +264,34 @@ func SendNotificationByID(app core.App, notificationID string) error {
 		return err
 	}
 
+	if notification.Id != "" && selectErr != nil {
+		app.Logger().Error(
+			"no transport available for notification",
+			"notification_id", notification.Id,
+			"error", selectErr,
+		)
+		updateNotificationStatus(app, notification, selectErr)
+		return nil
+	}
+
 	if notification.Id != "" {
-		deliver := func(app core.App, message *mailer.Message, notification Notification) {
+		transport := transports[transportName]
+		deliver := func(app core.App, message OutboundMessage, notification Notification) {
 			defer func() {
 				if r := recover(); r != nil {
 					app.Logger().Error(
-						"recovered from panic while sending notification email",
+						"recovered from panic while sending notification",
 						"notification_id", notification.Id,
 						"panic", fmt.Sprintf("%v", r),
 					)
 				}
 			}()
-			err := app.NewMailClient().Send(message)
+			err := transport.Send(app, message)
 			if err != nil {
 				app.Logger().Error(
-					"Failed to send notification email",
+					"Failed to send notification",
 					"notification_id", notification.Id,
+					"transport", transportName,
 					"error", err,
 				)
 			}
@@ -0,0 +1,170 @@
+// transport.go contains the delivery transports used by the send engine.
+//
+// A rendered notification is handed to exactly one Transport. Email sends via
+// the PocketBase mailer; webhook posts a generic incoming-webhook payload that
+// Teams and Slack both accept. The transport is chosen from the recipient's
+// profile preference and the transports the template allows.
+package notifications
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/mailer"
+)
+
+const (
+	TransportEmail   = "email"
+	TransportWebhook = "webhook"
+)
+
+const webhookClientStoreKey = "tybalt.notifications.webhookClient"
+
+// OutboundMessage is a rendered notification ready for delivery.
+type OutboundMessage struct {
+	NotificationID string
+	RecipientName  string
+	RecipientEmail string
+	WebhookURL     string
+	Subject        string
+	Text           string
+	Attachments    map[string]io.Reader
+	// UnsubscribeURL is the signed one-click unsubscribe link for mutable
+	// templates. It is empty for system notifications.
+	UnsubscribeURL string
+}
+
+// Transport delivers a rendered notification. Send errors are persisted to
+// notifications.error by the send engine.
+type Transport interface {
+	Send(app core.App, message OutboundMessage) error
+}
+
+var transports = map[string]Transport{
+	TransportEmail:   emailTransport{},
+	TransportWebhook: webhookTransport{},
+}
+
+type emailTransport struct{}
+
+func (emailTransport) Send(app core.App, message OutboundMessage) error {
+	var headers map[string]string
+	if message.UnsubscribeURL != "" {
+		// RFC 8058 one-click unsubscribe: mail clients POST to the link.
+		headers = map[string]string{
+			"List-Unsubscribe":      "<" + message.UnsubscribeURL + ">",
+			"List-Unsubscribe-Post": "List-Unsubscribe=One-Click",
+		}
+	}
+	return app.NewMailClient().Send(&mailer.Message{
+		From:        mail.Address{Name: app.Settings().Meta.SenderName, Address: app.Settings().Meta.SenderAddress},
+		To:          []mail.Address{{Name: message.RecipientName, Address: message.RecipientEmail}},
+		Subject:     message.Subject,
+		Text:        message.Text,
+		Headers:     headers,
+		Attachments: message.Attachments,
+	})
+}
+
+type webhookTransport struct{}
+
+// webhookPayload is the incoming-webhook body. Slack and Teams both render the
+// top-level text field, so the subject is sent as its first line.
+type webhookPayload struct {
+	Text string `json:"text"`
+}
+
+func (webhookTransport) Send(app core.App, message OutboundMessage) error {
+	if err := ValidateWebhookURL(message.WebhookURL); err != nil {
+		return err
+	}
+	body, err := json.Marshal(webhookPayload{
+		Text: message.Subject + "\n\n" + message.Text,
+	})
+	if err != nil {
+		return fmt.Errorf("error encoding webhook payload: %w", err)
+	}
+	response, err := webhookClientForApp(app).Post(message.WebhookURL, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("error posting webhook: %w", err)
+	}
+	defer response.Body.Close()
+	if response.StatusCode < 200 || response.StatusCode > 299 {
+		detail, _ := io.ReadAll(io.LimitReader(response.Body, 512))
+		return fmt.Errorf("webhook responded with status %d: %s", response.StatusCode, strings.TrimSpace(string(detail)))
+	}
+	return nil
+}
+
+func webhookClientForApp(app core.App) *http.Client {
+	if client, ok := app.Store().Get(webhookClientStoreKey).(*http.Client); ok {
+		return client
+	}
+	return &http.Client{Timeout: 15 * time.Second}
+}
+
+// SetWebhookClientForTest overrides the HTTP client used by the webhook
+// transport for a specific app instance, so tests can trust a local TLS
+// stand-in server.
+func SetWebhookClientForTest(app core.App, client *http.Client) {
+	app.Store().Set(webhookClientStoreKey, client)
+}
+
+// ValidateWebhookURL reports whether rawURL is usable as a notification
+// webhook. Only absolute https URLs are accepted.
+func ValidateWebhookURL(rawURL string) error {
+	if strings.TrimSpace(rawURL) == "" {
+		return errors.New("no webhook URL is configured")
+	}
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		return fmt.Errorf("invalid webhook URL: %w", err)
+	}
+	if parsed.Scheme != "https" || parsed.Host == "" {
+		return errors.New("webhook URL must be an absolute https URL")
+	}
+	return nil
+}
+
+// selectTransport picks the transport for a notification. The recipient's
+// preferred transport is used when the template allows it and the message can
+// be carried by it; otherwise the first allowed transport that can carry the
+// message is used. Webhooks cannot carry attachments and require a configured
+// URL. An empty allowed list means the template accepts every transport.
+func selectTransport(preferred string, allowed []string, webhookURL string, hasAttachments bool) (string, error) {
+	if len(allowed) == 0 {
+		allowed = []string{TransportEmail, TransportWebhook}
+	}
+	usable := func(name string) bool {
+		if !slices.Contains(allowed, name) {
+			return false
+		}
+		if name == TransportWebhook {
+			return webhookURL != "" && !hasAttachments
+		}
+		return name == TransportEmail
+	}
+
+	if preferred == "" {
+		preferred = TransportEmail
+	}
+	if usable(preferred) {
+		return preferred, nil
+	}
+	for _, name := range allowed {
+		if usable(name) {
+			return name, nil
+		}
+	}
+	return "", fmt.Errorf("no allowed transport (%s) can deliver this notification to the recipient", strings.Join(allowed, ", "))
+}
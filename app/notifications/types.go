@@ -4,19 +4,29 @@
 // dispatch and reminder queue orchestration.
 package notifications
 
-import "github.com/pocketbase/dbx"
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
 
 type Notification struct {
 	Id                 string `db:"id"`
+	RecipientUID       string `db:"recipient"`
 	RecipientEmail     string `db:"email"`
 	RecipientName      string `db:"recipient_name"`
 	NotificationType   string `db:"notification_type"`
+	PreferredTransport string `db:"notification_transport"`
+	WebhookURL         string `db:"notification_webhook_url"`
+	TemplateTransports string `db:"template_transports"`
+	TemplateCode       string `db:"template_code"`
+	TemplateMutable    bool   `db:"template_mutable"`
 	UserName           string `db:"user_name"`
 	Subject            string `db:"subject"`
 	Template           string `db:"text_email"`
 	Status             string `db:"status"`
 	StatusUpdated      string `db:"status_updated"`
 	Error              string `db:"error"`
+	Attempts           int    `db:"attempts"`
 	UserId             string `db:"user"`
 	SystemNotification bool   `db:"system_notification"`
 	Data               []byte `db:"data"`
@@ -37,6 +47,9 @@ type DispatchArgs struct {
 	System       bool
 	ActorUID     string
 	Mode         DeliveryMode
+	// Attachments are stored on the notification record and sent with the
+	// email. Most notifications have none.
+	Attachments []*filesystem.File
 }
 
 type DedupeSpec struct {
@@ -10,6 +10,7 @@ import (
 
 	"tybalt/internal/testutils"
 	"tybalt/notifications"
+	"tybalt/utilities"
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
@@ -700,10 +701,9 @@ func TestQueueTimesheetSubmissionReminders_CreatesNotifications(t *testing.T) {
 			SELECT
 				COUNT(*) AS count
 			FROM users u
-			LEFT JOIN time_sheets ts ON ts.uid = u.id AND ts.week_ending = {:week_ending} AND ts.submitted = 1
-			LEFT JOIN admin_profiles ap ON ap.uid = u.id
+			LEFT JOIN time_sheets ts ON ts.uid = u.id AND ts.week_ending = {:week_ending} AND ts.submitted = 1` + utilities.TimesheetExpectationJoins + `
 			WHERE ts.id IS NULL
-			  AND COALESCE(ap.time_sheet_expected, 0) = 1
+			  AND ` + utilities.TimesheetExpectedCondition + `
 		`).Bind(dbx.Params{
 			"week_ending": w.WeekEnding,
 		}).One(&res)
@@ -753,6 +753,94 @@ func TestQueueTimesheetSubmissionReminders_CreatesNotifications(t *testing.T) {
 	}
 }
 
+// QueueTimesheetSubmissionRemindersForWeek()
+//
+//  1. skips users with a missing-time-sheet exception, inactive users and users
+//     not expected to submit, matching the tracking missing list.
+//  2. escalates once to the manager after two sent reminders for the week.
+func TestQueueTimesheetSubmissionReminders_RespectsExpectationsAndEscalates(t *testing.T) {
+	app := testutils.SetupTestApp(t)
+	defer app.Cleanup()
+
+	const weekEnding = "2026-04-25"
+	const timeUID = "rzr98oadsp9qc11"        // manager f2j5a8vk006baub
+	const selfApproverUID = "u_self_apv_yes" // manages themself
+	const exemptUID = "u_self_apv_no"
+	const inactiveUID = "u_has_inactive_mgr"
+
+	exec := func(query string, params dbx.Params) {
+		t.Helper()
+		if _, err := app.NonconcurrentDB().NewQuery(query).Bind(params).Execute(); err != nil {
+			t.Fatalf("failed to prepare fixtures: %v", err)
+		}
+	}
+	exec("UPDATE admin_profiles SET time_sheet_expected = 1 WHERE uid = {:uid}", dbx.Params{"uid": timeUID})
+	exec("UPDATE admin_profiles SET active = 0 WHERE uid = {:uid}", dbx.Params{"uid": inactiveUID})
+	exec(`INSERT INTO time_sheet_missing_exceptions (id, uid, week_ending, created, updated)
+		VALUES ('tsmissexempt001', {:uid}, {:week_ending}, '', '')`, dbx.Params{"uid": exemptUID, "week_ending": weekEnding})
+
+	notificationsFor := func(code string) map[string][]string {
+		t.Helper()
+		var rows []struct {
+			Recipient string `db:"recipient"`
+			Data      string `db:"data"`
+		}
+		if err := app.DB().NewQuery(`
+			SELECT n.recipient, COALESCE(n.data, '') AS data
+			FROM notifications n
+			JOIN notification_templates t ON t.id = n.template
+			WHERE t.code = {:code}
+			  AND json_extract(n.data, '$.WeekEnding') = {:week_ending}
+		`).Bind(dbx.Params{"code": code, "week_ending": weekEnding}).All(&rows); err != nil {
+			t.Fatalf("failed to load %s notifications: %v", code, err)
+		}
+		byRecipient := map[string][]string{}
+		for _, row := range rows {
+			byRecipient[row.Recipient] = append(byRecipient[row.Recipient], row.Data)
+		}
+		return byRecipient
+	}
+	runAndMarkSent := func() {
+		t.Helper()
+		if err := notifications.QueueTimesheetSubmissionRemindersForWeek(app, weekEnding, false); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		exec("UPDATE notifications SET status = 'sent' WHERE status = 'pending'", dbx.Params{})
+	}
+
+	runAndMarkSent()
+	reminders := notificationsFor("timesheet_submission_reminder")
+	if len(reminders) != 2 || len(reminders[timeUID]) != 1 || len(reminders[selfApproverUID]) != 1 {
+		t.Fatalf("expected one reminder each for %s and %s only, got %v", timeUID, selfApproverUID, reminders)
+	}
+
+	runAndMarkSent()
+	if escalations := notificationsFor("timesheet_submission_escalation"); len(escalations) != 0 {
+		t.Fatalf("expected no escalation before two reminders were sent, got %v", escalations)
+	}
+
+	runAndMarkSent()
+	escalations := notificationsFor("timesheet_submission_escalation")
+	if len(escalations) != 1 || len(escalations["f2j5a8vk006baub"]) != 1 {
+		t.Fatalf("expected one escalation to the manager of %s, got %v", timeUID, escalations)
+	}
+	var data map[string]any
+	if err := json.Unmarshal([]byte(escalations["f2j5a8vk006baub"][0]), &data); err != nil {
+		t.Fatalf("failed to decode escalation data: %v", err)
+	}
+	if data["EmployeeUID"] != timeUID || data["EmployeeName"] != "Tester Time" || data["ReminderCount"] != "2" {
+		t.Fatalf("unexpected escalation data %v", data)
+	}
+	if got := len(notificationsFor("timesheet_submission_reminder")[timeUID]); got != 3 {
+		t.Fatalf("expected reminders to continue after escalation, got %d", got)
+	}
+
+	runAndMarkSent()
+	if got := len(notificationsFor("timesheet_submission_escalation")["f2j5a8vk006baub"]); got != 1 {
+		t.Fatalf("expected the escalation to be sent only once, got %d", got)
+	}
+}
+
 // QueueTimesheetApprovalReminders()
 //
 //  1. creates one or more notifications with the timesheet_approval_reminder template
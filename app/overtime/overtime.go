@@ -0,0 +1,150 @@
+// Package overtime holds the overtime rules and the calculation that splits a
+// week of worked hours into regular and overtime hours.
+//
+// Rules come from the "overtime" domain in app_config. The top-level values
+// apply company-wide and the "branches" object overrides them for the branch
+// ids it lists. An employee's rules are those of their admin profile's
+// default_branch. Timesheet validation, the payroll time report and the
+// timesheet tallies route all use Calculate so they agree on the numbers.
+package overtime
+
+import (
+	"math"
+	"sort"
+
+	"tybalt/utilities"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Rules are the overtime rules for one branch.
+type Rules struct {
+	// WeeklyThreshold is the number of worked hours in a week after which
+	// hours are overtime.
+	WeeklyThreshold float64 `json:"weekly_threshold"`
+	// DailyThreshold is the number of worked hours in a day after which hours
+	// are overtime. Zero disables the daily rule.
+	DailyThreshold float64 `json:"daily_threshold"`
+	// BankingAllowed lets staff bank overtime (RB) instead of being paid.
+	BankingAllowed bool `json:"banking_allowed"`
+	// SalariedExempt means salaried staff never earn overtime.
+	SalariedExempt bool `json:"salaried_exempt"`
+}
+
+// DefaultRules are the Ontario-style rules used when app_config has no
+// overtime domain: overtime after 44 hours in a week, no daily threshold,
+// banking allowed and salaried staff exempt.
+var DefaultRules = Rules{
+	WeeklyThreshold: 44,
+	DailyThreshold:  0,
+	BankingAllowed:  true,
+	SalariedExempt:  true,
+}
+
+// Config is the company-wide rules plus per-branch overrides.
+type Config struct {
+	Default  Rules
+	Branches map[string]Rules
+}
+
+// ForBranch returns the rules for branchID, falling back to the company-wide
+// rules for branches without an override.
+func (c Config) ForBranch(branchID string) Rules {
+	if rules, ok := c.Branches[branchID]; ok {
+		return rules
+	}
+	return c.Default
+}
+
+// LoadConfig reads the "overtime" domain from app_config. Missing or invalid
+// values fall back to DefaultRules; branch overrides start from the
+// company-wide rules and replace only the valid values they set.
+func LoadConfig(app core.App) Config {
+	config := Config{Default: DefaultRules, Branches: map[string]Rules{}}
+
+	value, err := utilities.GetConfigValue(app, "overtime")
+	if err != nil || value == nil {
+		return config
+	}
+	config.Default = applyRules(DefaultRules, value)
+
+	branches, ok := value["branches"].(map[string]any)
+	if !ok {
+		return config
+	}
+	for branchID, raw := range branches {
+		if overrides, ok := raw.(map[string]any); ok {
+			config.Branches[branchID] = applyRules(config.Default, overrides)
+		}
+	}
+	return config
+}
+
+func applyRules(base Rules, values map[string]any) Rules {
+	rules := base
+	if threshold, err := utilities.CoerceFloat64(values["weekly_threshold"]); err == nil && threshold > 0 {
+		rules.WeeklyThreshold = threshold
+	}
+	if threshold, err := utilities.CoerceFloat64(values["daily_threshold"]); err == nil && threshold >= 0 {
+		rules.DailyThreshold = threshold
+	}
+	if allowed, ok := values["banking_allowed"].(bool); ok {
+		rules.BankingAllowed = allowed
+	}
+	if exempt, ok := values["salaried_exempt"].(bool); ok {
+		rules.SalariedExempt = exempt
+	}
+	return rules
+}
+
+// Result is a week of worked hours split by the rules.
+type Result struct {
+	WorkedHours       float64 `json:"worked_hours"`
+	RegularHours      float64 `json:"regular_hours"`
+	DailyOvertime     float64 `json:"daily_overtime_hours"`
+	WeeklyOvertime    float64 `json:"weekly_overtime_hours"`
+	OvertimeHours     float64 `json:"overtime_hours"`
+	BankedHours       float64 `json:"banked_hours"`
+	PaidOvertimeHours float64 `json:"paid_overtime_hours"`
+	Exempt            bool    `json:"exempt"`
+}
+
+// Calculate splits worked (R and RT) hours into regular and overtime hours.
+// dailyHours maps a date (or any other key, for hours that cannot be placed on
+// a day) to the hours worked. Hours over the daily threshold are overtime and
+// do not count towards the weekly threshold; the remaining hours over the
+// weekly threshold are overtime too. requestedBank is the RB hours claimed.
+// Banked hours come out of overtime and never exceed it; the rest is paid.
+func Calculate(rules Rules, salary bool, dailyHours map[string]float64, requestedBank float64) Result {
+	result := Result{}
+
+	days := make([]string, 0, len(dailyHours))
+	for day := range dailyHours {
+		days = append(days, day)
+	}
+	sort.Strings(days)
+	for _, day := range days {
+		result.WorkedHours += dailyHours[day]
+	}
+
+	if salary && rules.SalariedExempt {
+		result.Exempt = true
+		result.RegularHours = result.WorkedHours
+		return result
+	}
+
+	if rules.DailyThreshold > 0 {
+		for _, day := range days {
+			result.DailyOvertime += math.Max(0, dailyHours[day]-rules.DailyThreshold)
+		}
+	}
+	result.WeeklyOvertime = math.Max(0, result.WorkedHours-result.DailyOvertime-rules.WeeklyThreshold)
+	result.OvertimeHours = result.DailyOvertime + result.WeeklyOvertime
+	result.RegularHours = result.WorkedHours - result.OvertimeHours
+
+	if rules.BankingAllowed {
+		result.BankedHours = math.Min(math.Max(requestedBank, 0), result.OvertimeHours)
+	}
+	result.PaidOvertimeHours = result.OvertimeHours - result.BankedHours
+	return result
+}
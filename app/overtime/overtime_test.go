@@ -0,0 +1,103 @@
+package overtime
+
+import (
+	"testing"
+
+	"tybalt/internal/testseed"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func ontarioWeek(hoursPerDay ...float64) map[string]float64 {
+	dates := []string{"2024-01-07", "2024-01-08", "2024-01-09", "2024-01-10", "2024-01-11", "2024-01-12", "2024-01-13"}
+	week := map[string]float64{}
+	for i, hours := range hoursPerDay {
+		week[dates[i]] = hours
+	}
+	return week
+}
+
+func TestCalculate_OntarioWeeklyThreshold(t *testing.T) {
+	cases := []struct {
+		name        string
+		week        map[string]float64
+		bank        float64
+		wantOT      float64
+		wantRegular float64
+		wantBanked  float64
+		wantPaid    float64
+	}{
+		{"under 44 hours", ontarioWeek(8, 8, 8, 8, 8), 0, 0, 40, 0, 0},
+		{"exactly 44 hours", ontarioWeek(0, 9, 9, 9, 9, 8), 0, 0, 44, 0, 0},
+		{"long days are not daily overtime", ontarioWeek(0, 12, 12, 12, 12), 0, 4, 44, 0, 4},
+		{"banked hours come out of overtime", ontarioWeek(0, 10, 10, 10, 10, 10), 4, 6, 44, 4, 2},
+		{"banked hours never exceed overtime", ontarioWeek(0, 10, 10, 10, 10, 6), 5, 2, 44, 2, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			result := Calculate(DefaultRules, false, tc.week, tc.bank)
+			if result.OvertimeHours != tc.wantOT || result.RegularHours != tc.wantRegular ||
+				result.BankedHours != tc.wantBanked || result.PaidOvertimeHours != tc.wantPaid {
+				t.Fatalf("result = %+v, want overtime %v regular %v banked %v paid %v", result, tc.wantOT, tc.wantRegular, tc.wantBanked, tc.wantPaid)
+			}
+		})
+	}
+}
+
+func TestCalculate_SalariedExemption(t *testing.T) {
+	result := Calculate(DefaultRules, true, ontarioWeek(0, 10, 10, 10, 10, 10), 0)
+	if !result.Exempt || result.OvertimeHours != 0 || result.RegularHours != 50 {
+		t.Fatalf("exempt result = %+v, want 50 regular hours and no overtime", result)
+	}
+
+	rules := DefaultRules
+	rules.SalariedExempt = false
+	result = Calculate(rules, true, ontarioWeek(0, 10, 10, 10, 10, 10), 0)
+	if result.Exempt || result.OvertimeHours != 6 {
+		t.Fatalf("non-exempt result = %+v, want 6 overtime hours", result)
+	}
+}
+
+func TestCalculate_DailyThreshold(t *testing.T) {
+	rules := Rules{WeeklyThreshold: 40, DailyThreshold: 8, BankingAllowed: false}
+	// Two 12 hour days earn 8 hours of daily overtime. The remaining 40 hours
+	// meet but do not exceed the weekly threshold.
+	result := Calculate(rules, false, ontarioWeek(0, 12, 12, 8, 8, 8), 3)
+	if result.DailyOvertime != 8 || result.WeeklyOvertime != 0 || result.RegularHours != 40 {
+		t.Fatalf("result = %+v, want 8 daily overtime hours and 40 regular hours", result)
+	}
+	if result.BankedHours != 0 || result.PaidOvertimeHours != 8 {
+		t.Fatalf("result = %+v, want all overtime paid when banking is not allowed", result)
+	}
+}
+
+func TestLoadConfig_BranchOverrides(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	defer app.Cleanup()
+
+	if config := LoadConfig(app); config.ForBranch("any") != DefaultRules {
+		t.Fatalf("rules without config = %+v, want defaults", config.ForBranch("any"))
+	}
+
+	collection, err := app.FindCollectionByNameOrId("app_config")
+	if err != nil {
+		t.Fatalf("failed to find app_config collection: %v", err)
+	}
+	record := core.NewRecord(collection)
+	record.Set("key", "overtime")
+	record.Set("value", `{"weekly_threshold": 40, "branches": {"north": {"daily_threshold": 8, "banking_allowed": false}, "south": {"weekly_threshold": -1}}}`)
+	if err := app.Save(record); err != nil {
+		t.Fatalf("failed to save overtime config: %v", err)
+	}
+
+	config := LoadConfig(app)
+	if got := config.ForBranch("other"); got.WeeklyThreshold != 40 || got.DailyThreshold != 0 || !got.BankingAllowed {
+		t.Fatalf("company rules = %+v, want a 40 hour week", got)
+	}
+	if got := config.ForBranch("north"); got.WeeklyThreshold != 40 || got.DailyThreshold != 8 || got.BankingAllowed {
+		t.Fatalf("north rules = %+v, want inherited 40 hour week with an 8 hour day and no banking", got)
+	}
+	if got := config.ForBranch("south"); got.WeeklyThreshold != 40 {
+		t.Fatalf("south rules = %+v, want the invalid threshold ignored", got)
+	}
+}
@@ -73,9 +73,12 @@ func TestPayrollTimeReport_AppliesBranchAllocationRules(t *testing.T) {
 
 			expectedOvertimeBranchHeaders := overtimeBranchHeaders(expectedBranchHeaders)
 			expectedPayrollBranchHeaders := append(append([]string{}, expectedBranchHeaders...), expectedOvertimeBranchHeaders...)
-			if got := headers[salaryIndex+1:]; !slices.Equal(got, expectedPayrollBranchHeaders) {
+			if got := headers[salaryIndex+1 : len(headers)-1]; !slices.Equal(got, expectedPayrollBranchHeaders) {
 				tb.Fatalf("branch headers = %+v, want %+v", got, expectedPayrollBranchHeaders)
 			}
+			if got := headers[len(headers)-1]; got != "calendar stat holiday hours" {
+				tb.Fatalf("last header = %q, want the calendar stat holiday hours column appended", got)
+			}
 
 			rowByPayrollID := map[string]map[string]string{}
 			for _, record := range rows[1:] {
@@ -135,6 +138,10 @@ func TestPayrollTimeReport_AppliesBranchAllocationRules(t *testing.T) {
 			assertCSVFloatEquals(tb, salaryStatRow["hours worked"], 34)
 			assertCSVFloatEquals(tb, salaryStatRow["adjustedHoursWorked"], 32)
 			assertCSVFloatEquals(tb, salaryStatRow["Stat Holiday"], 8)
+			// Thunder Bay observes one calendar holiday this week, a fifth of
+			// the 40 hour work week; the Toronto holiday does not apply to
+			// this employee's default branch.
+			assertCSVFloatEquals(tb, salaryStatRow["calendar stat holiday hours"], 8)
 			assertCSVFloatEquals(tb, salaryStatRow["Thunder Bay"], 18)
 			assertCSVFloatEquals(tb, salaryStatRow["Toronto"], 14)
 
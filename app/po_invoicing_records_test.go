@@ -0,0 +1,250 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"tybalt/internal/testutils"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// =============================================================================
+// PO Invoicing Records
+// =============================================================================
+//
+// Fixtures: job tt4eipt6wapu9zh has one Active project PO (y660i6a14ql2355)
+// with two seeded invoicing records totalling 650.50, one Unapproved PO
+// (0gfs12b4695339i) and one Cancelled PO (338568325487lo2).
+
+const (
+	invoicingJobID             = "tt4eipt6wapu9zh"
+	invoicingActivePOID        = "y660i6a14ql2355"
+	invoicingUnapprovedPOID    = "0gfs12b4695339i"
+	invoicingCancelledPOID     = "338568325487lo2"
+	invoicingOtherJobClosedPO  = "0pia83nnprdlzf8"
+	invoicingUserEmail         = "invoicer@example.com"
+	invoicingReportUserEmail   = "fatt@mac.com"
+	invoicingNoClaimsUserEmail = "noclaims@example.com"
+)
+
+func countPOInvoicingRecords(tb testing.TB, app *tests.TestApp, poID string) int {
+	tb.Helper()
+
+	var count int
+	if err := app.DB().NewQuery(`
+		SELECT COUNT(*) FROM po_invoicing_records WHERE purchase_order = {:po}
+	`).Bind(dbx.Params{"po": poID}).Row(&count); err != nil {
+		tb.Fatalf("failed to count invoicing records: %v", err)
+	}
+	return count
+}
+
+func TestJobInvoicingPOs_ListAndSummary(t *testing.T) {
+	invoicerToken, err := testutils.GenerateRecordToken("users", invoicingUserEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportToken, err := testutils.GenerateRecordToken("users", invoicingReportUserEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	noClaimsToken, err := testutils.GenerateRecordToken("users", invoicingNoClaimsUserEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:           "invoicing claim holder lists only eligible POs with invoiced totals",
+			Method:         http.MethodGet,
+			URL:            "/api/jobs/" + invoicingJobID + "/pos/invoicing",
+			Headers:        map[string]string{"Authorization": invoicerToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"id":"` + invoicingActivePOID + `"`,
+				`"invoiced_total":650.5`,
+				`"invoice_count":2`,
+				`"last_invoiced_on":"2026-05-29"`,
+				`"total":1`,
+			},
+			NotExpectedContent: []string{
+				invoicingUnapprovedPOID,
+				invoicingCancelledPOID,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "report claim holder can read the invoicing summary",
+			Method:         http.MethodGet,
+			URL:            "/api/jobs/" + invoicingJobID + "/pos/invoicing/summary",
+			Headers:        map[string]string{"Authorization": reportToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"total_amount":1000`,
+				`"po_count":1`,
+				`"invoiced_total":650.5`,
+				`"uninvoiced_balance":349.5`,
+				`"invoice_count":2`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "records are listed newest invoiced_on first",
+			Method:         http.MethodGet,
+			URL:            "/api/jobs/" + invoicingJobID + "/pos/invoicing/" + invoicingActivePOID + "/records",
+			Headers:        map[string]string{"Authorization": invoicerToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`[{"id":"poinvrec0000002"`,
+				`"invoice_number":"INV-1001"`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:            "users without invoicing or report claim cannot list",
+			Method:          http.MethodGet,
+			URL:             "/api/jobs/" + invoicingJobID + "/pos/invoicing",
+			Headers:         map[string]string{"Authorization": noClaimsToken},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{`"code":"unauthorized"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
+
+func TestCreatePOInvoicingRecord(t *testing.T) {
+	invoicerToken, err := testutils.GenerateRecordToken("users", invoicingUserEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportToken, err := testutils.GenerateRecordToken("users", invoicingReportUserEmail)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	recordsURL := func(poID string) string {
+		return "/api/jobs/" + invoicingJobID + "/pos/invoicing/" + poID + "/records"
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "invoicing claim holder appends a record with server-assigned recorder",
+			Method: http.MethodPost,
+			URL:    recordsURL(invoicingActivePOID),
+			Body: strings.NewReader(`{
+				"invoiced_on": "2026-06-10",
+				"amount": 99.99,
+				"invoice_number": " INV-1003 ",
+				"note": "final holdback"
+			}`),
+			Headers:        map[string]string{"Authorization": invoicerToken},
+			ExpectedStatus: http.StatusCreated,
+			ExpectedContent: []string{
+				`"invoice_number":"INV-1003"`,
+				`"uid":"u_invoicer"`,
+				`"job":"` + invoicingJobID + `"`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, _ *http.Response) {
+				if got := countPOInvoicingRecords(tb, app, invoicingActivePOID); got != 3 {
+					tb.Fatalf("expected 3 invoicing records, got %d", got)
+				}
+				record, err := app.FindFirstRecordByFilter("po_invoicing_records", "invoice_number = 'INV-1003'")
+				if err != nil {
+					tb.Fatalf("failed to load created record: %v", err)
+				}
+				if got := record.GetString("client"); got != "iq7ge8osf6kuj3l" {
+					tb.Fatalf("expected client derived from job, got %q", got)
+				}
+			},
+		},
+		{
+			Name:   "backdated record is returned rather than the newest existing one",
+			Method: http.MethodPost,
+			URL:    recordsURL(invoicingActivePOID),
+			Body: strings.NewReader(`{
+				"invoiced_on": "2026-05-20",
+				"amount": 12.5,
+				"invoice_number": "INV-0999"
+			}`),
+			Headers:        map[string]string{"Authorization": invoicerToken},
+			ExpectedStatus: http.StatusCreated,
+			ExpectedContent: []string{
+				`"invoice_number":"INV-0999"`,
+				`"invoiced_on":"2026-05-20"`,
+				`"amount":12.5`,
+			},
+			NotExpectedContent: []string{`"INV-1002"`},
+			TestAppFactory:     testutils.SetupTestApp,
+		},
+		{
+			Name:            "report claim alone cannot create records",
+			Method:          http.MethodPost,
+			URL:             recordsURL(invoicingActivePOID),
+			Body:            strings.NewReader(`{"invoiced_on":"2026-06-10","amount":10}`),
+			Headers:         map[string]string{"Authorization": reportToken},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{`"code":"unauthorized"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:            "unapproved PO is not eligible",
+			Method:          http.MethodPost,
+			URL:             recordsURL(invoicingUnapprovedPOID),
+			Body:            strings.NewReader(`{"invoiced_on":"2026-06-10","amount":10}`),
+			Headers:         map[string]string{"Authorization": invoicerToken},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"code":"po_not_eligible_status"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:            "cancelled PO is not eligible",
+			Method:          http.MethodPost,
+			URL:             recordsURL(invoicingCancelledPOID),
+			Body:            strings.NewReader(`{"invoiced_on":"2026-06-10","amount":10}`),
+			Headers:         map[string]string{"Authorization": invoicerToken},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"code":"po_not_eligible_status"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:            "PO belonging to another job is not found",
+			Method:          http.MethodPost,
+			URL:             recordsURL(invoicingOtherJobClosedPO),
+			Body:            strings.NewReader(`{"invoiced_on":"2026-06-10","amount":10}`),
+			Headers:         map[string]string{"Authorization": invoicerToken},
+			ExpectedStatus:  http.StatusNotFound,
+			ExpectedContent: []string{`"code":"not_found"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:           "amount, date and text lengths are validated",
+			Method:         http.MethodPost,
+			URL:            recordsURL(invoicingActivePOID),
+			Body:           strings.NewReader(`{"invoiced_on":"2999-01-01","amount":-5,"invoice_number":"` + strings.Repeat("x", 101) + `"}`),
+			Headers:        map[string]string{"Authorization": invoicerToken},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedContent: []string{
+				`"invoiced_on":{"code":"future_date"`,
+				`"amount":{`,
+				`"invoice_number":{`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, _ *http.Response) {
+				if got := countPOInvoicingRecords(tb, app, invoicingActivePOID); got != 2 {
+					tb.Fatalf("expected no new invoicing records, got %d", got)
+				}
+			},
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
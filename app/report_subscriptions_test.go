@@ -0,0 +1,233 @@
+package main
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tybalt/internal/testutils"
+	"tybalt/reports"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// =============================================================================
+// Report Subscriptions
+// =============================================================================
+//
+// Fixtures: fatt@mac.com (report claim) has an active weekly_time subscription
+// (rsfattweekly001) and time@test.com (no report claim) has an active
+// payroll_expense subscription (rstimeexpense01). Monday 2026-04-27 follows
+// week ending 2026-04-25, which is also a pay period ending with committed
+// time sheets and expenses.
+
+const (
+	reportSubscriptionFattWeeklyID = "rsfattweekly001"
+	reportSubscriptionNoClaimID    = "rstimeexpense01"
+)
+
+var reportSubscriptionDeliveryTime = time.Date(2026, 4, 27, 11, 0, 0, 0, time.UTC)
+
+func TestScheduledReportPeriod(t *testing.T) {
+	cases := []struct {
+		name   string
+		report string
+		now    time.Time
+		want   string
+	}{
+		{"weekly on monday covers previous saturday", "weekly_time", time.Date(2026, 4, 27, 11, 0, 0, 0, time.UTC), "2026-04-25"},
+		{"weekly on saturday covers the week before", "weekly_time", time.Date(2026, 4, 25, 11, 0, 0, 0, time.UTC), "2026-04-18"},
+		{"payroll on pay period monday", "payroll_time", time.Date(2026, 4, 27, 11, 0, 0, 0, time.UTC), "2026-04-25"},
+		{"payroll on off week monday", "payroll_expense", time.Date(2026, 5, 4, 11, 0, 0, 0, time.UTC), "2026-04-25"},
+		{"payables uses the week", "payables_spreadsheet", time.Date(2026, 5, 4, 11, 0, 0, 0, time.UTC), "2026-05-02"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := reports.ScheduledReportPeriod(tc.report, tc.now)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if got.Format("2006-01-02") != tc.want {
+				t.Fatalf("expected %s, got %s", tc.want, got.Format("2006-01-02"))
+			}
+		})
+	}
+
+	if _, err := reports.ScheduledReportPeriod("nope", reportSubscriptionDeliveryTime); err == nil {
+		t.Fatal("expected an error for an unknown report")
+	}
+}
+
+func TestDeliverReportSubscriptions_DeliversOncePerPeriod(t *testing.T) {
+	app := setupTestAppWithSynchronousImmediateNotifications(t)
+	defer app.Cleanup()
+
+	delivered, err := reports.DeliverReportSubscriptions(app, reportSubscriptionDeliveryTime)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if delivered != 1 {
+		t.Fatalf("expected 1 delivery, got %d", delivered)
+	}
+
+	subscription, err := app.FindRecordById("report_subscriptions", reportSubscriptionFattWeeklyID)
+	if err != nil {
+		t.Fatalf("failed to load subscription: %v", err)
+	}
+	if got := subscription.GetString("last_period"); got != "2026-04-25" {
+		t.Fatalf("expected last_period 2026-04-25, got %q", got)
+	}
+	if subscription.GetDateTime("last_delivered").IsZero() {
+		t.Fatal("expected last_delivered to be set")
+	}
+	if got := subscription.GetString("last_error"); got != "" {
+		t.Fatalf("expected no last_error, got %q", got)
+	}
+
+	var notification struct {
+		ID          string `db:"id"`
+		Status      string `db:"status"`
+		Attachments string `db:"attachments"`
+	}
+	if err := app.DB().NewQuery(`
+		SELECT n.id, n.status, n.attachments
+		FROM notifications n
+		JOIN notification_templates t ON t.id = n.template
+		WHERE t.code = 'scheduled_report' AND n.recipient = {:uid}
+	`).Bind(dbx.Params{"uid": "etysnrlup2f6bak"}).One(&notification); err != nil {
+		t.Fatalf("failed to load scheduled report notification: %v", err)
+	}
+	if notification.Status != "sent" {
+		t.Fatalf("expected notification to be sent, got %q", notification.Status)
+	}
+	if !strings.Contains(notification.Attachments, "weekly_time_2026_04_25") {
+		t.Fatalf("expected the weekly time csv to be stored on the notification, got %s", notification.Attachments)
+	}
+
+	message := app.TestMailer.LastMessage()
+	if message.Subject != "Your scheduled report is attached" {
+		t.Fatalf("unexpected subject %q", message.Subject)
+	}
+	// Stored file names are normalized, so the date separators become
+	// underscores by the time the message is sent.
+	if _, ok := message.Attachments["weekly_time_2026_04_25.csv"]; !ok {
+		t.Fatalf("expected weekly_time_2026_04_25.csv attachment, got %v", message.Attachments)
+	}
+	if !strings.Contains(message.Text, "Weekly Time report for 2026-04-25") {
+		t.Fatalf("unexpected message body %q", message.Text)
+	}
+
+	noClaim, err := app.FindRecordById("report_subscriptions", reportSubscriptionNoClaimID)
+	if err != nil {
+		t.Fatalf("failed to load subscription: %v", err)
+	}
+	if got := noClaim.GetString("last_error"); !strings.Contains(got, "report claim") {
+		t.Fatalf("expected a report claim error, got %q", got)
+	}
+	if got := noClaim.GetString("last_period"); got != "" {
+		t.Fatalf("expected no last_period for a subscriber without the claim, got %q", got)
+	}
+
+	sentBefore := app.TestMailer.TotalSend()
+	delivered, err = reports.DeliverReportSubscriptions(app, reportSubscriptionDeliveryTime.Add(2*time.Hour))
+	if err != nil {
+		t.Fatalf("unexpected error on second run: %v", err)
+	}
+	if delivered != 0 {
+		t.Fatalf("expected no deliveries on second run, got %d", delivered)
+	}
+	if app.TestMailer.TotalSend() != sentBefore {
+		t.Fatal("expected no additional emails on second run")
+	}
+}
+
+func TestReportSubscriptionsCollectionRules(t *testing.T) {
+	reportToken, err := testutils.GenerateRecordToken("users", "fatt@mac.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	timeToken, err := testutils.GenerateRecordToken("users", "time@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:           "report claim holder subscribes to a report",
+			Method:         http.MethodPost,
+			URL:            "/api/collections/report_subscriptions/records",
+			Body:           strings.NewReader(`{"uid":"etysnrlup2f6bak","report":"payroll_time","active":true}`),
+			Headers:        map[string]string{"Authorization": reportToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"report":"payroll_time"`,
+				`"last_period":""`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnRecordCreate":        1,
+				"OnRecordCreateRequest": 1,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "user without the report claim cannot subscribe",
+			Method:         http.MethodPost,
+			URL:            "/api/collections/report_subscriptions/records",
+			Body:           strings.NewReader(`{"uid":"rzr98oadsp9qc11","report":"payroll_time","active":true}`),
+			Headers:        map[string]string{"Authorization": timeToken},
+			ExpectedStatus: http.StatusBadRequest,
+			ExpectedContent: []string{
+				`"message":"Failed to create record."`,
+			},
+			ExpectedEvents: map[string]int{"*": 0},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "delivery state cannot be set by the owner",
+			Method:         http.MethodPatch,
+			URL:            "/api/collections/report_subscriptions/records/" + reportSubscriptionFattWeeklyID,
+			Body:           strings.NewReader(`{"last_period":"2099-01-01"}`),
+			Headers:        map[string]string{"Authorization": reportToken},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedContent: []string{
+				`"status":404`,
+			},
+			ExpectedEvents: map[string]int{"*": 0},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "owner can pause a subscription",
+			Method:         http.MethodPatch,
+			URL:            "/api/collections/report_subscriptions/records/" + reportSubscriptionFattWeeklyID,
+			Body:           strings.NewReader(`{"active":false}`),
+			Headers:        map[string]string{"Authorization": reportToken},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"active":false`,
+			},
+			ExpectedEvents: map[string]int{
+				"OnRecordUpdate":        1,
+				"OnRecordUpdateRequest": 1,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:           "other users cannot see a subscription",
+			Method:         http.MethodGet,
+			URL:            "/api/collections/report_subscriptions/records/" + reportSubscriptionFattWeeklyID,
+			Headers:        map[string]string{"Authorization": timeToken},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedContent: []string{
+				`"status":404`,
+			},
+			ExpectedEvents: map[string]int{"*": 0},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
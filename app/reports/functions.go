@@ -5,6 +5,7 @@ import (
 	"bytes"
 	"encoding/csv"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"slices"
@@ -73,6 +74,10 @@ func convertToCSV(report []dbx.NullStringMap, headers []string) (string, error)
 	return builder.String(), nil
 }
 
+// errNoAttachmentsToZip is returned by zipAttachments for an empty report so
+// scheduled deliveries can send an empty-period notice instead of failing.
+var errNoAttachmentsToZip = errors.New("no attachments to zip")
+
 // zipAttachments takes a slice of Attachment and produces a zip archive of each
 // file referenced by the source_path property giving it the corresponding
 // filename from the filename property. It then creates a zip_cache record with a
@@ -80,7 +85,7 @@ func convertToCSV(report []dbx.NullStringMap, headers []string) (string, error)
 // zip_cache record.
 func zipAttachments(app core.App, report []Attachment, collectionId string, class string, key string) (*core.Record, error) {
 	if len(report) == 0 {
-		return nil, fmt.Errorf("no attachments to zip")
+		return nil, errNoAttachmentsToZip
 	}
 	manifest := attachmentManifest(report, collectionId)
 
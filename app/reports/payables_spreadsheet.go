@@ -6,6 +6,8 @@ import (
 	"fmt"
 	"net/http"
 	"regexp"
+	"slices"
+	"strconv"
 	"strings"
 	"time"
 	"tybalt/utilities"
@@ -42,9 +44,12 @@ var payablesSpreadsheetHeaders = []string{
 	"Description", "Supplier", "Employee",
 	"Approved By", "Entered By", "Vendor Inv #", "Inv Date",
 	"Notes", "Pd By", "TBTE #", "Status",
+	"Tax Code", "GST", "PST", "QST",
+	"Est. Tax Code", "Est. Subtotal", "Est. HST", "Est. GST", "Est. PST", "Est. QST",
 }
 
 type payablesRow struct {
+	POID         string `db:"po_id"`
 	PaymentType  string `db:"payment_type"`
 	JobNumber    string `db:"job_number"`
 	DivisionCode string `db:"division_code"`
@@ -60,6 +65,93 @@ type payablesRow struct {
 	Employee     string `db:"employee"`
 	ApprovedBy   string `db:"approved_by"`
 	Status       string `db:"status"`
+	// VendorTaxCode and BranchID pick the PO's default tax code for the
+	// estimated split.
+	VendorTaxCode string `db:"vendor_tax_code"`
+	BranchID      string `db:"branch_id"`
+	// Tax is the split recorded on the expenses against the PO and fills the
+	// Subtotal, HST, Tax Code, GST, PST and QST columns. EstimatedTax is the
+	// default split of the PO total, which is only an estimate, and fills the
+	// Est. columns instead. At most one of them is set.
+	Tax          payablesTaxSplit `db:"-"`
+	EstimatedTax payablesTaxSplit `db:"-"`
+}
+
+// payablesTaxSplit holds the formatted subtotal and sales tax columns of a
+// payables row. Components the tax code does not charge are blank.
+type payablesTaxSplit struct {
+	Code     string
+	Subtotal string
+	HST      string
+	GST      string
+	PST      string
+	QST      string
+}
+
+func newPayablesTaxSplit(code string, subtotal float64, components map[string]float64) payablesTaxSplit {
+	amount := func(component string) string {
+		if value, ok := components[component]; ok {
+			return fmt.Sprintf("%.2f", value)
+		}
+		return ""
+	}
+	return payablesTaxSplit{
+		Code:     code,
+		Subtotal: fmt.Sprintf("%.2f", subtotal),
+		HST:      amount("HST"),
+		GST:      amount("GST"),
+		PST:      amount("PST"),
+		QST:      amount("QST"),
+	}
+}
+
+// payablesExpenseTax is the recorded sales tax of an approved expense against
+// a PO.
+type payablesExpenseTax struct {
+	PurchaseOrder string  `db:"purchase_order"`
+	TaxCode       string  `db:"tax_code"`
+	TaxAmount     float64 `db:"tax_amount"`
+	PreTaxAmount  float64 `db:"pre_tax_amount"`
+}
+
+// applySalesTax fills the split of a home currency PO. When the PO has
+// approved expenses and every one of them records a tax code, their recorded
+// amounts are added up into the Subtotal, HST and tax columns. Otherwise those
+// columns stay blank for payables to key in from the invoice, and the PO total
+// is split at the default tax code for the PO's vendor and branch into the
+// estimate columns. Foreign currency POs are left blank.
+func (r *payablesRow) applySalesTax(config utilities.SalesTaxConfig, expenses []payablesExpenseTax) {
+	total, err := strconv.ParseFloat(r.Total, 64)
+	if err != nil || !strings.EqualFold(r.CurrencyCode, utilities.HomeCurrencyCode) {
+		return
+	}
+
+	recorded := len(expenses) > 0
+	codes := []string{}
+	subtotal := 0.0
+	components := map[string]float64{}
+	for _, expense := range expenses {
+		if expense.TaxCode == "" {
+			recorded = false
+			break
+		}
+		if !slices.Contains(codes, expense.TaxCode) {
+			codes = append(codes, expense.TaxCode)
+		}
+		subtotal += expense.PreTaxAmount
+		for component, value := range config.Components(expense.TaxCode, expense.TaxAmount) {
+			components[component] = utilities.RoundCurrencyAmount(components[component] + value)
+		}
+	}
+	if recorded {
+		slices.Sort(codes)
+		r.Tax = newPayablesTaxSplit(strings.Join(codes, "/"), utilities.RoundCurrencyAmount(subtotal), components)
+		return
+	}
+
+	code := config.DefaultCode(r.VendorTaxCode, r.BranchID)
+	estimatedSubtotal, tax := config.SplitInclusive(code, total)
+	r.EstimatedTax = newPayablesTaxSplit(code, estimatedSubtotal, config.Components(code, tax))
 }
 
 func (r payablesRow) toRecord() []string {
@@ -74,10 +166,13 @@ func (r payablesRow) toRecord() []string {
 	return []string{
 		r.PaymentType, r.JobNumber, r.DivisionCode, r.BranchCode, r.POType,
 		day, mon, year,
-		"", "", r.Total, r.CurrencyCode,
+		r.Tax.Subtotal, r.Tax.HST, r.Total, r.CurrencyCode,
 		r.PONumber, "", r.Description, r.VendorName, r.Employee,
 		r.ApprovedBy, "TURBO", "", "", "", "", "",
 		r.Status,
+		r.Tax.Code, r.Tax.GST, r.Tax.PST, r.Tax.QST,
+		r.EstimatedTax.Code, r.EstimatedTax.Subtotal, r.EstimatedTax.HST,
+		r.EstimatedTax.GST, r.EstimatedTax.PST, r.EstimatedTax.QST,
 	}
 }
 
@@ -89,8 +184,46 @@ func queryPayablesRows(app core.App, extraWhere string, params dbx.Params) ([]pa
 	query += "\nORDER BY approval_date ASC"
 
 	var rows []payablesRow
-	err := app.DB().NewQuery(query).Bind(params).All(&rows)
-	return rows, err
+	if err := app.DB().NewQuery(query).Bind(params).All(&rows); err != nil {
+		return nil, err
+	}
+	expenses, err := queryPayablesExpenseTax(app, rows)
+	if err != nil {
+		return nil, err
+	}
+	config := utilities.LoadSalesTaxConfig(app)
+	for i := range rows {
+		rows[i].applySalesTax(config, expenses[rows[i].POID])
+	}
+	return rows, nil
+}
+
+// queryPayablesExpenseTax loads the recorded sales tax of the approved,
+// unrejected expenses against the POs in rows, keyed by PO id.
+func queryPayablesExpenseTax(app core.App, rows []payablesRow) (map[string][]payablesExpenseTax, error) {
+	byPO := map[string][]payablesExpenseTax{}
+	if len(rows) == 0 {
+		return byPO, nil
+	}
+	ids := make([]any, 0, len(rows))
+	for _, row := range rows {
+		ids = append(ids, row.POID)
+	}
+
+	var expenses []payablesExpenseTax
+	err := app.DB().
+		Select("purchase_order", "COALESCE(tax_code, '') AS tax_code", "COALESCE(tax_amount, 0) AS tax_amount", "COALESCE(pre_tax_amount, 0) AS pre_tax_amount").
+		From("expenses").
+		Where(dbx.In("purchase_order", ids...)).
+		AndWhere(dbx.NewExp("approved != '' AND rejected = ''")).
+		All(&expenses)
+	if err != nil {
+		return nil, err
+	}
+	for _, expense := range expenses {
+		byPO[expense.PurchaseOrder] = append(byPO[expense.PurchaseOrder], expense)
+	}
+	return byPO, nil
 }
 
 func rowsToCSV(rows []payablesRow) (string, error) {
@@ -1,4 +1,5 @@
 SELECT
+  po.id AS po_id,
   po.payment_type,
   COALESCE(j.number, '') AS job_number,
   COALESCE(d.code, '') AS division_code,
@@ -15,6 +16,8 @@ SELECT
   po.po_number,
   po.description,
   COALESCE(v.name, '') AS vendor_name,
+  COALESCE(v.tax_code, '') AS vendor_tax_code,
+  COALESCE(po.branch, '') AS branch_id,
   COALESCE(owner.given_name || ' ' || owner.surname, '') AS employee,
   CASE
     WHEN po.second_approver != ''
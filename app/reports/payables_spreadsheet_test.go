@@ -3,6 +3,8 @@ package reports
 import (
 	"strings"
 	"testing"
+
+	"tybalt/utilities"
 )
 
 func TestPayablesRowToRecordUsesRecordDateForDisplayColumns(t *testing.T) {
@@ -151,3 +153,67 @@ func TestRowsToCSVAndTSV(t *testing.T) {
 		t.Fatalf("tsv should end with newline, got %q", tsvString)
 	}
 }
+
+func TestPayablesRowApplySalesTax(t *testing.T) {
+	t.Parallel()
+
+	config := utilities.SalesTaxConfig{
+		Codes:           map[string]map[string]float64{utilities.SalesTaxCodeExempt: {}, "ON": {"HST": 13}, "BC": {"GST": 5, "PST": 7}},
+		Branches:        map[string]string{"bcbranch": "BC"},
+		DefaultProvince: "ON",
+	}
+	tail := func(record []string) string {
+		return strings.Join(record[len(record)-10:], ",")
+	}
+
+	// Without expenses the PO total is only an estimate, so the Subtotal,
+	// HST and tax columns stay blank.
+	onRow := payablesRow{Total: "113", CurrencyCode: "CAD", BranchID: "onbranch"}
+	onRow.applySalesTax(config, nil)
+	record := onRow.toRecord()
+	if record[8] != "" || record[9] != "" || record[10] != "113" {
+		t.Fatalf("expected a PO without expenses to leave Subtotal and HST blank, got %q", record[8:11])
+	}
+	if got := tail(record); got != ",,,,ON,100.00,13.00,,," {
+		t.Fatalf("expected the ON estimate in the estimate columns only, got %q", got)
+	}
+
+	bcRow := payablesRow{Total: "112", CurrencyCode: "CAD", BranchID: "bcbranch"}
+	bcRow.applySalesTax(config, nil)
+	if got := tail(bcRow.toRecord()); got != ",,,,BC,100.00,,5.00,7.00," {
+		t.Fatalf("expected a BC estimate of GST and PST, got %q", got)
+	}
+
+	exemptRow := payablesRow{Total: "50", CurrencyCode: "CAD", BranchID: "bcbranch", VendorTaxCode: "exempt"}
+	exemptRow.applySalesTax(config, nil)
+	if exemptRow.EstimatedTax.Subtotal != "50.00" || exemptRow.EstimatedTax.Code != utilities.SalesTaxCodeExempt || exemptRow.EstimatedTax.GST != "" {
+		t.Fatalf("expected the vendor's tax code to win over the branch, got %+v", exemptRow.EstimatedTax)
+	}
+
+	// Approved expenses that record their tax fill the real columns.
+	recordedRow := payablesRow{Total: "300", CurrencyCode: "CAD", BranchID: "onbranch"}
+	recordedRow.applySalesTax(config, []payablesExpenseTax{
+		{TaxCode: "ON", TaxAmount: 13, PreTaxAmount: 100},
+		{TaxCode: "BC", TaxAmount: 12, PreTaxAmount: 100},
+	})
+	record = recordedRow.toRecord()
+	if record[8] != "200.00" || record[9] != "13.00" {
+		t.Fatalf("expected the expenses' subtotal and HST, got %q", record[8:10])
+	}
+	if got := tail(record); got != "BC/ON,5.00,7.00,,,,,,," {
+		t.Fatalf("expected the expenses' codes and components without an estimate, got %q", got)
+	}
+
+	// An expense without a tax code leaves the real split unknown.
+	partialRow := payablesRow{Total: "113", CurrencyCode: "CAD", BranchID: "onbranch"}
+	partialRow.applySalesTax(config, []payablesExpenseTax{{TaxCode: "ON", TaxAmount: 13, PreTaxAmount: 100}, {}})
+	if partialRow.Tax != (payablesTaxSplit{}) || partialRow.EstimatedTax.Code != "ON" {
+		t.Fatalf("expected only an estimate when an expense has no tax code, got %+v %+v", partialRow.Tax, partialRow.EstimatedTax)
+	}
+
+	foreignRow := payablesRow{Total: "113", CurrencyCode: "USD", BranchID: "onbranch"}
+	foreignRow.applySalesTax(config, []payablesExpenseTax{{TaxCode: "ON", TaxAmount: 13, PreTaxAmount: 100}})
+	if foreignRow.Tax != (payablesTaxSplit{}) || foreignRow.EstimatedTax != (payablesTaxSplit{}) {
+		t.Fatalf("expected foreign currency POs to be left blank, got %+v %+v", foreignRow.Tax, foreignRow.EstimatedTax)
+	}
+}
@@ -0,0 +1,45 @@
+-- Worked (R/RT) and banked (RB) hours per payroll row and day for the payroll
+-- time report. reports.go splits these with the overtime rules of the
+-- employee's default branch. Amendments are included under their committed
+-- week, matching payroll_time.sql.
+SELECT payrollId,
+  salary,
+  defaultBranch,
+  date,
+  SUM(workedHours) AS workedHours,
+  SUM(bankedHours) AS bankedHours
+FROM (
+  SELECT ap.payroll_id AS payrollId,
+    ts.salary AS salary,
+    ap.default_branch AS defaultBranch,
+    te.date AS date,
+    CASE WHEN tt.code IN ('R', 'RT') THEN IFNULL(te.hours, 0) ELSE 0 END AS workedHours,
+    CASE WHEN tt.code = 'RB' THEN IFNULL(te.hours, 0) ELSE 0 END AS bankedHours
+  FROM time_entries te
+  LEFT JOIN time_sheets ts ON te.tsid = ts.id
+  LEFT JOIN admin_profiles ap ON ap.uid = te.uid
+  LEFT JOIN time_types tt ON te.time_type = tt.id
+  WHERE te.week_ending = {:weekEnding}
+  AND te.tsid != ''
+  AND ts.committed != ''
+  AND tt.code IN ('R', 'RT', 'RB')
+  AND NOT ({:placeholder_payroll_id_condition})
+
+  UNION ALL
+
+  SELECT ap.payroll_id AS payrollId,
+    ap.salary AS salary,
+    ap.default_branch AS defaultBranch,
+    ta.date AS date,
+    CASE WHEN tt.code IN ('R', 'RT') THEN IFNULL(ta.hours, 0) ELSE 0 END AS workedHours,
+    CASE WHEN tt.code = 'RB' THEN IFNULL(ta.hours, 0) ELSE 0 END AS bankedHours
+  FROM time_amendments ta
+  LEFT JOIN admin_profiles ap ON ap.uid = ta.uid
+  LEFT JOIN time_types tt ON ta.time_type = tt.id
+  WHERE ta.committed_week_ending = {:weekEnding}
+  AND ta.committed != ''
+  AND tt.code IN ('R', 'RT', 'RB')
+  AND NOT ({:placeholder_payroll_id_condition})
+)
+GROUP BY payrollId, salary, defaultBranch, date
+ORDER BY LENGTH(payrollId), payrollId, date
@@ -0,0 +1,347 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+)
+
+// PDFDocument is a minimal PDF writer for print-ready report output. It only
+// uses the standard Helvetica fonts, which every PDF reader provides, so no
+// font files are embedded and no external renderer or service is needed.
+// Content flows top to bottom and new pages are started automatically.
+type PDFDocument struct {
+	title      string
+	pageWidth  float64
+	pageHeight float64
+	margin     float64
+	pages      []*bytes.Buffer
+	current    *bytes.Buffer
+	y          float64
+	finished   bool
+}
+
+// PDFColumn describes one table column. Width is a share of the usable page
+// width; the shares of a table do not need to add up to exactly 1.
+type PDFColumn struct {
+	Header     string
+	Width      float64
+	AlignRight bool
+}
+
+const (
+	pdfLetterWidth  = 612.0
+	pdfLetterHeight = 792.0
+	pdfMargin       = 36.0
+	pdfFooterSpace  = 18.0
+	pdfCellPadding  = 3.0
+)
+
+const (
+	pdfFontRegular = "F1"
+	pdfFontBold    = "F2"
+)
+
+// helveticaWidths holds the Helvetica advance widths, in 1/1000 em, for the
+// printable ASCII range starting at the space character.
+var helveticaWidths = [...]int{
+	278, 278, 355, 556, 556, 889, 667, 191, 333, 333, 389, 584, 278, 333, 278, 278,
+	556, 556, 556, 556, 556, 556, 556, 556, 556, 556, 278, 278, 584, 584, 584, 556,
+	1015, 667, 667, 722, 722, 667, 611, 778, 722, 278, 500, 667, 556, 833, 722, 778,
+	667, 778, 722, 667, 611, 722, 667, 944, 667, 667, 611, 278, 278, 278, 469, 556,
+	333, 556, 556, 500, 556, 556, 278, 556, 556, 222, 222, 500, 222, 833, 556, 556,
+	556, 556, 333, 500, 278, 556, 500, 722, 500, 500, 500, 334, 260, 334, 584,
+}
+
+// NewPDFDocument starts a US Letter document. Landscape suits wide tables such
+// as the job time report.
+func NewPDFDocument(title string, landscape bool) *PDFDocument {
+	doc := &PDFDocument{
+		title:      title,
+		pageWidth:  pdfLetterWidth,
+		pageHeight: pdfLetterHeight,
+		margin:     pdfMargin,
+	}
+	if landscape {
+		doc.pageWidth, doc.pageHeight = doc.pageHeight, doc.pageWidth
+	}
+	doc.addPage()
+	return doc
+}
+
+// PageCount returns the number of pages written so far.
+func (d *PDFDocument) PageCount() int {
+	return len(d.pages)
+}
+
+func (d *PDFDocument) contentWidth() float64 {
+	return d.pageWidth - 2*d.margin
+}
+
+func (d *PDFDocument) addPage() {
+	d.current = &bytes.Buffer{}
+	d.pages = append(d.pages, d.current)
+	d.y = d.pageHeight - d.margin
+}
+
+// ensureSpace starts a new page when fewer than height points remain above
+// the footer. It reports whether a page break happened.
+func (d *PDFDocument) ensureSpace(height float64) bool {
+	if d.y-height >= d.margin+pdfFooterSpace {
+		return false
+	}
+	d.addPage()
+	return true
+}
+
+// Heading writes a bold line, larger for level 1 than for level 2.
+func (d *PDFDocument) Heading(text string, level int) {
+	size := 11.0
+	if level <= 1 {
+		size = 15.0
+	}
+	lineHeight := size * 1.4
+	d.ensureSpace(lineHeight * 2)
+	d.y -= lineHeight
+	d.writeText(d.margin, d.y, pdfFontBold, size, text)
+	d.y -= size * 0.4
+}
+
+// Paragraph writes text wrapped to the page width.
+func (d *PDFDocument) Paragraph(text string) {
+	const size = 9.0
+	for _, line := range wrapPDFText(text, d.contentWidth(), size, false) {
+		d.ensureSpace(size * 1.4)
+		d.y -= size * 1.4
+		d.writeText(d.margin, d.y, pdfFontRegular, size, line)
+	}
+	d.y -= size * 0.4
+}
+
+// Fields writes label/value pairs in two columns. Empty values are skipped so
+// optional fields do not leave blank labels on the page.
+func (d *PDFDocument) Fields(fields [][2]string) {
+	const size = 9.0
+	labelWidth := d.contentWidth() * 0.25
+	valueWidth := d.contentWidth() - labelWidth
+	for _, field := range fields {
+		if strings.TrimSpace(field[1]) == "" {
+			continue
+		}
+		lines := wrapPDFText(field[1], valueWidth, size, false)
+		for i, line := range lines {
+			d.ensureSpace(size * 1.4)
+			d.y -= size * 1.4
+			if i == 0 {
+				d.writeText(d.margin, d.y, pdfFontBold, size, field[0])
+			}
+			d.writeText(d.margin+labelWidth, d.y, pdfFontRegular, size, line)
+		}
+	}
+	d.y -= size * 0.6
+}
+
+// Table writes rows under a header row. Cells wrap within their column and
+// the header is repeated after each page break.
+func (d *PDFDocument) Table(columns []PDFColumn, rows [][]string) {
+	const size = 8.0
+	lineHeight := size * 1.3
+
+	totalShare := 0.0
+	for _, column := range columns {
+		totalShare += column.Width
+	}
+	widths := make([]float64, len(columns))
+	for i, column := range columns {
+		widths[i] = d.contentWidth() * column.Width / totalShare
+	}
+
+	writeRow := func(cells []string, font string, shade bool) {
+		wrapped := make([][]string, len(columns))
+		lineCount := 1
+		for i := range columns {
+			cell := ""
+			if i < len(cells) {
+				cell = cells[i]
+			}
+			wrapped[i] = wrapPDFText(cell, widths[i]-2*pdfCellPadding, size, font == pdfFontBold)
+			lineCount = max(lineCount, len(wrapped[i]))
+		}
+		rowHeight := float64(lineCount)*lineHeight + pdfCellPadding
+		top := d.y
+		if shade {
+			fmt.Fprintf(d.current, "0.9 g %s %s %s %s re f 0 g\n",
+				pdfNumber(d.margin), pdfNumber(top-rowHeight), pdfNumber(d.contentWidth()), pdfNumber(rowHeight))
+		}
+		x := d.margin
+		for i, column := range columns {
+			for j, line := range wrapped[i] {
+				lineX := x + pdfCellPadding
+				if column.AlignRight {
+					lineX = x + widths[i] - pdfCellPadding - pdfTextWidth(line, size, font == pdfFontBold)
+				}
+				d.writeText(lineX, top-float64(j+1)*lineHeight+2, font, size, line)
+			}
+			x += widths[i]
+		}
+		d.y = top - rowHeight
+		fmt.Fprintf(d.current, "0.75 G 0.5 w %s %s m %s %s l S 0 G\n",
+			pdfNumber(d.margin), pdfNumber(d.y), pdfNumber(d.margin+d.contentWidth()), pdfNumber(d.y))
+	}
+
+	headers := make([]string, len(columns))
+	for i, column := range columns {
+		headers[i] = column.Header
+	}
+	d.ensureSpace(lineHeight * 3)
+	writeRow(headers, pdfFontBold, true)
+	for _, row := range rows {
+		if d.ensureSpace(lineHeight*2 + pdfCellPadding) {
+			writeRow(headers, pdfFontBold, true)
+		}
+		writeRow(row, pdfFontRegular, false)
+	}
+	d.y -= size
+}
+
+func (d *PDFDocument) writeText(x float64, y float64, font string, size float64, text string) {
+	if text == "" {
+		return
+	}
+	fmt.Fprintf(d.current, "BT /%s %s Tf %s %s Td (%s) Tj ET\n", font, pdfNumber(size), pdfNumber(x), pdfNumber(y), pdfEscape(text))
+}
+
+// Bytes finishes the document, adding the title and page numbers to every
+// page footer, and returns the encoded PDF. Nothing should be written to the
+// document after the first call.
+func (d *PDFDocument) Bytes() []byte {
+	if !d.finished {
+		for i, page := range d.pages {
+			d.current = page
+			footerY := d.margin / 2
+			d.writeText(d.margin, footerY, pdfFontRegular, 7, d.title)
+			pageLabel := fmt.Sprintf("Page %d of %d", i+1, len(d.pages))
+			d.writeText(d.pageWidth-d.margin-pdfTextWidth(pageLabel, 7, false), footerY, pdfFontRegular, 7, pageLabel)
+		}
+		d.finished = true
+	}
+
+	// Objects 1-5 are the catalog, page tree, the two fonts and the info
+	// dictionary; each page then adds a page object and a content stream.
+	objects := []string{
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"", // page tree, filled in once the page object numbers are known
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica-Bold /Encoding /WinAnsiEncoding >>",
+		fmt.Sprintf("<< /Title (%s) /Producer (Tybalt) >>", pdfEscape(d.title)),
+	}
+	kids := make([]string, 0, len(d.pages))
+	for _, page := range d.pages {
+		pageObject := len(objects) + 1
+		kids = append(kids, fmt.Sprintf("%d 0 R", pageObject))
+		objects = append(objects,
+			fmt.Sprintf("<< /Type /Page /Parent 2 0 R /MediaBox [0 0 %s %s] /Resources << /Font << /%s 3 0 R /%s 4 0 R >> >> /Contents %d 0 R >>",
+				pdfNumber(d.pageWidth), pdfNumber(d.pageHeight), pdfFontRegular, pdfFontBold, pageObject+1),
+			fmt.Sprintf("<< /Length %d >>\nstream\n%sendstream", page.Len(), page.String()),
+		)
+	}
+	objects[1] = fmt.Sprintf("<< /Type /Pages /Kids [%s] /Count %d >>", strings.Join(kids, " "), len(d.pages))
+
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n%\xe2\xe3\xcf\xd3\n")
+	offsets := make([]int, len(objects))
+	for i, object := range objects {
+		offsets[i] = out.Len()
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	xrefOffset := out.Len()
+	fmt.Fprintf(&out, "xref\n0 %d\n0000000000 65535 f \n", len(objects)+1)
+	for _, offset := range offsets {
+		fmt.Fprintf(&out, "%010d 00000 n \n", offset)
+	}
+	fmt.Fprintf(&out, "trailer\n<< /Size %d /Root 1 0 R /Info 5 0 R >>\nstartxref\n%d\n%%%%EOF\n", len(objects)+1, xrefOffset)
+	return out.Bytes()
+}
+
+// pdfEscape encodes text for a PDF string literal using WinAnsiEncoding.
+// Latin-1 characters map directly; anything else is replaced with '?'.
+func pdfEscape(text string) string {
+	var b strings.Builder
+	for _, r := range text {
+		switch {
+		case r == '\\' || r == '(' || r == ')':
+			b.WriteByte('\\')
+			b.WriteRune(r)
+		case r == '\t' || r == '\n' || r == '\r':
+			b.WriteByte(' ')
+		case r >= 0x20 && r < 0x7f:
+			b.WriteRune(r)
+		case r >= 0xa0 && r <= 0xff:
+			fmt.Fprintf(&b, "\\%03o", r)
+		default:
+			b.WriteByte('?')
+		}
+	}
+	return b.String()
+}
+
+// pdfTextWidth estimates the rendered width of text in points. Bold text is
+// measured with a small allowance because Helvetica-Bold runs wider.
+func pdfTextWidth(text string, size float64, bold bool) float64 {
+	units := 0
+	for _, r := range text {
+		if r >= ' ' && int(r-' ') < len(helveticaWidths) {
+			units += helveticaWidths[r-' ']
+		} else {
+			units += 556
+		}
+	}
+	width := float64(units) * size / 1000
+	if bold {
+		width *= 1.07
+	}
+	return width
+}
+
+// wrapPDFText splits text into lines no wider than maxWidth, breaking on
+// spaces where possible and inside long words otherwise. Explicit newlines
+// are kept.
+func wrapPDFText(text string, maxWidth float64, size float64, bold bool) []string {
+	lines := []string{}
+	for _, paragraph := range strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n") {
+		line := ""
+		for _, word := range strings.Fields(paragraph) {
+			candidate := word
+			if line != "" {
+				candidate = line + " " + word
+			}
+			if pdfTextWidth(candidate, size, bold) <= maxWidth {
+				line = candidate
+				continue
+			}
+			if line != "" {
+				lines = append(lines, line)
+				line = ""
+			}
+			for pdfTextWidth(word, size, bold) > maxWidth {
+				cut := 1
+				for cut < len([]rune(word)) && pdfTextWidth(string([]rune(word)[:cut+1]), size, bold) <= maxWidth {
+					cut++
+				}
+				lines = append(lines, string([]rune(word)[:cut]))
+				word = string([]rune(word)[cut:])
+			}
+			line = word
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
+
+func pdfNumber(value float64) string {
+	formatted := strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.2f", value), "0"), ".")
+	if formatted == "" || formatted == "-0" {
+		return "0"
+	}
+	return formatted
+}
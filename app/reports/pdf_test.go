@@ -0,0 +1,95 @@
+package reports
+
+import (
+	"bytes"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+)
+
+func TestPDFDocumentCrossReferenceOffsets(t *testing.T) {
+	doc := NewPDFDocument("Offsets", false)
+	doc.Heading("Offsets", 1)
+	doc.Paragraph("A short paragraph.")
+	out := doc.Bytes()
+
+	if !bytes.HasPrefix(out, []byte("%PDF-1.4\n")) || !bytes.HasSuffix(out, []byte("%%EOF\n")) {
+		t.Fatalf("missing PDF header or trailer")
+	}
+	startxref := regexp.MustCompile(`startxref\n(\d+)\n`).FindSubmatch(out)
+	if startxref == nil {
+		t.Fatalf("missing startxref")
+	}
+	xrefOffset, _ := strconv.Atoi(string(startxref[1]))
+	if !bytes.HasPrefix(out[xrefOffset:], []byte("xref\n")) {
+		t.Fatalf("startxref %d does not point at the xref table", xrefOffset)
+	}
+	entries := regexp.MustCompile(`(\d{10}) 00000 n `).FindAllSubmatch(out[xrefOffset:], -1)
+	if len(entries) != 7 {
+		t.Fatalf("xref entries = %d, want 7 for a one page document", len(entries))
+	}
+	for i, entry := range entries {
+		offset, _ := strconv.Atoi(string(entry[1]))
+		want := fmt.Sprintf("%d 0 obj\n", i+1)
+		if !bytes.HasPrefix(out[offset:], []byte(want)) {
+			t.Fatalf("xref entry %d points at %q, want %q", i+1, out[offset:offset+len(want)], want)
+		}
+	}
+	if !bytes.Equal(out, doc.Bytes()) {
+		t.Fatalf("Bytes is not stable across calls")
+	}
+}
+
+func TestPDFDocumentTableBreaksPagesAndRepeatsHeader(t *testing.T) {
+	doc := NewPDFDocument("Long table", true)
+	rows := make([][]string, 0, 120)
+	for i := range 120 {
+		rows = append(rows, []string{strconv.Itoa(i), "a description long enough that it has to wrap inside the narrow column"})
+	}
+	doc.Table([]PDFColumn{{Header: "Row", Width: 0.1}, {Header: "Description", Width: 0.2}}, rows)
+	out := string(doc.Bytes())
+
+	if doc.PageCount() < 2 {
+		t.Fatalf("page count = %d, want a page break", doc.PageCount())
+	}
+	if got := strings.Count(out, "(Description) Tj"); got != doc.PageCount() {
+		t.Fatalf("header rows = %d, want one per page (%d)", got, doc.PageCount())
+	}
+	if !strings.Contains(out, fmt.Sprintf("/Count %d", doc.PageCount())) {
+		t.Fatalf("page tree count does not match %d pages", doc.PageCount())
+	}
+	if !strings.Contains(out, fmt.Sprintf("(Page %d of %d) Tj", doc.PageCount(), doc.PageCount())) {
+		t.Fatalf("missing final page footer")
+	}
+}
+
+func TestPDFEscape(t *testing.T) {
+	tests := map[string]string{
+		`plain`:          `plain`,
+		`(a) \ b`:        `\(a\) \\ b`,
+		"tab\tnewline\n": "tab newline ",
+		"café":           `caf\351`,
+		"→ arrow":        "? arrow",
+	}
+	for input, want := range tests {
+		if got := pdfEscape(input); got != want {
+			t.Errorf("pdfEscape(%q) = %q, want %q", input, got, want)
+		}
+	}
+}
+
+func TestWrapPDFText(t *testing.T) {
+	lines := wrapPDFText("one two three four five six", pdfTextWidth("one two three", 10, false), 10, false)
+	if strings.Join(lines, "|") != "one two three|four five six" {
+		t.Fatalf("lines = %q", lines)
+	}
+	long := wrapPDFText("abcdefghijklmnopqrstuvwxyz", pdfTextWidth("abcdefghij", 10, false), 10, false)
+	if len(long) < 2 || strings.Join(long, "") != "abcdefghijklmnopqrstuvwxyz" {
+		t.Fatalf("long word lines = %q", long)
+	}
+	if got := wrapPDFText("first\nsecond", 500, 10, false); len(got) != 2 {
+		t.Fatalf("explicit newline lines = %q", got)
+	}
+}
@@ -3,6 +3,7 @@ package reports
 import (
 	"database/sql"
 	_ "embed" // Needed for //go:embed
+	"fmt"
 	"math"
 	"net/http"
 	"sort"
@@ -10,6 +11,7 @@ import (
 	"strings"
 	"time"
 	"tybalt/constants"
+	"tybalt/overtime"
 	"tybalt/utilities"
 
 	"github.com/pocketbase/dbx"
@@ -35,6 +37,9 @@ var payrollTimeQuery string
 //go:embed payroll_time_branch_hours.sql
 var payrollTimeBranchHoursQuery string
 
+//go:embed payroll_time_overtime.sql
+var payrollTimeOvertimeQuery string
+
 //go:embed expenses.sql
 var expensesQueryTemplate string
 
@@ -54,7 +59,6 @@ const placeholderPayrollIDConditionToken = "{:placeholder_payroll_id_condition}"
 const payrollUnassignedBranchHeader = "Unassigned"
 const payrollOvertimeBranchHeaderPrefix = "OT "
 const payrollBranchHoursEpsilon = 0.000000001
-const hourlyPayrollBranchRegularCap = 44.0
 
 var payrollTimeBaseHeaders = []string{"payrollId", "weekEnding", "surname", "givenName", "name", "manager", "meals", "days off rotation", "hours worked", "salaryHoursOver44", "adjustedHoursWorked", "total overtime hours", "overtime hours to pay", "Bereavement", "Stat Holiday", "PPTO", "Sick", "Vacation", "overtime hours to bank", "Overtime Payout Requested", "hasAmendmentsForWeeksEnding", "salary"}
 
@@ -85,6 +89,7 @@ type payrollBranchHoursAllocation struct {
 	UnassignedBanked    float64
 	StatHours           float64
 	BereavementHours    float64
+	PaidOvertimeHours   float64
 }
 
 func withPlaceholderPayrollIDCondition(query string, columnExpr string) string {
@@ -123,6 +128,142 @@ func getOrderedPayrollBranchHeaders(app core.App) ([]string, error) {
 	return headers, nil
 }
 
+// payrollCalendarStatHolidayHoursHeader is the hours of the holidays in the
+// statutory holiday calendar that the employee's default branch observes in
+// the week, a fifth of work_week_hours each as in time validation. "Stat
+// Holiday" holds the OH hours actually claimed. The column is last so the
+// positions of the existing columns do not change.
+const payrollCalendarStatHolidayHoursHeader = "calendar stat holiday hours"
+
+// applyPayrollCalendarStatHolidayHours fills the calendar stat holiday hours
+// column for every row of the payroll time report.
+func applyPayrollCalendarStatHolidayHours(app core.App, report []dbx.NullStringMap, weekEnding time.Time) error {
+	var rows []struct {
+		PayrollID string  `db:"payrollId"`
+		Hours     float64 `db:"hours"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT ap.payroll_id AS payrollId, COUNT(h.id) * ap.work_week_hours / 5.0 AS hours
+		FROM admin_profiles ap
+		JOIN statutory_holidays h
+		  ON h.date BETWEEN {:weekStart} AND {:weekEnding}
+		 AND ` + utilities.StatutoryHolidayBranchCondition("ap.default_branch") + `
+		GROUP BY ap.payroll_id
+	`).Bind(dbx.Params{
+		"weekStart":  weekEnding.AddDate(0, 0, -6).Format("2006-01-02"),
+		"weekEnding": weekEnding.Format("2006-01-02"),
+	}).All(&rows)
+	if err != nil {
+		return err
+	}
+
+	hoursByPayrollID := make(map[string]float64, len(rows))
+	for _, row := range rows {
+		hoursByPayrollID[row.PayrollID] = row.Hours
+	}
+	for _, row := range report {
+		hours := 0.0
+		if payrollIDValue, ok := row["payrollId"]; ok && payrollIDValue.Valid {
+			hours = hoursByPayrollID[payrollIDValue.String]
+		}
+		row[payrollCalendarStatHolidayHoursHeader] = sql.NullString{String: strconv.FormatFloat(hours, 'f', -1, 64), Valid: true}
+	}
+	return nil
+}
+
+// payrollOvertime is one payroll row's worked hours split by the overtime
+// rules of the employee's default branch.
+type payrollOvertime struct {
+	Salary bool
+	Rules  overtime.Rules
+	Result overtime.Result
+}
+
+// getPayrollOvertime runs the overtime calculation for every payroll row of
+// the week, keyed by payroll id.
+func getPayrollOvertime(app core.App, weekEnding string) (map[string]payrollOvertime, error) {
+	var rows []struct {
+		PayrollID     string  `db:"payrollId"`
+		Salary        bool    `db:"salary"`
+		DefaultBranch string  `db:"defaultBranch"`
+		Date          string  `db:"date"`
+		WorkedHours   float64 `db:"workedHours"`
+		BankedHours   float64 `db:"bankedHours"`
+	}
+	query := withPlaceholderPayrollIDCondition(payrollTimeOvertimeQuery, "ap.payroll_id")
+	if err := app.DB().NewQuery(query).Bind(dbx.Params{
+		"weekEnding": weekEnding,
+	}).All(&rows); err != nil {
+		return nil, err
+	}
+
+	config := overtime.LoadConfig(app)
+	type payrollHours struct {
+		salary        bool
+		defaultBranch string
+		dailyHours    map[string]float64
+		bankedHours   float64
+	}
+	hoursByPayrollID := map[string]*payrollHours{}
+	for _, row := range rows {
+		hours, ok := hoursByPayrollID[row.PayrollID]
+		if !ok {
+			hours = &payrollHours{dailyHours: map[string]float64{}}
+			hoursByPayrollID[row.PayrollID] = hours
+		}
+		hours.salary = hours.salary || row.Salary
+		if hours.defaultBranch == "" {
+			hours.defaultBranch = row.DefaultBranch
+		}
+		hours.dailyHours[row.Date] += row.WorkedHours
+		hours.bankedHours += row.BankedHours
+	}
+
+	overtimeByPayrollID := make(map[string]payrollOvertime, len(hoursByPayrollID))
+	for payrollID, hours := range hoursByPayrollID {
+		rules := config.ForBranch(hours.defaultBranch)
+		overtimeByPayrollID[payrollID] = payrollOvertime{
+			Salary: hours.salary,
+			Rules:  rules,
+			Result: overtime.Calculate(rules, hours.salary, hours.dailyHours, hours.bankedHours),
+		}
+	}
+	return overtimeByPayrollID, nil
+}
+
+// applyPayrollOvertime replaces the overtime columns of the payroll time
+// report with the results of the overtime calculation. payroll_time.sql
+// computes them against a fixed 44 hour week; rows without worked hours keep
+// those values. Exempt salaried staff keep their work-week adjusted hours and
+// report the hours over the weekly threshold in salaryHoursOver44.
+func applyPayrollOvertime(report []dbx.NullStringMap, overtimeByPayrollID map[string]payrollOvertime) {
+	formatHours := func(hours float64) sql.NullString {
+		return sql.NullString{String: formatPayrollBranchHours(hours), Valid: true}
+	}
+	for _, row := range report {
+		payrollIDValue, ok := row["payrollId"]
+		if !ok || !payrollIDValue.Valid {
+			continue
+		}
+		payroll, ok := overtimeByPayrollID[payrollIDValue.String]
+		if !ok {
+			continue
+		}
+
+		result := payroll.Result
+		salaryHoursOver := 0.0
+		if payroll.Salary {
+			salaryHoursOver = math.Max(0, result.WorkedHours-payroll.Rules.WeeklyThreshold)
+		}
+		row["salaryHoursOver44"] = formatHours(salaryHoursOver)
+		if !result.Exempt {
+			row["adjustedHoursWorked"] = formatHours(result.RegularHours)
+		}
+		row["total overtime hours"] = formatHours(result.OvertimeHours)
+		row["overtime hours to pay"] = formatHours(result.PaidOvertimeHours)
+	}
+}
+
 func getPayrollOvertimeBranchHeaders(branchHeaders []string) []string {
 	headers := make([]string, 0, len(branchHeaders))
 	for _, header := range branchHeaders {
@@ -135,7 +276,7 @@ func payrollOvertimeBranchHeader(branchName string) string {
 	return payrollOvertimeBranchHeaderPrefix + branchName
 }
 
-func getPayrollBranchHours(app core.App, weekEnding string) (map[string]map[string]string, error) {
+func getPayrollBranchHours(app core.App, weekEnding string, overtimeByPayrollID map[string]payrollOvertime) (map[string]map[string]string, error) {
 	var rows []payrollBranchHoursRow
 	query := withPlaceholderPayrollIDCondition(payrollTimeBranchHoursQuery, "ap.payroll_id")
 	if err := app.DB().NewQuery(query).Bind(dbx.Params{
@@ -178,6 +319,7 @@ func getPayrollBranchHours(app core.App, weekEnding string) (map[string]map[stri
 
 	branchHours := make(map[string]map[string]string, len(allocations))
 	for payrollID, allocation := range allocations {
+		allocation.PaidOvertimeHours = overtimeByPayrollID[payrollID].Result.PaidOvertimeHours
 		normalizeBankedOvertimePayrollBranchHours(allocation)
 		normalizeHourlyPayrollBranchOvertimeHours(allocation)
 		normalizeSalaryPayrollBranchHours(allocation)
@@ -215,21 +357,21 @@ func normalizeHourlyPayrollBranchOvertimeHours(allocation *payrollBranchHoursAll
 		return
 	}
 
+	// The paid overtime comes from the overtime rules of the employee's
+	// default branch, so the branch columns agree with the report's
+	// "overtime hours to pay".
+	overageUnits := payrollBranchHoursToUnits(allocation.PaidOvertimeHours)
+	if overageUnits <= 0 {
+		return
+	}
+
 	branchUnits := make(map[string]int, len(allocation.BranchHours))
-	totalUnits := 0
 	for branchName, branchHours := range allocation.BranchHours {
 		units := payrollBranchHoursToUnits(branchHours)
 		if units <= 0 {
 			continue
 		}
 		branchUnits[branchName] = units
-		totalUnits += units
-	}
-
-	capUnits := payrollBranchHoursToUnits(hourlyPayrollBranchRegularCap)
-	overageUnits := totalUnits - capUnits
-	if overageUnits <= 0 {
-		return
 	}
 
 	reductionUnits := distributePayrollBranchOvertimeUnits(branchUnits, overageUnits)
@@ -456,41 +598,61 @@ func CreatePayrollTimeReportHandler(app core.App) func(e *core.RequestEvent) err
 			dateColumnValue = dateColumnValue.AddDate(0, 0, -7)
 		}
 
-		// Execute the query
-		var report []dbx.NullStringMap
-		query := withPlaceholderPayrollIDCondition(payrollTimeQuery, "ap.payroll_id")
-		err = app.DB().NewQuery(query).Bind(dbx.Params{
-			"weekEnding": dateColumnValue.Format("2006-01-02"),
-		}).All(&report)
+		csvString, err := payrollTimeCSV(app, dateColumnValue)
 		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to execute query: "+err.Error(), err)
+			return e.Error(http.StatusInternalServerError, err.Error(), err)
 		}
 
-		branchHeaders, err := getOrderedPayrollBranchHeaders(app)
-		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to load payroll branch headers: "+err.Error(), err)
-		}
+		// Set content type and return the CSV string
+		e.Response.Header().Set("Content-Type", "text/csv")
+		return e.String(http.StatusOK, csvString)
+	}
+}
 
-		branchHours, err := getPayrollBranchHours(app, dateColumnValue.Format("2006-01-02"))
-		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to execute payroll branch query: "+err.Error(), err)
-		}
+// payrollTimeCSV renders the payroll time report for the week ending on
+// weekEnding.
+func payrollTimeCSV(app core.App, weekEnding time.Time) (string, error) {
+	// Execute the query
+	var report []dbx.NullStringMap
+	query := withPlaceholderPayrollIDCondition(payrollTimeQuery, "ap.payroll_id")
+	err := app.DB().NewQuery(query).Bind(dbx.Params{
+		"weekEnding": weekEnding.Format("2006-01-02"),
+	}).All(&report)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute query: %w", err)
+	}
 
-		overtimeBranchHeaders := getPayrollOvertimeBranchHeaders(branchHeaders)
-		payrollBranchHeaders := append(append([]string{}, branchHeaders...), overtimeBranchHeaders...)
-		applyPayrollBranchHours(report, payrollBranchHeaders, branchHours)
+	branchHeaders, err := getOrderedPayrollBranchHeaders(app)
+	if err != nil {
+		return "", fmt.Errorf("failed to load payroll branch headers: %w", err)
+	}
 
-		// convert the report to a csv string
-		headers := append(append([]string{}, payrollTimeBaseHeaders...), payrollBranchHeaders...)
-		csvString, err := convertToCSV(report, headers)
-		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to generate CSV report: "+err.Error(), err)
-		}
+	overtimeByPayrollID, err := getPayrollOvertime(app, weekEnding.Format("2006-01-02"))
+	if err != nil {
+		return "", fmt.Errorf("failed to execute payroll overtime query: %w", err)
+	}
+	applyPayrollOvertime(report, overtimeByPayrollID)
 
-		// Set content type and return the CSV string
-		e.Response.Header().Set("Content-Type", "text/csv")
-		return e.String(http.StatusOK, csvString)
+	branchHours, err := getPayrollBranchHours(app, weekEnding.Format("2006-01-02"), overtimeByPayrollID)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute payroll branch query: %w", err)
 	}
+
+	overtimeBranchHeaders := getPayrollOvertimeBranchHeaders(branchHeaders)
+	payrollBranchHeaders := append(append([]string{}, branchHeaders...), overtimeBranchHeaders...)
+	applyPayrollBranchHours(report, payrollBranchHeaders, branchHours)
+	if err := applyPayrollCalendarStatHolidayHours(app, report, weekEnding); err != nil {
+		return "", fmt.Errorf("failed to load statutory holidays: %w", err)
+	}
+
+	// convert the report to a csv string
+	headers := append(append([]string{}, payrollTimeBaseHeaders...), payrollBranchHeaders...)
+	headers = append(headers, payrollCalendarStatHolidayHoursHeader)
+	csvString, err := convertToCSV(report, headers)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate CSV report: %w", err)
+	}
+	return csvString, nil
 }
 
 // CreateTimeReportHandler returns a function that creates a weekly time report for a given value of date_column (provided in the request path)
@@ -501,24 +663,9 @@ func CreateTimeReportHandler(app core.App, dateColumnEntriesName string, dateCol
 			return err
 		}
 
-		timeQuery := strings.ReplaceAll(weeklyTimeQueryTemplate, "{:date_column_entries}", dateColumnEntriesName)
-		timeQuery = strings.ReplaceAll(timeQuery, "{:date_column_amendments}", dateColumnAmendmentsName)
-
-		// Execute the query
-		var report []dbx.NullStringMap
-		err = app.DB().NewQuery(timeQuery).Bind(dbx.Params{
-			"company_short_name": "TBTE",
-			"date_column_value":  dateColumnValue.Format("2006-01-02"),
-		}).All(&report)
+		csvString, err := timeReportCSV(app, dateColumnEntriesName, dateColumnAmendmentsName, dateColumnValue)
 		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to execute query: "+err.Error(), err)
-		}
-
-		// convert the report to a csv string
-		headers := []string{"client", "job", "division", "timetype", "date", "month", "year", "qty", "unit", "nc", "meals", "ref", "project", "description", "comments", "employee", "surname", "givenName", "amended"}
-		csvString, err := convertToCSV(report, headers)
-		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to generate CSV report: "+err.Error(), err)
+			return e.Error(http.StatusInternalServerError, err.Error(), err)
 		}
 
 		// Set content type and return the CSV string
@@ -527,6 +674,31 @@ func CreateTimeReportHandler(app core.App, dateColumnEntriesName string, dateCol
 	}
 }
 
+// timeReportCSV renders the weekly time report for entries and amendments
+// whose date columns match dateColumnValue.
+func timeReportCSV(app core.App, dateColumnEntriesName string, dateColumnAmendmentsName string, dateColumnValue time.Time) (string, error) {
+	timeQuery := strings.ReplaceAll(weeklyTimeQueryTemplate, "{:date_column_entries}", dateColumnEntriesName)
+	timeQuery = strings.ReplaceAll(timeQuery, "{:date_column_amendments}", dateColumnAmendmentsName)
+
+	// Execute the query
+	var report []dbx.NullStringMap
+	err := app.DB().NewQuery(timeQuery).Bind(dbx.Params{
+		"company_short_name": "TBTE",
+		"date_column_value":  dateColumnValue.Format("2006-01-02"),
+	}).All(&report)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	// convert the report to a csv string
+	headers := []string{"client", "job", "division", "timetype", "date", "month", "year", "qty", "unit", "nc", "meals", "ref", "project", "description", "comments", "employee", "surname", "givenName", "amended"}
+	csvString, err := convertToCSV(report, headers)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate CSV report: %w", err)
+	}
+	return csvString, nil
+}
+
 // CreateJobTimeReportHandler returns a function that creates a full time report for a specific job.
 // It mirrors CreateTimeReportHandler but uses the job_report.sql query and filters by job id.
 func CreateJobTimeReportHandler(app core.App) func(e *core.RequestEvent) error {
@@ -559,6 +731,121 @@ func CreateJobTimeReportHandler(app core.App) func(e *core.RequestEvent) error {
 	}
 }
 
+// jobTimeReportPDFColumns are the job time report columns printed on the PDF
+// version. The remaining CSV columns repeat the job header or are empty.
+var jobTimeReportPDFColumns = []PDFColumn{
+	{Header: "Date", Width: 0.09},
+	{Header: "Employee", Width: 0.14},
+	{Header: "Div", Width: 0.05},
+	{Header: "Type", Width: 0.05},
+	{Header: "Hours", Width: 0.06, AlignRight: true},
+	{Header: "NC", Width: 0.05, AlignRight: true},
+	{Header: "Meals", Width: 0.05, AlignRight: true},
+	{Header: "Ref", Width: 0.09},
+	{Header: "Description", Width: 0.36},
+	{Header: "Amended", Width: 0.06},
+}
+
+// CreateJobTimeReportPDFHandler returns a function that renders the job full
+// time report as a print-ready PDF. It uses the same query and access as the
+// CSV version, adds the job header, sorts rows chronologically and totals the
+// hours.
+func CreateJobTimeReportPDFHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		jobId := e.Request.PathValue("id")
+		if jobId == "" {
+			return e.Error(http.StatusBadRequest, "id is required", nil)
+		}
+		job, err := app.FindRecordById("jobs", jobId)
+		if err != nil {
+			return e.Error(http.StatusNotFound, "job not found", err)
+		}
+
+		var report []dbx.NullStringMap
+		err = app.DB().NewQuery(jobTimeQueryTemplate).Bind(dbx.Params{
+			"company_short_name": "TBTE",
+			"job_id":             jobId,
+		}).All(&report)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to execute query: "+err.Error(), err)
+		}
+
+		value := func(row dbx.NullStringMap, key string) string {
+			return row[key].String
+		}
+		sortDate := func(row dbx.NullStringMap) string {
+			month := strings.Index("JanFebMarAprMayJunJulAugSepOctNovDec", value(row, "month"))/3 + 1
+			day, _ := strconv.Atoi(value(row, "date"))
+			return fmt.Sprintf("%s-%02d-%02d", value(row, "year"), month, day)
+		}
+		sort.SliceStable(report, func(i, j int) bool {
+			return sortDate(report[i]) < sortDate(report[j])
+		})
+
+		var hours, nc, meals float64
+		rows := make([][]string, 0, len(report)+1)
+		for _, row := range report {
+			qty, _ := strconv.ParseFloat(value(row, "qty"), 64)
+			ncHours, _ := strconv.ParseFloat(value(row, "nc"), 64)
+			mealsHours, _ := strconv.ParseFloat(value(row, "meals"), 64)
+			hours += qty
+			nc += ncHours
+			meals += mealsHours
+			amended := ""
+			if value(row, "amended") == "true" {
+				amended = "Yes"
+			}
+			rows = append(rows, []string{
+				sortDate(row),
+				value(row, "employee"),
+				value(row, "division"),
+				value(row, "timetype"),
+				FormatPDFNumber(qty),
+				FormatPDFNumber(ncHours),
+				FormatPDFNumber(mealsHours),
+				value(row, "ref"),
+				value(row, "description"),
+				amended,
+			})
+		}
+		rows = append(rows, []string{"Total", "", "", "", FormatPDFNumber(hours), FormatPDFNumber(nc), FormatPDFNumber(meals), "", "", ""})
+
+		clientName := ""
+		if client, err := app.FindRecordById("clients", job.GetString("client")); err == nil {
+			clientName = client.GetString("name")
+		}
+
+		title := "Job " + job.GetString("number") + " Full Time Report"
+		doc := NewPDFDocument(title, true)
+		doc.Heading(title, 1)
+		doc.Fields([][2]string{
+			{"Job", job.GetString("number")},
+			{"Description", job.GetString("description")},
+			{"Client", clientName},
+			{"Entries", strconv.Itoa(len(report))},
+		})
+		doc.Table(jobTimeReportPDFColumns, rows)
+
+		return WritePDFResponse(e, job.GetString("number")+"_time_report.pdf", doc)
+	}
+}
+
+// WritePDFResponse sends a finished document inline so browsers open it in
+// the print-ready viewer rather than downloading it.
+func WritePDFResponse(e *core.RequestEvent, filename string, doc *PDFDocument) error {
+	e.Response.Header().Set("Content-Disposition", fmt.Sprintf("inline; filename=%q", filename))
+	return e.Blob(http.StatusOK, "application/pdf", doc.Bytes())
+}
+
+// FormatPDFNumber prints quantities rounded to two decimals without trailing
+// zeros, and leaves zero blank so PDF tables stay easy to scan.
+func FormatPDFNumber(value float64) string {
+	if value == 0 {
+		return ""
+	}
+	return strconv.FormatFloat(math.Round(value*100)/100, 'f', -1, 64)
+}
+
 // CreateTimeSummaryByEmployeeHandler returns a function that creates a time summary by employee report for a given value of date_column (provided in the request path)
 func CreateTimeSummaryByEmployeeHandler(app core.App, dateColumnName string) func(e *core.RequestEvent) error {
 	return func(e *core.RequestEvent) error {
@@ -596,28 +883,9 @@ func CreateExpenseReportHandler(app core.App, dateColumnName string) func(e *cor
 			return err
 		}
 
-		// Replace the placeholder in the query string with the column name. We do
-		// this instead of using Bind() because the column name will be incorrectly
-		// quoted by Bind() for SQLite (it will be enclosed in single quotes which
-		// SQL will interpret as a string literal rather than as an identifier).
-		expensesQuery := strings.ReplaceAll(expensesQueryTemplate, "{:date_column}", dateColumnName)
-		expensesQuery = withPlaceholderPayrollIDCondition(expensesQuery, "ap.payroll_id")
-
-		// Execute the query
-		var report []dbx.NullStringMap
-		err = app.DB().NewQuery(expensesQuery).Bind(dbx.Params{
-			"date_column_value": dateColumnValue.Format("2006-01-02"),
-		}).All(&report)
-		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to execute query: "+err.Error(), err)
-		}
-
-		// Keep the legacy columns and order intact. Total remains the CAD amount;
-		// additive currency columns come last so downstream consumers can opt in.
-		headers := []string{"payrollId", "Acct/Visa/Exp", "Job #", "Client", "Job Description", "Div", "Date", "Month", "Year", "calculatedSubtotal", "calculatedOntarioHST", "Total", "PO#", "Description", "Company", "Employee", "Approved By", "currency", "foreign_currency_total"}
-		csvString, err := convertToCSV(report, headers)
+		csvString, err := expenseReportCSV(app, dateColumnName, dateColumnValue)
 		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to generate CSV report: "+err.Error(), err)
+			return e.Error(http.StatusInternalServerError, err.Error(), err)
 		}
 
 		// Set content type and return the CSV string
@@ -626,6 +894,35 @@ func CreateExpenseReportHandler(app core.App, dateColumnName string) func(e *cor
 	}
 }
 
+// expenseReportCSV renders the expense report for expenses whose
+// dateColumnName column matches dateColumnValue.
+func expenseReportCSV(app core.App, dateColumnName string, dateColumnValue time.Time) (string, error) {
+	// Replace the placeholder in the query string with the column name. We do
+	// this instead of using Bind() because the column name will be incorrectly
+	// quoted by Bind() for SQLite (it will be enclosed in single quotes which
+	// SQL will interpret as a string literal rather than as an identifier).
+	expensesQuery := strings.ReplaceAll(expensesQueryTemplate, "{:date_column}", dateColumnName)
+	expensesQuery = withPlaceholderPayrollIDCondition(expensesQuery, "ap.payroll_id")
+
+	// Execute the query
+	var report []dbx.NullStringMap
+	err := app.DB().NewQuery(expensesQuery).Bind(dbx.Params{
+		"date_column_value": dateColumnValue.Format("2006-01-02"),
+	}).All(&report)
+	if err != nil {
+		return "", fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	// Keep the legacy columns and order intact. Total remains the CAD amount;
+	// additive currency columns come last so downstream consumers can opt in.
+	headers := []string{"payrollId", "Acct/Visa/Exp", "Job #", "Client", "Job Description", "Div", "Date", "Month", "Year", "calculatedSubtotal", "calculatedOntarioHST", "Total", "PO#", "Description", "Company", "Employee", "Approved By", "currency", "foreign_currency_total"}
+	csvString, err := convertToCSV(report, headers)
+	if err != nil {
+		return "", fmt.Errorf("failed to generate CSV report: %w", err)
+	}
+	return csvString, nil
+}
+
 // CreateReceiptsReportHandler returns a function that creates a payroll receipts zip archive for a given value of date_column (provided in the request path)
 func CreateReceiptsReportHandler(app core.App, dateColumnName string) func(e *core.RequestEvent) error {
 	return func(e *core.RequestEvent) error {
@@ -634,95 +931,102 @@ func CreateReceiptsReportHandler(app core.App, dateColumnName string) func(e *co
 			return err
 		}
 
-		// Replace the placeholder in the query string with the column name. We do
-		// this instead of using Bind() because the column name will be incorrectly
-		// quoted by Bind() for SQLite (it will be enclosed in single quotes which
-		// SQL will interpret as a string literal rather than as an identifier).
-		receiptsQuery := strings.ReplaceAll(receiptsQueryTemplate, "{:date_column}", dateColumnName)
-
-		// Execute the query
-		var report []dbx.NullStringMap
-		err = app.DB().NewQuery(receiptsQuery).Bind(dbx.Params{
-			"date_column_value": dateColumnValue.Format("2006-01-02"),
-		}).All(&report)
+		zipCacheRecord, err := receiptsZipRecord(app, dateColumnName, dateColumnValue)
 		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to execute query: "+err.Error(), err)
+			return e.Error(http.StatusInternalServerError, err.Error(), err)
 		}
 
-		// build a list of receipts
-		receipts := []Attachment{}
-		for _, rowMap := range report {
-			idVal, idOk := rowMap["id"]
-			collectionIDVal, collectionIDOk := rowMap["collection_id"]
-			sourcePathVal, sourcePathOk := rowMap["source_path"]
-			filenameVal, filenameOk := rowMap["filename"]
-			zipFilenameVal, zipFilenameOk := rowMap["zip_filename"]
-			sha256Val, sha256Ok := rowMap["sha256"]
-			if !idOk || !sourcePathOk || !filenameOk || !zipFilenameOk || !sha256Ok {
-				// skip rows that don't have all the required fields
-				continue
-			}
-			receipts = append(receipts, Attachment{
-				Id:           idVal.String,
-				CollectionID: collectionIDVal.String,
-				Filename:     filenameVal.String,
-				ZipFilename:  zipFilenameVal.String,
-				SourcePath:   sourcePathVal.String,
-				Sha256:       sha256Val.String,
-			})
-			if !collectionIDOk {
-				receipts[len(receipts)-1].CollectionID = expenseCollectionId
-			}
-		}
+		url := zipCacheRecord.BaseFilesPath() + "/" + zipCacheRecord.GetString("zip")
+		return e.JSON(http.StatusOK, map[string]string{"url": url})
+	}
+}
 
-		// Check the zip cache for a record that matches the dateColumnValue in the
-		// specified class and receipts. If there's a cache hit, return the file
-		// url. The class for this zip is "receipts_by_" + dateColumnName.
-		zipCacheRecord, err := zipCacheLookup(app, dateColumnValue.Format("2006-01-02"), "receipts_by_"+dateColumnName, receipts, expenseCollectionId)
-		if err != nil {
-			return e.Error(http.StatusInternalServerError, "failed to lookup zip cache: "+err.Error(), err)
+// receiptsZipRecord returns the zip_cache record holding the receipts for
+// expenses whose dateColumnName column matches dateColumnValue, building and
+// caching the archive on a cache miss.
+func receiptsZipRecord(app core.App, dateColumnName string, dateColumnValue time.Time) (*core.Record, error) {
+	// Replace the placeholder in the query string with the column name. We do
+	// this instead of using Bind() because the column name will be incorrectly
+	// quoted by Bind() for SQLite (it will be enclosed in single quotes which
+	// SQL will interpret as a string literal rather than as an identifier).
+	receiptsQuery := strings.ReplaceAll(receiptsQueryTemplate, "{:date_column}", dateColumnName)
+
+	// Execute the query
+	var report []dbx.NullStringMap
+	err := app.DB().NewQuery(receiptsQuery).Bind(dbx.Params{
+		"date_column_value": dateColumnValue.Format("2006-01-02"),
+	}).All(&report)
+	if err != nil {
+		return nil, fmt.Errorf("failed to execute query: %w", err)
+	}
+
+	// build a list of receipts
+	receipts := []Attachment{}
+	for _, rowMap := range report {
+		idVal, idOk := rowMap["id"]
+		collectionIDVal, collectionIDOk := rowMap["collection_id"]
+		sourcePathVal, sourcePathOk := rowMap["source_path"]
+		filenameVal, filenameOk := rowMap["filename"]
+		zipFilenameVal, zipFilenameOk := rowMap["zip_filename"]
+		sha256Val, sha256Ok := rowMap["sha256"]
+		if !idOk || !sourcePathOk || !filenameOk || !zipFilenameOk || !sha256Ok {
+			// skip rows that don't have all the required fields
+			continue
 		}
-		if zipCacheRecord != nil {
-			url := zipCacheRecord.BaseFilesPath() + "/" + zipCacheRecord.GetString("zip")
-			app.Logger().Debug("zip_cache hit", dateColumnName, dateColumnValue.Format("2006-01-02"))
-			return e.JSON(http.StatusOK, map[string]string{"url": url})
+		receipts = append(receipts, Attachment{
+			Id:           idVal.String,
+			CollectionID: collectionIDVal.String,
+			Filename:     filenameVal.String,
+			ZipFilename:  zipFilenameVal.String,
+			SourcePath:   sourcePathVal.String,
+			Sha256:       sha256Val.String,
+		})
+		if !collectionIDOk {
+			receipts[len(receipts)-1].CollectionID = expenseCollectionId
 		}
-		app.Logger().Debug("zip_cache miss", dateColumnName, dateColumnValue.Format("2006-01-02"))
+	}
 
-		// If we get here, we have a cache miss. Create the zip and store it in the cache.
+	// Check the zip cache for a record that matches the dateColumnValue in the
+	// specified class and receipts. If there's a cache hit, return it. The
+	// class for this zip is "receipts_by_" + dateColumnName.
+	zipCacheRecord, err := zipCacheLookup(app, dateColumnValue.Format("2006-01-02"), "receipts_by_"+dateColumnName, receipts, expenseCollectionId)
+	if err != nil {
+		return nil, fmt.Errorf("failed to lookup zip cache: %w", err)
+	}
+	if zipCacheRecord != nil {
+		app.Logger().Debug("zip_cache hit", dateColumnName, dateColumnValue.Format("2006-01-02"))
+		return zipCacheRecord, nil
+	}
+	app.Logger().Debug("zip_cache miss", dateColumnName, dateColumnValue.Format("2006-01-02"))
 
-		// Define a struct to hold the result from the goroutine
-		type zipResult struct {
-			zipCacheRecord *core.Record
-			err            error
-		}
+	// If we get here, we have a cache miss. Create the zip and store it in the cache.
 
-		// Create a channel to receive the result. A buffered channel of size 1 allows
-		// the goroutine to send the result and exit without waiting for the receiver.
-		resultChan := make(chan zipResult, 1)
+	// Define a struct to hold the result from the goroutine
+	type zipResult struct {
+		zipCacheRecord *core.Record
+		err            error
+	}
 
-		// Launch the goroutine to perform the zipping operation.
-		// This allows the zipping (which can be I/O intensive) to happen concurrently.
-		go func() {
-			// The 'report' variable is captured from the outer function's scope.
-			// The 'zipAttachments' function is defined in functions.go within the same package.
-			zipCacheRecord, err := zipAttachments(app, receipts, expenseCollectionId, "receipts_by_"+dateColumnName, dateColumnValue.Format("2006-01-02"))
-			resultChan <- zipResult{zipCacheRecord: zipCacheRecord, err: err}
-		}()
+	// Create a channel to receive the result. A buffered channel of size 1 allows
+	// the goroutine to send the result and exit without waiting for the receiver.
+	resultChan := make(chan zipResult, 1)
 
-		// Wait for the result from the goroutine.
-		res := <-resultChan
+	// Launch the goroutine to perform the zipping operation.
+	// This allows the zipping (which can be I/O intensive) to happen concurrently.
+	go func() {
+		// The 'zipAttachments' function is defined in functions.go within the same package.
+		zipCacheRecord, err := zipAttachments(app, receipts, expenseCollectionId, "receipts_by_"+dateColumnName, dateColumnValue.Format("2006-01-02"))
+		resultChan <- zipResult{zipCacheRecord: zipCacheRecord, err: err}
+	}()
 
-		// Handle any error returned from the zipAttachments function.
-		if res.err != nil {
-			// Optionally, log the error on the server side here using app.Logger()
-			// For example: app.Logger().Error("Failed to generate zip archive", "error", res.err)
-			return e.Error(http.StatusInternalServerError, "failed to generate zip archive: "+res.err.Error(), res.err)
-		}
+	// Wait for the result from the goroutine.
+	res := <-resultChan
 
-		url := res.zipCacheRecord.BaseFilesPath() + "/" + res.zipCacheRecord.GetString("zip")
-		return e.JSON(http.StatusOK, map[string]string{"url": url})
+	// Handle any error returned from the zipAttachments function.
+	if res.err != nil {
+		return nil, fmt.Errorf("failed to generate zip archive: %w", res.err)
 	}
+	return res.zipCacheRecord, nil
 }
 
 // getDateColumnValue returns the parsed and validated date column value
@@ -0,0 +1,254 @@
+package reports
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"time"
+	"tybalt/constants"
+	"tybalt/notifications"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/filesystem"
+)
+
+// scheduledReportNames maps report_subscriptions.report values to the names
+// used in the delivery email.
+var scheduledReportNames = map[string]string{
+	"payroll_time":         "Payroll Time",
+	"weekly_time":          "Weekly Time",
+	"payroll_expense":      "Payroll Expense",
+	"payroll_receipts":     "Payroll Receipts",
+	"payables_spreadsheet": "Payables Spreadsheet",
+}
+
+// ScheduledReportFile is one attachment of a rendered scheduled report.
+type ScheduledReportFile struct {
+	Name    string
+	Content []byte
+}
+
+// ScheduledReport is a report rendered for a completed period. Period is the
+// week ending or pay period ending date the report covers and is what
+// subscriptions record to avoid delivering the same period twice.
+type ScheduledReport struct {
+	Report string
+	Period string
+	Files  []ScheduledReportFile
+}
+
+// previousWeekEnding returns the most recent Saturday strictly before now's
+// UTC date, which is the last fully completed week.
+func previousWeekEnding(now time.Time) time.Time {
+	today := time.Date(now.UTC().Year(), now.UTC().Month(), now.UTC().Day(), 0, 0, 0, 0, time.UTC)
+	daysBack := (int(today.Weekday()) - int(time.Saturday) + 7) % 7
+	if daysBack == 0 {
+		daysBack = 7
+	}
+	return today.AddDate(0, 0, -daysBack)
+}
+
+// previousPayPeriodEnding returns the most recent pay period ending, relative
+// to PAYROLL_EPOCH, that is fully completed before now.
+func previousPayPeriodEnding(now time.Time) time.Time {
+	weekEnding := previousWeekEnding(now)
+	days := int(weekEnding.Sub(constants.PAYROLL_EPOCH).Hours() / 24)
+	if ((days%14)+14)%14 != 0 {
+		weekEnding = weekEnding.AddDate(0, 0, -7)
+	}
+	return weekEnding
+}
+
+// ScheduledReportPeriod returns the period a subscription to report would
+// cover if delivered at now.
+func ScheduledReportPeriod(report string, now time.Time) (time.Time, error) {
+	switch report {
+	case "weekly_time", "payables_spreadsheet":
+		return previousWeekEnding(now), nil
+	case "payroll_time", "payroll_expense", "payroll_receipts":
+		return previousPayPeriodEnding(now), nil
+	}
+	return time.Time{}, fmt.Errorf("unknown scheduled report %q", report)
+}
+
+// RenderScheduledReport renders report for the most recently completed period
+// using the same queries as the interactive report routes. A period with no
+// data yields a report without files for reports that have nothing to attach.
+func RenderScheduledReport(app core.App, report string, now time.Time) (ScheduledReport, error) {
+	period, err := ScheduledReportPeriod(report, now)
+	if err != nil {
+		return ScheduledReport{}, err
+	}
+	periodText := period.Format("2006-01-02")
+	rendered := ScheduledReport{Report: report, Period: periodText}
+
+	addCSV := func(name string, content string, err error) error {
+		if err != nil {
+			return err
+		}
+		rendered.Files = append(rendered.Files, ScheduledReportFile{Name: name, Content: []byte(content)})
+		return nil
+	}
+
+	switch report {
+	case "payroll_time":
+		// The interactive route renders one week of the pay period at a time;
+		// the scheduled delivery attaches both.
+		week1 := period.AddDate(0, 0, -7)
+		content, err := payrollTimeCSV(app, week1)
+		if err := addCSV(fmt.Sprintf("payroll_time_%s_week1.csv", periodText), content, err); err != nil {
+			return ScheduledReport{}, err
+		}
+		content, err = payrollTimeCSV(app, period)
+		if err := addCSV(fmt.Sprintf("payroll_time_%s_week2.csv", periodText), content, err); err != nil {
+			return ScheduledReport{}, err
+		}
+	case "weekly_time":
+		content, err := timeReportCSV(app, "week_ending", "committed_week_ending", period)
+		if err := addCSV(fmt.Sprintf("weekly_time_%s.csv", periodText), content, err); err != nil {
+			return ScheduledReport{}, err
+		}
+	case "payroll_expense":
+		content, err := expenseReportCSV(app, "pay_period_ending", period)
+		if err := addCSV(fmt.Sprintf("payroll_expense_%s.csv", periodText), content, err); err != nil {
+			return ScheduledReport{}, err
+		}
+	case "payables_spreadsheet":
+		rows, err := queryPayablesRows(app,
+			"SUBSTR(CASE WHEN po.second_approval != '' AND po.second_approval > po.approved THEN po.second_approval ELSE po.approved END, 1, 10) BETWEEN {:start} AND {:end}",
+			dbx.Params{"start": period.AddDate(0, 0, -6).Format("2006-01-02"), "end": periodText},
+		)
+		if err != nil {
+			return ScheduledReport{}, fmt.Errorf("failed to query payables: %w", err)
+		}
+		content, err := rowsToCSV(rows)
+		if err := addCSV(fmt.Sprintf("payables_spreadsheet_%s.csv", periodText), content, err); err != nil {
+			return ScheduledReport{}, err
+		}
+	case "payroll_receipts":
+		zipCacheRecord, err := receiptsZipRecord(app, "pay_period_ending", period)
+		if errors.Is(err, errNoAttachmentsToZip) {
+			return rendered, nil
+		}
+		if err != nil {
+			return ScheduledReport{}, err
+		}
+		content, err := readRecordFile(app, zipCacheRecord, zipCacheRecord.GetString("zip"))
+		if err != nil {
+			return ScheduledReport{}, err
+		}
+		rendered.Files = append(rendered.Files, ScheduledReportFile{Name: fmt.Sprintf("payroll_receipts_%s.zip", periodText), Content: content})
+	}
+	return rendered, nil
+}
+
+func readRecordFile(app core.App, record *core.Record, filename string) ([]byte, error) {
+	fsys, err := app.NewFilesystem()
+	if err != nil {
+		return nil, fmt.Errorf("failed to create filesystem: %w", err)
+	}
+	defer fsys.Close()
+	reader, err := fsys.GetReader(record.BaseFilesPath() + "/" + filename)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open %s: %w", filename, err)
+	}
+	defer reader.Close()
+	return io.ReadAll(reader)
+}
+
+// DeliverReportSubscriptions renders and emails every active report
+// subscription whose most recently completed period has not been delivered
+// yet. The report claim is checked at delivery time; subscribers who have lost
+// it are skipped and the reason is recorded on the subscription. It returns the
+// number of subscriptions delivered.
+func DeliverReportSubscriptions(app core.App, now time.Time) (int, error) {
+	subscriptions, err := app.FindRecordsByFilter("report_subscriptions", "active = true", "created", 0, 0)
+	if err != nil {
+		return 0, fmt.Errorf("failed to load report subscriptions: %w", err)
+	}
+
+	rendered := map[string]ScheduledReport{}
+	delivered := 0
+	for _, subscription := range subscriptions {
+		report := subscription.GetString("report")
+		period, err := ScheduledReportPeriod(report, now)
+		if err != nil {
+			app.Logger().Error("skipping report subscription", "subscription_id", subscription.Id, "error", err)
+			continue
+		}
+		if subscription.GetString("last_period") == period.Format("2006-01-02") {
+			continue
+		}
+
+		uid := subscription.GetString("uid")
+		hasReportClaim, err := utilities.HasClaimByUserID(app, uid, "report")
+		if err != nil {
+			return delivered, fmt.Errorf("failed to check report claim for %s: %w", uid, err)
+		}
+		if !hasReportClaim {
+			recordReportSubscriptionError(app, subscription, "the report claim is required to receive scheduled reports")
+			continue
+		}
+
+		scheduled, ok := rendered[report]
+		if !ok {
+			scheduled, err = RenderScheduledReport(app, report, now)
+			if err != nil {
+				app.Logger().Error("failed to render scheduled report", "report", report, "error", err)
+				recordReportSubscriptionError(app, subscription, err.Error())
+				continue
+			}
+			rendered[report] = scheduled
+		}
+
+		attachments := make([]*filesystem.File, 0, len(scheduled.Files))
+		for _, file := range scheduled.Files {
+			attachment, err := filesystem.NewFileFromBytes(file.Content, file.Name)
+			if err != nil {
+				return delivered, fmt.Errorf("failed to prepare %s: %w", file.Name, err)
+			}
+			attachments = append(attachments, attachment)
+		}
+
+		notificationID, err := notifications.DispatchNotification(app, notifications.DispatchArgs{
+			TemplateCode: "scheduled_report",
+			RecipientUID: uid,
+			Data: map[string]any{
+				"ReportName": scheduledReportNames[report],
+				"Period":     scheduled.Period,
+				"HasFiles":   len(attachments) > 0,
+				"ActionURL":  notifications.BuildActionURL(app, "/reports"),
+			},
+			System:      true,
+			Mode:        notifications.DeliveryImmediate,
+			Attachments: attachments,
+		})
+		if err != nil {
+			recordReportSubscriptionError(app, subscription, err.Error())
+			continue
+		}
+		if notificationID == "" {
+			// The scheduled_report notification feature is disabled. Leave the
+			// period undelivered so it goes out once the feature is enabled.
+			continue
+		}
+
+		subscription.Set("last_period", scheduled.Period)
+		subscription.Set("last_delivered", now.UTC())
+		subscription.Set("last_error", "")
+		if err := app.Save(subscription); err != nil {
+			return delivered, fmt.Errorf("failed to record delivery of subscription %s: %w", subscription.Id, err)
+		}
+		delivered++
+	}
+	return delivered, nil
+}
+
+func recordReportSubscriptionError(app core.App, subscription *core.Record, message string) {
+	subscription.Set("last_error", message)
+	if err := app.Save(subscription); err != nil {
+		app.Logger().Error("failed to record report subscription error", "subscription_id", subscription.Id, "error", err)
+	}
+}
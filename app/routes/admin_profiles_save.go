@@ -24,6 +24,7 @@ var hrLimitedEditableFields = map[string]struct{}{
 	"allow_personal_reimbursement":      {},
 	"default_branch":                    {},
 	"default_charge_out_rate":           {},
+	"fuel_card_number":                  {},
 	"job_title":                         {},
 	"mobile_phone":                      {},
 	"off_rotation_permitted":            {},
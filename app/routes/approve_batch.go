@@ -0,0 +1,150 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxBatchRecords caps the number of records one batch request may approve
+// or reject.
+const maxBatchRecords = 200
+
+// batchRecordRequest is the body of the approve_batch and reject_batch
+// routes. RejectionReason is shared by every record in a reject batch.
+type batchRecordRequest struct {
+	IDs             []string `json:"ids"`
+	RejectionReason string   `json:"rejection_reason"`
+}
+
+// batchRecordResult is the outcome for one record of a batch. Status is the
+// HTTP status the single-record route would have returned.
+type batchRecordResult struct {
+	ID      string `json:"id"`
+	Status  int    `json:"status"`
+	Code    string `json:"code,omitempty"`
+	Message string `json:"message"`
+}
+
+// bindBatchRecordRequest reads the batch body and returns the unique, non-empty
+// ids in request order.
+func bindBatchRecordRequest(e *core.RequestEvent) (batchRecordRequest, error) {
+	var req batchRecordRequest
+	if err := e.BindBody(&req); err != nil {
+		return req, &CodeError{Code: "invalid_request_body", Message: "the request body must list the record ids"}
+	}
+
+	ids := make([]string, 0, len(req.IDs))
+	seen := map[string]bool{}
+	for _, id := range req.IDs {
+		id = strings.TrimSpace(id)
+		if id == "" || seen[id] {
+			continue
+		}
+		seen[id] = true
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		return req, &CodeError{Code: "missing_ids", Message: "at least one record id is required"}
+	}
+	if len(ids) > maxBatchRecords {
+		return req, &CodeError{Code: "too_many_ids", Message: fmt.Sprintf("a batch can include at most %d records", maxBatchRecords)}
+	}
+	req.IDs = ids
+	return req, nil
+}
+
+// runBatch runs action for every id in its own transaction so one failing
+// record does not roll back the others.
+func runBatch(app core.App, ids []string, successMessage string, action func(txApp core.App, id string) (int, error)) []batchRecordResult {
+	results := make([]batchRecordResult, 0, len(ids))
+	for _, id := range ids {
+		status := http.StatusOK
+		err := app.RunInTransaction(func(txApp core.App) error {
+			var err error
+			status, err = action(txApp, id)
+			return err
+		})
+
+		result := batchRecordResult{ID: id, Status: status, Message: successMessage}
+		if err != nil {
+			if status == http.StatusOK {
+				result.Status = http.StatusInternalServerError
+			}
+			result.Message = err.Error()
+			if codeError, ok := err.(*CodeError); ok {
+				result.Code = codeError.Code
+				result.Message = codeError.Message
+			}
+		}
+		results = append(results, result)
+	}
+	return results
+}
+
+// createApproveBatchHandler returns a handler that approves several records
+// of collectionName with the same checks as createApproveRecordHandler. The
+// response lists the outcome for each id.
+func createApproveBatchHandler(app core.App, collectionName string) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		switch collectionName {
+		case "expenses":
+			if err := requireExpensesEditing(app, collectionName); err != nil {
+				return err
+			}
+		case "time_sheets":
+			if err := requireTimeEditing(app); err != nil {
+				return err
+			}
+		default:
+			return e.Error(http.StatusInternalServerError, "unsupported approval collection", nil)
+		}
+
+		req, err := bindBatchRecordRequest(e)
+		if err != nil {
+			codeError := err.(*CodeError)
+			return e.JSON(http.StatusBadRequest, map[string]any{"message": codeError.Message, "code": codeError.Code})
+		}
+
+		results := runBatch(app, req.IDs, "record approved successfully", func(txApp core.App, id string) (int, error) {
+			return approveRecord(txApp, e.Auth, collectionName, id)
+		})
+		return e.JSON(http.StatusOK, results)
+	}
+}
+
+// createRejectBatchHandler returns a handler that rejects several records of
+// collectionName with one shared reason, using the same checks as
+// createRejectRecordHandler and queueing the same notifications for each
+// rejected record. The response lists the outcome for each id.
+func createRejectBatchHandler(app core.App, collectionName string) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireExpensesEditing(app, collectionName); err != nil {
+			return err
+		}
+
+		req, err := bindBatchRecordRequest(e)
+		if err != nil {
+			codeError := err.(*CodeError)
+			return e.JSON(http.StatusBadRequest, map[string]any{"message": codeError.Message, "code": codeError.Code})
+		}
+		if len(req.RejectionReason) < 4 {
+			return e.JSON(http.StatusBadRequest, map[string]any{
+				"message": "rejection reason must be at least 4 characters long",
+				"code":    "rejection_reason_too_short",
+			})
+		}
+
+		results := runBatch(app, req.IDs, "record rejected successfully", func(txApp core.App, id string) (int, error) {
+			return rejectRecord(txApp, e.Auth, collectionName, id, req.RejectionReason)
+		})
+		for _, result := range results {
+			if result.Code == "" && result.Status == http.StatusOK {
+				queueRejectionNotifications(app, collectionName, result.ID, e.Auth.Id, req.RejectionReason)
+			}
+		}
+		return e.JSON(http.StatusOK, results)
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"net/http"
 	"time"
+	"tybalt/utilities"
 
 	"github.com/pocketbase/pocketbase/core"
 )
@@ -14,7 +15,9 @@ func createApproveRecordHandler(app core.App, collectionName string) func(e *cor
 	// 1. Retrieves the authenticated user's ID.
 	// 2. Runs a database transaction to:
 	//    a. Fetch the record by ID.
-	//    b. Verify that the authenticated user is the assigned approver.
+	//    b. Verify that the authenticated user is the assigned approver,
+	//       holds an active approval delegation from them or, for an
+	//       escalated timesheet, is the user it was escalated to.
 	//    c. Check if the record is submitted and not committed or already approved.
 	//    d. Set the approval timestamp.
 	//    e. Save the updated record.
@@ -26,7 +29,7 @@ func createApproveRecordHandler(app core.App, collectionName string) func(e *cor
 			if err := requireExpensesEditing(app, collectionName); err != nil {
 				return err
 			}
-		case "time_sheets":
+		case "time_sheets", "time_off_requests":
 			if err := requireTimeEditing(app); err != nil {
 				return err
 			}
@@ -35,83 +38,139 @@ func createApproveRecordHandler(app core.App, collectionName string) func(e *cor
 		}
 
 		authRecord := e.Auth
-		userId := authRecord.Id
 
 		var httpResponseStatusCode int
 
 		err := app.RunInTransaction(func(txApp core.App) error {
-			record, err := txApp.FindRecordById(collectionName, e.Request.PathValue("id"))
-			if err != nil {
-				httpResponseStatusCode = http.StatusNotFound
-				return &CodeError{
-					Code:    "record_not_found",
-					Message: fmt.Sprintf("error fetching record: %v", err),
-				}
-			}
+			var err error
+			httpResponseStatusCode, err = approveRecord(txApp, authRecord, collectionName, e.Request.PathValue("id"))
+			return err
+		})
 
-			// Check if the user is the approver
-			if record.GetString("approver") != userId {
-				httpResponseStatusCode = http.StatusForbidden
-				return &CodeError{
-					Code:    "unauthorized",
-					Message: "you are not authorized to approve this record",
-				}
-			}
+		if err != nil {
+			return e.JSON(httpResponseStatusCode, map[string]string{"error": err.Error()})
+		}
 
-			// Check if the record is submitted
-			if !record.GetBool("submitted") {
-				httpResponseStatusCode = http.StatusBadRequest
-				return &CodeError{
-					Code:    "record_not_submitted",
-					Message: "only submitted records can be approved",
-				}
-			}
+		return e.JSON(http.StatusOK, map[string]string{"message": "Record approved successfully"})
+	}
+}
 
-			// Check if the record is committed
-			if !record.GetDateTime("committed").IsZero() {
-				httpResponseStatusCode = http.StatusBadRequest
-				return &CodeError{
-					Code:    "record_committed",
-					Message: "committed records cannot be approved",
-				}
-			}
+// approveRecord approves the submitted record id in collectionName on behalf
+// of authRecord. It must run inside a transaction. On failure it returns the
+// HTTP status code for the CodeError describing why the record could not be
+// approved.
+func approveRecord(txApp core.App, authRecord *core.Record, collectionName string, id string) (int, error) {
+	userId := authRecord.Id
+
+	record, err := txApp.FindRecordById(collectionName, id)
+	if err != nil {
+		return http.StatusNotFound, &CodeError{
+			Code:    "record_not_found",
+			Message: fmt.Sprintf("error fetching record: %v", err),
+		}
+	}
 
-			// Check if the record is already approved
-			if !record.GetDateTime("approved").IsZero() {
-				httpResponseStatusCode = http.StatusBadRequest
-				return &CodeError{
-					Code:    "record_already_approved",
-					Message: "this record is already approved",
-				}
+	// Check if the user is the approver. Time off managers may also
+	// approve time off requests on the approver's behalf.
+	isAuthorized := record.GetString("approver") == userId
+	if !isAuthorized && collectionName == "time_off_requests" {
+		isAuthorized, err = utilities.HasClaim(txApp, authRecord, "time_off_manager")
+		if err != nil {
+			return http.StatusInternalServerError, &CodeError{
+				Code:    "error_fetching_user_claims",
+				Message: fmt.Sprintf("error fetching user claims: %v", err),
 			}
-
-			if poErr := validateExpensePurchaseOrderIsActive(txApp, record); poErr != nil {
-				httpResponseStatusCode = http.StatusBadRequest
-				if poErr.Code == "purchase_order_lookup_error" {
-					httpResponseStatusCode = http.StatusInternalServerError
-				}
-				return poErr
+		}
+		// The claim lets a manager act for other approvers, not for themself.
+		if isAuthorized && record.GetString("uid") == userId {
+			return http.StatusForbidden, &CodeError{
+				Code:    "time_off_manager_is_owner",
+				Message: "you cannot approve your own time off request as a time off manager",
 			}
+		}
+	}
+	// A delegate of the approver may approve while the delegation is active.
+	// The approver is kept and the delegate is recorded as delegated_approver.
+	delegationScope := utilities.ApprovalDelegationScopeForCollection(collectionName)
+	isDelegated := false
+	if !isAuthorized {
+		isDelegated, err = utilities.HasActiveApprovalDelegation(txApp, record.GetString("approver"), userId, delegationScope)
+		if err != nil {
+			return http.StatusInternalServerError, &CodeError{
+				Code:    "error_fetching_approval_delegations",
+				Message: fmt.Sprintf("error fetching approval delegations: %v", err),
+			}
+		}
+		isAuthorized = isDelegated
+	}
+	// A timesheet escalated after waiting too long for approval may also be
+	// approved by the user it was escalated to, recorded the same way.
+	// delegated_approver matching approval_escalated_to marks the approval as
+	// an escalation rather than a delegation.
+	if !isAuthorized && collectionName == "time_sheets" && record.GetString("approval_escalated_to") == userId {
+		isAuthorized = true
+		isDelegated = true
+	}
+	if !isAuthorized {
+		return http.StatusForbidden, &CodeError{
+			Code:    "unauthorized",
+			Message: "you are not authorized to approve this record",
+		}
+	}
+	// Delegates act for the approver and may not approve their own records.
+	if isDelegated && record.GetString("uid") == userId {
+		return http.StatusForbidden, &CodeError{
+			Code:    "delegate_is_owner",
+			Message: "you cannot approve your own record as a delegate",
+		}
+	}
 
-			// Set the approved timestamp
-			record.Set("approved", time.Now())
+	// Check if the record is submitted
+	if !record.GetBool("submitted") {
+		return http.StatusBadRequest, &CodeError{
+			Code:    "record_not_submitted",
+			Message: "only submitted records can be approved",
+		}
+	}
 
-			// Save the updated record
-			if err := txApp.Save(record); err != nil {
-				httpResponseStatusCode = http.StatusInternalServerError
-				return &CodeError{
-					Code:    "error_saving_record",
-					Message: fmt.Sprintf("error saving record: %v", err),
-				}
-			}
+	// Check if the record is committed
+	if !record.GetDateTime("committed").IsZero() {
+		return http.StatusBadRequest, &CodeError{
+			Code:    "record_committed",
+			Message: "committed records cannot be approved",
+		}
+	}
 
-			return nil
-		})
+	// Check if the record is already approved
+	if !record.GetDateTime("approved").IsZero() {
+		return http.StatusBadRequest, &CodeError{
+			Code:    "record_already_approved",
+			Message: "this record is already approved",
+		}
+	}
 
-		if err != nil {
-			return e.JSON(httpResponseStatusCode, map[string]string{"error": err.Error()})
+	if poErr := validateExpensePurchaseOrderIsActive(txApp, record); poErr != nil {
+		if poErr.Code == "purchase_order_lookup_error" {
+			return http.StatusInternalServerError, poErr
 		}
+		return http.StatusBadRequest, poErr
+	}
 
-		return e.JSON(http.StatusOK, map[string]string{"message": "Record approved successfully"})
+	// Set the approved timestamp
+	record.Set("approved", time.Now())
+	if isDelegated {
+		record.Set("delegated_approver", userId)
+	} else if delegationScope != "" {
+		record.Set("delegated_approver", "")
 	}
+
+	// Save the updated record
+	if err := txApp.Save(record); err != nil {
+		return http.StatusInternalServerError, &CodeError{
+			Code:    "error_saving_record",
+			Message: fmt.Sprintf("error saving record: %v", err),
+		}
+	}
+
+	return http.StatusOK, nil
 }
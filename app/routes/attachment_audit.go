@@ -111,9 +111,11 @@ type attachmentAuditOrphanedRow struct {
 
 var (
 	attachmentAuditTargets = []attachmentAuditTarget{
+		{Key: "client_agreements_client_agreement", Label: "Client Agreements", Collection: "client_agreements", Field: "client_agreement"},
 		{Key: "expense_documents_attachment", Label: "Expense Documents", Collection: "expense_documents", Field: "attachment"},
 		{Key: "jobs_project_authorization_doc", Label: "Project Authorization Documents", Collection: "jobs", Field: "project_authorization_doc"},
 		{Key: "purchase_orders_attachment", Label: "Purchase Orders", Collection: "purchase_orders", Field: "attachment"},
+		{Key: "work_records_attachment", Label: "Work Records", Collection: "work_records", Field: "attachment"},
 	}
 	attachmentAuditActiveRuns sync.Map
 )
@@ -6,6 +6,7 @@ import (
 	"time"
 
 	"tybalt/hooks"
+	"tybalt/timeoff"
 	"tybalt/utilities"
 
 	"github.com/pocketbase/dbx"
@@ -113,28 +114,9 @@ func createBundleTimesheetHandler(app core.App) func(e *core.RequestEvent) error
 			// validateTimeEntries function but we load a time_off_reset_date record
 			// here then pass it to that function.
 
-			// get the latest time_off_reset_date record that is less than the or
-			// equal to the week_ending of the new timesheet. We use
-			// FindFirstRecordByFilter because we want to order the results by date in
-			// descending order and limit the results to 1. limit the results to 1.
-			payrollYearEndDatesRecords, err := txApp.FindRecordsByFilter("payroll_year_end_dates", "date <= {:weekEnding}", "-date", 1, 0, dbx.Params{
-				"weekEnding": weekEnding,
-			})
-			if err != nil {
-				return fmt.Errorf("error fetching time off reset dates")
-			}
-			if len(payrollYearEndDatesRecords) == 0 {
-				return fmt.Errorf("no payroll year end dates found for the week ending %v", weekEnding)
-			}
-			payrollYearEndDate := payrollYearEndDatesRecords[0].Get("date")
-			payrollYearEndDateAsTime, err := time.Parse("2006-01-02", payrollYearEndDate.(string))
+			payrollYearEndDateAsTime, err := findPayrollYearEndForWeek(txApp, weekEnding)
 			if err != nil {
-				return fmt.Errorf("error parsing time off reset date: %v", err)
-			}
-
-			// verify that the time_off_reset_date is a Saturday
-			if payrollYearEndDateAsTime.Weekday() != time.Saturday {
-				return fmt.Errorf("payroll year end date %s is not a Saturday, contact support", payrollYearEndDate)
+				return err
 			}
 
 			// Validate the time entries as a group
@@ -150,42 +132,13 @@ func createBundleTimesheetHandler(app core.App) func(e *core.RequestEvent) error
 				return fmt.Errorf("error fetching time_sheets collection: %v", err)
 			}
 
-			// Get the manager (approver) from the profiles collection
-			profile, err := txApp.FindFirstRecordByFilter("profiles", "uid = {:userId}", dbx.Params{
-				"userId": userId,
-			})
+			// Get the manager (approver) and verify they may approve.
+			approverUID, approverErr, err := checkTimesheetApprover(txApp, userId)
 			if err != nil {
-				return fmt.Errorf("error fetching user profile: %v", err)
-			}
-
-			// manager is mandatory on the profiles collection in pocketbase
-			// rules so there is no need to check if it exists. Verify that the
-			// manager has the `tapr` claim (server-side enforcement mirrors UI).
-			approverUID := profile.GetString("manager")
-
-			// Check that the approver (manager) is an active user
-			active, activeErr := utilities.IsUserActive(txApp, approverUID)
-			if activeErr != nil {
-				return fmt.Errorf("error checking manager active status: %v", activeErr)
-			}
-			if !active {
-				transactionError = &CodeError{
-					Code:    "approver_not_active",
-					Message: "the approver (your manager) is not an active user",
-				}
-				httpResponseStatusCode = http.StatusBadRequest
-				return transactionError
-			}
-
-			hasTapr, claimErr := utilities.HasClaimByUserID(txApp, approverUID, "tapr")
-			if claimErr != nil {
-				return fmt.Errorf("error checking manager tapr claim: %v", claimErr)
+				return err
 			}
-			if !hasTapr {
-				transactionError = &CodeError{
-					Code:    "unqualified_approver",
-					Message: "the approver must have the tapr claim",
-				}
+			if approverErr != nil {
+				transactionError = approverErr
 				httpResponseStatusCode = http.StatusBadRequest
 				return transactionError
 			}
@@ -251,6 +204,106 @@ func createBundleTimesheetHandler(app core.App) func(e *core.RequestEvent) error
 			return e.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
-		return e.JSON(http.StatusOK, map[string]string{"message": "Time sheet processed successfully"})
+		body := map[string]any{"message": "Time sheet processed successfully"}
+
+		// In warn mode, OP/OV entries without an approved time off request are
+		// bundled but reported back so the user and approver can follow up. The
+		// block mode equivalent lives in validateTimeEntries.
+		warnings, err := timeOffRequestWarnings(app, userId, weekEnding)
+		if err != nil {
+			app.Logger().Error("error checking time off requests after bundle", "uid", userId, "week_ending", weekEnding, "error", err)
+		} else if len(warnings) > 0 {
+			body["warnings"] = warnings
+		}
+
+		return e.JSON(http.StatusOK, body)
+	}
+}
+
+// timeOffRequestWarnings reports OP/OV entries for the week that no approved
+// time off request covers when enforcement is in warn mode. It returns nil in
+// the other modes.
+func timeOffRequestWarnings(app core.App, userId string, weekEnding string) ([]map[string]any, error) {
+	if utilities.GetTimeOffRequestEnforcement(app) != utilities.TimeOffRequestEnforcementWarn {
+		return nil, nil
+	}
+	unrequested, err := timeoff.FindUnrequestedEntries(app, userId, weekEnding)
+	if err != nil || len(unrequested) == 0 {
+		return nil, err
+	}
+	return []map[string]any{{
+		"code":    "time_off_request_missing",
+		"message": "some time off on this timesheet has no approved time off request",
+		"entries": unrequested,
+	}}, nil
+}
+
+// findPayrollYearEndForWeek returns the latest payroll_year_end_dates date on
+// or before weekEnding. It must be a Saturday.
+func findPayrollYearEndForWeek(app core.App, weekEnding string) (time.Time, error) {
+	// get the latest time_off_reset_date record that is less than the or
+	// equal to the week_ending of the new timesheet. We use
+	// FindRecordsByFilter because we want to order the results by date in
+	// descending order and limit the results to 1.
+	payrollYearEndDatesRecords, err := app.FindRecordsByFilter("payroll_year_end_dates", "date <= {:weekEnding}", "-date", 1, 0, dbx.Params{
+		"weekEnding": weekEnding,
+	})
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error fetching time off reset dates")
+	}
+	if len(payrollYearEndDatesRecords) == 0 {
+		return time.Time{}, fmt.Errorf("no payroll year end dates found for the week ending %v", weekEnding)
+	}
+	payrollYearEndDate := payrollYearEndDatesRecords[0].Get("date")
+	payrollYearEndDateAsTime, err := time.Parse("2006-01-02", payrollYearEndDate.(string))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("error parsing time off reset date: %v", err)
+	}
+
+	// verify that the time_off_reset_date is a Saturday
+	if payrollYearEndDateAsTime.Weekday() != time.Saturday {
+		return time.Time{}, fmt.Errorf("payroll year end date %s is not a Saturday, contact support", payrollYearEndDate)
+	}
+	return payrollYearEndDateAsTime, nil
+}
+
+// checkTimesheetApprover returns the user's manager, who approves their time
+// sheets. The CodeError result reports a manager who is inactive or lacks the
+// tapr claim; the error result reports lookup failures.
+func checkTimesheetApprover(app core.App, userId string) (string, *CodeError, error) {
+	profile, err := app.FindFirstRecordByFilter("profiles", "uid = {:userId}", dbx.Params{
+		"userId": userId,
+	})
+	if err != nil {
+		return "", nil, fmt.Errorf("error fetching user profile: %v", err)
+	}
+
+	// manager is mandatory on the profiles collection in pocketbase
+	// rules so there is no need to check if it exists. Verify that the
+	// manager has the `tapr` claim (server-side enforcement mirrors UI).
+	approverUID := profile.GetString("manager")
+
+	// Check that the approver (manager) is an active user
+	active, activeErr := utilities.IsUserActive(app, approverUID)
+	if activeErr != nil {
+		return "", nil, fmt.Errorf("error checking manager active status: %v", activeErr)
+	}
+	if !active {
+		return approverUID, &CodeError{
+			Code:    "approver_not_active",
+			Message: "the approver (your manager) is not an active user",
+		}, nil
+	}
+
+	hasTapr, claimErr := utilities.HasClaimByUserID(app, approverUID, "tapr")
+	if claimErr != nil {
+		return "", nil, fmt.Errorf("error checking manager tapr claim: %v", claimErr)
+	}
+	if !hasTapr {
+		return approverUID, &CodeError{
+			Code:    "unqualified_approver",
+			Message: "the approver must have the tapr claim",
+		}, nil
 	}
+	return approverUID, nil, nil
 }
@@ -0,0 +1,661 @@
+package routes
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"tybalt/errs"
+	"tybalt/notifications"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// cardStatementMatchDateWindowDays is how many days a statement line's posted
+// date may differ from an expense's date and still match. Card issuers
+// usually post a charge a day or two after the purchase.
+const cardStatementMatchDateWindowDays = 5
+
+// maxCardStatementLines caps the number of charges one statement may hold.
+const maxCardStatementLines = 2000
+
+var cardLast4Pattern = regexp.MustCompile(`^\d{4}$`)
+
+type cardStatementSummary struct {
+	ID             string `db:"id" json:"id"`
+	CCLast4Digits  string `db:"cc_last_4_digits" json:"cc_last_4_digits"`
+	Cardholder     string `db:"cardholder" json:"cardholder"`
+	CardholderName string `db:"cardholder_name" json:"cardholder_name"`
+	PeriodStart    string `db:"period_start" json:"period_start"`
+	PeriodEnd      string `db:"period_end" json:"period_end"`
+	SourceFormat   string `db:"source_format" json:"source_format"`
+	FileName       string `db:"file_name" json:"file_name"`
+	Importer       string `db:"importer" json:"importer"`
+	ImporterName   string `db:"importer_name" json:"importer_name"`
+	Created        string `db:"created" json:"created"`
+	LineCount      int    `db:"line_count" json:"line_count"`
+	UnmatchedCount int    `db:"unmatched_count" json:"unmatched_count"`
+	SuggestedCount int    `db:"suggested_count" json:"suggested_count"`
+	ConfirmedCount int    `db:"confirmed_count" json:"confirmed_count"`
+}
+
+type cardStatementLineRow struct {
+	ID                 string  `db:"id" json:"id"`
+	Line               int     `db:"line" json:"line"`
+	Date               string  `db:"date" json:"date"`
+	Amount             float64 `db:"amount" json:"amount"`
+	Description        string  `db:"description" json:"description"`
+	Reference          string  `db:"reference" json:"reference"`
+	MatchStatus        string  `db:"match_status" json:"match_status"`
+	Expense            string  `db:"expense" json:"expense"`
+	ExpenseDate        string  `db:"expense_date" json:"expense_date"`
+	ExpenseTotal       float64 `db:"expense_total" json:"expense_total"`
+	ExpenseUID         string  `db:"expense_uid" json:"expense_uid"`
+	ExpenseCreatorName string  `db:"expense_creator_name" json:"expense_creator_name"`
+	ExpenseVendorName  string  `db:"expense_vendor_name" json:"expense_vendor_name"`
+	ExpenseVendorAlias string  `db:"expense_vendor_alias" json:"-"`
+	ExpenseDescription string  `db:"expense_description" json:"expense_description"`
+	VendorMatched      bool    `db:"-" json:"vendor_matched"`
+	Confirmer          string  `db:"confirmer" json:"confirmer"`
+	ConfirmerName      string  `db:"confirmer_name" json:"confirmer_name"`
+	Confirmed          string  `db:"confirmed" json:"confirmed"`
+	Reminded           string  `db:"reminded" json:"reminded"`
+}
+
+type cardStatementExpenseRow struct {
+	ID           string  `db:"id" json:"id"`
+	Date         string  `db:"date" json:"date"`
+	UID          string  `db:"uid" json:"uid"`
+	CreatorName  string  `db:"creator_name" json:"creator_name"`
+	VendorName   string  `db:"vendor_name" json:"vendor_name"`
+	Description  string  `db:"description" json:"description"`
+	Total        float64 `db:"total" json:"total"`
+	CurrencyCode string  `db:"currency_code" json:"currency_code"`
+	Submitted    bool    `db:"submitted" json:"submitted"`
+	Approved     string  `db:"approved" json:"approved"`
+}
+
+type cardStatementDetail struct {
+	Statement         cardStatementSummary      `json:"statement"`
+	Lines             []cardStatementLineRow    `json:"lines"`
+	UnmatchedExpenses []cardStatementExpenseRow `json:"unmatched_expenses"`
+}
+
+type confirmCardStatementLineRequest struct {
+	Expense string `json:"expense"`
+}
+
+func cardStatementError(status int, field string, code string, message string) error {
+	return &errs.HookError{
+		Status:  status,
+		Message: message,
+		Data: map[string]errs.CodeError{
+			field: {Code: code, Message: message},
+		},
+	}
+}
+
+// cardStatementExpenseCADTotal is the amount a CorporateCreditCard expense
+// (aliased e, with currencies aliased cur) should appear as on the card
+// statement. Foreign-currency expenses only have one once they are settled.
+const cardStatementExpenseCADTotal = `CASE
+	WHEN COALESCE(cur.code, 'CAD') = 'CAD' THEN CAST(e.total AS REAL)
+	ELSE CAST(COALESCE(e.settled_total, 0) AS REAL)
+END`
+
+const cardStatementSummaryQuery = `
+	SELECT
+		s.id,
+		s.cc_last_4_digits,
+		s.cardholder,
+		TRIM(COALESCE(cp.given_name, '') || ' ' || COALESCE(cp.surname, '')) AS cardholder_name,
+		s.period_start,
+		s.period_end,
+		s.source_format,
+		COALESCE(s.file_name, '') AS file_name,
+		s.importer,
+		TRIM(COALESCE(ip.given_name, '') || ' ' || COALESCE(ip.surname, '')) AS importer_name,
+		s.created,
+		(SELECT COUNT(*) FROM card_statement_lines l WHERE l.statement = s.id) AS line_count,
+		(SELECT COUNT(*) FROM card_statement_lines l WHERE l.statement = s.id AND l.match_status = 'unmatched') AS unmatched_count,
+		(SELECT COUNT(*) FROM card_statement_lines l WHERE l.statement = s.id AND l.match_status = 'suggested') AS suggested_count,
+		(SELECT COUNT(*) FROM card_statement_lines l WHERE l.statement = s.id AND l.match_status = 'confirmed') AS confirmed_count
+	FROM card_statements s
+	LEFT JOIN profiles cp ON cp.uid = s.cardholder
+	LEFT JOIN profiles ip ON ip.uid = s.importer
+`
+
+// createCardStatementListHandler lists imported card statements, newest
+// period first, with how many of their lines are matched.
+func createCardStatementListHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requirePayablesAdmin(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		rows := []cardStatementSummary{}
+		if err := app.DB().NewQuery(cardStatementSummaryQuery + ` ORDER BY s.period_end DESC, s.created DESC`).All(&rows); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load card statements", err)
+		}
+		return e.JSON(http.StatusOK, rows)
+	}
+}
+
+// createCardStatementDetailsHandler returns a card statement with its lines
+// and the card's expenses in the statement period that no line matches.
+func createCardStatementDetailsHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requirePayablesAdmin(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+		return writeCardStatementDetail(e, app, e.Request.PathValue("id"))
+	}
+}
+
+// createImportCardStatementHandler imports a corporate credit card statement
+// uploaded as the "file" form field, in CSV or OFX format, for the card whose
+// last four digits are in cc_last_4_digits and whose holder is cardholder.
+// The period defaults to the one in an OFX file, else to the first and last
+// charge dates; period_start and period_end override it. The statement's
+// lines are matched to expenses before the details are returned.
+func createImportCardStatementHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requirePayablesAdmin(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		files, err := e.FindUploadedFiles("file")
+		if err != nil && !errors.Is(err, http.ErrMissingFile) {
+			return e.BadRequestError("failed to read uploaded file", err)
+		}
+		if len(files) != 1 {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "file", "required", "upload exactly one statement file"))
+		}
+		file := files[0]
+
+		last4 := strings.TrimSpace(e.Request.FormValue("cc_last_4_digits"))
+		if !cardLast4Pattern.MatchString(last4) {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "cc_last_4_digits", "invalid_card", "cc_last_4_digits must be the last four digits of the card"))
+		}
+		cardholderID := strings.TrimSpace(e.Request.FormValue("cardholder"))
+		if cardholderID == "" {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "cardholder", "required", "cardholder is required"))
+		}
+		if _, err := app.FindRecordById("users", cardholderID); err != nil {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "cardholder", "invalid_reference", "cardholder is not a user"))
+		}
+
+		sourceFormat := strings.ToLower(strings.TrimPrefix(filepath.Ext(file.OriginalName), "."))
+		if sourceFormat == "qfx" {
+			sourceFormat = "ofx"
+		}
+		if sourceFormat != "csv" && sourceFormat != "ofx" {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "file", "unsupported_format", "statement files must be .csv or .ofx"))
+		}
+
+		reader, err := file.Reader.Open()
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to open uploaded file", err)
+		}
+		defer reader.Close()
+
+		var statement *utilities.CardStatement
+		if sourceFormat == "ofx" {
+			statement, err = utilities.ParseCardStatementOFX(reader)
+		} else {
+			statement, err = utilities.ParseCardStatementCSV(reader)
+		}
+		if err != nil {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "file", "invalid_statement", err.Error()))
+		}
+		if statement.AccountLast4 != "" && statement.AccountLast4 != last4 {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "cc_last_4_digits", "card_mismatch", fmt.Sprintf("the statement is for the card ending in %s", statement.AccountLast4)))
+		}
+		if len(statement.Lines) == 0 {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "file", "no_charges", "the statement has no charges"))
+		}
+		if len(statement.Lines) > maxCardStatementLines {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "file", "too_many_lines", fmt.Sprintf("a statement can include at most %d charges", maxCardStatementLines)))
+		}
+
+		periodStart, periodEnd := statement.PeriodStart, statement.PeriodEnd
+		for _, line := range statement.Lines {
+			if statement.PeriodStart == "" && (periodStart == "" || line.Date < periodStart) {
+				periodStart = line.Date
+			}
+			if statement.PeriodEnd == "" && line.Date > periodEnd {
+				periodEnd = line.Date
+			}
+		}
+		for field, target := range map[string]*string{"period_start": &periodStart, "period_end": &periodEnd} {
+			if value := strings.TrimSpace(e.Request.FormValue(field)); value != "" {
+				if _, err := time.Parse(time.DateOnly, value); err != nil {
+					return writeHookError(e, cardStatementError(http.StatusBadRequest, field, "invalid_date", field+" must be YYYY-MM-DD"))
+				}
+				*target = value
+			}
+		}
+		if periodEnd < periodStart {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "period_end", "invalid_period", "period_end must not be before period_start"))
+		}
+
+		var statementID string
+		err = app.RunInTransaction(func(txApp core.App) error {
+			statementsCollection, err := txApp.FindCollectionByNameOrId("card_statements")
+			if err != nil {
+				return err
+			}
+			linesCollection, err := txApp.FindCollectionByNameOrId("card_statement_lines")
+			if err != nil {
+				return err
+			}
+
+			statementRecord := core.NewRecord(statementsCollection)
+			statementRecord.Set("cc_last_4_digits", last4)
+			statementRecord.Set("cardholder", cardholderID)
+			statementRecord.Set("period_start", periodStart)
+			statementRecord.Set("period_end", periodEnd)
+			statementRecord.Set("source_format", sourceFormat)
+			statementRecord.Set("file_name", file.OriginalName)
+			statementRecord.Set("importer", e.Auth.Id)
+			if err := txApp.Save(statementRecord); err != nil {
+				return err
+			}
+
+			for i, line := range statement.Lines {
+				lineRecord := core.NewRecord(linesCollection)
+				lineRecord.Set("statement", statementRecord.Id)
+				lineRecord.Set("line", i+1)
+				lineRecord.Set("date", line.Date)
+				lineRecord.Set("amount", line.Amount)
+				lineRecord.Set("description", line.Description)
+				lineRecord.Set("reference", line.Reference)
+				lineRecord.Set("match_status", "unmatched")
+				if err := txApp.Save(lineRecord); err != nil {
+					return err
+				}
+			}
+
+			statementID = statementRecord.Id
+			return matchCardStatementLines(txApp, statementRecord)
+		})
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to import card statement", err)
+		}
+
+		return writeCardStatementDetail(e, app, statementID)
+	}
+}
+
+// createMatchCardStatementHandler suggests expenses for the statement's lines
+// that are not matched yet, for example after cardholders submit expenses for
+// charges that had none.
+func createMatchCardStatementHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requirePayablesAdmin(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		statementID := e.Request.PathValue("id")
+		err := app.RunInTransaction(func(txApp core.App) error {
+			statement, err := txApp.FindRecordById("card_statements", statementID)
+			if err != nil {
+				return err
+			}
+			return matchCardStatementLines(txApp, statement)
+		})
+		if errors.Is(err, sql.ErrNoRows) {
+			return writeHookError(e, cardStatementError(http.StatusNotFound, "global", "not_found", "card statement not found"))
+		}
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to match card statement", err)
+		}
+
+		return writeCardStatementDetail(e, app, statementID)
+	}
+}
+
+// createConfirmCardStatementLineHandler confirms a line's match. With an
+// empty body it confirms the suggested expense; an "expense" in the body
+// matches that expense instead, which must be an unrejected
+// CorporateCreditCard expense for the statement's card that no other line
+// matches.
+func createConfirmCardStatementLineHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requirePayablesAdmin(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		var req confirmCardStatementLineRequest
+		if e.Request.ContentLength != 0 {
+			if err := json.NewDecoder(e.Request.Body).Decode(&req); err != nil {
+				return e.Error(http.StatusBadRequest, "invalid JSON body", err)
+			}
+		}
+
+		statementID := e.Request.PathValue("id")
+		err := app.RunInTransaction(func(txApp core.App) error {
+			statement, line, err := findCardStatementLine(txApp, statementID, e.Request.PathValue("lineId"))
+			if err != nil {
+				return err
+			}
+
+			expenseID := strings.TrimSpace(req.Expense)
+			if expenseID == "" {
+				if line.GetString("match_status") != "suggested" {
+					return cardStatementError(http.StatusBadRequest, "expense", "no_suggested_expense", "the line has no suggested expense to confirm")
+				}
+				expenseID = line.GetString("expense")
+			}
+			if expenseID != line.GetString("expense") {
+				if err := validateCardStatementExpense(txApp, statement, line, expenseID); err != nil {
+					return err
+				}
+			}
+
+			line.Set("expense", expenseID)
+			line.Set("match_status", "confirmed")
+			line.Set("confirmer", e.Auth.Id)
+			line.Set("confirmed", time.Now())
+			return txApp.Save(line)
+		})
+		if err != nil {
+			return writeCardStatementLineError(e, err)
+		}
+
+		return writeCardStatementDetail(e, app, statementID)
+	}
+}
+
+// createUnmatchCardStatementLineHandler clears a line's suggested or
+// confirmed expense. Matching will not suggest an expense for the line again
+// until it is rerun.
+func createUnmatchCardStatementLineHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requirePayablesAdmin(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		statementID := e.Request.PathValue("id")
+		err := app.RunInTransaction(func(txApp core.App) error {
+			_, line, err := findCardStatementLine(txApp, statementID, e.Request.PathValue("lineId"))
+			if err != nil {
+				return err
+			}
+			line.Set("expense", "")
+			line.Set("match_status", "unmatched")
+			line.Set("confirmer", "")
+			line.Set("confirmed", "")
+			return txApp.Save(line)
+		})
+		if err != nil {
+			return writeCardStatementLineError(e, err)
+		}
+
+		return writeCardStatementDetail(e, app, statementID)
+	}
+}
+
+// createRemindCardStatementHandler notifies the cardholder of the statement's
+// unmatched lines with the card_statement_unmatched template and records when
+// each line was included in a reminder.
+func createRemindCardStatementHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requirePayablesAdmin(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		statement, err := app.FindRecordById("card_statements", e.Request.PathValue("id"))
+		if err != nil {
+			return writeHookError(e, cardStatementError(http.StatusNotFound, "global", "not_found", "card statement not found"))
+		}
+		lines, err := app.FindRecordsByFilter("card_statement_lines", "statement = {:statement} && match_status = 'unmatched'", "line", 0, 0, dbx.Params{
+			"statement": statement.Id,
+		})
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load card statement lines", err)
+		}
+		if len(lines) == 0 {
+			return writeHookError(e, cardStatementError(http.StatusBadRequest, "global", "no_unmatched_lines", "the statement has no unmatched lines"))
+		}
+
+		createdCount, err := notifications.QueueCardStatementUnmatchedNotifications(app, statement, lines, e.Auth.Id)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to notify cardholder", err)
+		}
+		if createdCount > 0 {
+			now := time.Now()
+			if err := app.RunInTransaction(func(txApp core.App) error {
+				for _, line := range lines {
+					line.Set("reminded", now)
+					if err := txApp.Save(line); err != nil {
+						return err
+					}
+				}
+				return nil
+			}); err != nil {
+				return e.Error(http.StatusInternalServerError, "failed to record reminder", err)
+			}
+		}
+
+		return e.JSON(http.StatusOK, map[string]int{
+			"line_count":         len(lines),
+			"notification_count": createdCount,
+		})
+	}
+}
+
+// matchCardStatementLines suggests an expense for each of the statement's
+// unmatched lines, in line order. A candidate is an unrejected
+// CorporateCreditCard expense for the same card with the line's amount,
+// dated within cardStatementMatchDateWindowDays of it, that no line in any
+// statement matches. Candidates whose vendor appears in the line description
+// are preferred, then the closest date. It must run inside a transaction.
+func matchCardStatementLines(txApp core.App, statement *core.Record) error {
+	lines, err := txApp.FindRecordsByFilter("card_statement_lines", "statement = {:statement} && match_status = 'unmatched'", "line", 0, 0, dbx.Params{
+		"statement": statement.Id,
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, line := range lines {
+		var candidates []struct {
+			ID          string `db:"id"`
+			VendorName  string `db:"vendor_name"`
+			VendorAlias string `db:"vendor_alias"`
+		}
+		if err := txApp.DB().NewQuery(`
+			SELECT e.id, COALESCE(v.name, '') AS vendor_name, COALESCE(v.alias, '') AS vendor_alias
+			FROM expenses e
+			LEFT JOIN vendors v ON v.id = e.vendor
+			LEFT JOIN currencies cur ON cur.id = e.currency
+			WHERE e.payment_type = 'CorporateCreditCard'
+			  AND e.cc_last_4_digits = {:last4}
+			  AND COALESCE(e.rejected, '') = ''
+			  AND ABS(julianday(e.date) - julianday({:date})) <= {:window}
+			  AND ABS(` + cardStatementExpenseCADTotal + ` - {:amount}) < 0.005
+			  AND NOT EXISTS (SELECT 1 FROM card_statement_lines l WHERE l.expense = e.id)
+			ORDER BY ABS(julianday(e.date) - julianday({:date})), e.created
+		`).Bind(dbx.Params{
+			"last4":  statement.GetString("cc_last_4_digits"),
+			"date":   line.GetString("date"),
+			"window": cardStatementMatchDateWindowDays,
+			"amount": line.GetFloat("amount"),
+		}).All(&candidates); err != nil {
+			return err
+		}
+		if len(candidates) == 0 {
+			continue
+		}
+
+		best := candidates[0].ID
+		for _, candidate := range candidates {
+			if cardStatementVendorMatches(line.GetString("description"), candidate.VendorName, candidate.VendorAlias) {
+				best = candidate.ID
+				break
+			}
+		}
+		line.Set("expense", best)
+		line.Set("match_status", "suggested")
+		if err := txApp.Save(line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// cardStatementVendorMatches reports whether a word of at least three letters
+// or digits from the vendor's name or alias appears in a statement line's
+// description. Issuers shorten and decorate merchant names, so an exact
+// comparison rarely matches.
+func cardStatementVendorMatches(description string, vendorName string, vendorAlias string) bool {
+	descriptionWords := map[string]bool{}
+	for _, word := range utilities.NameWords(description) {
+		descriptionWords[word] = true
+	}
+	for _, word := range append(utilities.NameWords(vendorName), utilities.NameWords(vendorAlias)...) {
+		if len(word) >= 3 && descriptionWords[word] {
+			return true
+		}
+	}
+	return false
+}
+
+// validateCardStatementExpense checks that expenseID may be confirmed as the
+// match for line on statement.
+func validateCardStatementExpense(txApp core.App, statement *core.Record, line *core.Record, expenseID string) error {
+	expense, err := txApp.FindRecordById("expenses", expenseID)
+	if err != nil {
+		return cardStatementError(http.StatusBadRequest, "expense", "invalid_reference", "expense not found")
+	}
+	if expense.GetString("payment_type") != "CorporateCreditCard" || expense.GetString("cc_last_4_digits") != statement.GetString("cc_last_4_digits") {
+		return cardStatementError(http.StatusBadRequest, "expense", "card_mismatch", "the expense was not charged to this card")
+	}
+	if !expense.GetDateTime("rejected").IsZero() {
+		return cardStatementError(http.StatusBadRequest, "expense", "expense_rejected", "rejected expenses cannot be matched")
+	}
+	other, err := txApp.FindFirstRecordByFilter("card_statement_lines", "expense = {:expense} && id != {:line}", dbx.Params{
+		"expense": expenseID,
+		"line":    line.Id,
+	})
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return err
+	}
+	if other != nil {
+		return cardStatementError(http.StatusConflict, "expense", "expense_already_matched", "the expense is already matched to another statement line")
+	}
+	return nil
+}
+
+// findCardStatementLine loads a statement and one of its lines, returning a
+// 404 HookError when either does not exist.
+func findCardStatementLine(txApp core.App, statementID string, lineID string) (*core.Record, *core.Record, error) {
+	statement, err := txApp.FindRecordById("card_statements", statementID)
+	if err != nil {
+		return nil, nil, cardStatementError(http.StatusNotFound, "global", "not_found", "card statement not found")
+	}
+	line, err := txApp.FindRecordById("card_statement_lines", lineID)
+	if err != nil || line.GetString("statement") != statement.Id {
+		return nil, nil, cardStatementError(http.StatusNotFound, "global", "not_found", "card statement line not found")
+	}
+	return statement, line, nil
+}
+
+func writeCardStatementLineError(e *core.RequestEvent, err error) error {
+	var hookErr *errs.HookError
+	if errors.As(err, &hookErr) {
+		return writeHookError(e, hookErr)
+	}
+	return e.Error(http.StatusInternalServerError, "failed to update card statement line", err)
+}
+
+func writeCardStatementDetail(e *core.RequestEvent, app core.App, statementID string) error {
+	var detail cardStatementDetail
+	if err := app.DB().NewQuery(cardStatementSummaryQuery + ` WHERE s.id = {:id}`).Bind(dbx.Params{
+		"id": statementID,
+	}).One(&detail.Statement); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return writeHookError(e, cardStatementError(http.StatusNotFound, "global", "not_found", "card statement not found"))
+		}
+		return e.Error(http.StatusInternalServerError, "failed to load card statement", err)
+	}
+
+	detail.Lines = []cardStatementLineRow{}
+	if err := app.DB().NewQuery(`
+		SELECT
+			l.id,
+			CAST(l.line AS INTEGER) AS line,
+			l.date,
+			CAST(l.amount AS REAL) AS amount,
+			COALESCE(l.description, '') AS description,
+			COALESCE(l.reference, '') AS reference,
+			l.match_status,
+			COALESCE(l.expense, '') AS expense,
+			COALESCE(e.date, '') AS expense_date,
+			CAST(COALESCE(e.total, 0) AS REAL) AS expense_total,
+			COALESCE(e.uid, '') AS expense_uid,
+			TRIM(COALESCE(ep.given_name, '') || ' ' || COALESCE(ep.surname, '')) AS expense_creator_name,
+			COALESCE(v.name, '') AS expense_vendor_name,
+			COALESCE(v.alias, '') AS expense_vendor_alias,
+			COALESCE(e.description, '') AS expense_description,
+			COALESCE(l.confirmer, '') AS confirmer,
+			TRIM(COALESCE(cp.given_name, '') || ' ' || COALESCE(cp.surname, '')) AS confirmer_name,
+			COALESCE(l.confirmed, '') AS confirmed,
+			COALESCE(l.reminded, '') AS reminded
+		FROM card_statement_lines l
+		LEFT JOIN expenses e ON e.id = l.expense
+		LEFT JOIN profiles ep ON ep.uid = e.uid
+		LEFT JOIN vendors v ON v.id = e.vendor
+		LEFT JOIN profiles cp ON cp.uid = l.confirmer
+		WHERE l.statement = {:id}
+		ORDER BY l.line
+	`).Bind(dbx.Params{"id": statementID}).All(&detail.Lines); err != nil {
+		return e.Error(http.StatusInternalServerError, "failed to load card statement lines", err)
+	}
+	for i := range detail.Lines {
+		line := &detail.Lines[i]
+		line.VendorMatched = line.Expense != "" && cardStatementVendorMatches(line.Description, line.ExpenseVendorName, line.ExpenseVendorAlias)
+	}
+
+	detail.UnmatchedExpenses = []cardStatementExpenseRow{}
+	if err := app.DB().NewQuery(`
+		SELECT
+			e.id,
+			e.date,
+			e.uid,
+			TRIM(COALESCE(p.given_name, '') || ' ' || COALESCE(p.surname, '')) AS creator_name,
+			COALESCE(v.name, '') AS vendor_name,
+			COALESCE(e.description, '') AS description,
+			CAST(e.total AS REAL) AS total,
+			COALESCE(cur.code, 'CAD') AS currency_code,
+			e.submitted,
+			COALESCE(e.approved, '') AS approved
+		FROM expenses e
+		LEFT JOIN profiles p ON p.uid = e.uid
+		LEFT JOIN vendors v ON v.id = e.vendor
+		LEFT JOIN currencies cur ON cur.id = e.currency
+		WHERE e.payment_type = 'CorporateCreditCard'
+		  AND e.cc_last_4_digits = {:last4}
+		  AND COALESCE(e.rejected, '') = ''
+		  AND e.date BETWEEN {:start} AND {:end}
+		  AND NOT EXISTS (SELECT 1 FROM card_statement_lines l WHERE l.expense = e.id)
+		ORDER BY e.date, e.created
+	`).Bind(dbx.Params{
+		"last4": detail.Statement.CCLast4Digits,
+		"start": detail.Statement.PeriodStart,
+		"end":   detail.Statement.PeriodEnd,
+	}).All(&detail.UnmatchedExpenses); err != nil {
+		return e.Error(http.StatusInternalServerError, "failed to load unmatched expenses", err)
+	}
+
+	return e.JSON(http.StatusOK, detail)
+}
@@ -0,0 +1,112 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+type clientAgreementHashAuditResponse struct {
+	AgreementID string `json:"agreement_id"`
+	JobID       string `json:"job_id"`
+	storedFileHashAuditResponse
+}
+
+type clientAgreementHashReplaceResponse struct {
+	AgreementID string `json:"agreement_id"`
+	JobID       string `json:"job_id"`
+	storedFileHashReplaceResponse
+}
+
+var clientAgreementHashMessages = storedFileHashMessages{
+	EmptyStoragePath: "client agreement not found",
+	OpenFilesystem:   "failed to open filesystem",
+	FileNotFound:     "client agreement file not found",
+	HashFailed:       "failed to hash client agreement",
+	TargetChanged:    "client agreement target changed",
+	UpdatedChanged:   "client agreement changed; rerun audit before replacing",
+	UniqueConflict:   "calculated hash already belongs to another client agreement",
+}
+
+func createAuditClientAgreementHashHandler(app core.App) func(e *core.RequestEvent) error {
+	return createStoredFileHashAuditHandler(app, auditClientAgreementHash, clientAgreementHashRouteError)
+}
+
+func createReplaceClientAgreementHashHandler(app core.App) func(e *core.RequestEvent) error {
+	return createStoredFileHashReplaceHandler(app, replaceClientAgreementHash, clientAgreementHashRouteError)
+}
+
+func auditClientAgreementHash(app core.App, agreementID string) (clientAgreementHashAuditResponse, error) {
+	var jobID string
+	audit, err := auditStoredFileHash(app, func(app core.App) (storedFileHashTarget, error) {
+		target, job, err := resolveClientAgreementHashTarget(app, agreementID)
+		jobID = job
+		return target, err
+	}, clientAgreementHashMessages)
+	if err != nil {
+		return clientAgreementHashAuditResponse{}, err
+	}
+	return clientAgreementHashAuditResponse{
+		AgreementID:                 audit.Target.TargetID,
+		JobID:                       jobID,
+		storedFileHashAuditResponse: storedFileHashAuditResponseFromStored(audit),
+	}, nil
+}
+
+func replaceClientAgreementHash(app core.App, agreementID string, expectedUpdated string) (clientAgreementHashReplaceResponse, error) {
+	var jobID string
+	replacement, err := replaceStoredFileHash(app, expectedUpdated, func(app core.App) (storedFileHashTarget, error) {
+		target, job, err := resolveClientAgreementHashTarget(app, agreementID)
+		jobID = job
+		return target, err
+	}, clientAgreementHashMessages)
+	if err != nil {
+		return clientAgreementHashReplaceResponse{}, err
+	}
+	return clientAgreementHashReplaceResponse{
+		AgreementID:                   replacement.Audit.Target.TargetID,
+		JobID:                         jobID,
+		storedFileHashReplaceResponse: storedFileHashReplaceResponseFromStored(replacement),
+	}, nil
+}
+
+func resolveClientAgreementHashTarget(app core.App, agreementID string) (storedFileHashTarget, string, error) {
+	agreementID = strings.TrimSpace(agreementID)
+	if agreementID == "" {
+		return storedFileHashTarget{}, "", &storedFileHashHTTPError{status: http.StatusBadRequest, message: "client agreement id is required"}
+	}
+
+	agreement, err := app.FindRecordById("client_agreements", agreementID)
+	if err != nil {
+		return storedFileHashTarget{}, "", &storedFileHashHTTPError{status: http.StatusNotFound, message: "client agreement not found", err: err}
+	}
+
+	filename := strings.TrimSpace(agreement.GetString("client_agreement"))
+	if filename == "" {
+		return storedFileHashTarget{}, "", &storedFileHashHTTPError{status: http.StatusNotFound, message: "client agreement has no file"}
+	}
+	updated, err := storedFileHashUpdatedString(app, "client_agreements", agreement.Id)
+	if err != nil {
+		return storedFileHashTarget{}, "", &storedFileHashHTTPError{status: http.StatusInternalServerError, message: "failed to load client agreement timestamp", err: err}
+	}
+
+	return storedFileHashTarget{
+		TargetCollection: "client_agreements",
+		TargetID:         agreement.Id,
+		Filename:         filename,
+		StoragePath:      agreement.BaseFilesPath() + "/" + filename,
+		StoredHash:       strings.TrimSpace(agreement.GetString("client_agreement_hash")),
+		Updated:          updated,
+		HashField:        "client_agreement_hash",
+	}, agreement.GetString("job"), nil
+}
+
+func clientAgreementHashRouteError(e *core.RequestEvent, err error) error {
+	var httpErr *storedFileHashHTTPError
+	if errors.As(err, &httpErr) {
+		return e.Error(httpErr.status, httpErr.message, httpErr.err)
+	}
+	return writeHookError(e, err)
+}
@@ -0,0 +1,281 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"tybalt/errs"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const duplicateClientAgreementMessage = "this client agreement has already been uploaded"
+
+// ClientAgreementRow is one agreement document on a job as returned by the
+// client agreements list and upload routes. FileURL points at the
+// authenticated download route rather than the protected PocketBase file.
+type ClientAgreementRow struct {
+	ID                  string `db:"id" json:"id"`
+	Job                 string `db:"job" json:"job"`
+	ClientAgreement     string `db:"client_agreement" json:"client_agreement"`
+	ClientAgreementHash string `db:"client_agreement_hash" json:"client_agreement_hash"`
+	FileURL             string `json:"file_url"`
+	Uploader            string `db:"uploader" json:"uploader"`
+	UploaderName        string `db:"uploader_name" json:"uploader_name"`
+	Uploaded            string `db:"uploaded" json:"uploaded"`
+}
+
+const clientAgreementsQuery = `
+	SELECT
+		ca.id,
+		ca.job,
+		ca.client_agreement,
+		ca.client_agreement_hash,
+		ca.uploader,
+		COALESCE(p.given_name || ' ' || p.surname, '') AS uploader_name,
+		ca.uploaded
+	FROM client_agreements ca
+	LEFT JOIN profiles p ON p.uid = ca.uploader
+	WHERE ca.job = {:job}
+`
+
+// canAccessClientAgreements reports whether auth may see, upload or remove
+// agreement documents on job. This is deliberately narrower than job
+// visibility: only the job's manager, its alternate manager and holders of the
+// client_agreements claim qualify.
+func canAccessClientAgreements(app core.App, job *core.Record, auth *core.Record) (bool, error) {
+	if job == nil || auth == nil || auth.Id == "" {
+		return false, nil
+	}
+	if auth.Id == strings.TrimSpace(job.GetString("manager")) || auth.Id == strings.TrimSpace(job.GetString("alternate_manager")) {
+		return true, nil
+	}
+	return utilities.HasClaim(app, auth, "client_agreements")
+}
+
+// loadClientAgreementJob loads the job named in the request path and confirms
+// the caller may access its agreements. Jobs the caller cannot access are
+// reported as forbidden so existence of the job itself is not hidden.
+func loadClientAgreementJob(app core.App, e *core.RequestEvent) (*core.Record, error) {
+	if e.Auth == nil || e.Auth.Id == "" {
+		return nil, &errs.HookError{
+			Status:  http.StatusUnauthorized,
+			Message: "unauthorized",
+			Data: map[string]errs.CodeError{
+				"global": {Code: "unauthorized", Message: "authentication required"},
+			},
+		}
+	}
+	job, err := app.FindRecordById("jobs", e.Request.PathValue("id"))
+	if err != nil {
+		return nil, &errs.HookError{
+			Status:  http.StatusNotFound,
+			Message: "job not found",
+			Data: map[string]errs.CodeError{
+				"job": {Code: "not_found", Message: "job not found"},
+			},
+		}
+	}
+	allowed, err := canAccessClientAgreements(app, job, e.Auth)
+	if err != nil {
+		return nil, err
+	}
+	if !allowed {
+		return nil, &errs.HookError{
+			Status:  http.StatusForbidden,
+			Message: "you are not authorized to access client agreements for this job",
+			Data: map[string]errs.CodeError{
+				"global": {Code: "unauthorized", Message: "job manager, alternate manager or client_agreements claim required"},
+			},
+		}
+	}
+	return job, nil
+}
+
+func loadClientAgreementForJob(app core.App, jobID string, agreementID string) (*core.Record, error) {
+	agreement, err := app.FindRecordById("client_agreements", agreementID)
+	if err != nil || agreement.GetString("job") != jobID {
+		return nil, &errs.HookError{
+			Status:  http.StatusNotFound,
+			Message: "client agreement not found for job",
+			Data: map[string]errs.CodeError{
+				"client_agreement": {Code: "not_found", Message: "client agreement not found for job"},
+			},
+		}
+	}
+	return agreement, nil
+}
+
+func clientAgreementFileURL(row ClientAgreementRow) string {
+	return fmt.Sprintf("/api/jobs/%s/client_agreements/%s/file", row.Job, row.ID)
+}
+
+func listClientAgreementRows(app core.App, jobID string, agreementID string) ([]ClientAgreementRow, error) {
+	query := clientAgreementsQuery
+	params := dbx.Params{"job": jobID}
+	if agreementID != "" {
+		query += " AND ca.id = {:id}"
+		params["id"] = agreementID
+	}
+	query += " ORDER BY ca.uploaded DESC, ca.created DESC"
+
+	rows := []ClientAgreementRow{}
+	if err := app.DB().NewQuery(query).Bind(params).All(&rows); err != nil {
+		return nil, err
+	}
+	for i := range rows {
+		rows[i].FileURL = clientAgreementFileURL(rows[i])
+	}
+	return rows, nil
+}
+
+func createGetClientAgreementsHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		job, err := loadClientAgreementJob(app, e)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+
+		rows, err := listClientAgreementRows(app, job.Id, "")
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to list client agreements", err)
+		}
+		return e.JSON(http.StatusOK, rows)
+	}
+}
+
+// createUploadClientAgreementHandler stores one PDF agreement on the job. The
+// job, uploader and upload timestamp are server-owned, and the SHA-256 of the
+// file is stored so the same document cannot be uploaded twice.
+func createUploadClientAgreementHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		job, err := loadClientAgreementJob(app, e)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+
+		files, err := e.FindUploadedFiles("client_agreement")
+		if err != nil && !errors.Is(err, http.ErrMissingFile) {
+			return e.BadRequestError("failed to read uploaded client agreement", err)
+		}
+		if len(files) != 1 {
+			return writeHookError(e, clientAgreementFieldError(http.StatusBadRequest, "required", "upload exactly one client agreement PDF"))
+		}
+		if !uploadedFileLooksLikePDF(files[0]) {
+			return writeHookError(e, clientAgreementFieldError(http.StatusBadRequest, "invalid_mime_type", "client agreement must be a PDF"))
+		}
+		agreementHash, err := hashUploadedFileSHA256(files[0])
+		if err != nil {
+			return e.InternalServerError("failed to hash client agreement", err)
+		}
+
+		var agreementID string
+		err = app.RunInTransaction(func(txApp core.App) error {
+			existing, _ := txApp.FindFirstRecordByFilter("client_agreements", "client_agreement_hash = {:hash}", dbx.Params{
+				"hash": agreementHash,
+			})
+			if existing != nil {
+				return clientAgreementFieldError(http.StatusBadRequest, "duplicate_file", duplicateClientAgreementMessage)
+			}
+
+			collection, err := txApp.FindCollectionByNameOrId("client_agreements")
+			if err != nil {
+				return err
+			}
+			record := core.NewRecord(collection)
+			record.Set("job", job.Id)
+			record.Set("uploader", e.Auth.Id)
+			record.Set("uploaded", time.Now().UTC())
+			record.Set("client_agreement", files[0])
+			record.Set("client_agreement_hash", agreementHash)
+			if err := txApp.Save(record); err != nil {
+				if isUniqueConstraintError(err) {
+					return clientAgreementFieldError(http.StatusBadRequest, "duplicate_file", duplicateClientAgreementMessage)
+				}
+				return err
+			}
+			agreementID = record.Id
+			return nil
+		})
+		if err != nil {
+			return writeHookError(e, err)
+		}
+
+		rows, err := listClientAgreementRows(app, job.Id, agreementID)
+		if err != nil || len(rows) != 1 {
+			return e.Error(http.StatusInternalServerError, "failed to load uploaded client agreement", err)
+		}
+		return e.JSON(http.StatusCreated, rows[0])
+	}
+}
+
+// createGetClientAgreementFileHandler streams the agreement file after the
+// job-scoped access check. The file field is protected, so this is the only
+// path for non-superusers to read agreement contents.
+func createGetClientAgreementFileHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		job, err := loadClientAgreementJob(app, e)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+		agreement, err := loadClientAgreementForJob(app, job.Id, e.Request.PathValue("agreement"))
+		if err != nil {
+			return writeHookError(e, err)
+		}
+		filename := agreement.GetString("client_agreement")
+		if filename == "" {
+			return e.Error(http.StatusNotFound, "client agreement file not found", nil)
+		}
+
+		fsys, err := app.NewFilesystem()
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to open filesystem", err)
+		}
+		defer fsys.Close()
+
+		reader, err := fsys.GetReader(agreement.BaseFilesPath() + "/" + filename)
+		if err != nil {
+			return e.Error(http.StatusNotFound, "client agreement file not found", err)
+		}
+		defer reader.Close()
+
+		e.Response.Header().Set("Content-Disposition", fmt.Sprintf(`inline; filename="%s"`, filename))
+		return e.Stream(http.StatusOK, "application/pdf", reader)
+	}
+}
+
+func createDeleteClientAgreementHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		job, err := loadClientAgreementJob(app, e)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+
+		err = app.RunInTransaction(func(txApp core.App) error {
+			agreement, err := loadClientAgreementForJob(txApp, job.Id, e.Request.PathValue("agreement"))
+			if err != nil {
+				return err
+			}
+			return txApp.Delete(agreement)
+		})
+		if err != nil {
+			return writeHookError(e, err)
+		}
+		return e.NoContent(http.StatusNoContent)
+	}
+}
+
+func clientAgreementFieldError(status int, code string, message string) *errs.HookError {
+	return &errs.HookError{
+		Status:  status,
+		Message: message,
+		Data: map[string]errs.CodeError{
+			"client_agreement": {Code: code, Message: message},
+		},
+	}
+}
@@ -0,0 +1,193 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+)
+
+// Fixtures: job paaltmgrjob0001 is managed by fakemanager@fakesite.xyz with
+// u_no_claims as alternate manager and has one seeded agreement. The
+// author@soup.com user holds the job and admin claims but not the
+// client_agreements claim, so it can see the job but not its agreements.
+const (
+	caJobID            = paAltManagerProjectID
+	caOtherJobID       = paTestProjectID
+	caFixtureID        = "clagreefix00001"
+	caFixtureContent   = "%PDF-1.4\n% client agreement fixture\n"
+	caManagerEmail     = paManagerEmail
+	caAltManagerEmail  = paNoClaimsEmail
+	caClaimHolderEmail = "agreements@example.com"
+	caJobViewerEmail   = paJobClaimEmail
+	caAdminEmail       = paAdminEmail
+	caUploadPDF        = "%PDF-1.4\n% uploaded client agreement\n"
+	clientAgreementsQ  = "/api/jobs/" + caJobID + "/client_agreements"
+)
+
+func TestClientAgreementUploadPermissions(t *testing.T) {
+	scenarios := []struct {
+		name   string
+		email  string
+		uid    string
+		status int
+	}{
+		{name: "job manager", email: caManagerEmail, uid: "wegviunlyr2jjjv", status: http.StatusCreated},
+		{name: "alternate manager", email: caAltManagerEmail, uid: "u_no_claims", status: http.StatusCreated},
+		{name: "client_agreements claim holder", email: caClaimHolderEmail, uid: "u_agreements", status: http.StatusCreated},
+		{name: "job claim holder without agreement access", email: caJobViewerEmail, status: http.StatusForbidden},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			app := newProjectAuthorizationTestApp(t)
+			token := authTokenForEmail(t, app, scenario.email)
+			rec := performProjectAuthorizationMultipartRequestWithCertification(t, app, http.MethodPost, clientAgreementsQ, token, "client_agreement", "agreement.pdf", "application/pdf", []byte(caUploadPDF), false)
+			if rec.Code != scenario.status {
+				t.Fatalf("status = %d, want %d; body=%s", rec.Code, scenario.status, rec.Body.String())
+			}
+			if scenario.status != http.StatusCreated {
+				return
+			}
+
+			var row ClientAgreementRow
+			if err := json.Unmarshal(rec.Body.Bytes(), &row); err != nil {
+				t.Fatalf("failed to decode upload response: %v", err)
+			}
+			record, err := app.FindRecordById("client_agreements", row.ID)
+			if err != nil {
+				t.Fatalf("failed to load uploaded agreement: %v", err)
+			}
+			if record.GetString("job") != caJobID || record.GetString("uploader") != scenario.uid || record.GetString("uploaded") == "" {
+				t.Fatalf("server-owned fields job=%q uploader=%q uploaded=%q", record.GetString("job"), record.GetString("uploader"), record.GetString("uploaded"))
+			}
+			if got := record.GetString("client_agreement_hash"); got != sha256HexForPATest(caUploadPDF) {
+				t.Fatalf("client_agreement_hash = %s, want calculated hash", got)
+			}
+			if row.FileURL != clientAgreementsQ+"/"+row.ID+"/file" {
+				t.Fatalf("file_url = %q, want authenticated download route", row.FileURL)
+			}
+		})
+	}
+}
+
+func TestClientAgreementUploadValidation(t *testing.T) {
+	app := newProjectAuthorizationTestApp(t)
+	token := authTokenForEmail(t, app, caManagerEmail)
+
+	nonPDF := performProjectAuthorizationMultipartRequestWithCertification(t, app, http.MethodPost, clientAgreementsQ, token, "client_agreement", "agreement.txt", "text/plain", []byte(paNonPDFContent), false)
+	if nonPDF.Code != http.StatusBadRequest || !strings.Contains(nonPDF.Body.String(), "invalid_mime_type") {
+		t.Fatalf("non-pdf response = %d, body=%s", nonPDF.Code, nonPDF.Body.String())
+	}
+
+	missing := performProjectAuthorizationMultipartRequestWithCertification(t, app, http.MethodPost, clientAgreementsQ, token, "other_field", "agreement.pdf", "application/pdf", []byte(caUploadPDF), false)
+	if missing.Code != http.StatusBadRequest || !strings.Contains(missing.Body.String(), `"code":"required"`) {
+		t.Fatalf("missing file response = %d, body=%s", missing.Code, missing.Body.String())
+	}
+
+	duplicate := performProjectAuthorizationMultipartRequestWithCertification(t, app, http.MethodPost, "/api/jobs/"+caOtherJobID+"/client_agreements", token, "client_agreement", "agreement.pdf", "application/pdf", []byte(caFixtureContent), false)
+	if duplicate.Code != http.StatusBadRequest || !strings.Contains(duplicate.Body.String(), "duplicate_file") {
+		t.Fatalf("duplicate response = %d, body=%s", duplicate.Code, duplicate.Body.String())
+	}
+}
+
+func TestClientAgreementListAndDownloadVisibility(t *testing.T) {
+	app := newProjectAuthorizationTestApp(t)
+	managerToken := authTokenForEmail(t, app, caManagerEmail)
+	claimToken := authTokenForEmail(t, app, caClaimHolderEmail)
+	viewerToken := authTokenForEmail(t, app, caJobViewerEmail)
+
+	list := performClaimsJSONRequest(t, app, http.MethodGet, clientAgreementsQ, managerToken, nil)
+	rows := decodeJSONResponseForTest[[]ClientAgreementRow](t, list, http.StatusOK, "manager list")
+	if len(rows) != 1 || rows[0].ID != caFixtureID || rows[0].Uploader != "wegviunlyr2jjjv" {
+		t.Fatalf("manager list = %+v, want fixture agreement", rows)
+	}
+
+	download := performClaimsJSONRequest(t, app, http.MethodGet, clientAgreementsQ+"/"+caFixtureID+"/file", claimToken, nil)
+	if download.Code != http.StatusOK || download.Body.String() != caFixtureContent {
+		t.Fatalf("claim holder download = %d, body=%q", download.Code, download.Body.String())
+	}
+
+	for _, path := range []string{clientAgreementsQ, clientAgreementsQ + "/" + caFixtureID + "/file"} {
+		rec := performClaimsJSONRequest(t, app, http.MethodGet, path, viewerToken, nil)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("job viewer GET %s = %d, want forbidden; body=%s", path, rec.Code, rec.Body.String())
+		}
+	}
+
+	// The manager also manages caOtherJobID, but the agreement belongs to caJobID.
+	crossJob := performClaimsJSONRequest(t, app, http.MethodGet, "/api/jobs/"+caOtherJobID+"/client_agreements/"+caFixtureID+"/file", managerToken, nil)
+	if crossJob.Code != http.StatusNotFound {
+		t.Fatalf("cross-job download = %d, want not found; body=%s", crossJob.Code, crossJob.Body.String())
+	}
+}
+
+func TestClientAgreementGenericCollectionAccessDenied(t *testing.T) {
+	app := newProjectAuthorizationTestApp(t)
+	token := authTokenForEmail(t, app, caManagerEmail)
+
+	list := performClaimsJSONRequest(t, app, http.MethodGet, "/api/collections/client_agreements/records", token, nil)
+	if list.Code == http.StatusOK {
+		t.Fatalf("generic list should be denied; body=%s", list.Body.String())
+	}
+
+	view := performClaimsJSONRequest(t, app, http.MethodGet, "/api/collections/client_agreements/records/"+caFixtureID, token, nil)
+	if view.Code == http.StatusOK {
+		t.Fatalf("generic view should be denied; body=%s", view.Body.String())
+	}
+
+	create := performProjectAuthorizationMultipartRequestWithCertification(t, app, http.MethodPost, "/api/collections/client_agreements/records", token, "client_agreement", "agreement.pdf", "application/pdf", []byte(caUploadPDF), false)
+	if create.Code == http.StatusOK {
+		t.Fatalf("generic create should be denied; body=%s", create.Body.String())
+	}
+}
+
+func TestClientAgreementDelete(t *testing.T) {
+	app := newProjectAuthorizationTestApp(t)
+	viewerToken := authTokenForEmail(t, app, caJobViewerEmail)
+	altManagerToken := authTokenForEmail(t, app, caAltManagerEmail)
+
+	forbidden := performClaimsJSONRequest(t, app, http.MethodDelete, clientAgreementsQ+"/"+caFixtureID, viewerToken, nil)
+	if forbidden.Code != http.StatusForbidden {
+		t.Fatalf("job viewer delete = %d, want forbidden; body=%s", forbidden.Code, forbidden.Body.String())
+	}
+
+	deleted := performClaimsJSONRequest(t, app, http.MethodDelete, clientAgreementsQ+"/"+caFixtureID, altManagerToken, nil)
+	if deleted.Code != http.StatusNoContent {
+		t.Fatalf("alternate manager delete = %d; body=%s", deleted.Code, deleted.Body.String())
+	}
+	if _, err := app.FindRecordById("client_agreements", caFixtureID); err == nil {
+		t.Fatal("expected agreement to be deleted")
+	}
+
+	again := performClaimsJSONRequest(t, app, http.MethodDelete, clientAgreementsQ+"/"+caFixtureID, altManagerToken, nil)
+	if again.Code != http.StatusNotFound {
+		t.Fatalf("repeat delete = %d, want not found; body=%s", again.Code, again.Body.String())
+	}
+}
+
+func TestClientAgreementHashAuditAndReplace(t *testing.T) {
+	app := newProjectAuthorizationTestApp(t)
+	adminToken := authTokenForEmail(t, app, caAdminEmail)
+	managerToken := authTokenForEmail(t, app, caManagerEmail)
+
+	nonAdmin := performClaimsJSONRequest(t, app, http.MethodPost, "/api/jobs/client_agreements/"+caFixtureID+"/hash/audit", managerToken, nil)
+	if nonAdmin.Code != http.StatusForbidden {
+		t.Fatalf("non-admin audit = %d, want forbidden; body=%s", nonAdmin.Code, nonAdmin.Body.String())
+	}
+
+	auditRec := performClaimsJSONRequest(t, app, http.MethodPost, "/api/jobs/client_agreements/"+caFixtureID+"/hash/audit", adminToken, nil)
+	audit := decodeJSONResponseForTest[clientAgreementHashAuditResponse](t, auditRec, http.StatusOK, "audit")
+	want := sha256HexForPATest(caFixtureContent)
+	if !audit.Matches || audit.CalculatedHash != want || audit.JobID != caJobID || audit.TargetCollection != "client_agreements" {
+		t.Fatalf("audit = %+v, want matching fixture hash", audit)
+	}
+
+	replaceRec := performClaimsJSONRequest(t, app, http.MethodPost, "/api/jobs/client_agreements/"+caFixtureID+"/hash/replace", adminToken, map[string]any{
+		"updated": audit.Updated,
+	})
+	replace := decodeJSONResponseForTest[clientAgreementHashReplaceResponse](t, replaceRec, http.StatusOK, "replace")
+	if !replace.Noop || replace.Replaced {
+		t.Fatalf("replace = %+v, want noop", replace)
+	}
+}
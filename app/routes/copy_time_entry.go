@@ -52,6 +52,10 @@ func copyTimeEntryWithDayOffset(txApp core.App, authRecord *core.Record, origina
 		switch fieldName {
 		case "id", "created", "updated", "date", "week_ending", "tsid":
 			continue
+		case "work_record_subject_id":
+			// A worker row may be linked to at most one time entry, and the
+			// copy lands on a different date than the worker row's parent.
+			continue
 		default:
 			newRecord.Set(fieldName, original.Get(fieldName))
 		}
@@ -14,11 +14,18 @@ SELECT
   COALESCE(e.attachment_missing_reason, '') AS attachment_missing_reason,
   CASE WHEN ed.id IS NOT NULL THEN 'pbc_2089657321' ELSE '' END AS attachment_collection_id,
   CASE WHEN ed.id IS NOT NULL THEN ed.id ELSE '' END AS attachment_record_id,
+  COALESCE(ed.extraction_status, '') AS extraction_status,
+  COALESCE(CAST(ed.extracted_total AS REAL), 0) AS extracted_total,
+  COALESCE(CAST(ed.extracted_tax AS REAL), 0) AS extracted_tax,
+  COALESCE(ed.extracted_invoice_date, '') AS extracted_invoice_date,
+  COALESCE(ed.extracted_vendor_name, '') AS extracted_vendor_name,
+  COALESCE(ed.extracted_invoice_number, '') AS extracted_invoice_number,
   e.rejector,
   e.rejected,
   e.rejection_reason,
   e.approver,
   e.approved,
+  COALESCE(e.delegated_approver, '') AS delegated_approver,
   e.job,
   e.category,
   e.kind,
@@ -34,6 +41,9 @@ SELECT
   CAST(COALESCE(e.settled_total, 0) AS REAL) AS settled_total,
   COALESCE(e.settler, '') AS settler,
   COALESCE(e.settled, '') AS settled,
+  COALESCE(e.tax_code, '') AS tax_code,
+  CAST(COALESCE(e.tax_amount, 0) AS REAL) AS tax_amount,
+  CAST(COALESCE(e.pre_tax_amount, 0) AS REAL) AS pre_tax_amount,
   e.purchase_order,
   e.vendor,
   COALESCE(po.po_number, '') AS purchase_order_number,
@@ -53,6 +63,7 @@ SELECT
   COALESCE(v.alias, '') AS vendor_alias,
   COALESCE(p0.given_name || ' ' || p0.surname, '') AS uid_name,
   COALESCE(p1.given_name || ' ' || p1.surname, '') AS approver_name,
+  COALESCE(p6.given_name || ' ' || p6.surname, '') AS delegated_approver_name,
   COALESCE(p2.given_name || ' ' || p2.surname, '') AS rejector_name,
   COALESCE(p4.given_name || ' ' || p4.surname, '') AS settler_name,
   COALESCE(p5.given_name || ' ' || p5.surname, '') AS creator_name,
@@ -92,6 +103,7 @@ LEFT JOIN categories ca ON e.category = ca.id
 LEFT JOIN expenditure_kinds ek ON e.kind = ek.id
 LEFT JOIN profiles p0 ON e.uid = p0.uid
 LEFT JOIN profiles p1 ON e.approver = p1.uid
+LEFT JOIN profiles p6 ON e.delegated_approver = p6.uid
 LEFT JOIN profiles p2 ON e.rejector = p2.uid
 LEFT JOIN purchase_orders po ON e.purchase_order = po.id
 LEFT JOIN profiles p3 ON po.uid = p3.uid
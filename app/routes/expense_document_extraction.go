@@ -0,0 +1,62 @@
+package routes
+
+import (
+	"math"
+	"strings"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// expenseExtractedTotalMismatch reports whether the total entered on an
+// expense differs from the total read from its receipt. Receipts where no
+// total was found never mismatch.
+func expenseExtractedTotalMismatch(total float64, extractedTotal float64) bool {
+	return extractedTotal > 0 && math.Abs(total-extractedTotal) >= 0.005
+}
+
+// findExtractedVendor returns the active vendor that the vendor name read
+// from a receipt refers to, or "" when it cannot tell. A vendor whose name or
+// alias equals the extracted name wins; otherwise the vendor sharing the most
+// words of at least three letters with it does, provided no other vendor
+// shares as many. Receipts print legal names with suffixes such as "Ltd." that
+// the vendor list usually omits.
+func findExtractedVendor(app core.App, vendorName string) (string, error) {
+	vendorName = strings.TrimSpace(vendorName)
+	if vendorName == "" {
+		return "", nil
+	}
+	vendors, err := utilities.LoadActiveVendors(app)
+	if err != nil {
+		return "", err
+	}
+
+	extractedWords := map[string]bool{}
+	for _, word := range utilities.NameWords(vendorName) {
+		if len(word) >= 3 {
+			extractedWords[word] = true
+		}
+	}
+	found, best, tied := "", 0, false
+	for _, vendor := range vendors {
+		if strings.EqualFold(vendor.Name, vendorName) || (vendor.Alias != "" && strings.EqualFold(vendor.Alias, vendorName)) {
+			return vendor.ID, nil
+		}
+		shared := map[string]bool{}
+		for _, word := range append(utilities.NameWords(vendor.Name), utilities.NameWords(vendor.Alias)...) {
+			if extractedWords[word] {
+				shared[word] = true
+			}
+		}
+		switch {
+		case len(shared) > best:
+			found, best, tied = vendor.ID, len(shared), false
+		case len(shared) == best && best > 0:
+			tied = true
+		}
+	}
+	if tied {
+		return "", nil
+	}
+	return found, nil
+}
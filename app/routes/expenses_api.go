@@ -33,17 +33,22 @@ var expenseDetailsQuery string
 // The shared expense visibility predicate below currently allows:
 // - expense owner: always
 // - expense approver: while the expense is submitted
+// - expense approver's delegate: while the expense is submitted
 // - commit claim holder: once the expense is approved
 // - report claim holder: once the expense is committed
 //
 // It intentionally does NOT automatically grant visibility based solely on PO
 // participation. That means a caller may be able to open a PO details page and
 // still see a filtered subset of linked expenses.
-const (
+var (
 	whereListMine     = "(e.uid = {:auth} OR e.creator = {:auth})"
 	whereListMineByPO = "(e.uid = {:auth} OR e.creator = {:auth}) AND e.purchase_order = {:purchase_order}"
-	wherePending      = "e.approver = {:auth} AND e.submitted = 1 AND (e.approved = '' OR e.approved IS NULL)"
-	whereApproved     = "e.approver = {:auth} AND (e.approved != '' AND e.approved IS NOT NULL)"
+	wherePending      = "(e.approver = {:auth} OR (e.uid != {:auth} AND " + expenseDelegateCondition + ")) AND e.submitted = 1 AND (e.approved = '' OR e.approved IS NULL)"
+	whereApproved     = "(e.approver = {:auth} OR e.delegated_approver = {:auth}) AND (e.approved != '' AND e.approved IS NOT NULL)"
+	// expenseDelegateCondition is true while the caller holds an active
+	// expenses approval delegation from the expense approver. Pending lists
+	// also exclude the caller's own expenses, which delegates cannot approve.
+	expenseDelegateCondition = utilities.ApprovalDelegationCondition("e.approver", "{:auth}", utilities.ApprovalDelegationScopeExpenses)
 	// expenseVisibilityPredicate is the canonical EXPENSE-level visibility rule
 	// used by expense details and by the PO-related-expenses endpoint.
 	//
@@ -65,6 +70,7 @@ const (
   e.uid = {:auth}
   OR e.creator = {:auth}
   OR (e.approver = {:auth} AND e.submitted = 1)
+  OR (e.submitted = 1 AND (e.delegated_approver = {:auth} OR ` + expenseDelegateCondition + `))
   OR (({:has_commit} = 1) AND e.approved != '')
   OR (({:has_report} = 1) AND e.committed != '')
 `
@@ -133,6 +139,7 @@ type ExpensesAugmentedRow struct {
 	RejectionReason         string  `db:"rejection_reason" json:"rejection_reason"`
 	Approver                string  `db:"approver" json:"approver"`
 	Approved                string  `db:"approved" json:"approved"`
+	DelegatedApprover       string  `db:"delegated_approver" json:"delegated_approver"`
 	Job                     string  `db:"job" json:"job"`
 	Category                string  `db:"category" json:"category"`
 	Kind                    string  `db:"kind" json:"kind"`
@@ -169,6 +176,7 @@ type ExpensesAugmentedRow struct {
 	UIDName                 string  `db:"uid_name" json:"uid_name"`
 	CreatorName             string  `db:"creator_name" json:"creator_name"`
 	ApproverName            string  `db:"approver_name" json:"approver_name"`
+	DelegatedApproverName   string  `db:"delegated_approver_name" json:"delegated_approver_name"`
 	RejectorName            string  `db:"rejector_name" json:"rejector_name"`
 	BranchName              string  `db:"branch_name" json:"branch_name"`
 }
@@ -204,6 +212,23 @@ type ExpenseDetailsRow struct {
 	POUID              string  `db:"po_uid" json:"po_uid"`
 	POUIDName          string  `db:"po_uid_name" json:"po_uid_name"`
 	POOwnerUIDMismatch bool    `json:"po_owner_uid_mismatch"`
+	// The sales tax split of the total. TaxComponents breaks TaxAmount down
+	// into HST, GST, PST and QST at the tax code's rates.
+	TaxCode       string             `db:"tax_code" json:"tax_code"`
+	TaxAmount     float64            `db:"tax_amount" json:"tax_amount"`
+	PreTaxAmount  float64            `db:"pre_tax_amount" json:"pre_tax_amount"`
+	TaxComponents map[string]float64 `json:"tax_components"`
+	// Fields read from the attached receipt when it was uploaded. They are
+	// suggestions for the expense form; ExtractedVendor is the active vendor
+	// the extracted name matches, if any.
+	ExtractionStatus       string  `db:"extraction_status" json:"extraction_status"`
+	ExtractedTotal         float64 `db:"extracted_total" json:"extracted_total"`
+	ExtractedTax           float64 `db:"extracted_tax" json:"extracted_tax"`
+	ExtractedInvoiceDate   string  `db:"extracted_invoice_date" json:"extracted_invoice_date"`
+	ExtractedVendorName    string  `db:"extracted_vendor_name" json:"extracted_vendor_name"`
+	ExtractedInvoiceNumber string  `db:"extracted_invoice_number" json:"extracted_invoice_number"`
+	ExtractedVendor        string  `json:"extracted_vendor"`
+	ExtractedTotalMismatch bool    `json:"extracted_total_mismatch"`
 }
 
 type PaginatedExpensesResponse struct {
@@ -469,6 +494,11 @@ func createGetExpenseDetailsHandler(app core.App) func(e *core.RequestEvent) err
 			return e.Error(http.StatusNotFound, "expense not found or not authorized", err)
 		}
 		row.POOwnerUIDMismatch = expensePurchaseOrderOwnerUIDMismatch(row.UID, row.POUID)
+		row.ExtractedTotalMismatch = expenseExtractedTotalMismatch(row.Total, row.ExtractedTotal)
+		row.TaxComponents = utilities.LoadSalesTaxConfig(app).Components(row.TaxCode, row.TaxAmount)
+		if row.ExtractedVendor, err = findExtractedVendor(app, row.ExtractedVendorName); err != nil {
+			return e.Error(http.StatusInternalServerError, "error matching the receipt vendor", err)
+		}
 
 		return e.JSON(http.StatusOK, row)
 	}
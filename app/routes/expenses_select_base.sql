@@ -19,6 +19,7 @@ SELECT
   e.rejection_reason,
   e.approver,
   e.approved,
+  COALESCE(e.delegated_approver, '') AS delegated_approver,
   e.job,
   e.category,
   e.kind,
@@ -53,6 +54,7 @@ SELECT
   COALESCE(v.alias, '') AS vendor_alias,
   COALESCE(p0.given_name || ' ' || p0.surname, '') AS uid_name,
   COALESCE(p1.given_name || ' ' || p1.surname, '') AS approver_name,
+  COALESCE(p5.given_name || ' ' || p5.surname, '') AS delegated_approver_name,
   COALESCE(p2.given_name || ' ' || p2.surname, '') AS rejector_name,
   COALESCE(p3.given_name || ' ' || p3.surname, '') AS settler_name,
   COALESCE(p4.given_name || ' ' || p4.surname, '') AS creator_name,
@@ -66,6 +68,7 @@ LEFT JOIN categories ca ON e.category = ca.id
 LEFT JOIN expenditure_kinds ek ON e.kind = ek.id
 LEFT JOIN profiles p0 ON e.uid = p0.uid
 LEFT JOIN profiles p1 ON e.approver = p1.uid
+LEFT JOIN profiles p5 ON e.delegated_approver = p5.uid
 LEFT JOIN profiles p2 ON e.rejector = p2.uid
 LEFT JOIN purchase_orders po ON e.purchase_order = po.id
 LEFT JOIN currencies cur ON e.currency = cur.id
@@ -31,6 +31,9 @@ var expenseWriteAllowedFields = map[string]struct{}{
 	"purchase_order":   {},
 	"vendor":           {},
 	"source_expense":   {},
+	"tax_code":         {},
+	"tax_amount":       {},
+	"pre_tax_amount":   {},
 }
 
 func createCreateExpenseHandler(app core.App) func(e *core.RequestEvent) error {
@@ -90,6 +90,9 @@ type expenseExportDBRow struct {
 	Distance            float64 `db:"distance"`
 	PaymentType         string  `db:"payment_type"`
 	Currency            string  `db:"currency"`
+	TaxCode             string  `db:"tax_code"`
+	TaxAmount           float64 `db:"tax_amount"`
+	PreTaxAmount        float64 `db:"pre_tax_amount"`
 	AllowanceTypesJSON  string  `db:"allowance_types_json"`
 	CcLast4Digits       string  `db:"cc_last_4_digits"`
 	Attachment          string  `db:"attachment"`
@@ -214,6 +217,14 @@ type expenseExportOutput struct {
 	CcLast4Digits  string   `json:"ccLast4digits,omitempty"`
 	Attachment     string   `json:"attachment,omitempty"`
 	AttachmentHash string   `json:"attachmentHash,omitempty"`
+	// Sales tax split (only present when the expense has a tax code)
+	TaxCode      string   `json:"taxCode,omitempty"`
+	PreTaxAmount *float64 `json:"preTaxAmount,omitempty"`
+	TaxAmount    *float64 `json:"taxAmount,omitempty"`
+	Hst          *float64 `json:"hst,omitempty"`
+	Gst          *float64 `json:"gst,omitempty"`
+	Pst          *float64 `json:"pst,omitempty"`
+	Qst          *float64 `json:"qst,omitempty"`
 	// Allowance flags (derived from allowance_types array, only present if at least one is true)
 	Breakfast *bool `json:"breakfast,omitempty"`
 	Lunch     *bool `json:"lunch,omitempty"`
@@ -307,6 +318,9 @@ func createExpensesExportLegacyHandler(app core.App) func(e *core.RequestEvent)
 			    WHEN COALESCE(e.currency, '') = '' THEN ''
 			    ELSE COALESCE(cur.code, '')
 			  END AS currency,
+			  COALESCE(e.tax_code, '') AS tax_code,
+			  COALESCE(e.tax_amount, 0) AS tax_amount,
+			  COALESCE(e.pre_tax_amount, 0) AS pre_tax_amount,
 			  COALESCE(e.allowance_types, '[]') AS allowance_types_json,
 			  COALESCE(e.cc_last_4_digits, '') AS cc_last_4_digits,
 			  COALESCE(ed.attachment, '') AS attachment,
@@ -522,6 +536,7 @@ func createExpensesExportLegacyHandler(app core.App) func(e *core.RequestEvent)
 		}
 
 		// Convert expense DB rows to output format
+		salesTaxConfig := utilities.LoadSalesTaxConfig(app)
 		expenses := make([]expenseExportOutput, len(expenseRows))
 		for i, r := range expenseRows {
 			expenses[i] = expenseExportOutput{
@@ -600,6 +615,24 @@ func createExpensesExportLegacyHandler(app core.App) func(e *core.RequestEvent)
 			if strings.TrimSpace(r.Currency) != "" {
 				expenses[i].SettledTotal = &r.SettledTotal
 			}
+
+			// Expenses saved before tax codes existed have no split to export.
+			if r.TaxCode != "" {
+				expenses[i].TaxCode = r.TaxCode
+				expenses[i].PreTaxAmount = &r.PreTaxAmount
+				expenses[i].TaxAmount = &r.TaxAmount
+				components := salesTaxConfig.Components(r.TaxCode, r.TaxAmount)
+				for component, target := range map[string]**float64{
+					"HST": &expenses[i].Hst,
+					"GST": &expenses[i].Gst,
+					"PST": &expenses[i].Pst,
+					"QST": &expenses[i].Qst,
+				} {
+					if amount, ok := components[component]; ok {
+						*target = &amount
+					}
+				}
+			}
 		}
 
 		// Convert vendor DB rows to output format
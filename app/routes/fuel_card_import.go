@@ -0,0 +1,376 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"net/http"
+	"strings"
+
+	"tybalt/errs"
+	"tybalt/hooks"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxFuelCardImportRows caps the number of transactions one import may hold.
+const maxFuelCardImportRows = 2000
+
+// errFuelCardImportRollback rolls back a dry run, or a confirmed import with
+// at least one invalid row, after every row was matched or saved.
+var errFuelCardImportRollback = errors.New("fuel card import rolled back")
+
+// fuelCardImportRow is the outcome for one transaction. Status is created
+// when a draft expense was created, matched when the driver had already
+// entered the expense, already_imported when an earlier import brought in the
+// transaction, or invalid. ID is the expense and is only set once the import
+// is confirmed, except for already_imported rows.
+type fuelCardImportRow struct {
+	Row        int                                  `json:"row"`
+	Status     string                               `json:"status"`
+	ID         string                               `json:"id,omitempty"`
+	UID        string                               `json:"uid"`
+	CardNumber string                               `json:"card_number"`
+	Date       string                               `json:"date"`
+	Amount     float64                              `json:"amount"`
+	Vendor     string                               `json:"vendor"`
+	Errors     []utilities.FuelCardTransactionError `json:"errors"`
+}
+
+type fuelCardImportResponse struct {
+	DryRun               bool                `json:"dry_run"`
+	RowCount             int                 `json:"row_count"`
+	InvalidCount         int                 `json:"invalid_count"`
+	CreatedCount         int                 `json:"created_count"`
+	MatchedCount         int                 `json:"matched_count"`
+	AlreadyImportedCount int                 `json:"already_imported_count"`
+	Rows                 []fuelCardImportRow `json:"rows"`
+}
+
+type fuelCardDriver struct {
+	UID             string `db:"uid"`
+	FuelCardNumber  string `db:"fuel_card_number"`
+	DefaultDivision string `db:"default_division"`
+	Active          bool   `db:"active"`
+}
+
+func fuelCardImportFileError(code string, message string) error {
+	return &errs.HookError{
+		Status:  http.StatusBadRequest,
+		Message: message,
+		Data: map[string]errs.CodeError{
+			"file": {Code: code, Message: message},
+		},
+	}
+}
+
+// createImportFuelCardTransactionsHandler imports a fuel card provider's
+// transaction export uploaded as the "file" form field. Each transaction is
+// assigned to the driver whose admin_profiles.fuel_card_number is the card.
+// A transaction the driver already entered as a FuelCard expense with the same
+// date and total is linked to that expense; otherwise a draft FuelCard
+// expense is created for the driver to complete and submit. Unless the
+// "confirm" form field is "true" the import is a dry run. A confirmed import
+// saves every row in one transaction, or none of them when any row is
+// invalid.
+func createImportFuelCardTransactionsHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireExpensesEditing(app, "expenses"); err != nil {
+			return err
+		}
+		if err := requirePayablesAdmin(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		files, err := e.FindUploadedFiles("file")
+		if err != nil && !errors.Is(err, http.ErrMissingFile) {
+			return e.BadRequestError("failed to read uploaded file", err)
+		}
+		if len(files) != 1 {
+			return writeHookError(e, fuelCardImportFileError("required", "upload exactly one CSV file"))
+		}
+		reader, err := files[0].Reader.Open()
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to open uploaded file", err)
+		}
+		defer reader.Close()
+
+		transactions, err := utilities.ParseFuelCardTransactionsCSV(reader)
+		if err != nil {
+			return writeHookError(e, fuelCardImportFileError("invalid_file", err.Error()))
+		}
+		if len(transactions) == 0 {
+			return writeHookError(e, fuelCardImportFileError("no_rows", "the file has no transactions"))
+		}
+		if len(transactions) > maxFuelCardImportRows {
+			return writeHookError(e, fuelCardImportFileError("too_many_rows", fmt.Sprintf("an import can include at most %d transactions", maxFuelCardImportRows)))
+		}
+
+		response := fuelCardImportResponse{
+			DryRun:   e.Request.FormValue("confirm") != "true",
+			RowCount: len(transactions),
+		}
+		attachmentMissingReason := fmt.Sprintf("Imported from fuel card transaction export %s", files[0].OriginalName)
+		err = app.RunInTransaction(func(txApp core.App) error {
+			rows, err := importFuelCardTransactions(txApp, transactions, attachmentMissingReason)
+			if err != nil {
+				return err
+			}
+			response.Rows = rows
+			for _, row := range rows {
+				switch row.Status {
+				case "invalid":
+					response.InvalidCount++
+				case "created":
+					response.CreatedCount++
+				case "matched":
+					response.MatchedCount++
+				case "already_imported":
+					response.AlreadyImportedCount++
+				}
+			}
+			if response.DryRun || response.InvalidCount > 0 {
+				return errFuelCardImportRollback
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errFuelCardImportRollback) {
+			return e.Error(http.StatusInternalServerError, "failed to import fuel card transactions", err)
+		}
+
+		if response.DryRun || response.InvalidCount > 0 {
+			for i := range response.Rows {
+				if response.Rows[i].Status != "already_imported" {
+					response.Rows[i].ID = ""
+				}
+			}
+		}
+		if !response.DryRun && response.InvalidCount > 0 {
+			return e.JSON(http.StatusUnprocessableEntity, response)
+		}
+		return e.JSON(http.StatusOK, response)
+	}
+}
+
+// importFuelCardTransactions matches each transaction to its driver and to an
+// expense, creating a draft expense when there is none. It must run inside a
+// transaction, which the caller rolls back unless every row is valid and the
+// import is confirmed. A row's problems are reported on the row; only
+// unexpected database errors are returned.
+func importFuelCardTransactions(txApp core.App, transactions []utilities.FuelCardTransaction, attachmentMissingReason string) ([]fuelCardImportRow, error) {
+	drivers := []fuelCardDriver{}
+	if err := txApp.DB().NewQuery(`
+		SELECT ap.uid, ap.fuel_card_number, COALESCE(p.default_division, '') AS default_division, ap.active
+		FROM admin_profiles ap
+		LEFT JOIN profiles p ON p.uid = ap.uid
+		WHERE COALESCE(ap.fuel_card_number, '') != ''
+	`).All(&drivers); err != nil {
+		return nil, err
+	}
+	vendors, err := utilities.LoadActiveVendors(txApp)
+	if err != nil {
+		return nil, err
+	}
+	collection, err := txApp.FindCollectionByNameOrId("expenses")
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]fuelCardImportRow, 0, len(transactions))
+	for _, transaction := range transactions {
+		row := fuelCardImportRow{
+			Row:        transaction.Row,
+			Status:     "invalid",
+			CardNumber: transaction.CardNumber,
+			Date:       transaction.Date,
+			Amount:     transaction.Amount,
+			Errors:     transaction.Errors,
+		}
+		addError := func(field string, code string, message string) {
+			row.Errors = append(row.Errors, utilities.FuelCardTransactionError{Field: field, Code: code, Message: message})
+		}
+
+		var driver *fuelCardDriver
+		if len(row.Errors) == 0 {
+			var ambiguous bool
+			driver, ambiguous = findFuelCardDriver(drivers, transaction)
+			switch {
+			case ambiguous:
+				addError("card_number", "ambiguous_card", fmt.Sprintf("more than one driver has a fuel card ending in %s", transaction.CardNumber))
+			case driver == nil:
+				addError("card_number", "unknown_card", fmt.Sprintf("no driver has fuel card %s", fuelCardNumberLabel(transaction)))
+			case !driver.Active:
+				addError("card_number", "inactive_driver", "the driver with this fuel card is not active")
+			}
+		}
+		if len(row.Errors) > 0 {
+			rows = append(rows, row)
+			continue
+		}
+		row.UID = driver.UID
+		row.Vendor = findFuelCardVendor(vendors, transaction.Merchant)
+		key := fuelCardTransactionKey(driver.FuelCardNumber, transaction)
+
+		imported, err := txApp.FindFirstRecordByFilter("expenses", "fuel_card_transaction = {:key}", dbx.Params{"key": key})
+		if err != nil && !errors.Is(err, sql.ErrNoRows) {
+			return nil, err
+		}
+		if imported != nil {
+			row.Status = "already_imported"
+			row.ID = imported.Id
+			rows = append(rows, row)
+			continue
+		}
+
+		expense, err := findFuelCardExpense(txApp, driver.UID, transaction)
+		if err != nil {
+			return nil, err
+		}
+		if expense != nil {
+			expense.Set("fuel_card_transaction", key)
+			if err := txApp.Save(expense); err != nil {
+				row.Errors = fuelCardImportErrors(err)
+			} else {
+				row.Status = "matched"
+				row.ID = expense.Id
+			}
+			rows = append(rows, row)
+			continue
+		}
+
+		record := core.NewRecord(collection)
+		record.Set("uid", driver.UID)
+		record.Set("creator", driver.UID)
+		record.Set("division", driver.DefaultDivision)
+		record.Set("payment_type", "FuelCard")
+		record.Set("date", transaction.Date)
+		record.Set("total", transaction.Amount)
+		record.Set("distance", transaction.Distance)
+		record.Set("vendor", row.Vendor)
+		record.Set("description", fuelCardExpenseDescription(transaction))
+		record.Set("attachment_missing_reason", attachmentMissingReason)
+		record.Set("fuel_card_transaction", key)
+		if driver.DefaultDivision == "" {
+			addError("division", "missing_default_division", "the driver's profile has no default division")
+		} else if err := hooks.PrepareDraftExpense(txApp, record); err != nil {
+			row.Errors = fuelCardImportErrors(err)
+		} else if err := txApp.Save(record); err != nil {
+			row.Errors = fuelCardImportErrors(err)
+		} else {
+			row.Status = "created"
+			row.ID = record.Id
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// findFuelCardDriver returns the driver whose fuel card made transaction, or
+// nil when there is none. When the provider masked the card number, the
+// visible trailing digits must identify exactly one card; ambiguous is set
+// when they match several.
+func findFuelCardDriver(drivers []fuelCardDriver, transaction utilities.FuelCardTransaction) (driver *fuelCardDriver, ambiguous bool) {
+	for i := range drivers {
+		number := drivers[i].FuelCardNumber
+		if number == transaction.CardNumber || (transaction.CardMasked && strings.HasSuffix(number, transaction.CardNumber)) {
+			if driver != nil {
+				return nil, true
+			}
+			driver = &drivers[i]
+		}
+	}
+	return driver, false
+}
+
+func fuelCardNumberLabel(transaction utilities.FuelCardTransaction) string {
+	if transaction.CardMasked {
+		return "ending in " + transaction.CardNumber
+	}
+	return transaction.CardNumber
+}
+
+// findFuelCardVendor returns the active vendor a transaction's merchant
+// names, or "" when it cannot tell. A vendor whose name or alias equals the
+// merchant wins; otherwise exactly one vendor must share a word with it.
+func findFuelCardVendor(vendors []utilities.ActiveVendor, merchant string) string {
+	merchant = strings.TrimSpace(merchant)
+	if merchant == "" {
+		return ""
+	}
+	for _, vendor := range vendors {
+		if strings.EqualFold(vendor.Name, merchant) || (vendor.Alias != "" && strings.EqualFold(vendor.Alias, merchant)) {
+			return vendor.ID
+		}
+	}
+	found := ""
+	for _, vendor := range vendors {
+		if cardStatementVendorMatches(merchant, vendor.Name, vendor.Alias) {
+			if found != "" {
+				return ""
+			}
+			found = vendor.ID
+		}
+	}
+	return found
+}
+
+// fuelCardTransactionKey identifies a transaction across imports. Providers
+// that export a transaction id make it exact; otherwise the date, amount and
+// merchant stand in for it.
+func fuelCardTransactionKey(cardNumber string, transaction utilities.FuelCardTransaction) string {
+	if transaction.Reference != "" {
+		return cardNumber + ":" + transaction.Reference
+	}
+	return fmt.Sprintf("%s:%s:%.2f:%s", cardNumber, transaction.Date, transaction.Amount, strings.ToLower(transaction.Merchant))
+}
+
+// findFuelCardExpense returns the driver's unlinked, unrejected FuelCard
+// expense with the transaction's date and total, or nil when there is none.
+func findFuelCardExpense(txApp core.App, uid string, transaction utilities.FuelCardTransaction) (*core.Record, error) {
+	expenses, err := txApp.FindRecordsByFilter(
+		"expenses",
+		"uid = {:uid} && payment_type = 'FuelCard' && date = {:date} && fuel_card_transaction = '' && rejected = ''",
+		"created",
+		0,
+		0,
+		dbx.Params{"uid": uid, "date": transaction.Date},
+	)
+	if err != nil {
+		return nil, err
+	}
+	for _, expense := range expenses {
+		if math.Abs(expense.GetFloat("total")-transaction.Amount) < 0.005 {
+			return expense, nil
+		}
+	}
+	return nil, nil
+}
+
+// fuelCardExpenseDescription describes a draft expense from what the provider
+// exported, such as "Diesel, 45.20 L at PETRO-CANADA #1234".
+func fuelCardExpenseDescription(transaction utilities.FuelCardTransaction) string {
+	description := "Fuel"
+	if transaction.Product != "" {
+		description = transaction.Product
+	}
+	if transaction.Quantity > 0 {
+		description += fmt.Sprintf(", %.2f L", transaction.Quantity)
+	}
+	if transaction.Merchant != "" {
+		description += " at " + transaction.Merchant
+	}
+	return description
+}
+
+// fuelCardImportErrors flattens a hook or save error into the field errors
+// reported for an imported row, as the time amendment import does.
+func fuelCardImportErrors(err error) []utilities.FuelCardTransactionError {
+	rowErrors := []utilities.FuelCardTransactionError{}
+	for _, rowError := range timeAmendmentImportErrors(err) {
+		rowErrors = append(rowErrors, utilities.FuelCardTransactionError(rowError))
+	}
+	return rowErrors
+}
@@ -0,0 +1,42 @@
+-- List invoicing-eligible purchase orders for a job with per-PO invoiced
+-- aggregates. Eligible POs are project-kind POs referencing the job whose
+-- status is Active or Closed. Cancelled and Unapproved POs are never billable.
+SELECT po.id,
+       po.po_number,
+       po.status,
+       po.date,
+       po.total,
+       po.type,
+       COALESCE(v.name, '') AS vendor_name,
+       COALESCE(b.code, '') AS branch_code,
+       COALESCE(d.code, '') AS division_code,
+       COALESCE(p.surname, '') AS surname,
+       COALESCE(p.given_name, '') AS given_name,
+       COALESCE(inv.invoiced_total, 0) AS invoiced_total,
+       COALESCE(inv.invoice_count, 0) AS invoice_count,
+       COALESCE(inv.last_invoiced_on, '') AS last_invoiced_on
+FROM   purchase_orders po
+JOIN   expenditure_kinds ek ON ek.id = po.kind
+LEFT   JOIN vendors   v ON po.vendor   = v.id
+LEFT   JOIN branches  b ON po.branch   = b.id
+LEFT   JOIN divisions d ON po.division = d.id
+LEFT   JOIN profiles  p ON po.uid      = p.uid
+LEFT   JOIN (
+  SELECT purchase_order,
+         SUM(amount)      AS invoiced_total,
+         COUNT(*)         AS invoice_count,
+         MAX(invoiced_on) AS last_invoiced_on
+  FROM   po_invoicing_records
+  WHERE  job = {:id}
+  GROUP  BY purchase_order
+) inv ON inv.purchase_order = po.id
+WHERE  po.status IN ('Active', 'Closed')
+  AND  ek.name = 'project'
+  AND  po.job = {:id}
+  AND  ({:status}   IS NULL OR {:status}   = '' OR po.status   = {:status})
+  AND  ({:branch}   IS NULL OR {:branch}   = '' OR po.branch   = {:branch})
+  AND  ({:division} IS NULL OR {:division} = '' OR po.division = {:division})
+  AND  ({:type} IS NULL OR {:type} = '' OR po.type = {:type})
+  AND  ({:uid} IS NULL OR {:uid} = '' OR po.uid = {:uid})
+ORDER BY po.date DESC, po.po_number DESC
+LIMIT {:limit} OFFSET {:offset};
@@ -0,0 +1,446 @@
+package routes
+
+import (
+	"database/sql"
+	_ "embed"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"tybalt/errs"
+	"tybalt/utilities"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+//go:embed job_po_invoicing.sql
+var jobPOInvoicingQuery string
+
+//go:embed job_po_invoicing_count.sql
+var jobPOInvoicingCountQuery string
+
+//go:embed job_po_invoicing_summary.sql
+var jobPOInvoicingSummaryQuery string
+
+// JobInvoicingPOEntry is an invoicing-eligible purchase order row with its
+// invoiced aggregates. Fields align with job_po_invoicing.sql aliases.
+type JobInvoicingPOEntry struct {
+	ID             string  `db:"id" json:"id"`
+	PONumber       string  `db:"po_number" json:"po_number"`
+	Status         string  `db:"status" json:"status"`
+	Date           string  `db:"date" json:"date"`
+	Total          float64 `db:"total" json:"total"`
+	Type           string  `db:"type" json:"type"`
+	VendorName     string  `db:"vendor_name" json:"vendor_name"`
+	BranchCode     string  `db:"branch_code" json:"branch_code"`
+	DivisionCode   string  `db:"division_code" json:"division_code"`
+	Surname        string  `db:"surname" json:"surname"`
+	GivenName      string  `db:"given_name" json:"given_name"`
+	InvoicedTotal  float64 `db:"invoiced_total" json:"invoiced_total"`
+	InvoiceCount   int     `db:"invoice_count" json:"invoice_count"`
+	LastInvoicedOn string  `db:"last_invoiced_on" json:"last_invoiced_on"`
+}
+
+type PaginatedJobInvoicingPOsResponse struct {
+	Data       []JobInvoicingPOEntry `json:"data"`
+	Page       int                   `json:"page"`
+	Limit      int                   `json:"limit"`
+	Total      int                   `json:"total"`
+	TotalPages int                   `json:"total_pages"`
+}
+
+// poInvoicingSummaryRow maps the result from job_po_invoicing_summary.sql
+type poInvoicingSummaryRow struct {
+	TotalAmount    sql.NullFloat64 `db:"total_amount"`
+	EarliestPO     sql.NullString  `db:"earliest_po"`
+	LatestPO       sql.NullString  `db:"latest_po"`
+	POCount        sql.NullInt64   `db:"po_count"`
+	InvoicedTotal  sql.NullFloat64 `db:"invoiced_total"`
+	InvoiceCount   sql.NullInt64   `db:"invoice_count"`
+	LastInvoicedOn sql.NullString  `db:"last_invoiced_on"`
+	Branches       sql.NullString  `db:"branches"`
+	Divisions      sql.NullString  `db:"divisions"`
+	Types          sql.NullString  `db:"types"`
+	Names          sql.NullString  `db:"names"`
+}
+
+// POInvoicingRecord is one append-only invoicing entry against a PO.
+type POInvoicingRecord struct {
+	ID             string  `db:"id" json:"id"`
+	PurchaseOrder  string  `db:"purchase_order" json:"purchase_order"`
+	Job            string  `db:"job" json:"job"`
+	InvoicedOn     string  `db:"invoiced_on" json:"invoiced_on"`
+	Amount         float64 `db:"amount" json:"amount"`
+	InvoiceNumber  string  `db:"invoice_number" json:"invoice_number"`
+	Note           string  `db:"note" json:"note"`
+	UID            string  `db:"uid" json:"uid"`
+	RecordedByName string  `db:"recorded_by_name" json:"recorded_by_name"`
+	Created        string  `db:"created" json:"created"`
+}
+
+type createPOInvoicingRecordRequest struct {
+	InvoicedOn    string  `json:"invoiced_on"`
+	Amount        float64 `json:"amount"`
+	InvoiceNumber string  `json:"invoice_number"`
+	Note          string  `json:"note"`
+}
+
+const poInvoicingRecordsQuery = `
+	SELECT
+		r.id,
+		r.purchase_order,
+		r.job,
+		r.invoiced_on,
+		r.amount,
+		COALESCE(r.invoice_number, '') AS invoice_number,
+		COALESCE(r.note, '') AS note,
+		r.uid,
+		COALESCE(p.given_name || ' ' || p.surname, '') AS recorded_by_name,
+		r.created
+	FROM po_invoicing_records r
+	LEFT JOIN profiles p ON p.uid = r.uid
+	WHERE r.job = {:job}
+	  AND r.purchase_order = {:po}
+	  AND ({:id} = '' OR r.id = {:id})
+	ORDER BY r.invoiced_on DESC, r.created DESC
+`
+
+// requireInvoicingViewer allows holders of the invoicing or report claim to
+// read invoicing records and eligible PO aggregates.
+func requireInvoicingViewer(app core.App, auth *core.Record) error {
+	for _, claim := range []string{"invoicing", "report"} {
+		hasClaim, err := utilities.HasClaim(app, auth, claim)
+		if err != nil {
+			return err
+		}
+		if hasClaim {
+			return nil
+		}
+	}
+	return &errs.HookError{
+		Status:  http.StatusForbidden,
+		Message: "you are not authorized to view invoicing records",
+		Data: map[string]errs.CodeError{
+			"global": {Code: "unauthorized", Message: "invoicing or report claim required"},
+		},
+	}
+}
+
+func requireInvoicingClaim(app core.App, auth *core.Record) error {
+	hasClaim, err := utilities.HasClaim(app, auth, "invoicing")
+	if err != nil {
+		return err
+	}
+	if !hasClaim {
+		return &errs.HookError{
+			Status:  http.StatusForbidden,
+			Message: "you are not authorized to create invoicing records",
+			Data: map[string]errs.CodeError{
+				"global": {Code: "unauthorized", Message: "invoicing claim required"},
+			},
+		}
+	}
+	return nil
+}
+
+// createGetJobInvoicingPOsHandler lists invoicing-eligible POs for a job with
+// per-PO invoiced totals. Supports the same filters as the active PO list plus
+// status (Active or Closed).
+func createGetJobInvoicingPOsHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireInvoicingViewer(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		id := e.Request.PathValue("id")
+		if id == "" {
+			return e.Error(http.StatusBadRequest, "id is required", nil)
+		}
+
+		q := e.Request.URL.Query()
+
+		page := 1
+		if pStr := q.Get("page"); pStr != "" {
+			if p, err := strconv.Atoi(pStr); err == nil && p > 0 {
+				page = p
+			}
+		}
+		limit := 50
+		if lStr := q.Get("limit"); lStr != "" {
+			if l, err := strconv.Atoi(lStr); err == nil && l > 0 {
+				if l > 200 {
+					l = 200
+				}
+				limit = l
+			}
+		}
+		offset := (page - 1) * limit
+
+		params := dbx.Params{
+			"id":       id,
+			"status":   q.Get("status"),
+			"branch":   q.Get("branch"),
+			"division": q.Get("division"),
+			"type":     q.Get("type"),
+			"uid":      q.Get("uid"),
+			"limit":    limit,
+			"offset":   offset,
+		}
+
+		var totalCount int
+		if err := app.DB().NewQuery(jobPOInvoicingCountQuery).Bind(params).Row(&totalCount); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to execute count query: "+err.Error(), err)
+		}
+
+		rows := []JobInvoicingPOEntry{}
+		if err := app.DB().NewQuery(jobPOInvoicingQuery).Bind(params).All(&rows); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to execute query: "+err.Error(), err)
+		}
+
+		return e.JSON(http.StatusOK, PaginatedJobInvoicingPOsResponse{
+			Data:       rows,
+			Page:       page,
+			Limit:      limit,
+			Total:      totalCount,
+			TotalPages: (totalCount + limit - 1) / limit,
+		})
+	}
+}
+
+// createGetJobInvoicingPOSummaryHandler returns the job_po_summary figures for
+// invoicing-eligible POs together with the job's invoiced totals.
+func createGetJobInvoicingPOSummaryHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireInvoicingViewer(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		id := e.Request.PathValue("id")
+		if id == "" {
+			return e.Error(http.StatusBadRequest, "id is required", nil)
+		}
+
+		var row poInvoicingSummaryRow
+		if err := app.DB().NewQuery(jobPOInvoicingSummaryQuery).Bind(dbx.Params{
+			"id": id,
+		}).One(&row); err != nil {
+			if err == sql.ErrNoRows {
+				return e.JSON(http.StatusOK, map[string]any{})
+			}
+			return e.Error(http.StatusInternalServerError, "failed to execute query: "+err.Error(), err)
+		}
+
+		ns := func(n sql.NullString) string {
+			if n.Valid {
+				return n.String
+			}
+			return ""
+		}
+
+		totalAmount := row.TotalAmount.Float64
+		invoicedTotal := row.InvoicedTotal.Float64
+
+		return e.JSON(http.StatusOK, map[string]any{
+			"total_amount":       totalAmount,
+			"earliest_po":        ns(row.EarliestPO),
+			"latest_po":          ns(row.LatestPO),
+			"po_count":           row.POCount.Int64,
+			"invoiced_total":     invoicedTotal,
+			"uninvoiced_balance": totalAmount - invoicedTotal,
+			"invoice_count":      row.InvoiceCount.Int64,
+			"last_invoiced_on":   ns(row.LastInvoicedOn),
+			"branches":           ns(row.Branches),
+			"divisions":          ns(row.Divisions),
+			"types":              ns(row.Types),
+			"names":              ns(row.Names),
+		})
+	}
+}
+
+// createGetPOInvoicingRecordsHandler lists the invoicing history for one PO on
+// a job, newest first.
+func createGetPOInvoicingRecordsHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireInvoicingViewer(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		jobID := e.Request.PathValue("id")
+		poID := e.Request.PathValue("po")
+		if jobID == "" || poID == "" {
+			return e.Error(http.StatusBadRequest, "job id and purchase order id are required", nil)
+		}
+
+		rows := []POInvoicingRecord{}
+		if err := app.DB().NewQuery(poInvoicingRecordsQuery).Bind(dbx.Params{
+			"job": jobID,
+			"po":  poID,
+			"id":  "",
+		}).All(&rows); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to execute query: "+err.Error(), err)
+		}
+
+		return e.JSON(http.StatusOK, rows)
+	}
+}
+
+// createCreatePOInvoicingRecordHandler appends an invoicing record to an
+// eligible PO. The recorder, job and client are derived server-side; records
+// cannot be edited or deleted once written.
+func createCreatePOInvoicingRecordHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireInvoicingClaim(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+
+		jobID := e.Request.PathValue("id")
+		poID := e.Request.PathValue("po")
+		if jobID == "" || poID == "" {
+			return e.Error(http.StatusBadRequest, "job id and purchase order id are required", nil)
+		}
+
+		var req createPOInvoicingRecordRequest
+		if err := json.NewDecoder(e.Request.Body).Decode(&req); err != nil {
+			return e.Error(http.StatusBadRequest, "invalid JSON body", err)
+		}
+		req.InvoicedOn = strings.TrimSpace(req.InvoicedOn)
+		req.InvoiceNumber = strings.TrimSpace(req.InvoiceNumber)
+		req.Note = strings.TrimSpace(req.Note)
+
+		if err := validatePOInvoicingRecordRequest(req); err != nil {
+			return writeHookError(e, err)
+		}
+
+		var created POInvoicingRecord
+		err := app.RunInTransaction(func(txApp core.App) error {
+			po, err := txApp.FindRecordById("purchase_orders", poID)
+			if err != nil || po.GetString("job") != jobID {
+				return &errs.HookError{
+					Status:  http.StatusNotFound,
+					Message: "purchase order not found for job",
+					Data: map[string]errs.CodeError{
+						"purchase_order": {Code: "not_found", Message: "purchase order not found for job"},
+					},
+				}
+			}
+			if err := validatePOInvoicingEligibility(po); err != nil {
+				return err
+			}
+
+			job, err := txApp.FindRecordById("jobs", jobID)
+			if err != nil {
+				return err
+			}
+
+			collection, err := txApp.FindCollectionByNameOrId("po_invoicing_records")
+			if err != nil {
+				return err
+			}
+			record := core.NewRecord(collection)
+			record.Set("purchase_order", po.Id)
+			record.Set("job", job.Id)
+			record.Set("client", job.GetString("client"))
+			record.Set("invoiced_on", req.InvoicedOn)
+			record.Set("amount", req.Amount)
+			record.Set("invoice_number", req.InvoiceNumber)
+			record.Set("note", req.Note)
+			record.Set("uid", e.Auth.Id)
+			if err := txApp.Save(record); err != nil {
+				return err
+			}
+
+			return txApp.DB().NewQuery(poInvoicingRecordsQuery).Bind(dbx.Params{
+				"job": job.Id,
+				"po":  po.Id,
+				"id":  record.Id,
+			}).One(&created)
+		})
+		if err != nil {
+			var hookErr *errs.HookError
+			if errors.As(err, &hookErr) {
+				return e.JSON(hookErr.Status, hookErr)
+			}
+			return e.Error(http.StatusInternalServerError, "failed to create invoicing record", err)
+		}
+
+		return e.JSON(http.StatusCreated, created)
+	}
+}
+
+func validatePOInvoicingRecordRequest(req createPOInvoicingRecordRequest) error {
+	today := time.Now().Format(time.DateOnly)
+	err := validation.Errors{
+		"invoiced_on": validation.Validate(req.InvoicedOn,
+			validation.Required.Error("invoiced_on is required"),
+			validation.By(utilities.IsValidDate),
+			validation.By(func(value any) error {
+				if s, _ := value.(string); s > today {
+					return validation.NewError("future_date", "invoiced_on cannot be in the future")
+				}
+				return nil
+			}),
+		),
+		"amount": validation.Validate(req.Amount,
+			validation.Required.Error("amount is required"),
+			validation.Min(0.01).Error("amount must be greater than 0"),
+			validation.By(utilities.IsPositiveMultipleOfPointZeroOne()),
+		),
+		"invoice_number": validation.Validate(req.InvoiceNumber, validation.Length(0, 100)),
+		"note":           validation.Validate(req.Note, validation.Length(0, 1000)),
+	}.Filter()
+	if err == nil {
+		return nil
+	}
+
+	fieldErrors := make(map[string]errs.CodeError)
+	for field, fieldErr := range err.(validation.Errors) {
+		code := "validation_error"
+		var ozzoErr validation.Error
+		if errors.As(fieldErr, &ozzoErr) {
+			code = ozzoErr.Code()
+		}
+		fieldErrors[field] = errs.CodeError{Code: code, Message: fieldErr.Error()}
+	}
+	return &errs.HookError{
+		Status:  http.StatusBadRequest,
+		Message: "validation failed",
+		Data:    fieldErrors,
+	}
+}
+
+// validatePOInvoicingEligibility enforces the invoicing eligibility rules: the
+// PO must be project-kind and Active or Closed. Cancelled and Unapproved POs
+// never represent billable work.
+func validatePOInvoicingEligibility(po *core.Record) error {
+	status := po.GetString("status")
+	if status != "Active" && status != "Closed" {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "purchase order is not eligible for invoicing",
+			Data: map[string]errs.CodeError{
+				"purchase_order": {
+					Code:    "po_not_eligible_status",
+					Message: "only Active or Closed purchase orders can be invoiced",
+				},
+			},
+		}
+	}
+	if po.GetString("kind") != utilities.DefaultProjectExpenditureKindID() {
+		return &errs.HookError{
+			Status:  http.StatusBadRequest,
+			Message: "purchase order is not eligible for invoicing",
+			Data: map[string]errs.CodeError{
+				"purchase_order": {
+					Code:    "po_not_eligible_kind",
+					Message: "only project purchase orders can be invoiced",
+				},
+			},
+		}
+	}
+	return nil
+}
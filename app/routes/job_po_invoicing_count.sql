@@ -0,0 +1,13 @@
+-- Count invoicing-eligible purchase orders for a job with the same filters as
+-- job_po_invoicing.sql.
+SELECT COUNT(*)
+FROM   purchase_orders po
+JOIN   expenditure_kinds ek ON ek.id = po.kind
+WHERE  po.status IN ('Active', 'Closed')
+  AND  ek.name = 'project'
+  AND  po.job = {:id}
+  AND  ({:status}   IS NULL OR {:status}   = '' OR po.status   = {:status})
+  AND  ({:branch}   IS NULL OR {:branch}   = '' OR po.branch   = {:branch})
+  AND  ({:division} IS NULL OR {:division} = '' OR po.division = {:division})
+  AND  ({:type} IS NULL OR {:type} = '' OR po.type = {:type})
+  AND  ({:uid} IS NULL OR {:uid} = '' OR po.uid = {:uid});
@@ -0,0 +1,43 @@
+-- Summary of invoicing-eligible purchase orders for a job. The PO figures
+-- mirror job_po_summary.sql; the invoiced figures aggregate
+-- po_invoicing_records across the same set of POs.
+WITH eligible AS (
+  SELECT po.*
+  FROM   purchase_orders po
+  JOIN   expenditure_kinds ek ON ek.id = po.kind
+  WHERE  po.status IN ('Active', 'Closed')
+    AND  ek.name = 'project'
+    AND  po.job = {:id}
+),
+invoiced AS (
+  SELECT SUM(r.amount)      AS invoiced_total,
+         COUNT(*)           AS invoice_count,
+         MAX(r.invoiced_on) AS last_invoiced_on
+  FROM   po_invoicing_records r
+  JOIN   eligible e ON e.id = r.purchase_order
+  WHERE  r.job = {:id}
+)
+SELECT
+  SUM(po.total)                           total_amount,
+  MIN(po.date)                            earliest_po,
+  MAX(po.date)                            latest_po,
+  COUNT(DISTINCT po.id)                   po_count,
+  (SELECT invoiced_total FROM invoiced)   invoiced_total,
+  (SELECT invoice_count FROM invoiced)    invoice_count,
+  (SELECT last_invoiced_on FROM invoiced) last_invoiced_on,
+  json_group_array(
+    DISTINCT json_object('id', b.id, 'code', b.code)
+  )                                        branches,
+  json_group_array(
+    DISTINCT json_object('id', d.id, 'code', d.code)
+  )                                        divisions,
+  json_group_array(
+    DISTINCT json_object('name', po.type)
+  )                                        types,
+  json_group_array(
+    DISTINCT json_object('id', p.uid, 'name', p.given_name || ' ' || p.surname)
+  )                                        names
+FROM   eligible po
+LEFT   JOIN branches  b ON po.branch  = b.id
+LEFT   JOIN divisions d ON po.division = d.id
+LEFT   JOIN profiles  p ON po.uid      = p.uid;
@@ -0,0 +1,432 @@
+package routes
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// mileageLogViewerClaims can see any employee's mileage summary and logbook.
+// Employees see their own and managers those of their direct reports.
+var mileageLogViewerClaims = []string{"commit", "report"}
+
+type mileageLogExpense struct {
+	ID          string  `db:"id"`
+	Date        string  `db:"date"`
+	Description string  `db:"description"`
+	Distance    float64 `db:"distance"`
+	Committed   string  `db:"committed"`
+	JobNumber   string  `db:"job_number"`
+}
+
+type mileageLogTrip struct {
+	ID          string  `db:"id"`
+	Expense     string  `db:"expense"`
+	Date        string  `db:"date"`
+	Origin      string  `db:"origin"`
+	Destination string  `db:"destination"`
+	Purpose     string  `db:"purpose"`
+	Distance    float64 `db:"distance"`
+	JobNumber   string  `db:"job_number"`
+}
+
+type mileageLogRate struct {
+	EffectiveDate string          `db:"effective_date"`
+	Mileage       string          `db:"mileage"`
+	Rates         map[int]float64 `db:"-"`
+}
+
+// MileageTier is one rate tier of a mileage summary with the committed
+// kilometres the year's claims put in it.
+type MileageTier struct {
+	FromKm   int     `json:"from_km"`
+	ToKm     *int    `json:"to_km"`
+	Rate     float64 `json:"rate"`
+	Distance int     `json:"distance"`
+	Amount   float64 `json:"amount"`
+}
+
+// MileageSummary is an employee's mileage for a calendar year.
+type MileageSummary struct {
+	UID  string `json:"uid"`
+	Year int    `json:"year"`
+	// PeriodStart is the mileage reset date in effect at the end of the year,
+	// or "" when there has never been a reset.
+	PeriodStart        string        `json:"period_start"`
+	Expenses           int           `json:"expenses"`
+	Trips              int           `json:"trips"`
+	CommittedDistance  int           `json:"committed_distance"`
+	CommittedAmount    float64       `json:"committed_amount"`
+	PendingDistance    int           `json:"pending_distance"`
+	CumulativeDistance int           `json:"cumulative_distance"`
+	CurrentTierFromKm  int           `json:"current_tier_from_km"`
+	DistanceToNextTier *int          `json:"distance_to_next_tier"`
+	Tiers              []MileageTier `json:"tiers"`
+}
+
+// mileageLog is the data behind an employee's mileage summary and logbook
+// for one year.
+type mileageLog struct {
+	uid        string
+	year       int
+	resets     []string
+	rates      []mileageLogRate
+	expenses   []mileageLogExpense // claimed Mileage expenses from the start of the reset period, by date
+	trips      map[string][]mileageLogTrip
+	yearStart  string
+	yearEnd    string
+	periodFrom string
+}
+
+// checkMileageLogAccess allows employees to view their own mileage, managers
+// that of their direct reports and holders of a viewer claim anyone's.
+func checkMileageLogAccess(app core.App, e *core.RequestEvent, uid string) error {
+	if uid == e.Auth.Id {
+		return nil
+	}
+	for _, claim := range mileageLogViewerClaims {
+		hasClaim, err := utilities.HasClaim(app, e.Auth, claim)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to check claims", err)
+		}
+		if hasClaim {
+			return nil
+		}
+	}
+	profile, err := app.FindFirstRecordByData("profiles", "uid", uid)
+	if err == nil && profile.GetString("manager") == e.Auth.Id {
+		return nil
+	}
+	return e.Error(http.StatusForbidden, "you do not have permission to view this mileage log", nil)
+}
+
+// mileageLogYear reads the year path value.
+func mileageLogYear(e *core.RequestEvent) (int, error) {
+	year, err := strconv.Atoi(e.Request.PathValue("year"))
+	if err != nil || year < 2000 || year > 2100 {
+		return 0, e.Error(http.StatusBadRequest, "year must be a four-digit year", nil)
+	}
+	return year, nil
+}
+
+// loadMileageLog reads an employee's claimed Mileage expenses and their trips.
+// A claimed expense is submitted or committed and not rejected. Expenses from
+// before the year are loaded back to the reset date in effect on January 1 so
+// cumulative kilometres start where CalculateMileageTotal starts them.
+func loadMileageLog(app core.App, uid string, year int) (*mileageLog, error) {
+	data := &mileageLog{
+		uid:       uid,
+		year:      year,
+		trips:     map[string][]mileageLogTrip{},
+		yearStart: fmt.Sprintf("%04d-01-01", year),
+		yearEnd:   fmt.Sprintf("%04d-12-31", year),
+	}
+
+	resetRows := []struct {
+		Date string `db:"date"`
+	}{}
+	if err := app.DB().NewQuery(`
+		SELECT date FROM mileage_reset_dates WHERE date <= {:yearEnd} ORDER BY date
+	`).Bind(dbx.Params{"yearEnd": data.yearEnd}).All(&resetRows); err != nil {
+		return nil, err
+	}
+	for _, row := range resetRows {
+		data.resets = append(data.resets, row.Date)
+	}
+	data.periodFrom = data.resetFor(data.yearStart)
+	if data.periodFrom == "" {
+		data.periodFrom = "0001-01-01"
+	}
+
+	if err := app.DB().NewQuery(`
+		SELECT effective_date, COALESCE(mileage, '') AS mileage
+		FROM expense_rates
+		ORDER BY effective_date
+	`).All(&data.rates); err != nil {
+		return nil, err
+	}
+	rates := data.rates[:0]
+	for _, rate := range data.rates {
+		if strings.TrimSpace(rate.Mileage) == "" || rate.Mileage == "null" {
+			continue
+		}
+		parsed, err := utilities.ParseMileageRates(rate.Mileage)
+		if err != nil {
+			return nil, fmt.Errorf("expense rates effective %s: %w", rate.EffectiveDate, err)
+		}
+		rate.Rates = parsed
+		rates = append(rates, rate)
+	}
+	data.rates = rates
+
+	params := dbx.Params{"uid": uid, "from": data.periodFrom, "yearStart": data.yearStart, "yearEnd": data.yearEnd}
+	if err := app.DB().NewQuery(`
+		SELECT
+		  e.id,
+		  e.date,
+		  COALESCE(e.description, '') AS description,
+		  CAST(COALESCE(e.distance, 0) AS REAL) AS distance,
+		  COALESCE(e.committed, '') AS committed,
+		  COALESCE(j.number, '') AS job_number
+		FROM expenses e
+		LEFT JOIN jobs j ON e.job = j.id
+		WHERE e.uid = {:uid}
+		  AND e.payment_type = 'Mileage'
+		  AND (e.submitted = 1 OR e.committed != '')
+		  AND COALESCE(e.rejected, '') = ''
+		  AND e.date >= {:from}
+		  AND e.date <= {:yearEnd}
+		ORDER BY e.date, e.id
+	`).Bind(params).All(&data.expenses); err != nil {
+		return nil, err
+	}
+
+	trips := []mileageLogTrip{}
+	if err := app.DB().NewQuery(`
+		SELECT
+		  t.id,
+		  t.expense,
+		  t.date,
+		  t.origin,
+		  t.destination,
+		  t.purpose,
+		  CAST(t.distance AS REAL) AS distance,
+		  COALESCE(j.number, '') AS job_number
+		FROM mileage_trips t
+		JOIN expenses e ON t.expense = e.id
+		LEFT JOIN jobs j ON t.job = j.id
+		WHERE e.uid = {:uid}
+		  AND e.payment_type = 'Mileage'
+		  AND e.date >= {:yearStart}
+		  AND e.date <= {:yearEnd}
+		ORDER BY t.date, t.id
+	`).Bind(params).All(&trips); err != nil {
+		return nil, err
+	}
+	for _, trip := range trips {
+		data.trips[trip.Expense] = append(data.trips[trip.Expense], trip)
+	}
+	return data, nil
+}
+
+// resetFor returns the last mileage reset date on or before date, or "".
+func (l *mileageLog) resetFor(date string) string {
+	reset := ""
+	for _, candidate := range l.resets {
+		if candidate <= date {
+			reset = candidate
+		}
+	}
+	return reset
+}
+
+// ratesFor returns the mileage tiers effective on date.
+func (l *mileageLog) ratesFor(date string) map[int]float64 {
+	var rates map[int]float64
+	for _, rate := range l.rates {
+		if rate.EffectiveDate <= date {
+			rates = rate.Rates
+		}
+	}
+	return rates
+}
+
+func (l *mileageLog) inYear(date string) bool {
+	return date >= l.yearStart && date <= l.yearEnd
+}
+
+// summary walks the committed claims in date order, as CalculateMileageTotal
+// does, restarting the cumulative kilometres at each reset date, and totals
+// the kilometres and amounts of the year's claims by tier.
+func (l *mileageLog) summary() MileageSummary {
+	summary := MileageSummary{UID: l.uid, Year: l.year, PeriodStart: l.resetFor(l.yearEnd), Tiers: []MileageTier{}}
+
+	byTier := map[int]*MileageTier{}
+	cumulative, period := 0, l.resetFor(l.periodFrom)
+	for _, expense := range l.expenses {
+		distance := int(expense.Distance)
+		if l.inYear(expense.Date) {
+			summary.Expenses++
+			summary.Trips += len(l.trips[expense.ID])
+			if expense.Committed == "" {
+				summary.PendingDistance += distance
+			}
+		}
+		if expense.Committed == "" {
+			continue
+		}
+		if reset := l.resetFor(expense.Date); reset != period {
+			cumulative, period = 0, reset
+		}
+		portions := utilities.SplitMileageAcrossTiers(l.ratesFor(expense.Date), cumulative, distance)
+		cumulative += distance
+		if !l.inYear(expense.Date) {
+			continue
+		}
+		summary.CommittedDistance += distance
+		summary.CommittedAmount += utilities.MileageTierAmount(portions)
+		for _, portion := range portions {
+			tier, ok := byTier[portion.FromKm]
+			if !ok {
+				tier = &MileageTier{FromKm: portion.FromKm, Rate: portion.Rate}
+				byTier[portion.FromKm] = tier
+			}
+			tier.Distance += portion.Distance
+			tier.Amount += utilities.MileageTierAmount([]utilities.MileageTierPortion{portion})
+		}
+	}
+	if period != summary.PeriodStart {
+		cumulative = 0
+	}
+	summary.CumulativeDistance = cumulative
+	summary.CommittedAmount = utilities.RoundCurrencyAmount(summary.CommittedAmount)
+
+	// The tiers are those in effect at the end of the year, plus any other
+	// tier the year's claims fell in under earlier rates.
+	current := l.ratesFor(l.yearEnd)
+	bounds := []int{}
+	for fromKm := range current {
+		bounds = append(bounds, fromKm)
+	}
+	sort.Ints(bounds)
+	for i, fromKm := range bounds {
+		tier := MileageTier{FromKm: fromKm, Rate: current[fromKm]}
+		if existing, ok := byTier[fromKm]; ok {
+			tier.Distance, tier.Amount = existing.Distance, existing.Amount
+			delete(byTier, fromKm)
+		}
+		if i+1 < len(bounds) {
+			toKm := bounds[i+1]
+			tier.ToKm = &toKm
+			if cumulative >= fromKm && cumulative < toKm {
+				remaining := toKm - cumulative
+				summary.DistanceToNextTier = &remaining
+			}
+		}
+		if cumulative >= fromKm {
+			summary.CurrentTierFromKm = fromKm
+		}
+		summary.Tiers = append(summary.Tiers, tier)
+	}
+	for _, tier := range byTier {
+		summary.Tiers = append(summary.Tiers, *tier)
+	}
+	sort.SliceStable(summary.Tiers, func(i, j int) bool { return summary.Tiers[i].FromKm < summary.Tiers[j].FromKm })
+	for i := range summary.Tiers {
+		summary.Tiers[i].Amount = utilities.RoundCurrencyAmount(summary.Tiers[i].Amount)
+	}
+	return summary
+}
+
+// createGetMileageSummaryHandler returns an employee's committed kilometres
+// for a year against the mileage rate tiers.
+func createGetMileageSummaryHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		uid := e.Request.PathValue("uid")
+		if err := checkMileageLogAccess(app, e, uid); err != nil {
+			return err
+		}
+		year, err := mileageLogYear(e)
+		if err != nil {
+			return err
+		}
+		data, err := loadMileageLog(app, uid, year)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load mileage", err)
+		}
+		return e.JSON(http.StatusOK, data.summary())
+	}
+}
+
+// createGetMileageLogbookHandler returns an employee's mileage logbook for a
+// year as CSV: one row per logged trip, or per claimed expense without trips,
+// with the cumulative kilometres since the last reset and a total row.
+func createGetMileageLogbookHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		uid := e.Request.PathValue("uid")
+		if err := checkMileageLogAccess(app, e, uid); err != nil {
+			return err
+		}
+		year, err := mileageLogYear(e)
+		if err != nil {
+			return err
+		}
+		data, err := loadMileageLog(app, uid, year)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load mileage", err)
+		}
+
+		employee := uid
+		if profile, err := app.FindFirstRecordByData("profiles", "uid", uid); err == nil {
+			employee = strings.TrimSpace(profile.GetString("given_name") + " " + profile.GetString("surname"))
+		}
+
+		var csvBuilder strings.Builder
+		writer := csv.NewWriter(&csvBuilder)
+		headers := []string{"Employee", "Date", "From", "To", "Purpose", "Job", "Distance (km)", "Cumulative (km)", "Status", "Expense"}
+		if err := writer.Write(headers); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to write csv header", err)
+		}
+		// Cumulative kilometres count the year's rows, restarting at any
+		// reset date within the year.
+		cumulative, total, period := 0, 0, data.resetFor(data.yearStart)
+		for _, expense := range data.expenses {
+			if !data.inYear(expense.Date) {
+				continue
+			}
+			status := "Submitted"
+			if expense.Committed != "" {
+				status = "Committed"
+			}
+			trips := data.trips[expense.ID]
+			if len(trips) == 0 {
+				trips = []mileageLogTrip{{Date: expense.Date, Purpose: expense.Description, Distance: expense.Distance, JobNumber: expense.JobNumber}}
+			}
+			for _, trip := range trips {
+				if reset := data.resetFor(trip.Date); reset != period {
+					cumulative, period = 0, reset
+				}
+				jobNumber := trip.JobNumber
+				if jobNumber == "" {
+					jobNumber = expense.JobNumber
+				}
+				distance := int(trip.Distance)
+				cumulative += distance
+				total += distance
+				if err := writer.Write([]string{
+					employee,
+					trip.Date,
+					trip.Origin,
+					trip.Destination,
+					trip.Purpose,
+					jobNumber,
+					strconv.Itoa(distance),
+					strconv.Itoa(cumulative),
+					status,
+					expense.ID,
+				}); err != nil {
+					return e.Error(http.StatusInternalServerError, "failed to write csv row", err)
+				}
+			}
+		}
+		if err := writer.Write([]string{employee, "Total", "", "", "", "", strconv.Itoa(total), "", "", ""}); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to write csv row", err)
+		}
+		writer.Flush()
+		if err := writer.Error(); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to finalize csv", err)
+		}
+
+		e.Response.Header().Set("Content-Type", "text/csv; charset=utf-8")
+		e.Response.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="mileage_logbook_%s_%d.csv"`, uid, year))
+		return e.String(http.StatusOK, csvBuilder.String())
+	}
+}
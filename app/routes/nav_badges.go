@@ -52,7 +52,7 @@ func createGetNavBadgesHandler(app core.App) func(e *core.RequestEvent) error {
 				SELECT te.tsid
 				FROM time_entries te
 				INNER JOIN time_sheets ts ON te.tsid = ts.id
-				WHERE ts.approver = {:uid}
+				WHERE (ts.approver = {:uid} OR ts.approval_escalated_to = {:uid} OR (ts.uid != {:uid} AND `+utilities.ApprovalDelegationCondition("ts.approver", "{:uid}", utilities.ApprovalDelegationScopeTime)+`))
 				  AND ts.approved = ''
 				GROUP BY te.tsid
 			)
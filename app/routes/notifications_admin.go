@@ -0,0 +1,111 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"net/http"
+	"strings"
+	"tybalt/notifications"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+type failedNotificationRow struct {
+	ID            string `db:"id" json:"id"`
+	Recipient     string `db:"recipient" json:"recipient"`
+	RecipientName string `db:"recipient_name" json:"recipient_name"`
+	TemplateCode  string `db:"template_code" json:"template_code"`
+	Subject       string `db:"subject" json:"subject"`
+	Status        string `db:"status" json:"status"`
+	Error         string `db:"error" json:"error"`
+	Transport     string `db:"transport" json:"transport"`
+	Attempts      int    `db:"attempts" json:"attempts"`
+	NextAttempt   string `db:"next_attempt" json:"next_attempt"`
+	StatusUpdated string `db:"status_updated" json:"status_updated"`
+	Created       string `db:"created" json:"created"`
+}
+
+// failedNotificationsQuery lists notifications awaiting retry (error) and
+// dead-lettered notifications, most recently updated first.
+const failedNotificationsQuery = `
+	SELECT
+		n.id,
+		n.recipient,
+		COALESCE(TRIM(p.given_name || ' ' || p.surname), '') AS recipient_name,
+		COALESCE(nt.code, '') AS template_code,
+		COALESCE(nt.subject, '') AS subject,
+		n.status,
+		COALESCE(n.error, '') AS error,
+		COALESCE(n.transport, '') AS transport,
+		COALESCE(n.attempts, 0) AS attempts,
+		COALESCE(n.next_attempt, '') AS next_attempt,
+		COALESCE(n.status_updated, '') AS status_updated,
+		n.created
+	FROM notifications n
+	LEFT JOIN profiles p ON p.uid = n.recipient
+	LEFT JOIN notification_templates nt ON nt.id = n.template
+	WHERE n.status IN ({:status_a}, {:status_b})
+	ORDER BY n.status_updated DESC, n.id
+`
+
+func requireAdminClaimForNotifications(app core.App, e *core.RequestEvent, action string) error {
+	hasAdminClaim, err := utilities.HasClaim(app, e.Auth, "admin")
+	if err != nil {
+		return e.Error(http.StatusInternalServerError, "failed to check admin claim", err)
+	}
+	if !hasAdminClaim {
+		return e.Error(http.StatusForbidden, "you do not have permission to "+action, nil)
+	}
+	return nil
+}
+
+// createListFailedNotificationsHandler lists failed notifications for admins.
+// The optional status query parameter narrows the list to error (awaiting
+// retry) or dead (retries exhausted).
+func createListFailedNotificationsHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireAdminClaimForNotifications(app, e, "view failed notifications"); err != nil {
+			return err
+		}
+
+		params := dbx.Params{"status_a": "error", "status_b": "dead"}
+		switch status := strings.TrimSpace(e.Request.URL.Query().Get("status")); status {
+		case "":
+		case "error", "dead":
+			params = dbx.Params{"status_a": status, "status_b": status}
+		default:
+			return e.Error(http.StatusBadRequest, "status must be error or dead", nil)
+		}
+
+		rows := []failedNotificationRow{}
+		if err := app.DB().NewQuery(failedNotificationsQuery).Bind(params).All(&rows); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to list failed notifications", err)
+		}
+		return e.JSON(http.StatusOK, rows)
+	}
+}
+
+// createRequeueNotificationHandler returns a failed notification to the
+// pending queue with a fresh attempt count. The retry cron job sends it.
+func createRequeueNotificationHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireAdminClaimForNotifications(app, e, "requeue notifications"); err != nil {
+			return err
+		}
+
+		id := e.Request.PathValue("id")
+		err := notifications.RequeueNotification(app, id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.Error(http.StatusNotFound, "notification not found", nil)
+		}
+		if errors.Is(err, notifications.ErrNotificationNotRequeueable) {
+			return e.Error(http.StatusBadRequest, err.Error(), nil)
+		}
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to requeue notification", err)
+		}
+		return e.JSON(http.StatusOK, map[string]string{"id": id, "status": "pending"})
+	}
+}
@@ -0,0 +1,107 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"tybalt/hooks"
+	"tybalt/internal/testseed"
+
+	"github.com/pocketbase/dbx"
+)
+
+func TestFailedNotificationsListAndRequeue(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	t.Cleanup(app.Cleanup)
+	hooks.AddHooks(app)
+	AddRoutes(app)
+
+	// Two of the seeded pending notifications are turned into a retrying and a
+	// dead-lettered notification.
+	const retryingID = "1x4na39zxa6cev4"
+	const deadID = "35ni9921v3if519"
+	const pendingID = "62c79cp2u5w62a6"
+	if _, err := app.NonconcurrentDB().NewQuery(`
+		UPDATE notifications SET status = 'error', attempts = 2, error = 'smtp timeout', next_attempt = '2099-01-01 00:00:00.000Z'
+		WHERE id = {:id}
+	`).Bind(dbx.Params{"id": retryingID}).Execute(); err != nil {
+		t.Fatalf("failed to seed retrying notification: %v", err)
+	}
+	if _, err := app.NonconcurrentDB().NewQuery(`
+		UPDATE notifications SET status = 'dead', attempts = 5, error = 'mailbox unavailable'
+		WHERE id = {:id}
+	`).Bind(dbx.Params{"id": deadID}).Execute(); err != nil {
+		t.Fatalf("failed to seed dead notification: %v", err)
+	}
+
+	adminToken := authTokenForEmail(t, app, "author@soup.com")
+	noClaimsToken := authTokenForEmail(t, app, "u_no_claims@example.com")
+
+	forbidden := performClaimsJSONRequest(t, app, http.MethodGet, "/api/notifications/failed", noClaimsToken, nil)
+	if forbidden.Code != http.StatusForbidden {
+		t.Fatalf("non-admin list status = %d, want %d; body=%s", forbidden.Code, http.StatusForbidden, forbidden.Body.String())
+	}
+
+	rec := performClaimsJSONRequest(t, app, http.MethodGet, "/api/notifications/failed", adminToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list status = %d, want %d; body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var rows []failedNotificationRow
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if len(rows) != 2 {
+		t.Fatalf("expected 2 failed notifications, got %d: %s", len(rows), rec.Body.String())
+	}
+	for _, row := range rows {
+		if row.ID == retryingID && (row.Status != "error" || row.Attempts != 2 || row.Error != "smtp timeout" || row.NextAttempt == "") {
+			t.Fatalf("unexpected retrying row: %+v", row)
+		}
+		if row.ID == deadID && (row.Status != "dead" || row.Attempts != 5 || row.TemplateCode == "") {
+			t.Fatalf("unexpected dead row: %+v", row)
+		}
+	}
+
+	rec = performClaimsJSONRequest(t, app, http.MethodGet, "/api/notifications/failed?status=dead", adminToken, nil)
+	rows = nil
+	if err := json.Unmarshal(rec.Body.Bytes(), &rows); err != nil {
+		t.Fatalf("failed to decode dead list: %v", err)
+	}
+	if len(rows) != 1 || rows[0].ID != deadID {
+		t.Fatalf("expected only the dead notification, got %s", rec.Body.String())
+	}
+
+	badStatus := performClaimsJSONRequest(t, app, http.MethodGet, "/api/notifications/failed?status=sent", adminToken, nil)
+	if badStatus.Code != http.StatusBadRequest {
+		t.Fatalf("bad status filter = %d, want %d", badStatus.Code, http.StatusBadRequest)
+	}
+
+	forbidden = performClaimsJSONRequest(t, app, http.MethodPost, "/api/notifications/"+deadID+"/requeue", noClaimsToken, nil)
+	if forbidden.Code != http.StatusForbidden {
+		t.Fatalf("non-admin requeue status = %d, want %d", forbidden.Code, http.StatusForbidden)
+	}
+	missing := performClaimsJSONRequest(t, app, http.MethodPost, "/api/notifications/missing0000000/requeue", adminToken, nil)
+	if missing.Code != http.StatusNotFound {
+		t.Fatalf("missing requeue status = %d, want %d", missing.Code, http.StatusNotFound)
+	}
+	notFailed := performClaimsJSONRequest(t, app, http.MethodPost, "/api/notifications/"+pendingID+"/requeue", adminToken, nil)
+	if notFailed.Code != http.StatusBadRequest {
+		t.Fatalf("pending requeue status = %d, want %d", notFailed.Code, http.StatusBadRequest)
+	}
+
+	rec = performClaimsJSONRequest(t, app, http.MethodPost, "/api/notifications/"+deadID+"/requeue", adminToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("requeue status = %d, want %d; body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	var state struct {
+		Status   string `db:"status"`
+		Attempts int    `db:"attempts"`
+	}
+	if err := app.DB().NewQuery("SELECT status, attempts FROM notifications WHERE id = {:id}").
+		Bind(dbx.Params{"id": deadID}).One(&state); err != nil {
+		t.Fatalf("failed to load requeued notification: %v", err)
+	}
+	if state.Status != "pending" || state.Attempts != 0 {
+		t.Fatalf("expected requeued notification to be pending with no attempts, got %+v", state)
+	}
+}
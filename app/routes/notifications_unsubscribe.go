@@ -0,0 +1,91 @@
+package routes
+
+import (
+	"bytes"
+	"database/sql"
+	"errors"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strings"
+	"tybalt/notifications"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// unsubscribeConfirmationPage asks the recipient to confirm an unsubscribe
+// link. Mail link scanners and prefetchers follow GET links, so only the
+// form's POST mutes the template.
+var unsubscribeConfirmationPage = template.Must(template.New("unsubscribe").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>Unsubscribe</title>
+</head>
+<body>
+<p>Stop receiving {{.TemplateCode}} notifications?</p>
+<form method="post" action="{{.Action}}">
+<input type="hidden" name="List-Unsubscribe" value="One-Click">
+<button type="submit">Unsubscribe</button>
+</form>
+</body>
+</html>
+`))
+
+// createUnsubscribeConfirmationHandler serves the unsubscribe link in the
+// email body. It validates the signed token and renders a confirmation form
+// that posts back to the same link; it never changes preferences itself.
+func createUnsubscribeConfirmationHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		token := strings.TrimSpace(e.Request.URL.Query().Get("token"))
+		if token == "" {
+			return e.Error(http.StatusBadRequest, "token is required", nil)
+		}
+
+		_, templateCode, err := notifications.ParseUnsubscribeToken(app, token)
+		if err != nil {
+			return e.Error(http.StatusBadRequest, notifications.ErrInvalidUnsubscribeToken.Error(), nil)
+		}
+
+		var page bytes.Buffer
+		if err := unsubscribeConfirmationPage.Execute(&page, map[string]string{
+			"TemplateCode": templateCode,
+			"Action":       e.Request.URL.Path + "?token=" + url.QueryEscape(token),
+		}); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to render unsubscribe page", err)
+		}
+		return e.HTML(http.StatusOK, page.String())
+	}
+}
+
+// createUnsubscribeNotificationHandler mutes a template for the recipient
+// named in a signed unsubscribe token. It needs no authentication: the token
+// is the credential. It serves RFC 8058 one-click unsubscribe from mail
+// clients and the confirmation form of the GET route.
+func createUnsubscribeNotificationHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		token := strings.TrimSpace(e.Request.URL.Query().Get("token"))
+		if token == "" {
+			return e.Error(http.StatusBadRequest, "token is required", nil)
+		}
+
+		recipientUID, templateCode, err := notifications.ParseUnsubscribeToken(app, token)
+		if err != nil {
+			return e.Error(http.StatusBadRequest, notifications.ErrInvalidUnsubscribeToken.Error(), nil)
+		}
+
+		err = notifications.SetNotificationPreference(app, recipientUID, templateCode, notifications.PreferenceMute)
+		if errors.Is(err, sql.ErrNoRows) {
+			return e.Error(http.StatusNotFound, "notification template not found", nil)
+		}
+		if errors.Is(err, notifications.ErrTemplateNotMutable) {
+			return e.Error(http.StatusBadRequest, err.Error(), nil)
+		}
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to unsubscribe", err)
+		}
+		return e.String(http.StatusOK, "You have been unsubscribed from "+templateCode+" notifications.")
+	}
+}
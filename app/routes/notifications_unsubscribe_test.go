@@ -0,0 +1,90 @@
+package routes
+
+import (
+	"html"
+	"net/http"
+	"strings"
+	"testing"
+	"tybalt/hooks"
+	"tybalt/internal/testseed"
+	"tybalt/notifications"
+
+	"github.com/pocketbase/dbx"
+)
+
+func TestUnsubscribeNotificationLink(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	t.Cleanup(app.Cleanup)
+	hooks.AddHooks(app)
+	AddRoutes(app)
+	app.Settings().Meta.AppURL = "https://tybalt.example.com"
+
+	const uid = "rzr98oadsp9qc11"
+	modeFor := func(templateCode string) string {
+		t.Helper()
+		var row struct {
+			Mode string `db:"mode"`
+		}
+		err := app.DB().NewQuery(`
+			SELECT np.mode FROM notification_preferences np
+			JOIN notification_templates nt ON nt.id = np.template
+			WHERE np.uid = {:uid} AND nt.code = {:code}
+		`).Bind(dbx.Params{"uid": uid, "code": templateCode}).One(&row)
+		if err != nil {
+			return ""
+		}
+		return row.Mode
+	}
+	pathFor := func(templateCode string) string {
+		link := notifications.BuildUnsubscribeURL(app, uid, templateCode)
+		if link == "" {
+			t.Fatal("expected an unsubscribe link")
+		}
+		return strings.TrimPrefix(link, "https://tybalt.example.com")
+	}
+
+	missing := performClaimsJSONRequest(t, app, http.MethodGet, "/api/notifications/unsubscribe", "", nil)
+	if missing.Code != http.StatusBadRequest {
+		t.Fatalf("missing token status = %d, want %d", missing.Code, http.StatusBadRequest)
+	}
+	tampered := performClaimsJSONRequest(t, app, http.MethodGet, pathFor("timesheet_submission_reminder")+"x", "", nil)
+	if tampered.Code != http.StatusBadRequest {
+		t.Fatalf("tampered token status = %d, want %d", tampered.Code, http.StatusBadRequest)
+	}
+
+	// Following the link, as mail scanners and prefetchers do, only renders a
+	// confirmation form that posts back to the same link.
+	path := pathFor("timesheet_submission_reminder")
+	rec := performClaimsJSONRequest(t, app, http.MethodGet, path, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("confirmation status = %d, want %d; body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if body := rec.Body.String(); !strings.Contains(body, `method="post"`) || !strings.Contains(body, html.EscapeString(path)) {
+		t.Fatalf("expected a confirmation form posting to the link, got %s", body)
+	}
+	if got := modeFor("timesheet_submission_reminder"); got != "" {
+		t.Fatalf("expected GET to leave the preference unchanged, got %q", got)
+	}
+
+	rec = performClaimsJSONRequest(t, app, http.MethodPost, path, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("unsubscribe status = %d, want %d; body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	if got := modeFor("timesheet_submission_reminder"); got != notifications.PreferenceMute {
+		t.Fatalf("expected the reminder to be muted, got %q", got)
+	}
+
+	// Mail clients repeat one-click unsubscribe; repeating it is harmless.
+	rec = performClaimsJSONRequest(t, app, http.MethodPost, path, "", nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("one-click unsubscribe status = %d, want %d; body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+
+	system := performClaimsJSONRequest(t, app, http.MethodPost, pathFor("po_approval_required"), "", nil)
+	if system.Code != http.StatusBadRequest {
+		t.Fatalf("system template unsubscribe status = %d, want %d", system.Code, http.StatusBadRequest)
+	}
+	if got := modeFor("po_approval_required"); got != "" {
+		t.Fatalf("expected no preference for a system template, got %q", got)
+	}
+}
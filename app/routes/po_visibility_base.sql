@@ -27,6 +27,8 @@
     - Policy-based second-stage visibility (eligible second approvers).
   - Actionability (pending queue) is intentionally narrower than visibility:
     - Stage 1 assigned-first-approver path (including assigned approver self-bypass).
+    - Stage 1 approval delegate path (approval_delegations with the
+      purchase_orders scope), excluding the delegate's own purchase orders.
     - Stage 2 priority owner path.
     - Stage 2 fallback after timeout path.
 
@@ -157,6 +159,7 @@ SELECT
   po.rejection_reason,
   po.approver,
   po.approved,
+  COALESCE(po.delegated_approver, '') AS delegated_approver,
   po.second_approver,
   po.second_approval,
   po.canceller,
@@ -228,6 +231,7 @@ SELECT
   END AS remaining_amount,
   COALESCE((p0.given_name || ' ' || p0.surname), '') AS uid_name,
   COALESCE((p1.given_name || ' ' || p1.surname), '') AS approver_name,
+  COALESCE((p5.given_name || ' ' || p5.surname), '') AS delegated_approver_name,
   COALESCE((p2.given_name || ' ' || p2.surname), '') AS second_approver_name,
   COALESCE((p3.given_name || ' ' || p3.surname), '') AS priority_second_approver_name,
   COALESCE((p4.given_name || ' ' || p4.surname), '') AS rejector_name,
@@ -294,6 +298,24 @@ SELECT
           po.rejected = ''
           AND (
             (po.approved = '' AND po.approver = {:userId})
+            -- Active purchase_orders delegates of the assigned approver.
+            OR (
+              po.approved = ''
+              AND EXISTS (
+                SELECT 1
+                FROM approval_delegations ad
+                WHERE
+                  ad.delegator = po.approver
+                  AND ad.delegate = {:userId}
+                  AND ad.start_date <= date('now')
+                  AND ad.end_date >= date('now')
+                  AND EXISTS (
+                    SELECT 1
+                    FROM JSON_EACH(CASE WHEN JSON_VALID(ad.scopes) THEN ad.scopes ELSE '[]' END)
+                    WHERE value = 'purchase_orders'
+                  )
+              )
+            )
             OR (
               po.approved != ''
               AND po.second_approval = ''
@@ -406,6 +428,29 @@ SELECT
               )
           )
         )
+        OR (
+          -- Stage 1 pending for an active purchase_orders delegate of the
+          -- assigned approver. The approve route checks the assigned
+          -- approver's first-stage eligibility on the delegate's behalf.
+          -- Delegates never approve their own purchase orders.
+          po.approved = ''
+          AND po.approver != ''
+          AND po.uid != {:userId}
+          AND EXISTS (
+            SELECT 1
+            FROM approval_delegations ad
+            WHERE
+              ad.delegator = po.approver
+              AND ad.delegate = {:userId}
+              AND ad.start_date <= date('now')
+              AND ad.end_date >= date('now')
+              AND EXISTS (
+                SELECT 1
+                FROM JSON_EACH(CASE WHEN JSON_VALID(ad.scopes) THEN ad.scopes ELSE '[]' END)
+                WHERE value = 'purchase_orders'
+              )
+          )
+        )
         OR (
           -- Stage 2 priority-owner window.
           po.approved != ''
@@ -447,6 +492,7 @@ LEFT JOIN profiles AS p1 ON po.approver = p1.uid
 LEFT JOIN profiles AS p2 ON po.second_approver = p2.uid
 LEFT JOIN profiles AS p3 ON po.priority_second_approver = p3.uid
 LEFT JOIN profiles AS p4 ON po.rejector = p4.uid
+LEFT JOIN profiles AS p5 ON po.delegated_approver = p5.uid
 LEFT JOIN purchase_orders AS po2 ON po.parent_po = po2.id
 LEFT JOIN vendors AS v ON po.vendor = v.id
 LEFT JOIN jobs AS j ON po.job = j.id
@@ -102,6 +102,7 @@ type purchaseOrderVisibilityRow struct {
 	RejectionReason            string  `db:"rejection_reason" json:"rejection_reason"`
 	Approver                   string  `db:"approver" json:"approver"`
 	Approved                   string  `db:"approved" json:"approved"`
+	DelegatedApprover          string  `db:"delegated_approver" json:"delegated_approver"`
 	SecondApprover             string  `db:"second_approver" json:"second_approver"`
 	SecondApproval             string  `db:"second_approval" json:"second_approval"`
 	Canceller                  string  `db:"canceller" json:"canceller"`
@@ -134,6 +135,7 @@ type purchaseOrderVisibilityRow struct {
 	RemainingAmount            float64 `db:"remaining_amount" json:"remaining_amount"`
 	UIDName                    string  `db:"uid_name" json:"uid_name"`
 	ApproverName               string  `db:"approver_name" json:"approver_name"`
+	DelegatedApproverName      string  `db:"delegated_approver_name" json:"delegated_approver_name"`
 	SecondApproverName         string  `db:"second_approver_name" json:"second_approver_name"`
 	PrioritySecondApproverName string  `db:"priority_second_approver_name" json:"priority_second_approver_name"`
 	RejectorName               string  `db:"rejector_name" json:"rejector_name"`
@@ -342,13 +344,35 @@ func createApprovePurchaseOrderHandler(app core.App) func(e *core.RequestEvent)
 						return err
 					}
 				} else {
+					// A delegate of the assigned approver may give the first
+					// approval while the delegation is active. The assigned
+					// approver is kept and the delegate is recorded as
+					// delegated_approver.
+					isDelegated := false
 					if userId != assignedApproverID {
+						isDelegated, err = utilities.HasActiveApprovalDelegation(txApp, assignedApproverID, userId, utilities.ApprovalDelegationScopePurchaseOrders)
+						if err != nil {
+							httpResponseStatusCode = http.StatusInternalServerError
+							return &CodeError{
+								Code:    "error_fetching_approval_delegations",
+								Message: fmt.Sprintf("error fetching approval delegations: %v", err),
+							}
+						}
+					}
+					if userId != assignedApproverID && !isDelegated {
 						httpResponseStatusCode = http.StatusForbidden
 						return &CodeError{
 							Code:    "unauthorized_approval",
 							Message: "you are not authorized to approve this purchase order",
 						}
 					}
+					if isDelegated && po.GetString("uid") == userId {
+						httpResponseStatusCode = http.StatusForbidden
+						return &CodeError{
+							Code:    "delegate_is_owner",
+							Message: "you cannot approve your own purchase order as a delegate",
+						}
+					}
 
 					if recordRequiresSecondApproval && len(policy.SecondStageApprovers) == 0 {
 						httpResponseStatusCode = http.StatusBadRequest
@@ -393,7 +417,11 @@ func createApprovePurchaseOrderHandler(app core.App) func(e *core.RequestEvent)
 					}
 
 					po.Set("approved", now)
-					po.Set("approver", userId)
+					if isDelegated {
+						po.Set("delegated_approver", userId)
+					} else {
+						po.Set("approver", userId)
+					}
 					recordNowFirstApproved = true
 
 					if !recordRequiresSecondApproval {
@@ -825,12 +853,34 @@ func createRejectPurchaseOrderHandler(app core.App) func(e *core.RequestEvent) e
 			}
 			callerIsQualifiedSecondApprover := policy.IsSecondStageApprover(authRecord.Id)
 
-			// If the caller is not an approver or a qualified second approver,
-			// return a 403 Forbidden status. NOTE: This means that even if a
-			// purchase_orders record requiring second approval is already approved,
-			// it can still be rejected by any approver or a qualified second
-			// approver since it isn't yet Active.
+			// A delegate of the assigned approver may reject while the
+			// delegation is active, but not their own purchase order.
+			callerIsDelegate := false
 			if !(callerIsApprover || callerIsQualifiedSecondApprover) {
+				callerIsDelegate, err = utilities.HasActiveApprovalDelegation(txApp, strings.TrimSpace(po.GetString("approver")), userId, utilities.ApprovalDelegationScopePurchaseOrders)
+				if err != nil {
+					httpResponseStatusCode = http.StatusInternalServerError
+					return &CodeError{
+						Code:    "error_fetching_approval_delegations",
+						Message: fmt.Sprintf("error fetching approval delegations: %v", err),
+					}
+				}
+				if callerIsDelegate && po.GetString("uid") == userId {
+					httpResponseStatusCode = http.StatusForbidden
+					return &CodeError{
+						Code:    "delegate_is_owner",
+						Message: "you cannot reject your own purchase order as a delegate",
+					}
+				}
+			}
+
+			// If the caller is not an approver, a delegate of the assigned
+			// approver or a qualified second approver, return a 403 Forbidden
+			// status. NOTE: This means that even if a purchase_orders record
+			// requiring second approval is already approved, it can still be
+			// rejected by any approver or a qualified second approver since it
+			// isn't yet Active.
+			if !(callerIsApprover || callerIsDelegate || callerIsQualifiedSecondApprover) {
 				httpResponseStatusCode = http.StatusForbidden
 				return &CodeError{
 					Code:    "unauthorized_rejection",
@@ -25,6 +25,11 @@ func createRecallRecordHandler(app core.App, collectionName string) func(e *core
 		if err := requireExpensesEditing(app, collectionName); err != nil {
 			return err
 		}
+		if collectionName == "time_off_requests" {
+			if err := requireTimeEditing(app); err != nil {
+				return err
+			}
+		}
 
 		authRecord := e.Auth
 		userId := authRecord.Id
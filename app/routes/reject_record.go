@@ -18,7 +18,9 @@ func createRejectRecordHandler(app core.App, collectionName string) func(e *core
 	// 3. Retrieves the authenticated user's ID.
 	// 4. Runs a database transaction to:
 	//    a. Fetch the record by ID.
-	//    b. Verify that the authenticated user is the assigned approver.
+	//    b. Verify that the authenticated user is the assigned approver,
+	//       holds an active approval delegation from them or, for an
+	//       escalated timesheet, is the user it was escalated to.
 	//    c. Check if the record is submitted and not committed or already rejected.
 	//    d. Set the rejection timestamp, reason, and rejector.
 	//    e. Save the updated record.
@@ -45,151 +47,199 @@ func createRejectRecordHandler(app core.App, collectionName string) func(e *core
 		var httpResponseStatusCode int
 
 		err := app.RunInTransaction(func(txApp core.App) error {
-			record, err := txApp.FindRecordById(collectionName, id)
-			if err != nil {
-				httpResponseStatusCode = http.StatusNotFound
-				return &CodeError{
-					Code:    "record_not_found",
-					Message: fmt.Sprintf("error fetching record: %v", err),
-				}
-			}
+			var err error
+			httpResponseStatusCode, err = rejectRecord(txApp, authRecord, collectionName, id, req.RejectionReason)
+			return err
+		})
 
-			// Check if the user is authorized to reject: approver OR user with commit claim
-			isApprover := record.GetString("approver") == userId
-			hasCommitClaim, err := utilities.HasClaim(txApp, authRecord, "commit")
-			if err != nil {
-				httpResponseStatusCode = http.StatusInternalServerError
-				return &CodeError{
-					Code:    "error_fetching_user_claims",
-					Message: fmt.Sprintf("error fetching user claims: %v", err),
-				}
-			}
-			if !isApprover && !hasCommitClaim {
-				httpResponseStatusCode = http.StatusUnauthorized
-				return &CodeError{
-					Code:    "rejection_unauthorized",
-					Message: "you are not authorized to reject this record",
-				}
+		if err != nil {
+			if codeError, ok := err.(*CodeError); ok {
+				return e.JSON(httpResponseStatusCode, map[string]interface{}{
+					"message": codeError.Message,
+					"code":    codeError.Code,
+				})
 			}
+			return e.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
+		}
 
-			// Commit-claim holders may only reject approved records. Approvers can
-			// still reject submitted records before approval.
-			if !isApprover && hasCommitClaim && record.GetDateTime("approved").IsZero() {
-				httpResponseStatusCode = http.StatusBadRequest
-				return &CodeError{
-					Code:    "record_not_approved",
-					Message: "only approved records can be rejected by a commit user",
-				}
-			}
+		// After successful rejection, send notifications (outside transaction to avoid blocking)
+		queueRejectionNotifications(app, collectionName, id, userId, req.RejectionReason)
 
-			// Check if the record is submitted
-			if !record.GetBool("submitted") {
-				httpResponseStatusCode = http.StatusBadRequest
-				return &CodeError{
-					Code:    "record_not_submitted",
-					Message: "only submitted records can be rejected",
-				}
-			}
+		return e.JSON(http.StatusOK, map[string]string{"message": "record rejected successfully"})
+	}
+}
 
-			// Check if the record is committed
-			if !record.GetDateTime("committed").IsZero() {
-				httpResponseStatusCode = http.StatusBadRequest
-				return &CodeError{
-					Code:    "record_committed",
-					Message: "committed records cannot be rejected",
-				}
-			}
+// rejectRecord rejects the submitted record id in collectionName on behalf of
+// authRecord with rejectionReason. It must run inside a transaction. On
+// failure it returns the HTTP status code for the CodeError describing why
+// the record could not be rejected.
+func rejectRecord(txApp core.App, authRecord *core.Record, collectionName string, id string, rejectionReason string) (int, error) {
+	userId := authRecord.Id
+
+	record, err := txApp.FindRecordById(collectionName, id)
+	if err != nil {
+		return http.StatusNotFound, &CodeError{
+			Code:    "record_not_found",
+			Message: fmt.Sprintf("error fetching record: %v", err),
+		}
+	}
 
-			// Check if the record is already rejected
-			if !record.GetDateTime("rejected").IsZero() {
-				httpResponseStatusCode = http.StatusBadRequest
-				return &CodeError{
-					Code:    "record_already_rejected",
-					Message: "this record is already rejected",
-				}
+	// Check if the user is authorized to reject: approver, a delegate of the
+	// approver OR user with commit claim. Time off requests are never
+	// committed, so time off managers take the commit holder's place for them.
+	isApprover := record.GetString("approver") == userId
+	// A timesheet escalated after waiting too long for approval may also be
+	// rejected by the user it was escalated to, like its approver.
+	if !isApprover && collectionName == "time_sheets" && record.GetString("approval_escalated_to") == userId {
+		isApprover = true
+	}
+	isDelegated := false
+	if !isApprover {
+		isDelegated, err = utilities.HasActiveApprovalDelegation(txApp, record.GetString("approver"), userId, utilities.ApprovalDelegationScopeForCollection(collectionName))
+		if err != nil {
+			return http.StatusInternalServerError, &CodeError{
+				Code:    "error_fetching_approval_delegations",
+				Message: fmt.Sprintf("error fetching approval delegations: %v", err),
 			}
-
-			// Check if the rejection reason is at least 4 characters long
-			if len(req.RejectionReason) < 4 {
-				httpResponseStatusCode = http.StatusBadRequest
-				return &CodeError{
-					Code:    "rejection_reason_too_short",
-					Message: "rejection reason must be at least 4 characters long",
-				}
+		}
+	}
+	overrideClaim := "commit"
+	if collectionName == "time_off_requests" {
+		overrideClaim = "time_off_manager"
+	}
+	hasCommitClaim, err := utilities.HasClaim(txApp, authRecord, overrideClaim)
+	if err != nil {
+		return http.StatusInternalServerError, &CodeError{
+			Code:    "error_fetching_user_claims",
+			Message: fmt.Sprintf("error fetching user claims: %v", err),
+		}
+	}
+	if !isApprover && !isDelegated && !hasCommitClaim {
+		return http.StatusUnauthorized, &CodeError{
+			Code:    "rejection_unauthorized",
+			Message: "you are not authorized to reject this record",
+		}
+	}
+	// Delegates act for the approver and may not reject their own records.
+	// A delegate holding the commit claim is treated as a commit user instead.
+	if isDelegated && record.GetString("uid") == userId {
+		if !hasCommitClaim {
+			return http.StatusForbidden, &CodeError{
+				Code:    "delegate_is_owner",
+				Message: "you cannot reject your own record as a delegate",
 			}
+		}
+		isDelegated = false
+	}
+	// Time off managers may not act on their own requests through the claim.
+	if !isApprover && collectionName == "time_off_requests" && record.GetString("uid") == userId {
+		return http.StatusForbidden, &CodeError{
+			Code:    "time_off_manager_is_owner",
+			Message: "you cannot reject your own time off request as a time off manager",
+		}
+	}
 
-			// Set the rejection timestamp, reason, and rejector
-			record.Set("rejected", time.Now())
-			record.Set("rejection_reason", req.RejectionReason)
-			record.Set("rejector", userId)
-
-			if collectionName == "expenses" {
-				currencyInfo, err := utilities.ResolveCurrencyInfo(txApp, record.GetString("currency"))
-				if err != nil {
-					httpResponseStatusCode = http.StatusBadRequest
-					return &CodeError{
-						Code:    "invalid_currency",
-						Message: "referenced currency not found",
-					}
-				}
-
-				if !utilities.IsHomeCurrencyInfo(currencyInfo) &&
-					(record.GetString("payment_type") == "OnAccount" || record.GetString("payment_type") == "CorporateCreditCard") {
-					record.Set("settled_total", 0)
-					record.Set("settler", "")
-					record.Set("settled", "")
-				}
-			}
+	// Commit-claim holders may only reject approved records. Approvers and
+	// their delegates can still reject submitted records before approval.
+	if !isApprover && !isDelegated && hasCommitClaim && collectionName != "time_off_requests" && record.GetDateTime("approved").IsZero() {
+		return http.StatusBadRequest, &CodeError{
+			Code:    "record_not_approved",
+			Message: "only approved records can be rejected by a commit user",
+		}
+	}
 
-			// Save the updated record
-			if err := txApp.Save(record); err != nil {
-				httpResponseStatusCode = http.StatusInternalServerError
-				return &CodeError{
-					Code:    "record_save_error",
-					Message: fmt.Sprintf("error saving record: %v", err),
-				}
-			}
+	// Check if the record is submitted
+	if !record.GetBool("submitted") {
+		return http.StatusBadRequest, &CodeError{
+			Code:    "record_not_submitted",
+			Message: "only submitted records can be rejected",
+		}
+	}
 
-			return nil
-		})
+	// Check if the record is committed
+	if !record.GetDateTime("committed").IsZero() {
+		return http.StatusBadRequest, &CodeError{
+			Code:    "record_committed",
+			Message: "committed records cannot be rejected",
+		}
+	}
 
+	// Check if the record is already rejected
+	if !record.GetDateTime("rejected").IsZero() {
+		return http.StatusBadRequest, &CodeError{
+			Code:    "record_already_rejected",
+			Message: "this record is already rejected",
+		}
+	}
+
+	// Check if the rejection reason is at least 4 characters long
+	if len(rejectionReason) < 4 {
+		return http.StatusBadRequest, &CodeError{
+			Code:    "rejection_reason_too_short",
+			Message: "rejection reason must be at least 4 characters long",
+		}
+	}
+
+	// Set the rejection timestamp, reason, and rejector
+	record.Set("rejected", time.Now())
+	record.Set("rejection_reason", rejectionReason)
+	record.Set("rejector", userId)
+
+	if collectionName == "expenses" {
+		currencyInfo, err := utilities.ResolveCurrencyInfo(txApp, record.GetString("currency"))
 		if err != nil {
-			if codeError, ok := err.(*CodeError); ok {
-				return e.JSON(httpResponseStatusCode, map[string]interface{}{
-					"message": codeError.Message,
-					"code":    codeError.Code,
-				})
+			return http.StatusBadRequest, &CodeError{
+				Code:    "invalid_currency",
+				Message: "referenced currency not found",
 			}
-			return e.JSON(http.StatusInternalServerError, map[string]string{"error": err.Error()})
 		}
 
-		// After successful rejection, send notifications (outside transaction to avoid blocking)
-		// Reload the record to get the updated values
-		rejectedRecord, err := app.FindRecordById(collectionName, id)
-		if err == nil {
-			// Queue notifications based on collection type
-			switch collectionName {
-			case "time_sheets":
-				// Log error but don't fail the request if notification fails
-				if notifErr := notifications.QueueTimesheetRejectedNotifications(app, rejectedRecord, userId, req.RejectionReason); notifErr != nil {
-					app.Logger().Error(
-						"error queueing timesheet rejection notifications",
-						"timesheet_id", id,
-						"error", notifErr,
-					)
-				}
-			case "expenses":
-				// Log error but don't fail the request if notification fails
-				if notifErr := notifications.QueueExpenseRejectedNotifications(app, rejectedRecord, userId, req.RejectionReason); notifErr != nil {
-					app.Logger().Error(
-						"error queueing expense rejection notifications",
-						"expense_id", id,
-						"error", notifErr,
-					)
-				}
-			}
+		if !utilities.IsHomeCurrencyInfo(currencyInfo) &&
+			(record.GetString("payment_type") == "OnAccount" || record.GetString("payment_type") == "CorporateCreditCard") {
+			record.Set("settled_total", 0)
+			record.Set("settler", "")
+			record.Set("settled", "")
 		}
+	}
 
-		return e.JSON(http.StatusOK, map[string]string{"message": "record rejected successfully"})
+	// Save the updated record
+	if err := txApp.Save(record); err != nil {
+		return http.StatusInternalServerError, &CodeError{
+			Code:    "record_save_error",
+			Message: fmt.Sprintf("error saving record: %v", err),
+		}
+	}
+
+	return http.StatusOK, nil
+}
+
+// queueRejectionNotifications queues the rejection notifications for a record
+// rejected by rejectorId. Failures are logged rather than returned because the
+// rejection has already been saved.
+func queueRejectionNotifications(app core.App, collectionName string, id string, rejectorId string, rejectionReason string) {
+	// Reload the record to get the updated values
+	rejectedRecord, err := app.FindRecordById(collectionName, id)
+	if err == nil {
+		// Queue notifications based on collection type
+		switch collectionName {
+		case "time_sheets":
+			// Log error but don't fail the request if notification fails
+			if notifErr := notifications.QueueTimesheetRejectedNotifications(app, rejectedRecord, rejectorId, rejectionReason); notifErr != nil {
+				app.Logger().Error(
+					"error queueing timesheet rejection notifications",
+					"timesheet_id", id,
+					"error", notifErr,
+				)
+			}
+		case "expenses":
+			// Log error but don't fail the request if notification fails
+			if notifErr := notifications.QueueExpenseRejectedNotifications(app, rejectedRecord, rejectorId, rejectionReason); notifErr != nil {
+				app.Logger().Error(
+					"error queueing expense rejection notifications",
+					"expense_id", id,
+					"error", notifErr,
+				)
+			}
+		}
 	}
 }
@@ -93,6 +93,9 @@ func AddRoutes(app core.App) {
 		tsGroup := se.Router.Group("/api/time_sheets")
 		tsGroup.Bind(apis.RequireAuth("users"))
 		tsGroup.POST("/{weekEnding}/bundle", createBundleTimesheetHandler(app))
+		tsGroup.POST("/approve_batch", createApproveBatchHandler(app, "time_sheets"))
+		tsGroup.POST("/reject_batch", createRejectBatchHandler(app, "time_sheets"))
+		tsGroup.GET("/{weekEnding}/preflight", createTimesheetPreflightHandler(app))
 		tsGroup.POST("/{id}/unbundle", createUnbundleTimesheetHandler(app))
 		tsGroup.POST("/{id}/copy_to_next_week", createCopyTimesheetEntriesNextWeekHandler(app))
 		tsGroup.GET("/{id}/details", createGetTimeSheetDetailsHandler(app))
@@ -116,6 +119,18 @@ func AddRoutes(app core.App) {
 		tsGroup.DELETE("/tracking/weeks/{weekEnding}/missing_exceptions/{uid}", createDeleteMissingTimesheetExceptionHandler(app))
 		tsGroup.GET("/tracking/weeks/{weekEnding}/not_expected", createTimesheetNotExpectedHandler(app))
 
+		// Time off balances (OP/OV) derived from opening values and committed time
+		timeOffGroup := se.Router.Group("/api/time_off")
+		timeOffGroup.Bind(apis.RequireAuth("users"))
+		timeOffGroup.GET("/balances", createOwnTimeOffBalanceHandler(app))
+		timeOffGroup.GET("/balances/list", createListTimeOffBalancesHandler(app))
+		timeOffGroup.GET("/balances/{uid}", createUserTimeOffBalanceHandler(app))
+		timeOffGroup.GET("/calendar", createTimeOffCalendarHandler(app))
+		timeOffGroup.POST("/requests/{id}/submit", createSubmitRecordHandler(app, "time_off_requests"))
+		timeOffGroup.POST("/requests/{id}/recall", createRecallRecordHandler(app, "time_off_requests"))
+		timeOffGroup.POST("/requests/{id}/approve", createApproveRecordHandler(app, "time_off_requests"))
+		timeOffGroup.POST("/requests/{id}/reject", createRejectRecordHandler(app, "time_off_requests"))
+
 		// Legacy writeback endpoints (custom auth via machine_secrets)
 		se.Router.GET("/api/export_legacy/time_sheets/{weekEnding}", createTimesheetExportLegacyHandler(app))
 		se.Router.GET("/api/export_legacy/jobs/{updatedAfter}", createJobsExportLegacyHandler(app))
@@ -128,6 +143,8 @@ func AddRoutes(app core.App) {
 		expensesGroup.POST("/{id}/submit", createSubmitRecordHandler(app, "expenses"))
 		expensesGroup.POST("/{id}/recall", createRecallRecordHandler(app, "expenses"))
 		expensesGroup.POST("/{id}/approve", createApproveRecordHandler(app, "expenses"))
+		expensesGroup.POST("/approve_batch", createApproveBatchHandler(app, "expenses"))
+		expensesGroup.POST("/reject_batch", createRejectBatchHandler(app, "expenses"))
 		expensesGroup.POST("/{id}/reject", createRejectRecordHandler(app, "expenses"))
 		expensesGroup.POST("/{id}/commit", createCommitRecordHandler(app, "expenses"))
 		expensesGroup.POST("/{id}/uncommit", createUncommitRecordHandler(app, "expenses"))
@@ -137,6 +154,7 @@ func AddRoutes(app core.App) {
 		expensesGroup.POST("/{id}/attachment_hash/audit", createAuditExpenseAttachmentHashHandler(app))
 		expensesGroup.POST("/{id}/attachment_hash/replace", createReplaceExpenseAttachmentHashHandler(app))
 		expensesGroup.POST("/{id}/attachment_missing/mark", createMarkExpenseAttachmentMissingHandler(app))
+		expensesGroup.POST("/fuel_card_import", createImportFuelCardTransactionsHandler(app))
 		expensesGroup.GET("/pending", createGetPendingExpensesHandler(app))
 		expensesGroup.GET("/approved", createGetApprovedExpensesHandler(app))
 		// Expense tracking endpoints plus the org-wide expense commit queue.
@@ -147,15 +165,30 @@ func AddRoutes(app core.App) {
 		expensesGroup.POST("/{id}/settle", createSettleExpenseHandler(app))
 		expensesGroup.POST("/{id}/clear_settlement", createClearExpenseSettlementHandler(app))
 		expensesGroup.GET("/tracking/{committedWeekEnding}", createExpenseTrackingListHandler(app))
+		expensesGroup.GET("/mileage/{uid}/{year}", createGetMileageSummaryHandler(app))
+		expensesGroup.GET("/mileage/{uid}/{year}/logbook", createGetMileageLogbookHandler(app))
+
+		// Corporate credit card statement reconciliation routes
+		cardStatementsGroup := se.Router.Group("/api/card_statements")
+		cardStatementsGroup.Bind(apis.RequireAuth("users"))
+		cardStatementsGroup.GET("", createCardStatementListHandler(app))
+		cardStatementsGroup.POST("/import", createImportCardStatementHandler(app))
+		cardStatementsGroup.GET("/{id}", createCardStatementDetailsHandler(app))
+		cardStatementsGroup.POST("/{id}/match", createMatchCardStatementHandler(app))
+		cardStatementsGroup.POST("/{id}/remind", createRemindCardStatementHandler(app))
+		cardStatementsGroup.POST("/{id}/lines/{lineId}/confirm", createConfirmCardStatementLineHandler(app))
+		cardStatementsGroup.POST("/{id}/lines/{lineId}/unmatch", createUnmatchCardStatementLineHandler(app))
 
 		timeAmendmentsGroup := se.Router.Group("/api/time_amendments")
 		timeAmendmentsGroup.Bind(apis.RequireAuth("users"))
 		timeAmendmentsGroup.POST("/{id}/commit", createCommitRecordHandler(app, "time_amendments"))
+		timeAmendmentsGroup.POST("/import", createImportTimeAmendmentsHandler(app))
 
 		// Time Entries routes
 		timeEntriesGroup := se.Router.Group("/api/time_entries")
 		timeEntriesGroup.Bind(apis.RequireAuth("users"))
 		timeEntriesGroup.POST("/{id}/copy_to_tomorrow", createCopyTimeEntryHandler(app))
+		timeEntriesGroup.POST("/templates/{weekEnding}/apply", createApplyTimeEntryTemplatesHandler(app))
 
 		usersGroup := se.Router.Group("/api/users")
 		usersGroup.Bind(apis.RequireAuth("users"))
@@ -247,6 +280,7 @@ func AddRoutes(app core.App) {
 		// time_type, user, or category.
 		jobsGroup.GET("/{id}/time/summary", createGetJobTimeSummaryHandler(app))
 		jobsGroup.GET("/{id}/time/full_report", reports.CreateJobTimeReportHandler(app))
+		jobsGroup.GET("/{id}/time/full_report.pdf", reports.CreateJobTimeReportPDFHandler(app))
 		jobsGroup.GET("/{id}/time/entries", createGetJobTimeEntriesHandler(app))
 		jobsGroup.GET("/{id}/staff/summary", createGetJobStaffSummaryHandler(app))
 		jobsGroup.GET("/{id}/divisions/summary", createGetJobDivisionsSummaryHandler(app))
@@ -254,6 +288,12 @@ func AddRoutes(app core.App) {
 		jobsGroup.GET("/{id}/expenses/list", createGetJobExpensesHandler(app))
 		jobsGroup.GET("/{id}/pos/summary", createGetJobPOSummaryHandler(app))
 		jobsGroup.GET("/{id}/pos/list", createGetJobPOsHandler(app))
+		// Invoicing-eligible POs (Active or Closed project POs) and their
+		// append-only invoicing records.
+		jobsGroup.GET("/{id}/pos/invoicing", createGetJobInvoicingPOsHandler(app))
+		jobsGroup.GET("/{id}/pos/invoicing/summary", createGetJobInvoicingPOSummaryHandler(app))
+		jobsGroup.GET("/{id}/pos/invoicing/{po}/records", createGetPOInvoicingRecordsHandler(app))
+		jobsGroup.POST("/{id}/pos/invoicing/{po}/records", createCreatePOInvoicingRecordHandler(app))
 		jobsGroup.GET("/{id}", createGetJobsHandler(app))
 		jobsGroup.GET("", createGetJobsHandler(app))
 		jobsGroup.GET("/unused", createGetUnusedJobsHandler(app))
@@ -268,6 +308,12 @@ func AddRoutes(app core.App) {
 		jobsGroup.POST("/{id}/project_authorization/approve", createApproveProjectAuthorizationHandler(app))
 		jobsGroup.POST("/{id}/project_authorization/reject", createRejectProjectAuthorizationHandler(app))
 		jobsGroup.POST("/{id}/project_authorization/revoke", createRevokeProjectAuthorizationHandler(app))
+		jobsGroup.GET("/{id}/client_agreements", createGetClientAgreementsHandler(app))
+		jobsGroup.POST("/{id}/client_agreements", createUploadClientAgreementHandler(app))
+		jobsGroup.GET("/{id}/client_agreements/{agreement}/file", createGetClientAgreementFileHandler(app))
+		jobsGroup.DELETE("/{id}/client_agreements/{agreement}", createDeleteClientAgreementHandler(app))
+		jobsGroup.POST("/client_agreements/{id}/hash/audit", createAuditClientAgreementHashHandler(app))
+		jobsGroup.POST("/client_agreements/{id}/hash/replace", createReplaceClientAgreementHashHandler(app))
 		jobsGroup.GET("/{id}/validate-proposal", createValidateProposalHandler(app))
 
 		reportsGroup := se.Router.Group("/api/reports")
@@ -311,6 +357,17 @@ func AddRoutes(app core.App) {
 		attachmentAuditGroup.GET("/targets/{target}/missing.csv", createDownloadAttachmentAuditReportHandler(app, "missing_report"))
 		attachmentAuditGroup.GET("/targets/{target}/orphaned.csv", createDownloadAttachmentAuditReportHandler(app, "orphaned_report"))
 
+		// Failed notification review and requeue (admin only)
+		notificationsGroup := se.Router.Group("/api/notifications")
+		notificationsGroup.Bind(apis.RequireAuth("users"))
+		notificationsGroup.GET("/failed", createListFailedNotificationsHandler(app))
+		notificationsGroup.POST("/{id}/requeue", createRequeueNotificationHandler(app))
+
+		// Unsubscribe links from notification emails (signed token, no auth). GET
+		// only renders a confirmation form; POST mutes the template.
+		se.Router.GET("/api/notifications/unsubscribe", createUnsubscribeConfirmationHandler(app))
+		se.Router.POST("/api/notifications/unsubscribe", createUnsubscribeNotificationHandler(app))
+
 		currenciesGroup := se.Router.Group("/api/currencies")
 		currenciesGroup.Bind(apis.RequireAuth("users"))
 		currenciesGroup.GET("", createGetCurrenciesHandler(app))
@@ -338,6 +395,20 @@ func AddRoutes(app core.App) {
 		workRecordsGroup.Bind(apis.RequireAuth("users"))
 		workRecordsGroup.GET("", createGetWorkRecordsHandler(app))
 		workRecordsGroup.GET("/{workRecord}", createGetWorkRecordDetailsHandler(app))
+		workRecordsGroup.GET("/{workRecord}/pdf", createGetWorkRecordPDFHandler(app))
+
+		// First-class work records. Writes go through these routes because the
+		// collections have no API write rules. They have their own group so
+		// their paths cannot overlap the legacy /{workRecord} routes above.
+		firstClassWorkRecordsGroup := se.Router.Group("/api/first_class_work_records")
+		firstClassWorkRecordsGroup.Bind(apis.RequireAuth("users"))
+		firstClassWorkRecordsGroup.POST("", createCreateWorkRecordHandler(app))
+		firstClassWorkRecordsGroup.GET("/{id}", createGetWorkRecordHandler(app))
+		firstClassWorkRecordsGroup.PUT("/{id}", createUpdateWorkRecordHandler(app))
+		firstClassWorkRecordsGroup.POST("/{id}/notes", createAddWorkRecordNoteHandler(app))
+		firstClassWorkRecordsGroup.POST("/{id}/attachment", createUploadWorkRecordAttachmentHandler(app))
+		firstClassWorkRecordsGroup.DELETE("/{id}/attachment", createDeleteWorkRecordAttachmentHandler(app))
+		firstClassWorkRecordsGroup.PATCH("/subjects/{id}", createUpdateWorkRecordSubjectHandler(app))
 
 		// Rate sheet entries management (admin claim required)
 		rateSheetEntriesGroup := se.Router.Group("/api/rate_sheet_entries")
@@ -26,6 +26,11 @@ func createSubmitRecordHandler(app core.App, collectionName string) func(e *core
 		if err := requireExpensesEditing(app, collectionName); err != nil {
 			return err
 		}
+		if collectionName == "time_off_requests" {
+			if err := requireTimeEditing(app); err != nil {
+				return err
+			}
+		}
 
 		authRecord := e.Auth
 		userId := authRecord.Id
@@ -130,7 +135,7 @@ func createSubmitRecordHandler(app core.App, collectionName string) func(e *core
 
 			// Set submitted to true
 			record.Set("submitted", true)
-			if collectionName == "expenses" && record.GetString("uid") == userId && record.GetString("approver") == userId {
+			if (collectionName == "expenses" || collectionName == "time_off_requests") && record.GetString("uid") == userId && record.GetString("approver") == userId {
 				record.Set("approved", time.Now())
 			}
 
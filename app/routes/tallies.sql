@@ -1,7 +1,7 @@
 -- Parameterized tallies query
 -- Params:
 --   :uid           - the caller id (required)
---   :role          - either 'uid' (list by owner) or 'approver' (list by approver) or 'reviewer' (list sheets shared with the caller) (default 'uid')
+--   :role          - either 'uid' (list by owner) or 'approver' (list by approver, including sheets the caller approves as an approval delegate or that were escalated to the caller) or 'reviewer' (list sheets shared with the caller) (default 'uid')
 --   :pendingOnly   - 1 to only include sheets with ts.approved = '' (default 0)
 --   :approvedOnly  - 1 to only include sheets with ts.approved != '' (default 0)
 -- The WHERE clause dynamically applies filters based on the above flags.
@@ -16,11 +16,16 @@ SELECT
   MAX(ts.rejection_reason) rejection_reason, 
   MAX(ts.approved) approved,
   MAX(ts.approver) approver,
+  MAX(ts.delegated_approver) delegated_approver,
+  MAX(ts.approval_escalated) approval_escalated,
+  MAX(COALESCE(ts.approval_escalated_to, '')) approval_escalated_to,
   MAX(COALESCE(ts.committer, '')) committer,
   MAX(ts.committed) committed,
   MAX(COALESCE(p.given_name, '')) given_name,
   MAX(COALESCE(p.surname, '')) surname,
   MAX(COALESCE(ap.given_name || ' ' || ap.surname, '')) approver_name,
+  MAX(COALESCE(dp.given_name || ' ' || dp.surname, '')) delegated_approver_name,
+  MAX(COALESCE(ep.given_name || ' ' || ep.surname, '')) approval_escalated_to_name,
   MAX(COALESCE(cp.given_name || ' ' || cp.surname, '')) committer_name,
   MAX(COALESCE(rp.given_name || ' ' || rp.surname, '')) rejector_name,
   MAX(COALESCE(tsrc.shared_reviewer_count, 0)) shared_reviewer_count,
@@ -52,6 +57,8 @@ LEFT JOIN time_types tt ON te.time_type = tt.id
 LEFT JOIN jobs j ON te.job = j.id
 LEFT JOIN profiles p ON te.uid = p.uid
 LEFT JOIN profiles ap ON ts.approver = ap.uid
+LEFT JOIN profiles dp ON ts.delegated_approver = dp.uid
+LEFT JOIN profiles ep ON ts.approval_escalated_to = ep.uid
 LEFT JOIN profiles cp ON ts.committer = cp.uid
 LEFT JOIN profiles rp ON ts.rejector = rp.uid
 LEFT JOIN (
@@ -64,6 +71,24 @@ LEFT JOIN (
 WHERE (
   ( {:role} = 'uid'      AND te.uid      = {:uid} ) OR
   ( {:role} = 'approver' AND ts.approver = {:uid} ) OR
+  -- caller approved the sheet, or may approve it, as a delegate of the approver;
+  -- delegates never approve their own sheets
+  ( {:role} = 'approver' AND (
+      ts.delegated_approver = {:uid}
+      OR ( ts.approved = '' AND ts.uid != {:uid} AND EXISTS (
+        SELECT 1 FROM approval_delegations ad
+        WHERE ad.delegator = ts.approver
+          AND ad.delegate  = {:uid}
+          AND ad.start_date <= date('now')
+          AND ad.end_date   >= date('now')
+          AND EXISTS (
+            SELECT 1 FROM json_each(CASE WHEN json_valid(ad.scopes) THEN ad.scopes ELSE '[]' END)
+            WHERE value = 'time'
+          )
+      ) )
+  ) ) OR
+  -- the sheet waited too long for approval and was escalated to the caller
+  ( {:role} = 'approver' AND ts.approval_escalated_to = {:uid} ) OR
   -- caller is explicitly listed as a reviewer on the time_sheet
   ( {:role} = 'reviewer' AND EXISTS (
       SELECT 1 FROM time_sheet_reviewers tsr
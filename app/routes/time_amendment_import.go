@@ -0,0 +1,441 @@
+package routes
+
+import (
+	"database/sql"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+
+	"tybalt/errs"
+	"tybalt/hooks"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/apis"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// maxTimeAmendmentImportRows caps the number of amendments one import may
+// create.
+const maxTimeAmendmentImportRows = 500
+
+// timeAmendmentImportColumns are the CSV columns an import may contain. Users
+// are identified by uid or payroll_id, jobs by number and divisions and time
+// types by code.
+var timeAmendmentImportColumns = []string{
+	"uid",
+	"payroll_id",
+	"date",
+	"job",
+	"division",
+	"time_type",
+	"hours",
+	"meals_hours",
+	"description",
+	"work_record",
+	"payout_request_amount",
+	"skip_tsid_check",
+}
+
+// errTimeAmendmentImportRollback rolls back a dry run, or a confirmed import
+// with at least one invalid row, after every row was validated and saved.
+var errTimeAmendmentImportRollback = errors.New("time amendment import rolled back")
+
+// timeAmendmentImportError is one problem with one field of an imported row.
+type timeAmendmentImportError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// timeAmendmentImportRow is the outcome for one CSV row. Row is the CSV line
+// number, so the header is row 1. ID is only set once the import is
+// confirmed.
+type timeAmendmentImportRow struct {
+	Row    int                        `json:"row"`
+	ID     string                     `json:"id,omitempty"`
+	UID    string                     `json:"uid"`
+	Date   string                     `json:"date"`
+	Errors []timeAmendmentImportError `json:"errors"`
+}
+
+type timeAmendmentImportResponse struct {
+	DryRun       bool                     `json:"dry_run"`
+	RowCount     int                      `json:"row_count"`
+	InvalidCount int                      `json:"invalid_count"`
+	CreatedCount int                      `json:"created_count"`
+	Rows         []timeAmendmentImportRow `json:"rows"`
+}
+
+// timeAmendmentImportLine is one parsed CSV data row keyed by column name.
+type timeAmendmentImportLine struct {
+	Row    int
+	Values map[string]string
+}
+
+func timeAmendmentImportFileError(code string, message string) error {
+	return &errs.HookError{
+		Status:  http.StatusBadRequest,
+		Message: message,
+		Data: map[string]errs.CodeError{
+			"file": {Code: code, Message: message},
+		},
+	}
+}
+
+// createImportTimeAmendmentsHandler imports time amendments from the CSV
+// uploaded as the "file" form field. Each row is resolved to record ids and
+// goes through the same cleaning and validation as a time amendment created
+// through the collection API. Unless the "confirm" form field is "true" the
+// import is a dry run: every row is checked and the report is returned but
+// nothing is saved. A confirmed import saves all rows in one transaction, or
+// none of them when any row is invalid.
+func createImportTimeAmendmentsHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireTimeEditing(app); err != nil {
+			return err
+		}
+
+		hasTame, err := utilities.HasClaim(app, e.Auth, "tame")
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to check claims", err)
+		}
+		if !hasTame {
+			return writeHookError(e, &errs.HookError{
+				Status:  http.StatusForbidden,
+				Message: "you are not authorized to import time amendments",
+				Data: map[string]errs.CodeError{
+					"global": {Code: "unauthorized", Message: "you are not authorized to import time amendments"},
+				},
+			})
+		}
+
+		files, err := e.FindUploadedFiles("file")
+		if err != nil && !errors.Is(err, http.ErrMissingFile) {
+			return e.BadRequestError("failed to read uploaded file", err)
+		}
+		if len(files) != 1 {
+			return writeHookError(e, timeAmendmentImportFileError("required", "upload exactly one CSV file"))
+		}
+		reader, err := files[0].Reader.Open()
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to open uploaded file", err)
+		}
+		defer reader.Close()
+
+		lines, err := readTimeAmendmentImportCSV(reader)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+
+		response := timeAmendmentImportResponse{
+			DryRun:   e.Request.FormValue("confirm") != "true",
+			RowCount: len(lines),
+		}
+		err = app.RunInTransaction(func(txApp core.App) error {
+			rows, err := importTimeAmendmentLines(txApp, e.Auth, lines)
+			if err != nil {
+				return err
+			}
+			response.Rows = rows
+			for _, row := range rows {
+				if len(row.Errors) > 0 {
+					response.InvalidCount++
+				}
+			}
+			if response.DryRun || response.InvalidCount > 0 {
+				return errTimeAmendmentImportRollback
+			}
+			return nil
+		})
+		if err != nil && !errors.Is(err, errTimeAmendmentImportRollback) {
+			return e.Error(http.StatusInternalServerError, "failed to import time amendments", err)
+		}
+
+		if response.DryRun || response.InvalidCount > 0 {
+			for i := range response.Rows {
+				response.Rows[i].ID = ""
+			}
+		}
+		if !response.DryRun && response.InvalidCount > 0 {
+			return e.JSON(http.StatusUnprocessableEntity, response)
+		}
+		if !response.DryRun {
+			response.CreatedCount = len(response.Rows)
+		}
+		return e.JSON(http.StatusOK, response)
+	}
+}
+
+// readTimeAmendmentImportCSV parses the uploaded CSV. The header row names
+// the columns in any order; it must identify the user with uid or payroll_id
+// and include date and time_type.
+func readTimeAmendmentImportCSV(reader io.Reader) ([]timeAmendmentImportLine, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, timeAmendmentImportFileError("no_rows", "the CSV file has no rows")
+	}
+	if err != nil {
+		return nil, timeAmendmentImportFileError("invalid_csv", fmt.Sprintf("the CSV file could not be read: %v", err))
+	}
+	columns := make([]string, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		if !slices.Contains(timeAmendmentImportColumns, name) {
+			return nil, timeAmendmentImportFileError("unknown_column", fmt.Sprintf("unknown column %q", header[i]))
+		}
+		if slices.Contains(columns, name) {
+			return nil, timeAmendmentImportFileError("duplicate_column", fmt.Sprintf("column %q appears more than once", name))
+		}
+		columns[i] = name
+	}
+	if !slices.Contains(columns, "uid") && !slices.Contains(columns, "payroll_id") {
+		return nil, timeAmendmentImportFileError("missing_column", "a uid or payroll_id column is required")
+	}
+	for _, required := range []string{"date", "time_type"} {
+		if !slices.Contains(columns, required) {
+			return nil, timeAmendmentImportFileError("missing_column", fmt.Sprintf("a %s column is required", required))
+		}
+	}
+
+	lines := []timeAmendmentImportLine{}
+	for {
+		fields, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, timeAmendmentImportFileError("invalid_csv", fmt.Sprintf("the CSV file could not be read: %v", err))
+		}
+		row, _ := csvReader.FieldPos(0)
+		values := map[string]string{}
+		for i, column := range columns {
+			if i < len(fields) {
+				values[column] = strings.TrimSpace(fields[i])
+			}
+		}
+		lines = append(lines, timeAmendmentImportLine{Row: row, Values: values})
+		if len(lines) > maxTimeAmendmentImportRows {
+			return nil, timeAmendmentImportFileError("too_many_rows", fmt.Sprintf("an import can include at most %d rows", maxTimeAmendmentImportRows))
+		}
+	}
+	if len(lines) == 0 {
+		return nil, timeAmendmentImportFileError("no_rows", "the CSV file has no rows")
+	}
+	return lines, nil
+}
+
+// importTimeAmendmentLines validates and saves each line as a time amendment
+// created by authRecord. It must run inside a transaction, which the caller
+// rolls back unless every row is valid and the import is confirmed. A row's
+// problems are reported on the row; only unexpected database errors are
+// returned.
+func importTimeAmendmentLines(txApp core.App, authRecord *core.Record, lines []timeAmendmentImportLine) ([]timeAmendmentImportRow, error) {
+	collection, err := txApp.FindCollectionByNameOrId("time_amendments")
+	if err != nil {
+		return nil, err
+	}
+
+	rows := make([]timeAmendmentImportRow, 0, len(lines))
+	for _, line := range lines {
+		row := timeAmendmentImportRow{
+			Row:    line.Row,
+			Date:   line.Values["date"],
+			Errors: []timeAmendmentImportError{},
+		}
+
+		record, rowErrors, err := buildImportedTimeAmendment(txApp, collection, line.Values)
+		if err != nil {
+			return nil, err
+		}
+		row.UID = record.GetString("uid")
+		row.Errors = rowErrors
+		if len(row.Errors) == 0 {
+			if err := hooks.PrepareTimeAmendment(txApp, record, authRecord); err != nil {
+				row.Errors = timeAmendmentImportErrors(err)
+			} else if err := txApp.Save(record); err != nil {
+				row.Errors = timeAmendmentImportErrors(err)
+			} else {
+				row.ID = record.Id
+			}
+		}
+		rows = append(rows, row)
+	}
+	return rows, nil
+}
+
+// buildImportedTimeAmendment resolves the user, job, division and time type
+// of one CSV row to ids and returns a new time_amendments record. The branch
+// is the job's branch, or the user's default branch when there is no job.
+func buildImportedTimeAmendment(txApp core.App, collection *core.Collection, values map[string]string) (*core.Record, []timeAmendmentImportError, error) {
+	record := core.NewRecord(collection)
+	rowErrors := []timeAmendmentImportError{}
+	addError := func(field string, code string, message string) {
+		rowErrors = append(rowErrors, timeAmendmentImportError{Field: field, Code: code, Message: message})
+	}
+
+	// lookup returns the record in collectionName whose field equals value, or
+	// nil when there is none.
+	lookup := func(collectionName string, field string, value string) (*core.Record, error) {
+		found, err := txApp.FindFirstRecordByFilter(collectionName, field+" = {:value}", dbx.Params{"value": value})
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		return found, err
+	}
+
+	uid := values["uid"]
+	var adminProfile *core.Record
+	if payrollID := values["payroll_id"]; payrollID != "" {
+		profile, err := lookup("admin_profiles", "payroll_id", payrollID)
+		if err != nil {
+			return nil, nil, err
+		}
+		switch {
+		case profile == nil:
+			addError("payroll_id", "unknown_payroll_id", fmt.Sprintf("no user has payroll id %s", payrollID))
+		case uid != "" && uid != profile.GetString("uid"):
+			addError("payroll_id", "uid_payroll_id_mismatch", "uid and payroll_id belong to different users")
+		default:
+			uid = profile.GetString("uid")
+			adminProfile = profile
+		}
+	} else if uid == "" {
+		addError("uid", "missing_user", "a uid or payroll_id is required")
+	}
+	if uid != "" && adminProfile == nil && len(rowErrors) == 0 {
+		profile, err := lookup("admin_profiles", "uid", uid)
+		if err != nil {
+			return nil, nil, err
+		}
+		if profile == nil {
+			addError("uid", "unknown_user", fmt.Sprintf("no user has uid %s", uid))
+		}
+		adminProfile = profile
+	}
+	record.Set("uid", uid)
+
+	if code := values["time_type"]; code != "" {
+		timeType, err := lookup("time_types", "code", code)
+		if err != nil {
+			return nil, nil, err
+		}
+		if timeType == nil {
+			addError("time_type", "unknown_time_type", fmt.Sprintf("no time type has code %s", code))
+		} else {
+			record.Set("time_type", timeType.Id)
+		}
+	} else {
+		addError("time_type", "missing_time_type", "a time type is required")
+	}
+
+	branch := ""
+	if number := values["job"]; number != "" {
+		job, err := lookup("jobs", "number", number)
+		if err != nil {
+			return nil, nil, err
+		}
+		if job == nil {
+			addError("job", "unknown_job", fmt.Sprintf("no job has number %s", number))
+		} else {
+			record.Set("job", job.Id)
+			branch = job.GetString("branch")
+		}
+	} else if adminProfile != nil {
+		branch = adminProfile.GetString("default_branch")
+	}
+	if branch == "" && len(rowErrors) == 0 {
+		addError("branch", "missing_branch", "the job or the user's default branch is required to set the branch")
+	}
+	record.Set("branch", branch)
+
+	if code := values["division"]; code != "" {
+		division, err := lookup("divisions", "code", code)
+		if err != nil {
+			return nil, nil, err
+		}
+		if division == nil {
+			addError("division", "unknown_division", fmt.Sprintf("no division has code %s", code))
+		} else {
+			record.Set("division", division.Id)
+		}
+	}
+
+	for _, field := range []string{"hours", "meals_hours", "payout_request_amount"} {
+		value := values[field]
+		if value == "" {
+			continue
+		}
+		number, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			addError(field, "invalid_number", fmt.Sprintf("%s must be a number", field))
+			continue
+		}
+		record.Set(field, number)
+	}
+
+	if value := values["skip_tsid_check"]; value != "" {
+		skip, err := strconv.ParseBool(value)
+		if err != nil {
+			addError("skip_tsid_check", "invalid_boolean", "skip_tsid_check must be true or false")
+		}
+		record.Set("skip_tsid_check", skip)
+	}
+
+	record.Set("date", values["date"])
+	record.Set("description", values["description"])
+	record.Set("work_record", values["work_record"])
+
+	return record, rowErrors, nil
+}
+
+// timeAmendmentImportErrors flattens a validation or save error into the
+// field errors reported for an imported row.
+func timeAmendmentImportErrors(err error) []timeAmendmentImportError {
+	var hookErr *errs.HookError
+	if errors.As(err, &hookErr) {
+		rowErrors := []timeAmendmentImportError{}
+		for field, codeError := range hookErr.Data {
+			rowErrors = append(rowErrors, timeAmendmentImportError{Field: field, Code: codeError.Code, Message: codeError.Message})
+		}
+		if len(rowErrors) == 0 {
+			rowErrors = append(rowErrors, timeAmendmentImportError{Field: "global", Code: "invalid", Message: hookErr.Message})
+		}
+		sortTimeAmendmentImportErrors(rowErrors)
+		return rowErrors
+	}
+
+	apiErr := apis.ToApiError(err)
+	rowErrors := []timeAmendmentImportError{}
+	for field, value := range apiErr.Data {
+		item, _ := value.(map[string]any)
+		code, _ := item["code"].(string)
+		message, _ := item["message"].(string)
+		if code == "" {
+			code = "invalid"
+			message = apiErr.Message
+		}
+		rowErrors = append(rowErrors, timeAmendmentImportError{Field: field, Code: code, Message: message})
+	}
+	if len(rowErrors) == 0 {
+		rowErrors = append(rowErrors, timeAmendmentImportError{Field: "global", Code: "invalid", Message: apiErr.Message})
+	}
+	sortTimeAmendmentImportErrors(rowErrors)
+	return rowErrors
+}
+
+func sortTimeAmendmentImportErrors(rowErrors []timeAmendmentImportError) {
+	sort.Slice(rowErrors, func(i, j int) bool {
+		return rowErrors[i].Field < rowErrors[j].Field
+	})
+}
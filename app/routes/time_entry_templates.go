@@ -0,0 +1,247 @@
+package routes
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"tybalt/errs"
+	"tybalt/hooks"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tools/list"
+)
+
+type skippedTimeEntryTemplate struct {
+	TemplateID string `json:"template_id"`
+	Date       string `json:"date"`
+	Reason     string `json:"reason"`
+}
+
+type applyTimeEntryTemplatesResponse struct {
+	Message      string                     `json:"message"`
+	WeekEnding   string                     `json:"week_ending"`
+	CreatedCount int                        `json:"created_count"`
+	NewRecordIDs []string                   `json:"new_record_ids"`
+	Skipped      []skippedTimeEntryTemplate `json:"skipped"`
+}
+
+// templateWeekday returns the time_entry_templates weekdays value for t.
+func templateWeekday(t time.Time) string {
+	return strings.ToLower(t.Weekday().String()[:3])
+}
+
+// statutoryHolidayDates returns the dates in the week that are statutory
+// holidays for the user: those observed by their default branch and those on
+// which they already entered statutory holiday (OH) time.
+func statutoryHolidayDates(app core.App, userID string, weekEnding string) (map[string]bool, error) {
+	var rows []struct {
+		Date string `db:"date"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT DISTINCT te.date
+		FROM time_entries te
+		JOIN time_types tt ON tt.id = te.time_type
+		WHERE te.uid = {:uid}
+		  AND te.week_ending = {:weekEnding}
+		  AND tt.code = 'OH'
+	`).Bind(dbx.Params{
+		"uid":        userID,
+		"weekEnding": weekEnding,
+	}).All(&rows)
+	if err != nil {
+		return nil, err
+	}
+	dates := make(map[string]bool, len(rows))
+	for _, row := range rows {
+		dates[row.Date] = true
+	}
+
+	adminProfile, err := app.FindFirstRecordByFilter("admin_profiles", "uid={:uid}", dbx.Params{"uid": userID})
+	if err != nil {
+		return nil, err
+	}
+	holidays, err := utilities.StatutoryHolidaysInWeek(app, adminProfile.GetString("default_branch"), weekEnding)
+	if err != nil {
+		return nil, err
+	}
+	for _, holiday := range holidays {
+		dates[holiday.Date] = true
+	}
+	return dates, nil
+}
+
+// timeEntryTemplateKey identifies the entry a template produces on a date so
+// applying the same week twice does not create duplicates.
+func timeEntryTemplateKey(record *core.Record, date string) string {
+	return strings.Join([]string{
+		date,
+		record.GetString("time_type"),
+		record.GetString("job"),
+		record.GetString("division"),
+	}, "|")
+}
+
+// createApplyTimeEntryTemplatesHandler materializes the caller's time entry
+// templates into time_entries for the week ending on the given Saturday. Each
+// generated entry goes through ProcessTimeEntry, and any failure rolls back
+// the whole week. Days with statutory holiday time and entries that already
+// exist are skipped and reported.
+func createApplyTimeEntryTemplatesHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireTimeEditing(app); err != nil {
+			return err
+		}
+		if err := requireTimeClaim(app, e.Auth); err != nil {
+			return err
+		}
+
+		weekEndingTime, err := time.Parse(time.DateOnly, e.Request.PathValue("weekEnding"))
+		if err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+		}
+		if weekEndingTime.Weekday() != time.Saturday {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": "Week ending date must be a Saturday"})
+		}
+		weekEnding := weekEndingTime.Format(time.DateOnly)
+
+		authRecord := e.Auth
+		userID := authRecord.Id
+		var httpResponseStatusCode int
+		var response applyTimeEntryTemplatesResponse
+
+		err = app.RunInTransaction(func(txApp core.App) error {
+			existingTimeSheet, err := txApp.FindFirstRecordByFilter("time_sheets", "uid={:userID} && week_ending={:weekEnding}", dbx.Params{
+				"userID":     userID,
+				"weekEnding": weekEnding,
+			})
+			if err != nil && !errors.Is(err, sql.ErrNoRows) {
+				httpResponseStatusCode = http.StatusInternalServerError
+				return &CodeError{
+					Code:    "error_checking_time_sheet",
+					Message: fmt.Sprintf("error checking time sheet: %v", err),
+				}
+			}
+			if err == nil && existingTimeSheet != nil {
+				httpResponseStatusCode = http.StatusConflict
+				return &CodeError{
+					Code:    "time_sheet_exists",
+					Message: "a time sheet already exists for this week",
+				}
+			}
+
+			templates, err := txApp.FindRecordsByFilter("time_entry_templates", "uid={:userID}", "created", 0, 0, dbx.Params{
+				"userID": userID,
+			})
+			if err != nil {
+				httpResponseStatusCode = http.StatusInternalServerError
+				return &CodeError{
+					Code:    "error_loading_templates",
+					Message: fmt.Sprintf("error loading time entry templates: %v", err),
+				}
+			}
+			if len(templates) == 0 {
+				httpResponseStatusCode = http.StatusBadRequest
+				return &CodeError{
+					Code:    "no_templates",
+					Message: "you have no time entry templates",
+				}
+			}
+
+			holidays, err := statutoryHolidayDates(txApp, userID, weekEnding)
+			if err != nil {
+				httpResponseStatusCode = http.StatusInternalServerError
+				return &CodeError{
+					Code:    "error_loading_holidays",
+					Message: fmt.Sprintf("error loading statutory holidays: %v", err),
+				}
+			}
+
+			existingEntries, err := txApp.FindRecordsByFilter("time_entries", "uid={:userID} && week_ending={:weekEnding}", "", 0, 0, dbx.Params{
+				"userID":     userID,
+				"weekEnding": weekEnding,
+			})
+			if err != nil {
+				httpResponseStatusCode = http.StatusInternalServerError
+				return &CodeError{
+					Code:    "error_checking_time_entries",
+					Message: fmt.Sprintf("error checking time entries: %v", err),
+				}
+			}
+			entered := make(map[string]bool, len(existingEntries))
+			for _, entry := range existingEntries {
+				entered[timeEntryTemplateKey(entry, entry.GetString("date"))] = true
+			}
+
+			response = applyTimeEntryTemplatesResponse{
+				WeekEnding:   weekEnding,
+				NewRecordIDs: []string{},
+				Skipped:      []skippedTimeEntryTemplate{},
+			}
+			for day := weekEndingTime.AddDate(0, 0, -6); !day.After(weekEndingTime); day = day.AddDate(0, 0, 1) {
+				date := day.Format(time.DateOnly)
+				for _, template := range templates {
+					if !list.ExistInSlice(templateWeekday(day), template.GetStringSlice("weekdays")) {
+						continue
+					}
+					if holidays[date] {
+						response.Skipped = append(response.Skipped, skippedTimeEntryTemplate{TemplateID: template.Id, Date: date, Reason: "statutory_holiday"})
+						continue
+					}
+					if entered[timeEntryTemplateKey(template, date)] {
+						response.Skipped = append(response.Skipped, skippedTimeEntryTemplate{TemplateID: template.Id, Date: date, Reason: "already_entered"})
+						continue
+					}
+
+					entry, err := hooks.NewTimeEntryFromTemplate(txApp, template, date)
+					if err != nil {
+						return err
+					}
+					if err := hooks.ProcessTimeEntry(txApp, &core.RecordRequestEvent{
+						RequestEvent: &core.RequestEvent{App: txApp, Auth: authRecord},
+						Record:       entry,
+					}); err != nil {
+						return err
+					}
+					if err := txApp.Save(entry); err != nil {
+						httpResponseStatusCode = http.StatusInternalServerError
+						return &CodeError{
+							Code:    "error_saving_record",
+							Message: fmt.Sprintf("error saving time entry from template %s: %v", template.Id, err),
+						}
+					}
+					entered[timeEntryTemplateKey(entry, date)] = true
+					response.NewRecordIDs = append(response.NewRecordIDs, entry.Id)
+				}
+			}
+
+			response.CreatedCount = len(response.NewRecordIDs)
+			response.Message = "Time entry templates applied"
+			return nil
+		})
+
+		if err != nil {
+			if codeErr, ok := err.(*CodeError); ok {
+				if httpResponseStatusCode == 0 {
+					httpResponseStatusCode = http.StatusBadRequest
+				}
+				return e.JSON(httpResponseStatusCode, map[string]any{
+					"error": codeErr.Message,
+					"code":  codeErr.Code,
+				})
+			}
+			var hookErr *errs.HookError
+			if errors.As(err, &hookErr) {
+				return e.JSON(hookErr.Status, hookErr)
+			}
+			return err
+		}
+
+		return e.JSON(http.StatusCreated, response)
+	}
+}
@@ -0,0 +1,98 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+	"tybalt/hooks"
+	"tybalt/internal/testseed"
+)
+
+func TestApplyTimeEntryTemplates(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	t.Cleanup(app.Cleanup)
+	hooks.AddHooks(app)
+	AddRoutes(app)
+
+	ownerToken := authTokenForEmail(t, app, "time@test.com")
+	outsiderToken := authTokenForEmail(t, app, "noclaims@example.com")
+
+	const regular = "sdyfl3q7j7ap849"
+	const statHoliday = "2yvywhhustq8zx2"
+	const division = "fy4i9poneukvq9u"
+
+	// Templates are validated as the time entry they would produce.
+	rec := performClaimsJSONRequest(t, app, http.MethodPost, "/api/collections/time_entry_templates/records", ownerToken, map[string]any{
+		"uid": "rzr98oadsp9qc11", "name": "Missing division", "weekdays": []string{"mon"}, "time_type": regular, "hours": 8, "description": "Office work",
+	})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("template without division status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+
+	rec = performClaimsJSONRequest(t, app, http.MethodPost, "/api/collections/time_entry_templates/records", ownerToken, map[string]any{
+		"uid": "rzr98oadsp9qc11", "name": "Office", "weekdays": []string{"mon", "tue", "wed", "thu", "fri"}, "time_type": regular, "division": division, "hours": 8, "description": "Office work",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create template status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	var template map[string]any
+	if err := json.Unmarshal(rec.Body.Bytes(), &template); err != nil {
+		t.Fatalf("failed to decode template: %v", err)
+	}
+	templateID := template["id"].(string)
+
+	if rec := performClaimsJSONRequest(t, app, http.MethodGet, "/api/collections/time_entry_templates/records/"+templateID, outsiderToken, nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("outsider view status = %d, want %d", rec.Code, http.StatusNotFound)
+	}
+
+	// Monday is a statutory holiday.
+	rec = performClaimsJSONRequest(t, app, http.MethodPost, "/api/collections/time_entries/records", ownerToken, map[string]any{
+		"uid": "rzr98oadsp9qc11", "date": "2030-03-04", "time_type": statHoliday, "hours": 8, "description": "Statutory holiday",
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("create holiday entry status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, "/api/time_entries/templates/2030-03-08/apply", ownerToken, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("non-Saturday apply status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, "/api/time_entries/templates/2030-03-09/apply", outsiderToken, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("apply without time claim status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+
+	apply := func() applyTimeEntryTemplatesResponse {
+		t.Helper()
+		rec := performClaimsJSONRequest(t, app, http.MethodPost, "/api/time_entries/templates/2030-03-09/apply", ownerToken, nil)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("apply status = %d; body=%s", rec.Code, rec.Body.String())
+		}
+		var response applyTimeEntryTemplatesResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+			t.Fatalf("failed to decode apply response: %v", err)
+		}
+		return response
+	}
+
+	first := apply()
+	if first.CreatedCount != 4 {
+		t.Fatalf("created_count = %d, want 4; response=%+v", first.CreatedCount, first)
+	}
+	if len(first.Skipped) != 1 || first.Skipped[0].Date != "2030-03-04" || first.Skipped[0].Reason != "statutory_holiday" {
+		t.Fatalf("skipped = %+v, want only the Monday holiday", first.Skipped)
+	}
+	for _, id := range first.NewRecordIDs {
+		entry, err := app.FindRecordById("time_entries", id)
+		if err != nil {
+			t.Fatalf("failed to load created entry: %v", err)
+		}
+		if entry.GetString("week_ending") != "2030-03-09" || entry.GetString("branch") == "" || entry.GetFloat("hours") != 8 {
+			t.Fatalf("created entry was not processed like a normal time entry: %v", entry.PublicExport())
+		}
+	}
+
+	// Applying again only reports skips.
+	second := apply()
+	if second.CreatedCount != 0 || len(second.Skipped) != 5 {
+		t.Fatalf("second apply = %+v, want nothing created and five skips", second)
+	}
+}
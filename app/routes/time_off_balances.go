@@ -0,0 +1,121 @@
+package routes
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+	"tybalt/timeoff"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// timeOffBalanceViewerClaims may view every user's time off balance. Other
+// callers see their own balance and their direct reports'.
+var timeOffBalanceViewerClaims = []string{"admin", "hr", "time_off_manager", "report"}
+
+func hasTimeOffBalanceViewerClaim(app core.App, auth *core.Record) (bool, error) {
+	for _, claim := range timeOffBalanceViewerClaims {
+		hasClaim, err := utilities.HasClaim(app, auth, claim)
+		if err != nil {
+			return false, err
+		}
+		if hasClaim {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// timeOffAsOf reads the optional as_of query parameter, defaulting to today.
+func timeOffAsOf(e *core.RequestEvent) (string, error) {
+	asOf := strings.TrimSpace(e.Request.URL.Query().Get("as_of"))
+	if asOf == "" {
+		return time.Now().Format(time.DateOnly), nil
+	}
+	if _, err := time.Parse(time.DateOnly, asOf); err != nil {
+		return "", e.Error(http.StatusBadRequest, "as_of must be in YYYY-MM-DD format", nil)
+	}
+	return asOf, nil
+}
+
+func writeTimeOffBalance(app core.App, e *core.RequestEvent, uid string, asOf string) error {
+	balance, err := timeoff.ComputeBalance(app, uid, asOf)
+	if errors.Is(err, timeoff.ErrNoAdminProfile) {
+		return e.Error(http.StatusNotFound, "no time off balance for this user", nil)
+	}
+	if err != nil {
+		return e.Error(http.StatusInternalServerError, "failed to compute time off balance", err)
+	}
+	return e.JSON(http.StatusOK, balance)
+}
+
+// createOwnTimeOffBalanceHandler returns the caller's OP/OV balance with its
+// ledger.
+func createOwnTimeOffBalanceHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		asOf, err := timeOffAsOf(e)
+		if err != nil {
+			return err
+		}
+		return writeTimeOffBalance(app, e, e.Auth.Id, asOf)
+	}
+}
+
+// createUserTimeOffBalanceHandler returns one user's balance with its ledger
+// to the user, their manager or a holder of a time off viewer claim.
+func createUserTimeOffBalanceHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		asOf, err := timeOffAsOf(e)
+		if err != nil {
+			return err
+		}
+
+		uid := e.Request.PathValue("uid")
+		if uid != e.Auth.Id {
+			allowed, err := hasTimeOffBalanceViewerClaim(app, e.Auth)
+			if err != nil {
+				return e.Error(http.StatusInternalServerError, "failed to check claims", err)
+			}
+			if !allowed {
+				profile, err := app.FindFirstRecordByData("profiles", "uid", uid)
+				if err == nil && profile.GetString("manager") == e.Auth.Id {
+					allowed = true
+				}
+			}
+			if !allowed {
+				return e.Error(http.StatusForbidden, "you do not have permission to view this time off balance", nil)
+			}
+		}
+
+		return writeTimeOffBalance(app, e, uid, asOf)
+	}
+}
+
+// createListTimeOffBalancesHandler lists balances without ledgers. Holders of
+// a time off viewer claim see every tracked user; managers see their direct
+// reports.
+func createListTimeOffBalancesHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		asOf, err := timeOffAsOf(e)
+		if err != nil {
+			return err
+		}
+
+		viewAll, err := hasTimeOffBalanceViewerClaim(app, e.Auth)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to check claims", err)
+		}
+		managerUID := ""
+		if !viewAll {
+			managerUID = e.Auth.Id
+		}
+
+		balances, err := timeoff.ListBalances(app, managerUID, asOf)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to list time off balances", err)
+		}
+		return e.JSON(http.StatusOK, balances)
+	}
+}
@@ -0,0 +1,116 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"tybalt/hooks"
+	"tybalt/internal/testseed"
+	"tybalt/timeoff"
+)
+
+func TestTimeOffBalances(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	t.Cleanup(app.Cleanup)
+	hooks.AddHooks(app)
+	AddRoutes(app)
+
+	adminToken := authTokenForEmail(t, app, "author@soup.com")
+	timeToken := authTokenForEmail(t, app, "time@test.com")
+
+	decode := func(body []byte) timeoff.Balance {
+		t.Helper()
+		var balance timeoff.Balance
+		if err := json.Unmarshal(body, &balance); err != nil {
+			t.Fatalf("failed to decode balance: %v", err)
+		}
+		return balance
+	}
+
+	// author@soup.com opened 2024-01-07 with 50 OP and 50 OV and has two
+	// committed OP amendments (2 + 3 hours) in week ending 2030-01-12. Their
+	// own time entries are not committed and do not count.
+	rec := performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances?as_of=2030-01-20", adminToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("own balance status = %d, want %d; body=%s", rec.Code, http.StatusOK, rec.Body.String())
+	}
+	own := decode(rec.Body.Bytes())
+	if own.UID != "f2j5a8vk006baub" || own.UsedOP != 5 || own.UsedOV != 0 {
+		t.Fatalf("unexpected balance: %+v", own)
+	}
+	if len(own.Ledger) != 2 || own.Ledger[0].Source != "time_amendment" || own.Ledger[1].Balance == nil || *own.Ledger[1].Balance != 45 {
+		t.Fatalf("unexpected ledger: %+v", own.Ledger)
+	}
+	// The 2030-01-12 payroll year end is after the opening date, so the
+	// opening balances need rolling over; the amendments fall in the old year.
+	// The new year's balances are unknown until accounting sets them.
+	if !own.RolloverRequired || own.PayrollYearEnd != "2030-01-12" || own.ClosingOP == nil || *own.ClosingOP != 45 || *own.ClosingOV != 50 {
+		t.Fatalf("unexpected rollover state: %+v", own)
+	}
+	if own.AvailableOP != nil || own.AvailableOV != nil || !strings.Contains(rec.Body.String(), `"available_op":null`) {
+		t.Fatalf("expected no available balances while the rollover is pending, got %s", rec.Body.String())
+	}
+
+	rec = performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances?as_of=2030-01-05", adminToken, nil)
+	before := decode(rec.Body.Bytes())
+	if before.UsedOP != 0 || len(before.Ledger) != 0 {
+		t.Fatalf("expected no usage before the amendments' week, got %+v", before)
+	}
+
+	// Within the opening payroll year the opening balances are available.
+	rec = performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances?as_of=2024-06-01", adminToken, nil)
+	opening := decode(rec.Body.Bytes())
+	if opening.RolloverRequired || opening.AvailableOP == nil || *opening.AvailableOP != 50 || opening.AvailableOV == nil || *opening.AvailableOV != 50 {
+		t.Fatalf("expected the opening balances to be available before the next year end, got %+v", opening)
+	}
+
+	// Committed time entries count too, including banked overtime.
+	rec = performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances/u_pbranch_hourly?as_of=2030-01-20", adminToken, nil)
+	hourly := decode(rec.Body.Bytes())
+	if hourly.UsedOP != 8 || hourly.UsedOV != 6 || hourly.AvailableOV != nil {
+		t.Fatalf("unexpected committed time entry usage: %+v", hourly)
+	}
+	rec = performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances/u_pbranch_hbank?as_of=2030-01-20", adminToken, nil)
+	if banked := decode(rec.Body.Bytes()); banked.BankedHours != 4 {
+		t.Fatalf("expected 4 banked hours, got %+v", banked)
+	}
+
+	// A user without viewer claims sees their own balance but not others'.
+	rec = performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances", timeToken, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("time user own balance status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	forbidden := performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances/f2j5a8vk006baub", timeToken, nil)
+	if forbidden.Code != http.StatusForbidden {
+		t.Fatalf("other balance status = %d, want %d", forbidden.Code, http.StatusForbidden)
+	}
+
+	// The list is limited to direct reports without a viewer claim.
+	rec = performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances/list", timeToken, nil)
+	var reports []timeoff.Balance
+	if err := json.Unmarshal(rec.Body.Bytes(), &reports); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if len(reports) != 0 {
+		t.Fatalf("expected no direct reports, got %d", len(reports))
+	}
+	rec = performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances/list", adminToken, nil)
+	var all []timeoff.Balance
+	if err := json.Unmarshal(rec.Body.Bytes(), &all); err != nil {
+		t.Fatalf("failed to decode list: %v", err)
+	}
+	if len(all) == 0 {
+		t.Fatal("expected tracked users in the list")
+	}
+	for _, balance := range all {
+		if len(balance.Ledger) != 0 {
+			t.Fatalf("expected list rows without ledgers, got %+v", balance)
+		}
+	}
+
+	badDate := performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/balances?as_of=2030-13-01", adminToken, nil)
+	if badDate.Code != http.StatusBadRequest {
+		t.Fatalf("bad as_of status = %d, want %d", badDate.Code, http.StatusBadRequest)
+	}
+}
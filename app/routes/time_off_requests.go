@@ -0,0 +1,77 @@
+package routes
+
+import (
+	"net/http"
+	"strings"
+	"time"
+	"tybalt/timeoff"
+	"tybalt/utilities"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// timeOffCalendarViewerClaims may see every submitted time off request on the
+// calendar, matching the time_off_requests list rule. Other callers see their
+// team.
+var timeOffCalendarViewerClaims = []string{"admin", "hr", "time_off_manager"}
+
+// timeOffCalendarDefaultDays is the window returned when end is omitted.
+const timeOffCalendarDefaultDays = 28
+
+// timeOffCalendarMaxDays bounds a single calendar request.
+const timeOffCalendarMaxDays = 366
+
+// createTimeOffCalendarHandler lists pending and approved time off requests
+// overlapping the optional start and end query parameters (YYYY-MM-DD,
+// inclusive). start defaults to today and end to four weeks after start.
+func createTimeOffCalendarHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		query := e.Request.URL.Query()
+
+		start := time.Now()
+		if value := strings.TrimSpace(query.Get("start")); value != "" {
+			parsed, err := time.Parse(time.DateOnly, value)
+			if err != nil {
+				return e.Error(http.StatusBadRequest, "start must be in YYYY-MM-DD format", nil)
+			}
+			start = parsed
+		}
+		end := start.AddDate(0, 0, timeOffCalendarDefaultDays-1)
+		if value := strings.TrimSpace(query.Get("end")); value != "" {
+			parsed, err := time.Parse(time.DateOnly, value)
+			if err != nil {
+				return e.Error(http.StatusBadRequest, "end must be in YYYY-MM-DD format", nil)
+			}
+			end = parsed
+		}
+		startDate, endDate := start.Format(time.DateOnly), end.Format(time.DateOnly)
+		if endDate < startDate {
+			return e.Error(http.StatusBadRequest, "end must not be before start", nil)
+		}
+		if end.Sub(start) > timeOffCalendarMaxDays*24*time.Hour {
+			return e.Error(http.StatusBadRequest, "the calendar range cannot exceed one year", nil)
+		}
+
+		viewAll := false
+		for _, claim := range timeOffCalendarViewerClaims {
+			hasClaim, err := utilities.HasClaim(app, e.Auth, claim)
+			if err != nil {
+				return e.Error(http.StatusInternalServerError, "failed to check claims", err)
+			}
+			if hasClaim {
+				viewAll = true
+				break
+			}
+		}
+
+		entries, err := timeoff.ListCalendar(app, e.Auth.Id, viewAll, startDate, endDate)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load time off calendar", err)
+		}
+		return e.JSON(http.StatusOK, map[string]any{
+			"start":    startDate,
+			"end":      endDate,
+			"requests": entries,
+		})
+	}
+}
@@ -0,0 +1,208 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+	"tybalt/hooks"
+	"tybalt/internal/testseed"
+	"tybalt/timeoff"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestTimeOffRequestWorkflow(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	t.Cleanup(app.Cleanup)
+	hooks.AddHooks(app)
+	AddRoutes(app)
+
+	// time@test.com reports to author@soup.com (tapr). u_with_claim holds
+	// time_off_manager and hr@example.com holds hr; neither manages them.
+	ownerToken := authTokenForEmail(t, app, "time@test.com")
+	managerToken := authTokenForEmail(t, app, "author@soup.com")
+	timeOffManagerToken := authTokenForEmail(t, app, "u_with_claim@example.com")
+	hrToken := authTokenForEmail(t, app, "hr@example.com")
+	outsiderToken := authTokenForEmail(t, app, "noclaims@example.com")
+
+	const vacation = "d35auo4vawx7t9u"
+	const sick = "yo20602uq83mfrp"
+
+	create := func(body map[string]any) (int, map[string]any) {
+		t.Helper()
+		rec := performClaimsJSONRequest(t, app, http.MethodPost, "/api/collections/time_off_requests/records", ownerToken, body)
+		var decoded map[string]any
+		_ = json.Unmarshal(rec.Body.Bytes(), &decoded)
+		return rec.Code, decoded
+	}
+
+	status, body := create(map[string]any{"uid": "rzr98oadsp9qc11", "time_type": sick, "start_date": "2030-02-04", "end_date": "2030-02-04", "hours": 8})
+	if status != http.StatusBadRequest || !strings.Contains(toJSON(t, body), `"invalid_time_type"`) {
+		t.Fatalf("sick request: status=%d body=%v", status, body)
+	}
+	status, body = create(map[string]any{"uid": "rzr98oadsp9qc11", "time_type": vacation, "start_date": "2030-02-05", "end_date": "2030-02-04", "hours": 8})
+	if status != http.StatusBadRequest || !strings.Contains(toJSON(t, body), `"end_before_start"`) {
+		t.Fatalf("reversed dates: status=%d body=%v", status, body)
+	}
+
+	status, body = create(map[string]any{"uid": "rzr98oadsp9qc11", "time_type": vacation, "start_date": "2030-02-04", "end_date": "2030-02-05", "hours": 16, "description": "Ski trip"})
+	if status != http.StatusOK {
+		t.Fatalf("create: status=%d body=%v", status, body)
+	}
+	if body["approver"] != "f2j5a8vk006baub" {
+		t.Fatalf("approver = %v, want the owner's manager", body["approver"])
+	}
+	id := body["id"].(string)
+	base := "/api/time_off/requests/" + id
+
+	// Only the owner submits; approving a draft is rejected.
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/submit", managerToken, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("manager submit status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/approve", managerToken, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("approve draft status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/submit", ownerToken, nil); rec.Code != http.StatusOK {
+		t.Fatalf("submit status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+
+	// Submitted requests are locked until recalled.
+	if rec := performClaimsJSONRequest(t, app, http.MethodPatch, "/api/collections/time_off_requests/records/"+id, ownerToken, map[string]any{"hours": 8}); rec.Code == http.StatusOK {
+		t.Fatal("expected submitted request to be locked")
+	}
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/recall", ownerToken, nil); rec.Code != http.StatusOK {
+		t.Fatalf("recall status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/submit", ownerToken, nil); rec.Code != http.StatusOK {
+		t.Fatalf("resubmit status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+
+	// Unrelated users can neither approve nor reject.
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/approve", outsiderToken, nil); rec.Code != http.StatusForbidden {
+		t.Fatalf("outsider approve status = %d, want %d", rec.Code, http.StatusForbidden)
+	}
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/reject", outsiderToken, map[string]any{"rejection_reason": "No thanks"}); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("outsider reject status = %d, want %d", rec.Code, http.StatusUnauthorized)
+	}
+
+	// A time off manager may approve on the manager's behalf.
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/approve", timeOffManagerToken, nil); rec.Code != http.StatusOK {
+		t.Fatalf("time off manager approve status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	record, err := app.FindRecordById("time_off_requests", id)
+	if err != nil {
+		t.Fatalf("failed to reload request: %v", err)
+	}
+	if record.GetDateTime("approved").IsZero() {
+		t.Fatal("expected approved timestamp")
+	}
+
+	calendar := func(token string) []timeoff.CalendarEntry {
+		t.Helper()
+		rec := performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/calendar?start=2030-02-01&end=2030-02-28", token, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("calendar status = %d; body=%s", rec.Code, rec.Body.String())
+		}
+		var result struct {
+			Requests []timeoff.CalendarEntry `json:"requests"`
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), &result); err != nil {
+			t.Fatalf("failed to decode calendar: %v", err)
+		}
+		return result.Requests
+	}
+
+	// The owner, their manager and hr see the request; an unrelated user on a
+	// different team does not.
+	for name, token := range map[string]string{"owner": ownerToken, "manager": managerToken, "hr": hrToken} {
+		entries := calendar(token)
+		if len(entries) != 1 || entries[0].ID != id || entries[0].Status != timeoff.RequestStatusApproved || entries[0].Code != "OV" {
+			t.Fatalf("%s calendar = %+v", name, entries)
+		}
+	}
+	if entries := calendar(outsiderToken); len(entries) != 0 {
+		t.Fatalf("outsider calendar = %+v, want empty", entries)
+	}
+
+	// Users without a manager are not each other's teammates.
+	setManager := func(uid string, manager string) {
+		t.Helper()
+		if _, err := app.DB().NewQuery("UPDATE profiles SET manager = {:manager} WHERE uid = {:uid}").Bind(dbx.Params{"uid": uid, "manager": manager}).Execute(); err != nil {
+			t.Fatalf("failed to set manager of %s: %v", uid, err)
+		}
+	}
+	setManager("rzr98oadsp9qc11", "")
+	setManager("4ssj9f1yg250o9y", "")
+	if entries := calendar(outsiderToken); len(entries) != 0 {
+		t.Fatalf("calendar of a viewer without a manager = %+v, want empty", entries)
+	}
+	setManager("rzr98oadsp9qc11", "f2j5a8vk006baub")
+	setManager("4ssj9f1yg250o9y", "dkv192wxprcqmho")
+	if rec := performClaimsJSONRequest(t, app, http.MethodGet, "/api/time_off/calendar?start=2030-02-28&end=2030-02-01", ownerToken, nil); rec.Code != http.StatusBadRequest {
+		t.Fatalf("reversed calendar range status = %d, want %d", rec.Code, http.StatusBadRequest)
+	}
+
+	// The approver can still withdraw an approved request, which removes it
+	// from the calendar.
+	if rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/reject", managerToken, map[string]any{"rejection_reason": "Coverage needed"}); rec.Code != http.StatusOK {
+		t.Fatalf("reject status = %d; body=%s", rec.Code, rec.Body.String())
+	}
+	if entries := calendar(hrToken); len(entries) != 0 {
+		t.Fatalf("calendar after rejection = %+v, want empty", entries)
+	}
+}
+
+func toJSON(t *testing.T, value any) string {
+	t.Helper()
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		t.Fatalf("failed to encode: %v", err)
+	}
+	return string(encoded)
+}
+
+func TestTimeOffManagerCannotActOnOwnRequest(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	t.Cleanup(app.Cleanup)
+	hooks.AddHooks(app)
+	AddRoutes(app)
+
+	// u_with_claim holds time_off_manager but is not the approver of their
+	// own request.
+	collection, err := app.FindCollectionByNameOrId("time_off_requests")
+	if err != nil {
+		t.Fatalf("failed to load time_off_requests collection: %v", err)
+	}
+	record := core.NewRecord(collection)
+	record.Set("uid", "u_with_claim")
+	record.Set("approver", "f2j5a8vk006baub")
+	record.Set("time_type", "d35auo4vawx7t9u")
+	record.Set("start_date", "2030-03-04")
+	record.Set("end_date", "2030-03-04")
+	record.Set("hours", 8)
+	record.Set("submitted", true)
+	if err := app.Save(record); err != nil {
+		t.Fatalf("failed to save time off request: %v", err)
+	}
+	base := "/api/time_off/requests/" + record.Id
+	ownerToken := authTokenForEmail(t, app, "u_with_claim@example.com")
+
+	rec := performClaimsJSONRequest(t, app, http.MethodPost, base+"/approve", ownerToken, nil)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "as a time off manager") {
+		t.Fatalf("own approve status = %d, want %d; body=%s", rec.Code, http.StatusForbidden, rec.Body.String())
+	}
+	rec = performClaimsJSONRequest(t, app, http.MethodPost, base+"/reject", ownerToken, map[string]any{"rejection_reason": "Changed plans"})
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), `"time_off_manager_is_owner"`) {
+		t.Fatalf("own reject status = %d, want %d; body=%s", rec.Code, http.StatusForbidden, rec.Body.String())
+	}
+
+	reloaded, err := app.FindRecordById("time_off_requests", record.Id)
+	if err != nil {
+		t.Fatalf("failed to reload request: %v", err)
+	}
+	if !reloaded.GetDateTime("approved").IsZero() || !reloaded.GetDateTime("rejected").IsZero() {
+		t.Fatal("expected the request to stay pending")
+	}
+}
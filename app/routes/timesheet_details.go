@@ -110,6 +110,10 @@ func canViewTimeSheetDetails(app core.App, auth *core.Record, timeSheet *core.Re
 		return true, nil
 	}
 
+	if timeSheet.GetBool("submitted") && auth.Id == timeSheet.GetString("approval_escalated_to") {
+		return true, nil
+	}
+
 	reviewers, err := app.FindRecordsByFilter("time_sheet_reviewers", "time_sheet={:time_sheet} && reviewer={:reviewer}", "", 1, 0, dbx.Params{
 		"time_sheet": timeSheet.Id,
 		"reviewer":   auth.Id,
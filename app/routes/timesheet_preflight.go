@@ -0,0 +1,151 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"tybalt/hooks"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// timesheetPreflight is the result of a bundle dry run. Ready is true when
+// bundling the week would succeed.
+type timesheetPreflight struct {
+	WeekEnding   string                                  `json:"week_ending"`
+	Ready        bool                                    `json:"ready"`
+	EntryCount   int                                     `json:"entry_count"`
+	ApproverUID  string                                  `json:"approver_uid"`
+	Violations   []timeEntryViolation                    `json:"violations"`
+	Warnings     []map[string]any                        `json:"warnings,omitempty"`
+	BlockingJobs []hooks.ProjectAuthorizationBlockingJob `json:"blocking_jobs,omitempty"`
+	Tallies      *timeEntryTallies                       `json:"tallies,omitempty"`
+}
+
+// createTimesheetPreflightHandler runs the bundle checks for the caller's week
+// without writing anything and reports every violation instead of stopping at
+// the first one.
+func createTimesheetPreflightHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		if err := requireTimeClaim(app, e.Auth); err != nil {
+			return err
+		}
+
+		weekEndingTime, err := time.Parse("2006-01-02", e.Request.PathValue("weekEnding"))
+		if err != nil {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": "Invalid date format. Use YYYY-MM-DD"})
+		}
+		if weekEndingTime.Weekday() != time.Saturday {
+			return e.JSON(http.StatusBadRequest, map[string]string{"error": "Week ending date must be a Saturday"})
+		}
+
+		result, err := preflightTimesheetBundle(app, e.Auth.Id, weekEndingTime.Format("2006-01-02"))
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to check timesheet", err)
+		}
+		return e.JSON(http.StatusOK, result)
+	}
+}
+
+// preflightTimesheetBundle mirrors createBundleTimesheetHandler check for
+// check, in the same order, turning each failure into a violation. Lookup
+// failures that would make the bundle return 500 are returned as errors,
+// except for the payroll year end date which is reported so staff know to
+// contact support.
+func preflightTimesheetBundle(app core.App, userId string, weekEnding string) (*timesheetPreflight, error) {
+	result := &timesheetPreflight{
+		WeekEnding: weekEnding,
+		Violations: []timeEntryViolation{},
+	}
+
+	existingTimeSheet, err := app.FindFirstRecordByFilter("time_sheets", "uid={:userId} && week_ending={:weekEnding}", dbx.Params{
+		"userId":     userId,
+		"weekEnding": weekEnding,
+	})
+	if err == nil && existingTimeSheet != nil {
+		result.Violations = append(result.Violations, timeEntryViolation{
+			Code:    "time_sheet_exists",
+			Message: "a time sheet already exists for this user and week ending date",
+		})
+	}
+
+	timeEntries, err := app.FindRecordsByFilter("time_entries", "uid={:userId} && week_ending={:weekEnding}", "date", 0, 0, dbx.Params{
+		"userId":     userId,
+		"weekEnding": weekEnding,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching time entries: %v", err)
+	}
+	result.EntryCount = len(timeEntries)
+
+	blockingJobs, err := hooks.UnapprovedProjectAuthorizationJobsForTimeEntries(app, userId, weekEnding)
+	if err != nil {
+		return nil, fmt.Errorf("error checking project authorization approvals: %v", err)
+	}
+	if len(blockingJobs) > 0 {
+		blocked := map[string]bool{}
+		for _, job := range blockingJobs {
+			blocked[job.ID] = true
+		}
+		entryIDs := []string{}
+		for _, entry := range timeEntries {
+			if blocked[entry.GetString("job")] {
+				entryIDs = append(entryIDs, entry.Id)
+			}
+		}
+		result.BlockingJobs = blockingJobs
+		result.Violations = append(result.Violations, timeEntryViolation{
+			Code:     hooks.ProjectAuthorizationNotApprovedCode,
+			Message:  hooks.ProjectAuthorizationNotApprovedMessage,
+			EntryIDs: entryIDs,
+		})
+	}
+
+	adminProfile, err := app.FindFirstRecordByFilter("admin_profiles", "uid={:userId}", dbx.Params{
+		"userId": userId,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user's admin profile: %v", err)
+	}
+
+	if len(timeEntries) == 0 {
+		result.Violations = append(result.Violations, timeEntryViolation{
+			Code:    "no_time_entries",
+			Message: "there are no time entries for this week",
+		})
+	} else if payrollYearEndDateAsTime, err := findPayrollYearEndForWeek(app, weekEnding); err != nil {
+		result.Violations = append(result.Violations, timeEntryViolation{
+			Code:    "payroll_year_end_date_unavailable",
+			Message: err.Error(),
+		})
+	} else {
+		tallies, violations, err := collectTimeEntryViolations(app, adminProfile, payrollYearEndDateAsTime, timeEntries)
+		if err != nil {
+			return nil, err
+		}
+		result.Tallies = &tallies
+		result.Violations = append(result.Violations, violations...)
+	}
+
+	approverUID, approverErr, err := checkTimesheetApprover(app, userId)
+	if err != nil {
+		return nil, err
+	}
+	result.ApproverUID = approverUID
+	if approverErr != nil {
+		result.Violations = append(result.Violations, timeEntryViolation{
+			Code:    approverErr.Code,
+			Message: approverErr.Message,
+		})
+	}
+
+	warnings, err := timeOffRequestWarnings(app, userId, weekEnding)
+	if err != nil {
+		return nil, err
+	}
+	result.Warnings = warnings
+	result.Ready = len(result.Violations) == 0
+	return result, nil
+}
@@ -3,6 +3,7 @@ package routes
 import (
 	_ "embed" // Needed for //go:embed
 	"net/http"
+	"tybalt/overtime"
 	"tybalt/utilities"
 
 	"github.com/pocketbase/dbx"
@@ -13,42 +14,50 @@ import (
 var talliesQuery string
 
 type TimeSheetTally struct {
-	Id                  string                    `json:"id"`
-	Uid                 string                    `json:"uid"`
-	Approved            string                    `json:"approved"`
-	BankEntryDates      utilities.JsonStringSlice `json:"bank_entry_dates"`
-	DivisionNames       utilities.JsonStringSlice `json:"division_names"`
-	Divisions           utilities.JsonStringSlice `json:"divisions"`
-	JobNumbers          utilities.JsonStringSlice `json:"job_numbers"`
-	MealsHours          float64                   `json:"meals_hours"`
-	NonWorkTotalHours   float64                   `json:"non_work_total_hours"`
-	ObHours             float64                   `json:"ob_hours"`
-	OffRotationDates    utilities.JsonStringSlice `json:"off_rotation_dates"`
-	OffWeekDates        utilities.JsonStringSlice `json:"off_week_dates"`
-	OpHours             float64                   `json:"op_hours"`
-	OsHours             float64                   `json:"os_hours"`
-	OvHours             float64                   `json:"ov_hours"`
-	PayoutRequestAmount float64                   `json:"payout_request_amount"`
-	PayoutRequestDates  utilities.JsonStringSlice `json:"payout_request_dates"`
-	SharedReviewerCount int                       `json:"shared_reviewer_count"`
-	Submitted           bool                      `json:"submitted"`
-	Rejected            string                    `json:"rejected"`
-	RejectionReason     string                    `json:"rejection_reason"`
-	Salary              string                    `json:"salary"`
-	TimeTypeNames       utilities.JsonStringSlice `json:"time_type_names"`
-	TimeTypes           utilities.JsonStringSlice `json:"time_types"`
-	WeekEnding          string                    `json:"week_ending"`
-	WorkHours           float64                   `json:"work_hours"`
-	WorkJobHours        float64                   `json:"work_job_hours"`
-	WorkTotalHours      float64                   `json:"work_total_hours"`
-	WorkWeekHours       float64                   `json:"work_week_hours"`
-	GivenName           string                    `json:"given_name"`
-	Surname             string                    `json:"surname"`
-	Approver            string                    `json:"approver"`
-	Committer           string                    `json:"committer"`
-	Committed           string                    `json:"committed"`
-	ApproverName        string                    `json:"approver_name"`
-	CommitterName       string                    `json:"committer_name"`
+	Id                      string                    `json:"id"`
+	Uid                     string                    `json:"uid"`
+	Approved                string                    `json:"approved"`
+	BankEntryDates          utilities.JsonStringSlice `json:"bank_entry_dates"`
+	DivisionNames           utilities.JsonStringSlice `json:"division_names"`
+	Divisions               utilities.JsonStringSlice `json:"divisions"`
+	JobNumbers              utilities.JsonStringSlice `json:"job_numbers"`
+	MealsHours              float64                   `json:"meals_hours"`
+	NonWorkTotalHours       float64                   `json:"non_work_total_hours"`
+	ObHours                 float64                   `json:"ob_hours"`
+	OffRotationDates        utilities.JsonStringSlice `json:"off_rotation_dates"`
+	OffWeekDates            utilities.JsonStringSlice `json:"off_week_dates"`
+	OpHours                 float64                   `json:"op_hours"`
+	OsHours                 float64                   `json:"os_hours"`
+	OvHours                 float64                   `json:"ov_hours"`
+	PayoutRequestAmount     float64                   `json:"payout_request_amount"`
+	PayoutRequestDates      utilities.JsonStringSlice `json:"payout_request_dates"`
+	RbHours                 float64                   `json:"rb_hours"`
+	SharedReviewerCount     int                       `json:"shared_reviewer_count"`
+	Submitted               bool                      `json:"submitted"`
+	Rejected                string                    `json:"rejected"`
+	RejectionReason         string                    `json:"rejection_reason"`
+	Salary                  string                    `json:"salary"`
+	TimeTypeNames           utilities.JsonStringSlice `json:"time_type_names"`
+	TimeTypes               utilities.JsonStringSlice `json:"time_types"`
+	WeekEnding              string                    `json:"week_ending"`
+	WorkHours               float64                   `json:"work_hours"`
+	WorkJobHours            float64                   `json:"work_job_hours"`
+	WorkTotalHours          float64                   `json:"work_total_hours"`
+	WorkWeekHours           float64                   `json:"work_week_hours"`
+	GivenName               string                    `json:"given_name"`
+	Surname                 string                    `json:"surname"`
+	Approver                string                    `json:"approver"`
+	DelegatedApprover       string                    `json:"delegated_approver"`
+	ApprovalEscalated       string                    `json:"approval_escalated"`
+	ApprovalEscalatedTo     string                    `json:"approval_escalated_to"`
+	Committer               string                    `json:"committer"`
+	Committed               string                    `json:"committed"`
+	ApproverName            string                    `json:"approver_name"`
+	DelegatedApproverName   string                    `json:"delegated_approver_name"`
+	ApprovalEscalatedToName string                    `json:"approval_escalated_to_name"`
+	CommitterName           string                    `json:"committer_name"`
+	// Overtime is filled in after the query by applyTimesheetTallyOvertime.
+	Overtime overtime.Result `db:"-" json:"overtime"`
 }
 
 // createTimesheetTalliesHandler returns a handler that creates a tally of the
@@ -65,7 +74,76 @@ func createTimesheetTalliesHandler(app core.App, role string, pendingOnly, appro
 		if err != nil {
 			return e.Error(http.StatusInternalServerError, "failed to execute query: "+err.Error(), err)
 		}
+		if err := applyTimesheetTallyOvertime(app, timeSheetTally); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to calculate overtime: "+err.Error(), err)
+		}
 
 		return e.JSON(http.StatusOK, timeSheetTally)
 	}
 }
+
+// applyTimesheetTallyOvertime splits the worked hours of each tally using the
+// overtime rules of the owner's default branch, the same calculation
+// validateTimeEntries applies when the timesheet is bundled.
+func applyTimesheetTallyOvertime(app core.App, tallies []TimeSheetTally) error {
+	if len(tallies) == 0 {
+		return nil
+	}
+
+	tsids := make([]any, 0, len(tallies))
+	uids := make([]any, 0, len(tallies))
+	for _, tally := range tallies {
+		tsids = append(tsids, tally.Id)
+		uids = append(uids, tally.Uid)
+	}
+
+	var dailyRows []struct {
+		Tsid  string  `db:"tsid"`
+		Date  string  `db:"date"`
+		Hours float64 `db:"hours"`
+	}
+	err := app.DB().
+		Select("te.tsid AS tsid", "te.date AS date", "SUM(te.hours) AS hours").
+		From("time_entries te").
+		InnerJoin("time_types tt", dbx.NewExp("te.time_type = tt.id")).
+		Where(dbx.In("te.tsid", tsids...)).
+		AndWhere(dbx.In("tt.code", "R", "RT")).
+		GroupBy("te.tsid", "te.date").
+		All(&dailyRows)
+	if err != nil {
+		return err
+	}
+	dailyHours := map[string]map[string]float64{}
+	for _, row := range dailyRows {
+		if dailyHours[row.Tsid] == nil {
+			dailyHours[row.Tsid] = map[string]float64{}
+		}
+		dailyHours[row.Tsid][row.Date] += row.Hours
+	}
+
+	var branchRows []struct {
+		Uid           string `db:"uid"`
+		DefaultBranch string `db:"default_branch"`
+	}
+	err = app.DB().
+		Select("uid", "default_branch").
+		From("admin_profiles").
+		Where(dbx.In("uid", uids...)).
+		All(&branchRows)
+	if err != nil {
+		return err
+	}
+	defaultBranches := make(map[string]string, len(branchRows))
+	for _, row := range branchRows {
+		defaultBranches[row.Uid] = row.DefaultBranch
+	}
+
+	config := overtime.LoadConfig(app)
+	for i := range tallies {
+		tally := &tallies[i]
+		salary := tally.Salary == "1" || tally.Salary == "true"
+		rules := config.ForBranch(defaultBranches[tally.Uid])
+		tally.Overtime = overtime.Calculate(rules, salary, dailyHours[tally.Id], tally.RbHours)
+	}
+	return nil
+}
@@ -16,6 +16,7 @@ type trackingCountRow struct {
 	ApprovedCount  int    `db:"approved_count" json:"approved_count"`
 	CommittedCount int    `db:"committed_count" json:"committed_count"`
 	RejectedCount  int    `db:"rejected_count" json:"rejected_count"`
+	EscalatedCount int    `db:"escalated_count" json:"escalated_count"`
 }
 
 type committedTimesheetTrackingAccess struct {
@@ -80,7 +81,9 @@ func createTimesheetTrackingCountsHandler(app core.App) func(e *core.RequestEven
                 -- submitted but neither approved nor committed
                 SUM(CASE WHEN {:has_admin} = 0 AND submitted = 1 AND approved = '' AND committed = '' THEN 1 ELSE 0 END) AS submitted_count,
                 -- rejected is non-exclusive (can overlap others)
-                SUM(CASE WHEN {:has_admin} = 0 AND rejected != '' THEN 1 ELSE 0 END) AS rejected_count
+                SUM(CASE WHEN {:has_admin} = 0 AND rejected != '' THEN 1 ELSE 0 END) AS rejected_count,
+                -- escalated is the subset of submitted escalated for waiting too long
+                SUM(CASE WHEN {:has_admin} = 0 AND submitted = 1 AND approved = '' AND committed = '' AND approval_escalated != '' THEN 1 ELSE 0 END) AS escalated_count
             FROM time_sheets
             GROUP BY week_ending
             ORDER BY week_ending DESC
@@ -111,6 +114,10 @@ type trackingListRow struct {
 	ApproverName  string `db:"approver_name" json:"approver_name"`
 	CommitterName string `db:"committer_name" json:"committer_name"`
 	RejectorName  string `db:"rejector_name" json:"rejector_name"`
+	// Set once a submitted timesheet waited too long for approval
+	ApprovalEscalated       string `db:"approval_escalated" json:"approval_escalated"`
+	ApprovalEscalatedTo     string `db:"approval_escalated_to" json:"approval_escalated_to"`
+	ApprovalEscalatedToName string `db:"approval_escalated_to_name" json:"approval_escalated_to_name"`
 	// Consolidated phase for grouping in UI
 	Phase string `db:"phase" json:"phase"`
 	// Aggregated totals for convenience in UI
@@ -156,6 +163,9 @@ func createTimesheetTrackingListHandler(app core.App) func(e *core.RequestEvent)
                 COALESCE(ap.given_name || ' ' || ap.surname, '') AS approver_name,
                 COALESCE(cp.given_name || ' ' || cp.surname, '') AS committer_name,
                 COALESCE(rp.given_name || ' ' || rp.surname, '') AS rejector_name,
+                ts.approval_escalated,
+                COALESCE(ts.approval_escalated_to, '') AS approval_escalated_to,
+                COALESCE(ep.given_name || ' ' || ep.surname, '') AS approval_escalated_to_name,
                 CASE
                     WHEN ts.committed != '' THEN 'Committed'
                     WHEN ts.approved != '' AND ts.committed = '' THEN 'Approved'
@@ -176,6 +186,7 @@ func createTimesheetTrackingListHandler(app core.App) func(e *core.RequestEvent)
             LEFT JOIN profiles ap ON ap.uid = ts.approver
             LEFT JOIN profiles cp ON cp.uid = ts.committer
             LEFT JOIN profiles rp ON rp.uid = ts.rejector
+            LEFT JOIN profiles ep ON ep.uid = ts.approval_escalated_to
             LEFT JOIN (
                 SELECT
                     te.tsid AS tsid,
@@ -258,12 +269,9 @@ func createTimesheetMissingHandler(app core.App) func(e *core.RequestEvent) erro
                 COALESCE(u.email, '') AS email
             FROM users u
             LEFT JOIN time_sheets ts ON ts.uid = u.id AND ts.week_ending = {:week_ending}
-            LEFT JOIN time_sheet_missing_exceptions ex ON ex.uid = u.id AND ex.week_ending = {:week_ending}
-            LEFT JOIN profiles p ON p.uid = u.id
-            LEFT JOIN admin_profiles ap ON ap.uid = u.id
+            LEFT JOIN profiles p ON p.uid = u.id` + utilities.TimesheetExpectationJoins + `
             WHERE ts.id IS NULL
-              AND ex.id IS NULL
-              AND COALESCE(ap.time_sheet_expected, 0) = 1
+              AND ` + utilities.TimesheetExpectedCondition + `
             ORDER BY p.surname, p.given_name
         `
 
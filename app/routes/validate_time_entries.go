@@ -3,18 +3,88 @@ package routes
 import (
 	"fmt"
 	"time"
+	"tybalt/overtime"
+	"tybalt/timeoff"
+	"tybalt/utilities"
 
 	"github.com/pocketbase/dbx"
 	"github.com/pocketbase/pocketbase/core"
 )
 
+// timeEntryViolation is one failed bundle check. EntryIDs lists the time
+// entries that caused it when the check is tied to specific entries.
+type timeEntryViolation struct {
+	Code     string   `json:"code"`
+	Message  string   `json:"message"`
+	EntryIDs []string `json:"entry_ids,omitempty"`
+}
+
+// timeEntryTallies summarizes the time entries as validateTimeEntries sees
+// them. UsedOP and UsedOV include this week and are only computed when the
+// opening balance checks are reached. Overtime is the split of the worked
+// hours under the overtime rules of the employee's default branch.
+type timeEntryTallies struct {
+	JobHours             float64            `json:"job_hours"`
+	NonJobHours          float64            `json:"non_job_hours"`
+	NonWorkHours         map[string]float64 `json:"non_work_hours"`
+	BankedHours          float64            `json:"banked_hours"`
+	OffRotationDays      int                `json:"off_rotation_days"`
+	TotalHours           float64            `json:"total_hours"`
+	WorkWeekHours        float64            `json:"work_week_hours"`
+	OpeningOP            float64            `json:"opening_op"`
+	OpeningOV            float64            `json:"opening_ov"`
+	UsedOP               float64            `json:"used_op"`
+	UsedOV               float64            `json:"used_ov"`
+	PayrollYearEnd       string             `json:"payroll_year_end"`
+	DiscretionaryTimeOff float64            `json:"discretionary_time_off"`
+	Overtime             overtime.Result    `json:"overtime"`
+}
+
 // This function will validate the time entries as a group. If the validation
 // fails, it will return an error. If the validation passes, it will return nil.
+// The error is the first violation collectTimeEntryViolations reports.
 func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYearEndDateAsTime time.Time, entries []*core.Record) error {
+	_, violations, err := collectTimeEntryViolations(txApp, admin_profile, payrollYearEndDateAsTime, entries)
+	if err != nil {
+		return err
+	}
+	if len(violations) > 0 {
+		return &CodeError{Code: violations[0].Code, Message: violations[0].Message}
+	}
+	return nil
+}
+
+// collectTimeEntryViolations runs every bundle validation over the entries
+// and returns all violations in the order validateTimeEntries checks them,
+// along with the tallies the checks were based on. Checks that make later
+// checks meaningless (invalid opening or week ending dates) stop collection.
+// The returned error is reserved for lookup failures.
+func collectTimeEntryViolations(txApp core.App, admin_profile *core.Record, payrollYearEndDateAsTime time.Time, entries []*core.Record) (timeEntryTallies, []timeEntryViolation, error) {
+	tallies := timeEntryTallies{NonWorkHours: map[string]float64{}}
+	violations := []timeEntryViolation{}
+
+	// addViolation records a failed check, merging repeats of the same code
+	// and message into one violation with every offending entry.
+	addViolation := func(code string, message string, entryIDs ...string) {
+		ids := []string{}
+		for _, id := range entryIDs {
+			if id != "" {
+				ids = append(ids, id)
+			}
+		}
+		for i := range violations {
+			if violations[i].Code == code && violations[i].Message == message {
+				violations[i].EntryIDs = append(violations[i].EntryIDs, ids...)
+				return
+			}
+		}
+		violations = append(violations, timeEntryViolation{Code: code, Message: message, EntryIDs: ids})
+	}
+
 	// Expand the time_type relations of the entries so we can access the
 	// time_type code stored in the time_types collection.
 	if errs := txApp.ExpandRecords(entries, []string{"time_type"}, nil); len(errs) > 0 {
-		return &CodeError{
+		return tallies, nil, &CodeError{
 			Code:    "error_expanding_time_type_relations",
 			Message: fmt.Sprintf("error expanding time_type relations: %v", errs),
 		}
@@ -33,26 +103,48 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 	workWeekHours := admin_profile.GetFloat("work_week_hours")
 	workRecordsSet := map[string]bool{}
 	offRotationDateSet := map[string]bool{}
+	statHolidayDateSet := map[string]bool{}
 	offRotationWeekEntryCount := 0
 	payoutRequestCount := 0
 	bankEntriesCount := 0
 	bankedHours := 0.0
 	jobHours := 0.0
 	nonJobHours := 0.0
+	dailyWorkedHours := map[string]float64{}
 	nonWorkHoursTally := map[string]float64{}
+	// entryIDsByCode lets cross-entry violations point at the entries of the
+	// time types involved.
+	entryIDsByCode := map[string][]string{}
+
+	// tally snapshots the running totals for the caller.
+	tally := func() timeEntryTallies {
+		tallies.JobHours = jobHours
+		tallies.NonJobHours = nonJobHours
+		tallies.NonWorkHours = nonWorkHoursTally
+		tallies.BankedHours = bankedHours
+		tallies.OffRotationDays = len(offRotationDateSet)
+		tallies.WorkWeekHours = workWeekHours
+		tallies.OpeningOP = openingOP
+		tallies.OpeningOV = openingOV
+		tallies.PayrollYearEnd = payrollYearEndDateAsTime.Format("2006-01-02")
+		total := jobHours + nonJobHours
+		for _, hours := range nonWorkHoursTally {
+			total += hours
+		}
+		tallies.TotalHours = total
+		return tallies
+	}
 
 	// Loop through each time entry, summarizing information as we go and
-	// returning errors if any validations fail.
+	// recording violations for individual entries.
 	for _, entry := range entries {
-		// Return an error immediately if the entry has a work_record value that is
-		// not an empty string and is already in the workRecordsSet. Otherwise, add
-		// the work_record value to the workRecordsSet.
+		// Record a violation if the entry has a work_record value that is not an
+		// empty string and is already in the workRecordsSet. Otherwise, add the
+		// work_record value to the workRecordsSet.
 		if workRecord := entry.GetString("work_record"); workRecord != "" {
 			if _, keyPresent := workRecordsSet[workRecord]; keyPresent {
-				return &CodeError{
-					Code:    "multiple_work_records",
-					Message: fmt.Sprintf("work record %s appears in multiple entries", workRecord),
-				}
+				addViolation("multiple_work_records", fmt.Sprintf("work record %s appears in multiple entries", workRecord), entry.Id)
+				continue
 			}
 			workRecordsSet[workRecord] = true
 		}
@@ -61,80 +153,61 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 		timeType := entry.ExpandedOne("time_type")
 		timeTypeCode := timeType.GetString("code")
 		entryHours := entry.GetFloat("hours")
+		entryIDsByCode[timeTypeCode] = append(entryIDsByCode[timeTypeCode], entry.Id)
+		if timeTypeCode == "OH" {
+			statHolidayDateSet[entry.GetString("date")] = true
+		}
 
 		switch timeTypeCode {
 		case "OR":
-			// Return an error immediately if the entry is of type OR (off rotation) and
-			// the date of the entry is already in the offRotationDateSet. If it is not
+			// Record a violation if the entry is of type OR (off rotation) and the
+			// date of the entry is already in the offRotationDateSet. If it is not
 			// in the set, add the date to the set.
 			if _, keyPresent := offRotationDateSet[entry.GetString("date")]; keyPresent {
-				return &CodeError{
-					Code:    "multiple_off_rotation_entries",
-					Message: fmt.Sprintf("more than one OR entry exists for the date: %s", entry.GetString("date")),
-				}
+				addViolation("multiple_off_rotation_entries", fmt.Sprintf("more than one OR entry exists for the date: %s", entry.GetString("date")), entry.Id)
+				continue
 			}
 			offRotationDateSet[entry.GetString("date")] = true
 		case "OW":
 			// prevent salaried employees from claiming full week off (OW)
 			if salary {
-				return &CodeError{
-					Code:    "salary_with_time_type_OW",
-					Message: "salaried staff cannot claim full week off, use OP or OV",
-				}
+				addViolation("salary_with_time_type_OW", "salaried staff cannot claim full week off, use OP or OV", entry.Id)
+				continue
 			}
-			// Return an error immediately if the total number of entries exceeds 1 since
-			// if an OW entry exists, it should be the only entry on the timesheet.
+			// If an OW entry exists, it should be the only entry on the timesheet.
 			if len(entries) > 1 {
-				return &CodeError{
-					Code:    "multiple_OW_entries",
-					Message: "if present, an OW entry must be the only entry on a timesheet",
-				}
+				addViolation("multiple_OW_entries", "if present, an OW entry must be the only entry on a timesheet", entry.Id)
+				continue
 			}
-			// Return an error immediately if the entry is of type OW (off rotation week)
-			// and the offRotationWeekEntryCount is greater than 1. If the entry is of
-			// type OW, increment the offRotationWeekEntryCount.
+			// Only one off-rotation week entry can exist on a timesheet.
 			offRotationWeekEntryCount++
 			if offRotationWeekEntryCount > 1 {
-				return &CodeError{
-					Code:    "multiple_off_rotation_week_entries",
-					Message: "only one off-rotation week entry can exist on a timesheet",
-				}
+				addViolation("multiple_off_rotation_week_entries", "only one off-rotation week entry can exist on a timesheet", entry.Id)
 			}
 		case "OTO":
 			// If the entry is of type OTO (Request Overtime Payout), the user must
 			// not be a salaried staff member.
 			if salary {
-				return &CodeError{
-					Code:    "salary_with_time_type_OTO",
-					Message: "salaried staff cannot request overtime payouts",
-				}
+				addViolation("salary_with_time_type_OTO", "salaried staff cannot request overtime payouts", entry.Id)
+				continue
 			}
+			// Only one payout request entry can exist on the timesheet.
 			payoutRequestCount++
-			// Return an error immediately if there is more than one payout request
-			// entry on the timesheet.
 			if payoutRequestCount > 1 {
-				return &CodeError{
-					Code:    "multiple_payout_request_entries",
-					Message: "only one payout request entry can exist on a timesheet",
-				}
+				addViolation("multiple_payout_request_entries", "only one payout request entry can exist on a timesheet", entry.Id)
 			}
 		case "RB":
 			// If the entry is of type RB (Add Overtime to Bank), the user must
 			// not be a salaried staff member.
 			if salary {
-				return &CodeError{
-					Code:    "salary_with_time_type_RB",
-					Message: "salaried staff cannot bank overtime",
-				}
+				addViolation("salary_with_time_type_RB", "salaried staff cannot bank overtime", entry.Id)
+				continue
 			}
-			// Return an error immediately if there is more than one bank entry on
-			// the timesheet.
+			// Only one bank entry can exist on the timesheet.
 			bankEntriesCount++
 			if bankEntriesCount > 1 {
-				return &CodeError{
-					Code:    "multiple_overtime_banking_entries",
-					Message: "only one overtime banking entry can exist on a timesheet",
-				}
+				addViolation("multiple_overtime_banking_entries", "only one overtime banking entry can exist on a timesheet", entry.Id)
+				continue
 			}
 			bankedHours += entryHours
 		case "R", "RT":
@@ -145,12 +218,11 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 			} else {
 				nonJobHours += entryHours
 			}
+			dailyWorkedHours[entry.GetString("date")] += entryHours
 		default:
 			if entryHours == 0 {
-				return &CodeError{
-					Code:    "time_entry_missing_hours",
-					Message: "a time entry is missing hours",
-				}
+				addViolation("time_entry_missing_hours", "a time entry is missing hours", entry.Id)
+				continue
 			}
 			// Initialize the nonWorkHoursTally for the timeTypeCode if it doesn't
 			// already exist.
@@ -163,13 +235,15 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 
 	// Now we look for validation errors that apply across multiple entries.
 
-	// If banked hours exist, the sum of all hours worked minus the banked hours
-	// mustn't be under 44.
-	if bankedHours > 0 && jobHours+nonJobHours-bankedHours < 44 {
-		return &CodeError{
-			Code:    "too_many_banked_hours",
-			Message: "banked hours cannot bring your total worked hours below 44 hours on a timesheet",
-		}
+	// Split the worked hours using the overtime rules of the employee's
+	// default branch. Banked hours must come out of overtime, so they cannot
+	// exceed the overtime worked, and branches may disallow banking entirely.
+	overtimeRules := overtime.LoadConfig(txApp).ForBranch(admin_profile.GetString("default_branch"))
+	tallies.Overtime = overtime.Calculate(overtimeRules, salary, dailyWorkedHours, bankedHours)
+	if bankedHours > 0 && !overtimeRules.BankingAllowed {
+		addViolation("overtime_banking_not_allowed", "overtime cannot be banked in your branch, request a payout instead", entryIDsByCode["RB"]...)
+	} else if bankedHours > tallies.Overtime.OvertimeHours {
+		addViolation("too_many_banked_hours", fmt.Sprintf("banked hours cannot exceed the %v overtime hours on this timesheet", tallies.Overtime.OvertimeHours), entryIDsByCode["RB"]...)
 	}
 
 	// sum the values of the nonWorkHoursTally into nonWorkHoursTotal
@@ -189,22 +263,40 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 	if _, ok := nonWorkHoursTally["OV"]; ok {
 		discretionaryTimeOff += nonWorkHoursTally["OV"]
 	}
+	tallies.DiscretionaryTimeOff = discretionaryTimeOff
+	discretionaryEntryIDs := append(append([]string{}, entryIDsByCode["OP"]...), entryIDsByCode["OV"]...)
 
 	// prevent staff from using vacation or PPTO to raise their timesheet hours
 	// beyond workWeekHours.
 	if discretionaryTimeOff > 0 && nonJobHours+jobHours+nonWorkHoursTotal > workWeekHours {
-		return &CodeError{
-			Code:    "too_much_discretionary_time_off",
-			Message: fmt.Sprintf("you cannot claim OV or OP entries that increase hours beyond %v", workWeekHours),
-		}
+		addViolation("too_much_discretionary_time_off", fmt.Sprintf("you cannot claim OV or OP entries that increase hours beyond %v", workWeekHours), discretionaryEntryIDs...)
 	}
 
 	// prevent salaried employees from claiming off rotation days (OR) unless
 	// permitted by admin profile.
 	if salary && !offRotationPermitted && len(offRotationDateSet) > 0 {
-		return &CodeError{
-			Code:    "salary_with_time_type_OR_without_permission",
-			Message: "salaried staff need permission to claim OR entries",
+		addViolation("salary_with_time_type_OR_without_permission", "salaried staff need permission to claim OR entries", entryIDsByCode["OR"]...)
+	}
+
+	// salaried employees are paid for the statutory holidays observed by their
+	// default branch and must record each one with an OH entry. Staff with
+	// untracked time off don't record OH. A missing holiday is reported on its
+	// own, so its hours (a fifth of the work week) count towards the minimum
+	// below rather than failing that check too.
+	missingStatHolidayHours := 0.0
+	if _, err := time.Parse("2006-01-02", weekEnding); err == nil && salary && !untrackedTimeOff {
+		holidays, err := utilities.StatutoryHolidaysInWeek(txApp, admin_profile.GetString("default_branch"), weekEnding)
+		if err != nil {
+			return tally(), violations, &CodeError{
+				Code:    "error_loading_statutory_holidays",
+				Message: fmt.Sprintf("error loading statutory holidays: %v", err),
+			}
+		}
+		for _, holiday := range holidays {
+			if !statHolidayDateSet[holiday.Date] {
+				addViolation("statutory_holiday_entry_missing", fmt.Sprintf("%s on %s is a statutory holiday, add an OH entry for it", holiday.Name, holiday.Date))
+				missingStatHolidayHours += workWeekHours / 5
+			}
 		}
 	}
 
@@ -212,22 +304,16 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 	// timesheet unless untracked time off is enabled or skipMinTimeCheck is set
 	// to "yes" or "on_next_bundle"
 	offRotationHours := float64(len(offRotationDateSet)) * 8
-	if salary && nonJobHours+jobHours+nonWorkHoursTotal+offRotationHours < workWeekHours {
+	if salary && nonJobHours+jobHours+nonWorkHoursTotal+offRotationHours+missingStatHolidayHours < workWeekHours {
 		if skipMinTimeCheck == "no" && !untrackedTimeOff {
-			return &CodeError{
-				Code:    "too_few_hours_on_timesheet",
-				Message: fmt.Sprintf("you must have a minimum of %v hours on your time sheet", workWeekHours),
-			}
+			addViolation("too_few_hours_on_timesheet", fmt.Sprintf("you must have a minimum of %v hours on your time sheet", workWeekHours))
 		}
 	}
 
 	// prevent salaried employees from claiming sick time by reporting an error if
 	// the key "OS" (sick time) exists in the nonWorkHoursTally.
 	if _, ok := nonWorkHoursTally["OS"]; ok && salary {
-		return &CodeError{
-			Code:    "salary_with_time_type_OS",
-			Message: "salaried staff cannot claim OS. Please use OP or OV instead",
-		}
+		addViolation("salary_with_time_type_OS", "salaried staff cannot claim OS. Please use OP or OV instead", entryIDsByCode["OS"]...)
 	}
 
 	// prevent salaried employees with untracked time off from claiming OB, OH,
@@ -235,22 +321,17 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 	if salary && untrackedTimeOff {
 		for _, code := range []string{"OB", "OH", "OP", "OV"} {
 			if _, ok := nonWorkHoursTally[code]; ok {
-				return &CodeError{
-					Code:    "untracked_time_off_restricted",
-					Message: "staff with untracked time off are only permitted to create R or RT entries",
-				}
+				addViolation("untracked_time_off_restricted", "staff with untracked time off are only permitted to create R or RT entries", entryIDsByCode[code]...)
 			}
 		}
 	}
 
-	// return an error if openingDate is not a valid date in the format
-	// "2006-01-02"
+	// record a violation and stop if openingDate is not a valid date in the
+	// format "2006-01-02"; the balance checks below depend on it.
 	openingDateAsTime, err := time.Parse("2006-01-02", openingDate)
 	if err != nil {
-		return &CodeError{
-			Code:    "invalid_opening_date",
-			Message: "your admin_profile has an invalid opening_date, contact support",
-		}
+		addViolation("invalid_opening_date", "your admin_profile has an invalid opening_date, contact support")
+		return tally(), violations, nil
 	}
 
 	// return an error if openingDate is not a Sunday (is this necessary?, why
@@ -269,26 +350,21 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 	// 	}
 	// }
 
-	// return an error if weekEnding is not a valid date in the format
-	// "2006-01-02"
+	// record a violation and stop if weekEnding is not a valid date in the
+	// format "2006-01-02"
 	weekEndingAsTime, err := time.Parse("2006-01-02", weekEnding)
 	if err != nil {
-		return &CodeError{
-			Code:    "invalid_week_ending_date",
-			Message: "an entry has an invalid week_ending date, contact support",
-		}
+		addViolation("invalid_week_ending_date", "an entry has an invalid week_ending date, contact support")
+		return tally(), violations, nil
 	}
 
-	// return an error if openingDate is after the weekEnding. This will prevent
-	// submission of an old timesheet if the openingDateTimeOff value has already
-	// been updated to the next fiscal year. This error is only triggered if PPTO
+	// record a violation if openingDate is after the weekEnding. This will
+	// prevent submission of an old timesheet if the openingDateTimeOff value has
+	// already been updated to the next fiscal year. This is only checked if PPTO
 	// or Vacation are claimed on this timesheet because the opening balances are
 	// otherwise irrelevant to the validation.
 	if discretionaryTimeOff > 0 && openingDateAsTime.After(weekEndingAsTime) {
-		return &CodeError{
-			Code:    "timesheet_prior_to_opening_date",
-			Message: fmt.Sprintf("your opening balances were set effective %v but you are submitting a timesheet for a prior period, contact support", openingDate),
-		}
+		addViolation("timesheet_prior_to_opening_date", fmt.Sprintf("your opening balances were set effective %v but you are submitting a timesheet for a prior period, contact support", openingDate), discretionaryEntryIDs...)
 	}
 
 	// Each timesheet submission is checked against the most recent payrollYearEndDate
@@ -298,20 +374,17 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 	// then the opening balances are out of date and the timesheet cannot be
 	// submitted until the opening balances are updated by accounting. This is to
 	// prevent the user from claiming expired time off from a previous year on a
-	// timesheet in the following year. This error is only triggered if PPTO or
-	// Vacation are claimed on this timesheet.
+	// timesheet in the following year. This is only checked if PPTO or Vacation
+	// are claimed on this timesheet.
 
 	if discretionaryTimeOff > 0 && payrollYearEndDateAsTime.After(openingDateAsTime) {
-		return &CodeError{
-			Code:    "opening_balances_out_of_date",
-			Message: fmt.Sprintf("your opening balances were set effective %v but you are submitting a timesheet for the time-off accounting period beginning on %v. contact accounting to have your opening balances updated for the new period prior to submitting a timesheet", openingDate, payrollYearEndDateAsTime.Format("2006-01-02")),
-		}
+		addViolation("opening_balances_out_of_date", fmt.Sprintf("your opening balances were set effective %v but you are submitting a timesheet for the time-off accounting period beginning on %v. contact accounting to have your opening balances updated for the new period prior to submitting a timesheet", openingDate, payrollYearEndDateAsTime.Format("2006-01-02")), discretionaryEntryIDs...)
 	}
 
 	// get the total PPTO and Vacation hours used in the period since the
 	// openingDate then check if the sum of the time entries for PPTO and Vacation
-	// is greater than corresponding opening values. If it is, return an
-	// error.
+	// is greater than corresponding opening values. If it is, record a
+	// violation.
 	//
 	// IMPORTANT: We use week_ending > openingDate (not >=) because the opening
 	// balances represent the state at the START of the opening_date. Entries with
@@ -335,7 +408,7 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 		"timeTypeCode": "OV",
 	}).All(&results)
 	if queryError != nil {
-		return &CodeError{
+		return tally(), violations, &CodeError{
 			Code:    "error_querying_for_used_vacation",
 			Message: fmt.Sprintf("error querying for used vacation: %v", queryError),
 		}
@@ -356,7 +429,7 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 		"timeTypeCode": "OP",
 	}).All(&results)
 	if queryError != nil {
-		return &CodeError{
+		return tally(), violations, &CodeError{
 			Code:    "error_querying_for_used_ppto",
 			Message: fmt.Sprintf("error querying for used ppto: %v", queryError),
 		}
@@ -365,33 +438,46 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 	if len(results) == 1 {
 		usedOP = results[0].TotalHours
 	}
+	tallies.UsedOP = usedOP
+	tallies.UsedOV = usedOV
 
 	// Only enforce the openingOV balance check if at least one OV entry exists on this
 	// timesheet. This prevents an error when the user's available vacation balance
 	// is already negative from previous activity but no additional vacation is
 	// being claimed in the current bundle (see issue #25).
 	if _, ovClaimed := nonWorkHoursTally["OV"]; ovClaimed && usedOV > openingOV {
-		return &CodeError{
-			Code:    "ov_claim_exceeds_balance",
-			Message: "your vacation claim exceeds your available vacation balance",
-		}
+		addViolation("ov_claim_exceeds_balance", "your vacation claim exceeds your available vacation balance", entryIDsByCode["OV"]...)
 	}
 
 	// Similarly, only enforce the PPTO balance check if at least one OP entry is
 	// present on this timesheet.
 	if _, opClaimed := nonWorkHoursTally["OP"]; opClaimed && usedOP > openingOP {
-		return &CodeError{
-			Code:    "ppto_claim_exceeds_balance",
-			Message: "your PPTO claim exceeds your available PPTO balance",
-		}
+		addViolation("ppto_claim_exceeds_balance", "your PPTO claim exceeds your available PPTO balance", entryIDsByCode["OP"]...)
 	}
 
-	// return an error if OP was claimed on this timesheet and the remaining
+	// record a violation if OP was claimed on this timesheet and the remaining
 	// available OV is greater than 0.
 	if _, pptoClaimed := nonWorkHoursTally["OP"]; pptoClaimed && openingOV-usedOV > 0 {
-		return &CodeError{
-			Code:    "ppto_used_before_ov",
-			Message: fmt.Sprintf("exhaust your vacation balance (%v hours) prior to claiming PPTO", openingOV-usedOV),
+		addViolation("ppto_used_before_ov", fmt.Sprintf("exhaust your vacation balance (%v hours) prior to claiming PPTO", openingOV-usedOV), entryIDsByCode["OP"]...)
+	}
+
+	// When time off requests are enforced, every OP and OV entry must fall
+	// within an approved request. In warn mode the bundle handler reports the
+	// same entries without failing.
+	if discretionaryTimeOff > 0 && utilities.GetTimeOffRequestEnforcement(txApp) == utilities.TimeOffRequestEnforcementBlock {
+		unrequested, err := timeoff.FindUnrequestedEntries(txApp, admin_profile.GetString("uid"), weekEnding)
+		if err != nil {
+			return tally(), violations, &CodeError{
+				Code:    "error_checking_time_off_requests",
+				Message: fmt.Sprintf("error checking time off requests: %v", err),
+			}
+		}
+		if len(unrequested) > 0 {
+			ids := make([]string, 0, len(unrequested))
+			for _, entry := range unrequested {
+				ids = append(ids, entry.ID)
+			}
+			addViolation("time_off_request_required", fmt.Sprintf("%s time off on %s needs an approved time off request", unrequested[0].Code, unrequested[0].Date), ids...)
 		}
 	}
 
@@ -401,5 +487,5 @@ func validateTimeEntries(txApp core.App, admin_profile *core.Record, payrollYear
 	// payroll_id is mandatory on the admin_profile in pocketbase rules so there
 	// is no need to check for it.
 
-	return nil
+	return tally(), violations, nil
 }
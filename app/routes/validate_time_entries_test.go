@@ -248,3 +248,144 @@ func TestValidateTimeEntries_UntrackedTimeOffRejectsRestrictedLeaveTypes(t *test
 		}
 	}
 }
+
+func TestValidateTimeEntries_SalariedStaffRecordStatutoryHolidays(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	defer app.Cleanup()
+
+	uid := "u_no_claims"
+	weekEnding := "2024-01-13"
+	payrollYearEndDate, _ := time.Parse("2006-01-02", "2024-01-06")
+
+	adminProfile, err := app.FindFirstRecordByFilter("admin_profiles", "uid={:uid}", dbx.Params{"uid": uid})
+	if err != nil {
+		t.Fatalf("failed to fetch admin_profile: %v", err)
+	}
+	adminProfile.Set("salary", true)
+	adminProfile.Set("skip_min_time_check", "no")
+	adminProfile.Set("untracked_time_off", false)
+	adminProfile.Set("work_week_hours", 40)
+
+	holidaysCollection, err := app.FindCollectionByNameOrId("statutory_holidays")
+	if err != nil {
+		t.Fatalf("failed to load statutory_holidays collection: %v", err)
+	}
+	holiday := core.NewRecord(holidaysCollection)
+	holiday.Set("date", "2024-01-08")
+	holiday.Set("name", "Test Holiday")
+	if err := app.Save(holiday); err != nil {
+		t.Fatalf("failed to save holiday: %v", err)
+	}
+	// A holiday observed only by another branch is ignored.
+	if _, err := app.DB().NewQuery(`
+		INSERT INTO statutory_holidays (id, date, name, branches)
+		VALUES ('sholidayother01', '2024-01-10', 'Other Branch Holiday', '["no_such_branch"]')
+	`).Execute(); err != nil {
+		t.Fatalf("failed to insert other branch holiday: %v", err)
+	}
+
+	timeEntriesCollection, _ := app.FindCollectionByNameOrId("time_entries")
+	newEntry := func(code string, date string, hours float64) *core.Record {
+		entry := core.NewRecord(timeEntriesCollection)
+		entry.Set("uid", uid)
+		entry.Set("time_type", getTimeTypeId(t, app, code))
+		entry.Set("hours", hours)
+		entry.Set("date", date)
+		entry.Set("week_ending", weekEnding)
+		return entry
+	}
+	worked := newEntry("R", "2024-01-09", 32)
+
+	// The missing OH entry is the only violation: its hours count towards the
+	// minimum so too_few_hours_on_timesheet is not reported as well.
+	_, violations, err := collectTimeEntryViolations(app, adminProfile, payrollYearEndDate, []*core.Record{worked})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Code != "statutory_holiday_entry_missing" {
+		t.Fatalf("violations = %+v, want only statutory_holiday_entry_missing", violations)
+	}
+
+	if err := validateTimeEntries(app, adminProfile, payrollYearEndDate, []*core.Record{worked, newEntry("OH", "2024-01-08", 8)}); err != nil {
+		t.Fatalf("validation should pass once the holiday is recorded, got: %v", err)
+	}
+
+	// Hourly staff are not expected to record the holiday.
+	adminProfile.Set("salary", false)
+	if err := validateTimeEntries(app, adminProfile, payrollYearEndDate, []*core.Record{worked}); err != nil {
+		t.Fatalf("validation should pass for hourly staff, got: %v", err)
+	}
+}
+
+func TestValidateTimeEntries_BankedOvertimeFollowsBranchRules(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	defer app.Cleanup()
+
+	uid := "u_no_claims"
+	weekEnding := "2024-01-13"
+	payrollYearEndDate, _ := time.Parse("2006-01-02", "2024-01-06")
+
+	adminProfile, err := app.FindFirstRecordByFilter("admin_profiles", "uid={:uid}", dbx.Params{"uid": uid})
+	if err != nil {
+		t.Fatalf("failed to fetch admin_profile: %v", err)
+	}
+
+	timeEntriesCollection, _ := app.FindCollectionByNameOrId("time_entries")
+	newEntry := func(code string, date string, hours float64) *core.Record {
+		entry := core.NewRecord(timeEntriesCollection)
+		entry.Set("uid", uid)
+		entry.Set("time_type", getTimeTypeId(t, app, code))
+		entry.Set("hours", hours)
+		entry.Set("date", date)
+		entry.Set("week_ending", weekEnding)
+		return entry
+	}
+	entries := []*core.Record{
+		newEntry("R", "2024-01-08", 12),
+		newEntry("R", "2024-01-09", 12),
+		newEntry("R", "2024-01-10", 12),
+		newEntry("R", "2024-01-11", 12),
+		newEntry("RB", "2024-01-11", 4),
+	}
+
+	// Ontario-style defaults: 48 hours is 4 hours over the 44 hour week, all
+	// of which may be banked.
+	tallies, violations, err := collectTimeEntryViolations(app, adminProfile, payrollYearEndDate, entries)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 0 {
+		t.Fatalf("violations = %+v, want none", violations)
+	}
+	if tallies.Overtime.OvertimeHours != 4 || tallies.Overtime.BankedHours != 4 {
+		t.Fatalf("overtime = %+v, want 4 overtime hours banked", tallies.Overtime)
+	}
+
+	configCollection, err := app.FindCollectionByNameOrId("app_config")
+	if err != nil {
+		t.Fatalf("failed to find app_config collection: %v", err)
+	}
+	config := core.NewRecord(configCollection)
+	config.Set("key", "overtime")
+	config.Set("value", `{"branches": {"`+adminProfile.GetString("default_branch")+`": {"weekly_threshold": 46}}}`)
+	if err := app.Save(config); err != nil {
+		t.Fatalf("failed to save overtime config: %v", err)
+	}
+
+	// A 46 hour week leaves only 2 overtime hours to bank.
+	_, violations, err = collectTimeEntryViolations(app, adminProfile, payrollYearEndDate, entries)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(violations) != 1 || violations[0].Code != "too_many_banked_hours" {
+		t.Fatalf("violations = %+v, want only too_many_banked_hours", violations)
+	}
+
+	config.Set("value", `{"branches": {"`+adminProfile.GetString("default_branch")+`": {"banking_allowed": false}}}`)
+	if err := app.Save(config); err != nil {
+		t.Fatalf("failed to save overtime config: %v", err)
+	}
+	if err := validateTimeEntries(app, adminProfile, payrollYearEndDate, entries); err == nil || err.(*CodeError).Code != "overtime_banking_not_allowed" {
+		t.Fatalf("err = %v, want overtime_banking_not_allowed", err)
+	}
+}
@@ -3,6 +3,7 @@ SELECT
   v.id,
   v.name,
   v.alias,
+  COALESCE(v.tax_code, '') AS tax_code,
   COALESCE(ec.expenses_count, 0) AS expenses_count,
   COALESCE(poc.purchase_orders_count, 0) AS purchase_orders_count
 FROM vendors v
@@ -16,6 +16,7 @@ type vendorRow struct {
 	ID                  string `db:"id"`
 	Name                string `db:"name"`
 	Alias               string `db:"alias"`
+	TaxCode             string `db:"tax_code"`
 	ExpensesCount       int    `db:"expenses_count"`
 	PurchaseOrdersCount int    `db:"purchase_orders_count"`
 }
@@ -24,6 +25,7 @@ type Vendor struct {
 	ID                  string `json:"id"`
 	Name                string `json:"name"`
 	Alias               string `json:"alias"`
+	TaxCode             string `json:"tax_code"`
 	ExpensesCount       int    `json:"expenses_count"`
 	PurchaseOrdersCount int    `json:"purchase_orders_count"`
 }
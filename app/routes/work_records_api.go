@@ -0,0 +1,789 @@
+package routes
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"tybalt/errs"
+	"tybalt/hooks"
+	"tybalt/utilities"
+
+	validation "github.com/go-ozzo/ozzo-validation/v4"
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const duplicateWorkRecordAttachmentMessage = "this work record attachment has already been uploaded"
+
+// WorkRecordSubjectInput is one worker row in a work record save request. Rows
+// are matched to existing worker rows by uid, which is immutable.
+type WorkRecordSubjectInput struct {
+	UID                      string  `json:"uid"`
+	HoursOnSite              float64 `json:"hours_on_site"`
+	HoursTravelTime          float64 `json:"hours_travel_time"`
+	DistanceTravelledKm      float64 `json:"distance_travelled_km"`
+	VehicleType              string  `json:"vehicle_type"`
+	IsPassenger              bool    `json:"is_passenger"`
+	CompanyVehicleUnitNumber string  `json:"company_vehicle_unit_number"`
+}
+
+// WorkRecordConsumableInput is one consumable in a work record save request.
+// Numeric consumables use QuantityNumber and boolean consumables use Selected;
+// a zero quantity or unselected boolean removes the stored entry.
+type WorkRecordConsumableInput struct {
+	Consumable     string  `json:"consumable"`
+	QuantityNumber float64 `json:"quantity_number"`
+	Selected       bool    `json:"selected"`
+}
+
+// WorkRecordSaveRequest is the body for creating and updating work records.
+// On update, a nil Subjects or Consumables leaves that part unchanged;
+// otherwise the submitted list is the desired final set.
+type WorkRecordSaveRequest struct {
+	CopyFrom            string                       `json:"copy_from"`
+	Date                string                       `json:"date"`
+	Job                 string                       `json:"job"`
+	Type                string                       `json:"type"`
+	Location            string                       `json:"location"`
+	WorkDescription     string                       `json:"work_description"`
+	ReportTo            string                       `json:"report_to"`
+	FieldBookNumber     string                       `json:"field_book_number"`
+	FieldBookPageNumber int                          `json:"field_book_page_number"`
+	LaneKms             float64                      `json:"lane_kms"`
+	SubContractor       string                       `json:"sub_contractor"`
+	Equipment           string                       `json:"equipment"`
+	Supplies            string                       `json:"supplies"`
+	Subjects            *[]WorkRecordSubjectInput    `json:"subjects"`
+	Consumables         *[]WorkRecordConsumableInput `json:"consumables"`
+}
+
+type WorkRecordSubjectRow struct {
+	ID                       string  `db:"id" json:"id"`
+	UID                      string  `db:"uid" json:"uid"`
+	GivenName                string  `db:"given_name" json:"given_name"`
+	Surname                  string  `db:"surname" json:"surname"`
+	HoursOnSite              float64 `db:"hours_on_site" json:"hours_on_site"`
+	HoursTravelTime          float64 `db:"hours_travel_time" json:"hours_travel_time"`
+	DistanceTravelledKm      float64 `db:"distance_travelled_km" json:"distance_travelled_km"`
+	VehicleType              string  `db:"vehicle_type" json:"vehicle_type"`
+	IsPassenger              bool    `db:"is_passenger" json:"is_passenger"`
+	CompanyVehicleUnitNumber string  `db:"company_vehicle_unit_number" json:"company_vehicle_unit_number"`
+	Approved                 bool    `db:"approved" json:"approved"`
+	TimeEntryID              string  `db:"time_entry_id" json:"time_entry_id"`
+}
+
+type WorkRecordConsumableRow struct {
+	ID             string  `db:"id" json:"id"`
+	Consumable     string  `db:"consumable" json:"consumable"`
+	Name           string  `db:"name" json:"name"`
+	InputKind      string  `db:"input_kind" json:"input_kind"`
+	UnitLabel      string  `db:"unit_label" json:"unit_label"`
+	QuantityNumber float64 `db:"quantity_number" json:"quantity_number"`
+}
+
+type WorkRecordNoteRow struct {
+	ID        string `db:"id" json:"id"`
+	UID       string `db:"uid" json:"uid"`
+	GivenName string `db:"given_name" json:"given_name"`
+	Surname   string `db:"surname" json:"surname"`
+	Note      string `db:"note" json:"note"`
+	Created   string `db:"created" json:"created"`
+}
+
+// WorkRecordDetails is the full shape of one work record as returned by the
+// work record create, update and detail routes.
+type WorkRecordDetails struct {
+	ID                  string                    `json:"id"`
+	Number              string                    `json:"number"`
+	ParentWorkRecord    string                    `json:"parent_work_record"`
+	Date                string                    `json:"date"`
+	Creator             string                    `json:"creator"`
+	Job                 string                    `json:"job"`
+	Type                string                    `json:"type"`
+	Location            string                    `json:"location"`
+	WorkDescription     string                    `json:"work_description"`
+	ReportTo            string                    `json:"report_to"`
+	FieldBookNumber     string                    `json:"field_book_number"`
+	FieldBookPageNumber int                       `json:"field_book_page_number"`
+	LaneKms             float64                   `json:"lane_kms"`
+	SubContractor       string                    `json:"sub_contractor"`
+	Equipment           string                    `json:"equipment"`
+	Supplies            string                    `json:"supplies"`
+	Attachment          string                    `json:"attachment"`
+	AttachmentHash      string                    `json:"attachment_hash"`
+	Locked              bool                      `json:"locked"`
+	Subjects            []WorkRecordSubjectRow    `json:"subjects"`
+	Consumables         []WorkRecordConsumableRow `json:"consumables"`
+	Notes               []WorkRecordNoteRow       `json:"notes"`
+}
+
+const workRecordSubjectRowsQuery = `
+	SELECT
+		s.id,
+		s.uid,
+		COALESCE(p.given_name, '') AS given_name,
+		COALESCE(p.surname, '') AS surname,
+		COALESCE(s.hours_on_site, 0) AS hours_on_site,
+		COALESCE(s.hours_travel_time, 0) AS hours_travel_time,
+		COALESCE(s.distance_travelled_km, 0) AS distance_travelled_km,
+		COALESCE(s.vehicle_type, '') AS vehicle_type,
+		s.is_passenger,
+		COALESCE(s.company_vehicle_unit_number, '') AS company_vehicle_unit_number,
+		s.approved,
+		COALESCE((SELECT te.id FROM time_entries te WHERE te.work_record_subject_id = s.id LIMIT 1), '') AS time_entry_id
+	FROM work_records_subjects s
+	LEFT JOIN profiles p ON p.uid = s.uid
+	WHERE s.work_record = {:id}
+	ORDER BY s.created, s.id
+`
+
+const workRecordConsumableRowsQuery = `
+	SELECT
+		ce.id,
+		ce.consumable,
+		c.name,
+		c.input_kind,
+		COALESCE(c.unit_label, '') AS unit_label,
+		COALESCE(ce.quantity_number, 0) AS quantity_number
+	FROM work_records_consumable_entries ce
+	JOIN work_records_consumables c ON c.id = ce.consumable
+	LEFT JOIN work_records_type_consumables tc ON tc.consumable = ce.consumable AND tc.type = {:type}
+	WHERE ce.work_record = {:id}
+	ORDER BY COALESCE(tc.sort_order, 0), c.name
+`
+
+const workRecordNoteRowsQuery = `
+	SELECT
+		n.id,
+		n.uid,
+		COALESCE(p.given_name, '') AS given_name,
+		COALESCE(p.surname, '') AS surname,
+		n.note,
+		n.created
+	FROM work_records_notes n
+	LEFT JOIN profiles p ON p.uid = n.uid
+	WHERE n.work_record = {:id}
+	ORDER BY n.created, n.id
+`
+
+func workRecordRouteError(status int, field string, code string, message string) *errs.HookError {
+	return &errs.HookError{
+		Status:  status,
+		Message: message,
+		Data: map[string]errs.CodeError{
+			field: {Code: code, Message: message},
+		},
+	}
+}
+
+// saveWorkRecordModel saves a row in one of the work records collections and
+// turns schema validation failures into field errors the routes return as 400s.
+func saveWorkRecordModel(app core.App, record *core.Record) error {
+	err := app.Save(record)
+	var validationErrs validation.Errors
+	if err == nil || !errors.As(err, &validationErrs) {
+		return err
+	}
+	fieldErrors := make(map[string]errs.CodeError)
+	for field, fieldErr := range validationErrs {
+		code := "validation_error"
+		var ozzoErr validation.Error
+		if errors.As(fieldErr, &ozzoErr) {
+			code = ozzoErr.Code()
+		}
+		fieldErrors[field] = errs.CodeError{Code: code, Message: fieldErr.Error()}
+	}
+	return &errs.HookError{
+		Status:  http.StatusBadRequest,
+		Message: "validation failed",
+		Data:    fieldErrors,
+	}
+}
+
+// workRecordRole describes how the caller relates to a work record.
+type workRecordRole struct {
+	Creator bool
+	Worker  bool
+	Report  bool
+}
+
+func (r workRecordRole) canView() bool { return r.Creator || r.Worker || r.Report }
+func (r workRecordRole) canEdit() bool { return r.Creator || r.Worker }
+
+func resolveWorkRecordRole(app core.App, workRecord *core.Record, auth *core.Record) (workRecordRole, error) {
+	role := workRecordRole{Creator: workRecord.GetString("creator") == auth.Id}
+	subject, err := app.FindFirstRecordByFilter("work_records_subjects", "work_record = {:id} && uid = {:uid}", dbx.Params{
+		"id":  workRecord.Id,
+		"uid": auth.Id,
+	})
+	role.Worker = err == nil && subject != nil
+	if !role.Creator && !role.Worker {
+		hasReport, err := utilities.HasClaim(app, auth, "report")
+		if err != nil {
+			return role, err
+		}
+		role.Report = hasReport
+	}
+	return role, nil
+}
+
+// loadWorkRecordForCaller loads a work record and the caller's role on it.
+// Records the caller cannot see are reported as not found.
+func loadWorkRecordForCaller(app core.App, workRecordID string, auth *core.Record) (*core.Record, workRecordRole, error) {
+	workRecord, err := app.FindRecordById("work_records", workRecordID)
+	if err != nil {
+		return nil, workRecordRole{}, workRecordRouteError(http.StatusNotFound, "work_record", "not_found", "work record not found")
+	}
+	role, err := resolveWorkRecordRole(app, workRecord, auth)
+	if err != nil {
+		return nil, workRecordRole{}, err
+	}
+	if !role.canView() {
+		return nil, workRecordRole{}, workRecordRouteError(http.StatusNotFound, "work_record", "not_found", "work record not found")
+	}
+	return workRecord, role, nil
+}
+
+func loadWorkRecordDetails(app core.App, workRecordID string) (WorkRecordDetails, error) {
+	record, err := app.FindRecordById("work_records", workRecordID)
+	if err != nil {
+		return WorkRecordDetails{}, err
+	}
+	locked, err := hooks.WorkRecordIsLocked(app, record.Id)
+	if err != nil {
+		return WorkRecordDetails{}, err
+	}
+	details := WorkRecordDetails{
+		ID:                  record.Id,
+		Number:              record.GetString("number"),
+		ParentWorkRecord:    record.GetString("parent_work_record"),
+		Date:                record.GetString("date"),
+		Creator:             record.GetString("creator"),
+		Job:                 record.GetString("job"),
+		Type:                record.GetString("type"),
+		Location:            record.GetString("location"),
+		WorkDescription:     record.GetString("work_description"),
+		ReportTo:            record.GetString("report_to"),
+		FieldBookNumber:     record.GetString("field_book_number"),
+		FieldBookPageNumber: record.GetInt("field_book_page_number"),
+		LaneKms:             record.GetFloat("lane_kms"),
+		SubContractor:       record.GetString("sub_contractor"),
+		Equipment:           record.GetString("equipment"),
+		Supplies:            record.GetString("supplies"),
+		Attachment:          record.GetString("attachment"),
+		AttachmentHash:      record.GetString("attachment_hash"),
+		Locked:              locked,
+		Subjects:            []WorkRecordSubjectRow{},
+		Consumables:         []WorkRecordConsumableRow{},
+		Notes:               []WorkRecordNoteRow{},
+	}
+	params := dbx.Params{"id": record.Id, "type": record.GetString("type")}
+	if err := app.DB().NewQuery(workRecordSubjectRowsQuery).Bind(params).All(&details.Subjects); err != nil {
+		return WorkRecordDetails{}, err
+	}
+	if err := app.DB().NewQuery(workRecordConsumableRowsQuery).Bind(params).All(&details.Consumables); err != nil {
+		return WorkRecordDetails{}, err
+	}
+	if err := app.DB().NewQuery(workRecordNoteRowsQuery).Bind(params).All(&details.Notes); err != nil {
+		return WorkRecordDetails{}, err
+	}
+	return details, nil
+}
+
+func setWorkRecordSharedFields(record *core.Record, req WorkRecordSaveRequest) {
+	record.Set("date", strings.TrimSpace(req.Date))
+	record.Set("job", strings.TrimSpace(req.Job))
+	record.Set("type", strings.TrimSpace(req.Type))
+	record.Set("location", strings.TrimSpace(req.Location))
+	record.Set("work_description", strings.TrimSpace(req.WorkDescription))
+	record.Set("report_to", strings.TrimSpace(req.ReportTo))
+	record.Set("field_book_number", strings.TrimSpace(req.FieldBookNumber))
+	record.Set("field_book_page_number", req.FieldBookPageNumber)
+	record.Set("lane_kms", req.LaneKms)
+	record.Set("sub_contractor", strings.TrimSpace(req.SubContractor))
+	record.Set("equipment", strings.TrimSpace(req.Equipment))
+	record.Set("supplies", strings.TrimSpace(req.Supplies))
+}
+
+func setWorkRecordSubjectFields(record *core.Record, input WorkRecordSubjectInput) {
+	record.Set("hours_on_site", input.HoursOnSite)
+	record.Set("hours_travel_time", input.HoursTravelTime)
+	record.Set("distance_travelled_km", input.DistanceTravelledKm)
+	record.Set("vehicle_type", strings.TrimSpace(input.VehicleType))
+	record.Set("is_passenger", input.IsPassenger)
+	record.Set("company_vehicle_unit_number", strings.TrimSpace(input.CompanyVehicleUnitNumber))
+}
+
+// indexWorkRecordSubjectError rewrites the field keys of a worker row error to
+// the subjects_<index>_<field> form so multi-worker forms can show the error
+// beside the specific worker input.
+func indexWorkRecordSubjectError(err error, index int) error {
+	var hookErr *errs.HookError
+	if !errors.As(err, &hookErr) {
+		return err
+	}
+	data := make(map[string]errs.CodeError, len(hookErr.Data))
+	for field, codeErr := range hookErr.Data {
+		// Roster-level errors describe the whole list rather than one row.
+		if field == "global" || field == "subjects" {
+			data[field] = codeErr
+			continue
+		}
+		data[fmt.Sprintf("subjects_%d_%s", index, field)] = codeErr
+	}
+	return &errs.HookError{Status: hookErr.Status, Message: hookErr.Message, Data: data}
+}
+
+// validateWorkRecordRosterInput checks the submitted roster before anything is
+// saved: every worker must be unique and hold the time claim, and a caller
+// who is not on the roster needs the work_record claim to create records for
+// other people.
+func validateWorkRecordRosterInput(app core.App, auth *core.Record, subjects []WorkRecordSubjectInput, enforceOnBehalf bool) error {
+	if len(subjects) == 0 {
+		return workRecordRouteError(http.StatusBadRequest, "subjects", "required", "a work record needs at least one worker")
+	}
+	seen := map[string]bool{}
+	includesCaller := false
+	for i, subject := range subjects {
+		uid := strings.TrimSpace(subject.UID)
+		if uid == "" {
+			return workRecordRouteError(http.StatusBadRequest, fmt.Sprintf("subjects_%d_uid", i), "required", "worker is required")
+		}
+		if seen[uid] {
+			return workRecordRouteError(http.StatusBadRequest, fmt.Sprintf("subjects_%d_uid", i), "duplicate_worker", "a worker may only appear once on a work record")
+		}
+		seen[uid] = true
+		if uid == auth.Id {
+			includesCaller = true
+		}
+		hasTime, err := utilities.HasClaimByUserID(app, uid, "time")
+		if err != nil {
+			return err
+		}
+		if !hasTime {
+			return workRecordRouteError(http.StatusBadRequest, fmt.Sprintf("subjects_%d_uid", i), "missing_time_claim", "workers must hold the time claim")
+		}
+	}
+	if enforceOnBehalf && !includesCaller {
+		hasWorkRecord, err := utilities.HasClaim(app, auth, "work_record")
+		if err != nil {
+			return err
+		}
+		if !hasWorkRecord {
+			return workRecordRouteError(http.StatusForbidden, "subjects", "unauthorized", "the work_record claim is required to create work records for other people")
+		}
+	}
+	return nil
+}
+
+// saveWorkRecordRoster applies the submitted roster as the desired final set.
+// Omitted rows are deleted first so a smaller roster and a type with a lower
+// max_subjects can be saved together; the roster as a whole is then validated.
+func saveWorkRecordRoster(txApp core.App, workRecordID string, subjects []WorkRecordSubjectInput) error {
+	existing, err := txApp.FindRecordsByFilter("work_records_subjects", "work_record = {:id}", "", 0, 0, dbx.Params{"id": workRecordID})
+	if err != nil {
+		return err
+	}
+	existingByUID := map[string]*core.Record{}
+	for _, record := range existing {
+		existingByUID[record.GetString("uid")] = record
+	}
+	wanted := map[string]bool{}
+	for _, subject := range subjects {
+		wanted[strings.TrimSpace(subject.UID)] = true
+	}
+	for uid, record := range existingByUID {
+		if wanted[uid] {
+			continue
+		}
+		if err := txApp.Delete(record); err != nil {
+			return err
+		}
+	}
+
+	collection, err := txApp.FindCollectionByNameOrId("work_records_subjects")
+	if err != nil {
+		return err
+	}
+	for i, subject := range subjects {
+		uid := strings.TrimSpace(subject.UID)
+		record, ok := existingByUID[uid]
+		if !ok {
+			record = core.NewRecord(collection)
+			record.Set("work_record", workRecordID)
+			record.Set("uid", uid)
+		}
+		setWorkRecordSubjectFields(record, subject)
+		if err := saveWorkRecordModel(txApp, record); err != nil {
+			return indexWorkRecordSubjectError(err, i)
+		}
+	}
+	return hooks.ValidateWorkRecordRoster(txApp, workRecordID)
+}
+
+// saveWorkRecordConsumables applies the submitted consumables as the desired
+// final set. Zero quantities and unselected booleans are stored as absent
+// rows rather than empty ones.
+func saveWorkRecordConsumables(txApp core.App, workRecordID string, consumables []WorkRecordConsumableInput) error {
+	existing, err := txApp.FindRecordsByFilter("work_records_consumable_entries", "work_record = {:id}", "", 0, 0, dbx.Params{"id": workRecordID})
+	if err != nil {
+		return err
+	}
+	existingByConsumable := map[string]*core.Record{}
+	for _, record := range existing {
+		existingByConsumable[record.GetString("consumable")] = record
+	}
+
+	collection, err := txApp.FindCollectionByNameOrId("work_records_consumable_entries")
+	if err != nil {
+		return err
+	}
+	kept := map[string]bool{}
+	for _, input := range consumables {
+		consumableID := strings.TrimSpace(input.Consumable)
+		consumable, err := txApp.FindRecordById("work_records_consumables", consumableID)
+		if err != nil {
+			return workRecordRouteError(http.StatusBadRequest, "consumables", "not_found", "consumable not found")
+		}
+		present := input.QuantityNumber > 0
+		quantity := input.QuantityNumber
+		if consumable.GetString("input_kind") == "boolean" {
+			present = input.Selected
+			quantity = 0
+		}
+		if !present {
+			continue
+		}
+		kept[consumableID] = true
+		record, ok := existingByConsumable[consumableID]
+		if !ok {
+			record = core.NewRecord(collection)
+			record.Set("work_record", workRecordID)
+			record.Set("consumable", consumableID)
+		}
+		record.Set("quantity_number", quantity)
+		if err := saveWorkRecordModel(txApp, record); err != nil {
+			return err
+		}
+	}
+	for consumableID, record := range existingByConsumable {
+		if kept[consumableID] {
+			continue
+		}
+		if err := txApp.Delete(record); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// createCreateWorkRecordHandler creates a work record with its worker roster
+// and consumables in one transaction. When copy_from is set the new record
+// joins the source's lineage group: the job is locked to the source job and
+// parent_work_record points at the root of the group.
+func createCreateWorkRecordHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		var req WorkRecordSaveRequest
+		if err := e.BindBody(&req); err != nil {
+			return e.BadRequestError("invalid request body", err)
+		}
+		subjects := []WorkRecordSubjectInput{}
+		if req.Subjects != nil {
+			subjects = *req.Subjects
+		}
+		if err := validateWorkRecordRosterInput(app, e.Auth, subjects, true); err != nil {
+			return writeHookError(e, err)
+		}
+
+		parentWorkRecord := ""
+		if copyFrom := strings.TrimSpace(req.CopyFrom); copyFrom != "" {
+			source, _, err := loadWorkRecordForCaller(app, copyFrom, e.Auth)
+			if err != nil {
+				return writeHookError(e, err)
+			}
+			if strings.TrimSpace(req.Job) != source.GetString("job") {
+				return writeHookError(e, workRecordRouteError(http.StatusBadRequest, "job", "copy_job_locked", "a copied work record must use the same job as its source"))
+			}
+			parentWorkRecord = source.GetString("parent_work_record")
+			if parentWorkRecord == "" {
+				parentWorkRecord = source.Id
+			}
+		}
+
+		var workRecordID string
+		err := app.RunInTransaction(func(txApp core.App) error {
+			collection, err := txApp.FindCollectionByNameOrId("work_records")
+			if err != nil {
+				return err
+			}
+			record := core.NewRecord(collection)
+			setWorkRecordSharedFields(record, req)
+			record.Set("creator", e.Auth.Id)
+			record.Set("parent_work_record", parentWorkRecord)
+			if err := saveWorkRecordModel(txApp, record); err != nil {
+				return err
+			}
+			workRecordID = record.Id
+
+			if err := saveWorkRecordRoster(txApp, record.Id, subjects); err != nil {
+				return err
+			}
+			if req.Consumables != nil {
+				return saveWorkRecordConsumables(txApp, record.Id, *req.Consumables)
+			}
+			return nil
+		})
+		if err != nil {
+			return writeHookError(e, err)
+		}
+
+		details, err := loadWorkRecordDetails(app, workRecordID)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load work record", err)
+		}
+		return e.JSON(http.StatusCreated, details)
+	}
+}
+
+// createUpdateWorkRecordHandler updates shared fields and, optionally, the
+// worker roster and consumables. The creator and any worker may edit shared
+// fields; only the creator may change the roster. Approval locking is
+// enforced by the model hooks.
+func createUpdateWorkRecordHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		workRecord, role, err := loadWorkRecordForCaller(app, e.Request.PathValue("id"), e.Auth)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+		if !role.canEdit() {
+			return writeHookError(e, workRecordRouteError(http.StatusForbidden, "global", "unauthorized", "only the creator and workers on this work record can edit it"))
+		}
+
+		var req WorkRecordSaveRequest
+		if err := e.BindBody(&req); err != nil {
+			return e.BadRequestError("invalid request body", err)
+		}
+		if req.Subjects != nil {
+			if !role.Creator {
+				return writeHookError(e, workRecordRouteError(http.StatusForbidden, "subjects", "unauthorized", "only the creator can change the worker roster"))
+			}
+			if err := validateWorkRecordRosterInput(app, e.Auth, *req.Subjects, false); err != nil {
+				return writeHookError(e, err)
+			}
+		}
+
+		err = app.RunInTransaction(func(txApp core.App) error {
+			record, err := txApp.FindRecordById("work_records", workRecord.Id)
+			if err != nil {
+				return err
+			}
+			setWorkRecordSharedFields(record, req)
+			if err := saveWorkRecordModel(txApp, record); err != nil {
+				return err
+			}
+			if req.Subjects != nil {
+				if err := saveWorkRecordRoster(txApp, record.Id, *req.Subjects); err != nil {
+					return err
+				}
+			} else if err := hooks.ValidateWorkRecordRoster(txApp, record.Id); err != nil {
+				// job, date and type changes are validated against the
+				// unchanged roster
+				return err
+			}
+			if req.Consumables != nil {
+				return saveWorkRecordConsumables(txApp, record.Id, *req.Consumables)
+			}
+			return nil
+		})
+		if err != nil {
+			return writeHookError(e, err)
+		}
+
+		details, err := loadWorkRecordDetails(app, workRecord.Id)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load work record", err)
+		}
+		return e.JSON(http.StatusOK, details)
+	}
+}
+
+func createGetWorkRecordHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		workRecord, _, err := loadWorkRecordForCaller(app, e.Request.PathValue("id"), e.Auth)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+		details, err := loadWorkRecordDetails(app, workRecord.Id)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load work record", err)
+		}
+		return e.JSON(http.StatusOK, details)
+	}
+}
+
+// createUpdateWorkRecordSubjectHandler updates the per-person values of one
+// worker row. Workers may edit their own row and the creator may edit any row;
+// approved rows are rejected by the model hook.
+func createUpdateWorkRecordSubjectHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		subject, err := app.FindRecordById("work_records_subjects", e.Request.PathValue("id"))
+		if err != nil {
+			return writeHookError(e, workRecordRouteError(http.StatusNotFound, "work_record_subject", "not_found", "work record worker row not found"))
+		}
+		workRecord, role, err := loadWorkRecordForCaller(app, subject.GetString("work_record"), e.Auth)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+		if !role.Creator && subject.GetString("uid") != e.Auth.Id {
+			return writeHookError(e, workRecordRouteError(http.StatusForbidden, "global", "unauthorized", "you can only edit your own worker row"))
+		}
+
+		var input WorkRecordSubjectInput
+		if err := e.BindBody(&input); err != nil {
+			return e.BadRequestError("invalid request body", err)
+		}
+		if uid := strings.TrimSpace(input.UID); uid != "" && uid != subject.GetString("uid") {
+			return writeHookError(e, workRecordRouteError(http.StatusBadRequest, "uid", "immutable", "worker rows cannot be reassigned; remove the row and add a new one instead"))
+		}
+		setWorkRecordSubjectFields(subject, input)
+		if err := saveWorkRecordModel(app, subject); err != nil {
+			return writeHookError(e, err)
+		}
+
+		details, err := loadWorkRecordDetails(app, workRecord.Id)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load work record", err)
+		}
+		return e.JSON(http.StatusOK, details)
+	}
+}
+
+// createAddWorkRecordNoteHandler appends a note. Notes are append-only and
+// remain allowed after approval.
+func createAddWorkRecordNoteHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		workRecord, role, err := loadWorkRecordForCaller(app, e.Request.PathValue("id"), e.Auth)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+		if !role.canEdit() {
+			return writeHookError(e, workRecordRouteError(http.StatusForbidden, "global", "unauthorized", "only the creator and workers on this work record can add notes"))
+		}
+
+		var req struct {
+			Note string `json:"note"`
+		}
+		if err := e.BindBody(&req); err != nil {
+			return e.BadRequestError("invalid request body", err)
+		}
+		note := strings.TrimSpace(req.Note)
+		if note == "" {
+			return writeHookError(e, workRecordRouteError(http.StatusBadRequest, "note", "required", "note is required"))
+		}
+
+		collection, err := app.FindCollectionByNameOrId("work_records_notes")
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load notes collection", err)
+		}
+		record := core.NewRecord(collection)
+		record.Set("work_record", workRecord.Id)
+		record.Set("uid", e.Auth.Id)
+		record.Set("note", note)
+		if err := saveWorkRecordModel(app, record); err != nil {
+			return writeHookError(e, err)
+		}
+
+		details, err := loadWorkRecordDetails(app, workRecord.Id)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load work record", err)
+		}
+		return e.JSON(http.StatusCreated, details)
+	}
+}
+
+// createUploadWorkRecordAttachmentHandler stores or replaces the scanned paper
+// copy of a saved work record. The SHA-256 of the file is stored so the same
+// scan cannot be attached to two work records.
+func createUploadWorkRecordAttachmentHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		workRecord, role, err := loadWorkRecordForCaller(app, e.Request.PathValue("id"), e.Auth)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+		if !role.canEdit() {
+			return writeHookError(e, workRecordRouteError(http.StatusForbidden, "global", "unauthorized", "only the creator and workers on this work record can change its attachment"))
+		}
+
+		files, err := e.FindUploadedFiles("attachment")
+		if err != nil && !errors.Is(err, http.ErrMissingFile) {
+			return e.BadRequestError("failed to read uploaded attachment", err)
+		}
+		if len(files) != 1 {
+			return writeHookError(e, workRecordRouteError(http.StatusBadRequest, "attachment", "required", "upload exactly one work record PDF"))
+		}
+		if !uploadedFileLooksLikePDF(files[0]) {
+			return writeHookError(e, workRecordRouteError(http.StatusBadRequest, "attachment", "invalid_mime_type", "work record attachment must be a PDF"))
+		}
+		attachmentHash, err := hashUploadedFileSHA256(files[0])
+		if err != nil {
+			return e.InternalServerError("failed to hash work record attachment", err)
+		}
+
+		err = app.RunInTransaction(func(txApp core.App) error {
+			existing, _ := txApp.FindFirstRecordByFilter("work_records", "attachment_hash = {:hash} && id != {:id}", dbx.Params{
+				"hash": attachmentHash,
+				"id":   workRecord.Id,
+			})
+			if existing != nil {
+				return workRecordRouteError(http.StatusBadRequest, "attachment", "duplicate_file", duplicateWorkRecordAttachmentMessage)
+			}
+			record, err := txApp.FindRecordById("work_records", workRecord.Id)
+			if err != nil {
+				return err
+			}
+			record.Set("attachment", files[0])
+			record.Set("attachment_hash", attachmentHash)
+			if err := saveWorkRecordModel(txApp, record); err != nil {
+				if isUniqueConstraintError(err) {
+					return workRecordRouteError(http.StatusBadRequest, "attachment", "duplicate_file", duplicateWorkRecordAttachmentMessage)
+				}
+				return err
+			}
+			return nil
+		})
+		if err != nil {
+			return writeHookError(e, err)
+		}
+
+		details, err := loadWorkRecordDetails(app, workRecord.Id)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load work record", err)
+		}
+		return e.JSON(http.StatusOK, details)
+	}
+}
+
+func createDeleteWorkRecordAttachmentHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		workRecord, role, err := loadWorkRecordForCaller(app, e.Request.PathValue("id"), e.Auth)
+		if err != nil {
+			return writeHookError(e, err)
+		}
+		if !role.canEdit() {
+			return writeHookError(e, workRecordRouteError(http.StatusForbidden, "global", "unauthorized", "only the creator and workers on this work record can change its attachment"))
+		}
+
+		record, err := app.FindRecordById("work_records", workRecord.Id)
+		if err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to load work record", err)
+		}
+		record.Set("attachment", nil)
+		record.Set("attachment_hash", "")
+		if err := saveWorkRecordModel(app, record); err != nil {
+			return writeHookError(e, err)
+		}
+		return e.NoContent(http.StatusNoContent)
+	}
+}
@@ -0,0 +1,322 @@
+package routes
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Fixtures: work record wrfixture000001 on job cjf0kt0defhq480 dated
+// 2026-04-13 was created by author@soup.com (time and work_record claims) and
+// has worker rows for the author and time@test.com (time claim only).
+// fatt@mac.com holds the report claim and u_no_claims@example.com holds none.
+const (
+	wrFixtureID          = "wrfixture000001"
+	wrAuthorSubjectID    = "wrsubjauthor001"
+	wrTimeSubjectID      = "wrsubjtime00001"
+	wrJobID              = "cjf0kt0defhq480"
+	wrTrafficTypeID      = "wrtypetraffic01"
+	wrInspectionTypeID   = "wrtypeinspect01"
+	wrConesID            = "wrconscones0001"
+	wrArrowBoardID       = "wrconsarrowbrd1"
+	wrPaintID            = "wrconspaint0001"
+	wrAuthorEmail        = "author@soup.com"
+	wrAuthorUID          = "f2j5a8vk006baub"
+	wrWorkerEmail        = "time@test.com"
+	wrWorkerUID          = "rzr98oadsp9qc11"
+	wrReportEmail        = "fatt@mac.com"
+	wrNoClaimsEmail      = "u_no_claims@example.com"
+	wrRecordsPath        = "/api/first_class_work_records"
+	wrFixtureRecordPath  = wrRecordsPath + "/" + wrFixtureID
+	wrNewRecordDate      = "2026-04-14"
+	wrSubjectsPathPrefix = "/api/first_class_work_records/subjects/"
+)
+
+// decodeWorkRecordDetailsForTest also decodes 201 responses, which the shared
+// decodeJSONResponseForTest helper skips.
+func decodeWorkRecordDetailsForTest(t *testing.T, rec *httptest.ResponseRecorder, status int, label string) WorkRecordDetails {
+	t.Helper()
+	if rec.Code != status {
+		t.Fatalf("%s status = %d, want %d; body=%s", label, rec.Code, status, rec.Body.String())
+	}
+	var details WorkRecordDetails
+	if err := json.Unmarshal(rec.Body.Bytes(), &details); err != nil {
+		t.Fatalf("failed to decode %s response: %v", label, err)
+	}
+	return details
+}
+
+func workRecordCreateBody(date string, typeID string, subjects []map[string]any) map[string]any {
+	return map[string]any{
+		"date":             date,
+		"job":              wrJobID,
+		"type":             typeID,
+		"location":         "Clarke St at Main",
+		"work_description": "Lane closure for crane lift",
+		"subjects":         subjects,
+	}
+}
+
+func workRecordSubjectBody(uid string, onSite float64, travel float64) map[string]any {
+	return map[string]any{"uid": uid, "hours_on_site": onSite, "hours_travel_time": travel}
+}
+
+func TestWorkRecordCreateAssignsNumberRosterAndConsumables(t *testing.T) {
+	app := newProjectAuthorizationTestApp(t)
+	token := authTokenForEmail(t, app, wrAuthorEmail)
+
+	body := workRecordCreateBody(wrNewRecordDate, wrTrafficTypeID, []map[string]any{
+		workRecordSubjectBody(wrAuthorUID, 7, 1),
+		workRecordSubjectBody(wrWorkerUID, 8, 0),
+	})
+	body["lane_kms"] = 2.5
+	body["consumables"] = []map[string]any{
+		{"consumable": wrConesID, "quantity_number": 20},
+		{"consumable": wrArrowBoardID, "selected": true},
+	}
+	rec := performClaimsJSONRequest(t, app, http.MethodPost, wrRecordsPath, token, body)
+	details := decodeWorkRecordDetailsForTest(t, rec, http.StatusCreated, "create")
+
+	if !regexp.MustCompile(`^W[0-9]{4}-[0-9]{4}$`).MatchString(details.Number) {
+		t.Fatalf("number = %q, want backend generated WYYMM-NNNN", details.Number)
+	}
+	if details.Creator != wrAuthorUID || details.ParentWorkRecord != "" || details.Locked {
+		t.Fatalf("details = %+v, want unlocked root record created by author", details)
+	}
+	if len(details.Subjects) != 2 || len(details.Consumables) != 2 {
+		t.Fatalf("subjects = %+v consumables = %+v", details.Subjects, details.Consumables)
+	}
+
+	copyBody := workRecordCreateBody("2026-04-15", wrTrafficTypeID, []map[string]any{workRecordSubjectBody(wrAuthorUID, 8, 0)})
+	copyBody["copy_from"] = details.ID
+	copyRec := performClaimsJSONRequest(t, app, http.MethodPost, wrRecordsPath, token, copyBody)
+	copied := decodeWorkRecordDetailsForTest(t, copyRec, http.StatusCreated, "copy")
+	if copied.ParentWorkRecord != details.ID || copied.Number == details.Number {
+		t.Fatalf("copied = %+v, want independent number linked to root %s", copied, details.ID)
+	}
+
+	// Copying from the copy still points at the root of the lineage group.
+	copyBody["copy_from"] = copied.ID
+	copyBody["date"] = "2026-04-16"
+	secondRec := performClaimsJSONRequest(t, app, http.MethodPost, wrRecordsPath, token, copyBody)
+	second := decodeWorkRecordDetailsForTest(t, secondRec, http.StatusCreated, "second copy")
+	if second.ParentWorkRecord != details.ID {
+		t.Fatalf("second copy parent = %q, want root %s", second.ParentWorkRecord, details.ID)
+	}
+}
+
+func TestWorkRecordCreateValidation(t *testing.T) {
+	scenarios := []struct {
+		name   string
+		email  string
+		body   map[string]any
+		status int
+		field  string
+		code   string
+	}{
+		{
+			name:   "time holder cannot create only for others",
+			email:  wrWorkerEmail,
+			body:   workRecordCreateBody(wrNewRecordDate, wrTrafficTypeID, []map[string]any{workRecordSubjectBody(wrAuthorUID, 8, 0)}),
+			status: http.StatusForbidden,
+			field:  "subjects",
+			code:   "unauthorized",
+		},
+		{
+			name:   "workers need the time claim",
+			email:  wrAuthorEmail,
+			body:   workRecordCreateBody(wrNewRecordDate, wrTrafficTypeID, []map[string]any{workRecordSubjectBody(wrAuthorUID, 8, 0), workRecordSubjectBody("u_no_claims", 8, 0)}),
+			status: http.StatusBadRequest,
+			field:  "subjects_1_uid",
+			code:   "missing_time_claim",
+		},
+		{
+			name:   "roster exceeds max_subjects",
+			email:  wrAuthorEmail,
+			body:   workRecordCreateBody(wrNewRecordDate, wrInspectionTypeID, []map[string]any{workRecordSubjectBody(wrAuthorUID, 8, 0), workRecordSubjectBody(wrWorkerUID, 8, 0)}),
+			status: http.StatusBadRequest,
+			field:  "subjects",
+			code:   "too_many_subjects",
+		},
+		{
+			name:  "lane_kms not allowed for type",
+			email: wrAuthorEmail,
+			body: func() map[string]any {
+				body := workRecordCreateBody(wrNewRecordDate, wrInspectionTypeID, []map[string]any{workRecordSubjectBody(wrAuthorUID, 8, 0)})
+				body["lane_kms"] = 1
+				return body
+			}(),
+			status: http.StatusBadRequest,
+			field:  "lane_kms",
+			code:   "not_allowed",
+		},
+		{
+			name:   "worker already on a record for the job and date",
+			email:  wrAuthorEmail,
+			body:   workRecordCreateBody("2026-04-13", wrInspectionTypeID, []map[string]any{workRecordSubjectBody(wrAuthorUID, 8, 0)}),
+			status: http.StatusBadRequest,
+			field:  "subjects",
+			code:   "duplicate_worker_for_day",
+		},
+		{
+			name:   "company vehicle needs unit number",
+			email:  wrAuthorEmail,
+			body:   workRecordCreateBody(wrNewRecordDate, wrTrafficTypeID, []map[string]any{{"uid": wrAuthorUID, "hours_on_site": 8, "vehicle_type": "company"}}),
+			status: http.StatusBadRequest,
+			field:  "subjects_0_company_vehicle_unit_number",
+			code:   "required",
+		},
+		{
+			name:  "consumable not allowed for type",
+			email: wrAuthorEmail,
+			body: func() map[string]any {
+				body := workRecordCreateBody(wrNewRecordDate, wrTrafficTypeID, []map[string]any{workRecordSubjectBody(wrAuthorUID, 8, 0)})
+				body["consumables"] = []map[string]any{{"consumable": wrPaintID, "quantity_number": 2}}
+				return body
+			}(),
+			status: http.StatusBadRequest,
+			field:  "consumable",
+			code:   "not_allowed",
+		},
+	}
+
+	for _, scenario := range scenarios {
+		t.Run(scenario.name, func(t *testing.T) {
+			app := newProjectAuthorizationTestApp(t)
+			token := authTokenForEmail(t, app, scenario.email)
+			rec := performClaimsJSONRequest(t, app, http.MethodPost, wrRecordsPath, token, scenario.body)
+			if rec.Code != scenario.status {
+				t.Fatalf("status = %d, want %d; body=%s", rec.Code, scenario.status, rec.Body.String())
+			}
+			want := `"` + scenario.field + `":{"code":"` + scenario.code + `"`
+			if !strings.Contains(rec.Body.String(), want) {
+				t.Fatalf("body = %s, want %s", rec.Body.String(), want)
+			}
+			if count, _ := app.CountRecords("work_records"); count != 1 {
+				t.Fatalf("work_records count = %d, want failed create rolled back", count)
+			}
+		})
+	}
+}
+
+func TestWorkRecordVisibility(t *testing.T) {
+	app := newProjectAuthorizationTestApp(t)
+
+	for _, email := range []string{wrAuthorEmail, wrWorkerEmail, wrReportEmail} {
+		rec := performClaimsJSONRequest(t, app, http.MethodGet, wrFixtureRecordPath, authTokenForEmail(t, app, email), nil)
+		details := decodeJSONResponseForTest[WorkRecordDetails](t, rec, http.StatusOK, email+" detail")
+		if details.Number != "W2604-0001" || len(details.Subjects) != 2 || len(details.Notes) != 1 || len(details.Consumables) != 1 {
+			t.Fatalf("%s detail = %+v", email, details)
+		}
+	}
+
+	noClaimsToken := authTokenForEmail(t, app, wrNoClaimsEmail)
+	hidden := performClaimsJSONRequest(t, app, http.MethodGet, wrFixtureRecordPath, noClaimsToken, nil)
+	if hidden.Code != http.StatusNotFound {
+		t.Fatalf("unrelated user detail = %d, want not found; body=%s", hidden.Code, hidden.Body.String())
+	}
+
+	type listResponse struct {
+		TotalItems int `json:"totalItems"`
+	}
+	workerList := performClaimsJSONRequest(t, app, http.MethodGet, "/api/collections/work_records/records", authTokenForEmail(t, app, wrWorkerEmail), nil)
+	if got := decodeJSONResponseForTest[listResponse](t, workerList, http.StatusOK, "worker list"); got.TotalItems != 1 {
+		t.Fatalf("worker list totalItems = %d, want 1", got.TotalItems)
+	}
+	otherList := performClaimsJSONRequest(t, app, http.MethodGet, "/api/collections/work_records/records", noClaimsToken, nil)
+	if got := decodeJSONResponseForTest[listResponse](t, otherList, http.StatusOK, "unrelated list"); got.TotalItems != 0 {
+		t.Fatalf("unrelated list totalItems = %d, want 0", got.TotalItems)
+	}
+
+	create := performClaimsJSONRequest(t, app, http.MethodPost, "/api/collections/work_records_notes/records", authTokenForEmail(t, app, wrWorkerEmail), map[string]any{
+		"work_record": wrFixtureID,
+		"uid":         wrWorkerUID,
+		"note":        "direct write",
+	})
+	if create.Code == http.StatusOK {
+		t.Fatalf("generic note create should be denied; body=%s", create.Body.String())
+	}
+}
+
+func TestWorkRecordUpdateRosterPermissionsAndLock(t *testing.T) {
+	app := newProjectAuthorizationTestApp(t)
+	authorToken := authTokenForEmail(t, app, wrAuthorEmail)
+	workerToken := authTokenForEmail(t, app, wrWorkerEmail)
+
+	update := workRecordCreateBody("2026-04-13", wrTrafficTypeID, []map[string]any{workRecordSubjectBody(wrAuthorUID, 4, 1)})
+	workerRoster := performClaimsJSONRequest(t, app, http.MethodPut, wrFixtureRecordPath, workerToken, update)
+	if workerRoster.Code != http.StatusForbidden {
+		t.Fatalf("worker roster change = %d, want forbidden; body=%s", workerRoster.Code, workerRoster.Body.String())
+	}
+
+	sharedOnly := workRecordCreateBody("2026-04-13", wrTrafficTypeID, nil)
+	delete(sharedOnly, "subjects")
+	sharedOnly["location"] = "Clarke St at King"
+	workerShared := performClaimsJSONRequest(t, app, http.MethodPut, wrFixtureRecordPath, workerToken, sharedOnly)
+	shared := decodeJSONResponseForTest[WorkRecordDetails](t, workerShared, http.StatusOK, "worker shared update")
+	if shared.Location != "Clarke St at King" || len(shared.Subjects) != 2 {
+		t.Fatalf("worker shared update = %+v", shared)
+	}
+
+	pruned := performClaimsJSONRequest(t, app, http.MethodPut, wrFixtureRecordPath, authorToken, update)
+	prunedDetails := decodeJSONResponseForTest[WorkRecordDetails](t, pruned, http.StatusOK, "creator roster update")
+	if len(prunedDetails.Subjects) != 1 || prunedDetails.Subjects[0].UID != wrAuthorUID {
+		t.Fatalf("pruned roster = %+v, want author only", prunedDetails.Subjects)
+	}
+
+	subject, err := app.FindRecordById("work_records_subjects", wrAuthorSubjectID)
+	if err != nil {
+		t.Fatalf("failed to load worker row: %v", err)
+	}
+	// Approval is normally synchronized from the linked time sheet; setting it
+	// directly isolates the route's lock handling.
+	subject.Set("approved", true)
+	if err := app.Save(subject); err != nil {
+		t.Fatalf("failed to approve worker row: %v", err)
+	}
+
+	locked := performClaimsJSONRequest(t, app, http.MethodPut, wrFixtureRecordPath, authorToken, sharedOnly)
+	if locked.Code != http.StatusConflict || !strings.Contains(locked.Body.String(), "work_record_locked") {
+		t.Fatalf("locked update = %d; body=%s", locked.Code, locked.Body.String())
+	}
+	lockedRow := performClaimsJSONRequest(t, app, http.MethodPatch, wrSubjectsPathPrefix+wrAuthorSubjectID, authorToken, workRecordSubjectBody(wrAuthorUID, 3, 1))
+	if lockedRow.Code != http.StatusConflict || !strings.Contains(lockedRow.Body.String(), "work_record_subject_locked") {
+		t.Fatalf("approved row update = %d; body=%s", lockedRow.Code, lockedRow.Body.String())
+	}
+	note := performClaimsJSONRequest(t, app, http.MethodPost, wrFixtureRecordPath+"/notes", authorToken, map[string]any{"note": "Invoice sent to client"})
+	noteDetails := decodeWorkRecordDetailsForTest(t, note, http.StatusCreated, "note after approval")
+	if len(noteDetails.Notes) != 2 || !noteDetails.Locked {
+		t.Fatalf("note details = %+v, want appended note on locked record", noteDetails)
+	}
+}
+
+func TestWorkRecordSubjectPatchPermissions(t *testing.T) {
+	app := newProjectAuthorizationTestApp(t)
+	workerToken := authTokenForEmail(t, app, wrWorkerEmail)
+
+	others := performClaimsJSONRequest(t, app, http.MethodPatch, wrSubjectsPathPrefix+wrAuthorSubjectID, workerToken, workRecordSubjectBody(wrAuthorUID, 2, 0))
+	if others.Code != http.StatusForbidden {
+		t.Fatalf("worker editing another row = %d, want forbidden; body=%s", others.Code, others.Body.String())
+	}
+
+	own := performClaimsJSONRequest(t, app, http.MethodPatch, wrSubjectsPathPrefix+wrTimeSubjectID, workerToken, map[string]any{
+		"hours_on_site":               5.5,
+		"hours_travel_time":           1,
+		"vehicle_type":                "company",
+		"company_vehicle_unit_number": "007",
+	})
+	details := decodeJSONResponseForTest[WorkRecordDetails](t, own, http.StatusOK, "own row update")
+	for _, subject := range details.Subjects {
+		if subject.ID == wrTimeSubjectID && (subject.HoursOnSite != 5.5 || subject.CompanyVehicleUnitNumber != "007") {
+			t.Fatalf("own row = %+v, want updated values", subject)
+		}
+	}
+
+	reassign := performClaimsJSONRequest(t, app, http.MethodPatch, wrSubjectsPathPrefix+wrTimeSubjectID, workerToken, workRecordSubjectBody(wrAuthorUID, 5, 0))
+	if reassign.Code != http.StatusBadRequest || !strings.Contains(reassign.Body.String(), "immutable") {
+		t.Fatalf("reassign = %d; body=%s", reassign.Code, reassign.Body.String())
+	}
+}
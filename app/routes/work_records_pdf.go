@@ -0,0 +1,242 @@
+package routes
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"tybalt/reports"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+type workRecordPDFTimeEntryRow struct {
+	Date        string  `db:"date"`
+	GivenName   string  `db:"given_name"`
+	Surname     string  `db:"surname"`
+	JobNumber   string  `db:"job_number"`
+	TimeType    string  `db:"time_type"`
+	Hours       float64 `db:"hours"`
+	Description string  `db:"description"`
+}
+
+// workRecordLinkedTimeEntriesQuery lists the time entries linked to the worker
+// rows of a first-class work record.
+const workRecordLinkedTimeEntriesQuery = `
+	SELECT
+		te.date,
+		COALESCE(p.given_name, '') AS given_name,
+		COALESCE(p.surname, '') AS surname,
+		COALESCE(j.number, '') AS job_number,
+		COALESCE(tt.code, '') AS time_type,
+		COALESCE(CAST(te.hours AS REAL), 0) AS hours,
+		COALESCE(te.description, '') AS description
+	FROM time_entries te
+	JOIN work_records_subjects s ON s.id = te.work_record_subject_id
+	LEFT JOIN profiles p ON p.uid = te.uid
+	LEFT JOIN jobs j ON j.id = te.job
+	LEFT JOIN time_types tt ON tt.id = te.time_type
+	WHERE s.work_record = {:id}
+	ORDER BY te.date, p.surname, p.given_name
+`
+
+// createGetWorkRecordPDFHandler renders a print-ready PDF of a work record.
+// The path value is resolved as a first-class work record number or id first,
+// using the same visibility as the first-class detail route; otherwise it is
+// treated as a legacy time entry work_record value and uses the same access
+// as the legacy details route.
+func createGetWorkRecordPDFHandler(app core.App) func(e *core.RequestEvent) error {
+	return func(e *core.RequestEvent) error {
+		workRecord := strings.TrimSpace(e.Request.PathValue("workRecord"))
+		if workRecord == "" {
+			return e.Error(http.StatusBadRequest, "workRecord is required", nil)
+		}
+
+		record, err := app.FindFirstRecordByFilter("work_records", "number = {:value} || id = {:value}", dbx.Params{"value": workRecord})
+		if err == nil && record != nil {
+			return writeFirstClassWorkRecordPDF(app, e, record.Id)
+		}
+
+		if err := requireWorkRecordViewer(app, e.Auth); err != nil {
+			return writeHookError(e, err)
+		}
+		var rows []WorkRecordEntryRow
+		if err := app.DB().NewQuery(workRecordsDetailsQuery).Bind(dbx.Params{
+			"work_record": workRecord,
+		}).All(&rows); err != nil {
+			return e.Error(http.StatusInternalServerError, "failed to execute work record details query", err)
+		}
+		if len(rows) == 0 {
+			return writeHookError(e, workRecordRouteError(http.StatusNotFound, "work_record", "not_found", "work record not found"))
+		}
+
+		title := "Work Record " + workRecord
+		doc := reports.NewPDFDocument(title, false)
+		doc.Heading(title, 1)
+		doc.Fields([][2]string{
+			{"Source", "Time entries"},
+			{"Entries", strconv.Itoa(len(rows))},
+		})
+		doc.Heading("Time Entries", 2)
+		tableRows := make([][]string, 0, len(rows)+1)
+		total := 0.0
+		for _, row := range rows {
+			total += row.Hours
+			tableRows = append(tableRows, []string{row.WeekEnding, workRecordPDFName(row.GivenName, row.Surname), row.JobNumber, reports.FormatPDFNumber(row.Hours), row.Description})
+		}
+		tableRows = append(tableRows, []string{"Total", "", "", reports.FormatPDFNumber(total), ""})
+		doc.Table([]reports.PDFColumn{
+			{Header: "Week Ending", Width: 0.14},
+			{Header: "Worker", Width: 0.2},
+			{Header: "Job", Width: 0.12},
+			{Header: "Hours", Width: 0.08, AlignRight: true},
+			{Header: "Description", Width: 0.46},
+		}, tableRows)
+
+		return reports.WritePDFResponse(e, "work_record_"+workRecord+".pdf", doc)
+	}
+}
+
+func writeFirstClassWorkRecordPDF(app core.App, e *core.RequestEvent, workRecordID string) error {
+	record, _, err := loadWorkRecordForCaller(app, workRecordID, e.Auth)
+	if err != nil {
+		return writeHookError(e, err)
+	}
+	details, err := loadWorkRecordDetails(app, record.Id)
+	if err != nil {
+		return e.Error(http.StatusInternalServerError, "failed to load work record", err)
+	}
+	var entries []workRecordPDFTimeEntryRow
+	if err := app.DB().NewQuery(workRecordLinkedTimeEntriesQuery).Bind(dbx.Params{"id": record.Id}).All(&entries); err != nil {
+		return e.Error(http.StatusInternalServerError, "failed to load linked time entries", err)
+	}
+
+	job := ""
+	if jobRecord, err := app.FindRecordById("jobs", details.Job); err == nil {
+		job = strings.TrimSpace(jobRecord.GetString("number") + " " + jobRecord.GetString("description"))
+	}
+	typeName := ""
+	if typeRecord, err := app.FindRecordById("work_records_types", details.Type); err == nil {
+		typeName = typeRecord.GetString("name")
+	}
+	parentNumber := ""
+	if details.ParentWorkRecord != "" {
+		if parent, err := app.FindRecordById("work_records", details.ParentWorkRecord); err == nil {
+			parentNumber = parent.GetString("number")
+		}
+	}
+	fieldBook := details.FieldBookNumber
+	if details.FieldBookPageNumber > 0 {
+		fieldBook = strings.TrimSpace(fmt.Sprintf("%s page %d", fieldBook, details.FieldBookPageNumber))
+	}
+	status := "Open"
+	if details.Locked {
+		status = "Approved (locked)"
+	}
+
+	title := "Work Record " + details.Number
+	doc := reports.NewPDFDocument(title, false)
+	doc.Heading(title, 1)
+	doc.Fields([][2]string{
+		{"Date", details.Date},
+		{"Job", job},
+		{"Type", typeName},
+		{"Status", status},
+		{"Location", details.Location},
+		{"Work Description", details.WorkDescription},
+		{"Report To", details.ReportTo},
+		{"Field Book", fieldBook},
+		{"Lane km", reports.FormatPDFNumber(details.LaneKms)},
+		{"Sub-contractor", details.SubContractor},
+		{"Equipment", details.Equipment},
+		{"Supplies", details.Supplies},
+		{"Copied From", parentNumber},
+	})
+
+	doc.Heading("Workers", 2)
+	subjectRows := make([][]string, 0, len(details.Subjects))
+	for _, subject := range details.Subjects {
+		vehicle := subject.VehicleType
+		if subject.IsPassenger {
+			vehicle = strings.TrimSpace(vehicle + " (passenger)")
+		}
+		approved := ""
+		if subject.Approved {
+			approved = "Yes"
+		}
+		subjectRows = append(subjectRows, []string{
+			workRecordPDFName(subject.GivenName, subject.Surname),
+			reports.FormatPDFNumber(subject.HoursOnSite),
+			reports.FormatPDFNumber(subject.HoursTravelTime),
+			reports.FormatPDFNumber(subject.HoursOnSite + subject.HoursTravelTime),
+			reports.FormatPDFNumber(subject.DistanceTravelledKm),
+			vehicle,
+			subject.CompanyVehicleUnitNumber,
+			approved,
+		})
+	}
+	doc.Table([]reports.PDFColumn{
+		{Header: "Worker", Width: 0.24},
+		{Header: "On Site", Width: 0.09, AlignRight: true},
+		{Header: "Travel", Width: 0.09, AlignRight: true},
+		{Header: "Total", Width: 0.09, AlignRight: true},
+		{Header: "Km", Width: 0.09, AlignRight: true},
+		{Header: "Vehicle", Width: 0.18},
+		{Header: "Unit", Width: 0.1},
+		{Header: "Approved", Width: 0.12},
+	}, subjectRows)
+
+	if len(details.Consumables) > 0 {
+		doc.Heading("Consumables", 2)
+		consumableRows := make([][]string, 0, len(details.Consumables))
+		for _, consumable := range details.Consumables {
+			quantity := "Yes"
+			if consumable.InputKind == "number" {
+				quantity = strings.TrimSpace(reports.FormatPDFNumber(consumable.QuantityNumber) + " " + consumable.UnitLabel)
+			}
+			consumableRows = append(consumableRows, []string{consumable.Name, quantity})
+		}
+		doc.Table([]reports.PDFColumn{
+			{Header: "Consumable", Width: 0.6},
+			{Header: "Quantity", Width: 0.4},
+		}, consumableRows)
+	}
+
+	doc.Heading("Time Entries", 2)
+	if len(entries) == 0 {
+		doc.Paragraph("No time entries are linked to this work record yet.")
+	} else {
+		entryRows := make([][]string, 0, len(entries))
+		for _, entry := range entries {
+			entryRows = append(entryRows, []string{entry.Date, workRecordPDFName(entry.GivenName, entry.Surname), entry.JobNumber, entry.TimeType, reports.FormatPDFNumber(entry.Hours), entry.Description})
+		}
+		doc.Table([]reports.PDFColumn{
+			{Header: "Date", Width: 0.12},
+			{Header: "Worker", Width: 0.2},
+			{Header: "Job", Width: 0.1},
+			{Header: "Type", Width: 0.06},
+			{Header: "Hours", Width: 0.08, AlignRight: true},
+			{Header: "Description", Width: 0.44},
+		}, entryRows)
+	}
+
+	if len(details.Notes) > 0 {
+		doc.Heading("Notes", 2)
+		noteRows := make([][]string, 0, len(details.Notes))
+		for _, note := range details.Notes {
+			noteRows = append(noteRows, []string{note.Created, workRecordPDFName(note.GivenName, note.Surname), note.Note})
+		}
+		doc.Table([]reports.PDFColumn{
+			{Header: "Added", Width: 0.2},
+			{Header: "By", Width: 0.2},
+			{Header: "Note", Width: 0.6},
+		}, noteRows)
+	}
+
+	return reports.WritePDFResponse(e, "work_record_"+details.Number+".pdf", doc)
+}
+
+func workRecordPDFName(givenName string, surname string) string {
+	return strings.TrimSpace(givenName + " " + surname)
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"tybalt/internal/testutils"
+)
+
+type salesTaxTestExpense struct {
+	ID           string  `json:"id"`
+	Total        float64 `json:"total"`
+	TaxCode      string  `json:"tax_code"`
+	TaxAmount    float64 `json:"tax_amount"`
+	PreTaxAmount float64 `json:"pre_tax_amount"`
+}
+
+func TestExpenseSalesTax(t *testing.T) {
+	token, err := testutils.GenerateRecordToken("users", "time@test.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	app := testutils.SetupTestApp(t)
+	defer app.Cleanup()
+
+	capitalKind, err := app.FindFirstRecordByFilter("expenditure_kinds", "name = 'capital'")
+	if err != nil {
+		t.Fatalf("failed to load capital kind: %v", err)
+	}
+
+	send := func(method string, url string, fields map[string]any) (*salesTaxTestExpense, string) {
+		t.Helper()
+		body, err := json.Marshal(fields)
+		if err != nil {
+			t.Fatal(err)
+		}
+		res := performTestAPIRequest(t, app, method, url, strings.NewReader(string(body)), map[string]string{
+			"Authorization": token,
+			"Content-Type":  "application/json",
+		})
+		if res.Code != http.StatusOK {
+			return nil, res.Body.String()
+		}
+		var expense salesTaxTestExpense
+		if err := json.Unmarshal(res.Body.Bytes(), &expense); err != nil {
+			t.Fatalf("failed to decode expense: %v", err)
+		}
+		return &expense, ""
+	}
+	uploads := 0
+	create := func(fields map[string]string) (*salesTaxTestExpense, string) {
+		t.Helper()
+		base := map[string]string{
+			"uid":          "rzr98oadsp9qc11",
+			"date":         "2024-09-18",
+			"division":     "vccd5fo56ctbigh",
+			"description":  "sales tax",
+			"payment_type": "OnAccount",
+			"vendor":       "2zqxtsmymf670ha",
+			"kind":         capitalKind.Id,
+		}
+		for key, value := range fields {
+			base[key] = value
+		}
+		uploads++
+		receipt := append([]byte{0x89, 0x50, 0x4E, 0x47, 0x0D, 0x0A, 0x1A, 0x0A}, byte(uploads))
+		body, contentType := mustMultipartExpenseWithContent(t, base, "receipt.png", receipt)
+		res := performTestAPIRequest(t, app, http.MethodPost, "/api/collections/expenses/records", body, map[string]string{
+			"Authorization": token,
+			"Content-Type":  contentType,
+		})
+		if res.Code != http.StatusOK {
+			return nil, res.Body.String()
+		}
+		var expense salesTaxTestExpense
+		if err := json.Unmarshal(res.Body.Bytes(), &expense); err != nil {
+			t.Fatalf("failed to decode expense: %v", err)
+		}
+		return &expense, ""
+	}
+
+	// A blank tax code defaults to the branch's province and splits the total.
+	expense, failure := create(map[string]string{"total": "11.30"})
+	if expense == nil {
+		t.Fatalf("expected the expense to be created, got %s", failure)
+	}
+	if expense.TaxCode != "ON" || expense.PreTaxAmount != 10 || expense.TaxAmount != 1.3 {
+		t.Fatalf("expected an ON split of 10 + 1.30, got %+v", expense)
+	}
+
+	// Changing the total without the split recomputes it.
+	updated, failure := send(http.MethodPatch, "/api/collections/expenses/records/"+expense.ID, map[string]any{"total": 56.5})
+	if updated == nil {
+		t.Fatalf("expected the expense to update, got %s", failure)
+	}
+	if updated.PreTaxAmount != 50 || updated.TaxAmount != 6.5 {
+		t.Fatalf("expected the split to follow the new total, got %+v", updated)
+	}
+
+	// Entering just the tax derives the pre-tax amount.
+	expense, failure = create(map[string]string{"total": "52.50", "tax_code": "ab", "tax_amount": "2.50"})
+	if expense == nil {
+		t.Fatalf("expected the expense to be created, got %s", failure)
+	}
+	if expense.TaxCode != "AB" || expense.PreTaxAmount != 50 || expense.TaxAmount != 2.5 {
+		t.Fatalf("expected an AB split of 50 + 2.50, got %+v", expense)
+	}
+
+	for _, tc := range []struct {
+		fields map[string]string
+		code   string
+	}{
+		{map[string]string{"total": "50", "tax_code": "XX"}, `"invalid_tax_code"`},
+		{map[string]string{"total": "50", "tax_amount": "10"}, `"tax_exceeds_rate"`},
+		{map[string]string{"total": "50", "tax_amount": "5", "pre_tax_amount": "40"}, `"tax_split_mismatch"`},
+		{map[string]string{"total": "50", "tax_code": "EXEMPT", "tax_amount": "1"}, `"tax_exceeds_rate"`},
+	} {
+		if expense, failure := create(tc.fields); expense != nil || !strings.Contains(failure, tc.code) {
+			t.Fatalf("expected %v to fail with %s, got %+v %s", tc.fields, tc.code, expense, failure)
+		}
+	}
+
+	// A vendor's tax code wins over the branch's province.
+	vendor, err := app.FindRecordById("vendors", "2zqxtsmymf670ha")
+	if err != nil {
+		t.Fatalf("failed to load vendor: %v", err)
+	}
+	vendor.Set("tax_code", "EXEMPT")
+	if err := app.Save(vendor); err != nil {
+		t.Fatalf("failed to save vendor: %v", err)
+	}
+	expense, failure = create(map[string]string{"total": "80"})
+	if expense == nil {
+		t.Fatalf("expected the expense to be created, got %s", failure)
+	}
+	if expense.TaxCode != "EXEMPT" || expense.PreTaxAmount != 80 || expense.TaxAmount != 0 {
+		t.Fatalf("expected the vendor's EXEMPT code with no tax, got %+v", expense)
+	}
+
+	// The legacy export carries the split and its components.
+	record, err := app.FindRecordById("expenses", updated.ID)
+	if err != nil {
+		t.Fatalf("failed to load expense: %v", err)
+	}
+	record.Set("submitted", true)
+	record.Set("approved", "2024-09-19 12:00:00.000Z")
+	record.Set("committed", "2024-09-20 12:00:00.000Z")
+	if err := app.Save(record); err != nil {
+		t.Fatalf("failed to commit expense: %v", err)
+	}
+	res := performTestAPIRequest(t, app, http.MethodGet, "/api/export_legacy/expenses/2000-01-01", nil, map[string]string{
+		"Authorization": "Bearer test-secret-123",
+	})
+	mustStatus(t, res, http.StatusOK)
+	var export struct {
+		Expenses []struct {
+			ImmutableID  string   `json:"immutableID"`
+			TaxCode      string   `json:"taxCode"`
+			PreTaxAmount *float64 `json:"preTaxAmount"`
+			TaxAmount    *float64 `json:"taxAmount"`
+			Hst          *float64 `json:"hst"`
+			Gst          *float64 `json:"gst"`
+		} `json:"expenses"`
+	}
+	if err := json.Unmarshal(res.Body.Bytes(), &export); err != nil {
+		t.Fatalf("failed to decode export: %v", err)
+	}
+	found := false
+	for _, exported := range export.Expenses {
+		if exported.ImmutableID != updated.ID {
+			continue
+		}
+		found = true
+		if exported.TaxCode != "ON" || exported.PreTaxAmount == nil || *exported.PreTaxAmount != 50 ||
+			exported.TaxAmount == nil || *exported.TaxAmount != 6.5 || exported.Hst == nil || *exported.Hst != 6.5 || exported.Gst != nil {
+			t.Fatalf("expected the exported split 50 + 6.50 HST, got %+v", exported)
+		}
+	}
+	if !found {
+		t.Fatalf("expected the committed expense in the export")
+	}
+}
@@ -0,0 +1,193 @@
+package main
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"testing"
+	"tybalt/internal/testutils"
+
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+func mustTimeAmendmentImportBody(t testing.TB, confirm bool, csvContent string) (*bytes.Buffer, string) {
+	t.Helper()
+
+	buf := &bytes.Buffer{}
+	w := multipart.NewWriter(buf)
+	if confirm {
+		if err := w.WriteField("confirm", "true"); err != nil {
+			t.Fatalf("failed to write confirm field: %v", err)
+		}
+	}
+	fw, err := w.CreateFormFile("file", "amendments.csv")
+	if err != nil {
+		t.Fatalf("failed to create file field: %v", err)
+	}
+	if _, err := fw.Write([]byte(csvContent)); err != nil {
+		t.Fatalf("failed to write csv: %v", err)
+	}
+	contentType := w.FormDataContentType()
+	if err := w.Close(); err != nil {
+		t.Fatalf("failed to close multipart writer: %v", err)
+	}
+	return buf, contentType
+}
+
+func countImportedTimeAmendments(tb testing.TB, app *tests.TestApp) int {
+	tb.Helper()
+
+	records, err := app.FindRecordsByFilter("time_amendments", "description ~ 'imported amendment'", "", 0, 0)
+	if err != nil {
+		tb.Fatalf("failed to load time amendments: %v", err)
+	}
+	return len(records)
+}
+
+const validTimeAmendmentImportCSV = "payroll_id,date,division,time_type,hours,description,skip_tsid_check\n" +
+	"900001,2024-09-02,CI,R,2,imported amendment one,true\n" +
+	"900001,2024-09-03,CI,R,1.5,imported amendment two,true\n"
+
+func TestTimeAmendmentImport(t *testing.T) {
+	tameToken, err := testutils.GenerateRecordToken("users", "author@soup.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+	noTameToken, err := testutils.GenerateRecordToken("users", "fakemanager@fakesite.xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dryRunBody, dryRunContentType := mustTimeAmendmentImportBody(t, false, validTimeAmendmentImportCSV+
+		"nobody,2024-09-04,CI,R,1,imported amendment bad user,true\n"+
+		"900001,2024-09-04,XX,R,1,imported amendment bad division,true\n"+
+		"900001,2024-09-04,CI,R,1.2,imported amendment bad hours,true\n")
+	confirmBody, confirmContentType := mustTimeAmendmentImportBody(t, true, validTimeAmendmentImportCSV)
+	invalidConfirmBody, invalidConfirmContentType := mustTimeAmendmentImportBody(t, true, validTimeAmendmentImportCSV+
+		"900001,2024-09-04,CI,ZZ,1,imported amendment bad time type,true\n")
+	unknownColumnBody, unknownColumnContentType := mustTimeAmendmentImportBody(t, true, "payroll_id,date,time_type,colour\n900001,2024-09-02,R,red\n")
+	forbiddenBody, forbiddenContentType := mustTimeAmendmentImportBody(t, true, validTimeAmendmentImportCSV)
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "dry run reports row errors and saves nothing",
+			Method: http.MethodPost,
+			URL:    "/api/time_amendments/import",
+			Body:   dryRunBody,
+			Headers: map[string]string{
+				"Authorization": tameToken,
+				"Content-Type":  dryRunContentType,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"dry_run":true`,
+				`"row_count":5`,
+				`"invalid_count":3`,
+				`"created_count":0`,
+				`{"row":2,"uid":"rzr98oadsp9qc11","date":"2024-09-02","errors":[]}`,
+				`"row":4,"uid":"","date":"2024-09-04","errors":[{"field":"payroll_id","code":"unknown_payroll_id"`,
+				`"row":5,"uid":"rzr98oadsp9qc11","date":"2024-09-04","errors":[{"field":"division","code":"unknown_division"`,
+				`"row":6,"uid":"rzr98oadsp9qc11","date":"2024-09-04","errors":[{"field":"hours"`,
+			},
+			NotExpectedContent: []string{`"id":`},
+			TestAppFactory:     testutils.SetupTestApp,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+				if got := countImportedTimeAmendments(tb, app); got != 0 {
+					tb.Fatalf("expected a dry run to save nothing, got %d amendments", got)
+				}
+			},
+		},
+		{
+			Name:   "confirmed import creates every row",
+			Method: http.MethodPost,
+			URL:    "/api/time_amendments/import",
+			Body:   confirmBody,
+			Headers: map[string]string{
+				"Authorization": tameToken,
+				"Content-Type":  confirmContentType,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"dry_run":false`,
+				`"row_count":2`,
+				`"invalid_count":0`,
+				`"created_count":2`,
+				`"id":`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+				records, err := app.FindRecordsByFilter("time_amendments", "description ~ 'imported amendment'", "date", 0, 0)
+				if err != nil {
+					tb.Fatalf("failed to load time amendments: %v", err)
+				}
+				if len(records) != 2 {
+					tb.Fatalf("expected 2 imported amendments, got %d", len(records))
+				}
+				record := records[0]
+				if record.GetString("uid") != "rzr98oadsp9qc11" ||
+					record.GetString("creator") != "f2j5a8vk006baub" ||
+					record.GetString("time_type") != "sdyfl3q7j7ap849" ||
+					record.GetString("division") != "vccd5fo56ctbigh" ||
+					record.GetString("branch") != "80875lm27v8wgi4" ||
+					record.GetString("week_ending") != "2024-09-07" ||
+					record.GetFloat("hours") != 2 {
+					tb.Fatalf("unexpected imported amendment %v", record.FieldsData())
+				}
+			},
+		},
+		{
+			Name:   "confirmed import with an invalid row saves nothing",
+			Method: http.MethodPost,
+			URL:    "/api/time_amendments/import",
+			Body:   invalidConfirmBody,
+			Headers: map[string]string{
+				"Authorization": tameToken,
+				"Content-Type":  invalidConfirmContentType,
+			},
+			ExpectedStatus: http.StatusUnprocessableEntity,
+			ExpectedContent: []string{
+				`"dry_run":false`,
+				`"invalid_count":1`,
+				`"created_count":0`,
+				`"errors":[{"field":"time_type","code":"unknown_time_type"`,
+			},
+			NotExpectedContent: []string{`"id":`},
+			TestAppFactory:     testutils.SetupTestApp,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+				if got := countImportedTimeAmendments(tb, app); got != 0 {
+					tb.Fatalf("expected an invalid import to save nothing, got %d amendments", got)
+				}
+			},
+		},
+		{
+			Name:   "unknown column is rejected",
+			Method: http.MethodPost,
+			URL:    "/api/time_amendments/import",
+			Body:   unknownColumnBody,
+			Headers: map[string]string{
+				"Authorization": tameToken,
+				"Content-Type":  unknownColumnContentType,
+			},
+			ExpectedStatus:  http.StatusBadRequest,
+			ExpectedContent: []string{`"code":"unknown_column"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+		{
+			Name:   "user without the tame claim cannot import",
+			Method: http.MethodPost,
+			URL:    "/api/time_amendments/import",
+			Body:   forbiddenBody,
+			Headers: map[string]string{
+				"Authorization": noTameToken,
+				"Content-Type":  forbiddenContentType,
+			},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{`"code":"unauthorized"`},
+			TestAppFactory:  testutils.SetupTestApp,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
@@ -115,6 +115,37 @@ func TestTimeSheetMissingExceptionsRouteFlow(t *testing.T) {
 	}
 }
 
+// Inactive users who are expected to submit stay on the tracking missing list;
+// only the submission reminders skip them.
+func TestTimeSheetTrackingMissingIncludesInactiveUsers(t *testing.T) {
+	app := testutils.SetupTestApp(t)
+	defer app.Cleanup()
+
+	committerToken, err := testutils.GenerateRecordToken("users", "fakemanager@fakesite.xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	missingPath := "/api/time_sheets/tracking/weeks/" + missingExceptionWeekEnding + "/missing"
+	missing := getTrackingRows(t, app, committerToken, missingPath)
+	if len(missing) == 0 {
+		t.Fatal("expected at least one missing user in seed data")
+	}
+	targetUID := missing[0].ID
+
+	if _, err := app.DB().NewQuery("UPDATE admin_profiles SET active = 0 WHERE uid = {:uid}").Bind(dbx.Params{"uid": targetUID}).Execute(); err != nil {
+		t.Fatalf("failed to deactivate %s: %v", targetUID, err)
+	}
+
+	if !trackingRowsContainUID(getTrackingRows(t, app, committerToken, missingPath), targetUID) {
+		t.Fatalf("expected inactive uid %s to remain in the missing list", targetUID)
+	}
+	notExpected := getTrackingRows(t, app, committerToken, "/api/time_sheets/tracking/weeks/"+missingExceptionWeekEnding+"/not_expected")
+	if trackingRowsContainUID(notExpected, targetUID) {
+		t.Fatalf("expected inactive uid %s to stay out of the not_expected list", targetUID)
+	}
+}
+
 func TestTimeSheetMissingExceptionsRouteAuthAndValidation(t *testing.T) {
 	app := testutils.SetupTestApp(t)
 	defer app.Cleanup()
@@ -0,0 +1,299 @@
+// Package timeoff derives PPTO (OP) and vacation (OV) balances.
+//
+// Balances are not stored. They are rebuilt from the admin profile's opening
+// values plus committed time entries and committed time amendments with a week
+// ending after opening_date, matching the time_off view and the limits
+// validateTimeEntries enforces at bundle time. Each contributing row is
+// returned as a ledger line so staff can see how a balance was reached.
+package timeoff
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"math"
+	"sort"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+const (
+	CodePPTO     = "OP"
+	CodeVacation = "OV"
+	CodeBank     = "RB"
+	CodePayout   = "OTO"
+)
+
+// ErrNoAdminProfile is returned when the user has no admin profile and
+// therefore no opening balances.
+var ErrNoAdminProfile = errors.New("user has no admin profile")
+
+// LedgerEntry is one committed row affecting a balance. Hours are negative
+// for OP and OV usage and positive for banked overtime. Payout requests carry
+// their dollar amount in Amount. Balance is the running OP or OV balance
+// after the entry. It is nil for RB and OTO lines and for lines after a
+// payroll year end whose rollover is pending.
+type LedgerEntry struct {
+	Source     string   `db:"source" json:"source"`
+	SourceID   string   `db:"source_id" json:"source_id"`
+	Code       string   `db:"code" json:"code"`
+	Date       string   `db:"date" json:"date"`
+	WeekEnding string   `db:"week_ending" json:"week_ending"`
+	Hours      float64  `db:"hours" json:"hours"`
+	Amount     float64  `db:"amount" json:"amount"`
+	Balance    *float64 `db:"-" json:"balance"`
+}
+
+// Balance is a user's time-off position as of a date.
+//
+// PayrollYearEnd is the latest payroll_year_end_dates date on or before
+// AsOf. When it falls after OpeningDate the opening balances belong to a
+// previous payroll year: RolloverRequired is set and ClosingOP/ClosingOV hold
+// the balances at the year end, which accounting uses to set the new opening
+// values. Until it does, the new year's opening values are unknown, so
+// AvailableOP and AvailableOV are nil.
+type Balance struct {
+	UID              string        `json:"uid"`
+	Name             string        `json:"name"`
+	ManagerUID       string        `json:"manager_uid"`
+	AsOf             string        `json:"as_of"`
+	OpeningDate      string        `json:"opening_date"`
+	OpeningOP        float64       `json:"opening_op"`
+	OpeningOV        float64       `json:"opening_ov"`
+	UsedOP           float64       `json:"used_op"`
+	UsedOV           float64       `json:"used_ov"`
+	AvailableOP      *float64      `json:"available_op"`
+	AvailableOV      *float64      `json:"available_ov"`
+	BankedHours      float64       `json:"banked_hours"`
+	PayoutRequested  float64       `json:"payout_requested"`
+	PayrollYearEnd   string        `json:"payroll_year_end"`
+	RolloverRequired bool          `json:"rollover_required"`
+	ClosingOP        *float64      `json:"closing_op,omitempty"`
+	ClosingOV        *float64      `json:"closing_ov,omitempty"`
+	Ledger           []LedgerEntry `json:"ledger,omitempty"`
+}
+
+type profileRow struct {
+	UID         string  `db:"uid"`
+	Name        string  `db:"name"`
+	ManagerUID  string  `db:"manager_uid"`
+	OpeningDate string  `db:"opening_date"`
+	OpeningOP   float64 `db:"opening_op"`
+	OpeningOV   float64 `db:"opening_ov"`
+}
+
+const profileSelect = `
+	SELECT
+		ap.uid,
+		TRIM(COALESCE(p.given_name, '') || ' ' || COALESCE(p.surname, '')) AS name,
+		COALESCE(p.manager, '') AS manager_uid,
+		COALESCE(ap.opening_date, '') AS opening_date,
+		COALESCE(ap.opening_op, 0) AS opening_op,
+		COALESCE(ap.opening_ov, 0) AS opening_ov
+	FROM admin_profiles ap
+	LEFT JOIN profiles p ON p.uid = ap.uid`
+
+// ledgerQuery lists committed OP, OV, RB and OTO rows for a user with a week
+// ending after the opening date and on or before the as-of date. Time entries
+// count once their time sheet is committed; amendments count from their
+// committed week ending.
+const ledgerQuery = `
+	SELECT source, source_id, code, date, week_ending, hours, amount FROM (
+		SELECT
+			'time_entry' AS source,
+			te.id AS source_id,
+			tt.code,
+			te.date,
+			te.week_ending,
+			COALESCE(te.hours, 0) AS hours,
+			COALESCE(te.payout_request_amount, 0) AS amount
+		FROM time_entries te
+		JOIN time_types tt ON tt.id = te.time_type
+		JOIN time_sheets ts ON ts.id = te.tsid
+		WHERE te.uid = {:uid}
+		  AND ts.committed != ''
+		  AND tt.code IN ('OP', 'OV', 'RB', 'OTO')
+		UNION ALL
+		SELECT
+			'time_amendment' AS source,
+			ta.id AS source_id,
+			tt.code,
+			ta.date,
+			ta.committed_week_ending AS week_ending,
+			COALESCE(ta.hours, 0) AS hours,
+			COALESCE(ta.payout_request_amount, 0) AS amount
+		FROM time_amendments ta
+		JOIN time_types tt ON tt.id = ta.time_type
+		WHERE ta.uid = {:uid}
+		  AND ta.committed != ''
+		  AND tt.code IN ('OP', 'OV', 'RB', 'OTO')
+	)
+	WHERE week_ending > {:opening_date}
+	  AND week_ending <= {:as_of}
+	ORDER BY week_ending, date, source, source_id`
+
+// ComputeBalance returns the user's balance and ledger as of asOf
+// (YYYY-MM-DD). It returns ErrNoAdminProfile when the user has none.
+func ComputeBalance(app core.App, uid string, asOf string) (*Balance, error) {
+	if _, err := time.Parse(time.DateOnly, asOf); err != nil {
+		return nil, fmt.Errorf("invalid as of date %q: %w", asOf, err)
+	}
+
+	var profile profileRow
+	err := app.DB().NewQuery(profileSelect + " WHERE ap.uid = {:uid}").Bind(dbx.Params{
+		"uid": uid,
+	}).One(&profile)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNoAdminProfile
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error loading admin profile for %s: %w", uid, err)
+	}
+
+	payrollYearEnd, err := latestPayrollYearEnd(app, asOf)
+	if err != nil {
+		return nil, err
+	}
+
+	var ledger []LedgerEntry
+	if err := app.DB().NewQuery(ledgerQuery).Bind(dbx.Params{
+		"uid":          uid,
+		"opening_date": profile.OpeningDate,
+		"as_of":        asOf,
+	}).All(&ledger); err != nil {
+		return nil, fmt.Errorf("error loading time off ledger for %s: %w", uid, err)
+	}
+
+	return buildBalance(profile, asOf, payrollYearEnd, ledger), nil
+}
+
+// ListBalances returns balances without ledgers for every user whose time off
+// is tracked (untracked_time_off off and time_sheet_expected on), the same
+// population as the time_off view. When managerUID is set only that manager's
+// direct reports are returned.
+func ListBalances(app core.App, managerUID string, asOf string) ([]Balance, error) {
+	query := profileSelect + `
+		WHERE COALESCE(ap.untracked_time_off, 0) = 0
+		  AND COALESCE(ap.time_sheet_expected, 0) = 1`
+	params := dbx.Params{}
+	if managerUID != "" {
+		query += " AND p.manager = {:manager}"
+		params["manager"] = managerUID
+	}
+	query += " ORDER BY p.surname, p.given_name"
+
+	var profiles []profileRow
+	if err := app.DB().NewQuery(query).Bind(params).All(&profiles); err != nil {
+		return nil, fmt.Errorf("error listing time off profiles: %w", err)
+	}
+
+	balances := make([]Balance, 0, len(profiles))
+	for _, profile := range profiles {
+		balance, err := ComputeBalance(app, profile.UID, asOf)
+		if err != nil {
+			return nil, err
+		}
+		balance.Ledger = nil
+		balances = append(balances, *balance)
+	}
+	return balances, nil
+}
+
+// latestPayrollYearEnd returns the most recent payroll year end date on or
+// before asOf, or an empty string when there is none.
+func latestPayrollYearEnd(app core.App, asOf string) (string, error) {
+	var result struct {
+		Date string `db:"date"`
+	}
+	err := app.DB().NewQuery(`
+		SELECT date FROM payroll_year_end_dates
+		WHERE date <= {:as_of}
+		ORDER BY date DESC
+		LIMIT 1
+	`).Bind(dbx.Params{"as_of": asOf}).One(&result)
+	if errors.Is(err, sql.ErrNoRows) {
+		return "", nil
+	}
+	if err != nil {
+		return "", fmt.Errorf("error loading payroll year end dates: %w", err)
+	}
+	return result.Date, nil
+}
+
+func buildBalance(profile profileRow, asOf string, payrollYearEnd string, ledger []LedgerEntry) *Balance {
+	balance := &Balance{
+		UID:            profile.UID,
+		Name:           profile.Name,
+		ManagerUID:     profile.ManagerUID,
+		AsOf:           asOf,
+		OpeningDate:    profile.OpeningDate,
+		OpeningOP:      profile.OpeningOP,
+		OpeningOV:      profile.OpeningOV,
+		PayrollYearEnd: payrollYearEnd,
+		// Same comparison as validateTimeEntries: opening balances set before
+		// the latest year end belong to the previous payroll year.
+		RolloverRequired: payrollYearEnd != "" && payrollYearEnd > profile.OpeningDate,
+	}
+
+	sort.SliceStable(ledger, func(i, j int) bool {
+		return ledger[i].WeekEnding < ledger[j].WeekEnding
+	})
+
+	runningOP, runningOV := profile.OpeningOP, profile.OpeningOV
+	closingOP, closingOV := runningOP, runningOV
+	for i := range ledger {
+		entry := &ledger[i]
+		switch entry.Code {
+		case CodePPTO:
+			balance.UsedOP += entry.Hours
+			runningOP -= entry.Hours
+			entry.Hours = -entry.Hours
+			entry.Balance = runningBalance(runningOP, balance.RolloverRequired && entry.WeekEnding > payrollYearEnd)
+		case CodeVacation:
+			balance.UsedOV += entry.Hours
+			runningOV -= entry.Hours
+			entry.Hours = -entry.Hours
+			entry.Balance = runningBalance(runningOV, balance.RolloverRequired && entry.WeekEnding > payrollYearEnd)
+		case CodeBank:
+			balance.BankedHours += entry.Hours
+		case CodePayout:
+			balance.PayoutRequested += entry.Amount
+		}
+		if entry.WeekEnding <= payrollYearEnd {
+			closingOP, closingOV = runningOP, runningOV
+		}
+	}
+
+	balance.UsedOP = round(balance.UsedOP)
+	balance.UsedOV = round(balance.UsedOV)
+	balance.BankedHours = round(balance.BankedHours)
+	balance.PayoutRequested = round(balance.PayoutRequested)
+	if balance.RolloverRequired {
+		closingOP, closingOV = round(closingOP), round(closingOV)
+		balance.ClosingOP = &closingOP
+		balance.ClosingOV = &closingOV
+	} else {
+		availableOP := round(profile.OpeningOP - balance.UsedOP)
+		availableOV := round(profile.OpeningOV - balance.UsedOV)
+		balance.AvailableOP = &availableOP
+		balance.AvailableOV = &availableOV
+	}
+	balance.Ledger = ledger
+	return balance
+}
+
+// runningBalance returns the rounded running balance of a ledger line, or nil
+// when the line falls in a payroll year whose opening values are not set yet.
+func runningBalance(value float64, pendingRollover bool) *float64 {
+	if pendingRollover {
+		return nil
+	}
+	rounded := round(value)
+	return &rounded
+}
+
+func round(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,134 @@
+package timeoff
+
+import (
+	"fmt"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// RequestCodes are the time types that are planned ahead and pre-approved
+// through time_off_requests. Sick time (OS) and the other non-work codes are
+// unplanned or administered separately, so they are not requested.
+var RequestCodes = []string{CodePPTO, CodeVacation}
+
+// IsRequestCode reports whether time entries of the given time type code need
+// an approved time off request.
+func IsRequestCode(code string) bool {
+	for _, requestCode := range RequestCodes {
+		if code == requestCode {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	RequestStatusPending  = "pending"
+	RequestStatusApproved = "approved"
+)
+
+// UnrequestedEntry is an OP or OV time entry that no approved time off
+// request covers.
+type UnrequestedEntry struct {
+	ID    string  `db:"id" json:"id"`
+	Date  string  `db:"date" json:"date"`
+	Code  string  `db:"code" json:"code"`
+	Hours float64 `db:"hours" json:"hours"`
+}
+
+// FindUnrequestedEntries returns the user's OP and OV time entries for the
+// week that are not covered by an approved, unrejected request of the same
+// time type whose date range includes the entry's date.
+func FindUnrequestedEntries(app core.App, uid string, weekEnding string) ([]UnrequestedEntry, error) {
+	entries := []UnrequestedEntry{}
+	err := app.DB().NewQuery(`
+		SELECT te.id, te.date, tt.code, COALESCE(te.hours, 0) AS hours
+		FROM time_entries te
+		JOIN time_types tt ON tt.id = te.time_type
+		WHERE te.uid = {:uid}
+		  AND te.week_ending = {:week_ending}
+		  AND tt.code IN ({:op}, {:ov})
+		  AND NOT EXISTS (
+			SELECT 1 FROM time_off_requests r
+			WHERE r.uid = te.uid
+			  AND r.time_type = te.time_type
+			  AND COALESCE(r.approved, '') != ''
+			  AND COALESCE(r.rejected, '') = ''
+			  AND te.date BETWEEN r.start_date AND r.end_date
+		  )
+		ORDER BY te.date, tt.code, te.id
+	`).Bind(dbx.Params{
+		"uid":         uid,
+		"week_ending": weekEnding,
+		"op":          CodePPTO,
+		"ov":          CodeVacation,
+	}).All(&entries)
+	if err != nil {
+		return nil, fmt.Errorf("error checking time off requests for %s: %w", uid, err)
+	}
+	return entries, nil
+}
+
+// CalendarEntry is a submitted, unrejected time off request shown on the team
+// calendar.
+type CalendarEntry struct {
+	ID        string  `db:"id" json:"id"`
+	UID       string  `db:"uid" json:"uid"`
+	Name      string  `db:"name" json:"name"`
+	Code      string  `db:"code" json:"code"`
+	StartDate string  `db:"start_date" json:"start_date"`
+	EndDate   string  `db:"end_date" json:"end_date"`
+	Hours     float64 `db:"hours" json:"hours"`
+	Status    string  `db:"status" json:"status"`
+}
+
+// ListCalendar returns submitted, unrejected requests overlapping start..end
+// (inclusive, YYYY-MM-DD). With viewAll every request is returned; otherwise
+// the viewer sees their team: themself, their manager, everyone who shares
+// their manager (when they have one) and their own direct reports.
+func ListCalendar(app core.App, viewerUID string, viewAll bool, start string, end string) ([]CalendarEntry, error) {
+	query := `
+		SELECT
+			r.id,
+			r.uid,
+			TRIM(COALESCE(p.given_name, '') || ' ' || COALESCE(p.surname, '')) AS name,
+			tt.code,
+			r.start_date,
+			r.end_date,
+			COALESCE(r.hours, 0) AS hours,
+			CASE WHEN COALESCE(r.approved, '') != '' THEN {:approved} ELSE {:pending} END AS status
+		FROM time_off_requests r
+		JOIN time_types tt ON tt.id = r.time_type
+		LEFT JOIN profiles p ON p.uid = r.uid
+		WHERE r.submitted = 1
+		  AND COALESCE(r.rejected, '') = ''
+		  AND r.start_date <= {:end}
+		  AND r.end_date >= {:start}`
+	params := dbx.Params{
+		"start":    start,
+		"end":      end,
+		"approved": RequestStatusApproved,
+		"pending":  RequestStatusPending,
+	}
+	if !viewAll {
+		query += `
+		  AND (
+			r.uid = {:viewer}
+			OR p.manager = {:viewer}
+			OR (
+				p.manager = (SELECT manager FROM profiles WHERE uid = {:viewer})
+				AND COALESCE((SELECT manager FROM profiles WHERE uid = {:viewer}), '') != ''
+			)
+			OR r.uid = (SELECT manager FROM profiles WHERE uid = {:viewer})
+		  )`
+		params["viewer"] = viewerUID
+	}
+	query += " ORDER BY r.start_date, p.surname, p.given_name, r.id"
+
+	entries := []CalendarEntry{}
+	if err := app.DB().NewQuery(query).Bind(params).All(&entries); err != nil {
+		return nil, fmt.Errorf("error listing time off calendar: %w", err)
+	}
+	return entries, nil
+}
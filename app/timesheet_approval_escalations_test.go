@@ -0,0 +1,281 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"tybalt/internal/testutils"
+	"tybalt/notifications"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+	"github.com/pocketbase/pocketbase/tests"
+)
+
+// Stale timesheets added by setupTestAppWithStaleSubmittedTimesheets:
+//
+//   - staleToAlternateSheetID belongs to Tester Time, whose alternate_manager
+//     is fakemanager@fakesite.xyz.
+//   - staleToManagerSheetID belongs to u_no_claims, who has no alternate
+//     manager. Its approver is fatt@mac.com, whose manager is author@soup.com.
+//   - recentSheetID was submitted today and is not yet stale.
+const (
+	staleToAlternateSheetID = "tsescalatealt01"
+	staleToManagerSheetID   = "tsescalatemgr01"
+	recentSheetID           = "tsescalatenew01"
+	escalationWeekEnding    = "2026-03-07"
+)
+
+func addSubmittedTimesheet(tb testing.TB, app core.App, id string, uid string, approver string, created time.Time) {
+	tb.Helper()
+
+	collection, err := app.FindCollectionByNameOrId("time_sheets")
+	if err != nil {
+		tb.Fatalf("failed to load time_sheets collection: %v", err)
+	}
+	record := core.NewRecord(collection)
+	record.Set("id", id)
+	record.Set("uid", uid)
+	record.Set("approver", approver)
+	record.Set("week_ending", escalationWeekEnding)
+	record.Set("submitted", true)
+	record.Set("work_week_hours", 40)
+	record.Set("payroll_id", "900001")
+	if err := app.Save(record); err != nil {
+		tb.Fatalf("failed to save timesheet %s: %v", id, err)
+	}
+	if _, err := app.NonconcurrentDB().NewQuery("UPDATE time_sheets SET created = {:created} WHERE id = {:id}").Bind(dbx.Params{
+		"created": created.UTC().Format("2006-01-02 15:04:05.000Z"),
+		"id":      id,
+	}).Execute(); err != nil {
+		tb.Fatalf("failed to backdate timesheet %s: %v", id, err)
+	}
+}
+
+// setTimesheetApprovalEscalationDays writes time.approval_escalation_business_days,
+// which is disabled unless configured.
+func setTimesheetApprovalEscalationDays(tb testing.TB, app core.App, days int) {
+	tb.Helper()
+
+	config, err := app.FindFirstRecordByData("app_config", "key", "time")
+	if err != nil {
+		tb.Fatalf("failed to load time config: %v", err)
+	}
+	config.Set("value", fmt.Sprintf(`{"create_edit":true,"approval_escalation_business_days":%d}`, days))
+	if err := app.Save(config); err != nil {
+		tb.Fatalf("failed to save time config: %v", err)
+	}
+}
+
+func setupTestAppWithStaleSubmittedTimesheets(tb testing.TB) *tests.TestApp {
+	tb.Helper()
+
+	app := testutils.SetupTestApp(tb)
+	setTimesheetApprovalEscalationDays(tb, app, 3)
+	now := time.Now().UTC()
+	addSubmittedTimesheet(tb, app, staleToAlternateSheetID, "rzr98oadsp9qc11", "f2j5a8vk006baub", now.AddDate(0, 0, -14))
+	addSubmittedTimesheet(tb, app, staleToManagerSheetID, "u_no_claims", "etysnrlup2f6bak", now.AddDate(0, 0, -14))
+	addSubmittedTimesheet(tb, app, recentSheetID, "u_admin_only", "f2j5a8vk006baub", now)
+	return app
+}
+
+func setupTestAppWithEscalatedTimesheets(tb testing.TB) *tests.TestApp {
+	tb.Helper()
+
+	app := setupTestAppWithStaleSubmittedTimesheets(tb)
+	if err := notifications.QueueTimesheetApprovalEscalations(app, false); err != nil {
+		tb.Fatalf("failed to escalate timesheets: %v", err)
+	}
+	return app
+}
+
+func TestQueueTimesheetApprovalEscalations(t *testing.T) {
+	app := setupTestAppWithStaleSubmittedTimesheets(t)
+	defer app.Cleanup()
+
+	if err := notifications.QueueTimesheetApprovalEscalations(app, false); err != nil {
+		t.Fatalf("expected no error from QueueTimesheetApprovalEscalations, got %v", err)
+	}
+
+	wantEscalatedTo := map[string]string{
+		staleToAlternateSheetID: "wegviunlyr2jjjv",
+		staleToManagerSheetID:   "f2j5a8vk006baub",
+		recentSheetID:           "",
+		// self-approved seed timesheets have nobody to escalate to
+		"aeyl94og4xmnpq4": "",
+	}
+	for id, want := range wantEscalatedTo {
+		record, err := app.FindRecordById("time_sheets", id)
+		if err != nil {
+			t.Fatalf("failed to load timesheet %s: %v", id, err)
+		}
+		if got := record.GetString("approval_escalated_to"); got != want {
+			t.Fatalf("timesheet %s approval_escalated_to = %q, want %q", id, got, want)
+		}
+		if escalated := !record.GetDateTime("approval_escalated").IsZero(); escalated != (want != "") {
+			t.Fatalf("timesheet %s approval_escalated set = %v, want %v", id, escalated, want != "")
+		}
+	}
+
+	var notices []struct {
+		Recipient string `db:"recipient"`
+		Data      string `db:"data"`
+	}
+	if err := app.DB().NewQuery(`
+		SELECT n.recipient, COALESCE(n.data, '') AS data
+		FROM notifications n
+		JOIN notification_templates t ON t.id = n.template
+		WHERE t.code = 'timesheet_approval_escalation'
+		ORDER BY n.recipient
+	`).All(&notices); err != nil {
+		t.Fatalf("failed to load escalation notifications: %v", err)
+	}
+	if len(notices) != 2 || notices[0].Recipient != "f2j5a8vk006baub" || notices[1].Recipient != "wegviunlyr2jjjv" {
+		t.Fatalf("expected one escalation notification per escalated timesheet, got %+v", notices)
+	}
+	var data map[string]any
+	if err := json.Unmarshal([]byte(notices[1].Data), &data); err != nil {
+		t.Fatalf("failed to decode escalation data: %v", err)
+	}
+	if data["EmployeeName"] != "Tester Time" || data["ApproverName"] != "Horace Silver" || data["WeekEnding"] != escalationWeekEnding || data["BusinessDays"] != "3" {
+		t.Fatalf("unexpected escalation data %v", data)
+	}
+
+	if err := notifications.QueueTimesheetApprovalEscalations(app, false); err != nil {
+		t.Fatalf("expected no error from a second run, got %v", err)
+	}
+	if got := testutils.CountNotificationsByTemplateCode(t, app, "timesheet_approval_escalation"); got != 2 {
+		t.Fatalf("expected timesheets to be escalated only once, got %d notifications", got)
+	}
+}
+
+func TestQueueTimesheetApprovalEscalations_DisabledByConfig(t *testing.T) {
+	app := setupTestAppWithStaleSubmittedTimesheets(t)
+	defer app.Cleanup()
+
+	setTimesheetApprovalEscalationDays(t, app, 0)
+
+	if err := notifications.QueueTimesheetApprovalEscalations(app, false); err != nil {
+		t.Fatalf("expected no error from QueueTimesheetApprovalEscalations, got %v", err)
+	}
+	record, err := app.FindRecordById("time_sheets", staleToAlternateSheetID)
+	if err != nil {
+		t.Fatalf("failed to load timesheet: %v", err)
+	}
+	if record.GetString("approval_escalated_to") != "" {
+		t.Fatalf("expected no escalation while disabled, got %q", record.GetString("approval_escalated_to"))
+	}
+}
+
+func TestTimesheetApprove_ByEscalationRecipient(t *testing.T) {
+	alternateToken, err := testutils.GenerateRecordToken("users", "fakemanager@fakesite.xyz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	reportToken, err := testutils.GenerateRecordToken("users", "fatt@mac.com")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	scenarios := []tests.ApiScenario{
+		{
+			Name:   "escalation recipient approves on behalf of the approver",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/" + staleToAlternateSheetID + "/approve",
+			Headers: map[string]string{
+				"Authorization": alternateToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"message":"Record approved successfully"`},
+			TestAppFactory:  setupTestAppWithEscalatedTimesheets,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+				record, err := app.FindRecordById("time_sheets", staleToAlternateSheetID)
+				if err != nil {
+					tb.Fatalf("failed to load timesheet: %v", err)
+				}
+				if record.GetDateTime("approved").IsZero() {
+					tb.Fatal("timesheet was not approved")
+				}
+				if record.GetString("approver") != "f2j5a8vk006baub" || record.GetString("delegated_approver") != "wegviunlyr2jjjv" {
+					tb.Fatalf("approver = %q delegated_approver = %q, want the approver kept and the escalation recipient recorded", record.GetString("approver"), record.GetString("delegated_approver"))
+				}
+			},
+		},
+		{
+			Name:   "escalation recipient rejects the escalated timesheet",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/" + staleToAlternateSheetID + "/reject",
+			Body:   strings.NewReader(`{"rejection_reason": "Missing job numbers"}`),
+			Headers: map[string]string{
+				"Authorization": alternateToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"message":"record rejected successfully"`},
+			TestAppFactory:  setupTestAppWithEscalatedTimesheets,
+			AfterTestFunc: func(tb testing.TB, app *tests.TestApp, res *http.Response) {
+				record, err := app.FindRecordById("time_sheets", staleToAlternateSheetID)
+				if err != nil {
+					tb.Fatalf("failed to load timesheet: %v", err)
+				}
+				if record.GetDateTime("rejected").IsZero() || record.GetString("rejector") != "wegviunlyr2jjjv" {
+					tb.Fatalf("rejected = %v rejector = %q, want the timesheet rejected by the escalation recipient", record.GetDateTime("rejected"), record.GetString("rejector"))
+				}
+			},
+		},
+		{
+			Name:   "alternate manager cannot approve before escalation",
+			Method: http.MethodPost,
+			URL:    "/api/time_sheets/" + staleToAlternateSheetID + "/approve",
+			Headers: map[string]string{
+				"Authorization": alternateToken,
+			},
+			ExpectedStatus:  http.StatusForbidden,
+			ExpectedContent: []string{"you are not authorized to approve this record"},
+			TestAppFactory:  setupTestAppWithStaleSubmittedTimesheets,
+		},
+		{
+			Name:   "escalation recipient can view the escalated timesheet",
+			Method: http.MethodGet,
+			URL:    "/api/collections/time_sheets/records/" + staleToAlternateSheetID,
+			Headers: map[string]string{
+				"Authorization": alternateToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"approval_escalated_to":"wegviunlyr2jjjv"`},
+			TestAppFactory:  setupTestAppWithEscalatedTimesheets,
+		},
+		{
+			Name:   "tracking list shows who a timesheet was escalated to",
+			Method: http.MethodGet,
+			URL:    "/api/time_sheets/tracking/weeks/" + escalationWeekEnding,
+			Headers: map[string]string{
+				"Authorization": reportToken,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				`"approval_escalated_to":"wegviunlyr2jjjv"`,
+				`"approval_escalated_to_name":"Fakesy Manjor"`,
+			},
+			TestAppFactory: setupTestAppWithEscalatedTimesheets,
+		},
+		{
+			Name:   "tracking counts include escalated timesheets",
+			Method: http.MethodGet,
+			URL:    "/api/time_sheets/tracking_counts",
+			Headers: map[string]string{
+				"Authorization": reportToken,
+			},
+			ExpectedStatus:  http.StatusOK,
+			ExpectedContent: []string{`"week_ending":"` + escalationWeekEnding + `","submitted_count":3,"approved_count":0,"committed_count":0,"rejected_count":0,"escalated_count":2`},
+			TestAppFactory:  setupTestAppWithEscalatedTimesheets,
+		},
+	}
+
+	for _, scenario := range scenarios {
+		scenario.Test(t)
+	}
+}
@@ -0,0 +1,88 @@
+package utilities
+
+import (
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// Approval delegation scopes stored in approval_delegations.scopes.
+const (
+	ApprovalDelegationScopeTime           = "time"
+	ApprovalDelegationScopeExpenses       = "expenses"
+	ApprovalDelegationScopePurchaseOrders = "purchase_orders"
+)
+
+// ApprovalDelegationScopeForCollection returns the delegation scope that
+// covers approvals of collectionName, or "" if its approvals cannot be
+// delegated.
+func ApprovalDelegationScopeForCollection(collectionName string) string {
+	switch collectionName {
+	case "time_sheets":
+		return ApprovalDelegationScopeTime
+	case "expenses":
+		return ApprovalDelegationScopeExpenses
+	case "purchase_orders":
+		return ApprovalDelegationScopePurchaseOrders
+	}
+	return ""
+}
+
+// activeApprovalDelegationWhere limits approval_delegations (aliased ad) to
+// delegations of scope that cover today.
+func activeApprovalDelegationWhere(scope string) string {
+	return `ad.start_date <= date('now')
+			AND ad.end_date >= date('now')
+			AND EXISTS (
+				SELECT 1 FROM json_each(CASE WHEN json_valid(ad.scopes) THEN ad.scopes ELSE '[]' END)
+				WHERE value = '` + scope + `'
+			)`
+}
+
+// ApprovalDelegationCondition is true when the user that delegateExpr
+// evaluates to holds an active delegation of scope from the approver that
+// delegatorExpr evaluates to. scope must be one of the
+// ApprovalDelegationScope constants.
+func ApprovalDelegationCondition(delegatorExpr string, delegateExpr string, scope string) string {
+	return `EXISTS (
+		SELECT 1 FROM approval_delegations ad
+		WHERE ad.delegator = ` + delegatorExpr + `
+			AND ad.delegate = ` + delegateExpr + `
+			AND ` + activeApprovalDelegationWhere(scope) + `
+	)`
+}
+
+// ApprovalRecipientExpr evaluates to the user who currently receives the
+// approvals of scope for the approver that approverExpr evaluates to on a
+// record owned by the user that ownerExpr evaluates to: the delegate of the
+// most recently started active delegation, or the approver when none is
+// active. Delegates never receive their own records.
+func ApprovalRecipientExpr(approverExpr string, ownerExpr string, scope string) string {
+	return `COALESCE((
+		SELECT ad.delegate FROM approval_delegations ad
+		WHERE ad.delegator = ` + approverExpr + `
+			AND ad.delegate != ` + ownerExpr + `
+			AND ` + activeApprovalDelegationWhere(scope) + `
+		ORDER BY ad.start_date DESC, ad.created DESC
+		LIMIT 1
+	), ` + approverExpr + `)`
+}
+
+// HasActiveApprovalDelegation reports whether delegateID may currently approve
+// records of scope on behalf of delegatorID.
+func HasActiveApprovalDelegation(app core.App, delegatorID string, delegateID string, scope string) (bool, error) {
+	if delegatorID == "" || delegateID == "" || scope == "" {
+		return false, nil
+	}
+
+	var result struct {
+		Active bool `db:"active"`
+	}
+	err := app.DB().NewQuery(`SELECT ` + ApprovalDelegationCondition("{:delegator}", "{:delegate}", scope) + ` AS active`).Bind(dbx.Params{
+		"delegator": delegatorID,
+		"delegate":  delegateID,
+	}).One(&result)
+	if err != nil {
+		return false, err
+	}
+	return result.Active, nil
+}
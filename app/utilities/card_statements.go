@@ -0,0 +1,258 @@
+package utilities
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"html"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// CardStatementLine is one charge on a corporate credit card statement.
+// Amount is positive and in the card's currency.
+type CardStatementLine struct {
+	Date        string
+	Amount      float64
+	Description string
+	Reference   string
+}
+
+// CardStatement is a parsed corporate credit card statement. AccountLast4 and
+// the period are only set when the file states them. Payments and credits are
+// not returned as lines; CreditCount is how many were skipped.
+type CardStatement struct {
+	AccountLast4 string
+	PeriodStart  string
+	PeriodEnd    string
+	Lines        []CardStatementLine
+	CreditCount  int
+}
+
+// cardStatementCSVColumns maps the accepted CSV header names, normalized to
+// lower case with spaces as underscores, to the field they hold.
+var cardStatementCSVColumns = map[string]string{
+	"date":             "date",
+	"transaction_date": "date",
+	"posted_date":      "date",
+	"posting_date":     "date",
+	"amount":           "amount",
+	"description":      "description",
+	"merchant":         "description",
+	"payee":            "description",
+	"name":             "description",
+	"reference":        "reference",
+	"transaction_id":   "reference",
+	"fitid":            "reference",
+}
+
+var cardStatementDateLayouts = []string{time.DateOnly, "2006/01/02", "Jan 2, 2006", "Jan 2 2006"}
+
+// ParseCardStatementCSV parses a CSV statement export. The header row must
+// include a date and an amount column; description and reference columns are
+// optional and other columns are ignored. Positive amounts are charges and
+// negative amounts are payments or credits.
+func ParseCardStatementCSV(reader io.Reader) (*CardStatement, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("the statement has no rows")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the statement could not be read: %w", err)
+	}
+	columnIndex := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.ReplaceAll(name, " ", "_")
+		if field, ok := cardStatementCSVColumns[name]; ok {
+			if _, seen := columnIndex[field]; !seen {
+				columnIndex[field] = i
+			}
+		}
+	}
+	for _, required := range []string{"date", "amount"} {
+		if _, ok := columnIndex[required]; !ok {
+			return nil, fmt.Errorf("the statement has no %s column", required)
+		}
+	}
+
+	value := func(fields []string, field string) string {
+		i, ok := columnIndex[field]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return strings.TrimSpace(fields[i])
+	}
+
+	statement := &CardStatement{Lines: []CardStatementLine{}}
+	for {
+		fields, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("the statement could not be read: %w", err)
+		}
+		row, _ := csvReader.FieldPos(0)
+		if strings.TrimSpace(strings.Join(fields, "")) == "" {
+			continue
+		}
+
+		date, err := parseCardStatementDate(value(fields, "date"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		amount, err := parseCardStatementAmount(value(fields, "amount"))
+		if err != nil {
+			return nil, fmt.Errorf("row %d: %w", row, err)
+		}
+		if amount <= 0 {
+			statement.CreditCount++
+			continue
+		}
+		statement.Lines = append(statement.Lines, CardStatementLine{
+			Date:        date,
+			Amount:      amount,
+			Description: value(fields, "description"),
+			Reference:   value(fields, "reference"),
+		})
+	}
+	return statement, nil
+}
+
+var (
+	ofxTransactionStart = regexp.MustCompile(`(?i)<STMTTRN>`)
+	ofxTransactionEnd   = regexp.MustCompile(`(?i)</BANKTRANLIST>`)
+)
+
+// ofxValue returns the value of the first <tag> element in text. It handles
+// both OFX 1.x SGML, where elements are not closed, and OFX 2.x XML.
+func ofxValue(text string, tag string) string {
+	match := regexp.MustCompile(`(?i)<` + tag + `>([^<\r\n]*)`).FindStringSubmatch(text)
+	if match == nil {
+		return ""
+	}
+	return strings.TrimSpace(html.UnescapeString(match[1]))
+}
+
+// ofxDate converts an OFX date such as 20240917 or 20240917120000[-8:PST]
+// to YYYY-MM-DD.
+func ofxDate(value string) (string, error) {
+	if len(value) < 8 {
+		return "", fmt.Errorf("invalid date %q", value)
+	}
+	date, err := time.Parse("20060102", value[:8])
+	if err != nil {
+		return "", fmt.Errorf("invalid date %q", value)
+	}
+	return date.Format(time.DateOnly), nil
+}
+
+// ParseCardStatementOFX parses an OFX (1.x SGML or 2.x XML) credit card
+// statement download. Following OFX, negative amounts are charges and
+// positive amounts are payments or credits.
+func ParseCardStatementOFX(reader io.Reader) (*CardStatement, error) {
+	content, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("the statement could not be read: %w", err)
+	}
+	text := string(content)
+
+	starts := ofxTransactionStart.FindAllStringIndex(text, -1)
+	if len(starts) == 0 && !strings.Contains(strings.ToUpper(text), "<OFX>") {
+		return nil, fmt.Errorf("the statement is not an OFX file")
+	}
+
+	statement := &CardStatement{Lines: []CardStatementLine{}}
+	header := text
+	if len(starts) > 0 {
+		header = text[:starts[0][0]]
+	}
+	if account := ofxValue(header, "ACCTID"); len(account) >= 4 {
+		statement.AccountLast4 = account[len(account)-4:]
+	}
+	if value := ofxValue(header, "DTSTART"); value != "" {
+		if statement.PeriodStart, err = ofxDate(value); err != nil {
+			return nil, err
+		}
+	}
+	if value := ofxValue(header, "DTEND"); value != "" {
+		if statement.PeriodEnd, err = ofxDate(value); err != nil {
+			return nil, err
+		}
+	}
+
+	for i, start := range starts {
+		end := len(text)
+		if i+1 < len(starts) {
+			end = starts[i+1][0]
+		} else if loc := ofxTransactionEnd.FindStringIndex(text[start[1]:]); loc != nil {
+			end = start[1] + loc[0]
+		}
+		transaction := text[start[1]:end]
+
+		date, err := ofxDate(ofxValue(transaction, "DTPOSTED"))
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		amount, err := parseCardStatementAmount(ofxValue(transaction, "TRNAMT"))
+		if err != nil {
+			return nil, fmt.Errorf("transaction %d: %w", i+1, err)
+		}
+		if amount >= 0 {
+			statement.CreditCount++
+			continue
+		}
+		description := ofxValue(transaction, "NAME")
+		if description == "" {
+			description = ofxValue(transaction, "MEMO")
+		}
+		statement.Lines = append(statement.Lines, CardStatementLine{
+			Date:        date,
+			Amount:      -amount,
+			Description: description,
+			Reference:   ofxValue(transaction, "FITID"),
+		})
+	}
+	return statement, nil
+}
+
+func parseCardStatementDate(value string) (string, error) {
+	for _, layout := range cardStatementDateLayouts {
+		if date, err := time.Parse(layout, value); err == nil {
+			return date.Format(time.DateOnly), nil
+		}
+	}
+	return "", fmt.Errorf("invalid date %q", value)
+}
+
+// parseCardStatementAmount parses amounts such as 69.42, -1,250.00, $12.00 or
+// (12.00), rounded to cents.
+func parseCardStatementAmount(value string) (float64, error) {
+	cleaned := strings.Map(func(r rune) rune {
+		if r == '$' || r == ',' || unicode.IsSpace(r) {
+			return -1
+		}
+		return r
+	}, value)
+	negative := strings.HasPrefix(cleaned, "(") && strings.HasSuffix(cleaned, ")")
+	if negative {
+		cleaned = cleaned[1 : len(cleaned)-1]
+	}
+	amount, err := strconv.ParseFloat(cleaned, 64)
+	if err != nil || cleaned == "" {
+		return 0, fmt.Errorf("invalid amount %q", value)
+	}
+	if negative {
+		amount = -amount
+	}
+	return RoundCurrencyAmount(amount), nil
+}
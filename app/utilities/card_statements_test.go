@@ -0,0 +1,116 @@
+package utilities
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseCardStatementCSV(t *testing.T) {
+	statement, err := ParseCardStatementCSV(strings.NewReader(
+		"\ufeffTransaction Date,Merchant,Amount,Reference,Card Member\n" +
+			"2024-09-18,BIG VENDOR IND #42,\"$1,069.42\",ref-1,TESTER TIME\n" +
+			"2024/09/20,PAYMENT - THANK YOU,-500.00,ref-2,TESTER TIME\n" +
+			"\n" +
+			"\"Sep 21, 2024\",COFFEE,(3.50),ref-3,TESTER TIME\n" +
+			"\"Sep 22, 2024\",HOTEL,120,ref-4,TESTER TIME\n",
+	))
+	if err != nil {
+		t.Fatalf("expected statement to parse, got %v", err)
+	}
+
+	want := []CardStatementLine{
+		{Date: "2024-09-18", Amount: 1069.42, Description: "BIG VENDOR IND #42", Reference: "ref-1"},
+		{Date: "2024-09-22", Amount: 120, Description: "HOTEL", Reference: "ref-4"},
+	}
+	if !reflect.DeepEqual(statement.Lines, want) {
+		t.Fatalf("lines = %+v, want %+v", statement.Lines, want)
+	}
+	if statement.CreditCount != 2 {
+		t.Fatalf("CreditCount = %d, want 2", statement.CreditCount)
+	}
+}
+
+func TestParseCardStatementCSV_Errors(t *testing.T) {
+	cases := map[string]string{
+		"missing amount column": "date,description\n2024-09-18,COFFEE\n",
+		"invalid date":          "date,amount\n18/09/2024,12.00\n",
+		"invalid amount":        "date,amount\n2024-09-18,twelve\n",
+	}
+	for name, content := range cases {
+		t.Run(name, func(t *testing.T) {
+			if _, err := ParseCardStatementCSV(strings.NewReader(content)); err == nil {
+				t.Fatal("expected an error")
+			}
+		})
+	}
+}
+
+func TestParseCardStatementOFX(t *testing.T) {
+	sgml := `OFXHEADER:100
+DATA:OFXSGML
+VERSION:102
+
+<OFX>
+<CREDITCARDMSGSRSV1>
+<CCSTMTTRNRS>
+<CCSTMTRS>
+<CURDEF>CAD
+<CCACCTFROM>
+<ACCTID>4500123456780656
+</CCACCTFROM>
+<BANKTRANLIST>
+<DTSTART>20240901
+<DTEND>20240930120000[-8:PST]
+<STMTTRN>
+<TRNTYPE>DEBIT
+<DTPOSTED>20240918
+<TRNAMT>-69.42
+<FITID>2024091801
+<NAME>BIG VENDOR IND &amp; CO
+<STMTTRN>
+<TRNTYPE>CREDIT
+<DTPOSTED>20240920
+<TRNAMT>500.00
+<FITID>2024092001
+<NAME>PAYMENT
+</BANKTRANLIST>
+</CCSTMTRS>
+</CCSTMTTRNRS>
+</CREDITCARDMSGSRSV1>
+</OFX>
+`
+	xml := `<?xml version="1.0" encoding="UTF-8"?>
+<?OFX OFXHEADER="200" VERSION="220"?>
+<OFX><CREDITCARDMSGSRSV1><CCSTMTTRNRS><CCSTMTRS>
+<CCACCTFROM><ACCTID>0656</ACCTID></CCACCTFROM>
+<BANKTRANLIST><DTSTART>20240901</DTSTART><DTEND>20240930</DTEND>
+<STMTTRN><TRNTYPE>DEBIT</TRNTYPE><DTPOSTED>20240918000000</DTPOSTED><TRNAMT>-69.42</TRNAMT><FITID>2024091801</FITID><MEMO>BIG VENDOR IND &amp; CO</MEMO></STMTTRN>
+<STMTTRN><TRNTYPE>CREDIT</TRNTYPE><DTPOSTED>20240920</DTPOSTED><TRNAMT>500.00</TRNAMT><FITID>2024092001</FITID><NAME>PAYMENT</NAME></STMTTRN>
+</BANKTRANLIST></CCSTMTRS></CCSTMTTRNRS></CREDITCARDMSGSRSV1></OFX>
+`
+	want := &CardStatement{
+		AccountLast4: "0656",
+		PeriodStart:  "2024-09-01",
+		PeriodEnd:    "2024-09-30",
+		Lines: []CardStatementLine{
+			{Date: "2024-09-18", Amount: 69.42, Description: "BIG VENDOR IND & CO", Reference: "2024091801"},
+		},
+		CreditCount: 1,
+	}
+	for name, content := range map[string]string{"sgml": sgml, "xml": xml} {
+		t.Run(name, func(t *testing.T) {
+			statement, err := ParseCardStatementOFX(strings.NewReader(content))
+			if err != nil {
+				t.Fatalf("expected statement to parse, got %v", err)
+			}
+			if !reflect.DeepEqual(statement, want) {
+				t.Fatalf("statement = %+v, want %+v", statement, want)
+			}
+		})
+	}
+
+	if _, err := ParseCardStatementOFX(strings.NewReader("date,amount\n2024-09-18,12.00\n")); err == nil {
+		t.Fatal("expected a CSV file to be rejected as OFX")
+	}
+}
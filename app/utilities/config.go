@@ -90,6 +90,61 @@ func IsTimeEditingEnabled(app core.App) (bool, error) {
 	return GetConfigBool(app, "time", "create_edit", true)
 }
 
+// IsLinkedWorkRecordsOnly checks whether time entries must reference a
+// first-class work record worker row instead of legacy work_record text.
+// Reads from app_config where key="time", checks value.linked_work_records_only.
+// Defaults to false so historical text entries remain editable until rollout.
+func IsLinkedWorkRecordsOnly(app core.App) bool {
+	enabled, err := GetConfigBool(app, "time", "linked_work_records_only", false)
+	if err != nil {
+		return false
+	}
+	return enabled
+}
+
+const (
+	TimeOffRequestEnforcementOff   = "off"
+	TimeOffRequestEnforcementWarn  = "warn"
+	TimeOffRequestEnforcementBlock = "block"
+)
+
+// GetTimeOffRequestEnforcement returns how bundling treats OP/OV time entries
+// without a matching approved time off request: "off" skips the check, "warn"
+// bundles and reports them and "block" rejects the bundle.
+// Reads from app_config where key="time", checks value.time_off_request_enforcement.
+// Defaults to "warn" when missing or invalid.
+func GetTimeOffRequestEnforcement(app core.App) string {
+	config, err := GetConfigValue(app, "time")
+	if err != nil || config == nil {
+		return TimeOffRequestEnforcementWarn
+	}
+	switch value, _ := config["time_off_request_enforcement"].(string); value {
+	case TimeOffRequestEnforcementOff, TimeOffRequestEnforcementWarn, TimeOffRequestEnforcementBlock:
+		return value
+	}
+	return TimeOffRequestEnforcementWarn
+}
+
+// GetTimesheetApprovalEscalationBusinessDays returns how many business days a
+// submitted timesheet may wait for approval before it is escalated. Zero
+// disables escalation.
+// Reads from app_config where key="time", checks value.approval_escalation_business_days.
+// Defaults to 0 (disabled) when missing or invalid, so escalation only starts
+// once it is configured.
+func GetTimesheetApprovalEscalationBusinessDays(app core.App) int {
+	const defaultBusinessDays = 0
+
+	config, err := GetConfigValue(app, "time")
+	if err != nil || config == nil {
+		return defaultBusinessDays
+	}
+	value, ok := config["approval_escalation_business_days"].(float64)
+	if !ok || value < 0 || value != float64(int(value)) {
+		return defaultBusinessDays
+	}
+	return int(value)
+}
+
 // IsNotificationFeatureEnabled checks whether a notification feature/template is enabled.
 // Reads from app_config where key="notifications", and uses templateCode as the JSON key.
 // Defaults to false (fail-closed) when config is missing.
@@ -364,6 +364,34 @@ func TestIsTimeEditingEnabled(t *testing.T) {
 	}
 }
 
+func TestGetTimesheetApprovalEscalationBusinessDays(t *testing.T) {
+	testsTable := []struct {
+		name     string
+		rawValue string
+		want     int
+	}{
+		{name: "disabled when key is missing", rawValue: `{"create_edit":true}`, want: 0},
+		{name: "returns configured value", rawValue: `{"approval_escalation_business_days":5}`, want: 5},
+		{name: "zero disables escalation", rawValue: `{"approval_escalation_business_days":0}`, want: 0},
+		{name: "returns default for negative value", rawValue: `{"approval_escalation_business_days":-1}`, want: 0},
+		{name: "returns default for fractional value", rawValue: `{"approval_escalation_business_days":1.5}`, want: 0},
+		{name: "returns default for non-number value", rawValue: `{"approval_escalation_business_days":"5"}`, want: 0},
+	}
+
+	for _, tc := range testsTable {
+		t.Run(tc.name, func(t *testing.T) {
+			app := testseed.NewSeededTestApp(t)
+			defer app.Cleanup()
+
+			upsertTimeConfig(t, app, tc.rawValue)
+
+			if got := GetTimesheetApprovalEscalationBusinessDays(app); got != tc.want {
+				t.Fatalf("GetTimesheetApprovalEscalationBusinessDays() = %d, want %d", got, tc.want)
+			}
+		})
+	}
+}
+
 func TestGetPOExpenseExcessConfig(t *testing.T) {
 	testsTable := []struct {
 		name        string
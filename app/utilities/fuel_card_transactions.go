@@ -0,0 +1,185 @@
+package utilities
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// FuelCardTransactionError is one problem with one field of a fuel card
+// transaction row.
+type FuelCardTransactionError struct {
+	Field   string `json:"field"`
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+// FuelCardTransaction is one purchase from a fuel card provider's transaction
+// export. Row is the CSV line number, so the header is row 1. CardNumber holds
+// only the digits of the card number; CardMasked is set when the provider
+// masked some of them, in which case CardNumber is the visible trailing
+// digits. Quantity is in litres. Errors lists the row's problems; the other
+// fields are only meaningful when it is empty.
+type FuelCardTransaction struct {
+	Row        int
+	CardNumber string
+	CardMasked bool
+	Date       string
+	Amount     float64
+	Merchant   string
+	Product    string
+	Quantity   float64
+	Distance   float64
+	Reference  string
+	Errors     []FuelCardTransactionError
+}
+
+// fuelCardCSVColumns maps the accepted CSV header names, normalized to lower
+// case with spaces as underscores, to the field they hold.
+var fuelCardCSVColumns = map[string]string{
+	"card":             "card_number",
+	"card_number":      "card_number",
+	"card_no":          "card_number",
+	"date":             "date",
+	"transaction_date": "date",
+	"purchase_date":    "date",
+	"amount":           "amount",
+	"total":            "amount",
+	"total_amount":     "amount",
+	"merchant":         "merchant",
+	"site":             "merchant",
+	"site_name":        "merchant",
+	"location":         "merchant",
+	"vendor":           "merchant",
+	"product":          "product",
+	"fuel_type":        "product",
+	"litres":           "quantity",
+	"liters":           "quantity",
+	"quantity":         "quantity",
+	"volume":           "quantity",
+	"distance":         "distance",
+	"km":               "distance",
+	"reference":        "reference",
+	"transaction_id":   "reference",
+	"authorization":    "reference",
+}
+
+// ParseFuelCardTransactionsCSV parses a fuel card provider's transaction
+// export. The header row must include card number, date and amount columns;
+// merchant, product, quantity, distance and reference columns are optional and
+// other columns are ignored. Blank rows are skipped. A row that cannot be read
+// is returned with Errors rather than failing the whole file, so an import can
+// report every problem at once.
+func ParseFuelCardTransactionsCSV(reader io.Reader) ([]FuelCardTransaction, error) {
+	csvReader := csv.NewReader(reader)
+	csvReader.FieldsPerRecord = -1
+	csvReader.TrimLeadingSpace = true
+
+	header, err := csvReader.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("the file has no rows")
+	}
+	if err != nil {
+		return nil, fmt.Errorf("the file could not be read: %w", err)
+	}
+	columnIndex := map[string]int{}
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(strings.TrimPrefix(name, "\ufeff")))
+		name = strings.ReplaceAll(name, " ", "_")
+		if field, ok := fuelCardCSVColumns[name]; ok {
+			if _, seen := columnIndex[field]; !seen {
+				columnIndex[field] = i
+			}
+		}
+	}
+	for _, required := range []string{"card_number", "date", "amount"} {
+		if _, ok := columnIndex[required]; !ok {
+			return nil, fmt.Errorf("the file has no %s column", strings.ReplaceAll(required, "_", " "))
+		}
+	}
+
+	value := func(fields []string, field string) string {
+		i, ok := columnIndex[field]
+		if !ok || i >= len(fields) {
+			return ""
+		}
+		return strings.TrimSpace(fields[i])
+	}
+
+	transactions := []FuelCardTransaction{}
+	for {
+		fields, err := csvReader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("the file could not be read: %w", err)
+		}
+		row, _ := csvReader.FieldPos(0)
+		if strings.TrimSpace(strings.Join(fields, "")) == "" {
+			continue
+		}
+
+		transaction := FuelCardTransaction{
+			Row:       row,
+			Merchant:  value(fields, "merchant"),
+			Product:   value(fields, "product"),
+			Reference: value(fields, "reference"),
+			Errors:    []FuelCardTransactionError{},
+		}
+		addError := func(field string, code string, message string) {
+			transaction.Errors = append(transaction.Errors, FuelCardTransactionError{Field: field, Code: code, Message: message})
+		}
+
+		transaction.CardNumber, transaction.CardMasked = normalizeFuelCardNumber(value(fields, "card_number"))
+		if len(transaction.CardNumber) < 4 {
+			addError("card_number", "invalid_card_number", "the card number must include at least 4 digits")
+		}
+		if transaction.Date, err = parseCardStatementDate(value(fields, "date")); err != nil {
+			addError("date", "invalid_date", err.Error())
+		}
+		if transaction.Amount, err = parseCardStatementAmount(value(fields, "amount")); err != nil {
+			addError("amount", "invalid_number", err.Error())
+		} else if transaction.Amount <= 0 {
+			addError("amount", "not_a_purchase", "only purchases can be imported; the amount must be greater than 0")
+		}
+		for _, field := range []string{"quantity", "distance"} {
+			raw := value(fields, field)
+			if raw == "" {
+				continue
+			}
+			number, err := parseCardStatementAmount(raw)
+			if err != nil || number < 0 {
+				addError(field, "invalid_number", fmt.Sprintf("%s must be a number that is not negative", field))
+				continue
+			}
+			if field == "quantity" {
+				transaction.Quantity = number
+			} else {
+				transaction.Distance = number
+			}
+		}
+		transactions = append(transactions, transaction)
+	}
+	return transactions, nil
+}
+
+// normalizeFuelCardNumber returns the digits of a card number as exported by
+// a provider. Providers often mask all but the last digits, as in
+// "XXXX-XXXX-1234" or "****1234"; masked reports whether they did, in which
+// case only the digits after the last masked position are returned.
+func normalizeFuelCardNumber(value string) (digits string, masked bool) {
+	var builder strings.Builder
+	for _, r := range value {
+		switch {
+		case r >= '0' && r <= '9':
+			builder.WriteRune(r)
+		case r == '*' || r == 'x' || r == 'X':
+			masked = true
+			builder.Reset()
+		}
+	}
+	return builder.String(), masked
+}
@@ -0,0 +1,58 @@
+package utilities
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestParseFuelCardTransactionsCSV(t *testing.T) {
+	transactions, err := ParseFuelCardTransactionsCSV(strings.NewReader(
+		"Card Number,Purchase Date,Site,Fuel Type,Litres,Total,KM,Authorization\n" +
+			"XXXX-XXXX-5678,2024-09-18,PETRO STATION #12,Diesel,45.2,\"$1,069.42\",412,A1\n" +
+			"\n" +
+			"7083 0500 1234 5678,2024/09/20,ROADSIDE FUEL,,,80,,\n" +
+			"12,18/09/2024,ROADSIDE FUEL,,lots,-5.00,,\n",
+	))
+	if err != nil {
+		t.Fatalf("expected transactions to parse, got %v", err)
+	}
+	if len(transactions) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(transactions))
+	}
+
+	masked := transactions[0]
+	if masked.Row != 2 || masked.CardNumber != "5678" || !masked.CardMasked || masked.Date != "2024-09-18" ||
+		masked.Amount != 1069.42 || masked.Quantity != 45.2 || masked.Distance != 412 ||
+		masked.Merchant != "PETRO STATION #12" || masked.Product != "Diesel" || masked.Reference != "A1" || len(masked.Errors) != 0 {
+		t.Fatalf("unexpected masked transaction %+v", masked)
+	}
+
+	full := transactions[1]
+	if full.Row != 4 || full.CardNumber != "7083050012345678" || full.CardMasked || full.Date != "2024-09-20" || full.Amount != 80 || len(full.Errors) != 0 {
+		t.Fatalf("unexpected transaction %+v", full)
+	}
+
+	invalid := transactions[2]
+	codes := map[string]string{}
+	for _, rowError := range invalid.Errors {
+		codes[rowError.Field] = rowError.Code
+	}
+	want := map[string]string{
+		"card_number": "invalid_card_number",
+		"date":        "invalid_date",
+		"amount":      "not_a_purchase",
+		"quantity":    "invalid_number",
+	}
+	if len(codes) != len(want) {
+		t.Fatalf("errors = %+v, want %+v", invalid.Errors, want)
+	}
+	for field, code := range want {
+		if codes[field] != code {
+			t.Fatalf("errors = %+v, want %+v", invalid.Errors, want)
+		}
+	}
+
+	if _, err := ParseFuelCardTransactionsCSV(strings.NewReader("date,amount\n2024-09-18,12.00\n")); err == nil {
+		t.Fatal("expected a file without a card number column to be rejected")
+	}
+}
@@ -0,0 +1,254 @@
+package utilities
+
+import (
+	"math"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// InvoiceFields are the values ExtractInvoiceFields found in an invoice or
+// receipt. Zero values mean the field was not found. InvoiceDate is formatted
+// as YYYY-MM-DD.
+type InvoiceFields struct {
+	Total         float64
+	Tax           float64
+	InvoiceDate   string
+	VendorName    string
+	InvoiceNumber string
+}
+
+// IsEmpty reports whether no field was found.
+func (f InvoiceFields) IsEmpty() bool {
+	return f == InvoiceFields{}
+}
+
+var (
+	invoiceAmount = regexp.MustCompile(`\(?-?\$?\s?\d{1,3}(?:,\d{3})*\.\d{2}\)?|\(?-?\$?\s?\d+\.\d{2}\)?`)
+	invoiceNumber = regexp.MustCompile(`(?i)\b(?:invoice|inv)\.?\s*(?:no\.?|number|num\.?|#)?\s*[:#]?\s*([A-Z0-9][A-Z0-9/-]*)`)
+	invoiceTaxes  = regexp.MustCompile(`(?i)\b(?:hst|gst|pst|qst|rst|tps|tvq|tvh|sales tax|tax)\b`)
+
+	invoiceMonths   = `(jan|feb|mar|apr|may|jun|jul|aug|sep|sept|oct|nov|dec)[a-z]*\.?`
+	invoiceISODate  = regexp.MustCompile(`\b(\d{4})[-/.](\d{1,2})[-/.](\d{1,2})\b`)
+	invoiceMDYDate  = regexp.MustCompile(`(?i)\b` + invoiceMonths + `\s+(\d{1,2})(?:st|nd|rd|th)?,?\s+(\d{4})\b`)
+	invoiceDMYDate  = regexp.MustCompile(`(?i)\b(\d{1,2})[\s-]+` + invoiceMonths + `[\s,-]+(\d{4})\b`)
+	invoiceSlashDMY = regexp.MustCompile(`\b(\d{1,2})/(\d{1,2})/(\d{4})\b`)
+)
+
+// invoiceTotalLabels lists the labels of the amount an invoice asks to be
+// paid, most specific first. A later rank is only used when no earlier one
+// has a non-zero amount.
+var invoiceTotalLabels = [][]string{
+	{"amount due", "balance due", "total due", "amount payable"},
+	{"grand total", "invoice total", "total amount", "total cad", "total usd", "total (cad)", "total (usd)"},
+	{"total"},
+}
+
+// ExtractInvoiceFields finds the total, tax, invoice date, vendor name and
+// invoice number in the text of an invoice or receipt, such as the output of
+// ExtractPDFText. It reads the labels invoices commonly use and is meant to
+// suggest values, not to be trusted without review: anything it cannot find
+// is left at its zero value.
+func ExtractInvoiceFields(text string) InvoiceFields {
+	lines := []string{}
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.Join(strings.Fields(line), " "); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	return InvoiceFields{
+		Total:         invoiceTotal(lines),
+		Tax:           invoiceTax(lines),
+		InvoiceDate:   invoiceDate(lines),
+		VendorName:    invoiceVendorName(lines),
+		InvoiceNumber: invoiceNumberValue(lines),
+	}
+}
+
+// invoiceLastAmount returns the last amount on a line, which is the value
+// column on most invoice layouts.
+func invoiceLastAmount(line string) (float64, bool) {
+	matches := invoiceAmount.FindAllString(line, -1)
+	if len(matches) == 0 {
+		return 0, false
+	}
+	amount, err := parseCardStatementAmount(strings.Replace(matches[len(matches)-1], "-$", "-", 1))
+	return amount, err == nil
+}
+
+func invoiceTotal(lines []string) float64 {
+	for _, labels := range invoiceTotalLabels {
+		found := 0.0
+		for _, line := range lines {
+			lower := strings.ToLower(line)
+			if strings.Contains(lower, "subtotal") || strings.Contains(lower, "sub-total") || strings.Contains(lower, "sub total") ||
+				strings.Contains(lower, "before tax") || strings.Contains(lower, "total tax") || strings.Contains(lower, "tax total") {
+				continue
+			}
+			for _, label := range labels {
+				if !strings.Contains(lower, label) {
+					continue
+				}
+				// The bottom-most total wins, since totals accumulate down
+				// the page.
+				if amount, ok := invoiceLastAmount(line); ok && amount > 0 {
+					found = amount
+				}
+				break
+			}
+		}
+		if found > 0 {
+			return found
+		}
+	}
+	return 0
+}
+
+func invoiceTax(lines []string) float64 {
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		if strings.Contains(lower, "total tax") || strings.Contains(lower, "tax total") {
+			if amount, ok := invoiceLastAmount(line); ok {
+				return amount
+			}
+		}
+	}
+	sum := 0.0
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		if !invoiceTaxes.MatchString(line) || strings.Contains(lower, "total") || strings.Contains(lower, "before tax") || strings.Contains(lower, "excl") {
+			continue
+		}
+		// Registration number lines have no decimal amount and are skipped
+		// here.
+		if amount, ok := invoiceLastAmount(line); ok {
+			sum += amount
+		}
+	}
+	return RoundCurrencyAmount(sum)
+}
+
+func invoiceDate(lines []string) string {
+	for _, labelled := range []bool{true, false} {
+		for _, line := range lines {
+			lower := strings.ToLower(line)
+			if strings.Contains(lower, "due") || strings.Contains(lower, "period") {
+				continue
+			}
+			if labelled && !strings.Contains(lower, "date") {
+				continue
+			}
+			if date := invoiceParseDate(line); date != "" {
+				return date
+			}
+		}
+	}
+	return ""
+}
+
+// invoiceParseDate returns the first date on a line. Numeric dates with the
+// day and month in either order are only accepted when the order is clear.
+func invoiceParseDate(line string) string {
+	build := func(year string, month int, day string) string {
+		y, errYear := strconv.Atoi(year)
+		d, errDay := strconv.Atoi(day)
+		if errYear != nil || errDay != nil || y < 1990 || y > 2100 {
+			return ""
+		}
+		date := time.Date(y, time.Month(month), d, 0, 0, 0, 0, time.UTC)
+		if date.Day() != d || int(date.Month()) != month {
+			return ""
+		}
+		return date.Format(time.DateOnly)
+	}
+	monthNumber := func(name string) int {
+		prefix := strings.ToLower(name)
+		if len(prefix) > 3 {
+			prefix = prefix[:3]
+		}
+		return strings.Index("janfebmaraprmayjunjulaugsepoctnovdec", prefix)/3 + 1
+	}
+
+	type candidate struct {
+		at   int
+		date string
+	}
+	candidates := []candidate{}
+	for _, match := range invoiceISODate.FindAllStringSubmatchIndex(line, -1) {
+		month, _ := strconv.Atoi(line[match[4]:match[5]])
+		candidates = append(candidates, candidate{match[0], build(line[match[2]:match[3]], month, line[match[6]:match[7]])})
+	}
+	for _, match := range invoiceMDYDate.FindAllStringSubmatchIndex(line, -1) {
+		candidates = append(candidates, candidate{match[0], build(line[match[6]:match[7]], monthNumber(line[match[2]:match[3]]), line[match[4]:match[5]])})
+	}
+	for _, match := range invoiceDMYDate.FindAllStringSubmatchIndex(line, -1) {
+		candidates = append(candidates, candidate{match[0], build(line[match[6]:match[7]], monthNumber(line[match[4]:match[5]]), line[match[2]:match[3]])})
+	}
+	for _, match := range invoiceSlashDMY.FindAllStringSubmatchIndex(line, -1) {
+		first, _ := strconv.Atoi(line[match[2]:match[3]])
+		second, _ := strconv.Atoi(line[match[4]:match[5]])
+		year := line[match[6]:match[7]]
+		switch {
+		case first > 12 && second <= 12:
+			candidates = append(candidates, candidate{match[0], build(year, second, strconv.Itoa(first))})
+		case second > 12 && first <= 12:
+			candidates = append(candidates, candidate{match[0], build(year, first, strconv.Itoa(second))})
+		case first == second:
+			candidates = append(candidates, candidate{match[0], build(year, first, strconv.Itoa(second))})
+		}
+	}
+	best := candidate{at: math.MaxInt}
+	for _, c := range candidates {
+		if c.date != "" && c.at < best.at {
+			best = c
+		}
+	}
+	return best.date
+}
+
+func invoiceNumberValue(lines []string) string {
+	for _, line := range lines {
+		for _, match := range invoiceNumber.FindAllStringSubmatch(line, -1) {
+			value := strings.Trim(match[1], "-/")
+			if !strings.ContainsAny(value, "0123456789") || invoiceParseDate(value) != "" {
+				continue
+			}
+			return value
+		}
+	}
+	return ""
+}
+
+// invoiceVendorName returns the first line that reads like a business name.
+// Invoices almost always open with the issuer's name or logo text.
+func invoiceVendorName(lines []string) string {
+	for _, line := range lines {
+		lower := strings.ToLower(line)
+		letters := 0
+		for _, r := range line {
+			if r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r > 0x7f {
+				letters++
+			}
+		}
+		if letters < 2 || invoiceAmount.MatchString(line) || invoiceParseDate(line) != "" {
+			continue
+		}
+		skip := false
+		for _, word := range []string{"invoice", "receipt", "statement", "page ", "bill to", "sold to", "ship to", "tax", "date"} {
+			if strings.HasPrefix(lower, word) || lower == strings.TrimSpace(word) {
+				skip = true
+				break
+			}
+		}
+		if skip {
+			continue
+		}
+		name := strings.Trim(line, " -:|")
+		if len([]rune(name)) > 100 {
+			name = string([]rune(name)[:100])
+		}
+		return name
+	}
+	return ""
+}
@@ -0,0 +1,148 @@
+package utilities
+
+import (
+	"bytes"
+	"compress/zlib"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// testPDF assembles a PDF from object bodies numbered from 1. Object 1 must be
+// the catalog. The cross-reference table is omitted because ExtractPDFText
+// does not need it.
+func testPDF(objects ...string) []byte {
+	var out bytes.Buffer
+	out.WriteString("%PDF-1.4\n")
+	for i, object := range objects {
+		fmt.Fprintf(&out, "%d 0 obj\n%s\nendobj\n", i+1, object)
+	}
+	out.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return out.Bytes()
+}
+
+func testPDFStream(dict string, data []byte) string {
+	return fmt.Sprintf("<< %s /Length %d >>\nstream\n%s\nendstream", dict, len(data), data)
+}
+
+func TestExtractPDFTextUncompressedCells(t *testing.T) {
+	// Laid out the way reports.PDFDocument writes tables: one text object per
+	// cell, with the label and value columns sharing a baseline. The second
+	// page comes first in the file but last in the page tree.
+	content := "BT /F1 18 Tf 50 740 Td (Big Vendor Industries) Tj ET\n" +
+		"BT /F1 10 Tf 50 700 Td (Invoice #: INV-20417) Tj ET\n" +
+		"BT /F1 10 Tf 300 700 Td (Invoice Date: Sep 18, 2024) Tj ET\n" +
+		"BT /F1 10 Tf 400 600 Td (1,130.00) Tj ET\n" +
+		"BT /F1 10 Tf 50 600.5 Td (Amount Due \\(CAD\\)) Tj ET\n"
+	pdf := testPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [6 0 R 4 0 R] /Count 2 >>",
+		"<< /Type /Font /Subtype /Type1 /BaseFont /Helvetica /Encoding /WinAnsiEncoding >>",
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 3 0 R >> >> /Contents 5 0 R >>",
+		testPDFStream("", []byte("BT /F1 10 Tf 50 700 Td (Thank you) Tj ET\n")),
+		"<< /Type /Page /Parent 2 0 R /Resources << /Font << /F1 3 0 R >> >> /Contents 7 0 R >>",
+		testPDFStream("", []byte(content)),
+	)
+
+	text, err := ExtractPDFText(pdf)
+	if err != nil {
+		t.Fatalf("expected text, got %v", err)
+	}
+	want := "Big Vendor Industries\nInvoice #: INV-20417 Invoice Date: Sep 18, 2024\nAmount Due (CAD) 1,130.00\nThank you"
+	if text != want {
+		t.Fatalf("text = %q, want %q", text, want)
+	}
+}
+
+func TestExtractPDFTextFlateAndToUnicode(t *testing.T) {
+	// A two-byte font whose ToUnicode map covers the codes used, shown with
+	// TJ and a word gap, inside a compressed content stream.
+	cmap := "/CIDInit /ProcSet findresource begin\nbegincmap\n" +
+		"1 begincodespacerange\n<0000> <FFFF>\nendcodespacerange\n" +
+		"2 beginbfchar\n<0010> <0024>\n<0050> <002E>\nendbfchar\n" +
+		"2 beginbfrange\n<0020> <0039> <0030>\n<0041> <0043> [<0054> <006F> <0074>]\nendbfrange\n" +
+		"endcmap\n"
+	var compressed bytes.Buffer
+	writer := zlib.NewWriter(&compressed)
+	// "Tot" then a gap, then "$45.20" using the mapped codes.
+	writer.Write([]byte("q 1 0 0 1 72 0 cm BT /C0 12 Tf 1 0 0 1 0 500 Tm [<004100420043> -400 <0010>] TJ (\x00\x24\x00\x25) Tj [<0050> 20 <00220020>] TJ ET Q\n"))
+	writer.Close()
+
+	pdf := testPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Resources 4 0 R /Contents 6 0 R >>",
+		"<< /Font << /C0 5 0 R >> >>",
+		"<< /Type /Font /Subtype /Type0 /BaseFont /Custom /Encoding /Identity-H /ToUnicode 7 0 R >>",
+		testPDFStream("/Filter /FlateDecode", compressed.Bytes()),
+		testPDFStream("", []byte(cmap)),
+	)
+
+	text, err := ExtractPDFText(pdf)
+	if err != nil {
+		t.Fatalf("expected text, got %v", err)
+	}
+	if text != "Tot $45.20" {
+		t.Fatalf("text = %q, want %q", text, "Tot $45.20")
+	}
+}
+
+func TestExtractPDFTextErrors(t *testing.T) {
+	if _, err := ExtractPDFText([]byte("\x89PNG\r\n")); err == nil {
+		t.Fatal("expected a non-PDF to be rejected")
+	}
+	scanned := testPDF(
+		"<< /Type /Catalog /Pages 2 0 R >>",
+		"<< /Type /Pages /Kids [3 0 R] /Count 1 >>",
+		"<< /Type /Page /Parent 2 0 R /Contents 4 0 R >>",
+		testPDFStream("", []byte("q 612 0 0 792 0 0 cm /Im0 Do Q\n")),
+	)
+	if _, err := ExtractPDFText(scanned); !errors.Is(err, ErrPDFNoText) {
+		t.Fatalf("expected ErrPDFNoText, got %v", err)
+	}
+}
+
+func TestExtractInvoiceFields(t *testing.T) {
+	fields := ExtractInvoiceFields(strings.Join([]string{
+		"INVOICE",
+		"Big Vendor Industries Ltd.",
+		"123 Main Street, Toronto ON",
+		"HST Reg No. 123456789 RT0001",
+		"Invoice No: INV-20417 Invoice Date: 18 September 2024",
+		"Due Date: 2024-10-18",
+		"Widgets 10 @ 50.00 500.00",
+		"Subtotal 1,000.00",
+		"HST 13% 130.00",
+		"Total $1,130.00",
+		"Payments (1,130.00)",
+		"Balance Due 0.00",
+	}, "\n"))
+	want := InvoiceFields{
+		Total:         1130,
+		Tax:           130,
+		InvoiceDate:   "2024-09-18",
+		VendorName:    "Big Vendor Industries Ltd.",
+		InvoiceNumber: "INV-20417",
+	}
+	if fields != want {
+		t.Fatalf("fields = %+v, want %+v", fields, want)
+	}
+
+	fields = ExtractInvoiceFields(strings.Join([]string{
+		"Corner Hardware",
+		"Receipt 4471",
+		"09/25/2024 14:02",
+		"GST 5% 2.50",
+		"PST 7% 3.50",
+		"Amount Due 56.00",
+	}, "\n"))
+	want = InvoiceFields{Total: 56, Tax: 6, InvoiceDate: "2024-09-25", VendorName: "Corner Hardware"}
+	if fields != want {
+		t.Fatalf("fields = %+v, want %+v", fields, want)
+	}
+
+	if fields := ExtractInvoiceFields("06/07/2024\n"); !fields.IsEmpty() {
+		t.Fatalf("expected an ambiguous date alone to find nothing, got %+v", fields)
+	}
+}
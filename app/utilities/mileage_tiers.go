@@ -0,0 +1,90 @@
+package utilities
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strconv"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+// MileageTierPortion is the part of a claim's kilometres that falls in one
+// rate tier.
+type MileageTierPortion struct {
+	// FromKm is the tier's lower bound in cumulative kilometres.
+	FromKm   int
+	Rate     float64
+	Distance int
+}
+
+// ParseMileageRates reads the mileage property of an expense_rates record:
+// each key is a tier's lower bound in kilometres and each value its rate.
+func ParseMileageRates(raw any) (map[int]float64, error) {
+	var data []byte
+	switch value := raw.(type) {
+	case types.JSONRaw:
+		data = value
+	case string:
+		data = []byte(value)
+	default:
+		return nil, fmt.Errorf("mileage data is not of type types.JSONRaw")
+	}
+	var byKey map[string]float64
+	if err := json.Unmarshal(data, &byKey); err != nil {
+		return nil, err
+	}
+	rates := make(map[int]float64, len(byKey))
+	for key, rate := range byKey {
+		fromKm, err := strconv.Atoi(key)
+		if err != nil {
+			return nil, err
+		}
+		rates[fromKm] = rate
+	}
+	if len(rates) == 0 {
+		return nil, fmt.Errorf("no mileage rates found")
+	}
+	return rates, nil
+}
+
+// SplitMileageAcrossTiers splits a claim of distance kilometres, driven after
+// prior kilometres in the same reset period, across the rate tiers. This is
+// the split CalculateMileageTotal prices. Kilometres below the lowest tier
+// count towards it.
+func SplitMileageAcrossTiers(rates map[int]float64, prior int, distance int) []MileageTierPortion {
+	bounds := make([]int, 0, len(rates))
+	for fromKm := range rates {
+		bounds = append(bounds, fromKm)
+	}
+	sort.Ints(bounds)
+
+	portions := []MileageTierPortion{}
+	start, end := prior, prior+distance
+	for i, fromKm := range bounds {
+		lower := fromKm
+		if i == 0 {
+			lower = math.MinInt
+		}
+		upper := math.MaxInt
+		if i+1 < len(bounds) {
+			upper = bounds[i+1]
+		}
+		overlap := min(end, upper) - max(start, lower)
+		if overlap > 0 {
+			portions = append(portions, MileageTierPortion{FromKm: fromKm, Rate: rates[fromKm], Distance: overlap})
+		}
+	}
+	return portions
+}
+
+// MileageTierAmount prices tier portions the way CalculateMileageTotal does:
+// rates to a tenth of a cent, with the sum rounded to cents.
+func MileageTierAmount(portions []MileageTierPortion) float64 {
+	totalX1000 := 0
+	for _, portion := range portions {
+		totalX1000 += portion.Distance * int(portion.Rate*1000)
+	}
+	return math.Round((float64(totalX1000)/1000)*100) / 100
+}
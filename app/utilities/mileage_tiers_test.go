@@ -0,0 +1,56 @@
+package utilities
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pocketbase/pocketbase/tools/types"
+)
+
+func TestParseMileageRates(t *testing.T) {
+	rates, err := ParseMileageRates(types.JSONRaw(`{"0": 0.70, "5000": 0.64}`))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !reflect.DeepEqual(rates, map[int]float64{0: 0.70, 5000: 0.64}) {
+		t.Fatalf("unexpected rates: %v", rates)
+	}
+	for _, raw := range []any{`{}`, `{"x": 1}`, `[]`, 12} {
+		if _, err := ParseMileageRates(raw); err == nil {
+			t.Fatalf("expected %v to fail", raw)
+		}
+	}
+}
+
+func TestSplitMileageAcrossTiers(t *testing.T) {
+	rates := map[int]float64{0: 0.61, 5000: 0.55}
+	cases := []struct {
+		name     string
+		prior    int
+		distance int
+		want     []MileageTierPortion
+		amount   float64
+	}{
+		{"first tier", 0, 100, []MileageTierPortion{{FromKm: 0, Rate: 0.61, Distance: 100}}, 61},
+		{"crosses a tier", 4900, 200, []MileageTierPortion{{FromKm: 0, Rate: 0.61, Distance: 100}, {FromKm: 5000, Rate: 0.55, Distance: 100}}, 116},
+		{"upper tier", 6000, 10, []MileageTierPortion{{FromKm: 5000, Rate: 0.55, Distance: 10}}, 5.5},
+		{"nothing", 10, 0, []MileageTierPortion{}, 0},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got := SplitMileageAcrossTiers(rates, tc.prior, tc.distance)
+			if !reflect.DeepEqual(got, tc.want) {
+				t.Fatalf("expected %+v, got %+v", tc.want, got)
+			}
+			if amount := MileageTierAmount(got); amount != tc.amount {
+				t.Fatalf("expected amount %v, got %v", tc.amount, amount)
+			}
+		})
+	}
+
+	// Kilometres below the lowest tier count towards it.
+	got := SplitMileageAcrossTiers(map[int]float64{100: 0.5}, 0, 50)
+	if len(got) != 1 || got[0].FromKm != 100 || got[0].Distance != 50 {
+		t.Fatalf("expected the lowest tier to take the distance, got %+v", got)
+	}
+}
@@ -0,0 +1,857 @@
+package utilities
+
+import (
+	"bytes"
+	"compress/flate"
+	"compress/zlib"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf16"
+)
+
+// maxPDFDecodedBytes caps how much stream data one document may inflate to,
+// so a small compressed upload cannot exhaust memory.
+const maxPDFDecodedBytes = 32 << 20
+
+// ErrPDFNoText is returned by ExtractPDFText when a PDF has no text layer,
+// such as a scanned receipt.
+var ErrPDFNoText = errors.New("the PDF has no embedded text")
+
+var (
+	pdfObjectHeader = regexp.MustCompile(`(\d+)\s+(\d+)\s+obj\b`)
+	pdfReference    = regexp.MustCompile(`(\d+)\s+\d+\s+R\b`)
+	pdfFontEntry    = regexp.MustCompile(`/([^\s/<>\[\]()]+)\s+(\d+)\s+\d+\s+R\b`)
+)
+
+// pdfObject is one indirect object. Dict is the object's text before any
+// stream; Stream is the decoded stream, or nil when the object has none or
+// its filter is not supported.
+type pdfObject struct {
+	Dict   string
+	Stream []byte
+}
+
+// pdfTextRun is a string shown by a content stream at a position in user
+// space.
+type pdfTextRun struct {
+	X, Y float64
+	Size float64
+	Text string
+	// Continued is set when the string follows the previous one without being
+	// positioned, so it is part of the same word.
+	Continued bool
+}
+
+// ExtractPDFText returns the text a PDF's pages show, one line per row of
+// text in reading order. It is a small pure-Go reader meant for invoices and
+// receipts that software generated: it understands uncompressed and
+// Flate-compressed content streams, object streams, simple font encodings
+// and ToUnicode maps, but it does not render the page, so text drawn as
+// images or paths is not found. A PDF that shows no text returns
+// ErrPDFNoText.
+func ExtractPDFText(content []byte) (text string, err error) {
+	// Uploaded files are untrusted, so a malformed structure the parser does
+	// not anticipate is reported as unreadable rather than crashing.
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			text, err = "", fmt.Errorf("the PDF could not be read: %v", recovered)
+		}
+	}()
+	if !bytes.HasPrefix(bytes.TrimLeft(content, "\x00\t\r\n "), []byte("%PDF-")) {
+		return "", fmt.Errorf("the file is not a PDF")
+	}
+	objects := parsePDFObjects(content)
+	if len(objects) == 0 {
+		return "", fmt.Errorf("the PDF could not be read")
+	}
+
+	lines := []string{}
+	for _, page := range pdfPageOrder(objects) {
+		fonts := pdfPageFonts(objects, objects[page].Dict)
+		content := []byte{}
+		for _, ref := range pdfContentRefs(objects[page].Dict) {
+			if object, ok := objects[ref]; ok && object.Stream != nil {
+				content = append(content, object.Stream...)
+				content = append(content, '\n')
+			}
+		}
+		lines = append(lines, pdfRunsToLines(interpretPDFContent(content, fonts))...)
+	}
+	text = strings.TrimSpace(strings.Join(lines, "\n"))
+	if text == "" {
+		return "", ErrPDFNoText
+	}
+	return text, nil
+}
+
+// parsePDFObjects finds every indirect object in the file, including those
+// packed in object streams. A later definition of an object number replaces
+// an earlier one, as with incremental updates.
+func parsePDFObjects(content []byte) map[int]*pdfObject {
+	objects := map[int]*pdfObject{}
+	budget := maxPDFDecodedBytes
+	headers := pdfObjectHeader.FindAllSubmatchIndex(content, -1)
+	for i, header := range headers {
+		number, err := strconv.Atoi(string(content[header[2]:header[3]]))
+		if err != nil {
+			continue
+		}
+		start := header[1]
+		end := len(content)
+		if i+1 < len(headers) {
+			end = headers[i+1][0]
+		}
+		body := content[start:end]
+
+		object := &pdfObject{}
+		streamAt := pdfStreamKeyword(body)
+		if streamAt < 0 {
+			if at := bytes.Index(body, []byte("endobj")); at >= 0 {
+				body = body[:at]
+			}
+			object.Dict = string(body)
+			objects[number] = object
+			continue
+		}
+		object.Dict = string(body[:streamAt])
+		data := body[streamAt+len("stream"):]
+		data = bytes.TrimPrefix(data, []byte("\r"))
+		data = bytes.TrimPrefix(data, []byte("\n"))
+		if at := bytes.LastIndex(data, []byte("endstream")); at >= 0 {
+			data = data[:at]
+		}
+		if length, ok := pdfDirectInt(object.Dict, "Length"); ok && length >= 0 && length <= len(data) {
+			data = data[:length]
+		}
+		object.Stream = decodePDFStream(object.Dict, data, &budget)
+		objects[number] = object
+	}
+
+	for _, object := range objects {
+		if object.Stream == nil || !strings.Contains(object.Dict, "/ObjStm") {
+			continue
+		}
+		count, okCount := pdfDirectInt(object.Dict, "N")
+		first, okFirst := pdfDirectInt(object.Dict, "First")
+		if !okCount || !okFirst || first > len(object.Stream) {
+			continue
+		}
+		fields := strings.Fields(string(object.Stream[:first]))
+		for i := 0; i+1 < len(fields) && i/2 < count; i += 2 {
+			number, errNumber := strconv.Atoi(fields[i])
+			offset, errOffset := strconv.Atoi(fields[i+1])
+			if errNumber != nil || errOffset != nil || first+offset > len(object.Stream) {
+				continue
+			}
+			end := len(object.Stream)
+			if i+3 < len(fields) {
+				if next, err := strconv.Atoi(fields[i+3]); err == nil && first+next <= end && next >= offset {
+					end = first + next
+				}
+			}
+			if _, exists := objects[number]; !exists {
+				objects[number] = &pdfObject{Dict: string(object.Stream[first+offset : end])}
+			}
+		}
+	}
+	return objects
+}
+
+// pdfStreamKeyword returns the offset of the "stream" keyword that starts an
+// object's stream data, or -1 when the object has none.
+func pdfStreamKeyword(body []byte) int {
+	for offset := 0; ; {
+		at := bytes.Index(body[offset:], []byte("stream"))
+		if at < 0 {
+			return -1
+		}
+		at += offset
+		if endobj := bytes.Index(body, []byte("endobj")); endobj >= 0 && endobj < at {
+			return -1
+		}
+		after := at + len("stream")
+		if (at == 0 || body[at-1] != 'd') && after < len(body) && (body[after] == '\r' || body[after] == '\n') {
+			return at
+		}
+		offset = after
+	}
+}
+
+// pdfDirectInt returns the value of a /key entry that is a direct integer.
+func pdfDirectInt(dict string, key string) (int, bool) {
+	match := regexp.MustCompile(`/` + key + `\s+(\d+)(\s+\d+\s+R)?`).FindStringSubmatch(dict)
+	if match == nil || match[2] != "" {
+		return 0, false
+	}
+	value, err := strconv.Atoi(match[1])
+	return value, err == nil
+}
+
+// decodePDFStream applies the stream's filters. Only FlateDecode and
+// ASCIIHexDecode are supported; anything else, such as the image filters,
+// returns nil. budget is the number of decoded bytes still allowed.
+func decodePDFStream(dict string, data []byte, budget *int) []byte {
+	filters := []string{}
+	if match := regexp.MustCompile(`/Filter\s*(\[[^\]]*\]|/\w+)`).FindStringSubmatch(dict); match != nil {
+		filters = regexp.MustCompile(`/(\w+)`).FindAllString(match[1], -1)
+	}
+	for _, filter := range filters {
+		switch filter {
+		case "/FlateDecode", "/Fl":
+			// Some writers omit the zlib header and emit a raw deflate stream.
+			var reader io.Reader
+			if zr, err := zlib.NewReader(bytes.NewReader(data)); err == nil {
+				reader = zr
+			} else {
+				reader = flate.NewReader(bytes.NewReader(data))
+			}
+			decoded, err := io.ReadAll(io.LimitReader(reader, int64(*budget)+1))
+			if len(decoded) == 0 && err != nil {
+				return nil
+			}
+			data = decoded
+		case "/ASCIIHexDecode", "/AHx":
+			cleaned := strings.Map(func(r rune) rune {
+				if strings.ContainsRune("0123456789abcdefABCDEF", r) {
+					return r
+				}
+				return -1
+			}, strings.SplitN(string(data), ">", 2)[0])
+			if len(cleaned)%2 == 1 {
+				cleaned += "0"
+			}
+			decoded, err := hex.DecodeString(cleaned)
+			if err != nil {
+				return nil
+			}
+			data = decoded
+		default:
+			return nil
+		}
+	}
+	if len(data) > *budget {
+		*budget = 0
+		return nil
+	}
+	*budget -= len(data)
+	return data
+}
+
+// pdfPageOrder returns the page object numbers in page order, following the
+// page tree from the catalog. Documents whose tree cannot be followed fall
+// back to object number order.
+func pdfPageOrder(objects map[int]*pdfObject) []int {
+	pages := []int{}
+	seen := map[int]bool{}
+	var walk func(number int)
+	walk = func(number int) {
+		object, ok := objects[number]
+		if !ok || seen[number] {
+			return
+		}
+		seen[number] = true
+		if pdfIsType(object.Dict, "Page") {
+			pages = append(pages, number)
+			return
+		}
+		if match := regexp.MustCompile(`/Kids\s*\[([^\]]*)\]`).FindStringSubmatch(object.Dict); match != nil {
+			for _, ref := range pdfReference.FindAllStringSubmatch(match[1], -1) {
+				kid, _ := strconv.Atoi(ref[1])
+				walk(kid)
+			}
+		}
+	}
+	for _, object := range objects {
+		if pdfIsType(object.Dict, "Catalog") {
+			if match := regexp.MustCompile(`/Pages\s+(\d+)\s+\d+\s+R`).FindStringSubmatch(object.Dict); match != nil {
+				root, _ := strconv.Atoi(match[1])
+				walk(root)
+			}
+			break
+		}
+	}
+	if len(pages) > 0 {
+		return pages
+	}
+	for number, object := range objects {
+		if pdfIsType(object.Dict, "Page") {
+			pages = append(pages, number)
+		}
+	}
+	sort.Ints(pages)
+	return pages
+}
+
+func pdfIsType(dict string, name string) bool {
+	return regexp.MustCompile(`/Type\s*/` + name + `\b`).MatchString(dict)
+}
+
+// pdfContentRefs returns the object numbers of a page's content streams.
+func pdfContentRefs(pageDict string) []int {
+	match := regexp.MustCompile(`/Contents\s*(\[[^\]]*\]|\d+\s+\d+\s+R)`).FindStringSubmatch(pageDict)
+	if match == nil {
+		return nil
+	}
+	refs := []int{}
+	for _, ref := range pdfReference.FindAllStringSubmatch(match[1], -1) {
+		number, _ := strconv.Atoi(ref[1])
+		refs = append(refs, number)
+	}
+	return refs
+}
+
+// pdfPageFonts maps the font resource names a page uses to their ToUnicode
+// maps. Fonts without one map to nil and are decoded as single-byte text.
+func pdfPageFonts(objects map[int]*pdfObject, pageDict string) map[string]*pdfCMap {
+	resources := pdfResolveDict(objects, pageDict, "Resources")
+	fontDict := pdfResolveDict(objects, resources, "Font")
+	fonts := map[string]*pdfCMap{}
+	for _, entry := range pdfFontEntry.FindAllStringSubmatch(fontDict, -1) {
+		number, _ := strconv.Atoi(entry[2])
+		font, ok := objects[number]
+		if !ok {
+			continue
+		}
+		var cmap *pdfCMap
+		if match := regexp.MustCompile(`/ToUnicode\s+(\d+)\s+\d+\s+R`).FindStringSubmatch(font.Dict); match != nil {
+			ref, _ := strconv.Atoi(match[1])
+			if stream, ok := objects[ref]; ok && stream.Stream != nil {
+				cmap = parsePDFCMap(stream.Stream)
+			}
+		}
+		fonts[entry[1]] = cmap
+	}
+	return fonts
+}
+
+// pdfResolveDict returns the text of the /key dictionary in dict, following
+// an indirect reference when the entry is one.
+func pdfResolveDict(objects map[int]*pdfObject, dict string, key string) string {
+	at := regexp.MustCompile(`/` + key + `\s*(<<|\d+\s+\d+\s+R)`).FindStringSubmatchIndex(dict)
+	if at == nil {
+		return ""
+	}
+	value := dict[at[2]:at[3]]
+	if value != "<<" {
+		number, _ := strconv.Atoi(pdfReference.FindStringSubmatch(value)[1])
+		if object, ok := objects[number]; ok {
+			return object.Dict
+		}
+		return ""
+	}
+	depth := 0
+	for i := at[2]; i+1 < len(dict); i++ {
+		switch dict[i : i+2] {
+		case "<<":
+			depth++
+			i++
+		case ">>":
+			depth--
+			i++
+			if depth == 0 {
+				return dict[at[2] : i+1]
+			}
+		}
+	}
+	return dict[at[2]:]
+}
+
+// pdfCMap is a font's ToUnicode map. CodeBytes is the length of a character
+// code, from the first codespace range.
+type pdfCMap struct {
+	CodeBytes int
+	Chars     map[uint32]string
+}
+
+// parsePDFCMap reads the bfchar and bfrange sections of a ToUnicode CMap.
+func parsePDFCMap(data []byte) *pdfCMap {
+	text := string(data)
+	cmap := &pdfCMap{CodeBytes: 1, Chars: map[uint32]string{}}
+	if match := regexp.MustCompile(`begincodespacerange\s*<([0-9a-fA-F]+)>`).FindStringSubmatch(text); match != nil {
+		cmap.CodeBytes = max(1, len(match[1])/2)
+	}
+	hexToken := `<([0-9a-fA-F]*)>`
+	for _, section := range regexp.MustCompile(`(?s)beginbfchar(.*?)endbfchar`).FindAllStringSubmatch(text, -1) {
+		for _, pair := range regexp.MustCompile(hexToken+`\s*`+hexToken).FindAllStringSubmatch(section[1], -1) {
+			code, err := strconv.ParseUint(pair[1], 16, 32)
+			if err == nil {
+				cmap.Chars[uint32(code)] = pdfUTF16Hex(pair[2])
+			}
+		}
+	}
+	for _, section := range regexp.MustCompile(`(?s)beginbfrange(.*?)endbfrange`).FindAllStringSubmatch(text, -1) {
+		for _, item := range regexp.MustCompile(hexToken+`\s*`+hexToken+`\s*(<[0-9a-fA-F]*>|\[[^\]]*\])`).FindAllStringSubmatch(section[1], -1) {
+			low, errLow := strconv.ParseUint(item[1], 16, 32)
+			high, errHigh := strconv.ParseUint(item[2], 16, 32)
+			if errLow != nil || errHigh != nil || high < low || high-low > 0xffff {
+				continue
+			}
+			if strings.HasPrefix(item[3], "[") {
+				targets := regexp.MustCompile(hexToken).FindAllStringSubmatch(item[3], -1)
+				for i, target := range targets {
+					if low+uint64(i) > high {
+						break
+					}
+					cmap.Chars[uint32(low)+uint32(i)] = pdfUTF16Hex(target[1])
+				}
+				continue
+			}
+			base := []rune(pdfUTF16Hex(strings.Trim(item[3], "<>")))
+			if len(base) == 0 {
+				continue
+			}
+			for code := low; code <= high; code++ {
+				chars := append([]rune{}, base...)
+				chars[len(chars)-1] += rune(code - low)
+				cmap.Chars[uint32(code)] = string(chars)
+			}
+		}
+	}
+	return cmap
+}
+
+func pdfUTF16Hex(value string) string {
+	raw, err := hex.DecodeString(value)
+	if err != nil {
+		return ""
+	}
+	units := make([]uint16, 0, len(raw)/2)
+	for i := 0; i+1 < len(raw); i += 2 {
+		units = append(units, uint16(raw[i])<<8|uint16(raw[i+1]))
+	}
+	return string(utf16.Decode(units))
+}
+
+// decode converts the bytes of a shown string to text. Without a ToUnicode
+// map the bytes are read as WinAnsi, which covers the standard fonts.
+func (cmap *pdfCMap) decode(raw []byte) string {
+	var builder strings.Builder
+	if cmap == nil || len(cmap.Chars) == 0 {
+		for _, b := range raw {
+			builder.WriteRune(pdfWinAnsiRune(b))
+		}
+		return builder.String()
+	}
+	for i := 0; i+cmap.CodeBytes <= len(raw); i += cmap.CodeBytes {
+		code := uint32(0)
+		for _, b := range raw[i : i+cmap.CodeBytes] {
+			code = code<<8 | uint32(b)
+		}
+		builder.WriteString(cmap.Chars[code])
+	}
+	return builder.String()
+}
+
+// pdfWinAnsiRune maps a WinAnsiEncoding byte to its character. Only the
+// punctuation that differs from Latin-1 and is common on invoices is mapped.
+func pdfWinAnsiRune(b byte) rune {
+	switch b {
+	case 0x80:
+		return '€'
+	case 0x91, 0x92:
+		return '\''
+	case 0x93, 0x94:
+		return '"'
+	case 0x96, 0x97:
+		return '-'
+	}
+	return rune(b)
+}
+
+// pdfMatrix is an affine transform [a b c d e f].
+type pdfMatrix [6]float64
+
+var pdfIdentity = pdfMatrix{1, 0, 0, 1, 0, 0}
+
+func (m pdfMatrix) multiply(n pdfMatrix) pdfMatrix {
+	return pdfMatrix{
+		m[0]*n[0] + m[1]*n[2],
+		m[0]*n[1] + m[1]*n[3],
+		m[2]*n[0] + m[3]*n[2],
+		m[2]*n[1] + m[3]*n[3],
+		m[4]*n[0] + m[5]*n[2] + n[4],
+		m[4]*n[1] + m[5]*n[3] + n[5],
+	}
+}
+
+// interpretPDFContent runs the text operators of a content stream and
+// returns the strings it shows with their positions. Glyph widths are not
+// known, so the position after a string is estimated from its length, and
+// only strings that follow one another without being positioned are joined
+// without a space.
+func interpretPDFContent(content []byte, fonts map[string]*pdfCMap) []pdfTextRun {
+	runs := []pdfTextRun{}
+	ctm := pdfIdentity
+	stack := []pdfMatrix{}
+	textMatrix, lineMatrix := pdfIdentity, pdfIdentity
+	var font *pdfCMap
+	fontSize, leading := 1.0, 0.0
+	positioned := true
+
+	show := func(raw []byte) {
+		text := font.decode(raw)
+		if text == "" {
+			return
+		}
+		position := textMatrix.multiply(ctm)
+		size := fontSize * math.Hypot(position[2], position[3])
+		runs = append(runs, pdfTextRun{X: position[4], Y: position[5], Size: size, Text: text, Continued: !positioned})
+		textMatrix = pdfMatrix{1, 0, 0, 1, float64(len([]rune(text))) * fontSize * 0.5, 0}.multiply(textMatrix)
+		positioned = false
+	}
+	nextLine := func(tx float64, ty float64) {
+		lineMatrix = pdfMatrix{1, 0, 0, 1, tx, ty}.multiply(lineMatrix)
+		textMatrix = lineMatrix
+		positioned = true
+	}
+
+	operands := []pdfToken{}
+	lexer := &pdfLexer{data: content}
+	for {
+		token, ok := lexer.next()
+		if !ok {
+			break
+		}
+		if token.kind != pdfTokenOperator {
+			operands = append(operands, token)
+			continue
+		}
+		numbers := func(count int) []float64 {
+			if len(operands) < count {
+				return nil
+			}
+			values := make([]float64, count)
+			for i, operand := range operands[len(operands)-count:] {
+				values[i] = operand.number
+			}
+			return values
+		}
+		switch token.text {
+		case "q":
+			stack = append(stack, ctm)
+		case "Q":
+			if len(stack) > 0 {
+				ctm = stack[len(stack)-1]
+				stack = stack[:len(stack)-1]
+			}
+		case "cm":
+			if v := numbers(6); v != nil {
+				ctm = pdfMatrix{v[0], v[1], v[2], v[3], v[4], v[5]}.multiply(ctm)
+			}
+		case "BT":
+			textMatrix, lineMatrix = pdfIdentity, pdfIdentity
+			positioned = true
+		case "Tf":
+			if len(operands) >= 2 {
+				font = fonts[strings.TrimPrefix(operands[len(operands)-2].text, "/")]
+				fontSize = operands[len(operands)-1].number
+			}
+		case "TL":
+			if v := numbers(1); v != nil {
+				leading = v[0]
+			}
+		case "Td":
+			if v := numbers(2); v != nil {
+				nextLine(v[0], v[1])
+			}
+		case "TD":
+			if v := numbers(2); v != nil {
+				leading = -v[1]
+				nextLine(v[0], v[1])
+			}
+		case "Tm":
+			if v := numbers(6); v != nil {
+				lineMatrix = pdfMatrix{v[0], v[1], v[2], v[3], v[4], v[5]}
+				textMatrix = lineMatrix
+				positioned = true
+			}
+		case "T*":
+			nextLine(0, -leading)
+		case "Tj":
+			if len(operands) > 0 {
+				show(operands[len(operands)-1].raw)
+			}
+		case "'", "\"":
+			nextLine(0, -leading)
+			if len(operands) > 0 {
+				show(operands[len(operands)-1].raw)
+			}
+		case "TJ":
+			for _, item := range lexer.lastArray {
+				if item.kind == pdfTokenString {
+					show(item.raw)
+				} else if item.kind == pdfTokenNumber {
+					textMatrix = pdfMatrix{1, 0, 0, 1, -item.number / 1000 * fontSize, 0}.multiply(textMatrix)
+					// A large negative adjustment is a gap between words.
+					if item.number < -200 {
+						positioned = true
+					}
+				}
+			}
+		}
+		operands = operands[:0]
+	}
+	return runs
+}
+
+// pdfRunsToLines groups text runs that share a baseline into lines, top of
+// the page first and left to right within a line.
+func pdfRunsToLines(runs []pdfTextRun) []string {
+	sort.SliceStable(runs, func(i, j int) bool {
+		return runs[i].Y > runs[j].Y
+	})
+	lines := []string{}
+	for start := 0; start < len(runs); {
+		end := start + 1
+		tolerance := math.Max(runs[start].Size*0.4, 1)
+		for end < len(runs) && math.Abs(runs[end].Y-runs[start].Y) <= tolerance {
+			end++
+		}
+		line := runs[start:end]
+		sort.SliceStable(line, func(i, j int) bool {
+			return line[i].X < line[j].X
+		})
+		var builder strings.Builder
+		for i, run := range line {
+			if i > 0 && !run.Continued {
+				builder.WriteByte(' ')
+			}
+			builder.WriteString(run.Text)
+		}
+		if text := strings.Join(strings.Fields(builder.String()), " "); text != "" {
+			lines = append(lines, text)
+		}
+		start = end
+	}
+	return lines
+}
+
+type pdfTokenKind int
+
+const (
+	pdfTokenNumber pdfTokenKind = iota
+	pdfTokenString
+	pdfTokenName
+	pdfTokenOperator
+	pdfTokenOther
+)
+
+type pdfToken struct {
+	kind   pdfTokenKind
+	text   string
+	number float64
+	raw    []byte
+}
+
+// pdfLexer splits a content stream into operands and operators. Arrays are
+// read whole; the items of the most recent one are kept in lastArray for TJ.
+type pdfLexer struct {
+	data      []byte
+	pos       int
+	lastArray []pdfToken
+}
+
+func (l *pdfLexer) next() (pdfToken, bool) {
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		switch {
+		case c == '%':
+			for l.pos < len(l.data) && l.data[l.pos] != '\n' && l.data[l.pos] != '\r' {
+				l.pos++
+			}
+		case pdfIsWhitespace(c):
+			l.pos++
+		case c == '(':
+			return pdfToken{kind: pdfTokenString, raw: l.literalString()}, true
+		case c == '<' && l.pos+1 < len(l.data) && l.data[l.pos+1] == '<':
+			l.skipDict()
+			return pdfToken{kind: pdfTokenOther}, true
+		case c == '<':
+			return pdfToken{kind: pdfTokenString, raw: l.hexString()}, true
+		case c == '[':
+			l.pos++
+			items := []pdfToken{}
+			for {
+				l.skipWhitespace()
+				if l.pos >= len(l.data) {
+					break
+				}
+				if l.data[l.pos] == ']' {
+					l.pos++
+					break
+				}
+				item, ok := l.next()
+				if !ok {
+					break
+				}
+				items = append(items, item)
+			}
+			l.lastArray = items
+			return pdfToken{kind: pdfTokenOther}, true
+		case c == ']' || c == '>' || c == ')' || c == '{' || c == '}':
+			l.pos++
+		case c == '/':
+			start := l.pos
+			l.pos++
+			for l.pos < len(l.data) && !pdfIsWhitespace(l.data[l.pos]) && !pdfIsDelimiter(l.data[l.pos]) {
+				l.pos++
+			}
+			return pdfToken{kind: pdfTokenName, text: string(l.data[start:l.pos])}, true
+		default:
+			start := l.pos
+			for l.pos < len(l.data) && !pdfIsWhitespace(l.data[l.pos]) && !pdfIsDelimiter(l.data[l.pos]) {
+				l.pos++
+			}
+			if l.pos == start {
+				l.pos++
+				continue
+			}
+			word := string(l.data[start:l.pos])
+			if number, err := strconv.ParseFloat(word, 64); err == nil {
+				return pdfToken{kind: pdfTokenNumber, text: word, number: number}, true
+			}
+			if word == "BI" {
+				l.skipInlineImage()
+				continue
+			}
+			return pdfToken{kind: pdfTokenOperator, text: word}, true
+		}
+	}
+	return pdfToken{}, false
+}
+
+func (l *pdfLexer) skipWhitespace() {
+	for l.pos < len(l.data) && pdfIsWhitespace(l.data[l.pos]) {
+		l.pos++
+	}
+}
+
+func (l *pdfLexer) literalString() []byte {
+	l.pos++
+	depth := 1
+	out := []byte{}
+	for l.pos < len(l.data) {
+		c := l.data[l.pos]
+		l.pos++
+		switch c {
+		case '\\':
+			if l.pos >= len(l.data) {
+				return out
+			}
+			escaped := l.data[l.pos]
+			l.pos++
+			switch escaped {
+			case 'n':
+				out = append(out, '\n')
+			case 'r':
+				out = append(out, '\r')
+			case 't':
+				out = append(out, '\t')
+			case 'b':
+				out = append(out, '\b')
+			case 'f':
+				out = append(out, '\f')
+			case '\r', '\n':
+				if escaped == '\r' && l.pos < len(l.data) && l.data[l.pos] == '\n' {
+					l.pos++
+				}
+			default:
+				if escaped >= '0' && escaped <= '7' {
+					value := int(escaped - '0')
+					for i := 0; i < 2 && l.pos < len(l.data) && l.data[l.pos] >= '0' && l.data[l.pos] <= '7'; i++ {
+						value = value*8 + int(l.data[l.pos]-'0')
+						l.pos++
+					}
+					out = append(out, byte(value))
+				} else {
+					out = append(out, escaped)
+				}
+			}
+		case '(':
+			depth++
+			out = append(out, c)
+		case ')':
+			depth--
+			if depth == 0 {
+				return out
+			}
+			out = append(out, c)
+		default:
+			out = append(out, c)
+		}
+	}
+	return out
+}
+
+func (l *pdfLexer) hexString() []byte {
+	l.pos++
+	start := l.pos
+	for l.pos < len(l.data) && l.data[l.pos] != '>' {
+		l.pos++
+	}
+	digits := strings.Map(func(r rune) rune {
+		if pdfIsWhitespace(byte(r)) {
+			return -1
+		}
+		return r
+	}, string(l.data[start:l.pos]))
+	l.pos++
+	if len(digits)%2 == 1 {
+		digits += "0"
+	}
+	raw, _ := hex.DecodeString(digits)
+	return raw
+}
+
+func (l *pdfLexer) skipDict() {
+	depth := 0
+	for l.pos+1 < len(l.data) {
+		switch string(l.data[l.pos : l.pos+2]) {
+		case "<<":
+			depth++
+			l.pos += 2
+			continue
+		case ">>":
+			depth--
+			l.pos += 2
+			if depth == 0 {
+				return
+			}
+			continue
+		}
+		l.pos++
+	}
+	l.pos = len(l.data)
+}
+
+// skipInlineImage skips the data of an inline image, which runs from the ID
+// operator to the EI operator.
+func (l *pdfLexer) skipInlineImage() {
+	at := bytes.Index(l.data[l.pos:], []byte("ID"))
+	if at < 0 {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += at + 2
+	end := regexp.MustCompile(`\sEI(\s|$)`).FindIndex(l.data[l.pos:])
+	if end == nil {
+		l.pos = len(l.data)
+		return
+	}
+	l.pos += end[1]
+}
+
+func pdfIsWhitespace(c byte) bool {
+	return c == ' ' || c == '\n' || c == '\r' || c == '\t' || c == '\f' || c == 0
+}
+
+func pdfIsDelimiter(c byte) bool {
+	return strings.IndexByte("()<>[]{}/%", c) >= 0
+}
@@ -0,0 +1,196 @@
+package utilities
+
+import (
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// SalesTaxCodeExempt is the tax code for purchases that carry no sales tax,
+// such as foreign invoices or zero-rated supplies.
+const SalesTaxCodeExempt = "EXEMPT"
+
+// SalesTaxComponents are the sales taxes an expense's tax can be split into,
+// in the order exports list them.
+var SalesTaxComponents = []string{"HST", "GST", "PST", "QST"}
+
+// SalesTaxTolerance is how far, in dollars, an entered tax amount may exceed
+// the tax the code's rates produce before it is rejected. It absorbs the
+// rounding vendors apply to each tax line separately.
+const SalesTaxTolerance = 0.02
+
+// DefaultSalesTaxProvinces are the provincial rates, in percent, used when
+// app_config has no sales_tax domain or does not override a province.
+var DefaultSalesTaxProvinces = map[string]map[string]float64{
+	"ON": {"HST": 13},
+	"NS": {"HST": 14},
+	"NB": {"HST": 15},
+	"NL": {"HST": 15},
+	"PE": {"HST": 15},
+	"BC": {"GST": 5, "PST": 7},
+	"MB": {"GST": 5, "PST": 7},
+	"SK": {"GST": 5, "PST": 6},
+	"QC": {"GST": 5, "QST": 9.975},
+	"AB": {"GST": 5},
+	"NT": {"GST": 5},
+	"NU": {"GST": 5},
+	"YT": {"GST": 5},
+}
+
+// DefaultSalesTaxProvince is the province used for branches without one.
+const DefaultSalesTaxProvince = "ON"
+
+// SalesTaxConfig is the tax codes expenses may use and the province each
+// branch buys in.
+type SalesTaxConfig struct {
+	// Codes maps a tax code to its component rates in percent. It holds every
+	// province plus SalesTaxCodeExempt.
+	Codes map[string]map[string]float64
+	// Branches maps a branch id to the tax code of its province.
+	Branches map[string]string
+	// DefaultProvince is the tax code for branches not in Branches.
+	DefaultProvince string
+}
+
+// LoadSalesTaxConfig reads the "sales_tax" domain from app_config. The
+// "provinces" object adds or replaces provinces' rates, "branches" maps branch
+// ids to a province and "default_province" sets the province of every other
+// branch. Missing or invalid values fall back to DefaultSalesTaxProvinces and
+// DefaultSalesTaxProvince; unknown tax components and negative rates are
+// ignored.
+func LoadSalesTaxConfig(app core.App) SalesTaxConfig {
+	config := SalesTaxConfig{
+		Codes:           map[string]map[string]float64{SalesTaxCodeExempt: {}},
+		Branches:        map[string]string{},
+		DefaultProvince: DefaultSalesTaxProvince,
+	}
+	for code, rates := range DefaultSalesTaxProvinces {
+		config.Codes[code] = rates
+	}
+
+	value, err := GetConfigValue(app, "sales_tax")
+	if err != nil || value == nil {
+		return config
+	}
+
+	if provinces, ok := value["provinces"].(map[string]any); ok {
+		for code, raw := range provinces {
+			code = NormalizeSalesTaxCode(code)
+			values, ok := raw.(map[string]any)
+			if !ok || code == "" || code == SalesTaxCodeExempt {
+				continue
+			}
+			rates := map[string]float64{}
+			for component, rawRate := range values {
+				component = strings.ToUpper(strings.TrimSpace(component))
+				rate, err := CoerceFloat64(rawRate)
+				if err != nil || rate < 0 || !isSalesTaxComponent(component) {
+					continue
+				}
+				if rate > 0 {
+					rates[component] = rate
+				}
+			}
+			config.Codes[code] = rates
+		}
+	}
+	if province, ok := value["default_province"].(string); ok {
+		if _, known := config.Codes[NormalizeSalesTaxCode(province)]; known {
+			config.DefaultProvince = NormalizeSalesTaxCode(province)
+		}
+	}
+	if branches, ok := value["branches"].(map[string]any); ok {
+		for branchID, raw := range branches {
+			province, ok := raw.(string)
+			if !ok {
+				continue
+			}
+			if _, known := config.Codes[NormalizeSalesTaxCode(province)]; known {
+				config.Branches[branchID] = NormalizeSalesTaxCode(province)
+			}
+		}
+	}
+	return config
+}
+
+func isSalesTaxComponent(component string) bool {
+	for _, known := range SalesTaxComponents {
+		if component == known {
+			return true
+		}
+	}
+	return false
+}
+
+// NormalizeSalesTaxCode trims and upper-cases a tax code.
+func NormalizeSalesTaxCode(code string) string {
+	return strings.ToUpper(strings.TrimSpace(code))
+}
+
+// Valid reports whether code is a configured tax code.
+func (c SalesTaxConfig) Valid(code string) bool {
+	_, ok := c.Codes[NormalizeSalesTaxCode(code)]
+	return ok
+}
+
+// Rate returns the combined rate of code as a fraction, e.g. 0.13 for ON.
+// Unknown codes have no tax.
+func (c SalesTaxConfig) Rate(code string) float64 {
+	total := 0.0
+	for _, rate := range c.Codes[NormalizeSalesTaxCode(code)] {
+		total += rate
+	}
+	return total / 100
+}
+
+// DefaultCode returns the tax code for a purchase: the vendor's tax code when
+// it is configured, else the province of the branch.
+func (c SalesTaxConfig) DefaultCode(vendorCode string, branchID string) string {
+	if c.Valid(vendorCode) {
+		return NormalizeSalesTaxCode(vendorCode)
+	}
+	if province, ok := c.Branches[branchID]; ok {
+		return province
+	}
+	return c.DefaultProvince
+}
+
+// SplitInclusive splits a tax-inclusive total into its pre-tax amount and tax
+// at code's rate. Both are rounded to cents and add up to the total.
+func (c SalesTaxConfig) SplitInclusive(code string, total float64) (preTax float64, tax float64) {
+	preTax = RoundCurrencyAmount(total / (1 + c.Rate(code)))
+	return preTax, RoundCurrencyAmount(total - preTax)
+}
+
+// Components splits a tax amount across code's taxes in proportion to their
+// rates. The last component absorbs rounding so the parts add up to tax.
+// Components not charged under code are omitted.
+func (c SalesTaxConfig) Components(code string, tax float64) map[string]float64 {
+	rates := c.Codes[NormalizeSalesTaxCode(code)]
+	combined := 0.0
+	charged := []string{}
+	for _, component := range SalesTaxComponents {
+		if rates[component] > 0 {
+			combined += rates[component]
+			charged = append(charged, component)
+		}
+	}
+	result := map[string]float64{}
+	remaining := tax
+	for i, component := range charged {
+		if i == len(charged)-1 {
+			result[component] = RoundCurrencyAmount(remaining)
+			break
+		}
+		part := RoundCurrencyAmount(tax * rates[component] / combined)
+		result[component] = part
+		remaining -= part
+	}
+	return result
+}
+
+// ExceedsRate reports whether tax is more than code's rates allow on preTax,
+// beyond SalesTaxTolerance.
+func (c SalesTaxConfig) ExceedsRate(code string, preTax float64, tax float64) bool {
+	return tax-preTax*c.Rate(code) > SalesTaxTolerance+1e-9
+}
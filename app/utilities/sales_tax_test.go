@@ -0,0 +1,90 @@
+package utilities
+
+import (
+	"testing"
+
+	"tybalt/internal/testseed"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+func TestLoadSalesTaxConfig(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	defer app.Cleanup()
+
+	config := LoadSalesTaxConfig(app)
+	if config.DefaultProvince != "ON" || config.Rate("on") != 0.13 || config.Rate(SalesTaxCodeExempt) != 0 {
+		t.Fatalf("expected the built-in rates without a sales_tax domain, got %+v", config)
+	}
+	if config.DefaultCode("", "1r7r6hyp681vi15") != "ON" {
+		t.Fatalf("expected branches to default to ON, got %q", config.DefaultCode("", "1r7r6hyp681vi15"))
+	}
+
+	collection, err := app.FindCollectionByNameOrId("app_config")
+	if err != nil {
+		t.Fatalf("failed to find app_config collection: %v", err)
+	}
+	record := core.NewRecord(collection)
+	record.Set("key", "sales_tax")
+	record.Set("value", `{
+		"default_province": "bc",
+		"provinces": {"ON": {"HST": 15}, "XX": {"GST": 5, "VAT": 20}, "EXEMPT": {"GST": 5}},
+		"branches": {"1r7r6hyp681vi15": "ON", "2b65d8161y8hx95": "ZZ"}
+	}`)
+	if err := app.Save(record); err != nil {
+		t.Fatalf("failed to save sales_tax config: %v", err)
+	}
+
+	config = LoadSalesTaxConfig(app)
+	if config.Rate("ON") != 0.15 || config.Rate("XX") != 0.05 || config.Rate("QC") != 0.14975 || config.Rate(SalesTaxCodeExempt) != 0 {
+		t.Fatalf("expected provinces to override and extend the built-in rates, got %+v", config.Codes)
+	}
+	if config.DefaultCode("", "1r7r6hyp681vi15") != "ON" || config.DefaultCode("", "2b65d8161y8hx95") != "BC" {
+		t.Fatalf("expected branch provinces with a BC default, got %+v default %q", config.Branches, config.DefaultProvince)
+	}
+	if config.DefaultCode(" exempt ", "1r7r6hyp681vi15") != SalesTaxCodeExempt || config.DefaultCode("ZZ", "1r7r6hyp681vi15") != "ON" {
+		t.Fatalf("expected a configured vendor tax code to win over the branch province")
+	}
+}
+
+func TestSalesTaxSplit(t *testing.T) {
+	config := SalesTaxConfig{Codes: map[string]map[string]float64{SalesTaxCodeExempt: {}}}
+	for code, rates := range DefaultSalesTaxProvinces {
+		config.Codes[code] = rates
+	}
+
+	cases := []struct {
+		code   string
+		total  float64
+		preTax float64
+		tax    float64
+	}{
+		{"ON", 113, 100, 13},
+		{"ON", 10, 8.85, 1.15},
+		{"QC", 114.98, 100, 14.98},
+		{"AB", 52.5, 50, 2.5},
+		{SalesTaxCodeExempt, 40, 40, 0},
+	}
+	for _, tc := range cases {
+		preTax, tax := config.SplitInclusive(tc.code, tc.total)
+		if preTax != tc.preTax || tax != tc.tax {
+			t.Fatalf("%s %.2f: expected %.2f + %.2f, got %.2f + %.2f", tc.code, tc.total, tc.preTax, tc.tax, preTax, tax)
+		}
+	}
+
+	components := config.Components("QC", 14.98)
+	if len(components) != 2 || components["GST"] != 5 || components["QST"] != 9.98 {
+		t.Fatalf("expected QC tax to split into GST and QST, got %+v", components)
+	}
+	components = config.Components("BC", 1.2)
+	if components["GST"] != 0.5 || components["PST"] != 0.7 {
+		t.Fatalf("expected BC tax to split into GST and PST, got %+v", components)
+	}
+	if len(config.Components(SalesTaxCodeExempt, 0)) != 0 {
+		t.Fatalf("expected exempt purchases to have no tax components")
+	}
+
+	if config.ExceedsRate("ON", 100, 13.02) || !config.ExceedsRate("ON", 100, 13.03) || !config.ExceedsRate(SalesTaxCodeExempt, 40, 0.05) {
+		t.Fatalf("expected tax beyond the rate and tolerance to be rejected")
+	}
+}
@@ -0,0 +1,55 @@
+package utilities
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/pocketbase/dbx"
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// StatutoryHoliday is a statutory_holidays record observed by a branch.
+type StatutoryHoliday struct {
+	ID   string `db:"id" json:"id"`
+	Date string `db:"date" json:"date"`
+	Name string `db:"name" json:"name"`
+}
+
+// StatutoryHolidayBranchCondition limits statutory_holidays (aliased h) to the
+// holidays observed by the branch id that branchExpr evaluates to. A holiday
+// without branches is observed company-wide.
+func StatutoryHolidayBranchCondition(branchExpr string) string {
+	return `(
+		json_array_length(CASE WHEN json_valid(h.branches) THEN h.branches ELSE '[]' END) = 0
+		OR EXISTS (
+			SELECT 1 FROM json_each(CASE WHEN json_valid(h.branches) THEN h.branches ELSE '[]' END)
+			WHERE value = ` + branchExpr + `
+		)
+	)`
+}
+
+// StatutoryHolidaysInWeek returns the statutory holidays observed by branchID
+// in the week ending on weekEnding (YYYY-MM-DD), ordered by date.
+func StatutoryHolidaysInWeek(app core.App, branchID string, weekEnding string) ([]StatutoryHoliday, error) {
+	weekEndingTime, err := time.Parse(time.DateOnly, weekEnding)
+	if err != nil {
+		return nil, fmt.Errorf("invalid week ending %q: %w", weekEnding, err)
+	}
+
+	holidays := []StatutoryHoliday{}
+	err = app.DB().NewQuery(`
+		SELECT h.id, h.date, h.name
+		FROM statutory_holidays h
+		WHERE h.date BETWEEN {:weekStart} AND {:weekEnding}
+		  AND ` + StatutoryHolidayBranchCondition("{:branch}") + `
+		ORDER BY h.date, h.name
+	`).Bind(dbx.Params{
+		"weekStart":  weekEndingTime.AddDate(0, 0, -6).Format(time.DateOnly),
+		"weekEnding": weekEnding,
+		"branch":     branchID,
+	}).All(&holidays)
+	if err != nil {
+		return nil, err
+	}
+	return holidays, nil
+}
@@ -0,0 +1,21 @@
+package utilities
+
+// TimesheetExpectationJoins and TimesheetExpectedCondition classify users for
+// a week the same way in the timesheet tracking lists and the submission
+// reminders. Queries alias users as u and bind {:week_ending}.
+//
+// A user is expected to submit a time sheet for the week when
+// time_sheet_expected is set on their admin profile and no
+// missing-time-sheet exception was recorded for that week.
+const TimesheetExpectationJoins = `
+	LEFT JOIN admin_profiles ap ON ap.uid = u.id
+	LEFT JOIN time_sheet_missing_exceptions ex ON ex.uid = u.id AND ex.week_ending = {:week_ending}`
+
+const TimesheetExpectedCondition = `COALESCE(ap.time_sheet_expected, 0) = 1
+	AND ex.id IS NULL`
+
+// TimesheetReminderCondition narrows TimesheetExpectedCondition to users with
+// an active admin profile. Only reminders use it: the tracking missing list
+// still shows inactive users who are expected to submit.
+const TimesheetReminderCondition = `COALESCE(ap.active, 0) = 1
+	AND ` + TimesheetExpectedCondition
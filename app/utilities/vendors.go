@@ -0,0 +1,36 @@
+package utilities
+
+import (
+	"strings"
+
+	"github.com/pocketbase/pocketbase/core"
+)
+
+// ActiveVendor is the id, name and alias of an active vendor, as importers
+// and receipt extraction need them to match free-text merchant names.
+type ActiveVendor struct {
+	ID    string `db:"id"`
+	Name  string `db:"name"`
+	Alias string `db:"alias"`
+}
+
+// LoadActiveVendors returns the id, name and alias of every active vendor.
+func LoadActiveVendors(app core.App) ([]ActiveVendor, error) {
+	vendors := []ActiveVendor{}
+	if err := app.DB().NewQuery(`
+		SELECT id, name, COALESCE(alias, '') AS alias
+		FROM vendors
+		WHERE status = 'Active'
+	`).All(&vendors); err != nil {
+		return nil, err
+	}
+	return vendors, nil
+}
+
+// NameWords splits a merchant or vendor name into lower-case words of letters
+// and digits, dropping punctuation, so names can be compared word by word.
+func NameWords(value string) []string {
+	return strings.FieldsFunc(strings.ToLower(value), func(r rune) bool {
+		return !(r >= 'a' && r <= 'z' || r >= '0' && r <= '9')
+	})
+}
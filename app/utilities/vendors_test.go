@@ -0,0 +1,40 @@
+package utilities
+
+import (
+	"reflect"
+	"testing"
+
+	"tybalt/internal/testseed"
+)
+
+func TestNameWords(t *testing.T) {
+	got := NameWords("Home Depot #7042, Ltd.")
+	if want := []string{"home", "depot", "7042", "ltd"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("NameWords() = %v, want %v", got, want)
+	}
+	if got := NameWords(" -- "); len(got) != 0 {
+		t.Fatalf("expected no words, got %v", got)
+	}
+}
+
+func TestLoadActiveVendors(t *testing.T) {
+	app := testseed.NewSeededTestApp(t)
+	defer app.Cleanup()
+
+	vendors, err := LoadActiveVendors(app)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(vendors) == 0 {
+		t.Fatal("expected active vendors in the seed data")
+	}
+	for _, vendor := range vendors {
+		record, err := app.FindRecordById("vendors", vendor.ID)
+		if err != nil {
+			t.Fatalf("failed to load vendor %s: %v", vendor.ID, err)
+		}
+		if record.GetString("status") != "Active" || record.GetString("name") != vendor.Name {
+			t.Fatalf("unexpected vendor %+v for record %v", vendor, record)
+		}
+	}
+}
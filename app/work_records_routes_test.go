@@ -43,6 +43,10 @@ func TestWorkRecordsRoutes(t *testing.T) {
 	if err != nil {
 		t.Fatal(err)
 	}
+	noClaimsToken, err := testutils.GenerateRecordToken("users", "u_no_claims@example.com")
+	if err != nil {
+		t.Fatal(err)
+	}
 
 	scenarios := []tests.ApiScenario{
 		{
@@ -170,6 +174,86 @@ func TestWorkRecordsRoutes(t *testing.T) {
 			},
 			TestAppFactory: testutils.SetupTestApp,
 		},
+		{
+			Name:   "report holder can print a legacy work record",
+			Method: http.MethodGet,
+			URL:    "/api/work_records/K12-314/pdf",
+			Headers: map[string]string{
+				"Authorization": reportToken,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				"%PDF-1.4",
+				"(Work Record K12-314) Tj",
+				"(Time Entries) Tj",
+			},
+			TestAppFactory: testutils.SetupTestApp,
+			AfterTestFunc: func(t testing.TB, _ *tests.TestApp, res *http.Response) {
+				if got := res.Header.Get("Content-Type"); got != "application/pdf" {
+					t.Fatalf("expected application/pdf content type, got %q", got)
+				}
+				if got := res.Header.Get("Content-Disposition"); got != `inline; filename="work_record_K12-314.pdf"` {
+					t.Fatalf("unexpected content disposition %q", got)
+				}
+			},
+		},
+		{
+			Name:   "unauthorized user cannot print a legacy work record",
+			Method: http.MethodGet,
+			URL:    "/api/work_records/K12-314/pdf",
+			Headers: map[string]string{
+				"Authorization": regularUserToken,
+			},
+			ExpectedStatus: http.StatusForbidden,
+			ExpectedContent: []string{
+				`"message":"you are not authorized to view work records"`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:   "worker on a first-class work record can print it by number",
+			Method: http.MethodGet,
+			URL:    "/api/work_records/W2604-0001/pdf",
+			Headers: map[string]string{
+				"Authorization": regularUserToken,
+			},
+			ExpectedStatus: http.StatusOK,
+			ExpectedContent: []string{
+				"(Work Record W2604-0001) Tj",
+				"(Traffic Control) Tj",
+				"(Workers) Tj",
+				"(Consumables) Tj",
+				"(Notes) Tj",
+				"(No time entries are linked to this work record yet.) Tj",
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:   "unrelated user cannot print a first-class work record",
+			Method: http.MethodGet,
+			URL:    "/api/work_records/W2604-0001/pdf",
+			Headers: map[string]string{
+				"Authorization": noClaimsToken,
+			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedContent: []string{
+				`"code":"not_found"`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
+		{
+			Name:   "unknown legacy work record cannot be printed",
+			Method: http.MethodGet,
+			URL:    "/api/work_records/Z99-999/pdf",
+			Headers: map[string]string{
+				"Authorization": reportToken,
+			},
+			ExpectedStatus: http.StatusNotFound,
+			ExpectedContent: []string{
+				`"code":"not_found"`,
+			},
+			TestAppFactory: testutils.SetupTestApp,
+		},
 	}
 
 	for _, scenario := range scenarios {